@@ -19,35 +19,60 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
-	backoff "github.com/cenkalti/backoff/v4"
 	"github.com/jonboulle/clockwork"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	workloadmanager "google.golang.org/api/workloadmanager/v1"
+	"github.com/GoogleCloudPlatform/sapagent/shared/commandlineexecutor"
 	"github.com/GoogleCloudPlatform/sapagent/shared/gce"
-	"github.com/GoogleCloudPlatform/sapagent/shared/gce/metadataserver"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 	"github.com/GoogleCloudPlatform/sql-server-agent/cmd/agent/agentshared"
 	"github.com/GoogleCloudPlatform/sql-server-agent/cmd/agent/flags"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/activation"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentusage"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/collectionwindow"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/configuration"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/cyclestatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/deadletter"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/filearchive"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/filesign"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/gcemetadata"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/history"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/guestcollector"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/iamproxy"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/instanceinfo"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/pubsub"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/openmetrics"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/remote"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/resourcelimits"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/secretmanager"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/sqlcollector"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/synthetic"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/tracing"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/webhook"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/wlm"
 	configpb "github.com/GoogleCloudPlatform/sql-server-agent/protos/sqlserveragentconfig"
 )
 
 const (
 	// ServiceName .
-	ServiceName = "google-cloud-sql-server-agent"
+	ServiceName = internal.ServiceName
 	// ServiceDisplayName .
 	ServiceDisplayName = "Google Cloud Agent for SQL Server"
 	// Description .
@@ -81,31 +106,75 @@ type InstanceProperties struct {
 	Image         string
 }
 
+// ruleLimiter bounds the number of rules running at the same time across all collection types.
+var ruleLimiter = internal.NewSemaphore(runtime.GOMAXPROCS(0))
+
 // UsageMetricsLogger logs usage metrics.
 var UsageMetricsLogger agentstatus.AgentStatus = UsageMetricsLoggerInit(false)
 
 // UsageMetricsLoggerInit initializes and returns usage metrics logger.
 func UsageMetricsLoggerInit(logUsage bool) agentstatus.AgentStatus {
 	ap := agentstatus.NewAgentProperties(ServiceName, internal.AgentVersion, logUsage)
-	sip := SourceInstanceProperties()
+	// The usage metrics are logged without the properties of the instance if they cannot be read.
+	sip, _ := SourceInstanceProperties()
 	cp := agentstatus.NewCloudProperties(sip.ProjectID, sip.Zone, sip.Instance, sip.ProjectNumber, sip.Image)
 	return agentstatus.NewUsageMetricsLogger(ap, cp, clockwork.NewRealClock(), []string{})
 }
 
+// metadataClient reads the properties of the instance the agent is running on from the metadata
+// server. MetadataSetup configures its timeout and retries.
+var metadataClient = gcemetadata.Client{Timeout: 2 * time.Second, MaxRetries: 3, InitialInterval: time.Second}
+
+// lastSourceProperties are the properties last read from the metadata server.
+var lastSourceProperties struct {
+	sync.Mutex
+	properties *gcemetadata.Properties
+}
+
+// MetadataSetup sets the timeout and retries of the requests to the metadata server from the
+// configuration.
+func MetadataSetup(cfg *configpb.Configuration) {
+	metadataClient.Timeout = time.Duration(cfg.GetMetadataServer().GetTimeoutInSeconds()) * time.Second
+	if metadataClient.Timeout == 0 {
+		metadataClient.Timeout = 2 * time.Second
+	}
+	metadataClient.MaxRetries = int(cfg.GetMetadataServer().GetMaxRetries())
+	if metadataClient.MaxRetries == 0 {
+		metadataClient.MaxRetries = 3
+	}
+}
+
 // SourceInstanceProperties returns properties of the instance the agent is running on.
-func SourceInstanceProperties() InstanceProperties {
-	properties := metadataserver.CloudPropertiesWithRetry(backoff.NewConstantBackOff(30 * time.Second))
-	location := string(properties.GetZone()[0:strings.LastIndex(properties.GetZone(), "-")])
-	name := fmt.Sprintf("projects/%s/locations/%s", properties.GetProjectId(), location)
-	return InstanceProperties{
-		Name:          name,
-		ProjectID:     properties.GetProjectId(),
-		ProjectNumber: properties.GetNumericProjectId(),
-		InstanceID:    properties.GetInstanceId(),
-		Instance:      properties.GetInstanceName(),
-		Zone:          properties.GetZone(),
-		Image:         properties.GetImage(),
+// If the metadata server cannot be reached after all retries, the properties last read from it
+// are returned. An error is returned if they were never read, so that the callers skip the cycle
+// rather than sending the collected data with empty properties.
+func SourceInstanceProperties() (InstanceProperties, error) {
+	properties, err := metadataClient.Properties(context.Background())
+	lastSourceProperties.Lock()
+	switch {
+	case err == nil:
+		lastSourceProperties.properties = properties
+	case lastSourceProperties.properties != nil:
+		log.Logger.Errorw("Failed to read the instance properties from the metadata server. Using the last read properties", "error", err)
+		properties = lastSourceProperties.properties
+	}
+	lastSourceProperties.Unlock()
+	if properties == nil {
+		return InstanceProperties{}, fmt.Errorf("failed to read the instance properties from the metadata server: %v", err)
 	}
+	location := properties.Zone
+	if i := strings.LastIndex(location, "-"); i >= 0 {
+		location = location[:i]
+	}
+	return InstanceProperties{
+		Name:          fmt.Sprintf("projects/%s/locations/%s", properties.ProjectID, location),
+		ProjectID:     properties.ProjectID,
+		ProjectNumber: properties.NumericProjectID,
+		InstanceID:    properties.InstanceID,
+		Instance:      properties.InstanceName,
+		Zone:          properties.Zone,
+		Image:         properties.Image,
+	}, nil
 }
 
 // Init parses flags and execute if certain flags are enabled.
@@ -115,9 +184,24 @@ func Init() (*flags.AgentFlags, string, bool) {
 	return f, output, proceed
 }
 
+// Diff returns the differences between the two persisted collection files of the --diff flag.
+func Diff(f *flags.AgentFlags) (string, error) {
+	before, err := agentshared.LoadCollection(f.DiffFiles[0])
+	if err != nil {
+		return "", err
+	}
+	after, err := agentshared.LoadCollection(f.DiffFiles[1])
+	if err != nil {
+		return "", err
+	}
+	return agentshared.FormatDiff(agentshared.DiffCollections(before, after), f.DiffFormat == flags.DiffFormatJSON)
+}
+
 // LoggingSetup initialize the agent logging level.
 func LoggingSetup(ctx context.Context, logPrefix string, cfg *configpb.Configuration) {
-	agentshared.LoggingSetup(ctx, logPrefix, cfg.GetLogLevel(), SourceInstanceProperties().ProjectID, cfg.GetLogToCloud())
+	// The logs are written locally only if the project of the instance cannot be read.
+	sip, _ := SourceInstanceProperties()
+	agentshared.LoggingSetup(ctx, logPrefix, cfg.GetLogLevel(), sip.ProjectID, cfg.GetLogToCloud())
 }
 
 // LoggingSetupDefault wraps LoggingSetupDefault function from agent_shared.go.
@@ -125,20 +209,371 @@ func LoggingSetupDefault(ctx context.Context, prefix string) {
 	agentshared.LoggingSetupDefault(ctx, prefix)
 }
 
+// ResourceLimitsSetup limits the CPU and memory used by the agent process as configured in
+// resource_limits.
+func ResourceLimitsSetup(cfg *configpb.Configuration) {
+	rl := cfg.GetResourceLimits()
+	if rl == nil {
+		return
+	}
+	limits := resourcelimits.Limits{
+		MaxProcs:         int(rl.GetMaxProcs()),
+		MemoryLimitBytes: int64(rl.GetMemoryLimitMb()) << 20,
+		LowPriority:      rl.GetLowPriority(),
+	}
+	if err := resourcelimits.Apply(limits); err != nil {
+		log.Logger.Errorw("Failed to lower the priority of the agent process", "error", err)
+		return
+	}
+	log.Logger.Infow("Applied the resource limits of the agent process", "max_procs", limits.MaxProcs, "memory_limit_mb", rl.GetMemoryLimitMb(), "low_priority", limits.LowPriority)
+}
+
+// DiskClassifierSetup classifies the physical disks of the guest collections with the
+// disk_type_mappings of the configuration before the disks of compute engine.
+func DiskClassifierSetup(cfg *configpb.Configuration) {
+	var mappings []guestcollector.DiskTypeMapping
+	for _, m := range cfg.GetDiskTypeMappings() {
+		pattern, err := regexp.Compile(m.GetFriendlyNamePattern())
+		if err != nil {
+			log.Logger.Errorw("Invalid disk type mapping", "friendly_name_pattern", m.GetFriendlyNamePattern(), "error", err)
+			continue
+		}
+		mappings = append(mappings, guestcollector.DiskTypeMapping{
+			FriendlyName: pattern,
+			SizeMultiple: m.GetSizeMultipleBytes(),
+			DiskType:     m.GetDiskType(),
+		})
+	}
+	guestcollector.SetDiskClassifier(guestcollector.NewDiskClassifier(mappings))
+}
+
+// WMIQueryRateSetup limits the WMI queries sent to each windows machine as configured in
+// wmi_queries_per_second.
+func WMIQueryRateSetup(cfg *configpb.Configuration) {
+	guestcollector.SetWMIQueryRate(cfg.GetCollectionConfiguration().GetWmiQueriesPerSecond())
+}
+
+// ReloadSetup re-applies the settings of a reloaded configuration that are not read per collection
+// cycle. The rule settings are built from the configuration of every cycle, while
+// resource_limits and otlp_traces_endpoint are applied once at startup and need a restart.
+func ReloadSetup(cfg *configpb.Configuration) {
+	DiskClassifierSetup(cfg)
+	WMIQueryRateSetup(cfg)
+}
+
+// TracingSetup enables tracing of the collection cycles if otlp_traces_endpoint is configured.
+// The returned func flushes the pending spans and is called before the agent exits.
+func TracingSetup(ctx context.Context, cfg *configpb.Configuration) func() {
+	shutdown, err := tracing.Setup(ctx, cfg.GetOtlpTracesEndpoint(), ServiceName, internal.AgentVersion)
+	if err != nil {
+		log.Logger.Errorw("Failed to set up tracing", "endpoint", cfg.GetOtlpTracesEndpoint(), "error", err)
+		return func() {}
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Logger.Warnw("Failed to flush traces", "error", err)
+		}
+	}
+}
+
+// StartCycleSpan starts the root span of a collection cycle.
+func StartCycleSpan(ctx context.Context, collectionType CollectionType) (context.Context, trace.Span) {
+	if collectionType == OS {
+		return tracing.Start(ctx, "os_collection")
+	}
+	return tracing.Start(ctx, "sql_collection")
+}
+
+// StartCycle returns a context recording the outcome of the instances and rules collected with it
+// in a collection cycle, along with the cycle. The rules run with the context are limited to
+// max_concurrent_collections at the same time across all collection types. The default limit is
+// read from GOMAXPROCS when the cycle starts, after max_procs is applied.
+func StartCycle(ctx context.Context, cfg *configpb.Configuration) (context.Context, *cyclestatus.Cycle) {
+	limit := int(cfg.GetMaxConcurrentCollections())
+	if limit == 0 {
+		limit = runtime.GOMAXPROCS(0)
+	}
+	ruleLimiter.SetLimit(limit)
+	return cyclestatus.NewContext(internal.WithRuleLimiter(ctx, ruleLimiter))
+}
+
+// EndCycle decides whether the collection cycle succeeded against the thresholds of the
+// configuration. err is the error that stopped the cycle, if any, which fails the cycle.
+// A successful cycle writes the readiness file of the collection type in dir, a directory only
+// the agent writes to, with the time and the summary of the cycle. A failed cycle removes the
+// readiness file and is reported in the agent status.
+func EndCycle(cycle *cyclestatus.Cycle, cfg *configpb.Configuration, collectionType CollectionType, dir string, err error) {
+	cycle.Abort(err)
+	file := readinessFile(dir, collectionType)
+	if !cycle.Succeeded(cycleThresholds(cfg)) {
+		log.Logger.Warnw("Collection cycle failed", "readiness_file", file, "summary", cycle)
+		if collectionType == OS {
+			UsageMetricsLogger.Error(agentstatus.GuestCycleFailure)
+		} else {
+			UsageMetricsLogger.Error(agentstatus.SQLCycleFailure)
+		}
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Logger.Warnw("Failed to remove the readiness file", "readiness_file", file, "error", err)
+		}
+		return
+	}
+	log.Logger.Debugw("Collection cycle succeeded", "readiness_file", file, "summary", cycle)
+	content := fmt.Sprintf("%s %s\n", time.Now().UTC().Format(time.RFC3339), cycle)
+	if err := writeReadinessFile(file, content); err != nil {
+		log.Logger.Warnw("Failed to write the readiness file", "readiness_file", file, "error", err)
+	}
+}
+
+// writeReadinessFile replaces the readiness file with content. The content is written to a new
+// temporary file in the same directory, which is renamed over the readiness file, so that the
+// file is never read half written and a link planted at its path is replaced, not followed.
+func writeReadinessFile(file, content string) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// cycleThresholds returns the thresholds of a successful collection cycle. Unset thresholds have
+// their default value.
+func cycleThresholds(cfg *configpb.Configuration) cyclestatus.Thresholds {
+	t := cyclestatus.Thresholds{
+		MinInstancePercent: float64(cfg.GetCycleStatus().GetMinInstanceSuccessPercent()),
+		MinRulePercent:     float64(cfg.GetCycleStatus().GetMinRuleSuccessPercent()),
+	}
+	if t.MinInstancePercent == 0 {
+		t.MinInstancePercent = cyclestatus.DefaultMinInstancePercent
+	}
+	if t.MinRulePercent == 0 {
+		t.MinRulePercent = cyclestatus.DefaultMinRulePercent
+	}
+	return t
+}
+
+// readinessFile returns the path of the readiness file of the collection type in dir.
+func readinessFile(dir string, collectionType CollectionType) string {
+	ct := "guest"
+	if collectionType == SQL {
+		ct = "sql"
+	}
+	return filepath.Join(dir, fmt.Sprintf("google-cloud-sql-server-agent-%s.ready", ct))
+}
+
+// deferredSQLInstances holds the number of consecutive sql collection cycles each sql instance
+// was deferred in. It raises the priority of the credentials of the instance in the next cycle.
+var deferredSQLInstances = map[string]int32{}
+
+// sqlInstanceKey identifies the sql instance of sqlCfg.
+func sqlInstanceKey(sqlCfg *configuration.SQLConfig) string {
+	return fmt.Sprintf("%s:%d", sqlCfg.Host, sqlCfg.PortNumber)
+}
+
+// SQLCycleBudget returns the budget of a sql collection cycle: the sql collection interval, shared
+// between the sql instances of all credentials. An instance is deferred when its share is shorter
+// than the collection timeout.
+func SQLCycleBudget(cfg *configpb.Configuration) *internal.CycleBudget {
+	n := 0
+	for _, credentialCfg := range cfg.GetCredentialConfiguration() {
+		n += len(credentialCfg.GetSqlConfigurations())
+	}
+	timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
+	return internal.NewCycleBudget(collectionInterval(cfg, SQL), n, timeout, clockwork.NewRealClock())
+}
+
+// SQLCredentials returns the credentials of cfg in the order of internal.CollectionOrder: from the
+// highest priority to the lowest, the priority of a credential being raised by the number of
+// consecutive cycles its sql instances were deferred in.
+func SQLCredentials(cfg *configpb.Configuration) []*configpb.CredentialConfiguration {
+	credentials := cfg.GetCredentialConfiguration()
+	priorities := make([]int32, len(credentials))
+	deferrals := make([]int32, len(credentials))
+	for i, credentialCfg := range credentials {
+		priorities[i] = credentialCfg.GetPriority()
+		for _, sqlCfg := range SQLConfigFromCredential(credentialCfg) {
+			deferrals[i] = max(deferrals[i], deferredSQLInstances[sqlInstanceKey(sqlCfg)])
+		}
+	}
+	var ordered []*configpb.CredentialConfiguration
+	for _, i := range internal.CollectionOrder(priorities, deferrals) {
+		ordered = append(ordered, credentials[i])
+	}
+	return ordered
+}
+
+// SQLInstanceDeadline returns the deadline of the collection of the sql instance of sqlCfg in the
+// cycle. It returns false and defers the instance to the next cycle if the cycle has no time left
+// for the instance.
+func SQLInstanceDeadline(budget *internal.CycleBudget, sqlCfg *configuration.SQLConfig) (time.Time, bool) {
+	key := sqlInstanceKey(sqlCfg)
+	deadline, ok := budget.Next()
+	if !ok {
+		log.Logger.Warnw("Not enough time left in the sql collection cycle. Deferring the instance to the next cycle", "instance", key)
+		deferredSQLInstances[key]++
+		return time.Time{}, false
+	}
+	delete(deferredSQLInstances, key)
+	return deadline, true
+}
+
+// StartInstanceSpan starts the span of the collection on a single target of a collection cycle.
+func StartInstanceSpan(ctx context.Context, target string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "instance", attribute.String("target", target))
+}
+
+// EndSpan ends the span, recording the error if any.
+func EndSpan(span trace.Span, err error) {
+	tracing.End(span, err)
+}
+
 // InitCollection executes steps for initializing a collection.
 // The func is called at the beginning of every guest and sql collection.
-func InitCollection(ctx context.Context) (*wlm.WLM, error) {
-	wlm, err := wlm.NewWorkloadManager(ctx)
+func InitCollection(ctx context.Context, cfg *configpb.Configuration) (*wlm.WLM, error) {
+	errorlog.Default.SetWindow(time.Duration(cfg.GetRepeatedErrorLogWindowInSeconds()) * time.Second)
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	wlm, err := wlm.NewWorkloadManager(ctx, cfg.GetWlmEndpoint(), dialer)
 	if err != nil {
 		return nil, err
 	}
 	return wlm, nil
 }
 
+// ProbeWLM checks at startup whether collected data can be sent to workload manager and logs a
+// warning with remediation guidance if it cannot. The agent keeps running either way.
+func ProbeWLM(ctx context.Context, cfg *configpb.Configuration) {
+	w, err := InitCollection(ctx, cfg)
+	if err != nil {
+		log.Logger.Warnw("Failed to create the workload manager client for the connectivity check", "error", err)
+		UsageMetricsLogger.Error(agentstatus.WorkloadManagerConnectionError)
+		return
+	}
+	sip, err := SourceInstanceProperties()
+	if err != nil {
+		log.Logger.Warnw("Failed to read the instance properties for the connectivity check", "error", err)
+		return
+	}
+	CheckWLMConnectivity(w, WLMLocation(cfg, sip))
+}
+
+// CheckWLMConnectivity probes workload manager at the location and logs the result.
+func CheckWLMConnectivity(wlmService wlm.WorkloadManagerService, location string) error {
+	err := wlmService.Probe(location)
+	switch {
+	case err == nil:
+		log.Logger.Infow("Workload manager connectivity check passed", "location", location)
+	case errors.Is(err, wlm.ErrPermissionDenied):
+		log.Logger.Warnw("WORKLOAD MANAGER PERMISSION CHECK FAILED: collected data cannot be sent to workload manager. "+
+			"Enable the Workload Manager API in the project, grant the service account of the VM the "+
+			"Workload Manager Insight Writer role (roles/workloadmanager.insightWriter) and make sure the VM "+
+			"has the cloud-platform access scope. The agent keeps collecting in the meantime.", "location", location, "error", err)
+		UsageMetricsLogger.Error(agentstatus.WorkloadManagerPermissionError)
+	default:
+		log.Logger.Warnw("WORKLOAD MANAGER CONNECTIVITY CHECK FAILED: workload manager cannot be reached. "+
+			"Check that the VM can reach the workload manager endpoint (Private Google Access, firewall and "+
+			"proxy settings) and the wlm_endpoint, outbound_source_address and socks5_proxy configuration. "+
+			"The agent keeps collecting in the meantime.", "location", location, "error", err)
+		UsageMetricsLogger.Error(agentstatus.WorkloadManagerConnectionError)
+	}
+	return err
+}
+
+// outboundDialerKey is the context key of the outbound dialer of a collection cycle.
+type outboundDialerKey struct{}
+
+// outboundDialer is the outbound dialer of a collection cycle, which is nil if no outbound
+// connection setting is configured.
+type outboundDialer struct {
+	dialer internal.ContextDialer
+}
+
+// WithOutboundDialer returns ctx with the outbound dialer of the configuration, which
+// OutboundDialer returns for ctx. Call it at the start of each collection cycle, so that the
+// password of the SOCKS5 proxy is read from the secret provider once per cycle.
+func WithOutboundDialer(ctx context.Context, cfg *configpb.Configuration) (context.Context, error) {
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, outboundDialerKey{}, outboundDialer{dialer: dialer}), nil
+}
+
+// OutboundDialer returns the dialer of the outbound connections from the configuration. The
+// connections are bound to the outbound source address and made through the SOCKS5 proxy if they
+// are configured. The dialer is nil if neither is configured. The dialer of ctx set by
+// WithOutboundDialer is returned if any.
+func OutboundDialer(ctx context.Context, cfg *configpb.Configuration) (internal.ContextDialer, error) {
+	if d, ok := ctx.Value(outboundDialerKey{}).(outboundDialer); ok {
+		return d.dialer, nil
+	}
+	var dialer *net.Dialer
+	if cfg.GetOutboundSourceAddress() != "" {
+		var err error
+		if dialer, err = internal.SourceDialer(cfg.GetOutboundSourceAddress()); err != nil {
+			return nil, err
+		}
+	}
+	socks := cfg.GetSocks5Proxy()
+	if socks.GetAddress() == "" {
+		if dialer == nil {
+			return nil, nil
+		}
+		return dialer, nil
+	}
+	password := ""
+	if name := socks.GetPasswordSecretName(); name != "" {
+		sip, err := SourceInstanceProperties()
+		if err != nil {
+			return nil, err
+		}
+		// The password cannot be read through the proxy it authenticates to.
+		var direct internal.ContextDialer
+		if dialer != nil {
+			direct = dialer
+		}
+		if password, err = secretValue(ctx, cfg, sip.ProjectID, name, direct); err != nil {
+			return nil, fmt.Errorf("failed to get the password of the SOCKS5 proxy: %v", err)
+		}
+	}
+	return internal.SOCKS5Dialer(socks.GetAddress(), socks.GetUserName(), password, dialer)
+}
+
+// WLMLocation returns the workload manager location name collected data is sent to.
+// The region of the instance is used unless a location is set in the configuration.
+func WLMLocation(cfg *configpb.Configuration, ip InstanceProperties) string {
+	if cfg.GetWlmLocation() == "" {
+		return ip.Name
+	}
+	return fmt.Sprintf("projects/%s/locations/%s", ip.ProjectID, cfg.GetWlmLocation())
+}
+
 // CheckAgentStatus checks agent status. Return error if it failed to activate.
-func CheckAgentStatus(wlm wlm.WorkloadManagerService, path string) error {
-	ip := SourceInstanceProperties()
-	return agentshared.CheckAgentStatus(activation.NewV1(), wlm, filepath.Join(filepath.Dir(path), "google-cloud-sql-server-agent.activated"), ip.Name, ip.ProjectID, ip.Instance, ip.InstanceID)
+func CheckAgentStatus(wlm wlm.WorkloadManagerService, path string, cfg *configpb.Configuration) error {
+	ip, err := SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
+	return agentshared.CheckAgentStatus(activation.NewV1(), wlm, filepath.Join(filepath.Dir(path), "google-cloud-sql-server-agent.activated"), WLMLocation(cfg, ip), ip.ProjectID, ip.Instance, ip.InstanceID)
 }
 
 // LoadConfiguration loads configuration from given path.
@@ -156,30 +591,304 @@ func ValidateCredCfgGuest(remote, windows bool, guestCfg *configuration.GuestCon
 	return configuration.ValidateCredCfgGuest(remote, windows, guestCfg, instanceID, instanceName)
 }
 
+// RuleSettings returns the thresholds, time windows, policies and ignore lists the sql rules are
+// collected with from the configuration.
+func RuleSettings(cfg *configpb.Configuration) internal.RuleSettings {
+	collectionCfg := cfg.GetCollectionConfiguration()
+	settings := internal.DefaultRuleSettings()
+	settings.VLFCountThreshold = int64(collectionCfg.GetVlfCountThreshold())
+	settings.ExpectTDEEncryption = collectionCfg.GetExpectTdeEncryption()
+	settings.IncrementalCollection = collectionCfg.GetIncrementalCollection()
+	settings.ReportCollectionManifest = collectionCfg.GetReportCollectionManifest()
+	settings.DiskFreeSpaceThresholdPercent = int64(collectionCfg.GetDiskFreeSpaceThresholdPercent())
+	settings.ClockSkewThreshold = time.Duration(collectionCfg.GetClockSkewThresholdSeconds()) * time.Second
+	settings.LockTimeout = time.Duration(collectionCfg.GetLockTimeoutMilliseconds()) * time.Millisecond
+	settings.RuleCacheTTL = time.Duration(collectionCfg.GetCachedRulesTtlInSeconds()) * time.Second
+	settings.TopQueries = int64(collectionCfg.GetTopQueries())
+	if metric := collectionCfg.GetTopQueriesMetric(); metric != "" {
+		settings.TopQueriesMetric = metric
+	}
+	settings.CollectIndexFillFactors = collectionCfg.GetCollectIndexFillFactors()
+	if minPages := collectionCfg.GetIndexFillFactorMinPages(); minPages != 0 {
+		settings.IndexFillFactorMinPages = int64(minPages)
+	}
+	if maxAge := collectionCfg.GetCheckdbMaxAgeInDays(); maxAge != 0 {
+		settings.CheckDBMaxAge = time.Duration(maxAge) * 24 * time.Hour
+	}
+	settings.DeadlockWindow = time.Duration(collectionCfg.GetSqlMetricsCollectionIntervalInSeconds()) * time.Second
+	settings.FailedLoginWindow = settings.DeadlockWindow
+	settings.SetIgnoreLists(cfg.GetIgnore().GetWaitTypes(), cfg.GetIgnore().GetErrorNumbers())
+	return settings
+}
+
+// SQLDialer returns the dialer for the connections to SQL Server along with a func closing it.
+// The connections are tunneled through the bastion host if one is set in the sql configuration.
+// The connections are made through the local IAM proxy, authenticated by the access tokens of its
+// IAM identity, if one is set in the sql configuration.
+// The returned dialer is nil if neither a bastion, an IAM proxy nor an outbound dialer is configured.
+func SQLDialer(ctx context.Context, sqlCfg *configuration.SQLConfig, dialer internal.ContextDialer) (sqlcollector.Dialer, func(), error) {
+	if sqlCfg.ProxyEndpoint != "" {
+		d, err := iamproxy.NewDialer(ctx, sqlCfg.ProxyEndpoint, sqlCfg.ProxyIAMIdentity)
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, func() {}, nil
+	}
+	if sqlCfg.BastionHost == "" {
+		if dialer == nil {
+			return nil, func() {}, nil
+		}
+		return dialer, func() {}, nil
+	}
+	port := sqlCfg.BastionPortNumber
+	if port == 0 {
+		port = 22
+	}
+	d, err := remote.NewTunnelDialer(ctx, sqlCfg.BastionHost, sqlCfg.BastionUserName, port, sqlCfg.BastionPrivateKeyPath, dialer, UsageMetricsLogger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, func() { d.Close() }, nil
+}
+
+// sqlPool keeps the connections to SQL Server open across the cycles of the collection service.
+var sqlPool = sqlcollector.NewPool(clockwork.NewRealClock(), 0)
+
+// SQLPool returns the pool reusing the connections to the instance across cycles, or nil if the
+// connections of the instance are not reused: reuse_sql_connections is disabled, the collection
+// is one-time or the instance is connected through a bastion host whose tunnel is closed after
+// each cycle.
+func SQLPool(cfg *configpb.Configuration, sqlCfg *configuration.SQLConfig, onetime bool) *sqlcollector.Pool {
+	if onetime || !cfg.GetCollectionConfiguration().GetReuseSqlConnections() || sqlCfg.BastionHost != "" {
+		return nil
+	}
+	idleTimeout := time.Duration(cfg.GetCollectionConfiguration().GetSqlConnectionIdleTimeoutInSeconds()) * time.Second
+	if idleTimeout == 0 {
+		idleTimeout = 2 * collectionInterval(cfg, SQL)
+	}
+	sqlPool.SetIdleTimeout(idleTimeout)
+	return sqlPool
+}
+
+// SQLCollectionOptions are the options of a sql collection run by RunSQLCollection.
+type SQLCollectionOptions struct {
+	// Timeout is the timeout of each query of the collection.
+	Timeout time.Duration
+	// Windows reports whether the sql server runs on windows.
+	Windows bool
+	// Dialer is used for the connections to SQL Server. The default one is used if it is nil.
+	Dialer sqlcollector.Dialer
+	// DatabaseInclude restricts the rules reading databases to the matching databases, if any.
+	DatabaseInclude []string
+	// DatabaseExclude leaves the matching databases out of the rules reading databases unless
+	// DatabaseInclude is set.
+	DatabaseExclude []string
+	// WorkloadType, if set, skips the rules that do not apply to it and is added to the fields of
+	// the details.
+	WorkloadType string
+	// Settings are the thresholds and options the master rules are collected with.
+	Settings internal.RuleSettings
+	// Pool, if not nil, keeps the connections open across collections. The collection fails if the
+	// sql server cannot be reached, which counts towards closing the pooled connections.
+	Pool *sqlcollector.Pool
+}
+
 // RunSQLCollection starts running sql collection based on given connection string.
-func RunSQLCollection(ctx context.Context, conn string, timeout time.Duration, windows bool) ([]internal.Details, error) {
-	c, err := sqlcollector.NewV1(driver, conn, windows, UsageMetricsLogger)
+func RunSQLCollection(ctx context.Context, conn string, opts SQLCollectionOptions) ([]internal.Details, error) {
+	open := func() (*sqlcollector.V1, error) {
+		return sqlcollector.NewV1(driver, conn, opts.Windows, UsageMetricsLogger, opts.Dialer)
+	}
+	collect := func(c *sqlcollector.V1) []internal.Details {
+		c.SetDatabaseFilter(opts.DatabaseInclude, opts.DatabaseExclude)
+		c.SetWorkloadType(opts.WorkloadType)
+		c.SetRuleSettings(opts.Settings)
+		details := agentshared.RunSQLCollection(ctx, c, opts.Timeout)
+		if opts.WorkloadType != "" {
+			details = internal.LabelWorkload(details, opts.WorkloadType)
+		}
+		return details
+	}
+	pool := opts.Pool
+	if pool == nil {
+		c, err := open()
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+		return collect(c), nil
+	}
+	c, err := pool.Get(conn, open)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.Ping(ctx); err != nil {
+		pool.Release(conn, err)
+		return nil, err
+	}
+	pool.Release(conn, nil)
+	return collect(c), nil
+}
+
+// RunRule runs the master or guest rule with the given name and returns its formatted result.
+// Master rules run against the first sql configuration of the first credential.
+// The guest collector is only created when a guest rule is requested.
+func RunRule(ctx context.Context, cfg *configpb.Configuration, name string, windows bool, guestCollector func() (guestcollector.GuestCollector, error)) (string, error) {
+	timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
+	if agentshared.IsGuestRule(name) {
+		c, err := guestCollector()
+		if err != nil {
+			return "", err
+		}
+		details, err := agentshared.RunGuestRule(ctx, c, name, timeout)
+		if err != nil {
+			return "", err
+		}
+		return agentshared.FormatRuleResult(nil, details)
+	}
+	if !agentshared.IsMasterRule(name) {
+		return "", agentshared.UnknownRuleError(name)
+	}
+
+	if len(cfg.GetCredentialConfiguration()) == 0 {
+		return "", fmt.Errorf("empty credentials")
+	}
+	sqlCfgs := SQLConfigFromCredential(cfg.GetCredentialConfiguration()[0])
+	if len(sqlCfgs) == 0 {
+		return "", fmt.Errorf("empty sql configurations")
+	}
+	sqlCfg := sqlCfgs[0]
+	sip, err := SourceInstanceProperties()
+	if err != nil {
+		return "", err
+	}
+	pswds, err := SQLPasswords(ctx, cfg, sip.ProjectID, sqlCfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret value: %v", err)
+	}
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	sqlDialer, closeDialer, err := SQLDialer(ctx, sqlCfg, dialer)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the sql server dialer: %v", err)
+	}
+	defer closeDialer()
+	conn, err := AuthenticatedConnectionString(ctx, cfg, sqlCfg, pswds, windows, sqlDialer)
+	if err != nil {
+		return "", err
+	}
+	c, err := sqlcollector.NewV1(driver, conn, windows, UsageMetricsLogger, sqlDialer)
+	if err != nil {
+		return "", err
+	}
 	defer c.Close()
-	return agentshared.RunSQLCollection(ctx, c, timeout), nil
+	c.SetRuleSettings(RuleSettings(cfg))
+	raw, details, err := c.RunRule(ctx, name, timeout)
+	if err != nil {
+		return "", err
+	}
+	return agentshared.FormatRuleResult(raw, details)
 }
 
-// RunOSCollection starts running os collection.
-func RunOSCollection(ctx context.Context, c guestcollector.GuestCollector, timeout time.Duration) []internal.Details {
-	return agentshared.RunOSCollection(ctx, c, timeout)
+// NewLinuxCollector returns the collector of the guest rules of a linux machine, with the optional
+// rules enabled by cfg. The remote machine is reached at ipAddr and port over ssh if isRemote.
+func NewLinuxCollector(cfg *configpb.Configuration, disks []*instanceinfo.Disks, ipAddr, username, privateKeyPath string, isRemote bool, port int32) *guestcollector.LinuxCollector {
+	c := guestcollector.NewLinuxCollector(disks, ipAddr, username, privateKeyPath, isRemote, port, UsageMetricsLogger)
+	if cfg.GetCollectionConfiguration().GetSampleHostUtilization() {
+		c.EnableHostUtilization()
+	}
+	return c
+}
+
+// RunOSCollection starts running os collection. The collection manifest of the guest rules is
+// reported along with the details if report_collection_manifest is enabled.
+func RunOSCollection(ctx context.Context, c guestcollector.GuestCollector, timeout time.Duration, cfg *configpb.Configuration) []internal.Details {
+	return agentshared.RunOSCollection(ctx, c, timeout, cfg.GetCollectionConfiguration().GetReportCollectionManifest())
+}
+
+// AddPowerPlanRecommended adds to details whether the power profile of the machine is one of the
+// configured recommended power plans.
+func AddPowerPlanRecommended(details []internal.Details, cfg *configpb.Configuration) {
+	internal.AddPowerPlanRecommended(details, cfg.GetCollectionConfiguration().GetRecommendedPowerPlans())
+}
+
+// AddAgentResourceUsage adds the resource usage of the agent process to details if enabled in the
+// configuration.
+func AddAgentResourceUsage(details []internal.Details, cfg *configpb.Configuration) []internal.Details {
+	if !cfg.GetCollectionConfiguration().GetReportAgentResourceUsage() {
+		return details
+	}
+	usage, err := agentusage.Collect(sqlcollector.OpenConnections())
+	if err != nil {
+		log.Logger.Warnw("Failed to collect the resource usage of the agent", "error", err)
+		return details
+	}
+	return append(details, usage.Details())
+}
+
+// SyntheticCollection sends synthetic details of the given number of fake instances through the
+// export path of the guest and sql collections. The details are generated from the seed so that
+// the same seed always exports the same data.
+func SyntheticCollection(ctx context.Context, cfg *configpb.Configuration, instances int, seed int64) error {
+	ctx, err := WithOutboundDialer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	wlmService, err := InitCollection(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	g := synthetic.New(seed)
+	sourceInstanceProps, err := SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
+	for _, inst := range g.Instances(instances) {
+		targetInstanceProps := sourceInstanceProps
+		targetInstanceProps.Instance = inst.Name
+		targetInstanceProps.InstanceID = inst.InstanceID
+		collections := []struct {
+			collectionType CollectionType
+			details        []internal.Details
+		}{
+			{collectionType: OS, details: []internal.Details{g.OSDetails(inst)}},
+			{collectionType: SQL, details: g.SQLDetails(inst)},
+		}
+		log.Logger.Infow("Sending synthetic collected data", "instance", inst.Name)
+		for _, c := range collections {
+			UpdateCollectedData(cfg, wlmService, sourceInstanceProps, targetInstanceProps, c.details)
+			SendRequestToWLM(wlmService, NewWLMSendOptions(cfg, WLMLocation(cfg, sourceInstanceProps)))
+			PublishCollectedData(ctx, cfg, wlmService, c.collectionType, sourceInstanceProps, targetInstanceProps)
+			PostCollectedData(ctx, cfg, wlmService, c.collectionType, sourceInstanceProps, targetInstanceProps)
+			WriteCollectedData(cfg, wlmService, c.collectionType, targetInstanceProps)
+		}
+	}
+	return nil
+}
+
+// SecretValue gets secret value from the secret provider set in the configuration.
+// Secret Manager is used if no secret provider is configured.
+// Transient errors of the secret provider are retried with the retry settings of the secret
+// provider configuration.
+func SecretValue(ctx context.Context, cfg *configpb.Configuration, projectID string, secretName string) (string, error) {
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	return secretValue(ctx, cfg, projectID, secretName, dialer)
 }
 
-// SecretValue gets secret value from Secret Manager.
-func SecretValue(ctx context.Context, projectID string, secretName string) (string, error) {
+// secretValue gets the secret value from the secret provider set in the configuration, connecting
+// with dialer.
+func secretValue(ctx context.Context, cfg *configpb.Configuration, projectID, secretName string, dialer internal.ContextDialer) (string, error) {
 	log.Logger.Debug("Getting secret.")
-	smClient, err := secretmanager.NewClient(ctx)
+	provider, err := NewSecretProvider(ctx, cfg.GetSecretProvider(), projectID, dialer)
 	if err != nil {
 		return "", err
 	}
-	defer smClient.Close()
-	pswd, err := smClient.GetSecretValue(ctx, projectID, secretName)
+	defer provider.Close()
+	pswd, err := secretmanager.GetSecretWithRetry(ctx, provider, secretName, SecretRetryConfig(cfg.GetSecretProvider()))
 	if err != nil {
 		return "", err
 	}
@@ -187,6 +896,146 @@ func SecretValue(ctx context.Context, projectID string, secretName string) (stri
 	return pswd, nil
 }
 
+// SecretRetryConfig returns the retry settings of the secret provider configuration.
+// Unset settings use the defaults.
+func SecretRetryConfig(cfg *configpb.SecretProviderConfiguration) secretmanager.RetryConfig {
+	retryCfg := secretmanager.RetryConfig{
+		MaxRetries:      3,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+	}
+	if cfg.GetMaxRetries() > 0 {
+		retryCfg.MaxRetries = int(cfg.GetMaxRetries())
+	}
+	if cfg.GetInitialRetryIntervalInMilliseconds() > 0 {
+		retryCfg.InitialInterval = time.Duration(cfg.GetInitialRetryIntervalInMilliseconds()) * time.Millisecond
+	}
+	return retryCfg
+}
+
+// SecretValues gets the given versions of the secret from the secret provider set in the
+// configuration, in the order of the versions. Versions that cannot be read are skipped.
+// Only the latest version is read if no versions are given.
+func SecretValues(ctx context.Context, cfg *configpb.Configuration, projectID string, secretName string, versions []string) ([]string, error) {
+	log.Logger.Debug("Getting secret versions.")
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := NewSecretProvider(ctx, cfg.GetSecretProvider(), projectID, dialer)
+	if err != nil {
+		return nil, err
+	}
+	defer provider.Close()
+	pswds, err := secretmanager.GetSecretVersionsWithRetry(ctx, provider, secretName, versions, SecretRetryConfig(cfg.GetSecretProvider()))
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Debug("Getting secret versions completes.")
+	return pswds, nil
+}
+
+// SQLPasswords returns the passwords of the secret versions of the sql configuration from the
+// secret provider, in the order of the versions. The only password is empty if the connections
+// are made through an IAM proxy.
+func SQLPasswords(ctx context.Context, cfg *configpb.Configuration, projectID string, sqlCfg *configuration.SQLConfig) ([]string, error) {
+	if sqlCfg.ProxyEndpoint != "" {
+		return []string{""}, nil
+	}
+	if len(sqlCfg.SecretVersions) == 0 {
+		pswd, err := SecretValue(ctx, cfg, projectID, sqlCfg.SecretName)
+		if err != nil {
+			return nil, err
+		}
+		return []string{pswd}, nil
+	}
+	return SecretValues(ctx, cfg, projectID, sqlCfg.SecretName, sqlCfg.SecretVersions)
+}
+
+// maxLoginBackoff bounds the time the passwords rejected by a sql instance are not tried again.
+const maxLoginBackoff = 24 * time.Hour
+
+// loginFailure records that a sql instance rejected all the passwords of its secret versions.
+type loginFailure struct {
+	// passwords is the hash of the rejected passwords. Other passwords are tried right away.
+	passwords [sha256.Size]byte
+	failures  int
+	retryAt   time.Time
+}
+
+// loginFailures holds the sql instances that rejected all their passwords, so that the same
+// passwords are not tried every cycle, which could lock the login out under CHECK_POLICY.
+var loginFailures = map[string]loginFailure{}
+
+// AuthenticatedConnectionString returns the connection string of the sql configuration with the
+// first of the passwords that SQL Server accepts, so that the collection keeps working while a
+// rotated password propagates. A single password is used without logging in first. Errors other
+// than login failures are left to the collection.
+// When all passwords are rejected, the same passwords are not tried again for a collection
+// interval, doubling with each further rejection up to a day.
+func AuthenticatedConnectionString(ctx context.Context, cfg *configpb.Configuration, sqlCfg *configuration.SQLConfig, pswds []string, windows bool, dialer sqlcollector.Dialer) (string, error) {
+	if len(pswds) == 1 {
+		return SQLConnectionString(cfg, sqlCfg, pswds[0]), nil
+	}
+	key := sqlInstanceKey(sqlCfg)
+	sum := sha256.Sum256([]byte(strings.Join(pswds, "\x00")))
+	f, ok := loginFailures[key]
+	if ok && f.passwords == sum && time.Now().Before(f.retryAt) {
+		return "", fmt.Errorf("SQL Server rejected the passwords of all secret versions of %q %d times in a row; not logging in again before %s", sqlCfg.SecretName, f.failures, f.retryAt.Format(time.RFC3339))
+	}
+	var err error
+	for i, pswd := range pswds {
+		conn := SQLConnectionString(cfg, sqlCfg, pswd)
+		if err = pingSQL(ctx, conn, windows, dialer); !sqlcollector.LoginFailed(err) {
+			if i > 0 {
+				log.Logger.Warnw("SQL Server rejected the passwords of the preceding secret versions, using a fallback version", "host", sqlCfg.Host, "secret", sqlCfg.SecretName, "fallback", i)
+			}
+			if err == nil {
+				delete(loginFailures, key)
+			}
+			return conn, nil
+		}
+	}
+	if f.passwords != sum {
+		f = loginFailure{passwords: sum}
+	}
+	f.failures++
+	f.retryAt = time.Now().Add(min(collectionInterval(cfg, SQL)<<min(f.failures-1, 16), maxLoginBackoff))
+	loginFailures[key] = f
+	return "", fmt.Errorf("SQL Server rejected the passwords of all secret versions of %q: %w", sqlCfg.SecretName, err)
+}
+
+// pingSQL logs in to the sql server of the connection string.
+func pingSQL(ctx context.Context, conn string, windows bool, dialer sqlcollector.Dialer) error {
+	c, err := sqlcollector.NewV1(driver, conn, windows, UsageMetricsLogger, dialer)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Ping(ctx)
+}
+
+// NewSecretProvider returns the secret provider based on the given configuration. If dialer is
+// not nil, the provider connects with it.
+func NewSecretProvider(ctx context.Context, cfg *configpb.SecretProviderConfiguration, projectID string, dialer internal.ContextDialer) (secretmanager.SecretProvider, error) {
+	switch cfg.GetType() {
+	case "", secretmanager.GCPSecretManager:
+		return secretmanager.NewGCPProvider(ctx, projectID, dialer)
+	case secretmanager.Vault:
+		return secretmanager.NewVaultProvider(ctx, secretmanager.VaultConfig{
+			Address:          cfg.GetVault().GetAddress(),
+			MountPath:        cfg.GetVault().GetMountPath(),
+			TokenFilePath:    cfg.GetVault().GetTokenFilePath(),
+			RoleID:           cfg.GetVault().GetRoleId(),
+			SecretIDFilePath: cfg.GetVault().GetSecretIdFilePath(),
+			Namespace:        cfg.GetVault().GetNamespace(),
+			Dialer:           dialer,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported secret provider type %q", cfg.GetType())
+	}
+}
+
 // AllDisks attempts to call compute api to return all possible disks.
 func AllDisks(ctx context.Context, ip InstanceProperties) ([]*instanceinfo.Disks, error) {
 	tempGCE, err := gce.NewGCEClient(ctx)
@@ -200,7 +1049,14 @@ func AllDisks(ctx context.Context, ip InstanceProperties) ([]*instanceinfo.Disks
 
 // UpdateCollectedData constructs writeinsightrequest from given collected details.
 // The func will be called by both guest and sql collections.
-func UpdateCollectedData(wlmService wlm.WorkloadManagerService, sourceProps, targetProps InstanceProperties, details []internal.Details) {
+// Signals derived from several details of the instance are added to the request.
+// The fields are renamed by the field renames of cfg before they are added.
+func UpdateCollectedData(cfg *configpb.Configuration, wlmService wlm.WorkloadManagerService, sourceProps, targetProps InstanceProperties, details []internal.Details) {
+	if memory, ok := internal.MemoryAllocation(details); ok {
+		// Limit the capacity so the details of the caller are not modified.
+		details = append(details[:len(details):len(details)], memory)
+	}
+	details = internal.RenameFields(details, configuration.FieldRenames(cfg.GetFieldRenames()))
 	sqlservervalidation := wlm.InitializeSQLServerValidation(sourceProps.ProjectID, targetProps.Instance)
 	sqlservervalidation = wlm.UpdateValidationDetails(sqlservervalidation, details)
 	writeInsightRequest := wlm.InitializeWriteInsightRequest(sqlservervalidation, targetProps.InstanceID)
@@ -209,35 +1065,459 @@ func UpdateCollectedData(wlmService wlm.WorkloadManagerService, sourceProps, tar
 	wlmService.UpdateRequest(writeInsightRequest)
 }
 
+// WLMSendOptions are the options requests are sent to workload manager with by SendRequestToWLM.
+type WLMSendOptions struct {
+	// Location is the workload manager location the requests are sent to.
+	Location string
+	// Retries is the number of retries of each request, Interval the time between them.
+	Retries  int32
+	Interval time.Duration
+	// MaxPayloadBytes splits larger requests into parts sent one by one. Zero does not split.
+	MaxPayloadBytes int32
+	// DeadLetter configures the directory the requests failing after all retries are written to.
+	DeadLetter *configpb.DeadLetterConfiguration
+}
+
+// NewWLMSendOptions returns the options of the configuration to send requests to the workload
+// manager location with.
+func NewWLMSendOptions(cfg *configpb.Configuration, location string) WLMSendOptions {
+	return WLMSendOptions{
+		Location:        location,
+		Retries:         cfg.GetMaxRetries(),
+		Interval:        time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second,
+		MaxPayloadBytes: cfg.GetMaxWlmPayloadBytes(),
+		DeadLetter:      cfg.GetDeadLetter(),
+	}
+}
+
 // SendRequestToWLM sends request to workloadmanager.
-func SendRequestToWLM(wlmService wlm.WorkloadManagerService, location string, retries int32, interval time.Duration) {
-	sendRequest := func() bool {
-		_, err := wlmService.SendRequest(location)
-		if err != nil {
-			log.Logger.Errorw("Failed to send request to workload manager", "error", err)
+// Requests larger than opts.MaxPayloadBytes are split into parts sent and retried one by one, so
+// that a failed part does not lose the collected data of the other parts. Parts failing after all
+// retries are written to the dead-letter directory, if configured. If replay is enabled, the
+// requests of the directory are replayed first, so that workload manager does not receive older
+// data after newer data. The request of wlmService is left unchanged.
+func SendRequestToWLM(wlmService wlm.WorkloadManagerService, opts WLMSendOptions) {
+	request := wlmService.WriteInsightRequest()
+	parts, err := wlm.SplitRequest(request, int(opts.MaxPayloadBytes))
+	if err != nil {
+		log.Logger.Errorw("Failed to split request to workload manager. Sending it as a whole", "error", err)
+		parts = []*workloadmanager.WriteInsightRequest{request}
+	}
+	if len(parts) > 1 {
+		log.Logger.Infow("Splitting request to workload manager", "parts", len(parts), "max_payload_bytes", opts.MaxPayloadBytes)
+	}
+	defer wlmService.UpdateRequest(request)
+	deadLetter := opts.DeadLetter
+	queue := deadLetterQueue(deadLetter)
+	if queue != nil && deadLetter.GetReplay() {
+		replayDeadLetters(wlmService, queue, deadLetter.GetDirectory())
+	}
+	for i, part := range parts {
+		wlmService.UpdateRequest(part)
+		sendRequest := func() bool {
+			_, err := wlmService.SendRequest(opts.Location)
+			if err != nil {
+				log.Logger.Errorw("Failed to send request to workload manager", "part", i+1, "parts", len(parts), "error", err)
+				UsageMetricsLogger.Error(agentstatus.WorkloadManagerConnectionError)
+				return false
+			}
+			return true
+		}
+
+		if err := Retry(sendRequest, opts.Retries, opts.Interval); err != nil {
+			log.Logger.Errorw("Failed to retry sending request to workload manager", "part", i+1, "parts", len(parts), "error", err)
 			UsageMetricsLogger.Error(agentstatus.WorkloadManagerConnectionError)
+			if queue == nil {
+				continue
+			}
+			if err := queue.Write(opts.Location, part, time.Now()); err != nil {
+				log.Logger.Errorw("Failed to write request to the dead-letter directory", "directory", deadLetter.GetDirectory(), "error", err)
+				UsageMetricsLogger.Error(agentstatus.DeadLetterError)
+				continue
+			}
+			log.Logger.Infow("Wrote request to the dead-letter directory", "directory", deadLetter.GetDirectory(), "part", i+1, "parts", len(parts))
+		}
+	}
+}
+
+// deadLetterQueue returns the dead-letter directory of the configuration, or nil if no directory
+// is configured. The maximum size defaults to 100 MiB.
+func deadLetterQueue(cfg *configpb.DeadLetterConfiguration) *deadletter.Queue {
+	if cfg.GetDirectory() == "" {
+		return nil
+	}
+	maxSizeMB := int64(100)
+	if cfg.GetMaxSizeMb() > 0 {
+		maxSizeMB = int64(cfg.GetMaxSizeMb())
+	}
+	return deadletter.New(cfg.GetDirectory(), maxSizeMB<<20)
+}
+
+// replayDeadLetters sends the requests of the dead-letter directory once each, stopping at the
+// first request that fails. The requests not sent are kept for the next replay.
+func replayDeadLetters(wlmService wlm.WorkloadManagerService, queue *deadletter.Queue, dir string) {
+	sent, err := queue.Replay(func(e deadletter.Entry) error {
+		wlmService.UpdateRequest(e.Request)
+		_, err := wlmService.SendRequest(e.Location)
+		return err
+	})
+	if sent > 0 {
+		log.Logger.Infow("Replayed requests of the dead-letter directory", "directory", dir, "requests", sent)
+	}
+	if err != nil {
+		log.Logger.Warnw("Failed to replay the requests of the dead-letter directory. They are replayed before the next request", "directory", dir, "error", err)
+		UsageMetricsLogger.Error(agentstatus.DeadLetterError)
+	}
+}
+
+// PublishCollectedData publishes the collected data of the target instance to the Pub/Sub topic
+// from the configuration. Nothing is published if no topic is configured.
+// Publishing is retried with the retry settings of the configuration.
+func PublishCollectedData(ctx context.Context, cfg *configpb.Configuration, wlmService *wlm.WLM, collectionType CollectionType, sourceProps, targetProps InstanceProperties) {
+	if cfg.GetPubsub().GetTopic() == "" {
+		return
+	}
+	topic, err := pubsub.TopicName(cfg.GetPubsub().GetTopic(), sourceProps.ProjectID)
+	if err != nil {
+		log.Logger.Errorw("Failed to publish collected data to Pub/Sub", "error", err)
+		UsageMetricsLogger.Error(agentstatus.PubSubPublishError)
+		return
+	}
+	data, err := json.Marshal(wlmService.Request)
+	if err != nil {
+		log.Logger.Errorw("Failed to marshal collected data", "error", err)
+		UsageMetricsLogger.Error(agentstatus.InvalidJSONFormatError)
+		return
+	}
+	publisher, err := pubsubClient(ctx, topic, cfg.GetPubsub().GetImpersonateServiceAccount())
+	if err != nil {
+		log.Logger.Errorw("Failed to create Pub/Sub client", "error", err)
+		UsageMetricsLogger.Error(agentstatus.PubSubPublishError)
+		return
+	}
+	attributes := map[string]string{
+		"collection_type": "guest",
+		"instance":        targetProps.Instance,
+		"instance_id":     targetProps.InstanceID,
+		"agent_version":   internal.AgentVersion,
+	}
+	if collectionType == SQL {
+		attributes["collection_type"] = "sql"
+	}
+	orderingKey := ""
+	if cfg.GetPubsub().GetEnableMessageOrdering() {
+		orderingKey = targetProps.Instance
+	}
+	deliverWithRetry(cfg, "Pub/Sub topic "+topic, agentstatus.PubSubPublishError, func() error {
+		return publisher.Publish(ctx, data, attributes, orderingKey)
+	})
+}
+
+// pubsubPublisher is the Pub/Sub client kept for the topic and the impersonated service account
+// it was created for. It is recreated when the pubsub configuration changes.
+var pubsubPublisher struct {
+	mu             sync.Mutex
+	topic          string
+	serviceAccount string
+	client         *pubsub.Client
+}
+
+// pubsubClient returns the Pub/Sub client of the topic and the impersonated service account,
+// creating it if the pubsub configuration changed. The client connects through the outbound
+// dialer of the collection cycle of each request.
+func pubsubClient(ctx context.Context, topic, serviceAccount string) (*pubsub.Client, error) {
+	pubsubPublisher.mu.Lock()
+	defer pubsubPublisher.mu.Unlock()
+	if pubsubPublisher.client != nil && pubsubPublisher.topic == topic && pubsubPublisher.serviceAccount == serviceAccount {
+		return pubsubPublisher.client, nil
+	}
+	// The client outlives the cycle, so its credentials must not be bound to the cycle.
+	client, err := pubsub.NewClient(context.WithoutCancel(ctx), topic, serviceAccount, cycleDialer{})
+	if err != nil {
+		return nil, err
+	}
+	pubsubPublisher.topic, pubsubPublisher.serviceAccount, pubsubPublisher.client = topic, serviceAccount, client
+	return client, nil
+}
+
+// cycleDialer dials through the outbound dialer of the collection cycle of the context of each
+// connection, so that clients kept across cycles follow the outbound settings of the current
+// cycle. Connections are made directly if the context has no outbound dialer.
+type cycleDialer struct{}
+
+// DialContext connects to the address with the outbound dialer of ctx.
+func (cycleDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d, ok := ctx.Value(outboundDialerKey{}).(outboundDialer); ok && d.dialer != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+// deliverWithRetry runs deliver with the retry settings of the configuration. Each failed
+// delivery of the collected data to the destination is logged and reported with errorCode.
+func deliverWithRetry(cfg *configpb.Configuration, destination string, errorCode int, deliver func() error) {
+	run := func() bool {
+		if err := deliver(); err != nil {
+			log.Logger.Errorw("Failed to deliver collected data", "destination", destination, "error", err)
+			UsageMetricsLogger.Error(errorCode)
 			return false
 		}
 		return true
 	}
+	interval := time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second
+	if err := Retry(run, cfg.GetMaxRetries(), interval); err != nil {
+		log.Logger.Errorw("Failed to retry delivering collected data", "destination", destination, "error", err)
+		UsageMetricsLogger.Error(errorCode)
+	}
+}
 
-	if err := Retry(sendRequest, retries, interval); err != nil {
-		log.Logger.Errorw("Failed to retry sending request to workload manager", "error", err)
-		UsageMetricsLogger.Error(agentstatus.WorkloadManagerConnectionError)
+// PostCollectedData posts a summary of the collected data of the target instance to the webhook
+// from the configuration. Nothing is posted if no webhook is configured, or if conditions are
+// configured and none of them matches the collected data.
+// Posting is retried with the retry settings of the configuration.
+func PostCollectedData(ctx context.Context, cfg *configpb.Configuration, wlmService *wlm.WLM, collectionType CollectionType, sourceProps, targetProps InstanceProperties) {
+	webhookCfg := cfg.GetWebhook()
+	if webhookCfg.GetUrl() == "" {
+		return
+	}
+	var details []internal.Details
+	if wlmService.Request != nil && wlmService.Request.Insight != nil {
+		details = wlm.ValidationDetailsToDetails(wlmService.Request.Insight.SqlserverValidation)
+	}
+	var conditions []webhook.Condition
+	for _, c := range webhookCfg.GetConditions() {
+		conditions = append(conditions, configuration.WebhookCondition(c))
+	}
+	matched := webhook.Matched(details, conditions)
+	if len(conditions) > 0 && len(matched) == 0 {
+		log.Logger.Debugw("No webhook condition matches the collected data", "instance", targetProps.Instance)
+		return
+	}
+	token := ""
+	if name := webhookCfg.GetBearerTokenSecretName(); name != "" {
+		var err error
+		if token, err = SecretValue(ctx, cfg, sourceProps.ProjectID, name); err != nil {
+			log.Logger.Errorw("Failed to get the bearer token of the webhook", "secret", name, "error", err)
+			UsageMetricsLogger.Error(agentstatus.WebhookError)
+			return
+		}
+	}
+	dialer, err := OutboundDialer(ctx, cfg)
+	if err != nil {
+		log.Logger.Errorw("Failed to post collected data to the webhook", "error", err)
+		UsageMetricsLogger.Error(agentstatus.WebhookError)
+		return
 	}
+	ct := "guest"
+	if collectionType == SQL {
+		ct = "sql"
+	}
+	summary := webhook.NewSummary(ct, targetProps.Instance, targetProps.InstanceID, details, matched)
+	client := webhook.NewClient(webhookCfg.GetUrl(), token, dialer)
+	deliverWithRetry(cfg, "webhook", agentstatus.WebhookError, func() error {
+		return client.Post(ctx, summary)
+	})
 }
 
-// PersistCollectedData persists collected data in the file system.
-// The file name follows the format "[target]-[collectionType].json"
-// e.g. "localhost-guest.json"
-// The file is saved in the same location as log file.
-func PersistCollectedData(wlm *wlm.WLM, path string) error {
+// WriteCollectedData writes the numeric collected data of the target instance in the OpenMetrics
+// text format to the textfile collector directory from the configuration. Nothing is written if no
+// directory is configured.
+func WriteCollectedData(cfg *configpb.Configuration, wlmService *wlm.WLM, collectionType CollectionType, targetProps InstanceProperties) {
+	dir := cfg.GetTextfileDirectory()
+	if dir == "" {
+		return
+	}
+	var details []internal.Details
+	if wlmService.Request != nil && wlmService.Request.Insight != nil {
+		details = wlm.ValidationDetailsToDetails(wlmService.Request.Insight.SqlserverValidation)
+	}
+	ct := "guest"
+	if collectionType == SQL {
+		ct = "sql"
+	}
+	labels := map[string]string{"instance": targetProps.Instance, "instance_id": targetProps.InstanceID}
+	if err := openmetrics.WriteFile(dir, openmetrics.FileName(targetProps.Instance, ct), labels, details); err != nil {
+		log.Logger.Errorw("Failed to write collected data to the textfile collector directory", "directory", dir, "error", err)
+		UsageMetricsLogger.Error(agentstatus.TextfileError)
+	}
+}
+
+// RecordCollectedData records the collected data of the target instance in the SQLite database
+// of the configuration, if any, and deletes the data older than the configured maximum age.
+func RecordCollectedData(ctx context.Context, cfg *configpb.Configuration, wlmService *wlm.WLM, targetProps InstanceProperties) {
+	path := cfg.GetSqlite().GetPath()
+	if path == "" {
+		return
+	}
+	var details []internal.Details
+	if wlmService.Request != nil && wlmService.Request.Insight != nil {
+		details = wlm.ValidationDetailsToDetails(wlmService.Request.Insight.SqlserverValidation)
+	}
+	maxAge := time.Duration(cfg.GetSqlite().GetMaxAgeInDays()) * 24 * time.Hour
+	if err := history.Write(ctx, path, targetProps.Instance, details, time.Now(), maxAge); err != nil {
+		log.Logger.Errorw("Failed to record collected data in the SQLite database", "path", path, "error", err)
+	}
+}
+
+// outputPrefix starts the names of the collection files and archives the agent persists, so that
+// the retention policy only deletes files written by the agent.
+const outputPrefix = "google-cloud-sql-server-agent-"
+
+// OutputFile returns the path of the file in dir persisting the data collected from the target.
+// The file name follows the format "google-cloud-sql-server-agent-[target]-[collectionType].json"
+// e.g. "google-cloud-sql-server-agent-localhost-guest.json".
+func OutputFile(dir, target string, collectionType CollectionType) string {
+	ct := "guest"
+	if collectionType == SQL {
+		ct = "sql"
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%s-%s.json", outputPrefix, target, ct))
+}
+
+// SigningKey is the key of the output signing secret of the configuration. The secret is read
+// once, when the first file is signed, so that the files persisted in a cycle do not read it
+// each.
+type SigningKey struct {
+	cfg  *configpb.Configuration
+	once sync.Once
+	key  []byte
+	err  error
+}
+
+// NewSigningKey returns the signing key of the configuration. Create one per cycle so that a
+// rotated secret is used from the next cycle.
+func NewSigningKey(cfg *configpb.Configuration) *SigningKey {
+	return &SigningKey{cfg: cfg}
+}
+
+// Get returns the key, or nil if no signing secret is configured.
+func (k *SigningKey) Get(ctx context.Context) ([]byte, error) {
+	name := k.cfg.GetOutputSigningSecretName()
+	if name == "" {
+		return nil, nil
+	}
+	k.once.Do(func() {
+		sip, err := SourceInstanceProperties()
+		if err != nil {
+			k.err = err
+			return
+		}
+		key, err := SecretValue(ctx, k.cfg, sip.ProjectID, name)
+		if err != nil {
+			k.err = fmt.Errorf("failed to get the signing key: %v", err)
+			return
+		}
+		k.key = []byte(key)
+	})
+	return k.key, k.err
+}
+
+// PersistCollectedData persists collected data in the file system at path, which is returned by
+// OutputFile.
+// If a signing secret is configured, the data written is signed with the key of the secret.
+// After saving, the retention policy from the configuration is applied to the persisted files
+// of the same collection type and their signatures. Files not named by OutputFile are left alone.
+func PersistCollectedData(ctx context.Context, wlm *wlm.WLM, path string, cfg *configpb.Configuration, signingKey *SigningKey) error {
 	log.Logger.Debug("Saving collected result locally.")
 	requestJSON, err := internal.PrettyStruct(wlm.Request)
 	if err != nil {
 		return err
 	}
-	return internal.SaveToFile(path, []byte(requestJSON))
+	if err := internal.SaveToFile(path, []byte(requestJSON)); err != nil {
+		return err
+	}
+	if cfg.GetOutputSigningSecretName() != "" {
+		key, err := signingKey.Get(ctx)
+		if err == nil {
+			err = filesign.SignData(path, []byte(requestJSON), key)
+		}
+		if err != nil {
+			log.Logger.Errorw("Failed to sign the persisted collection file", "path", path, "error", err)
+			return err
+		}
+	}
+	name := filepath.Base(path)
+	i := strings.LastIndex(name, "-")
+	if !strings.HasPrefix(name, outputPrefix) || i < len(outputPrefix) {
+		return nil
+	}
+	pattern := outputPrefix + "*" + name[i:]
+	maxAge := time.Duration(cfg.GetOutputRetentionMaxAgeInDays()) * 24 * time.Hour
+	for _, p := range []string{pattern, pattern + filesign.Extension} {
+		if err := internal.ApplyRetention(filepath.Dir(path), p, int(cfg.GetOutputRetentionMaxFiles()), maxAge, time.Now()); err != nil {
+			log.Logger.Warnw("Failed to apply retention to persisted collection files", "error", err)
+		}
+	}
+	return nil
+}
+
+// ArchiveCollectedData bundles the files persisted by a one-time collection into the gzip-compressed
+// tar archive "google-cloud-sql-server-agent-[collectionType]-[time].tar.gz" in the directory of the
+// files when the output format
+// of the configuration is "json+archive". The persisted files are kept. The archive is signed and
+// the retention policy is applied to the archives in the same way as to the persisted files.
+func ArchiveCollectedData(ctx context.Context, paths []string, collectionType CollectionType, cfg *configpb.Configuration, signingKey *SigningKey) error {
+	if cfg.GetOutputFormat() != "json+archive" || len(paths) == 0 {
+		return nil
+	}
+	ct := "guest"
+	if collectionType == SQL {
+		ct = "sql"
+	}
+	now := time.Now()
+	dir := filepath.Dir(paths[0])
+	path := filepath.Join(dir, fmt.Sprintf("%s%s-%s.tar.gz", outputPrefix, ct, now.UTC().Format("20060102T150405Z")))
+	manifest, err := filearchive.Write(path, paths, now)
+	if err != nil {
+		log.Logger.Errorw("Failed to archive the persisted collection files", "path", path, "error", err)
+		return err
+	}
+	log.Logger.Infow("Archived the persisted collection files", "path", path, "files", len(manifest.Files))
+	if cfg.GetOutputSigningSecretName() != "" {
+		key, err := signingKey.Get(ctx)
+		if err == nil {
+			err = filesign.Sign(path, key)
+		}
+		if err != nil {
+			log.Logger.Errorw("Failed to sign the collection archive", "path", path, "error", err)
+			return err
+		}
+	}
+	pattern := outputPrefix + ct + "-*.tar.gz"
+	maxAge := time.Duration(cfg.GetOutputRetentionMaxAgeInDays()) * 24 * time.Hour
+	for _, p := range []string{pattern, pattern + filesign.Extension} {
+		if err := internal.ApplyRetention(dir, p, int(cfg.GetOutputRetentionMaxFiles()), maxAge, now); err != nil {
+			log.Logger.Warnw("Failed to apply retention to collection archives", "error", err)
+		}
+	}
+	return nil
+}
+
+// VerifyCollectedData checks the signatures of the persisted collection files with the key of the
+// signing secret of the configuration and returns the result of each file.
+func VerifyCollectedData(ctx context.Context, cfg *configpb.Configuration, paths []string) (string, error) {
+	if cfg.GetOutputSigningSecretName() == "" {
+		return "", fmt.Errorf("output_signing_secret_name is not set in the configuration")
+	}
+	key, err := NewSigningKey(cfg).Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	failed := 0
+	for _, path := range paths {
+		if err := filesign.Verify(path, key); err != nil {
+			failed++
+			fmt.Fprintf(&b, "%s: FAILED: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: OK\n", path)
+	}
+	if failed > 0 {
+		return b.String(), fmt.Errorf("%d of %d files failed verification", failed, len(paths))
+	}
+	return b.String(), nil
 }
 
 // Retry returns error if it exceeds max retries limits.
@@ -263,35 +1543,95 @@ func Retry(run func() bool, maxRetries int32, interval time.Duration) error {
 }
 
 // CollectionService runs the passed in collection as a service.
+// The configuration is reloaded between runs when the configuration file changes or the agent
+// receives SIGHUP, which closes the reused connections to SQL Server and re-applies the settings
+// of ReloadSetup. A configuration that fails to load is rejected and the previous configuration is
+// kept. Outside of the collection_windows of the configuration, the service idles until the next
+// window opens.
 func CollectionService(p string, collection func(cfg *configpb.Configuration, onetime bool) error, collectionType CollectionType) {
+	watcher := configuration.NewWatcher(p)
+	defer watcher.Stop()
+	var previous *configpb.Configuration
 	for {
 		cfg, err := LoadConfiguration(p)
-		if cfg == nil {
+		switch {
+		case err != nil && previous != nil:
+			log.Logger.Errorw("Failed to load configuration. Using the previous configuration", "error", err)
+			UsageMetricsLogger.Error(agentstatus.ProtoJSONUnmarshalError)
+			cfg = previous
+		case cfg == nil:
 			log.Logger.Errorw("Failed to load configuration", "error", err)
 			UsageMetricsLogger.Error(agentstatus.ProtoJSONUnmarshalError)
-			time.Sleep(time.Duration(time.Hour))
+			watcher.WaitUntil(time.Now().Add(time.Hour))
+			continue
+		case err != nil:
+			log.Logger.Errorw("Failed to load configuration. Using default configurations", "error", err)
+		}
+		previous = cfg
+		// The configuration loaded for each run may change the WMI query rate.
+		WMIQueryRateSetup(cfg)
+		if schedule := collectionSchedule(cfg); !schedule.Contains(time.Now()) {
+			next := schedule.Next(time.Now())
+			log.Logger.Infow("Outside of the collection windows. Waiting for the next window", "collection type", collectionType, "next window", next)
+			watcher.WaitUntil(next)
 			continue
 		}
 		// Init UsageMetricsLogger for each collection cycle.
 		UsageMetricsLogger = UsageMetricsLoggerInit(!cfg.GetDisableLogUsage())
 		// Set onetime to false for running collection as service
-		if err := collection(cfg, false); err != nil {
+		start := time.Now()
+		err = collection(cfg, false)
+		if err != nil {
 			log.Logger.Errorw("Failed to run collection", "collection type", collectionType, "error", err)
 			if collectionType == OS {
 				UsageMetricsLogger.Error(agentstatus.GuestCollectionFailure)
 			} else {
 				UsageMetricsLogger.Error(agentstatus.SQLCollectionFailure)
 			}
-			time.Sleep(time.Duration(time.Hour))
+			// A configuration change may fix the failure, so the next run starts after a reload.
+			watcher.WaitUntil(time.Now().Add(time.Hour))
 			continue
 		}
-		// Sleep for collection interval.
-		if collectionType == OS {
-			time.Sleep(time.Duration(cfg.GetCollectionConfiguration().GetGuestOsMetricsCollectionIntervalInSeconds()) * time.Second)
-		} else if collectionType == SQL {
-			time.Sleep(time.Duration(cfg.GetCollectionConfiguration().GetSqlMetricsCollectionIntervalInSeconds()) * time.Second)
+		// Wait for the collection interval, which is recomputed from every reloaded configuration.
+		for watcher.WaitUntil(start.Add(collectionInterval(cfg, collectionType))) {
+			reloaded, err := LoadConfiguration(p)
+			if err != nil {
+				// A missing or unreadable file loads the default configuration, which is rejected too.
+				log.Logger.Errorw("Rejected the reloaded configuration. Keeping the previous configuration", "collection type", collectionType, "error", err)
+				continue
+			}
+			log.Logger.Infow("Reloaded the configuration", "collection type", collectionType)
+			ReloadSetup(reloaded)
+			if collectionType == SQL {
+				// The reused connections may use credentials or settings of the previous configuration.
+				sqlPool.Close()
+			}
+			cfg = reloaded
+			previous = reloaded
+		}
+	}
+}
+
+// collectionSchedule returns the collection windows of the configuration.
+func collectionSchedule(cfg *configpb.Configuration) collectionwindow.Schedule {
+	var schedule collectionwindow.Schedule
+	for _, w := range cfg.GetCollectionWindows() {
+		window, err := collectionwindow.Parse(w.GetDays(), w.GetStartTime(), w.GetEndTime(), w.GetTimeZone())
+		if err != nil {
+			log.Logger.Errorw("Invalid collection window", "error", err)
+			continue
 		}
+		schedule = append(schedule, window)
+	}
+	return schedule
+}
+
+// collectionInterval returns the interval between the runs of the collection type.
+func collectionInterval(cfg *configpb.Configuration, collectionType CollectionType) time.Duration {
+	if collectionType == OS {
+		return time.Duration(cfg.GetCollectionConfiguration().GetGuestOsMetricsCollectionIntervalInSeconds()) * time.Second
 	}
+	return time.Duration(cfg.GetCollectionConfiguration().GetSqlMetricsCollectionIntervalInSeconds()) * time.Second
 }
 
 // AddPhysicalDriveRemoteLinux adds physical drive to sql collection based off details for windows to remote linux instances
@@ -363,6 +1703,75 @@ func AddPhysicalDriveRemoteLinux(details []internal.Details, cred *configuration
 	}
 }
 
+// AddSQLVolumeAllocationUnits adds the allocation unit sizes of the volumes hosting sql server data
+// and log files to details. volumes returns the volumes of the machine running sql server.
+func AddSQLVolumeAllocationUnits(details []internal.Details, volumes func() ([]internal.Volume, error)) []internal.Details {
+	v, err := volumes()
+	if err != nil {
+		log.Logger.Warnw("Failed to get the volumes of the machine running sql server", "error", err)
+		return details
+	}
+	if d, ok := internal.SQLVolumeAllocationUnits(details, v); ok {
+		details = append(details, d)
+	}
+	return details
+}
+
+// AddSQLVolumeFreeSpace adds the size and free space of the volumes hosting sql server data and log
+// files to details, flagging the volumes with less free space than thresholdPercent. volumes returns
+// the volumes of the machine running sql server that contain the given data and log files.
+func AddSQLVolumeFreeSpace(details []internal.Details, thresholdPercent int64, volumes func(paths []string) ([]internal.Volume, error)) []internal.Details {
+	paths := internal.SQLFilePaths(details)
+	if len(paths) == 0 {
+		return details
+	}
+	v, err := volumes(paths)
+	if err != nil {
+		log.Logger.Warnw("Failed to get the free space of the volumes of the machine running sql server", "error", err)
+		return details
+	}
+	if d, ok := internal.SQLVolumeFreeSpace(details, v, thresholdPercent); ok {
+		details = append(details, d)
+	}
+	return details
+}
+
+// AddDataLogSameDisk adds to details whether the data and log files of each database share a
+// physical drive. It must be called after the physical drives of the files are added.
+func AddDataLogSameDisk(details []internal.Details) []internal.Details {
+	if d, ok := internal.DataLogSameDisk(details); ok {
+		details = append(details, d)
+	}
+	return details
+}
+
+// AddVMVCPUCount adds the vCPU count of the machine running sql server to the licensed cores in
+// details. vcpus returns the vCPU count of the machine running sql server.
+func AddVMVCPUCount(details []internal.Details, vcpus func() (int64, error)) {
+	v, err := vcpus()
+	if err != nil {
+		log.Logger.Warnw("Failed to get the vCPU count of the machine running sql server", "error", err)
+		return
+	}
+	internal.AddVMVCPUCount(details, v)
+}
+
+// AddFailoverClusterInstance records in details whether sqlCfg marks the instance as a failover
+// cluster instance.
+func AddFailoverClusterInstance(details []internal.Details, sqlCfg *configuration.SQLConfig) {
+	internal.AddFailoverClusterInstance(details, sqlCfg.FailoverClusterInstance)
+}
+
+// LinuxVCPUs wraps the function LinuxVCPUs in guestcollector package.
+func LinuxVCPUs(ctx context.Context) (int64, error) {
+	return guestcollector.LinuxVCPUs(ctx, commandlineexecutor.ExecuteCommand)
+}
+
+// LinuxVolumes wraps the function LinuxVolumes in guestcollector package.
+func LinuxVolumes(ctx context.Context, paths []string) ([]internal.Volume, error) {
+	return guestcollector.LinuxVolumes(ctx, paths, commandlineexecutor.ExecuteCommand)
+}
+
 // AddPhysicalDriveLocal starts physical drive to physical path mapping
 func AddPhysicalDriveLocal(ctx context.Context, details []internal.Details, windows bool) {
 	agentshared.AddPhysicalDriveLocal(ctx, details, windows)
@@ -378,6 +1787,12 @@ func SQLConfigFromCredential(cred *configpb.CredentialConfiguration) []*configur
 	return configuration.SQLConfigFromCredential(cred)
 }
 
+// SQLConnectionString wraps the function SQLConnectionString in configuration package.
+// The minimum TLS version is taken from the configuration.
+func SQLConnectionString(cfg *configpb.Configuration, sqlCfg *configuration.SQLConfig, password string) string {
+	return configuration.SQLConnectionString(sqlCfg, password, cfg.GetMinTlsVersion())
+}
+
 // GuestConfigFromCredential wraps the function GuestConfigFromCredential in configuration package.
 func GuestConfigFromCredential(cred *configpb.CredentialConfiguration) *configuration.GuestConfig {
 	return configuration.GuestConfigFromCredential(cred)
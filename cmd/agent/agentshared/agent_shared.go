@@ -19,7 +19,9 @@ package agentshared
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -28,6 +30,7 @@ import (
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/activation"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/guestcollector"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/sqlcollector"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/wlm"
 )
@@ -93,14 +96,21 @@ func RunSQLCollection(ctx context.Context, c sqlcollector.SQLCollector, timeout
 
 // RunOSCollection runs guest collection based on given collector type.
 // GuestCollector could be either for Linux or for Windows.
-func RunOSCollection(ctx context.Context, c guestcollector.GuestCollector, timeout time.Duration) []internal.Details {
+func RunOSCollection(ctx context.Context, c guestcollector.GuestCollector, timeout time.Duration, reportManifest bool) []internal.Details {
 	details := []internal.Details{}
+	var m *manifest.Manifest
+	if reportManifest {
+		ctx, m = manifest.NewContext(ctx)
+	}
 	log.Logger.Debug("Collecting guest rules")
 	details = append(details, c.CollectGuestRules(ctx, timeout))
 	err := guestcollector.MarkUnknownOsFields(&details)
 	if err != nil {
 		log.Logger.Warnf("RunOSCollection: Failed to mark unknown collected fields. error: %v", err)
 	}
+	if m != nil {
+		details = append(details, m.Details())
+	}
 
 	log.Logger.Debug("Collecting guest rules completes")
 	return details
@@ -122,3 +132,75 @@ func AddPhysicalDriveLocal(ctx context.Context, details []internal.Details, wind
 		}
 	}
 }
+
+// RuleNames returns the names of all master rules followed by the names of all guest rules.
+func RuleNames() []string {
+	var names []string
+	for _, rule := range internal.MasterRules {
+		names = append(names, rule.Name)
+	}
+	return append(names, guestcollector.CollectionOSFields()...)
+}
+
+// IsMasterRule returns true if the name is one of the master rules.
+func IsMasterRule(name string) bool {
+	for _, rule := range internal.MasterRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGuestRule returns true if the name is one of the guest rules.
+func IsGuestRule(name string) bool {
+	for _, field := range guestcollector.CollectionOSFields() {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownRuleError returns the error for a rule name that is neither a master rule nor a guest rule.
+func UnknownRuleError(name string) error {
+	return fmt.Errorf("unknown rule %q, available rules:\n  %s", name, strings.Join(RuleNames(), "\n  "))
+}
+
+// RunGuestRule runs the guest rule with the given name, along with the rules it depends on, and
+// returns its details.
+func RunGuestRule(ctx context.Context, c guestcollector.GuestCollector, name string, timeout time.Duration) (internal.Details, error) {
+	if !IsGuestRule(name) {
+		return internal.Details{}, UnknownRuleError(name)
+	}
+	details := c.CollectGuestRule(ctx, name, timeout)
+	value := "unknown"
+	if len(details.Fields) == 1 {
+		if v, ok := details.Fields[0][name]; ok && v != "" {
+			value = v
+		}
+	}
+	return internal.Details{
+		Name:   name,
+		Fields: []map[string]string{{name: value}},
+	}, nil
+}
+
+// FormatRuleResult returns the raw query result and the transformed fields of a rule in a readable format.
+// The raw result is omitted if it is nil, as for the guest rules.
+func FormatRuleResult(raw [][]any, details internal.Details) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rule: %s\n", details.Name)
+	if raw != nil {
+		b.WriteString("Raw result:\n")
+		for _, row := range raw {
+			fmt.Fprintf(&b, "  %v\n", row)
+		}
+	}
+	fields, err := json.MarshalIndent(details.Fields, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "Fields:\n%s", fields)
+	return b.String(), nil
+}
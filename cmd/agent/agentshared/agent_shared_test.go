@@ -18,6 +18,7 @@ package agentshared
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -56,6 +57,10 @@ func (c *mockGuestOsCollector) CollectGuestRules(ctx context.Context, timeout ti
 	}
 }
 
+func (c *mockGuestOsCollector) CollectGuestRule(ctx context.Context, rule string, timeout time.Duration) internal.Details {
+	return c.CollectGuestRules(ctx, timeout)
+}
+
 func TestCheckAgentStatus(t *testing.T) {
 	testcases := []struct {
 		name        string
@@ -170,7 +175,7 @@ func TestRunSQLCollection(t *testing.T) {
 }
 
 func TestRunOSCollection(t *testing.T) {
-	got := RunOSCollection(context.Background(), &mockGuestOsCollector{}, time.Second)
+	got := RunOSCollection(context.Background(), &mockGuestOsCollector{}, time.Second, false)
 	want := []internal.Details{
 		{
 			Name: "mockResult",
@@ -187,6 +192,28 @@ func TestRunOSCollection(t *testing.T) {
 	}
 }
 
+func TestRunOSCollectionManifest(t *testing.T) {
+	got := RunOSCollection(context.Background(), &mockGuestOsCollector{}, time.Second, true)
+	want := []internal.Details{
+		{
+			Name: "mockResult",
+			Fields: []map[string]string{
+				{
+					"mockField": "mockValue",
+				},
+			},
+		},
+		{
+			Name:   internal.CollectionManifestName,
+			Fields: []map[string]string{},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("RunOSCollection() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
 func TestAddPhysicalDriveLocal(t *testing.T) {
 	testcases := []struct {
 		name    string
@@ -249,3 +276,113 @@ func TestAddPhysicalDriveLocal(t *testing.T) {
 		}
 	}
 }
+
+// mockGuestRuleCollector collects the power profile setting rule only, and fails the test when
+// all guest rules are collected.
+type mockGuestRuleCollector struct {
+	t *testing.T
+}
+
+func (c *mockGuestRuleCollector) CollectGuestRules(ctx context.Context, timeout time.Duration) internal.Details {
+	c.t.Error("CollectGuestRules() was called, want only the requested rule to be collected")
+	return internal.Details{}
+}
+
+func (c *mockGuestRuleCollector) CollectGuestRule(ctx context.Context, rule string, timeout time.Duration) internal.Details {
+	fields := map[string]string{}
+	if rule == internal.PowerProfileSettingRule {
+		fields[rule] = "Balanced"
+	}
+	return internal.Details{Name: "OS", Fields: []map[string]string{fields}}
+}
+
+func TestRunGuestRule(t *testing.T) {
+	testcases := []struct {
+		name    string
+		rule    string
+		want    internal.Details
+		wantErr bool
+	}{
+		{
+			name: "success",
+			rule: internal.PowerProfileSettingRule,
+			want: internal.Details{
+				Name:   internal.PowerProfileSettingRule,
+				Fields: []map[string]string{{internal.PowerProfileSettingRule: "Balanced"}},
+			},
+		},
+		{
+			name: "missing field is unknown",
+			rule: internal.LocalSSDRule,
+			want: internal.Details{
+				Name:   internal.LocalSSDRule,
+				Fields: []map[string]string{{internal.LocalSSDRule: "unknown"}},
+			},
+		},
+		{
+			name:    "unknown rule",
+			rule:    "any",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RunGuestRule(context.Background(), &mockGuestRuleCollector{t: t}, tc.rule, time.Second)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("RunGuestRule(%q) = %v, want error presence = %v", tc.rule, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("RunGuestRule(%q) returned wrong result (-got +want):\n%s", tc.rule, diff)
+			}
+		})
+	}
+}
+
+func TestUnknownRuleError(t *testing.T) {
+	err := UnknownRuleError("any")
+	for _, name := range []string{internal.MasterRules[0].Name, internal.PowerProfileSettingRule} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("UnknownRuleError(%q) = %v, want it to list rule %q", "any", err, name)
+		}
+	}
+}
+
+func TestFormatRuleResult(t *testing.T) {
+	testcases := []struct {
+		name    string
+		raw     [][]any
+		details internal.Details
+		want    string
+	}{
+		{
+			name: "master rule",
+			raw:  [][]any{{"db1", int64(1)}},
+			details: internal.Details{
+				Name:   "testRule",
+				Fields: []map[string]string{{"db_name": "db1"}},
+			},
+			want: "Rule: testRule\nRaw result:\n  [db1 1]\nFields:\n[\n  {\n    \"db_name\": \"db1\"\n  }\n]",
+		},
+		{
+			name: "guest rule without raw result",
+			details: internal.Details{
+				Name:   "local_ssd",
+				Fields: []map[string]string{{"local_ssd": "unknown"}},
+			},
+			want: "Rule: local_ssd\nFields:\n[\n  {\n    \"local_ssd\": \"unknown\"\n  }\n]",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FormatRuleResult(tc.raw, tc.details)
+			if err != nil {
+				t.Fatalf("FormatRuleResult() returned unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("FormatRuleResult() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agentshared
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+
+	workloadmanager "google.golang.org/api/workloadmanager/v1"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/wlm"
+)
+
+// Statuses of the rules, rows and fields in a collection diff.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// RuleDiff is the difference of a rule between two collections.
+type RuleDiff struct {
+	Rule   string    `json:"rule"`
+	Status string    `json:"status"`
+	Rows   []RowDiff `json:"rows"`
+}
+
+// RowDiff is the difference of a row of fields of a rule between two collections.
+// Before is set for removed rows, After for added rows and Fields for changed rows.
+type RowDiff struct {
+	Status string            `json:"status"`
+	Before map[string]string `json:"before,omitempty"`
+	After  map[string]string `json:"after,omitempty"`
+	Fields []FieldDiff       `json:"fields,omitempty"`
+}
+
+// FieldDiff is the difference of a field value of a changed row.
+type FieldDiff struct {
+	Field  string `json:"field"`
+	Status string `json:"status"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// LoadCollection loads the details of a collection persisted by the agent.
+func LoadCollection(path string) ([]internal.Details, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	req := workloadmanager.WriteInsightRequest{}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid collection file %s: %v", path, err)
+	}
+	if req.Insight == nil {
+		return nil, fmt.Errorf("invalid collection file %s: no sql server validation", path)
+	}
+	return wlm.ValidationDetailsToDetails(req.Insight.SqlserverValidation), nil
+}
+
+// DiffCollections returns the differences between the details of two collections, sorted by rule.
+// Rows without changes are matched regardless of their order. The remaining rows are paired in
+// order and reported as changed, and the rows left over are reported as added or removed.
+func DiffCollections(before, after []internal.Details) []RuleDiff {
+	beforeRows, afterRows := rowsByRule(before), rowsByRule(after)
+	rules := []string{}
+	for rule := range beforeRows {
+		rules = append(rules, rule)
+	}
+	for rule := range afterRows {
+		if _, ok := beforeRows[rule]; !ok {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Strings(rules)
+
+	diffs := []RuleDiff{}
+	for _, rule := range rules {
+		b, inBefore := beforeRows[rule]
+		a, inAfter := afterRows[rule]
+		rows := diffRows(b, a)
+		switch {
+		case !inBefore:
+			diffs = append(diffs, RuleDiff{Rule: rule, Status: DiffAdded, Rows: rows})
+		case !inAfter:
+			diffs = append(diffs, RuleDiff{Rule: rule, Status: DiffRemoved, Rows: rows})
+		case len(rows) > 0:
+			diffs = append(diffs, RuleDiff{Rule: rule, Status: DiffChanged, Rows: rows})
+		}
+	}
+	return diffs
+}
+
+// rowsByRule returns the rows of the details by rule. The rows of details with the same name,
+// as for multiple sql configurations, are merged.
+func rowsByRule(details []internal.Details) map[string][]map[string]string {
+	rows := map[string][]map[string]string{}
+	for _, d := range details {
+		rows[d.Name] = append(rows[d.Name], d.Fields...)
+	}
+	return rows
+}
+
+func diffRows(before, after []map[string]string) []RowDiff {
+	matched := make([]bool, len(after))
+	var removed []map[string]string
+	for _, b := range before {
+		found := false
+		for i, a := range after {
+			if !matched[i] && maps.Equal(a, b) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, b)
+		}
+	}
+	var added []map[string]string
+	for i, a := range after {
+		if !matched[i] {
+			added = append(added, a)
+		}
+	}
+
+	rows := []RowDiff{}
+	for len(removed) > 0 && len(added) > 0 {
+		rows = append(rows, RowDiff{Status: DiffChanged, Fields: diffFields(removed[0], added[0])})
+		removed, added = removed[1:], added[1:]
+	}
+	for _, r := range removed {
+		rows = append(rows, RowDiff{Status: DiffRemoved, Before: r})
+	}
+	for _, a := range added {
+		rows = append(rows, RowDiff{Status: DiffAdded, After: a})
+	}
+	return rows
+}
+
+func diffFields(before, after map[string]string) []FieldDiff {
+	fields := []FieldDiff{}
+	for _, f := range sortedKeys(before, after) {
+		b, inBefore := before[f]
+		a, inAfter := after[f]
+		switch {
+		case !inBefore:
+			fields = append(fields, FieldDiff{Field: f, Status: DiffAdded, After: a})
+		case !inAfter:
+			fields = append(fields, FieldDiff{Field: f, Status: DiffRemoved, Before: b})
+		case a != b:
+			fields = append(fields, FieldDiff{Field: f, Status: DiffChanged, Before: b, After: a})
+		}
+	}
+	return fields
+}
+
+func sortedKeys(ms ...map[string]string) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, m := range ms {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatDiff returns the collection differences in a readable format, or as JSON if asJSON is true.
+func FormatDiff(diffs []RuleDiff, asJSON bool) (string, error) {
+	if asJSON {
+		b, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	if len(diffs) == 0 {
+		return "No differences.", nil
+	}
+	var b strings.Builder
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "%s %s\n", diffMarker(d.Status), d.Rule)
+		for _, r := range d.Rows {
+			switch r.Status {
+			case DiffAdded:
+				fmt.Fprintf(&b, "  + %s\n", formatRow(r.After))
+			case DiffRemoved:
+				fmt.Fprintf(&b, "  - %s\n", formatRow(r.Before))
+			case DiffChanged:
+				b.WriteString("  ~ row\n")
+				for _, f := range r.Fields {
+					switch f.Status {
+					case DiffAdded:
+						fmt.Fprintf(&b, "      + %s: %q\n", f.Field, f.After)
+					case DiffRemoved:
+						fmt.Fprintf(&b, "      - %s: %q\n", f.Field, f.Before)
+					case DiffChanged:
+						fmt.Fprintf(&b, "      ~ %s: %q -> %q\n", f.Field, f.Before, f.After)
+					}
+				}
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func diffMarker(status string) string {
+	switch status {
+	case DiffAdded:
+		return "+"
+	case DiffRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// formatRow returns the fields of a row sorted by name.
+func formatRow(row map[string]string) string {
+	pairs := []string{}
+	for _, k := range sortedKeys(row) {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, row[k]))
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agentshared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+func TestLoadCollection(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`{
+  "insight": {
+    "sqlserverValidation": {
+      "validationDetails": [
+        {"type": "DB_MAX_SERVER_MEMORY", "details": [{"fields": {"value_in_use": "2048"}}]}
+      ]
+    }
+  }
+}`), 0644)
+	malformed := filepath.Join(dir, "malformed.json")
+	os.WriteFile(malformed, []byte(`{"insight":`), 0644)
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, []byte(`{}`), 0644)
+
+	testcases := []struct {
+		name    string
+		path    string
+		want    []internal.Details
+		wantErr bool
+	}{
+		{
+			name: "success",
+			path: valid,
+			want: []internal.Details{{Name: "DB_MAX_SERVER_MEMORY", Fields: []map[string]string{{"value_in_use": "2048"}}}},
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(dir, "missing.json"),
+			wantErr: true,
+		},
+		{
+			name:    "malformed file",
+			path:    malformed,
+			wantErr: true,
+		},
+		{
+			name:    "no insight",
+			path:    empty,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := LoadCollection(tc.path)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("LoadCollection(%q) returned error %v, want error presence = %v", tc.path, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("LoadCollection(%q) returned wrong result (-got +want):\n%s", tc.path, diff)
+			}
+		})
+	}
+}
+
+func TestDiffCollections(t *testing.T) {
+	before := []internal.Details{
+		{Name: "DB_MAX_SERVER_MEMORY", Fields: []map[string]string{{"value_in_use": "2048"}}},
+		{Name: "DB_QUERY_STORE", Fields: []map[string]string{
+			{"db_name": "db1", "actual_state": "OFF"},
+			{"db_name": "db2", "actual_state": "READ_WRITE"},
+		}},
+		{Name: "SQL_EDITION", Fields: []map[string]string{{"edition": "STANDARD"}}},
+		{Name: "OS_SETTINGS", Fields: []map[string]string{{"power_profile": "balanced"}}},
+	}
+	after := []internal.Details{
+		{Name: "OS_SETTINGS", Fields: []map[string]string{{"power_profile": "balanced"}}},
+		{Name: "DB_MAX_SERVER_MEMORY", Fields: []map[string]string{{"value_in_use": "4096", "host_name": "localhost"}}},
+		{Name: "DB_QUERY_STORE", Fields: []map[string]string{
+			{"db_name": "db2", "actual_state": "READ_WRITE"},
+			{"db_name": "db1", "actual_state": "READ_WRITE"},
+			{"db_name": "db3", "actual_state": "OFF"},
+		}},
+		{Name: "DB_USER_CONNECTIONS", Fields: []map[string]string{{"sessions": "3"}}},
+	}
+	want := []RuleDiff{
+		{
+			Rule:   "DB_MAX_SERVER_MEMORY",
+			Status: DiffChanged,
+			Rows: []RowDiff{{Status: DiffChanged, Fields: []FieldDiff{
+				{Field: "host_name", Status: DiffAdded, After: "localhost"},
+				{Field: "value_in_use", Status: DiffChanged, Before: "2048", After: "4096"},
+			}}},
+		},
+		{
+			Rule:   "DB_QUERY_STORE",
+			Status: DiffChanged,
+			Rows: []RowDiff{
+				{Status: DiffChanged, Fields: []FieldDiff{{Field: "actual_state", Status: DiffChanged, Before: "OFF", After: "READ_WRITE"}}},
+				{Status: DiffAdded, After: map[string]string{"db_name": "db3", "actual_state": "OFF"}},
+			},
+		},
+		{
+			Rule:   "DB_USER_CONNECTIONS",
+			Status: DiffAdded,
+			Rows:   []RowDiff{{Status: DiffAdded, After: map[string]string{"sessions": "3"}}},
+		},
+		{
+			Rule:   "SQL_EDITION",
+			Status: DiffRemoved,
+			Rows:   []RowDiff{{Status: DiffRemoved, Before: map[string]string{"edition": "STANDARD"}}},
+		},
+	}
+
+	got := DiffCollections(before, after)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("DiffCollections() returned wrong result (-got +want):\n%s", diff)
+	}
+	if got := DiffCollections(before, before); len(got) != 0 {
+		t.Errorf("DiffCollections() of identical collections = %v, want empty", got)
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	diffs := []RuleDiff{
+		{
+			Rule:   "DB_MAX_SERVER_MEMORY",
+			Status: DiffChanged,
+			Rows: []RowDiff{{Status: DiffChanged, Fields: []FieldDiff{
+				{Field: "host_name", Status: DiffRemoved, Before: "localhost"},
+				{Field: "value_in_use", Status: DiffChanged, Before: "2048", After: "4096"},
+			}}},
+		},
+		{
+			Rule:   "DB_USER_CONNECTIONS",
+			Status: DiffAdded,
+			Rows:   []RowDiff{{Status: DiffAdded, After: map[string]string{"sessions": "3", "program_name": "sqlcmd"}}},
+		},
+	}
+	testcases := []struct {
+		name   string
+		diffs  []RuleDiff
+		asJSON bool
+		want   string
+	}{
+		{
+			name:  "text",
+			diffs: diffs,
+			want: `~ DB_MAX_SERVER_MEMORY
+  ~ row
+      - host_name: "localhost"
+      ~ value_in_use: "2048" -> "4096"
++ DB_USER_CONNECTIONS
+  + program_name="sqlcmd" sessions="3"`,
+		},
+		{
+			name: "text without differences",
+			want: "No differences.",
+		},
+		{
+			name:   "json",
+			diffs:  diffs[1:],
+			asJSON: true,
+			want: `[
+  {
+    "rule": "DB_USER_CONNECTIONS",
+    "status": "added",
+    "rows": [
+      {
+        "status": "added",
+        "after": {
+          "program_name": "sqlcmd",
+          "sessions": "3"
+        }
+      }
+    ]
+  }
+]`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FormatDiff(tc.diffs, tc.asJSON)
+			if err != nil {
+				t.Fatalf("FormatDiff() returned unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("FormatDiff() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -19,25 +19,58 @@ package flags
 
 import (
 	"fmt"
+	"runtime"
+	"runtime/debug"
 
 	"flag"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 )
 
+// Supported values of the --collect flag.
+const (
+	CollectAll = "all"
+	CollectOS  = "os"
+	CollectSQL = "sql"
+)
+
+// Supported values of the --diff-format flag.
+const (
+	DiffFormatText = "text"
+	DiffFormatJSON = "json"
+)
+
 // AgentFlags .
 type AgentFlags struct {
-	Action  string
-	Onetime bool
-	version bool
-	help    bool
-	h       bool
+	Action        string
+	Onetime       bool
+	RunRule       string
+	Collect       string
+	Diff          bool
+	DiffFormat    string
+	DiffFiles     []string
+	Verify        bool
+	VerifyFiles   []string
+	Synthetic     int
+	SyntheticSeed int64
+	version       bool
+	fullVersion   bool
+	help          bool
+	h             bool
 }
 
 // NewAgentFlags initialize flags and return the reference of struct agentFlags.
 func NewAgentFlags() *AgentFlags {
 	action := flag.String("action", "", "Action for running the agent.")
 	onetime := flag.Bool("onetime", false, "Onetime mode for the agent.")
+	runRule := flag.String("run-rule", "", "Run a single master or guest rule by name and print its result.")
+	collect := flag.String("collect", CollectAll, "Collection types run by the agent: all, os or sql.")
+	diff := flag.Bool("diff", false, "Print the differences between the two persisted collection files given as arguments.")
+	diffFormat := flag.String("diff-format", DiffFormatText, "Output format of --diff: text or json.")
+	verify := flag.Bool("verify", false, "Verify the signatures of the persisted collection files given as arguments.")
+	synthetic := flag.Int("synthetic", 0, "Export synthetic collection data for the given number of fake instances instead of collecting from SQL Server.")
+	syntheticSeed := flag.Int64("synthetic-seed", 1, "Seed of the synthetic collection data generated by --synthetic.")
 	version := flag.Bool("agent_version", false, "Display the version of the agent.")
+	fullVersion := flag.Bool("version", false, "Display the version and build information of the agent.")
 	help := flag.Bool("help", false, "Display the usage of each flag.")
 	h := flag.Bool("h", false, "Display the usage of each flag.")
 
@@ -46,11 +79,21 @@ func NewAgentFlags() *AgentFlags {
 	}
 
 	return &AgentFlags{
-		Action:  *action,
-		Onetime: *onetime,
-		version: *version,
-		help:    *help,
-		h:       *h,
+		Action:        *action,
+		Onetime:       *onetime,
+		RunRule:       *runRule,
+		Collect:       *collect,
+		Diff:          *diff,
+		DiffFormat:    *diffFormat,
+		DiffFiles:     flag.Args(),
+		Verify:        *verify,
+		VerifyFiles:   flag.Args(),
+		Synthetic:     *synthetic,
+		SyntheticSeed: *syntheticSeed,
+		version:       *version,
+		fullVersion:   *fullVersion,
+		help:          *help,
+		h:             *h,
 	}
 }
 
@@ -64,7 +107,31 @@ func (af *AgentFlags) Execute() (string, bool) {
 	if af.version {
 		return fmt.Sprintf("Google Cloud SQL Server Agent version: %v.", internal.AgentVersion), false
 	}
-	if af.Onetime {
+	if af.fullVersion {
+		return versionInfo(), false
+	}
+	if af.Collect != "" && af.Collect != CollectAll && af.Collect != CollectOS && af.Collect != CollectSQL {
+		return fmt.Sprintf("Invalid value %q for flag --collect. Supported values are %s, %s and %s.", af.Collect, CollectAll, CollectOS, CollectSQL), false
+	}
+	if af.Diff {
+		if af.DiffFormat != "" && af.DiffFormat != DiffFormatText && af.DiffFormat != DiffFormatJSON {
+			return fmt.Sprintf("Invalid value %q for flag --diff-format. Supported values are %s and %s.", af.DiffFormat, DiffFormatText, DiffFormatJSON), false
+		}
+		if len(af.DiffFiles) != 2 {
+			return "Flag --diff requires exactly two collection files: --diff [--diff-format=json] <fileA> <fileB>", false
+		}
+		return "", true
+	}
+	if af.Verify {
+		if len(af.VerifyFiles) == 0 {
+			return "Flag --verify requires at least one collection file: --verify <file>...", false
+		}
+		return "", true
+	}
+	if af.Synthetic < 0 {
+		return fmt.Sprintf("Invalid value %d for flag --synthetic. The number of fake instances cannot be negative.", af.Synthetic), false
+	}
+	if af.Onetime || af.RunRule != "" || af.Synthetic > 0 {
 		return "", true
 	}
 	if af.Action == "" {
@@ -73,6 +140,46 @@ func (af *AgentFlags) Execute() (string, bool) {
 	return "", true
 }
 
+// CollectOS reports whether the guest os collection runs.
+func (af *AgentFlags) CollectOS() bool {
+	return af.Collect != CollectSQL
+}
+
+// CollectSQL reports whether the sql collection runs.
+func (af *AgentFlags) CollectSQL() bool {
+	return af.Collect != CollectOS
+}
+
+// ServiceArgs returns the flags that are passed to the installed service.
+func (af *AgentFlags) ServiceArgs() []string {
+	if af.Collect == "" || af.Collect == CollectAll {
+		return nil
+	}
+	return []string{"--collect=" + af.Collect}
+}
+
 func (af *AgentFlags) usage() string {
-	return `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`
+	return `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`
+}
+
+// versionInfo returns the version of the agent along with its build information.
+func versionInfo() string {
+	return fmt.Sprintf("Google Cloud SQL Server Agent\nversion: %s\nservice name: %s\ngo version: %s\nbuild timestamp: %s",
+		internal.AgentVersion, internal.ServiceName, runtime.Version(), buildTimestamp())
+}
+
+// buildTimestamp returns the build timestamp set at build time,
+// or the commit time recorded in the build info if it is not set.
+func buildTimestamp() string {
+	if internal.BuildTimestamp != "" {
+		return internal.BuildTimestamp
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.time" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
 }
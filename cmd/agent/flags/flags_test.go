@@ -18,8 +18,10 @@ package flags
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 )
 
@@ -34,12 +36,36 @@ func TestNewAgentFlags(t *testing.T) {
 	if af.version != false {
 		t.Errorf("NewAgentFlags() = %v, want %v", af.version, true)
 	}
+	if af.fullVersion != false {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.fullVersion, false)
+	}
 	if af.Onetime != false {
 		t.Errorf("NewAgentFlags() = %v, want %v", af.Onetime, true)
 	}
+	if af.RunRule != "" {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.RunRule, "")
+	}
 	if af.Action != "" {
 		t.Errorf("NewAgentFlags() = %v, want %v", af.Action, "")
 	}
+	if af.Collect != CollectAll {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.Collect, CollectAll)
+	}
+	if af.Diff != false {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.Diff, false)
+	}
+	if af.DiffFormat != DiffFormatText {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.DiffFormat, DiffFormatText)
+	}
+	if af.Verify != false {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.Verify, false)
+	}
+	if af.Synthetic != 0 {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.Synthetic, 0)
+	}
+	if af.SyntheticSeed != 1 {
+		t.Errorf("NewAgentFlags() = %v, want %v", af.SyntheticSeed, 1)
+	}
 }
 
 func TestExecute(t *testing.T) {
@@ -52,13 +78,13 @@ func TestExecute(t *testing.T) {
 		{
 			name:     "flag --help is enabled",
 			af:       &AgentFlags{help: true},
-			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`,
+			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`,
 			wantBool: false,
 		},
 		{
 			name:     "flag --h is enabled",
 			af:       &AgentFlags{h: true},
-			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`,
+			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`,
 			wantBool: false,
 		},
 		{
@@ -67,16 +93,28 @@ func TestExecute(t *testing.T) {
 			wantStr:  fmt.Sprintf("Google Cloud SQL Server Agent version: %v.", internal.AgentVersion),
 			wantBool: false,
 		},
+		{
+			name:     "flag --version is enabled",
+			af:       &AgentFlags{fullVersion: true},
+			wantStr:  versionInfo(),
+			wantBool: false,
+		},
 		{
 			name:     "flag --onetime is enabled",
 			af:       &AgentFlags{Onetime: true},
 			wantStr:  "",
 			wantBool: true,
 		},
+		{
+			name:     "flag --run-rule has value",
+			af:       &AgentFlags{RunRule: "DB_LOG_DISK_SEPARATION"},
+			wantStr:  "",
+			wantBool: true,
+		},
 		{
 			name:     "flag --action is empty",
 			af:       &AgentFlags{Action: ""},
-			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`,
+			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`,
 			wantBool: false,
 		},
 		{
@@ -85,16 +123,70 @@ func TestExecute(t *testing.T) {
 			wantStr:  "",
 			wantBool: true,
 		},
+		{
+			name:     "flag --collect has invalid value",
+			af:       &AgentFlags{Action: "run", Collect: "guest"},
+			wantStr:  `Invalid value "guest" for flag --collect. Supported values are all, os and sql.`,
+			wantBool: false,
+		},
+		{
+			name:     "flag --collect has value",
+			af:       &AgentFlags{Action: "run", Collect: CollectSQL},
+			wantStr:  "",
+			wantBool: true,
+		},
+		{
+			name:     "flag --diff has two files",
+			af:       &AgentFlags{Diff: true, DiffFiles: []string{"a.json", "b.json"}},
+			wantStr:  "",
+			wantBool: true,
+		},
+		{
+			name:     "flag --diff has one file",
+			af:       &AgentFlags{Diff: true, DiffFiles: []string{"a.json"}},
+			wantStr:  "Flag --diff requires exactly two collection files: --diff [--diff-format=json] <fileA> <fileB>",
+			wantBool: false,
+		},
+		{
+			name:     "flag --diff-format has invalid value",
+			af:       &AgentFlags{Diff: true, DiffFormat: "yaml", DiffFiles: []string{"a.json", "b.json"}},
+			wantStr:  `Invalid value "yaml" for flag --diff-format. Supported values are text and json.`,
+			wantBool: false,
+		},
+		{
+			name:     "flag --verify has files",
+			af:       &AgentFlags{Verify: true, VerifyFiles: []string{"a.json", "b.json"}},
+			wantStr:  "",
+			wantBool: true,
+		},
+		{
+			name:     "flag --verify has no files",
+			af:       &AgentFlags{Verify: true},
+			wantStr:  "Flag --verify requires at least one collection file: --verify <file>...",
+			wantBool: false,
+		},
+		{
+			name:     "flag --synthetic has value",
+			af:       &AgentFlags{Synthetic: 3},
+			wantStr:  "",
+			wantBool: true,
+		},
+		{
+			name:     "flag --synthetic has negative value",
+			af:       &AgentFlags{Synthetic: -1},
+			wantStr:  "Invalid value -1 for flag --synthetic. The number of fake instances cannot be negative.",
+			wantBool: false,
+		},
 		{
 			name:     "having flag --h ignores other flags",
 			af:       &AgentFlags{h: true, version: true},
-			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`,
+			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`,
 			wantBool: false,
 		},
 		{
 			name:     "having flag --help ignores other flags",
 			af:       &AgentFlags{help: true, version: true},
-			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|onetime)`,
+			wantStr:  `Usage: google-cloud-sql-server-agent -(h|agent_version|version|onetime|run-rule <name>|diff <fileA> <fileB>|verify <file>...)`,
 			wantBool: false,
 		},
 	}
@@ -108,3 +200,60 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestCollect(t *testing.T) {
+	testcases := []struct {
+		name            string
+		collect         string
+		wantOS          bool
+		wantSQL         bool
+		wantServiceArgs []string
+	}{
+		{
+			name:    "default collects all",
+			wantOS:  true,
+			wantSQL: true,
+		},
+		{
+			name:    "all",
+			collect: CollectAll,
+			wantOS:  true,
+			wantSQL: true,
+		},
+		{
+			name:            "os only",
+			collect:         CollectOS,
+			wantOS:          true,
+			wantServiceArgs: []string{"--collect=os"},
+		},
+		{
+			name:            "sql only",
+			collect:         CollectSQL,
+			wantSQL:         true,
+			wantServiceArgs: []string{"--collect=sql"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			af := &AgentFlags{Collect: tc.collect}
+			if got := af.CollectOS(); got != tc.wantOS {
+				t.Errorf("CollectOS() = %v, want %v", got, tc.wantOS)
+			}
+			if got := af.CollectSQL(); got != tc.wantSQL {
+				t.Errorf("CollectSQL() = %v, want %v", got, tc.wantSQL)
+			}
+			if diff := cmp.Diff(af.ServiceArgs(), tc.wantServiceArgs); diff != "" {
+				t.Errorf("ServiceArgs() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVersionInfo(t *testing.T) {
+	defer func(ts string) { internal.BuildTimestamp = ts }(internal.BuildTimestamp)
+	internal.BuildTimestamp = "2024-05-01T00:00:00Z"
+	want := fmt.Sprintf("Google Cloud SQL Server Agent\nversion: %s\nservice name: google-cloud-sql-server-agent\ngo version: %s\nbuild timestamp: 2024-05-01T00:00:00Z", internal.AgentVersion, runtime.Version())
+	if got := versionInfo(); got != want {
+		t.Errorf("versionInfo() = %q, want %q", got, want)
+	}
+}
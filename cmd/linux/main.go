@@ -23,18 +23,24 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 
 	_ "github.com/microsoft/go-mssqldb"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 	"github.com/GoogleCloudPlatform/sql-server-agent/cmd/agent"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/daemon"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/guestcollector"
 	configpb "github.com/GoogleCloudPlatform/sql-server-agent/protos/sqlserveragentconfig"
 )
 
+// readinessDir is the directory the readiness files of the collection cycles are written to. Only
+// the agent writes to it.
+const readinessDir = "/var/run/google-cloud-sql-server-agent"
+
 func main() {
 	flags, output, proceed := agent.Init()
 	if output != "" {
@@ -43,6 +49,16 @@ func main() {
 	if !proceed {
 		return
 	}
+	// diff of two persisted collections
+	if flags.Diff {
+		res, err := agent.Diff(flags)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(res)
+		return
+	}
 
 	const configPath = "/etc/google-cloud-sql-server-agent/"
 	const logPrefix = "/var/log/google-cloud-sql-server-agent"
@@ -59,14 +75,61 @@ func main() {
 	if err != nil {
 		log.Logger.Errorw("Failed to load configuration. Using default configurations", "error", err)
 	}
+	agent.MetadataSetup(cfg)
 	agent.LoggingSetup(ctx, logPrefix, cfg)
+	agent.ResourceLimitsSetup(cfg)
+	agent.DiskClassifierSetup(cfg)
+	defer agent.TracingSetup(ctx, cfg)()
+	// verification of the signatures of persisted collections
+	if flags.Verify {
+		res, err := agent.VerifyCollectedData(ctx, cfg, flags.VerifyFiles)
+		fmt.Print(res)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	// single rule run for debugging
+	if flags.RunRule != "" {
+		guestCollector := func() (guestcollector.GuestCollector, error) {
+			sip, err := agent.SourceInstanceProperties()
+			if err != nil {
+				return nil, err
+			}
+			disks, err := agent.AllDisks(ctx, sip)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to collect disk info: %w", err)
+			}
+			return agent.NewLinuxCollector(cfg, disks, "", "", "", false, 22), nil
+		}
+		res, err := agent.RunRule(ctx, cfg, flags.RunRule, false, guestCollector)
+		if err != nil {
+			log.Logger.Errorw("Failed to run rule", "rule", flags.RunRule, "error", err)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(res)
+		return
+	}
+	// synthetic data for demos and load tests
+	if flags.Synthetic > 0 {
+		if err := agent.SyntheticCollection(ctx, cfg, flags.Synthetic, flags.SyntheticSeed); err != nil {
+			log.Logger.Errorw("Failed to send synthetic collected data", "error", err)
+		}
+		return
+	}
 	// onetime collection
 	if flags.Onetime {
-		if err := osCollection(ctx, tmpPath, logPrefix, cfg, true); err != nil {
-			log.Logger.Errorw("Failed to complete os collection", "error", err)
+		if flags.CollectOS() {
+			if err := osCollection(ctx, tmpPath, logPrefix, cfg, true); err != nil {
+				log.Logger.Errorw("Failed to complete os collection", "error", err)
+			}
 		}
-		if err := sqlCollection(ctx, tmpPath, logPrefix, cfg, true); err != nil {
-			log.Logger.Errorw("Failed to complete sql collection", "error", err)
+		if flags.CollectSQL() {
+			if err := sqlCollection(ctx, tmpPath, logPrefix, cfg, true); err != nil {
+				log.Logger.Errorw("Failed to complete sql collection", "error", err)
+			}
 		}
 		return
 	}
@@ -81,10 +144,24 @@ func main() {
 		return sqlCollection(ctx, tmpPath, logPrefix, cfg, onetime)
 	}
 
+	// Warn early if collected data cannot be sent to workload manager.
+	if flags.Action == "run" {
+		go agent.ProbeWLM(ctx, cfg)
+	}
+
+	// Only the collection types selected by the --collect flag run as part of the service.
+	var osService, sqlService func()
+	if flags.CollectOS() {
+		osService = func() { agent.CollectionService(configPath, osCollectionFunc, agent.OS) }
+	}
+	if flags.CollectSQL() {
+		sqlService = func() { agent.CollectionService(configPath, sqlCollectionFunc, agent.SQL) }
+	}
+
 	s, err := daemon.CreateService(
-		func() { agent.CollectionService(configPath, osCollectionFunc, agent.OS) },
-		func() { agent.CollectionService(configPath, sqlCollectionFunc, agent.SQL) },
-		daemon.CreateConfig(agent.ServiceName, agent.ServiceDisplayName, agent.Description),
+		osService,
+		sqlService,
+		daemon.CreateConfig(agent.ServiceName, agent.ServiceDisplayName, agent.Description, flags.ServiceArgs()...),
 		agent.UsageMetricsLogger)
 
 	if err != nil {
@@ -96,10 +173,14 @@ func main() {
 	}
 }
 
-func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) error {
+func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) (err error) {
 	if !cfg.GetCollectionConfiguration().GetCollectGuestOsMetrics() {
 		return nil
 	}
+	ctx, span := agent.StartCycleSpan(ctx, agent.OS)
+	defer span.End()
+	ctx, cycle := agent.StartCycle(ctx, cfg)
+	defer func() { agent.EndCycle(cycle, cfg, agent.OS, readinessDir, err) }()
 
 	if cfg.GetRemoteCollection() {
 		return fmt.Errorf("remote collection from a linux vm is not supported; please use a windows vm to collect on other remote machines or turn off the remote collection flag")
@@ -109,13 +190,17 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 		return fmt.Errorf("empty credentials")
 	}
 
-	wlm, err := agent.InitCollection(ctx)
+	// The password of the SOCKS5 proxy, if any, is read once per cycle.
+	if ctx, err = agent.WithOutboundDialer(ctx, cfg); err != nil {
+		return err
+	}
+	wlm, err := agent.InitCollection(ctx, cfg)
 	if err != nil {
 		return err
 	}
 
 	if !onetime {
-		if err := agent.CheckAgentStatus(wlm, path); err != nil {
+		if err := agent.CheckAgentStatus(wlm, path, cfg); err != nil {
 			return err
 		}
 	}
@@ -128,34 +213,49 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 		return err
 	}
 
-	sourceInstanceProps := agent.SourceInstanceProperties()
+	sourceInstanceProps, err := agent.SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
 	targetInstanceProps := sourceInstanceProps
 	disks, err := agent.AllDisks(ctx, targetInstanceProps)
 	if err != nil {
 		return fmt.Errorf("Failed to collect disk info: %w", err)
 	}
 
-	c := guestcollector.NewLinuxCollector(disks, "", "", "", false, 22, agent.UsageMetricsLogger)
+	c := agent.NewLinuxCollector(cfg, disks, "", "", "", false, 22)
 	timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
-	details := agent.RunOSCollection(ctx, c, timeout)
-	agent.UpdateCollectedData(wlm, sourceInstanceProps, targetInstanceProps, details)
+	instanceCtx, instanceSpan := agent.StartInstanceSpan(ctx, targetInstanceProps.Instance)
+	details := agent.RunOSCollection(instanceCtx, c, timeout, cfg)
+	cycle.Instance(nil)
+	agent.AddPowerPlanRecommended(details, cfg)
+	details = agent.AddAgentResourceUsage(details, cfg)
+	instanceSpan.End()
+	agent.UpdateCollectedData(cfg, wlm, sourceInstanceProps, targetInstanceProps, details)
+	agent.RecordCollectedData(ctx, cfg, wlm, targetInstanceProps)
 
 	if onetime {
 		target := "localhost"
-		agent.PersistCollectedData(wlm, filepath.Join(filepath.Dir(logPrefix), fmt.Sprintf("%s-%s.json", target, "guest")))
+		agent.PersistCollectedData(ctx, wlm, agent.OutputFile(filepath.Dir(logPrefix), target, agent.OS), cfg, agent.NewSigningKey(cfg))
 	} else {
 		log.Logger.Debugf("Source vm %s is sending os collected data on target machine, %s, to workload manager.", sourceInstanceProps.Instance, targetInstanceProps.Instance)
-		interval := time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second
-		agent.SendRequestToWLM(wlm, sourceInstanceProps.Name, cfg.GetMaxRetries(), interval)
+		agent.SendRequestToWLM(wlm, agent.NewWLMSendOptions(cfg, agent.WLMLocation(cfg, sourceInstanceProps)))
+		agent.PublishCollectedData(ctx, cfg, wlm, agent.OS, sourceInstanceProps, targetInstanceProps)
+		agent.PostCollectedData(ctx, cfg, wlm, agent.OS, sourceInstanceProps, targetInstanceProps)
+		agent.WriteCollectedData(cfg, wlm, agent.OS, targetInstanceProps)
 	}
 	log.Logger.Info("Guest os rules collection ends.")
 	return nil
 }
 
-func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) error {
+func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) (err error) {
 	if !cfg.GetCollectionConfiguration().GetCollectSqlMetrics() {
 		return nil
 	}
+	ctx, span := agent.StartCycleSpan(ctx, agent.SQL)
+	defer span.End()
+	ctx, cycle := agent.StartCycle(ctx, cfg)
+	defer func() { agent.EndCycle(cycle, cfg, agent.SQL, readinessDir, err) }()
 	if cfg.GetRemoteCollection() {
 		return fmt.Errorf("remote collection from a linux vm is not supported; please use a windows vm to collect on other remote machines or turn off the remote collection flag")
 	}
@@ -163,49 +263,114 @@ func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Co
 		return fmt.Errorf("empty credentials")
 	}
 
-	wlm, err := agent.InitCollection(ctx)
+	// The password of the SOCKS5 proxy, if any, is read once per cycle.
+	if ctx, err = agent.WithOutboundDialer(ctx, cfg); err != nil {
+		return err
+	}
+	wlm, err := agent.InitCollection(ctx, cfg)
 	if err != nil {
 		return err
 	}
 
 	if !onetime {
-		if err := agent.CheckAgentStatus(wlm, path); err != nil {
+		if err := agent.CheckAgentStatus(wlm, path, cfg); err != nil {
 			return err
 		}
 	}
 
+	dialer, err := agent.OutboundDialer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	settings := agent.RuleSettings(cfg)
 	log.Logger.Info("Sql rules collection starts.")
-	for _, credentialCfg := range cfg.GetCredentialConfiguration() {
+	sourceInstanceProps, err := agent.SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
+	signingKey := agent.NewSigningKey(cfg)
+	budget := agent.SQLCycleBudget(cfg)
+	for _, credentialCfg := range agent.SQLCredentials(cfg) {
 		validationDetails := agent.InitDetails()
-		sourceInstanceProps := agent.SourceInstanceProperties()
 		guestCfg := agent.GuestConfigFromCredential(credentialCfg)
 		for _, sqlCfg := range agent.SQLConfigFromCredential(credentialCfg) {
+			deadline, ok := agent.SQLInstanceDeadline(budget, sqlCfg)
+			if !ok {
+				continue
+			}
 			if err := agent.ValidateCredCfgSQL(false, !guestCfg.LinuxRemote, sqlCfg, guestCfg, credentialCfg.GetInstanceId(), credentialCfg.GetInstanceName()); err != nil {
 				log.Logger.Errorw("Invalid credential configuration", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.InvalidConfigurationsError)
+				cycle.Instance(err)
 				continue
 			}
-			pswd, err := agent.SecretValue(ctx, sourceInstanceProps.ProjectID, sqlCfg.SecretName)
+			pswds, err := agent.SQLPasswords(ctx, cfg, sourceInstanceProps.ProjectID, sqlCfg)
 			if err != nil {
 				log.Logger.Errorw("Failed to get secret value", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.SecretValueError)
+				cycle.Instance(err)
+				continue
+			}
+			sqlDialer, closeDialer, err := agent.SQLDialer(ctx, sqlCfg, dialer)
+			if err != nil && sqlCfg.ProxyEndpoint != "" {
+				log.Logger.Errorw("Failed to set up the IAM proxy connection", "proxy", sqlCfg.ProxyEndpoint, "identity", sqlCfg.ProxyIAMIdentity, "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.IAMProxyError)
+				cycle.Instance(err)
+				continue
+			}
+			if err != nil {
+				log.Logger.Errorw("Failed to connect to the bastion host", "bastion", sqlCfg.BastionHost, "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.SSHDialError)
+				cycle.Instance(err)
+				continue
+			}
+			conn, err := agent.AuthenticatedConnectionString(ctx, cfg, sqlCfg, pswds, false, sqlDialer)
+			if err != nil {
+				log.Logger.Errorw("Failed to log in to SQL Server", "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.SQLLoginError)
+				closeDialer()
+				cycle.Instance(err)
 				continue
 			}
-			conn := fmt.Sprintf("server=%s;user id=%s;password=%s;port=%d;", sqlCfg.Host, sqlCfg.Username, pswd, sqlCfg.PortNumber)
 			timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
-			details, err := agent.RunSQLCollection(ctx, conn, timeout, false)
+			instanceCtx, cancel := context.WithDeadline(ctx, deadline)
+			instanceCtx, instanceSpan := agent.StartInstanceSpan(instanceCtx, fmt.Sprintf("%s:%d", sqlCfg.Host, sqlCfg.PortNumber))
+			details, err := agent.RunSQLCollection(instanceCtx, conn, agent.SQLCollectionOptions{
+				Timeout:         timeout,
+				Windows:         false,
+				Dialer:          sqlDialer,
+				DatabaseInclude: sqlCfg.DatabaseInclude,
+				DatabaseExclude: sqlCfg.DatabaseExclude,
+				WorkloadType:    sqlCfg.WorkloadType,
+				Settings:        settings,
+				Pool:            agent.SQLPool(cfg, sqlCfg, onetime),
+			})
+			agent.EndSpan(instanceSpan, err)
+			cancel()
+			closeDialer()
 			if err != nil {
 				log.Logger.Errorw("Failed to run sql collection", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.SQLCollectionFailure)
+				cycle.Instance(err)
 				continue
 			}
+			cycle.Instance(nil)
 			for _, detail := range details {
 				for _, field := range detail.Fields {
 					field["host_name"] = sqlCfg.Host
 					field["port_number"] = fmt.Sprintf("%d", sqlCfg.PortNumber)
 				}
 			}
+			agent.AddFailoverClusterInstance(details, sqlCfg)
 			agent.AddPhysicalDriveLocal(ctx, details, false)
+			details = agent.AddDataLogSameDisk(details)
+			details = agent.AddSQLVolumeFreeSpace(details, settings.DiskFreeSpaceThresholdPercent, func(paths []string) ([]internal.Volume, error) {
+				return agent.LinuxVolumes(ctx, paths)
+			})
+			agent.AddVMVCPUCount(details, func() (int64, error) {
+				return agent.LinuxVCPUs(ctx)
+			})
 
 			for i, detail := range details {
 				for _, vd := range validationDetails {
@@ -219,14 +384,17 @@ func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Co
 			validationDetails = details
 		}
 		targetInstanceProps := sourceInstanceProps
-		agent.UpdateCollectedData(wlm, sourceInstanceProps, targetInstanceProps, validationDetails)
+		agent.UpdateCollectedData(cfg, wlm, sourceInstanceProps, targetInstanceProps, validationDetails)
+		agent.RecordCollectedData(ctx, cfg, wlm, targetInstanceProps)
 
 		if onetime {
-			agent.PersistCollectedData(wlm, filepath.Join(filepath.Dir(logPrefix), fmt.Sprintf("%s-%s.json", targetInstanceProps.Instance, "sql")))
+			agent.PersistCollectedData(ctx, wlm, agent.OutputFile(filepath.Dir(logPrefix), targetInstanceProps.Instance, agent.SQL), cfg, signingKey)
 		} else {
 			log.Logger.Debugf("Source vm %s is sending collected sql data on target machine, %s, to workload manager.", sourceInstanceProps.Instance, targetInstanceProps.Instance)
-			interval := time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second
-			agent.SendRequestToWLM(wlm, sourceInstanceProps.Name, cfg.GetMaxRetries(), interval)
+			agent.SendRequestToWLM(wlm, agent.NewWLMSendOptions(cfg, agent.WLMLocation(cfg, sourceInstanceProps)))
+			agent.PublishCollectedData(ctx, cfg, wlm, agent.SQL, sourceInstanceProps, targetInstanceProps)
+			agent.PostCollectedData(ctx, cfg, wlm, agent.SQL, sourceInstanceProps, targetInstanceProps)
+			agent.WriteCollectedData(cfg, wlm, agent.SQL, targetInstanceProps)
 		}
 	}
 	log.Logger.Info("Sql rules collection ends.")
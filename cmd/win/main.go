@@ -25,11 +25,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	_ "github.com/microsoft/go-mssqldb"
+	// Registers the named pipes and shared memory transports of local_transport.
+	_ "github.com/microsoft/go-mssqldb/namedpipe"
+	_ "github.com/microsoft/go-mssqldb/sharedmemory"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 	"github.com/GoogleCloudPlatform/sql-server-agent/cmd/agent"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/daemon"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/guestcollector"
@@ -44,6 +49,16 @@ func main() {
 	if !proceed {
 		return
 	}
+	// diff of two persisted collections
+	if flags.Diff {
+		res, err := agent.Diff(flags)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(res)
+		return
+	}
 
 	ctx := context.Background()
 	logPrefix := filepath.Join(
@@ -70,14 +85,54 @@ func main() {
 	if err != nil {
 		log.Logger.Errorw("Failed to load configuration. Using default configurations", "error", err)
 	}
+	agent.MetadataSetup(cfg)
 	agent.LoggingSetup(ctx, logPrefix, cfg)
+	agent.ResourceLimitsSetup(cfg)
+	agent.DiskClassifierSetup(cfg)
+	agent.WMIQueryRateSetup(cfg)
+	defer agent.TracingSetup(ctx, cfg)()
+	// verification of the signatures of persisted collections
+	if flags.Verify {
+		res, err := agent.VerifyCollectedData(ctx, cfg, flags.VerifyFiles)
+		fmt.Print(res)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	// single rule run for debugging
+	if flags.RunRule != "" {
+		guestCollector := func() (guestcollector.GuestCollector, error) {
+			return guestcollector.NewWindowsCollector(nil, nil, nil, agent.UsageMetricsLogger), nil
+		}
+		res, err := agent.RunRule(ctx, cfg, flags.RunRule, true, guestCollector)
+		if err != nil {
+			log.Logger.Errorw("Failed to run rule", "rule", flags.RunRule, "error", err)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(res)
+		return
+	}
+	// synthetic data for demos and load tests
+	if flags.Synthetic > 0 {
+		if err := agent.SyntheticCollection(ctx, cfg, flags.Synthetic, flags.SyntheticSeed); err != nil {
+			log.Logger.Errorw("Failed to send synthetic collected data", "error", err)
+		}
+		return
+	}
 	// onetime collection
 	if flags.Onetime {
-		if err := osCollection(ctx, p, logPrefix, cfg, true); err != nil {
-			log.Logger.Errorw("Failed to complete os collection", "error", err)
+		if flags.CollectOS() {
+			if err := osCollection(ctx, p, logPrefix, cfg, true); err != nil {
+				log.Logger.Errorw("Failed to complete os collection", "error", err)
+			}
 		}
-		if err := sqlCollection(ctx, p, logPrefix, cfg, true); err != nil {
-			log.Logger.Errorw("Failed to complete sql collection", "error", err)
+		if flags.CollectSQL() {
+			if err := sqlCollection(ctx, p, logPrefix, cfg, true); err != nil {
+				log.Logger.Errorw("Failed to complete sql collection", "error", err)
+			}
 		}
 		return
 	}
@@ -90,10 +145,24 @@ func main() {
 		return sqlCollection(ctx, p, logPrefix, cfg, onetime)
 	}
 
+	// Warn early if collected data cannot be sent to workload manager.
+	if flags.Action == "run" {
+		go agent.ProbeWLM(ctx, cfg)
+	}
+
+	// Only the collection types selected by the --collect flag run as part of the service.
+	var osService, sqlService func()
+	if flags.CollectOS() {
+		osService = func() { agent.CollectionService(p, osCollectionFunc, agent.OS) }
+	}
+	if flags.CollectSQL() {
+		sqlService = func() { agent.CollectionService(p, sqlCollectionFunc, agent.SQL) }
+	}
+
 	s, err := daemon.CreateService(
-		func() { agent.CollectionService(p, osCollectionFunc, agent.OS) },
-		func() { agent.CollectionService(p, sqlCollectionFunc, agent.SQL) },
-		daemon.CreateConfig(agent.ServiceName, agent.ServiceDisplayName, agent.Description),
+		osService,
+		sqlService,
+		daemon.CreateConfig(agent.ServiceName, agent.ServiceDisplayName, agent.Description, flags.ServiceArgs()...),
 		agent.UsageMetricsLogger)
 
 	if err != nil {
@@ -105,33 +174,47 @@ func main() {
 	}
 }
 
-func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) error {
+func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) (err error) {
 	if !cfg.GetCollectionConfiguration().GetCollectGuestOsMetrics() {
 		return nil
 	}
+	ctx, span := agent.StartCycleSpan(ctx, agent.OS)
+	defer span.End()
+	ctx, cycle := agent.StartCycle(ctx, cfg)
+	defer func() { agent.EndCycle(cycle, cfg, agent.OS, filepath.Dir(path), err) }()
 	if cfg.GetCredentialConfiguration() == nil || len(cfg.GetCredentialConfiguration()) == 0 {
 		return fmt.Errorf("empty credentials")
 	}
-	wlm, err := agent.InitCollection(ctx)
+	// The password of the SOCKS5 proxy, if any, is read once per cycle.
+	if ctx, err = agent.WithOutboundDialer(ctx, cfg); err != nil {
+		return err
+	}
+	wlm, err := agent.InitCollection(ctx, cfg)
 	if err != nil {
 		return err
 	}
 	if !onetime {
-		if err := agent.CheckAgentStatus(wlm, path); err != nil {
+		if err := agent.CheckAgentStatus(wlm, path, cfg); err != nil {
 			return err
 		}
 	}
 
-	sourceInstanceProps := agent.SourceInstanceProperties()
+	sourceInstanceProps, err := agent.SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
+	signingKey := agent.NewSigningKey(cfg)
 	timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
-	interval := time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second
 
 	log.Logger.Info("Guest rules collection starts.")
+	// persisted are the files written by a one-time remote collection, which are archived together.
+	var persisted []string
 	for _, credentialCfg := range cfg.GetCredentialConfiguration() {
 		guestCfg := agent.GuestConfigFromCredential(credentialCfg)
 		if err := agent.ValidateCredCfgGuest(cfg.GetRemoteCollection(), !guestCfg.LinuxRemote, guestCfg, credentialCfg.GetInstanceId(), credentialCfg.GetInstanceName()); err != nil {
 			log.Logger.Errorw("Invalid credential configuration", "error", err)
 			agent.UsageMetricsLogger.Error(agentstatus.InvalidConfigurationsError)
+			cycle.Instance(err)
 			if !cfg.GetRemoteCollection() {
 				break
 			}
@@ -150,10 +233,11 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 			username := guestCfg.GuestUserName
 			if !guestCfg.LinuxRemote {
 				log.Logger.Debug("Starting remote win guest collection for ip " + host)
-				pswd, err := agent.SecretValue(ctx, sourceInstanceProps.ProjectID, guestCfg.GuestSecretName)
+				pswd, err := agent.SecretValue(ctx, cfg, sourceInstanceProps.ProjectID, guestCfg.GuestSecretName)
 				if err != nil {
 					log.Logger.Errorw("Collection failed", "target", guestCfg.ServerName, "error", fmt.Errorf("failed to get secret value: %v", err))
 					agent.UsageMetricsLogger.Error(agentstatus.SecretValueError)
+					cycle.Instance(err)
 					if !cfg.GetRemoteCollection() {
 						break
 					}
@@ -164,7 +248,7 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 				// on local windows vm collecting on remote linux vm's, we use ssh, otherwise we use wmi
 				log.Logger.Debug("Starting remote linux guest collection for ip " + host)
 				// disks only used for local linux collection
-				c = guestcollector.NewLinuxCollector(nil, host, username, guestCfg.LinuxSSHPrivateKeyPath, true, guestCfg.GuestPortNumber, agent.UsageMetricsLogger)
+				c = agent.NewLinuxCollector(cfg, nil, host, username, guestCfg.LinuxSSHPrivateKeyPath, true, guestCfg.GuestPortNumber)
 			}
 		} else {
 			// local win collection
@@ -172,8 +256,14 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 			c = guestcollector.NewWindowsCollector(nil, nil, nil, agent.UsageMetricsLogger)
 		}
 
-		details := agent.RunOSCollection(ctx, c, timeout)
-		agent.UpdateCollectedData(wlm, sourceInstanceProps, targetInstanceProps, details)
+		instanceCtx, instanceSpan := agent.StartInstanceSpan(ctx, targetInstanceProps.Instance)
+		details := agent.RunOSCollection(instanceCtx, c, timeout, cfg)
+		cycle.Instance(nil)
+		agent.AddPowerPlanRecommended(details, cfg)
+		details = agent.AddAgentResourceUsage(details, cfg)
+		instanceSpan.End()
+		agent.UpdateCollectedData(cfg, wlm, sourceInstanceProps, targetInstanceProps, details)
+		agent.RecordCollectedData(ctx, cfg, wlm, targetInstanceProps)
 		log.Logger.Debug("Finished guest collection")
 
 		if onetime {
@@ -181,10 +271,16 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 			if cfg.GetRemoteCollection() {
 				target = credentialCfg.GetInstanceName()
 			}
-			agent.PersistCollectedData(wlm, filepath.Join(filepath.Dir(logPrefix), fmt.Sprintf("%s-%s.json", target, "guest")))
+			p := agent.OutputFile(filepath.Dir(logPrefix), target, agent.OS)
+			if err := agent.PersistCollectedData(ctx, wlm, p, cfg, signingKey); err == nil && cfg.GetRemoteCollection() {
+				persisted = append(persisted, p)
+			}
 		} else {
 			log.Logger.Debugf("Source vm %s is sending os collected data on target machine, %s, to workload manager.", sourceInstanceProps.Instance, targetInstanceProps.Instance)
-			agent.SendRequestToWLM(wlm, sourceInstanceProps.Name, cfg.GetMaxRetries(), interval)
+			agent.SendRequestToWLM(wlm, agent.NewWLMSendOptions(cfg, agent.WLMLocation(cfg, sourceInstanceProps)))
+			agent.PublishCollectedData(ctx, cfg, wlm, agent.OS, sourceInstanceProps, targetInstanceProps)
+			agent.PostCollectedData(ctx, cfg, wlm, agent.OS, sourceInstanceProps, targetInstanceProps)
+			agent.WriteCollectedData(cfg, wlm, agent.OS, targetInstanceProps)
 		}
 		// Local collection.
 		// Exit the loop. Only take the first credential in the credentialconfiguration array.
@@ -192,56 +288,117 @@ func osCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Con
 			break
 		}
 	}
+	agent.ArchiveCollectedData(ctx, persisted, agent.OS, cfg, signingKey)
 	log.Logger.Info("Guest rules collection ends.")
 
 	return nil
 }
 
-func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) error {
+func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Configuration, onetime bool) (err error) {
 	if !cfg.GetCollectionConfiguration().GetCollectSqlMetrics() {
 		return nil
 	}
+	ctx, span := agent.StartCycleSpan(ctx, agent.SQL)
+	defer span.End()
+	ctx, cycle := agent.StartCycle(ctx, cfg)
+	defer func() { agent.EndCycle(cycle, cfg, agent.SQL, filepath.Dir(path), err) }()
 	if cfg.GetCredentialConfiguration() == nil || len(cfg.GetCredentialConfiguration()) == 0 {
 		return fmt.Errorf("empty credentials")
 	}
 
-	wlm, err := agent.InitCollection(ctx)
+	// The password of the SOCKS5 proxy, if any, is read once per cycle.
+	if ctx, err = agent.WithOutboundDialer(ctx, cfg); err != nil {
+		return err
+	}
+	wlm, err := agent.InitCollection(ctx, cfg)
 	if err != nil {
 		return err
 	}
 	if !onetime {
-		if err := agent.CheckAgentStatus(wlm, path); err != nil {
+		if err := agent.CheckAgentStatus(wlm, path, cfg); err != nil {
 			return err
 		}
 	}
 
-	sourceInstanceProps := agent.SourceInstanceProperties()
+	sourceInstanceProps, err := agent.SourceInstanceProperties()
+	if err != nil {
+		return err
+	}
+	signingKey := agent.NewSigningKey(cfg)
 	timeout := time.Duration(cfg.GetCollectionTimeoutSeconds()) * time.Second
-	interval := time.Duration(cfg.GetRetryIntervalInSeconds()) * time.Second
 
+	dialer, err := agent.OutboundDialer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	settings := agent.RuleSettings(cfg)
 	log.Logger.Info("SQL rules collection starts.")
-	for _, credentialCfg := range cfg.GetCredentialConfiguration() {
+	budget := agent.SQLCycleBudget(cfg)
+	for _, credentialCfg := range agent.SQLCredentials(cfg) {
 		validationDetails := agent.InitDetails()
 		guestCfg := agent.GuestConfigFromCredential(credentialCfg)
 		for _, sqlCfg := range agent.SQLConfigFromCredential(credentialCfg) {
+			deadline, ok := agent.SQLInstanceDeadline(budget, sqlCfg)
+			if !ok {
+				continue
+			}
 			if err := agent.ValidateCredCfgSQL(cfg.GetRemoteCollection(), !guestCfg.LinuxRemote, sqlCfg, guestCfg, credentialCfg.GetInstanceId(), credentialCfg.GetInstanceName()); err != nil {
 				log.Logger.Errorw("Invalid credential configuration", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.InvalidConfigurationsError)
+				cycle.Instance(err)
 				continue
 			}
-			pswd, err := agent.SecretValue(ctx, sourceInstanceProps.ProjectID, sqlCfg.SecretName)
+			pswds, err := agent.SQLPasswords(ctx, cfg, sourceInstanceProps.ProjectID, sqlCfg)
 			if err != nil {
 				log.Logger.Errorw("Failed to get secret value", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.SecretValueError)
+				cycle.Instance(err)
+				continue
+			}
+			sqlDialer, closeDialer, err := agent.SQLDialer(ctx, sqlCfg, dialer)
+			if err != nil && sqlCfg.ProxyEndpoint != "" {
+				log.Logger.Errorw("Failed to set up the IAM proxy connection", "proxy", sqlCfg.ProxyEndpoint, "identity", sqlCfg.ProxyIAMIdentity, "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.IAMProxyError)
+				cycle.Instance(err)
 				continue
 			}
-			conn := fmt.Sprintf("server=%s;user id=%s;password=%s;port=%d;", sqlCfg.Host, sqlCfg.Username, pswd, sqlCfg.PortNumber)
-			details, err := agent.RunSQLCollection(ctx, conn, timeout, !guestCfg.LinuxRemote)
+			if err != nil {
+				log.Logger.Errorw("Failed to connect to the bastion host", "bastion", sqlCfg.BastionHost, "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.SSHDialError)
+				cycle.Instance(err)
+				continue
+			}
+			conn, err := agent.AuthenticatedConnectionString(ctx, cfg, sqlCfg, pswds, !guestCfg.LinuxRemote, sqlDialer)
+			if err != nil {
+				log.Logger.Errorw("Failed to log in to SQL Server", "error", err)
+				agent.UsageMetricsLogger.Error(agentstatus.SQLLoginError)
+				closeDialer()
+				cycle.Instance(err)
+				continue
+			}
+			instanceCtx, cancel := context.WithDeadline(ctx, deadline)
+			instanceCtx, instanceSpan := agent.StartInstanceSpan(instanceCtx, fmt.Sprintf("%s:%d", sqlCfg.Host, sqlCfg.PortNumber))
+			details, err := agent.RunSQLCollection(instanceCtx, conn, agent.SQLCollectionOptions{
+				Timeout:         timeout,
+				Windows:         !guestCfg.LinuxRemote,
+				Dialer:          sqlDialer,
+				DatabaseInclude: sqlCfg.DatabaseInclude,
+				DatabaseExclude: sqlCfg.DatabaseExclude,
+				WorkloadType:    sqlCfg.WorkloadType,
+				Settings:        settings,
+				Pool:            agent.SQLPool(cfg, sqlCfg, onetime),
+			})
+			agent.EndSpan(instanceSpan, err)
+			cancel()
+			closeDialer()
 			if err != nil {
 				log.Logger.Errorw("Failed to run sql collection", "error", err)
 				agent.UsageMetricsLogger.Error(agentstatus.SQLCollectionFailure)
+				cycle.Instance(err)
 				continue
 			}
+			cycle.Instance(nil)
 
 			for _, detail := range details {
 				for _, field := range detail.Fields {
@@ -249,12 +406,29 @@ func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Co
 					field["port_number"] = fmt.Sprintf("%d", sqlCfg.PortNumber)
 				}
 			}
+			agent.AddFailoverClusterInstance(details, sqlCfg)
 
 			// getting physical drive if on local windows collecting sql on linux remote
 			if cfg.GetRemoteCollection() && guestCfg.LinuxRemote {
 				agent.AddPhysicalDriveRemoteLinux(details, guestCfg)
+				details = agent.AddDataLogSameDisk(details)
 			} else {
 				agent.AddPhysicalDriveLocal(ctx, details, true)
+				details = agent.AddDataLogSameDisk(details)
+				// The volumes, disks and processors of the instance are queried with a single collector,
+				// built on first use.
+				collector := sync.OnceValues(func() (*guestcollector.WindowsCollector, error) {
+					return windowsCollector(ctx, cfg, sourceInstanceProps.ProjectID, guestCfg.ServerName, guestCfg.GuestUserName, guestCfg.GuestSecretName)
+				})
+				details = agent.AddSQLVolumeAllocationUnits(details, func() ([]internal.Volume, error) {
+					return windowsVolumes(ctx, collector)
+				})
+				details = agent.AddSQLVolumeFreeSpace(details, settings.DiskFreeSpaceThresholdPercent, func([]string) ([]internal.Volume, error) {
+					return windowsLogicalDisks(ctx, collector)
+				})
+				agent.AddVMVCPUCount(details, func() (int64, error) {
+					return windowsLogicalProcessors(ctx, collector)
+				})
 			}
 
 			for i, detail := range details {
@@ -278,18 +452,66 @@ func sqlCollection(ctx context.Context, path, logPrefix string, cfg *configpb.Co
 				Instance:   credentialCfg.GetInstanceName(),
 			}
 		}
-		agent.UpdateCollectedData(wlm, sourceInstanceProps, targetInstanceProps, validationDetails)
+		agent.UpdateCollectedData(cfg, wlm, sourceInstanceProps, targetInstanceProps, validationDetails)
+		agent.RecordCollectedData(ctx, cfg, wlm, targetInstanceProps)
 		if onetime {
 			target := "localhost"
 			if cfg.GetRemoteCollection() {
 				target = targetInstanceProps.Instance
 			}
-			agent.PersistCollectedData(wlm, filepath.Join(filepath.Dir(logPrefix), fmt.Sprintf("%s-%s.json", target, "sql")))
+			agent.PersistCollectedData(ctx, wlm, agent.OutputFile(filepath.Dir(logPrefix), target, agent.SQL), cfg, signingKey)
 		} else {
 			log.Logger.Debugf("Source vm %s is sending collected sql data on target machine, %s, to workload manager.", sourceInstanceProps.Instance, targetInstanceProps.Instance)
-			agent.SendRequestToWLM(wlm, sourceInstanceProps.Name, cfg.GetMaxRetries(), interval)
+			agent.SendRequestToWLM(wlm, agent.NewWLMSendOptions(cfg, agent.WLMLocation(cfg, sourceInstanceProps)))
+			agent.PublishCollectedData(ctx, cfg, wlm, agent.SQL, sourceInstanceProps, targetInstanceProps)
+			agent.PostCollectedData(ctx, cfg, wlm, agent.SQL, sourceInstanceProps, targetInstanceProps)
+			agent.WriteCollectedData(cfg, wlm, agent.SQL, targetInstanceProps)
 		}
 	}
 	log.Logger.Info("SQL rules collection ends.")
 	return nil
 }
+
+// windowsVolumes returns the volumes of the windows machine running sql server, queried with the
+// collector returned by collector.
+func windowsVolumes(ctx context.Context, collector func() (*guestcollector.WindowsCollector, error)) ([]internal.Volume, error) {
+	c, err := collector()
+	if err != nil {
+		return nil, err
+	}
+	return c.Volumes(ctx)
+}
+
+// windowsLogicalDisks returns the local logical disks of the windows machine running sql server,
+// queried with the collector returned by collector.
+func windowsLogicalDisks(ctx context.Context, collector func() (*guestcollector.WindowsCollector, error)) ([]internal.Volume, error) {
+	c, err := collector()
+	if err != nil {
+		return nil, err
+	}
+	return c.LogicalDisks(ctx)
+}
+
+// windowsLogicalProcessors returns the number of logical processors of the windows machine running
+// sql server, queried with the collector returned by collector.
+func windowsLogicalProcessors(ctx context.Context, collector func() (*guestcollector.WindowsCollector, error)) (int64, error) {
+	c, err := collector()
+	if err != nil {
+		return 0, err
+	}
+	return c.LogicalProcessors(ctx)
+}
+
+// windowsCollector returns the collector of the windows machine running sql server. The collector
+// of the local machine is returned unless remote collection is enabled, in which case the password
+// of the machine is read from the secret.
+func windowsCollector(ctx context.Context, cfg *configpb.Configuration, projectID, host, username, secretName string) (*guestcollector.WindowsCollector, error) {
+	if !cfg.GetRemoteCollection() {
+		return guestcollector.NewWindowsCollector(nil, nil, nil, agent.UsageMetricsLogger), nil
+	}
+	pswd, err := agent.SecretValue(ctx, cfg, projectID, secretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret value: %v", err)
+	}
+	return guestcollector.NewWindowsCollector(host, username, pswd, agent.UsageMetricsLogger), nil
+}
@@ -71,6 +71,16 @@ const (
 	WinGuestCollectionTimeout
 	LinuxGuestCollectionTimeout
 	MappingLocalLinuxDiskTypeTimeout
+	WorkloadManagerPermissionError
+	PubSubPublishError
+	IAMProxyError
+	WebhookError
+	TextfileError
+	SQLLockTimeoutError
+	DeadLetterError
+	GuestCycleFailure
+	SQLCycleFailure
+	SQLLoginError
 )
 
 // NewUsageMetricsLogger wraps NewLogger function from usagemetrics package.
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package agentusage reports the resource usage of the agent process, so that agents leaking or
+// misbehaving across a fleet can be spotted in the collected data.
+package agentusage
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// Usage is the resource usage of the agent process.
+type Usage struct {
+	CPUTime         time.Duration
+	RSSBytes        int64
+	Goroutines      int
+	OpenConnections int
+}
+
+// Collect returns the resource usage of the agent process along with the given number of open
+// SQL Server connections. Reading the process counters is cheap and does not sample over time.
+func Collect(openConnections int) (Usage, error) {
+	cpuTime, rssBytes, err := processUsage()
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to read the resource usage of the agent process: %w", err)
+	}
+	return Usage{
+		CPUTime:         cpuTime,
+		RSSBytes:        rssBytes,
+		Goroutines:      runtime.NumGoroutine(),
+		OpenConnections: openConnections,
+	}, nil
+}
+
+// Details returns the usage as details with a single row.
+func (u Usage) Details() internal.Details {
+	return internal.Details{
+		Name: internal.AgentResourceUsageName,
+		Fields: []map[string]string{{
+			"agent_version":    internal.AgentVersion,
+			"cpu_time_seconds": strconv.FormatFloat(u.CPUTime.Seconds(), 'f', 3, 64),
+			"rss_bytes":        strconv.FormatInt(u.RSSBytes, 10),
+			"goroutines":       strconv.Itoa(u.Goroutines),
+			"open_connections": strconv.Itoa(u.OpenConnections),
+		}},
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agentusage
+
+import (
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"testing"
+	"time"
+)
+
+func TestCollect(t *testing.T) {
+	got, err := Collect(3)
+	if err != nil {
+		t.Fatalf("Collect(3) returned error: %v", err)
+	}
+	if got.CPUTime < 0 {
+		t.Errorf("Collect(3) returned CPU time %v, want at least 0", got.CPUTime)
+	}
+	if got.RSSBytes <= 0 {
+		t.Errorf("Collect(3) returned resident memory %d, want more than 0", got.RSSBytes)
+	}
+	if got.Goroutines <= 0 {
+		t.Errorf("Collect(3) returned %d goroutines, want more than 0", got.Goroutines)
+	}
+	if got.OpenConnections != 3 {
+		t.Errorf("Collect(3) returned %d open connections, want 3", got.OpenConnections)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	u := Usage{CPUTime: 1500 * time.Millisecond, RSSBytes: 64 << 20, Goroutines: 12, OpenConnections: 2}
+	got := u.Details()
+	if got.Name != internal.AgentResourceUsageName || len(got.Fields) != 1 {
+		t.Fatalf("Details() = %v, want a single row of %s", got, internal.AgentResourceUsageName)
+	}
+	want := map[string]string{
+		"cpu_time_seconds": "1.500",
+		"rss_bytes":        "67108864",
+		"goroutines":       "12",
+		"open_connections": "2",
+	}
+	for k, v := range want {
+		if got.Fields[0][k] != v {
+			t.Errorf("Details() field %s = %q, want %q", k, got.Fields[0][k], v)
+		}
+	}
+}
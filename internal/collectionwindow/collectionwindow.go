@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package collectionwindow restricts the collections of the agent to configured time windows,
+// e.g. the maintenance hours of the SQL Server instances.
+package collectionwindow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdays are the days of the week by name.
+var weekdays = map[string]time.Weekday{
+	"SUNDAY":    time.Sunday,
+	"MONDAY":    time.Monday,
+	"TUESDAY":   time.Tuesday,
+	"WEDNESDAY": time.Wednesday,
+	"THURSDAY":  time.Thursday,
+	"FRIDAY":    time.Friday,
+	"SATURDAY":  time.Saturday,
+}
+
+// Window is a daily time range on some days of the week. Start and End are offsets from
+// midnight. A window ending before or when it starts ends the next day, and belongs to the day it
+// starts on.
+type Window struct {
+	Days     map[time.Weekday]bool
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// Parse returns the window opening on the days at start and closing at end in the time zone.
+// The days are names of the days of the week, case-insensitive; no days means every day.
+// The times are in the 24-hour format "HH:MM". The time zone is an IANA time zone name and
+// defaults to UTC.
+func Parse(days []string, start, end, timeZone string) (Window, error) {
+	w := Window{Location: time.UTC}
+	for _, d := range days {
+		day, ok := weekdays[strings.ToUpper(strings.TrimSpace(d))]
+		if !ok {
+			return Window{}, fmt.Errorf("invalid day of the week %q", d)
+		}
+		if w.Days == nil {
+			w.Days = map[time.Weekday]bool{}
+		}
+		w.Days[day] = true
+	}
+	var err error
+	if w.Start, err = parseTimeOfDay(start); err != nil {
+		return Window{}, err
+	}
+	if w.End, err = parseTimeOfDay(end); err != nil {
+		return Window{}, err
+	}
+	if timeZone != "" {
+		if w.Location, err = time.LoadLocation(timeZone); err != nil {
+			return Window{}, fmt.Errorf("invalid time zone %q: %v", timeZone, err)
+		}
+	}
+	return w, nil
+}
+
+// parseTimeOfDay returns the offset from midnight of a time in the 24-hour format "HH:MM".
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// opensOn reports whether the window opens on the day.
+func (w Window) opensOn(day time.Weekday) bool {
+	return w.Days == nil || w.Days[day]
+}
+
+// opening returns when the window opens on the day of t, in the time zone of the window.
+func (w Window) opening(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute), 0, 0, w.Location)
+}
+
+// closing returns when the window opening on the day of t closes, in the time zone of the window.
+// The closing time is read from the wall clock, so a window spanning a daylight saving time
+// transition is open for an hour more or less than on other days.
+func (w Window) closing(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if w.End <= w.Start {
+		d++
+	}
+	return time.Date(y, m, d, int(w.End/time.Hour), int(w.End%time.Hour/time.Minute), 0, 0, w.Location)
+}
+
+// Contains reports whether the window is open at t.
+func (w Window) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	// The window open at t opened today or, if it ends the next day, yesterday.
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		if w.opensOn(day.Weekday()) && !t.Before(w.opening(day)) && t.Before(w.closing(day)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time at or after t the window is open.
+func (w Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	t = t.In(w.Location)
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, i)
+		if open := w.opening(day); w.opensOn(day.Weekday()) && open.After(t) {
+			return open
+		}
+	}
+	// Unreachable as every window opens on at least one day of the week.
+	return t
+}
+
+// Schedule is a set of windows. An empty schedule is always open.
+type Schedule []Window
+
+// Contains reports whether any window of the schedule is open at t.
+func (s Schedule) Contains(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, w := range s {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time at or after t any window of the schedule is open.
+func (s Schedule) Next(t time.Time) time.Time {
+	if len(s) == 0 {
+		return t
+	}
+	next := s[0].Next(t)
+	for _, w := range s[1:] {
+		if n := w.Next(t); n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectionwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testcases := []struct {
+		name     string
+		days     []string
+		start    string
+		end      string
+		timeZone string
+		wantErr  bool
+	}{
+		{
+			name:  "every day",
+			start: "22:00",
+			end:   "04:00",
+		},
+		{
+			name:     "days in a time zone",
+			days:     []string{"saturday", " SUNDAY "},
+			start:    "01:30",
+			end:      "05:00",
+			timeZone: "America/New_York",
+		},
+		{
+			name:    "invalid day",
+			days:    []string{"SAT"},
+			start:   "22:00",
+			end:     "04:00",
+			wantErr: true,
+		},
+		{
+			name:    "invalid start time",
+			start:   "24:00",
+			end:     "04:00",
+			wantErr: true,
+		},
+		{
+			name:    "missing end time",
+			start:   "22:00",
+			wantErr: true,
+		},
+		{
+			name:     "invalid time zone",
+			start:    "22:00",
+			end:      "04:00",
+			timeZone: "Mars/Olympus_Mons",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.days, tc.start, tc.end, tc.timeZone)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("Parse(%v, %q, %q, %q) returned error: %v, want error: %v", tc.days, tc.start, tc.end, tc.timeZone, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, days []string, start, end, timeZone string) Window {
+	t.Helper()
+	w, err := Parse(days, start, end, timeZone)
+	if err != nil {
+		t.Fatalf("Parse(%v, %q, %q, %q) returned error: %v", days, start, end, timeZone, err)
+	}
+	return w
+}
+
+func TestSchedule(t *testing.T) {
+	// The weekend nights in Berlin, which is one hour ahead of UTC in January.
+	weekend := Schedule{mustParse(t, []string{"SATURDAY", "SUNDAY"}, "22:00", "04:00", "Europe/Berlin")}
+	// 2024-01-06 is a Saturday.
+	saturday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 6, hour, minute, 0, 0, time.UTC)
+	}
+	testcases := []struct {
+		name         string
+		schedule     Schedule
+		now          time.Time
+		wantContains bool
+		wantNext     time.Time
+	}{
+		{
+			name:         "empty schedule",
+			now:          saturday(12, 0),
+			wantContains: true,
+			wantNext:     saturday(12, 0),
+		},
+		{
+			name:         "before the window",
+			schedule:     weekend,
+			now:          saturday(12, 0),
+			wantContains: false,
+			wantNext:     saturday(21, 0),
+		},
+		{
+			name:         "window opens",
+			schedule:     weekend,
+			now:          saturday(21, 0),
+			wantContains: true,
+			wantNext:     saturday(21, 0),
+		},
+		{
+			name:         "window continues the next day",
+			schedule:     weekend,
+			now:          saturday(23, 30),
+			wantContains: true,
+			wantNext:     saturday(23, 30),
+		},
+		{
+			name:         "window closes",
+			schedule:     weekend,
+			now:          saturday(27, 0),
+			wantContains: false,
+			wantNext:     saturday(45, 0),
+		},
+		{
+			name:         "window of sunday closes on monday",
+			schedule:     weekend,
+			now:          saturday(50, 0),
+			wantContains: true,
+			wantNext:     saturday(50, 0),
+		},
+		{
+			name:         "next week",
+			schedule:     weekend,
+			now:          saturday(51, 0),
+			wantContains: false,
+			wantNext:     saturday(7*24+21, 0),
+		},
+		{
+			name: "earliest window of the schedule",
+			schedule: Schedule{
+				weekend[0],
+				mustParse(t, nil, "13:00", "14:00", ""),
+			},
+			now:          saturday(12, 0),
+			wantContains: false,
+			wantNext:     saturday(13, 0),
+		},
+		{
+			name:         "window of a whole day",
+			schedule:     Schedule{mustParse(t, []string{"SUNDAY"}, "00:00", "00:00", "")},
+			now:          saturday(12, 0),
+			wantContains: false,
+			wantNext:     saturday(24, 0),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.schedule.Contains(tc.now); got != tc.wantContains {
+				t.Errorf("Contains(%v) = %v, want %v", tc.now, got, tc.wantContains)
+			}
+			if got := tc.schedule.Next(tc.now); !got.Equal(tc.wantNext) {
+				t.Errorf("Next(%v) = %v, want %v", tc.now, got, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestContainsDaylightSavingTime(t *testing.T) {
+	// The window closes at 04:00 by the wall clock of Berlin, also on the nights the clocks change.
+	weekend := mustParse(t, []string{"SATURDAY"}, "22:00", "04:00", "Europe/Berlin")
+	testcases := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{
+			// 2024-03-31 03:30 CEST, the clocks moved from 02:00 to 03:00.
+			name: "spring forward before closing",
+			now:  time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			// 2024-03-31 04:30 CEST.
+			name: "spring forward after closing",
+			now:  time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			// 2024-10-27 03:30 CET, the clocks moved from 03:00 back to 02:00.
+			name: "fall back before closing",
+			now:  time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			// 2024-10-27 04:30 CET.
+			name: "fall back after closing",
+			now:  time.Date(2024, 10, 27, 3, 30, 0, 0, time.UTC),
+			want: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := weekend.Contains(tc.now); got != tc.want {
+				t.Errorf("Contains(%v) = %v, want %v", tc.now, got, tc.want)
+			}
+		})
+	}
+}
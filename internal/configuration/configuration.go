@@ -18,21 +18,78 @@ limitations under the License.
 package configuration
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 
 	"google.golang.org/protobuf/encoding/protojson"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/collectionwindow"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/pubsub"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/webhook"
 	configpb "github.com/GoogleCloudPlatform/sql-server-agent/protos/sqlserveragentconfig"
 )
 
+// Lower bounds of the intervals and timeouts of the collection. Smaller values from the
+// configuration file are raised to these bounds so a misconfigured agent does not run collections
+// in a tight loop against SQL Server and workload manager.
+const (
+	MinCollectionTimeoutSeconds  = 5
+	MinRetryIntervalSeconds      = 10
+	MinCollectionIntervalSeconds = 10
+)
+
+// MinWLMPayloadBytes is the lower bound of max_wlm_payload_bytes, so that the collected data of
+// a rule is not split into a request per row.
+const MinWLMPayloadBytes = 64 << 10
+
+// wlmLocationRegex matches Google Cloud region names such as "us-central1".
+var wlmLocationRegex = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)+$`)
+
+// waitTypeRegex matches SQL Server wait type names such as "PAGEIOLATCH_SH".
+var waitTypeRegex = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// Transports of the connections to SQL Server running on the same machine as the agent.
+const (
+	LocalTransportTCP          = "tcp"
+	LocalTransportNamedPipes   = "np"
+	LocalTransportSharedMemory = "shared_memory"
+)
+
+// localTransportsSupported reports whether the driver supports named pipes and shared memory,
+// which are only available on windows. It is replaced in unit tests.
+var localTransportsSupported = runtime.GOOS == "windows"
+
 // SQLConfig .
 type SQLConfig struct {
-	Host       string
-	Username   string
-	SecretName string
-	PortNumber int32
+	Host                    string
+	Username                string
+	SecretName              string
+	SecretVersions          []string
+	PortNumber              int32
+	BastionHost             string
+	BastionUserName         string
+	BastionPrivateKeyPath   string
+	BastionPortNumber       int32
+	ConnectionStringExtra   string
+	ProxyEndpoint           string
+	ProxyIAMIdentity        string
+	FailoverClusterInstance bool
+	Encrypt                 string
+	TrustServerCertificate  bool
+	MinTLSVersion           string
+	LocalTransport          string
+	DatabaseInclude         []string
+	DatabaseExclude         []string
+	WorkloadType            string
 }
 
 // GuestConfig .
@@ -47,7 +104,8 @@ type GuestConfig struct {
 
 // LoadConfiguration loads configuration from config file.
 // Returns default configurations with error if reading configuration file has an error.
-// Returns nil with error if the configuration file is in invalid format.
+// Returns nil with error if the configuration file is in invalid format or none of its
+// collection windows is valid.
 func LoadConfiguration(p string) (*configpb.Configuration, error) {
 	// Read config file from file system.
 	b, err := os.ReadFile(filepath.Join(filepath.Dir(p), "configuration.json"))
@@ -58,6 +116,11 @@ func LoadConfiguration(p string) (*configpb.Configuration, error) {
 				CollectSqlMetrics:                         true,
 				GuestOsMetricsCollectionIntervalInSeconds: 3600,
 				SqlMetricsCollectionIntervalInSeconds:     3600,
+				VlfCountThreshold:                         1000,
+				DiskFreeSpaceThresholdPercent:             10,
+				ClockSkewThresholdSeconds:                 5,
+				LockTimeoutMilliseconds:                   5000,
+				TopQueries:                                10,
 			},
 			CredentialConfiguration: []*configpb.CredentialConfiguration{
 				&configpb.CredentialConfiguration{
@@ -71,29 +134,60 @@ func LoadConfiguration(p string) (*configpb.Configuration, error) {
 					},
 				},
 			},
-			LogLevel:                 "INFO",
-			LogToCloud:               true,
-			CollectionTimeoutSeconds: 10,
-			MaxRetries:               5,
-			RetryIntervalInSeconds:   3600,
+			LogLevel:                        "INFO",
+			LogToCloud:                      true,
+			CollectionTimeoutSeconds:        10,
+			MaxRetries:                      5,
+			RetryIntervalInSeconds:          3600,
+			OutputRetentionMaxFiles:         100,
+			OutputRetentionMaxAgeInDays:     30,
+			RepeatedErrorLogWindowInSeconds: 3600,
+			MaxWlmPayloadBytes:              10 << 20,
 		}, fmt.Errorf("failed to load the configuration file. filepath: %v, error: %v", p, err)
 	}
 	cfg := configpb.Configuration{}
 	if err := protojson.Unmarshal(b, &cfg); err != nil {
 		return nil, err
 	}
-	return validateConfigValues(&cfg), nil
+	windows := len(cfg.GetCollectionWindows())
+	config := validateConfigValues(&cfg)
+	// Collecting at any time would ignore the intent of the windows, so the configuration is
+	// rejected instead.
+	if windows > 0 && len(config.GetCollectionWindows()) == 0 {
+		return nil, errors.New("none of the collection_windows is valid")
+	}
+	return config, nil
 }
 
 // SQLConfigFromCredential returns config for SQL collection.
 func SQLConfigFromCredential(creCfg *configpb.CredentialConfiguration) []*SQLConfig {
 	var sqlConfigs []*SQLConfig
 	for _, sqlCfg := range creCfg.GetSqlConfigurations() {
+		encrypt := sqlCfg.GetTls().GetEncrypt()
+		if sqlCfg.GetTls() != nil && encrypt == "" {
+			encrypt = "true"
+		}
 		sqlConfigs = append(sqlConfigs, &SQLConfig{
-			Host:       sqlCfg.GetHost(),
-			Username:   sqlCfg.GetUserName(),
-			SecretName: sqlCfg.GetSecretName(),
-			PortNumber: sqlCfg.GetPortNumber(),
+			Host:                    sqlCfg.GetHost(),
+			Username:                sqlCfg.GetUserName(),
+			SecretName:              sqlCfg.GetSecretName(),
+			SecretVersions:          sqlCfg.GetSecretVersions(),
+			PortNumber:              sqlCfg.GetPortNumber(),
+			BastionHost:             sqlCfg.GetBastion().GetHost(),
+			BastionUserName:         sqlCfg.GetBastion().GetUserName(),
+			BastionPrivateKeyPath:   sqlCfg.GetBastion().GetPrivateKeyPath(),
+			BastionPortNumber:       sqlCfg.GetBastion().GetPortNumber(),
+			ConnectionStringExtra:   sqlCfg.GetConnectionStringExtra(),
+			ProxyEndpoint:           sqlCfg.GetIamProxy().GetEndpoint(),
+			ProxyIAMIdentity:        sqlCfg.GetIamProxy().GetIamIdentity(),
+			FailoverClusterInstance: sqlCfg.GetFailoverClusterInstance(),
+			Encrypt:                 encrypt,
+			TrustServerCertificate:  sqlCfg.GetTls().GetTrustServerCertificate(),
+			MinTLSVersion:           sqlCfg.GetTls().GetMinTlsVersion(),
+			LocalTransport:          sqlCfg.GetLocalTransport(),
+			DatabaseInclude:         sqlCfg.GetDatabaseInclude(),
+			DatabaseExclude:         sqlCfg.GetDatabaseExclude(),
+			WorkloadType:            creCfg.GetWorkloadType(),
 		})
 	}
 	return sqlConfigs
@@ -122,11 +216,13 @@ func GuestConfigFromCredential(creCfg *configpb.CredentialConfiguration) *GuestC
 
 // ValidateConfigValues verifies if the numeric values from the config file are valid.
 // If not, the default value will be set to the field.
+// Valid values below the lower bound of a field are raised to the lower bound.
 func validateConfigValues(config *configpb.Configuration) *configpb.Configuration {
 	fields := []struct {
 		name            string
 		defaultValue    int32
 		minValue        int32
+		lowerBound      int32
 		valueFromConfig int32
 		setDefaultValue func(int32)
 	}{
@@ -134,6 +230,7 @@ func validateConfigValues(config *configpb.Configuration) *configpb.Configuratio
 			name:            "collection_timeout_seconds",
 			defaultValue:    10,
 			minValue:        1,
+			lowerBound:      MinCollectionTimeoutSeconds,
 			valueFromConfig: config.GetCollectionTimeoutSeconds(),
 			setDefaultValue: func(defaultValue int32) {
 				config.CollectionTimeoutSeconds = defaultValue
@@ -152,15 +249,63 @@ func validateConfigValues(config *configpb.Configuration) *configpb.Configuratio
 			name:            "retry_interval_in_seconds",
 			defaultValue:    3600,
 			minValue:        1,
+			lowerBound:      MinRetryIntervalSeconds,
 			valueFromConfig: config.GetRetryIntervalInSeconds(),
 			setDefaultValue: func(defaultValue int32) {
 				config.RetryIntervalInSeconds = defaultValue
 			},
 		},
+		{
+			name:            "output_retention_max_files",
+			defaultValue:    100,
+			minValue:        1,
+			valueFromConfig: config.GetOutputRetentionMaxFiles(),
+			setDefaultValue: func(defaultValue int32) {
+				config.OutputRetentionMaxFiles = defaultValue
+			},
+		},
+		{
+			name:            "output_retention_max_age_in_days",
+			defaultValue:    30,
+			minValue:        1,
+			valueFromConfig: config.GetOutputRetentionMaxAgeInDays(),
+			setDefaultValue: func(defaultValue int32) {
+				config.OutputRetentionMaxAgeInDays = defaultValue
+			},
+		},
+		{
+			name:            "max_concurrent_collections",
+			defaultValue:    0,
+			minValue:        0,
+			valueFromConfig: config.GetMaxConcurrentCollections(),
+			setDefaultValue: func(defaultValue int32) {
+				config.MaxConcurrentCollections = defaultValue
+			},
+		},
+		{
+			name:            "max_wlm_payload_bytes",
+			defaultValue:    10 << 20,
+			minValue:        1,
+			lowerBound:      MinWLMPayloadBytes,
+			valueFromConfig: config.GetMaxWlmPayloadBytes(),
+			setDefaultValue: func(defaultValue int32) {
+				config.MaxWlmPayloadBytes = defaultValue
+			},
+		},
+		{
+			name:            "repeated_error_log_window_in_seconds",
+			defaultValue:    3600,
+			minValue:        1,
+			valueFromConfig: config.GetRepeatedErrorLogWindowInSeconds(),
+			setDefaultValue: func(defaultValue int32) {
+				config.RepeatedErrorLogWindowInSeconds = defaultValue
+			},
+		},
 		{
 			name:            "guest_os_metrics_collection_interval_in_seconds",
 			defaultValue:    3600,
 			minValue:        1,
+			lowerBound:      MinCollectionIntervalSeconds,
 			valueFromConfig: config.GetCollectionConfiguration().GetGuestOsMetricsCollectionIntervalInSeconds(),
 			setDefaultValue: func(defaultValue int32) {
 				config.GetCollectionConfiguration().GuestOsMetricsCollectionIntervalInSeconds = defaultValue
@@ -170,25 +315,432 @@ func validateConfigValues(config *configpb.Configuration) *configpb.Configuratio
 			name:            "sql_metrics_collection_interval_in_seconds",
 			defaultValue:    3600,
 			minValue:        1,
+			lowerBound:      MinCollectionIntervalSeconds,
 			valueFromConfig: config.GetCollectionConfiguration().GetSqlMetricsCollectionIntervalInSeconds(),
 			setDefaultValue: func(defaultValue int32) {
 				config.GetCollectionConfiguration().SqlMetricsCollectionIntervalInSeconds = defaultValue
 			},
 		},
+		{
+			name:            "vlf_count_threshold",
+			defaultValue:    1000,
+			minValue:        1,
+			valueFromConfig: config.GetCollectionConfiguration().GetVlfCountThreshold(),
+			setDefaultValue: func(defaultValue int32) {
+				config.GetCollectionConfiguration().VlfCountThreshold = defaultValue
+			},
+		},
+		{
+			name:            "disk_free_space_threshold_percent",
+			defaultValue:    10,
+			minValue:        1,
+			valueFromConfig: config.GetCollectionConfiguration().GetDiskFreeSpaceThresholdPercent(),
+			setDefaultValue: func(defaultValue int32) {
+				config.GetCollectionConfiguration().DiskFreeSpaceThresholdPercent = defaultValue
+			},
+		},
+		{
+			name:            "clock_skew_threshold_seconds",
+			defaultValue:    5,
+			minValue:        1,
+			valueFromConfig: config.GetCollectionConfiguration().GetClockSkewThresholdSeconds(),
+			setDefaultValue: func(defaultValue int32) {
+				config.GetCollectionConfiguration().ClockSkewThresholdSeconds = defaultValue
+			},
+		},
+		{
+			name:            "lock_timeout_milliseconds",
+			defaultValue:    5000,
+			minValue:        1,
+			valueFromConfig: config.GetCollectionConfiguration().GetLockTimeoutMilliseconds(),
+			setDefaultValue: func(defaultValue int32) {
+				config.GetCollectionConfiguration().LockTimeoutMilliseconds = defaultValue
+			},
+		},
+		{
+			name:            "top_queries",
+			defaultValue:    10,
+			minValue:        1,
+			valueFromConfig: config.GetCollectionConfiguration().GetTopQueries(),
+			setDefaultValue: func(defaultValue int32) {
+				config.GetCollectionConfiguration().TopQueries = defaultValue
+			},
+		},
 	}
 
 	for _, f := range fields {
 		if f.valueFromConfig < f.minValue {
 			log.Logger.Warnf("Invalid value for field %v. Using the default value %v", f.name, f.defaultValue)
 			f.setDefaultValue(f.defaultValue)
+			continue
+		}
+		if f.valueFromConfig < f.lowerBound {
+			log.Logger.Warnf("Value %v for field %v is below the minimum %v. Using the minimum value", f.valueFromConfig, f.name, f.lowerBound)
+			f.setDefaultValue(f.lowerBound)
+		}
+	}
+
+	if endpoint := config.GetWlmEndpoint(); endpoint != "" && !validEndpoint(endpoint) {
+		log.Logger.Warnf("Invalid value %q for field wlm_endpoint. Using the default endpoint", endpoint)
+		config.WlmEndpoint = ""
+	}
+	if location := config.GetWlmLocation(); location != "" && !wlmLocationRegex.MatchString(location) {
+		log.Logger.Warnf("Invalid value %q for field wlm_location. Using the region of the instance", location)
+		config.WlmLocation = ""
+	}
+	if endpoint := config.GetOtlpTracesEndpoint(); endpoint != "" && !validEndpoint(endpoint) {
+		log.Logger.Warnf("Invalid value %q for field otlp_traces_endpoint. Tracing is disabled", endpoint)
+		config.OtlpTracesEndpoint = ""
+	}
+	if topic := config.GetPubsub().GetTopic(); topic != "" && !pubsub.ValidTopic(topic) {
+		log.Logger.Warnf("Invalid value %q for field pubsub.topic. Publishing to Pub/Sub is disabled", topic)
+		config.Pubsub = nil
+	}
+	if sp := config.GetSecretProvider(); sp.GetMaxRetries() < 0 {
+		log.Logger.Warnf("Invalid value %d for field secret_provider.max_retries. Using the default value", sp.GetMaxRetries())
+		sp.MaxRetries = 0
+	}
+	if sp := config.GetSecretProvider(); sp.GetInitialRetryIntervalInMilliseconds() < 0 {
+		log.Logger.Warnf("Invalid value %d for field secret_provider.initial_retry_interval_in_milliseconds. Using the default value", sp.GetInitialRetryIntervalInMilliseconds())
+		sp.InitialRetryIntervalInMilliseconds = 0
+	}
+	if u := config.GetWebhook().GetUrl(); u != "" && !webhook.ValidURL(u) {
+		log.Logger.Warnf("Invalid value %q for field webhook.url. The webhook is disabled", u)
+		config.Webhook = nil
+	}
+	for _, c := range config.GetWebhook().GetConditions() {
+		if err := webhook.ValidCondition(WebhookCondition(c)); err != nil {
+			log.Logger.Warnf("Invalid value %v for field webhook.conditions: %v. The webhook is disabled", c, err)
+			config.Webhook = nil
+			break
+		}
+	}
+	if d := config.GetTextfileDirectory(); d != "" && !filepath.IsAbs(d) {
+		log.Logger.Warnf("Invalid value %q for field textfile_directory. The directory must be an absolute path; writing OpenMetrics files is disabled", d)
+		config.TextfileDirectory = ""
+	}
+	if v := config.GetMinTlsVersion(); v != "" && !validTLSVersions[v] {
+		log.Logger.Warnf("Invalid value %q for field min_tls_version. Using the minimum version %s", v, defaultMinTLSVersion)
+		config.MinTlsVersion = defaultMinTLSVersion
+	}
+	if f := config.GetOutputFormat(); f != "" && !validOutputFormats[f] {
+		log.Logger.Warnf("Invalid value %q for field output_format. Writing one file per host", f)
+		config.OutputFormat = ""
+	}
+	if sl := config.GetSqlite(); sl.GetPath() != "" && !filepath.IsAbs(sl.GetPath()) {
+		log.Logger.Warnf("Invalid value %q for field sqlite.path. The path must be an absolute path; recording the collected data in SQLite is disabled", sl.GetPath())
+		sl.Path = ""
+	}
+	if sl := config.GetSqlite(); sl.GetMaxAgeInDays() < 0 {
+		log.Logger.Warnf("Invalid value %d for field sqlite.max_age_in_days. Keeping all recorded data", sl.GetMaxAgeInDays())
+		sl.MaxAgeInDays = 0
+	}
+	if renames := config.GetFieldRenames(); len(renames) > 0 {
+		if err := internal.ValidateFieldRenames(FieldRenames(renames), internal.DetailFields()); err != nil {
+			log.Logger.Warnf("Invalid value for field field_renames: %v. The fields are not renamed", err)
+			config.FieldRenames = nil
+		}
+	}
+	if n := config.GetCollectionConfiguration().GetTopQueries(); n > maxTopQueries {
+		log.Logger.Warnf("Value %v for field top_queries is above the maximum %v. Using the maximum value", n, maxTopQueries)
+		config.GetCollectionConfiguration().TopQueries = maxTopQueries
+	}
+	if m := config.GetCollectionConfiguration().GetTopQueriesMetric(); m != "" && !validTopQueriesMetrics[m] {
+		log.Logger.Warnf("Invalid value %q for field top_queries_metric. Ranking the queries by worker time", m)
+		config.GetCollectionConfiguration().TopQueriesMetric = ""
+	}
+	if qps := config.GetCollectionConfiguration().GetWmiQueriesPerSecond(); qps < 0 {
+		log.Logger.Warnf("Invalid value %d for field wmi_queries_per_second. The WMI queries are not rate limited", qps)
+		config.GetCollectionConfiguration().WmiQueriesPerSecond = 0
+	}
+	if ttl := config.GetCollectionConfiguration().GetCachedRulesTtlInSeconds(); ttl < 0 {
+		log.Logger.Warnf("Invalid value %d for field cached_rules_ttl_in_seconds. The results of the rules are not cached", ttl)
+		config.GetCollectionConfiguration().CachedRulesTtlInSeconds = 0
+	}
+	if n := config.GetCollectionConfiguration().GetIndexFillFactorMinPages(); n < 0 {
+		log.Logger.Warnf("Invalid value %d for field index_fill_factor_min_pages. Using the default value", n)
+		config.GetCollectionConfiguration().IndexFillFactorMinPages = 0
+	}
+	if n := config.GetCollectionConfiguration().GetCheckdbMaxAgeInDays(); n < 0 {
+		log.Logger.Warnf("Invalid value %d for field checkdb_max_age_in_days. Using the default value", n)
+		config.GetCollectionConfiguration().CheckdbMaxAgeInDays = 0
+	}
+	if n := config.GetCollectionConfiguration().GetSqlConnectionIdleTimeoutInSeconds(); n < 0 {
+		log.Logger.Warnf("Invalid value %d for field sql_connection_idle_timeout_in_seconds. Using the default value", n)
+		config.GetCollectionConfiguration().SqlConnectionIdleTimeoutInSeconds = 0
+	}
+	if ms := config.GetMetadataServer(); ms.GetTimeoutInSeconds() < 0 {
+		log.Logger.Warnf("Invalid value %d for field metadata_server.timeout_in_seconds. Using the default value", ms.GetTimeoutInSeconds())
+		ms.TimeoutInSeconds = 0
+	}
+	if ms := config.GetMetadataServer(); ms.GetMaxRetries() < 0 {
+		log.Logger.Warnf("Invalid value %d for field metadata_server.max_retries. Using the default value", ms.GetMaxRetries())
+		ms.MaxRetries = 0
+	}
+	if rl := config.GetResourceLimits(); rl.GetMaxProcs() < 0 {
+		log.Logger.Warnf("Invalid value %d for field resource_limits.max_procs. Using all CPUs", rl.GetMaxProcs())
+		rl.MaxProcs = 0
+	}
+	if rl := config.GetResourceLimits(); rl.GetMemoryLimitMb() < 0 {
+		log.Logger.Warnf("Invalid value %d for field resource_limits.memory_limit_mb. The memory limit is disabled", rl.GetMemoryLimitMb())
+		rl.MemoryLimitMb = 0
+	}
+	if d := config.GetDeadLetter().GetDirectory(); d != "" && !filepath.IsAbs(d) {
+		log.Logger.Warnf("Invalid value %q for field dead_letter.directory. The directory must be an absolute path; the dead-letter directory is disabled", d)
+		config.DeadLetter = nil
+	}
+	if dl := config.GetDeadLetter(); dl.GetMaxSizeMb() < 0 {
+		log.Logger.Warnf("Invalid value %d for field dead_letter.max_size_mb. Using the default value", dl.GetMaxSizeMb())
+		dl.MaxSizeMb = 0
+	}
+	// A threshold of 0 would accept cycles collecting nothing, so it is rejected along with the
+	// values out of range when cycle_status is set.
+	if cs := config.GetCycleStatus(); cs != nil && (cs.GetMinInstanceSuccessPercent() < 1 || cs.GetMinInstanceSuccessPercent() > 100) {
+		log.Logger.Warnf("Invalid value %d for field cycle_status.min_instance_success_percent. Using the default value", cs.GetMinInstanceSuccessPercent())
+		cs.MinInstanceSuccessPercent = 0
+	}
+	if cs := config.GetCycleStatus(); cs != nil && (cs.GetMinRuleSuccessPercent() < 1 || cs.GetMinRuleSuccessPercent() > 100) {
+		log.Logger.Warnf("Invalid value %d for field cycle_status.min_rule_success_percent. Using the default value", cs.GetMinRuleSuccessPercent())
+		cs.MinRuleSuccessPercent = 0
+	}
+	for _, c := range config.GetCredentialConfiguration() {
+		if w := c.GetWorkloadType(); w != "" && !internal.ValidWorkloadType(w) {
+			log.Logger.Warnf("Invalid value %q for field workload_type. All rules run and the workload type is not reported", w)
+			c.WorkloadType = ""
 		}
 	}
+	config.DiskTypeMappings = validDiskTypeMappings(config.GetDiskTypeMappings())
+	config.CollectionWindows = validCollectionWindows(config.GetCollectionWindows())
+	if ignore := config.GetIgnore(); ignore != nil {
+		ignore.WaitTypes = validWaitTypes(ignore.GetWaitTypes())
+		ignore.ErrorNumbers = validErrorNumbers(ignore.GetErrorNumbers())
+	}
 
 	return config
 }
 
+// WebhookCondition returns the webhook condition of the configuration.
+func WebhookCondition(c *configpb.WebhookCondition) webhook.Condition {
+	return webhook.Condition{
+		Rule:     c.GetRule(),
+		Field:    c.GetField(),
+		Operator: c.GetOperator(),
+		Value:    c.GetValue(),
+	}
+}
+
+// FieldRenames returns the field renames of the configuration.
+func FieldRenames(renames []*configpb.FieldRename) []internal.FieldRename {
+	var res []internal.FieldRename
+	for _, r := range renames {
+		res = append(res, internal.FieldRename{Rule: r.GetRule(), Field: r.GetField(), Name: r.GetName()})
+	}
+	return res
+}
+
+// validWaitTypes returns the wait types in upper case. Invalid wait types are dropped.
+func validWaitTypes(waitTypes []string) []string {
+	var valid []string
+	for _, w := range waitTypes {
+		upper := strings.ToUpper(strings.TrimSpace(w))
+		if !waitTypeRegex.MatchString(upper) {
+			log.Logger.Warnf("Invalid value %q for field ignore.wait_types. The wait type is not ignored", w)
+			continue
+		}
+		valid = append(valid, upper)
+	}
+	return valid
+}
+
+// validDiskTypeMappings returns the disk type mappings with a valid pattern, a disk type and a
+// size multiple that is not negative. Other mappings are dropped.
+func validDiskTypeMappings(mappings []*configpb.DiskTypeMapping) []*configpb.DiskTypeMapping {
+	var valid []*configpb.DiskTypeMapping
+	for _, m := range mappings {
+		if _, err := regexp.Compile(m.GetFriendlyNamePattern()); err != nil {
+			log.Logger.Warnf("Invalid value %q for field disk_type_mappings.friendly_name_pattern: %v. The mapping is ignored", m.GetFriendlyNamePattern(), err)
+			continue
+		}
+		if strings.TrimSpace(m.GetDiskType()) == "" {
+			log.Logger.Warnf("Empty value for field disk_type_mappings.disk_type of pattern %q. The mapping is ignored", m.GetFriendlyNamePattern())
+			continue
+		}
+		if m.GetSizeMultipleBytes() < 0 {
+			log.Logger.Warnf("Invalid value %d for field disk_type_mappings.size_multiple_bytes. The mapping is ignored", m.GetSizeMultipleBytes())
+			continue
+		}
+		valid = append(valid, m)
+	}
+	return valid
+}
+
+// validCollectionWindows returns the collection windows with valid days, times and time zone.
+// Other windows are dropped.
+func validCollectionWindows(windows []*configpb.CollectionWindow) []*configpb.CollectionWindow {
+	var valid []*configpb.CollectionWindow
+	for _, w := range windows {
+		if _, err := collectionwindow.Parse(w.GetDays(), w.GetStartTime(), w.GetEndTime(), w.GetTimeZone()); err != nil {
+			log.Logger.Warnf("Invalid value for field collection_windows: %v. The window is ignored", err)
+			continue
+		}
+		valid = append(valid, w)
+	}
+	return valid
+}
+
+// validErrorNumbers returns the positive error numbers. Other error numbers are dropped.
+func validErrorNumbers(errorNumbers []int32) []int32 {
+	var valid []int32
+	for _, n := range errorNumbers {
+		if n <= 0 {
+			log.Logger.Warnf("Invalid value %d for field ignore.error_numbers. The error number is not ignored", n)
+			continue
+		}
+		valid = append(valid, n)
+	}
+	return valid
+}
+
+// validEndpoint returns true if the endpoint is an absolute http or https URL without query or fragment.
+func validEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// validTLSVersions are the supported values of min_tls_version.
+var validTLSVersions = map[string]bool{"1.0": true, "1.1": true, "1.2": true, "1.3": true}
+
+// defaultMinTLSVersion replaces an invalid min_tls_version so that a misspelled version does not
+// disable the minimum version.
+const defaultMinTLSVersion = "1.2"
+
+// validOutputFormats are the supported values of output_format.
+var validOutputFormats = map[string]bool{"json": true, "json+archive": true}
+
+// maxTopQueries bounds top_queries to keep the size of DB_TOP_QUERIES small.
+const maxTopQueries = 100
+
+// validTopQueriesMetrics are the supported values of top_queries_metric.
+var validTopQueriesMetrics = map[string]bool{"worker_time": true, "logical_reads": true}
+
+// managedConnectionKeys are the connection string parameters set by the agent, including their
+// aliases in go-mssqldb. They are not allowed in connection_string_extra.
+var managedConnectionKeys = map[string]bool{
+	"server":          true,
+	"data source":     true,
+	"address":         true,
+	"addr":            true,
+	"network address": true,
+	"user id":         true,
+	"user":            true,
+	"uid":             true,
+	"password":        true,
+	"pwd":             true,
+	"port":            true,
+}
+
+// connectionStringKeys returns the lowercase keys of the connection string parameters.
+func connectionStringKeys(conn string) map[string]bool {
+	keys := map[string]bool{}
+	for _, param := range strings.Split(conn, ";") {
+		key, _, _ := strings.Cut(param, "=")
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// validateConnectionStringExtra returns an error if the extra connection string parameters are
+// malformed or set a parameter managed by the agent.
+func validateConnectionStringExtra(extra string) error {
+	for _, param := range strings.Split(extra, ";") {
+		if strings.TrimSpace(param) == "" {
+			continue
+		}
+		key, _, ok := strings.Cut(param, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return fmt.Errorf("malformed connection string parameter %q", param)
+		}
+		if managedConnectionKeys[key] {
+			return fmt.Errorf("connection string parameter %q is managed by the agent", key)
+		}
+	}
+	return nil
+}
+
+// SQLConnectionString returns the connection string to the SQL Server of sqlCfg.
+// The parameters of connection_string_extra are appended after the parameters managed by the agent.
+// The user and password are omitted if the connection is made through an IAM proxy, which is
+// authenticated by access tokens instead.
+// If minTLSVersion is set, encryption is required with at least the version, overriding
+// connection_string_extra, so the connection fails instead of falling back to an unencrypted or
+// older connection. The server certificate is trusted as before unless connection_string_extra
+// configures the encryption. minTLSVersion is not applied to the local transports, which do not
+// leave the machine.
+// The encryption of the tls settings of sqlCfg overrides both minTLSVersion and the encryption
+// parameters of connection_string_extra.
+func SQLConnectionString(sqlCfg *SQLConfig, password, minTLSVersion string) string {
+	address := fmt.Sprintf("port=%d;", sqlCfg.PortNumber)
+	switch sqlCfg.LocalTransport {
+	case LocalTransportNamedPipes:
+		address = "protocol=np;"
+		// The pipe of a named instance is looked up with the SQL Server Browser.
+		if !strings.Contains(sqlCfg.Host, `\`) {
+			address += `pipe=sql\query;`
+		}
+		minTLSVersion = ""
+	case LocalTransportSharedMemory:
+		address = "protocol=lpc;"
+		minTLSVersion = ""
+	}
+	conn := fmt.Sprintf("server=%s;user id=%s;password=%s;%s", sqlCfg.Host, sqlCfg.Username, password, address)
+	if sqlCfg.ProxyEndpoint != "" {
+		conn = fmt.Sprintf("server=%s;%s", sqlCfg.Host, address)
+	}
+	extra := strings.Trim(strings.TrimSpace(sqlCfg.ConnectionStringExtra), ";")
+	if extra != "" {
+		conn += extra + ";"
+	}
+	if sqlCfg.Encrypt != "" {
+		conn += fmt.Sprintf("encrypt=%s;trustservercertificate=%t;", sqlCfg.Encrypt, sqlCfg.TrustServerCertificate)
+		if sqlCfg.MinTLSVersion != "" {
+			minTLSVersion = sqlCfg.MinTLSVersion
+		}
+		if sqlCfg.Encrypt != "disable" && minTLSVersion != "" {
+			conn += fmt.Sprintf("tlsmin=%s;", minTLSVersion)
+		}
+		return conn
+	}
+	if minTLSVersion == "" {
+		return conn
+	}
+	keys := connectionStringKeys(extra)
+	if !keys["encrypt"] && !keys["trustservercertificate"] {
+		conn += "trustservercertificate=true;"
+	}
+	return conn + fmt.Sprintf("encrypt=true;tlsmin=%s;", minTLSVersion)
+}
+
 // ValidateCredCfgSQL validates if the configuration file is valid for SQL collection.
 // Each CredentialConfiguration must provide valid "user_name", "secret_name" and "port_number".
+// "connection_string_extra" must not set a parameter managed by the agent.
+// "host" of a failover cluster instance must be its virtual network name and
+// "connection_string_extra" must not set ApplicationIntent, which is used for availability groups.
+// "tls.encrypt" must be "true", "false" or "disable" and "tls.min_tls_version" a supported version;
+// connection_string_extra must not set the encryption parameters along with them. Disabling the
+// encryption of a managed instance, such as Azure SQL, is logged as a warning.
+// "local_transport" must be "tcp", "np" or "shared_memory"; named pipes and shared memory are only
+// supported on windows for a local host, without remote collection, a bastion, an IAM proxy or a
+// failover cluster instance, and do not require "port_number".
+// "database_include" and "database_exclude" must not contain empty entries or malformed glob
+// patterns, and must not both list the same entry.
+// "iam_proxy.endpoint" and "iam_proxy.iam_identity" must be provided together; "user_name" and
+// "secret_name" are not required with an IAM proxy, which cannot be combined with a bastion.
 // If remote collection is enabled, the following fields must be provided:
 //
 //	"host", "instance_id", "instance_name"
@@ -196,21 +748,91 @@ func ValidateCredCfgSQL(remote, windows bool, sqlCfg *SQLConfig, guestCfg *Guest
 	errMsg := "invalid value for"
 	hasError := false
 
-	if sqlCfg.Username == "" {
+	proxy := sqlCfg.ProxyEndpoint != "" || sqlCfg.ProxyIAMIdentity != ""
+	if !proxy && sqlCfg.Username == "" {
 		errMsg = errMsg + ` "user_name"`
 		hasError = true
 	}
-	if sqlCfg.SecretName == "" {
+	if !proxy && sqlCfg.SecretName == "" {
 		errMsg = errMsg + ` "secret_name"`
 		hasError = true
 	}
-	if sqlCfg.PortNumber == 0 {
+	if proxy {
+		if sqlCfg.ProxyEndpoint == "" {
+			errMsg = errMsg + ` "iam_proxy.endpoint"`
+			hasError = true
+		}
+		if sqlCfg.ProxyIAMIdentity == "" {
+			errMsg = errMsg + ` "iam_proxy.iam_identity"`
+			hasError = true
+		}
+		if sqlCfg.BastionHost != "" {
+			errMsg = errMsg + ` "bastion"`
+			hasError = true
+		}
+	}
+	local := sqlCfg.LocalTransport == LocalTransportNamedPipes || sqlCfg.LocalTransport == LocalTransportSharedMemory
+	if sqlCfg.PortNumber == 0 && !local {
 		errMsg = errMsg + ` "port_number"`
 		hasError = true
 	}
+	if sqlCfg.LocalTransport != "" && sqlCfg.LocalTransport != LocalTransportTCP && !local {
+		errMsg = errMsg + ` "local_transport"`
+		hasError = true
+	}
+	if local && (remote || !localTransportsSupported || !localHost(sqlCfg.Host) || sqlCfg.BastionHost != "" || proxy || sqlCfg.FailoverClusterInstance) {
+		errMsg = errMsg + ` "local_transport"`
+		hasError = true
+	}
+	if sqlCfg.FailoverClusterInstance && !virtualNetworkName(sqlCfg.Host) {
+		errMsg = errMsg + ` "host"`
+		hasError = true
+	}
+	// ApplicationIntent only applies to the listeners of availability groups.
+	fciWithIntent := sqlCfg.FailoverClusterInstance && connectionStringKeys(sqlCfg.ConnectionStringExtra)["applicationintent"]
+	if fciWithIntent || (sqlCfg.ConnectionStringExtra != "" && validateConnectionStringExtra(sqlCfg.ConnectionStringExtra) != nil) {
+		errMsg = errMsg + ` "connection_string_extra"`
+		hasError = true
+	}
+	if sqlCfg.Encrypt != "" {
+		if !validEncryptValues[sqlCfg.Encrypt] {
+			errMsg = errMsg + ` "tls.encrypt"`
+			hasError = true
+		}
+		if sqlCfg.MinTLSVersion != "" && !validTLSVersions[sqlCfg.MinTLSVersion] {
+			errMsg = errMsg + ` "tls.min_tls_version"`
+			hasError = true
+		}
+		keys := connectionStringKeys(sqlCfg.ConnectionStringExtra)
+		if keys["encrypt"] || keys["trustservercertificate"] || keys["tlsmin"] {
+			errMsg = errMsg + ` "connection_string_extra"`
+			hasError = true
+		}
+		if (sqlCfg.Encrypt == "false" || sqlCfg.Encrypt == "disable") && managedHost(sqlCfg.Host) {
+			log.Logger.Warnw("Encryption is disabled for a managed SQL Server instance, which requires encrypted connections", "host", sqlCfg.Host, "encrypt", sqlCfg.Encrypt)
+		}
+	}
+	if !validDatabasePatterns(sqlCfg.DatabaseInclude) {
+		errMsg = errMsg + ` "database_include"`
+		hasError = true
+	}
+	if !validDatabasePatterns(sqlCfg.DatabaseExclude) || contradictoryDatabasePatterns(sqlCfg.DatabaseInclude, sqlCfg.DatabaseExclude) {
+		errMsg = errMsg + ` "database_exclude"`
+		hasError = true
+	}
+	if sqlCfg.BastionHost != "" {
+		if sqlCfg.BastionUserName == "" {
+			errMsg = errMsg + ` "bastion.user_name"`
+			hasError = true
+		}
+		if sqlCfg.BastionPrivateKeyPath == "" {
+			errMsg = errMsg + ` "bastion.private_key_path"`
+			hasError = true
+		}
+	}
 
 	if remote {
-		if sqlCfg.Host == "" {
+		if sqlCfg.Host == "" && !sqlCfg.FailoverClusterInstance {
 			errMsg = errMsg + ` "host"`
 			hasError = true
 		}
@@ -252,6 +874,71 @@ func ValidateCredCfgSQL(remote, windows bool, sqlCfg *SQLConfig, guestCfg *Guest
 	return nil
 }
 
+// validEncryptValues are the supported values of tls.encrypt.
+var validEncryptValues = map[string]bool{"true": true, "false": true, "disable": true}
+
+// localHost reports whether host is the machine of the agent, optionally with an instance name,
+// e.g. "localhost" or ".\SQLEXPRESS".
+func localHost(host string) bool {
+	host, _, _ = strings.Cut(host, `\`)
+	switch strings.ToLower(host) {
+	case "localhost", ".", "(local)":
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validDatabasePatterns reports whether the database names or glob patterns are not empty and
+// well formed.
+func validDatabasePatterns(patterns []string) bool {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); p == "" || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// contradictoryDatabasePatterns reports whether a database name or glob pattern is both included
+// and excluded, ignoring case.
+func contradictoryDatabasePatterns(include, exclude []string) bool {
+	for _, i := range include {
+		for _, e := range exclude {
+			if strings.EqualFold(i, e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// managedHostSuffixes are the DNS suffixes of managed SQL Server services, which require
+// encrypted connections.
+var managedHostSuffixes = []string{".database.windows.net", ".rds.amazonaws.com"}
+
+// managedHost reports whether host is an instance of a managed SQL Server service.
+func managedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, suffix := range managedHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualNetworkName reports whether host can be the virtual network name of a failover cluster
+// instance. The local host and IP addresses identify a node of the cluster rather than the
+// instance, which moves between the nodes.
+func virtualNetworkName(host string) bool {
+	switch strings.ToLower(host) {
+	case "", "localhost", ".", "(local)":
+		return false
+	}
+	return net.ParseIP(host) == nil
+}
+
 // ValidateCredCfgGuest validates if the configuration file is valid for guest collection.
 // If remote collection is enabled, the following fields must be provided:
 // "server_name", "guest_user_name", "guest_secret_name", "instance_id", "instance_name"
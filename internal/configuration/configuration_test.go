@@ -42,6 +42,11 @@ func TestLoadConfiguration(t *testing.T) {
 					GuestOsMetricsCollectionIntervalInSeconds: 30,
 					CollectSqlMetrics:                         true,
 					SqlMetricsCollectionIntervalInSeconds:     30,
+					VlfCountThreshold:                         1000,
+					DiskFreeSpaceThresholdPercent:             10,
+					ClockSkewThresholdSeconds:                 5,
+					LockTimeoutMilliseconds:                   5000,
+					TopQueries:                                10,
 				},
 				CredentialConfiguration: []*configpb.CredentialConfiguration{
 					&configpb.CredentialConfiguration{
@@ -58,9 +63,13 @@ func TestLoadConfiguration(t *testing.T) {
 						},
 					},
 				},
-				LogLevel:                 "DEBUG",
-				CollectionTimeoutSeconds: 30,
-				RetryIntervalInSeconds:   3600,
+				LogLevel:                        "DEBUG",
+				CollectionTimeoutSeconds:        30,
+				RetryIntervalInSeconds:          3600,
+				OutputRetentionMaxFiles:         100,
+				OutputRetentionMaxAgeInDays:     30,
+				RepeatedErrorLogWindowInSeconds: 3600,
+				MaxWlmPayloadBytes:              10 << 20,
 			},
 		},
 		{
@@ -78,6 +87,11 @@ func TestLoadConfiguration(t *testing.T) {
 					GuestOsMetricsCollectionIntervalInSeconds: 3600,
 					CollectSqlMetrics:                         true,
 					SqlMetricsCollectionIntervalInSeconds:     3600,
+					VlfCountThreshold:                         1000,
+					DiskFreeSpaceThresholdPercent:             10,
+					ClockSkewThresholdSeconds:                 5,
+					LockTimeoutMilliseconds:                   5000,
+					TopQueries:                                10,
 				},
 				CredentialConfiguration: []*configpb.CredentialConfiguration{
 					&configpb.CredentialConfiguration{
@@ -91,11 +105,15 @@ func TestLoadConfiguration(t *testing.T) {
 						},
 					},
 				},
-				LogLevel:                 "INFO",
-				CollectionTimeoutSeconds: 10,
-				LogToCloud:               true,
-				MaxRetries:               5,
-				RetryIntervalInSeconds:   3600,
+				LogLevel:                        "INFO",
+				CollectionTimeoutSeconds:        10,
+				LogToCloud:                      true,
+				MaxRetries:                      5,
+				RetryIntervalInSeconds:          3600,
+				OutputRetentionMaxFiles:         100,
+				OutputRetentionMaxAgeInDays:     30,
+				RepeatedErrorLogWindowInSeconds: 3600,
+				MaxWlmPayloadBytes:              10 << 20,
 			},
 			wantErr: true,
 		},
@@ -154,6 +172,41 @@ func TestLoadConfiguration(t *testing.T) {
 	}
 }
 
+func TestLoadConfigurationCollectionWindows(t *testing.T) {
+	testcases := []struct {
+		name        string
+		windows     string
+		wantWindows int
+		wantErr     bool
+	}{
+		{
+			name:        "invalid window dropped",
+			windows:     `[{"start_time": "22:00", "end_time": "04:00"}, {"start_time": "25:00", "end_time": "04:00"}]`,
+			wantWindows: 1,
+		},
+		{
+			name:    "every window invalid",
+			windows: `[{"start_time": "25:00", "end_time": "04:00"}, {"days": ["SAT"], "start_time": "22:00", "end_time": "04:00"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempFilePath := path.Join(t.TempDir(), "configuration.json")
+			if err := os.WriteFile(tempFilePath, []byte(`{"collection_configuration": {}, "collection_windows": `+tc.windows+`}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadConfiguration(tempFilePath)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("LoadConfiguration() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if len(got.GetCollectionWindows()) != tc.wantWindows {
+				t.Errorf("LoadConfiguration() returned %d collection windows, want %d", len(got.GetCollectionWindows()), tc.wantWindows)
+			}
+		})
+	}
+}
+
 func TestSQLConfigFromCredential(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -181,6 +234,159 @@ func TestSQLConfigFromCredential(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "SQLConfig with bastion",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:       "test-host",
+						UserName:   "test-user-name",
+						SecretName: "test-secret-name",
+						PortNumber: 1433,
+						Bastion: &configpb.CredentialConfiguration_SshBastion{
+							Host:           "test-bastion-host",
+							UserName:       "test-bastion-user-name",
+							PrivateKeyPath: "test-bastion-private-key-path",
+							PortNumber:     2222,
+						},
+					},
+				},
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:                  "test-host",
+					Username:              "test-user-name",
+					SecretName:            "test-secret-name",
+					PortNumber:            1433,
+					BastionHost:           "test-bastion-host",
+					BastionUserName:       "test-bastion-user-name",
+					BastionPrivateKeyPath: "test-bastion-private-key-path",
+					BastionPortNumber:     2222,
+				},
+			},
+		},
+		{
+			name: "SQLConfig with tls settings",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:       "test-host",
+						UserName:   "test-user-name",
+						SecretName: "test-secret-name",
+						PortNumber: 1433,
+						Tls: &configpb.CredentialConfiguration_Tls{
+							TrustServerCertificate: true,
+							MinTlsVersion:          "1.3",
+						},
+					},
+				},
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:                   "test-host",
+					Username:               "test-user-name",
+					SecretName:             "test-secret-name",
+					PortNumber:             1433,
+					Encrypt:                "true",
+					TrustServerCertificate: true,
+					MinTLSVersion:          "1.3",
+				},
+			},
+		},
+		{
+			name: "SQLConfig with connection string extra",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:                  "test-host",
+						UserName:              "test-user-name",
+						SecretName:            "test-secret-name",
+						PortNumber:            1433,
+						ConnectionStringExtra: "packet size=16384",
+					},
+				},
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:                  "test-host",
+					Username:              "test-user-name",
+					SecretName:            "test-secret-name",
+					PortNumber:            1433,
+					ConnectionStringExtra: "packet size=16384",
+				},
+			},
+		},
+		{
+			name: "SQLConfig with secret versions",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:           "test-host",
+						UserName:       "test-user-name",
+						SecretName:     "test-secret-name",
+						SecretVersions: []string{"latest", "4"},
+						PortNumber:     1433,
+					},
+				},
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:           "test-host",
+					Username:       "test-user-name",
+					SecretName:     "test-secret-name",
+					SecretVersions: []string{"latest", "4"},
+					PortNumber:     1433,
+				},
+			},
+		},
+		{
+			name: "SQLConfig with database include and exclude",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:            "test-host",
+						UserName:        "test-user-name",
+						SecretName:      "test-secret-name",
+						PortNumber:      1433,
+						DatabaseInclude: []string{"sales", "crm_*"},
+						DatabaseExclude: []string{"staging_*"},
+					},
+				},
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:            "test-host",
+					Username:        "test-user-name",
+					SecretName:      "test-secret-name",
+					PortNumber:      1433,
+					DatabaseInclude: []string{"sales", "crm_*"},
+					DatabaseExclude: []string{"staging_*"},
+				},
+			},
+		},
+		{
+			name: "SQLConfig with workload type",
+			input: &configpb.CredentialConfiguration{
+				SqlConfigurations: []*configpb.CredentialConfiguration_SqlCredentials{
+					&configpb.CredentialConfiguration_SqlCredentials{
+						Host:       "test-host",
+						UserName:   "test-user-name",
+						SecretName: "test-secret-name",
+						PortNumber: 1433,
+					},
+				},
+				WorkloadType: "reporting",
+			},
+			want: []*SQLConfig{
+				&SQLConfig{
+					Host:         "test-host",
+					Username:     "test-user-name",
+					SecretName:   "test-secret-name",
+					PortNumber:   1433,
+					WorkloadType: "reporting",
+				},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -266,38 +472,377 @@ func TestValidateConfigValues(t *testing.T) {
 		{
 			name: "values are all invalid",
 			input: &configpb.Configuration{
-				CollectionConfiguration: &configpb.CollectionConfiguration{},
+				CollectionConfiguration: &configpb.CollectionConfiguration{TopQueries: 500, TopQueriesMetric: "cpu", WmiQueriesPerSecond: -5, CachedRulesTtlInSeconds: -60, IndexFillFactorMinPages: -1, CheckdbMaxAgeInDays: -7, SqlConnectionIdleTimeoutInSeconds: -1},
 				MaxRetries:              -2,
+				WlmEndpoint:             "workloadmanager-datawarehouse.googleapis.com",
+				WlmLocation:             "us central1",
+				OtlpTracesEndpoint:      "localhost:4318",
+				Pubsub:                  &configpb.PubSubConfiguration{Topic: "projects/test-project/subscriptions/sub"},
+				Webhook:                 &configpb.WebhookConfiguration{Url: "alerts.example.com/hooks"},
+				SecretProvider:          &configpb.SecretProviderConfiguration{MaxRetries: -1, InitialRetryIntervalInMilliseconds: -500},
+				TextfileDirectory:       "textfile_collector",
+				MinTlsVersion:           "TLS1.2",
+				OutputFormat:            "zip",
+				DeadLetter:              &configpb.DeadLetterConfiguration{Directory: "deadletter"},
+				CycleStatus:             &configpb.CycleStatusConfiguration{MinInstanceSuccessPercent: -1, MinRuleSuccessPercent: 101},
+				ResourceLimits:          &configpb.ResourceLimits{MaxProcs: -1, MemoryLimitMb: -256},
+				MetadataServer:          &configpb.MetadataServerConfiguration{TimeoutInSeconds: -2, MaxRetries: -1},
+				FieldRenames: []*configpb.FieldRename{
+					{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "backup_age_days"},
+					{Rule: "DB_BACKUP_POLICY", Field: "backup_age", Name: "backup_age_days"},
+				},
+				DiskTypeMappings: []*configpb.DiskTypeMapping{
+					{FriendlyNamePattern: "NETAPP (LUN", DiskType: "SAN"},
+					{FriendlyNamePattern: "NETAPP LUN"},
+					{FriendlyNamePattern: "NETAPP LUN", DiskType: "SAN", SizeMultipleBytes: -1},
+				},
+				CollectionWindows: []*configpb.CollectionWindow{
+					{StartTime: "25:00", EndTime: "04:00"},
+					{Days: []string{"SOMEDAY"}, StartTime: "22:00", EndTime: "04:00"},
+					{StartTime: "22:00", EndTime: "04:00", TimeZone: "Mars/Olympus_Mons"},
+				},
+				Ignore: &configpb.IgnoreConfiguration{
+					WaitTypes:    []string{"sleep_task", "not a wait type", ""},
+					ErrorNumbers: []int32{1205, 0, -1},
+				},
 			},
 			want: &configpb.Configuration{
 				CollectionConfiguration: &configpb.CollectionConfiguration{
 					GuestOsMetricsCollectionIntervalInSeconds: 3600,
 					SqlMetricsCollectionIntervalInSeconds:     3600,
+					VlfCountThreshold:                         1000,
+					DiskFreeSpaceThresholdPercent:             10,
+					ClockSkewThresholdSeconds:                 5,
+					LockTimeoutMilliseconds:                   5000,
+					TopQueries:                                100,
+				},
+				CollectionTimeoutSeconds:        10,
+				MaxRetries:                      3,
+				RetryIntervalInSeconds:          3600,
+				OutputRetentionMaxFiles:         100,
+				OutputRetentionMaxAgeInDays:     30,
+				RepeatedErrorLogWindowInSeconds: 3600,
+				MaxWlmPayloadBytes:              10 << 20,
+				SecretProvider:                  &configpb.SecretProviderConfiguration{},
+				MinTlsVersion:                   "1.2",
+				ResourceLimits:                  &configpb.ResourceLimits{},
+				MetadataServer:                  &configpb.MetadataServerConfiguration{},
+				CycleStatus:                     &configpb.CycleStatusConfiguration{},
+				Ignore: &configpb.IgnoreConfiguration{
+					WaitTypes:    []string{"SLEEP_TASK"},
+					ErrorNumbers: []int32{1205},
 				},
-				CollectionTimeoutSeconds: 10,
-				MaxRetries:               3,
-				RetryIntervalInSeconds:   3600,
 			},
 		},
 		{
 			name: "values are all valid",
 			input: &configpb.Configuration{
 				CollectionConfiguration: &configpb.CollectionConfiguration{
-					GuestOsMetricsCollectionIntervalInSeconds: 1,
-					SqlMetricsCollectionIntervalInSeconds:     1,
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+					TopQueriesMetric:                          "logical_reads",
+					WmiQueriesPerSecond:                       20,
+					CachedRulesTtlInSeconds:                   86400,
+					CollectIndexFillFactors:                   true,
+					IndexFillFactorMinPages:                   500,
+					CheckdbMaxAgeInDays:                       14,
+					ReuseSqlConnections:                       true,
+					ReportCollectionManifest:                  true,
+					SqlConnectionIdleTimeoutInSeconds:         7200,
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+				OutputFormat:                    "json+archive",
+				Sqlite:                          &configpb.SqliteConfiguration{Path: "/var/lib/google-cloud-sql-server-agent/history.db", MaxAgeInDays: 90},
+				MetadataServer:                  &configpb.MetadataServerConfiguration{TimeoutInSeconds: 5, MaxRetries: 10},
+				WlmEndpoint:                     "https://staging-workloadmanager-datawarehouse.sandbox.googleapis.com/",
+				WlmLocation:                     "europe-west4",
+				OtlpTracesEndpoint:              "http://localhost:4318",
+				Pubsub:                          &configpb.PubSubConfiguration{Topic: "sql-server-agent"},
+				FieldRenames: []*configpb.FieldRename{
+					{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "backup_age_days"},
+				},
+				Webhook: &configpb.WebhookConfiguration{
+					Url:                   "https://alerts.example.com/hooks?team=dba",
+					BearerTokenSecretName: "webhook-token",
+					Conditions: []*configpb.WebhookCondition{
+						{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: "greater_than", Value: "7"},
+					},
+				},
+				Ignore: &configpb.IgnoreConfiguration{
+					WaitTypes:    []string{"WRITELOG"},
+					ErrorNumbers: []int32{1222},
+				},
+				DiskTypeMappings: []*configpb.DiskTypeMapping{
+					{FriendlyNamePattern: "^NETAPP LUN", DiskType: "SAN", SizeMultipleBytes: 1 << 30},
+				},
+				CollectionWindows: []*configpb.CollectionWindow{
+					{Days: []string{"saturday", "SUNDAY"}, StartTime: "22:00", EndTime: "04:00", TimeZone: "Europe/Berlin"},
+				},
+				DeadLetter: &configpb.DeadLetterConfiguration{
+					Directory: "/var/lib/google-cloud-sql-server-agent/deadletter",
+					MaxSizeMb: 50,
+					Replay:    true,
+				},
+				CycleStatus: &configpb.CycleStatusConfiguration{
+					MinInstanceSuccessPercent: 50,
+					MinRuleSuccessPercent:     100,
+				},
+			},
+			want: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+					TopQueriesMetric:                          "logical_reads",
+					WmiQueriesPerSecond:                       20,
+					CachedRulesTtlInSeconds:                   86400,
+					CollectIndexFillFactors:                   true,
+					IndexFillFactorMinPages:                   500,
+					CheckdbMaxAgeInDays:                       14,
+					ReuseSqlConnections:                       true,
+					ReportCollectionManifest:                  true,
+					SqlConnectionIdleTimeoutInSeconds:         7200,
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+				OutputFormat:                    "json+archive",
+				Sqlite:                          &configpb.SqliteConfiguration{Path: "/var/lib/google-cloud-sql-server-agent/history.db", MaxAgeInDays: 90},
+				MetadataServer:                  &configpb.MetadataServerConfiguration{TimeoutInSeconds: 5, MaxRetries: 10},
+				WlmEndpoint:                     "https://staging-workloadmanager-datawarehouse.sandbox.googleapis.com/",
+				WlmLocation:                     "europe-west4",
+				OtlpTracesEndpoint:              "http://localhost:4318",
+				Pubsub:                          &configpb.PubSubConfiguration{Topic: "sql-server-agent"},
+				FieldRenames: []*configpb.FieldRename{
+					{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "backup_age_days"},
+				},
+				Webhook: &configpb.WebhookConfiguration{
+					Url:                   "https://alerts.example.com/hooks?team=dba",
+					BearerTokenSecretName: "webhook-token",
+					Conditions: []*configpb.WebhookCondition{
+						{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: "greater_than", Value: "7"},
+					},
+				},
+				Ignore: &configpb.IgnoreConfiguration{
+					WaitTypes:    []string{"WRITELOG"},
+					ErrorNumbers: []int32{1222},
+				},
+				DiskTypeMappings: []*configpb.DiskTypeMapping{
+					{FriendlyNamePattern: "^NETAPP LUN", DiskType: "SAN", SizeMultipleBytes: 1 << 30},
+				},
+				CollectionWindows: []*configpb.CollectionWindow{
+					{Days: []string{"saturday", "SUNDAY"}, StartTime: "22:00", EndTime: "04:00", TimeZone: "Europe/Berlin"},
+				},
+				DeadLetter: &configpb.DeadLetterConfiguration{
+					Directory: "/var/lib/google-cloud-sql-server-agent/deadletter",
+					MaxSizeMb: 50,
+					Replay:    true,
+				},
+				CycleStatus: &configpb.CycleStatusConfiguration{
+					MinInstanceSuccessPercent: 50,
+					MinRuleSuccessPercent:     100,
+				},
+			},
+		},
+		{
+			name: "invalid webhook condition disables the webhook",
+			input: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
 				},
-				CollectionTimeoutSeconds: 1,
-				MaxRetries:               1,
-				RetryIntervalInSeconds:   1,
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+				Webhook: &configpb.WebhookConfiguration{
+					Url: "https://alerts.example.com/hooks",
+					Conditions: []*configpb.WebhookCondition{
+						{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: "greater_than", Value: "a week"},
+					},
+				},
+			},
+			want: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+			},
+		},
+		{
+			name: "relative sqlite path disables recording in sqlite",
+			input: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+				Sqlite:                          &configpb.SqliteConfiguration{Path: "history.db", MaxAgeInDays: -1},
+			},
+			want: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+				Sqlite:                          &configpb.SqliteConfiguration{},
+			},
+		},
+		{
+			name: "unknown workload type is cleared",
+			input: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CredentialConfiguration: []*configpb.CredentialConfiguration{
+					&configpb.CredentialConfiguration{WorkloadType: "olap"},
+					&configpb.CredentialConfiguration{WorkloadType: "batch"},
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
 			},
 			want: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: 10,
+					SqlMetricsCollectionIntervalInSeconds:     10,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CredentialConfiguration: []*configpb.CredentialConfiguration{
+					&configpb.CredentialConfiguration{WorkloadType: "olap"},
+					&configpb.CredentialConfiguration{},
+				},
+				CollectionTimeoutSeconds:        5,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          10,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
+			},
+		},
+		{
+			name: "values below the lower bounds are raised",
+			input: &configpb.Configuration{
 				CollectionConfiguration: &configpb.CollectionConfiguration{
 					GuestOsMetricsCollectionIntervalInSeconds: 1,
-					SqlMetricsCollectionIntervalInSeconds:     1,
+					SqlMetricsCollectionIntervalInSeconds:     9,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
 				},
-				CollectionTimeoutSeconds: 1,
-				MaxRetries:               1,
-				RetryIntervalInSeconds:   1,
+				CollectionTimeoutSeconds:        1,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          1,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              1,
+			},
+			want: &configpb.Configuration{
+				CollectionConfiguration: &configpb.CollectionConfiguration{
+					GuestOsMetricsCollectionIntervalInSeconds: MinCollectionIntervalSeconds,
+					SqlMetricsCollectionIntervalInSeconds:     MinCollectionIntervalSeconds,
+					VlfCountThreshold:                         1,
+					DiskFreeSpaceThresholdPercent:             1,
+					ClockSkewThresholdSeconds:                 1,
+					LockTimeoutMilliseconds:                   1,
+					TopQueries:                                1,
+				},
+				CollectionTimeoutSeconds:        MinCollectionTimeoutSeconds,
+				MaxRetries:                      1,
+				RetryIntervalInSeconds:          MinRetryIntervalSeconds,
+				OutputRetentionMaxFiles:         1,
+				OutputRetentionMaxAgeInDays:     1,
+				MaxConcurrentCollections:        1,
+				RepeatedErrorLogWindowInSeconds: 1,
+				MaxWlmPayloadBytes:              MinWLMPayloadBytes,
 			},
 		},
 	}
@@ -350,6 +895,266 @@ func TestValidateCredCfgSQL(t *testing.T) {
 			remote:       true,
 			windows:      true,
 		},
+		{
+			name: "success-local-with-bastion",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				BastionHost:           "test-bastion-host",
+				BastionUserName:       "test-bastion-user-name",
+				BastionPrivateKeyPath: "test-bastion-private-key-path",
+			},
+		},
+		{
+			name: "success-local-with-connection-string-extra",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: "packet size=16384; log=1;",
+			},
+		},
+		{
+			name: "success-local-failover-cluster-instance",
+			inputSQLConfig: &SQLConfig{
+				Host:                    "sqlfci",
+				Username:                "test-user-name",
+				SecretName:              "test-secret-name",
+				PortNumber:              1433,
+				FailoverClusterInstance: true,
+			},
+		},
+		{
+			name: "failure-local-failover-cluster-instance-localhost",
+			inputSQLConfig: &SQLConfig{
+				Host:                    "localhost",
+				Username:                "test-user-name",
+				SecretName:              "test-secret-name",
+				PortNumber:              1433,
+				FailoverClusterInstance: true,
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "host"`,
+		},
+		{
+			name: "failure-local-failover-cluster-instance-ip",
+			inputSQLConfig: &SQLConfig{
+				Host:                    "10.0.0.5",
+				Username:                "test-user-name",
+				SecretName:              "test-secret-name",
+				PortNumber:              1433,
+				FailoverClusterInstance: true,
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "host"`,
+		},
+		{
+			name: "failure-local-failover-cluster-instance-application-intent",
+			inputSQLConfig: &SQLConfig{
+				Host:                    "sqlfci",
+				Username:                "test-user-name",
+				SecretName:              "test-secret-name",
+				PortNumber:              1433,
+				ConnectionStringExtra:   "ApplicationIntent=ReadOnly;",
+				FailoverClusterInstance: true,
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "connection_string_extra"`,
+		},
+		{
+			name: "success-local-with-tls",
+			inputSQLConfig: &SQLConfig{
+				Host:          "test-sql.database.windows.net",
+				Username:      "test-user-name",
+				SecretName:    "test-secret-name",
+				PortNumber:    1433,
+				Encrypt:       "true",
+				MinTLSVersion: "1.2",
+			},
+		},
+		{
+			name: "success-local-managed-host-without-encryption",
+			inputSQLConfig: &SQLConfig{
+				Host:       "test-sql.database.windows.net",
+				Username:   "test-user-name",
+				SecretName: "test-secret-name",
+				PortNumber: 1433,
+				Encrypt:    "disable",
+			},
+		},
+		{
+			name: "failure-local-invalid-tls",
+			inputSQLConfig: &SQLConfig{
+				Username:      "test-user-name",
+				SecretName:    "test-secret-name",
+				PortNumber:    1433,
+				Encrypt:       "strict",
+				MinTLSVersion: "TLS1.2",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "tls.encrypt" "tls.min_tls_version"`,
+		},
+		{
+			name: "success-local-with-database-include",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				DatabaseInclude: []string{"sales", "crm_*"},
+			},
+		},
+		{
+			name: "failure-local-invalid-database-include",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				DatabaseInclude: []string{"sales", "crm_[", ""},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "database_include"`,
+		},
+		{
+			name: "success-local-with-database-exclude",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				DatabaseInclude: []string{"crm_*"},
+				DatabaseExclude: []string{"crm_test", "staging_*"},
+			},
+		},
+		{
+			name: "failure-local-invalid-database-exclude",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				DatabaseExclude: []string{"staging_["},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "database_exclude"`,
+		},
+		{
+			name: "failure-local-contradictory-database-include-exclude",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				DatabaseInclude: []string{"sales", "crm_*"},
+				DatabaseExclude: []string{"CRM_*"},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "database_exclude"`,
+		},
+		{
+			name: "failure-local-tls-with-extra-encryption",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: "TrustServerCertificate=true",
+				Encrypt:               "true",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "connection_string_extra"`,
+		},
+		{
+			name: "success-local-with-iam-proxy",
+			inputSQLConfig: &SQLConfig{
+				PortNumber:       1433,
+				ProxyEndpoint:    "127.0.0.1:1433",
+				ProxyIAMIdentity: "test-sa@test-project.iam.gserviceaccount.com",
+			},
+		},
+		{
+			name: "failure-local-iam-proxy-without-identity",
+			inputSQLConfig: &SQLConfig{
+				PortNumber:    1433,
+				ProxyEndpoint: "127.0.0.1:1433",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "iam_proxy.iam_identity"`,
+		},
+		{
+			name: "failure-local-iam-proxy-without-endpoint",
+			inputSQLConfig: &SQLConfig{
+				PortNumber:       1433,
+				ProxyIAMIdentity: "test-sa@test-project.iam.gserviceaccount.com",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "iam_proxy.endpoint"`,
+		},
+		{
+			name: "failure-local-iam-proxy-with-bastion",
+			inputSQLConfig: &SQLConfig{
+				PortNumber:            1433,
+				ProxyEndpoint:         "127.0.0.1:1433",
+				ProxyIAMIdentity:      "test-sa@test-project.iam.gserviceaccount.com",
+				BastionHost:           "test-bastion-host",
+				BastionUserName:       "test-bastion-user-name",
+				BastionPrivateKeyPath: "test-bastion-private-key-path",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "bastion"`,
+		},
+		{
+			name: "failure-local-connection-string-extra-with-password",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: "packet size=16384;PWD=secret",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "connection_string_extra"`,
+		},
+		{
+			name: "failure-local-connection-string-extra-with-managed-alias",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: " Data Source =other-host",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "connection_string_extra"`,
+		},
+		{
+			name: "failure-local-connection-string-extra-malformed",
+			inputSQLConfig: &SQLConfig{
+				Username:              "test-user-name",
+				SecretName:            "test-secret-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: "encrypt",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "connection_string_extra"`,
+		},
+		{
+			name: "failure-local-bastion-missing-private_key_path",
+			inputSQLConfig: &SQLConfig{
+				Username:        "test-user-name",
+				SecretName:      "test-secret-name",
+				PortNumber:      1433,
+				BastionHost:     "test-bastion-host",
+				BastionUserName: "test-bastion-user-name",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "bastion.private_key_path"`,
+		},
+		{
+			name: "failure-local-bastion-missing-user_name-and-private_key_path",
+			inputSQLConfig: &SQLConfig{
+				Username:    "test-user-name",
+				SecretName:  "test-secret-name",
+				PortNumber:  1433,
+				BastionHost: "test-bastion-host",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid value for "bastion.user_name" "bastion.private_key_path"`,
+		},
 		{
 			name: "failure-local-missing-user_name",
 			inputSQLConfig: &SQLConfig{
@@ -514,6 +1319,203 @@ func TestValidateCredCfgSQL(t *testing.T) {
 	}
 }
 
+func TestValidateCredCfgSQLLocalTransport(t *testing.T) {
+	testcases := []struct {
+		name       string
+		sqlConfig  *SQLConfig
+		supported  bool
+		wantErrMsg string
+	}{
+		{
+			name:      "named pipes",
+			sqlConfig: &SQLConfig{Host: "localhost", Username: "test-user-name", SecretName: "test-secret-name", LocalTransport: LocalTransportNamedPipes},
+			supported: true,
+		},
+		{
+			name:      "shared memory of a named instance",
+			sqlConfig: &SQLConfig{Host: `.\SQLEXPRESS`, Username: "test-user-name", SecretName: "test-secret-name", LocalTransport: LocalTransportSharedMemory},
+			supported: true,
+		},
+		{
+			name:      "tcp",
+			sqlConfig: &SQLConfig{Host: "test-host", Username: "test-user-name", SecretName: "test-secret-name", PortNumber: 1433, LocalTransport: LocalTransportTCP},
+		},
+		{
+			name:       "unknown transport",
+			sqlConfig:  &SQLConfig{Host: "localhost", Username: "test-user-name", SecretName: "test-secret-name", PortNumber: 1433, LocalTransport: "via"},
+			supported:  true,
+			wantErrMsg: `invalid value for "local_transport"`,
+		},
+		{
+			name:       "not supported",
+			sqlConfig:  &SQLConfig{Host: "localhost", Username: "test-user-name", SecretName: "test-secret-name", LocalTransport: LocalTransportNamedPipes},
+			wantErrMsg: `invalid value for "local_transport"`,
+		},
+		{
+			name:       "remote host",
+			sqlConfig:  &SQLConfig{Host: "test-host", Username: "test-user-name", SecretName: "test-secret-name", LocalTransport: LocalTransportSharedMemory},
+			supported:  true,
+			wantErrMsg: `invalid value for "local_transport"`,
+		},
+		{
+			name:       "bastion",
+			sqlConfig:  &SQLConfig{Host: "127.0.0.1", Username: "test-user-name", SecretName: "test-secret-name", BastionHost: "test-bastion-host", BastionUserName: "test-bastion-user-name", BastionPrivateKeyPath: "test-key-path", LocalTransport: LocalTransportNamedPipes},
+			supported:  true,
+			wantErrMsg: `invalid value for "local_transport"`,
+		},
+	}
+	supported := localTransportsSupported
+	t.Cleanup(func() { localTransportsSupported = supported })
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			localTransportsSupported = tc.supported
+			err := ValidateCredCfgSQL(false, true, tc.sqlConfig, &GuestConfig{}, "", "")
+			gotErrMsg := ""
+			if err != nil {
+				gotErrMsg = err.Error()
+			}
+			if gotErrMsg != tc.wantErrMsg {
+				t.Errorf("ValidateCredCfgSQL(%v) = %v, want error message = %q", tc.sqlConfig, err, tc.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestSQLConnectionString(t *testing.T) {
+	testcases := []struct {
+		name          string
+		input         *SQLConfig
+		minTLSVersion string
+		want          string
+	}{
+		{
+			name: "without extra",
+			input: &SQLConfig{
+				Host:       "test-host",
+				Username:   "test-user-name",
+				PortNumber: 1433,
+			},
+			want: "server=test-host;user id=test-user-name;password=test-password;port=1433;",
+		},
+		{
+			name: "with extra",
+			input: &SQLConfig{
+				Host:                  "test-host",
+				Username:              "test-user-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: " packet size=16384;log=1; ",
+			},
+			want: "server=test-host;user id=test-user-name;password=test-password;port=1433;packet size=16384;log=1;",
+		},
+		{
+			name: "with iam proxy",
+			input: &SQLConfig{
+				Host:             "test-host",
+				PortNumber:       1433,
+				ProxyEndpoint:    "127.0.0.1:1433",
+				ProxyIAMIdentity: "test-sa@test-project.iam.gserviceaccount.com",
+			},
+			want: "server=test-host;port=1433;",
+		},
+		{
+			name: "with min tls version",
+			input: &SQLConfig{
+				Host:       "test-host",
+				Username:   "test-user-name",
+				PortNumber: 1433,
+			},
+			minTLSVersion: "1.2",
+			want:          "server=test-host;user id=test-user-name;password=test-password;port=1433;trustservercertificate=true;encrypt=true;tlsmin=1.2;",
+		},
+		{
+			name: "with min tls version overriding extra encryption",
+			input: &SQLConfig{
+				Host:                  "test-host",
+				Username:              "test-user-name",
+				PortNumber:            1433,
+				ConnectionStringExtra: "Encrypt=false;certificate=/etc/ssl/sql.pem",
+			},
+			minTLSVersion: "1.3",
+			want:          "server=test-host;user id=test-user-name;password=test-password;port=1433;Encrypt=false;certificate=/etc/ssl/sql.pem;encrypt=true;tlsmin=1.3;",
+		},
+		{
+			name: "with tls settings overriding min tls version",
+			input: &SQLConfig{
+				Host:          "test-host",
+				Username:      "test-user-name",
+				PortNumber:    1433,
+				Encrypt:       "true",
+				MinTLSVersion: "1.3",
+			},
+			minTLSVersion: "1.2",
+			want:          "server=test-host;user id=test-user-name;password=test-password;port=1433;encrypt=true;trustservercertificate=false;tlsmin=1.3;",
+		},
+		{
+			name: "with tls settings using min tls version",
+			input: &SQLConfig{
+				Host:                   "test-host",
+				Username:               "test-user-name",
+				PortNumber:             1433,
+				Encrypt:                "true",
+				TrustServerCertificate: true,
+			},
+			minTLSVersion: "1.2",
+			want:          "server=test-host;user id=test-user-name;password=test-password;port=1433;encrypt=true;trustservercertificate=true;tlsmin=1.2;",
+		},
+		{
+			name: "with tls settings disabling encryption",
+			input: &SQLConfig{
+				Host:       "legacy-host",
+				Username:   "test-user-name",
+				PortNumber: 1433,
+				Encrypt:    "disable",
+			},
+			minTLSVersion: "1.2",
+			want:          "server=legacy-host;user id=test-user-name;password=test-password;port=1433;encrypt=disable;trustservercertificate=false;",
+		},
+		{
+			name: "with named pipes",
+			input: &SQLConfig{
+				Host:           "localhost",
+				Username:       "test-user-name",
+				PortNumber:     1433,
+				LocalTransport: LocalTransportNamedPipes,
+			},
+			minTLSVersion: "1.2",
+			want:          `server=localhost;user id=test-user-name;password=test-password;protocol=np;pipe=sql\query;`,
+		},
+		{
+			name: "with named pipes of a named instance",
+			input: &SQLConfig{
+				Host:           `.\SQLEXPRESS`,
+				Username:       "test-user-name",
+				LocalTransport: LocalTransportNamedPipes,
+			},
+			want: `server=.\SQLEXPRESS;user id=test-user-name;password=test-password;protocol=np;`,
+		},
+		{
+			name: "with shared memory and tls settings",
+			input: &SQLConfig{
+				Host:           "(local)",
+				Username:       "test-user-name",
+				LocalTransport: LocalTransportSharedMemory,
+				Encrypt:        "true",
+				MinTLSVersion:  "1.2",
+			},
+			want: "server=(local);user id=test-user-name;password=test-password;protocol=lpc;encrypt=true;trustservercertificate=false;tlsmin=1.2;",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SQLConnectionString(tc.input, "test-password", tc.minTLSVersion)
+			if got != tc.want {
+				t.Errorf("SQLConnectionString(%v) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestValidateCredCfgGuest(t *testing.T) {
 	testcases := []struct {
 		name             string
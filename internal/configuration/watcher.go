@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// watchPollInterval is how often the watcher checks the configuration file for changes.
+const watchPollInterval = 10 * time.Second
+
+// Watcher detects changes of the configuration file and reload requests sent to the agent with
+// SIGHUP so that the configuration can be reloaded between collection cycles.
+type Watcher struct {
+	path         string
+	modTime      time.Time
+	signals      chan os.Signal
+	pollInterval time.Duration
+}
+
+// NewWatcher returns a watcher of the configuration file in the directory of p.
+// Stop must be called once the watcher is no longer used.
+func NewWatcher(p string) *Watcher {
+	w := &Watcher{
+		path:         filepath.Join(filepath.Dir(p), "configuration.json"),
+		signals:      make(chan os.Signal, 1),
+		pollInterval: watchPollInterval,
+	}
+	w.modTime = w.lastModified()
+	signal.Notify(w.signals, syscall.SIGHUP)
+	return w
+}
+
+// Stop stops relaying SIGHUP to the watcher.
+func (w *Watcher) Stop() {
+	signal.Stop(w.signals)
+}
+
+// WaitUntil blocks until the deadline passes, the configuration file changes or SIGHUP is
+// received. It reports whether the configuration needs to be reloaded.
+func (w *Watcher) WaitUntil(deadline time.Time) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return false
+		case <-w.signals:
+			w.modTime = w.lastModified()
+			return true
+		case <-ticker.C:
+			if modTime := w.lastModified(); !modTime.Equal(w.modTime) {
+				w.modTime = modTime
+				return true
+			}
+		}
+	}
+}
+
+// lastModified returns the modification time of the configuration file, or the zero time if the
+// file cannot be read.
+func (w *Watcher) lastModified() time.Time {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatcherWaitUntil(t *testing.T) {
+	testcases := []struct {
+		name   string
+		change func(t *testing.T, w *Watcher)
+		want   bool
+	}{
+		{
+			name:   "deadline passes without changes",
+			change: func(t *testing.T, w *Watcher) {},
+			want:   false,
+		},
+		{
+			name: "configuration file modified",
+			change: func(t *testing.T, w *Watcher) {
+				modTime := time.Now().Add(time.Minute)
+				if err := os.Chtimes(w.path, modTime, modTime); err != nil {
+					t.Fatalf("os.Chtimes() failed: %v", err)
+				}
+			},
+			want: true,
+		},
+		{
+			name: "configuration file removed",
+			change: func(t *testing.T, w *Watcher) {
+				if err := os.Remove(w.path); err != nil {
+					t.Fatalf("os.Remove() failed: %v", err)
+				}
+			},
+			want: true,
+		},
+		{
+			name: "SIGHUP received",
+			change: func(t *testing.T, w *Watcher) {
+				w.signals <- syscall.SIGHUP
+			},
+			want: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "configuration.json"), []byte("{}"), 0644); err != nil {
+				t.Fatalf("os.WriteFile() failed: %v", err)
+			}
+			w := NewWatcher(dir + "/")
+			defer w.Stop()
+			w.pollInterval = 10 * time.Millisecond
+			tc.change(t, w)
+			got := w.WaitUntil(time.Now().Add(200 * time.Millisecond))
+			if got != tc.want {
+				t.Errorf("WaitUntil() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cyclestatus decides whether a collection cycle succeeded from the share of its instances
+// and rules that were collected, so that a few transient failures do not fail the whole cycle.
+package cyclestatus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Default thresholds of a successful cycle, in percent of the instances and rules collected.
+const (
+	DefaultMinInstancePercent = 80
+	DefaultMinRulePercent     = 90
+)
+
+// Thresholds are the percentages of the instances and of the rules of a cycle that must be
+// collected for the cycle to succeed.
+type Thresholds struct {
+	MinInstancePercent float64
+	MinRulePercent     float64
+}
+
+// Cycle counts the instances and rules collected and failed in a collection cycle. All methods
+// are safe for concurrent use and do nothing on a nil Cycle.
+type Cycle struct {
+	mu              sync.Mutex
+	instances       int
+	failedInstances int
+	rules           int
+	failedRules     int
+	err             error
+}
+
+type cycleKey struct{}
+
+// NewContext returns a context carrying a new Cycle, along with the Cycle.
+func NewContext(ctx context.Context) (context.Context, *Cycle) {
+	c := &Cycle{}
+	return context.WithValue(ctx, cycleKey{}, c), c
+}
+
+// FromContext returns the Cycle of the context, or nil if it has none.
+func FromContext(ctx context.Context) *Cycle {
+	c, _ := ctx.Value(cycleKey{}).(*Cycle)
+	return c
+}
+
+// Instance records the collection of an instance, which failed if err is not nil.
+func (c *Cycle) Instance(err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.instances++
+	if err != nil {
+		c.failedInstances++
+	}
+}
+
+// Rule records the collection of a rule, which failed if err is not nil.
+func (c *Cycle) Rule(err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules++
+	if err != nil {
+		c.failedRules++
+	}
+}
+
+// Abort records an error that stopped the cycle before its instances were collected. An aborted
+// cycle fails regardless of the thresholds.
+func (c *Cycle) Abort(err error) {
+	if c == nil || err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// Succeeded reports whether the cycle collected at least the thresholds of its instances and
+// rules. A cycle without instances or rules meets the respective threshold.
+func (c *Cycle) Succeeded(t Thresholds) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err == nil &&
+		percent(c.instances-c.failedInstances, c.instances) >= t.MinInstancePercent &&
+		percent(c.rules-c.failedRules, c.rules) >= t.MinRulePercent
+}
+
+// String summarizes the instances and rules collected in the cycle.
+func (c *Cycle) String() string {
+	if c == nil {
+		return "no cycle"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := fmt.Sprintf("%d/%d instances and %d/%d rules collected", c.instances-c.failedInstances, c.instances, c.rules-c.failedRules, c.rules)
+	if c.err != nil {
+		s += fmt.Sprintf(", aborted: %v", c.err)
+	}
+	return s
+}
+
+// percent returns the percentage of n in total, or 100 if total is zero.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(n) / float64(total)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cyclestatus
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSucceeded(t *testing.T) {
+	thresholds := Thresholds{MinInstancePercent: 80, MinRulePercent: 90}
+	errCollection := errors.New("collection failed")
+	testcases := []struct {
+		name            string
+		instances       []error
+		rules           []error
+		abort           error
+		want            bool
+		wantDescription string
+	}{
+		{
+			name:            "empty cycle",
+			want:            true,
+			wantDescription: "0/0 instances and 0/0 rules collected",
+		},
+		{
+			name:            "all collected",
+			instances:       []error{nil, nil},
+			rules:           []error{nil, nil, nil},
+			want:            true,
+			wantDescription: "2/2 instances and 3/3 rules collected",
+		},
+		{
+			name:            "failures within the thresholds",
+			instances:       []error{nil, nil, nil, nil, errCollection},
+			rules:           []error{nil, nil, nil, nil, nil, nil, nil, nil, nil, errCollection},
+			want:            true,
+			wantDescription: "4/5 instances and 9/10 rules collected",
+		},
+		{
+			name:            "too many instances failed",
+			instances:       []error{nil, nil, errCollection},
+			want:            false,
+			wantDescription: "2/3 instances and 0/0 rules collected",
+		},
+		{
+			name:            "too many rules failed",
+			instances:       []error{nil},
+			rules:           []error{nil, nil, nil, nil, errCollection},
+			want:            false,
+			wantDescription: "1/1 instances and 4/5 rules collected",
+		},
+		{
+			name:            "aborted",
+			abort:           errCollection,
+			want:            false,
+			wantDescription: "0/0 instances and 0/0 rules collected, aborted: collection failed",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, c := NewContext(context.Background())
+			if got := FromContext(ctx); got != c {
+				t.Fatalf("FromContext() = %p, want %p", got, c)
+			}
+			for _, err := range tc.instances {
+				c.Instance(err)
+			}
+			for _, err := range tc.rules {
+				c.Rule(err)
+			}
+			c.Abort(tc.abort)
+			if got := c.Succeeded(thresholds); got != tc.want {
+				t.Errorf("Succeeded() = %t, want %t", got, tc.want)
+			}
+			if got := c.String(); got != tc.wantDescription {
+				t.Errorf("String() = %q, want %q", got, tc.wantDescription)
+			}
+		})
+	}
+}
+
+func TestNilCycle(t *testing.T) {
+	c := FromContext(context.Background())
+	c.Instance(errors.New("failed"))
+	c.Rule(errors.New("failed"))
+	c.Abort(errors.New("failed"))
+	if !c.Succeeded(Thresholds{MinInstancePercent: 100, MinRulePercent: 100}) {
+		t.Errorf("Succeeded() of a nil cycle = false, want true")
+	}
+}
@@ -71,8 +71,9 @@ func (p *program) Uninstall(s service.Service) error {
 }
 
 // CreateConfig creates and returns Config pointer for the service.
-func CreateConfig(name, displayName, description string) *service.Config {
-	serviceArg := []string{"--action=run"}
+// args are passed to the service in addition to the run action.
+func CreateConfig(name, displayName, description string, args ...string) *service.Config {
+	serviceArg := append([]string{"--action=run"}, args...)
 
 	return &service.Config{
 		Name:        name,
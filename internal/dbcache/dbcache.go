@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dbcache keeps the results of per-database rules across collection cycles so that
+// incremental collections only run the rules for the databases that changed.
+package dbcache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
+)
+
+// Default is the cache shared by all collections of the agent.
+var Default = New()
+
+// database is the last collected result of a rule for a database.
+type database struct {
+	// signal identifies the state of the database when the rule was collected.
+	signal string
+	rows   []map[string]string
+}
+
+// Cache keeps the rows of per-database rules along with the signal of each database when the
+// rows were collected.
+type Cache struct {
+	mu    sync.Mutex
+	rules map[string]map[string]database
+}
+
+// New creates an empty Cache.
+func New() *Cache {
+	return &Cache{rules: map[string]map[string]database{}}
+}
+
+// Changed returns the sorted names of the databases whose signal differs from their signal when
+// the rule was last collected from the target. Databases not collected yet are changed.
+func (c *Cache) Changed(target, rule string, signals map[string]string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := c.rules[errorlog.Key(target, rule)]
+	changed := []string{}
+	for name, signal := range signals {
+		if db, ok := cached[name]; !ok || db.signal != signal {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Update replaces the cached rows of the changed databases with the rows of the same db_name and
+// drops the databases without a signal, which no longer exist.
+// It returns the cached rows of all databases ordered by database name.
+func (c *Cache) Update(target, rule string, signals map[string]string, changed []string, rows []map[string]string) []map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := errorlog.Key(target, rule)
+	cached, ok := c.rules[k]
+	if !ok {
+		cached = map[string]database{}
+		c.rules[k] = cached
+	}
+	collected := map[string]bool{}
+	for _, name := range changed {
+		cached[name] = database{signal: signals[name]}
+		collected[name] = true
+	}
+	for _, row := range rows {
+		name := row["db_name"]
+		if !collected[name] {
+			continue
+		}
+		db := cached[name]
+		db.rows = append(db.rows, row)
+		cached[name] = db
+	}
+	names := []string{}
+	for name := range cached {
+		if _, ok := signals[name]; !ok {
+			delete(cached, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := []map[string]string{}
+	for _, name := range names {
+		res = append(res, cached[name].rows...)
+	}
+	return res
+}
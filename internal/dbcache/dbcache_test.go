@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbcache
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCache(t *testing.T) {
+	type cycle struct {
+		signals     map[string]string
+		wantChanged []string
+		rows        []map[string]string
+		want        []map[string]string
+	}
+	testcases := []struct {
+		name   string
+		cycles []cycle
+	}{
+		{
+			name: "all databases changed on first collection",
+			cycles: []cycle{
+				{
+					signals:     map[string]string{"db2": "a", "db1": "a"},
+					wantChanged: []string{"db1", "db2"},
+					rows:        []map[string]string{{"db_name": "db2", "v": "2"}, {"db_name": "db1", "v": "1"}},
+					want:        []map[string]string{{"db_name": "db1", "v": "1"}, {"db_name": "db2", "v": "2"}},
+				},
+			},
+		},
+		{
+			name: "unchanged databases reported from cache",
+			cycles: []cycle{
+				{
+					signals:     map[string]string{"db1": "a", "db2": "a"},
+					wantChanged: []string{"db1", "db2"},
+					rows:        []map[string]string{{"db_name": "db1", "v": "1"}, {"db_name": "db2", "v": "2"}},
+					want:        []map[string]string{{"db_name": "db1", "v": "1"}, {"db_name": "db2", "v": "2"}},
+				},
+				{
+					signals:     map[string]string{"db1": "a", "db2": "b", "db3": "a"},
+					wantChanged: []string{"db2", "db3"},
+					rows:        []map[string]string{{"db_name": "db2", "v": "22"}, {"db_name": "db3", "v": "3"}, {"db_name": "db1", "v": "ignored"}},
+					want: []map[string]string{
+						{"db_name": "db1", "v": "1"},
+						{"db_name": "db2", "v": "22"},
+						{"db_name": "db3", "v": "3"},
+					},
+				},
+				{
+					signals:     map[string]string{"db1": "a", "db3": "a"},
+					wantChanged: []string{},
+					want:        []map[string]string{{"db_name": "db1", "v": "1"}, {"db_name": "db3", "v": "3"}},
+				},
+			},
+		},
+		{
+			name: "changed database without rows is cached",
+			cycles: []cycle{
+				{
+					signals:     map[string]string{"master": "a"},
+					wantChanged: []string{"master"},
+					want:        []map[string]string{},
+				},
+				{
+					signals:     map[string]string{"master": "a"},
+					wantChanged: []string{},
+					want:        []map[string]string{},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			for i, cy := range tc.cycles {
+				changed := c.Changed("host", "rule", cy.signals)
+				if diff := cmp.Diff(changed, cy.wantChanged); diff != "" {
+					t.Errorf("cycle %d: Changed() returned wrong result (-got +want):\n%s", i, diff)
+				}
+				got := c.Update("host", "rule", cy.signals, changed, cy.rows)
+				if diff := cmp.Diff(got, cy.want); diff != "" {
+					t.Errorf("cycle %d: Update() returned wrong result (-got +want):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheKeysByTargetAndRule(t *testing.T) {
+	c := New()
+	signals := map[string]string{"db1": "a"}
+	c.Update("host1", "rule", signals, c.Changed("host1", "rule", signals), []map[string]string{{"db_name": "db1"}})
+	if got := c.Changed("host1", "rule", signals); len(got) != 0 {
+		t.Errorf("Changed(host1, rule) = %v, want no changed databases", got)
+	}
+	if got := c.Changed("host2", "rule", signals); len(got) != 1 {
+		t.Errorf("Changed(host2, rule) = %v, want [db1]", got)
+	}
+	if got := c.Changed("host1", "other", signals); len(got) != 1 {
+		t.Errorf("Changed(host1, other) = %v, want [db1]", got)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deadletter keeps the requests to workload manager that failed after all retries in a
+// local directory, so that they can be inspected or replayed instead of being lost.
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	workloadmanager "google.golang.org/api/workloadmanager/v1"
+)
+
+// filePrefix and fileSuffix surround the names of the dead-letter files. Other files of the
+// directory are neither replayed nor deleted.
+const (
+	filePrefix = "sqlserveragent-deadletter-"
+	fileSuffix = ".json"
+)
+
+// mu serializes the writes and replays of the dead-letter directories, so that concurrent
+// collections do not replay the same request twice.
+var mu sync.Mutex
+
+// Entry is a request that failed to be sent to workload manager, with the location it was sent to.
+type Entry struct {
+	Location string
+	Request  *workloadmanager.WriteInsightRequest
+}
+
+// Queue is a dead-letter directory holding at most maxBytes of requests.
+type Queue struct {
+	dir      string
+	maxBytes int64
+}
+
+// New returns the queue of the directory. The directory is created when the first request is
+// written.
+func New(dir string, maxBytes int64) *Queue {
+	return &Queue{dir: dir, maxBytes: maxBytes}
+}
+
+// file is a dead-letter file.
+type file struct {
+	path string
+	size int64
+}
+
+// files returns the dead-letter files from the oldest to the newest.
+func (q *Queue) files() ([]file, error) {
+	entries, err := os.ReadDir(q.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []file
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), filePrefix) || !strings.HasSuffix(e.Name(), fileSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(q.dir, e.Name()), size: info.Size()})
+	}
+	// The names start with the prefix and the time the request was written.
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// Write adds the request sent to the location to the queue. The oldest requests are deleted
+// while the queue holds more than its maximum size; a request larger than the maximum size is
+// not kept.
+func (q *Queue) Write(location string, request *workloadmanager.WriteInsightRequest, now time.Time) error {
+	data, err := json.Marshal(Entry{Location: location, Request: request})
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > q.maxBytes {
+		return fmt.Errorf("request of %d bytes is larger than the maximum size of the dead-letter directory of %d bytes", len(data), q.maxBytes)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return err
+	}
+	files, err := q.files()
+	if err != nil {
+		return err
+	}
+	total := int64(len(data))
+	for _, f := range files {
+		total += f.size
+	}
+	for len(files) > 0 && total > q.maxBytes {
+		if err := os.Remove(files[0].path); err != nil {
+			return err
+		}
+		total -= files[0].size
+		files = files[1:]
+	}
+	// The random part of the name keeps the requests written at the same time apart.
+	f, err := os.CreateTemp(q.dir, filePrefix+now.UTC().Format("20060102T150405.000000000")+"-*"+fileSuffix)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return f.Close()
+}
+
+// Replay sends the requests of the queue from the oldest to the newest, deleting each request
+// sent. It stops at the first request that fails to be sent and returns the number of requests
+// sent. Files that are not valid requests are deleted.
+func (q *Queue) Replay(send func(Entry) error) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	files, err := q.files()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return sent, err
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil || e.Request == nil {
+			os.Remove(f.path)
+			continue
+		}
+		if err := send(e); err != nil {
+			return sent, err
+		}
+		sent++
+		if err := os.Remove(f.path); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	workloadmanager "google.golang.org/api/workloadmanager/v1"
+)
+
+func request(id string) *workloadmanager.WriteInsightRequest {
+	return &workloadmanager.WriteInsightRequest{RequestId: id}
+}
+
+func TestWriteAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "deadletter")
+	q := New(dir, 1<<20)
+	now := time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"first", "second", "third"} {
+		if err := q.Write("us-central1", request(id), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Write(%q) returned error: %v", id, err)
+		}
+	}
+
+	var got []string
+	sent, err := q.Replay(func(e Entry) error {
+		if e.Request.RequestId == "second" {
+			return errors.New("unavailable")
+		}
+		got = append(got, e.Location+"/"+e.Request.RequestId)
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Replay() returned no error, want error of the second request")
+	}
+	if sent != 1 {
+		t.Errorf("Replay() sent %d requests, want 1", sent)
+	}
+
+	sent, err = q.Replay(func(e Entry) error {
+		got = append(got, e.Location+"/"+e.Request.RequestId)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Replay() returned error: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("Replay() sent %d requests, want 2", sent)
+	}
+	want := []string{"us-central1/first", "us-central1/second", "us-central1/third"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Replay() sent wrong requests (-got +want):\n%s", diff)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%q) returned error: %v", dir, err)
+	}
+	if len(files) != 0 {
+		t.Errorf("Replay() left %d files in the dead-letter directory, want 0", len(files))
+	}
+}
+
+func TestWriteDeletesOldestRequests(t *testing.T) {
+	data, err := json.Marshal(Entry{Location: "us-central1", Request: request("first")})
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	// The queue holds two requests.
+	q := New(t.TempDir(), int64(len(data))*5/2)
+	now := time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"first", "second", "third"} {
+		if err := q.Write("us-central1", request(id), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Write(%q) returned error: %v", id, err)
+		}
+	}
+	var got []string
+	if _, err := q.Replay(func(e Entry) error {
+		got = append(got, e.Request.RequestId)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay() returned error: %v", err)
+	}
+	want := []string{"second", "third"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Replay() sent wrong requests (-got +want):\n%s", diff)
+	}
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	q := New(t.TempDir(), 10)
+	if err := q.Write("us-central1", request("large"), time.Now()); err == nil {
+		t.Errorf("Write() returned no error for a request larger than the maximum size")
+	}
+}
+
+func TestReplayMissingDirectory(t *testing.T) {
+	q := New(filepath.Join(t.TempDir(), "missing"), 1<<20)
+	sent, err := q.Replay(func(Entry) error { return nil })
+	if sent != 0 || err != nil {
+		t.Errorf("Replay() = %d, %v, want 0, nil", sent, err)
+	}
+}
+
+func TestForeignFilesKept(t *testing.T) {
+	dir := t.TempDir()
+	foreign := filepath.Join(dir, "other.json")
+	if err := os.WriteFile(foreign, []byte("not a request"), 0600); err != nil {
+		t.Fatalf("WriteFile(%q) returned error: %v", foreign, err)
+	}
+	data, err := json.Marshal(Entry{Location: "us-central1", Request: request("first")})
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	// The queue holds one request.
+	q := New(dir, int64(len(data))*3/2)
+	now := time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"first", "second"} {
+		if err := q.Write("us-central1", request(id), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Write(%q) returned error: %v", id, err)
+		}
+	}
+	sent, err := q.Replay(func(Entry) error { return nil })
+	if sent != 1 || err != nil {
+		t.Errorf("Replay() = %d, %v, want 1, nil", sent, err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("Stat(%q) returned error: %v, want the foreign file kept", foreign, err)
+	}
+}
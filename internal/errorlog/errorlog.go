@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errorlog suppresses repeated logging of the same error of a rule.
+// The state is kept across collection cycles so chronic failures are logged as a summary
+// instead of in full on every cycle.
+package errorlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+)
+
+// Default is the logger shared by all collections of the agent.
+var Default = New(time.Hour, clockwork.NewRealClock())
+
+// outcome is the result of recording a failure.
+type outcome int
+
+const (
+	// logFull logs the error in full as it is new or changed.
+	logFull outcome = iota
+	// logSummary logs that the rule is still failing as the window has passed.
+	logSummary
+	// suppress does not log the error as it was logged within the window.
+	suppress
+)
+
+// failure is the last error of a rule.
+type failure struct {
+	err        string
+	lastLogged time.Time
+	// count is the number of failures since the error was last logged.
+	count int
+	// total is the number of consecutive failures with the error.
+	total int
+}
+
+// Logger logs the errors of rules, suppressing an error that was already logged for the rule
+// within the window.
+type Logger struct {
+	mu       sync.Mutex
+	window   time.Duration
+	clock    clockwork.Clock
+	failures map[string]*failure
+}
+
+// New creates a Logger suppressing repeated errors within the window.
+func New(window time.Duration, clock clockwork.Clock) *Logger {
+	return &Logger{window: window, clock: clock, failures: map[string]*failure{}}
+}
+
+// Key returns the key of a rule collected from a target.
+func Key(target, rule string) string {
+	return target + "/" + rule
+}
+
+// SetWindow sets the window in which repeated errors are suppressed.
+func (l *Logger) SetWindow(window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window = window
+}
+
+// Failed logs the error of the rule with msg and keysAndValues.
+// The error is logged in full when it is new or differs from the last error of the rule.
+// A repeated error is only logged as a summary with the number of failures once the window has
+// passed since it was last logged.
+func (l *Logger) Failed(key string, err error, msg string, keysAndValues ...any) {
+	o, count, total := l.record(key, err.Error())
+	keysAndValues = append(keysAndValues, "key", key, "error", err)
+	switch o {
+	case logFull:
+		log.Logger.Errorw(msg, keysAndValues...)
+	case logSummary:
+		log.Logger.Errorw(fmt.Sprintf("%s; still failing (%dx)", msg, total), append(keysAndValues, "failures_since_last_log", count)...)
+	default:
+		log.Logger.Debugw(msg+"; repeated error suppressed", keysAndValues...)
+	}
+}
+
+// Succeeded clears the last error of the rule and logs that the rule recovered if it failed.
+func (l *Logger) Succeeded(key string) {
+	if total := l.clear(key); total > 0 {
+		log.Logger.Infow("Rule succeeded after failing", "key", key, "consecutive_failures", total)
+	}
+}
+
+func (l *Logger) record(key, err string) (outcome, int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	f, ok := l.failures[key]
+	if !ok || f.err != err {
+		l.failures[key] = &failure{err: err, lastLogged: now, total: 1}
+		return logFull, 0, 1
+	}
+	f.count++
+	f.total++
+	if now.Sub(f.lastLogged) < l.window {
+		return suppress, f.count, f.total
+	}
+	count := f.count
+	f.lastLogged = now
+	f.count = 0
+	return logSummary, count, f.total
+}
+
+func (l *Logger) clear(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[key]
+	if !ok {
+		return 0
+	}
+	delete(l.failures, key)
+	return f.total
+}
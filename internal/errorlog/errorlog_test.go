@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errorlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+func TestRecord(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		err       string
+		succeeded bool
+		want      outcome
+		wantCount int
+		wantTotal int
+	}
+	testcases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "repeated error is suppressed within the window",
+			steps: []step{
+				{err: "permission denied", want: logFull, wantCount: 0, wantTotal: 1},
+				{advance: time.Minute, err: "permission denied", want: suppress, wantCount: 1, wantTotal: 2},
+				{advance: time.Minute, err: "permission denied", want: suppress, wantCount: 2, wantTotal: 3},
+			},
+		},
+		{
+			name: "repeated error is summarized after the window",
+			steps: []step{
+				{err: "permission denied", want: logFull, wantCount: 0, wantTotal: 1},
+				{advance: 30 * time.Minute, err: "permission denied", want: suppress, wantCount: 1, wantTotal: 2},
+				{advance: 30 * time.Minute, err: "permission denied", want: logSummary, wantCount: 2, wantTotal: 3},
+				{advance: time.Minute, err: "permission denied", want: suppress, wantCount: 1, wantTotal: 4},
+			},
+		},
+		{
+			name: "changed error is logged in full",
+			steps: []step{
+				{err: "permission denied", want: logFull, wantCount: 0, wantTotal: 1},
+				{advance: time.Minute, err: "timeout", want: logFull, wantCount: 0, wantTotal: 1},
+			},
+		},
+		{
+			name: "error after success is logged in full",
+			steps: []step{
+				{err: "permission denied", want: logFull, wantCount: 0, wantTotal: 1},
+				{advance: time.Minute, succeeded: true},
+				{advance: time.Minute, err: "permission denied", want: logFull, wantCount: 0, wantTotal: 1},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := clockwork.NewFakeClock()
+			l := New(time.Hour, clock)
+			for i, s := range tc.steps {
+				clock.Advance(s.advance)
+				if s.succeeded {
+					l.Succeeded("host/rule")
+					continue
+				}
+				got, count, total := l.record("host/rule", s.err)
+				if got != s.want || count != s.wantCount || total != s.wantTotal {
+					t.Errorf("step %d: record() = %v, %d, %d, want %v, %d, %d", i, got, count, total, s.want, s.wantCount, s.wantTotal)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordKeysAreIndependent(t *testing.T) {
+	l := New(time.Hour, clockwork.NewFakeClock())
+	l.Failed(Key("host1", "rule"), errors.New("permission denied"), "Failed to run rule")
+	if got, _, _ := l.record(Key("host2", "rule"), "permission denied"); got != logFull {
+		t.Errorf("record() for another target = %v, want %v", got, logFull)
+	}
+}
+
+func TestSetWindow(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	l := New(time.Hour, clock)
+	l.SetWindow(time.Minute)
+	l.record("host/rule", "permission denied")
+	clock.Advance(time.Minute)
+	if got, _, _ := l.record("host/rule", "permission denied"); got != logSummary {
+		t.Errorf("record() after the window = %v, want %v", got, logSummary)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filearchive bundles files into a single gzip-compressed tar archive with a manifest of
+// its contents, e.g. the persisted collection files of the hosts of a remote collection run.
+package filearchive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the name of the manifest in the archive.
+const ManifestName = "manifest.json"
+
+// Entry describes a file in the archive.
+type Entry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files in the archive. It is the first file of the archive.
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Files   []Entry   `json:"files"`
+}
+
+// Write writes the files to a gzip-compressed tar archive at path. The files are stored under
+// their base names after the manifest. The archive is written to a temporary file first so that
+// an interrupted run does not leave a partial archive at path.
+func Write(path string, files []string, now time.Time) (*Manifest, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to archive")
+	}
+	manifest := &Manifest{Created: now.UTC()}
+	contents := make([][]byte, len(files))
+	seen := map[string]bool{}
+	for i, f := range files {
+		name := filepath.Base(f)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate file name %q", name)
+		}
+		seen[name] = true
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		contents[i] = b
+		manifest.Files = append(manifest.Files, Entry{Name: name, Size: int64(len(b)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	m, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	err = writeFile(tw, ManifestName, m, manifest.Created)
+	for i := 0; err == nil && i < len(files); i++ {
+		err = writeFile(tw, manifest.Files[i].Name, contents[i], manifest.Created)
+	}
+	for _, c := range []func() error{tw.Close, gz.Close, out.Close} {
+		if cerr := c(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeFile writes a regular file to the tar archive.
+func writeFile(tw *tar.Writer, name string, b []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(b)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
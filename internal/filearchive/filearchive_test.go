@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filearchive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name      string
+		files     map[string]string
+		wantFiles []string
+		wantErr   bool
+	}{
+		{
+			name:      "two hosts",
+			files:     map[string]string{"host1-guest.json": `{"a":1}`, "host2-guest.json": `{"b":2}`},
+			wantFiles: []string{ManifestName, "host1-guest.json", "host2-guest.json"},
+		},
+		{
+			name:    "no files",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			for _, name := range []string{"host1-guest.json", "host2-guest.json"} {
+				content, ok := tc.files[name]
+				if !ok {
+					continue
+				}
+				p := filepath.Join(dir, name)
+				if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, p)
+			}
+			path := filepath.Join(dir, "guest.tar.gz")
+			manifest, err := Write(path, paths, now)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Write() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if len(manifest.Files) != len(tc.files) || !manifest.Created.Equal(now) {
+				t.Errorf("Write() returned manifest %+v, want %d files created at %v", manifest, len(tc.files), now)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := tar.NewReader(gz)
+			var gotFiles []string
+			got := map[string]string{}
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotFiles = append(gotFiles, hdr.Name)
+				got[hdr.Name] = string(b)
+			}
+			if diff := cmp.Diff(tc.wantFiles, gotFiles); diff != "" {
+				t.Errorf("archive files returned unexpected diff (-want +got):\n%s", diff)
+			}
+			var gotManifest Manifest
+			if err := json.Unmarshal([]byte(got[ManifestName]), &gotManifest); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(*manifest, gotManifest); diff != "" {
+				t.Errorf("archived manifest returned unexpected diff (-want +got):\n%s", diff)
+			}
+			for name, content := range tc.files {
+				if got[name] != content {
+					t.Errorf("archived %s = %q, want %q", name, got[name], content)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDuplicateName(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, sub := range []string{"a", "b"} {
+		p := filepath.Join(dir, sub, "host-guest.json")
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	if _, err := Write(filepath.Join(dir, "guest.tar.gz"), paths, time.Now()); err == nil {
+		t.Error("Write() returned nil error for duplicate file names, want error")
+	}
+}
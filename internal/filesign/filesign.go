@@ -0,0 +1,89 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filesign signs files with HMAC-SHA256 so that modifications after they were written can
+// be detected. The signature of a file is stored hex-encoded in a sidecar file next to it.
+package filesign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Extension is the extension appended to the path of a file to get the path of its signature.
+const Extension = ".sig"
+
+// SidecarPath returns the path of the signature of the file.
+func SidecarPath(path string) string {
+	return path + Extension
+}
+
+// Sign writes the signature of the file with the key to its sidecar file.
+func Sign(path string, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("empty signing key")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return SignData(path, b, key)
+}
+
+// SignData writes the signature of data, the content just written to the file, with the key to
+// the sidecar file of the file. The file is not read, so that a modification made after data was
+// written is detected by Verify.
+func SignData(path string, data, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("empty signing key")
+	}
+	return os.WriteFile(SidecarPath(path), []byte(hex.EncodeToString(signature(data, key))+"\n"), 0644)
+}
+
+// Verify returns an error if the file does not match the signature in its sidecar file, e.g.
+// because the file was modified after it was signed or it was signed with another key.
+func Verify(path string, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("empty signing key")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s, err := os.ReadFile(SidecarPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to read the signature: %w", err)
+	}
+	want, err := hex.DecodeString(strings.TrimSpace(string(s)))
+	if err != nil {
+		return fmt.Errorf("invalid signature in %s: %v", SidecarPath(path), err)
+	}
+	if !hmac.Equal(signature(b, key), want) {
+		return fmt.Errorf("signature mismatch: %s was modified or signed with another key", path)
+	}
+	return nil
+}
+
+// signature returns the HMAC-SHA256 of the data with the key.
+func signature(data, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("signing-key")
+	testcases := []struct {
+		name    string
+		modify  func(t *testing.T, path string)
+		key     []byte
+		wantErr bool
+	}{
+		{
+			name:   "unmodified file",
+			modify: func(t *testing.T, path string) {},
+			key:    key,
+		},
+		{
+			name: "modified file",
+			modify: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte(`{"instance": "other"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			key:     key,
+			wantErr: true,
+		},
+		{
+			name:    "other key",
+			modify:  func(t *testing.T, path string) {},
+			key:     []byte("other-key"),
+			wantErr: true,
+		},
+		{
+			name: "missing signature",
+			modify: func(t *testing.T, path string) {
+				if err := os.Remove(SidecarPath(path)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			key:     key,
+			wantErr: true,
+		},
+		{
+			name: "invalid signature",
+			modify: func(t *testing.T, path string) {
+				if err := os.WriteFile(SidecarPath(path), []byte("not hex"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			key:     key,
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			modify:  func(t *testing.T, path string) {},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "localhost-sql.json")
+			if err := os.WriteFile(path, []byte(`{"instance": "localhost"}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Sign(path, key); err != nil {
+				t.Fatalf("Sign() returned error: %v", err)
+			}
+			tc.modify(t, path)
+			if err := Verify(path, tc.key); (err != nil) != tc.wantErr {
+				t.Errorf("Verify() = %v, want error presence = %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignData(t *testing.T) {
+	key := []byte("signing-key")
+	path := filepath.Join(t.TempDir(), "localhost-sql.json")
+	data := []byte(`{"instance": "localhost"}`)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SignData(path, data, key); err != nil {
+		t.Fatalf("SignData() returned error: %v", err)
+	}
+	if err := Verify(path, key); err != nil {
+		t.Errorf("Verify() returned error: %v", err)
+	}
+	if err := SignData(path, []byte(`{"instance": "other"}`), key); err != nil {
+		t.Fatalf("SignData() returned error: %v", err)
+	}
+	if err := Verify(path, key); err == nil {
+		t.Error("Verify() of a file not matching the signed data returned nil, want error")
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := Sign(filepath.Join(dir, "missing.json"), []byte("signing-key")); err == nil {
+		t.Error("Sign() of a missing file returned nil, want error")
+	}
+	path := filepath.Join(dir, "localhost-sql.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sign(path, nil); err == nil {
+		t.Error("Sign() with an empty key returned nil, want error")
+	}
+}
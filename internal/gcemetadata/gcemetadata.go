@@ -0,0 +1,149 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gcemetadata reads the properties of the compute engine instance the agent runs on from
+// the metadata server, with a timeout for each request and retries with exponential backoff.
+package gcemetadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+)
+
+// DefaultURL is the URL of the metadata server of compute engine.
+const DefaultURL = "http://metadata.google.internal/computeMetadata/v1"
+
+// zonePattern matches the zone name of the "projects/[number]/zones/[zone]" zone of the instance.
+var zonePattern = regexp.MustCompile("zones/([^/]*)")
+
+// Properties are the properties of the instance read from the metadata server.
+type Properties struct {
+	ProjectID        string
+	NumericProjectID string
+	InstanceID       string
+	InstanceName     string
+	Zone             string
+	Image            string
+}
+
+// Client reads the properties of the instance from the metadata server.
+type Client struct {
+	// URL is the URL of the metadata server, DefaultURL if empty.
+	URL string
+	// Timeout is the timeout of each request to the metadata server.
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+	// InitialInterval is the wait before the first retry, which doubles for every further retry.
+	InitialInterval time.Duration
+}
+
+// response is the part of the recursive response of the metadata server read by the client.
+type response struct {
+	Project struct {
+		ProjectID        string `json:"projectId"`
+		NumericProjectID int64  `json:"numericProjectId"`
+	} `json:"project"`
+	Instance struct {
+		ID    int64  `json:"id"`
+		Zone  string `json:"zone"`
+		Name  string `json:"name"`
+		Image string `json:"image"`
+	} `json:"instance"`
+}
+
+// Properties returns the properties of the instance. Failed and incomplete responses are retried
+// with exponential backoff until the retries are exhausted or ctx is done.
+func (c Client) Properties(ctx context.Context) (*Properties, error) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = c.InitialInterval
+	b.MaxElapsedTime = 0
+	var properties *Properties
+	attempt := 0
+	get := func() error {
+		attempt++
+		p, err := c.request(ctx)
+		if err != nil {
+			if attempt <= c.MaxRetries {
+				log.Logger.Warnw("Failed to read the instance properties from the metadata server, retrying", "attempt", attempt, "error", err)
+			}
+			return err
+		}
+		properties = p
+		return nil
+	}
+	if err := backoff.Retry(get, backoff.WithContext(backoff.WithMaxRetries(b, uint64(c.MaxRetries)), ctx)); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// request reads the properties of the instance with a single request to the metadata server.
+func (c Client) request(ctx context.Context) (*Properties, error) {
+	url := c.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/?recursive=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Metadata-Flavor", "Google")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive response from metadata server: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unsuccessful response from metadata server: %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body from metadata server: %v", err)
+	}
+	r := response{}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body from metadata server: %v", err)
+	}
+	p := &Properties{
+		ProjectID:        r.Project.ProjectID,
+		NumericProjectID: strconv.FormatInt(r.Project.NumericProjectID, 10),
+		InstanceID:       strconv.FormatInt(r.Instance.ID, 10),
+		InstanceName:     r.Instance.Name,
+		Image:            r.Instance.Image,
+	}
+	if match := zonePattern.FindStringSubmatch(r.Instance.Zone); len(match) == 2 {
+		p.Zone = match[1]
+	}
+	if p.Image == "" {
+		p.Image = "unknown"
+	}
+	if p.ProjectID == "" || r.Project.NumericProjectID == 0 || r.Instance.ID == 0 || p.Zone == "" || p.InstanceName == "" {
+		return nil, fmt.Errorf("metadata server responded with incomplete information")
+	}
+	return p, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcemetadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const validResponse = `{
+	"project": {"projectId": "test-project", "numericProjectId": 123},
+	"instance": {"id": 456, "name": "test-instance", "zone": "projects/123/zones/us-central1-a", "image": "projects/windows-cloud/global/images/windows"}
+}`
+
+// server returns a metadata server failing the first failures requests with a 503 response.
+func server(t *testing.T, failures int, body string, requests *int) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("request header Metadata-Flavor = %q, want Google", r.Header.Get("Metadata-Flavor"))
+		}
+		if *requests <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestProperties(t *testing.T) {
+	testcases := []struct {
+		name         string
+		failures     int
+		body         string
+		maxRetries   int
+		want         *Properties
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:       "first request succeeds",
+			body:       validResponse,
+			maxRetries: 2,
+			want: &Properties{
+				ProjectID:        "test-project",
+				NumericProjectID: "123",
+				InstanceID:       "456",
+				InstanceName:     "test-instance",
+				Zone:             "us-central1-a",
+				Image:            "projects/windows-cloud/global/images/windows",
+			},
+			wantRequests: 1,
+		},
+		{
+			name:       "retried request succeeds",
+			failures:   2,
+			body:       validResponse,
+			maxRetries: 2,
+			want: &Properties{
+				ProjectID:        "test-project",
+				NumericProjectID: "123",
+				InstanceID:       "456",
+				InstanceName:     "test-instance",
+				Zone:             "us-central1-a",
+				Image:            "projects/windows-cloud/global/images/windows",
+			},
+			wantRequests: 3,
+		},
+		{
+			name:         "retries exhausted",
+			failures:     3,
+			body:         validResponse,
+			maxRetries:   2,
+			wantRequests: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "incomplete response",
+			body:         `{"project": {"projectId": "test-project"}}`,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			s := server(t, tc.failures, tc.body, &requests)
+			c := Client{URL: s.URL, Timeout: time.Second, MaxRetries: tc.maxRetries, InitialInterval: time.Millisecond}
+			got, err := c.Properties(context.Background())
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Properties() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Properties() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("Properties() sent %d requests, want %d", requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPropertiesTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+	c := Client{URL: s.URL, Timeout: 10 * time.Millisecond, InitialInterval: time.Millisecond}
+	if _, err := c.Properties(context.Background()); err == nil {
+		t.Errorf("Properties() of a slow metadata server succeeded, want error")
+	}
+}
@@ -18,15 +18,58 @@ package guestcollector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/cyclestatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
 )
 
 // GuestCollector interface.
 type GuestCollector interface {
 	CollectGuestRules(context.Context, time.Duration) internal.Details
+	// CollectGuestRule collects a single guest rule along with the rules it depends on.
+	CollectGuestRule(context.Context, string, time.Duration) internal.Details
+}
+
+// ruleDependencies are the rules collected before a guest rule that reads their results. The
+// dependencies a collector does not have are skipped.
+var ruleDependencies = map[string][]string{
+	// The data disks of linux are found while classifying the disks.
+	internal.DataDiskAllocationUnitsRule: {internal.LocalSSDRule},
+	// The disk types of windows are mapped from the partitions and the physical disks.
+	internal.LocalSSDRule: {internal.LogicalDiskToPartition, internal.PhysicalDiskToType},
+}
+
+// ruleWithDependencies returns the set of the rule and the rules it depends on.
+func ruleWithDependencies(rule string) map[string]bool {
+	rules := map[string]bool{rule: true}
+	for _, r := range ruleDependencies[rule] {
+		rules[r] = true
+	}
+	return rules
+}
+
+// errGuestRuleFailed is recorded in the trace of a guest rule that failed to run.
+var errGuestRuleFailed = errors.New("guest rule failed")
+
+// recordRule records the outcome of a guest rule, which failed if err is not nil, in the cycle and
+// the manifest of the context.
+func recordRule(ctx context.Context, rule string, err error) {
+	cyclestatus.FromContext(ctx).Rule(err)
+	switch {
+	case err == nil:
+		manifest.FromContext(ctx).Add(rule, manifest.Ran, "")
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		manifest.FromContext(ctx).Add(rule, manifest.Failed, manifest.FailedTimeout)
+	default:
+		manifest.FromContext(ctx).Add(rule, manifest.Failed, manifest.FailedCommand)
+	}
 }
 
 // allOSFields are all expected fields in OS collection in collection order.
@@ -36,6 +79,176 @@ var allOSFields = []string{
 	internal.LocalSSDRule,
 	internal.DataDiskAllocationUnitsRule,
 	internal.GCBDRAgentRunning,
+	internal.SQLServiceAccountRule,
+	internal.PageFileRule,
+	internal.SQLNetworkProtocolsRule,
+	internal.SQLAgentServiceRule,
+}
+
+// Account types reported by the sql_service_account rule.
+const (
+	builtinAccount        = "BUILTIN"
+	virtualAccount        = "VIRTUAL_ACCOUNT"
+	managedServiceAccount = "MANAGED_SERVICE_ACCOUNT"
+	userAccount           = "USER"
+)
+
+// builtinAccounts are the built-in accounts that are discouraged for running SQL Server.
+var builtinAccounts = map[string]bool{
+	"localsystem":                  true,
+	"nt authority\\system":         true,
+	"nt authority\\localservice":   true,
+	"nt authority\\networkservice": true,
+	"root":                         true,
+}
+
+// serviceAccount is the account a SQL Server service runs as.
+type serviceAccount struct {
+	Name        string
+	StartName   string
+	AccountType string
+	IsBuiltin   bool
+}
+
+// newServiceAccount classifies the start name of a service and returns its serviceAccount.
+func newServiceAccount(name, startName string) serviceAccount {
+	accountType := ServiceAccountType(startName)
+	return serviceAccount{
+		Name:        name,
+		StartName:   startName,
+		AccountType: accountType,
+		IsBuiltin:   accountType == builtinAccount,
+	}
+}
+
+// ServiceAccountType returns the type of the account a service runs as.
+// Group managed service accounts and standalone managed service accounts end with "$".
+func ServiceAccountType(startName string) string {
+	name := strings.ToLower(strings.TrimSpace(startName))
+	switch {
+	case builtinAccounts[name]:
+		return builtinAccount
+	case strings.HasPrefix(name, "nt service\\"):
+		return virtualAccount
+	case strings.HasSuffix(name, "$"):
+		return managedServiceAccount
+	default:
+		return userAccount
+	}
+}
+
+// sqlAgentService is the SQL Server Agent service of a SQL Server instance. State and StartMode
+// are the state and start mode of the windows service, e.g. "Running" and "Auto". On linux the
+// agent runs inside SQL Server when it is enabled, so it runs when SQL Server runs and starts
+// automatically when SQL Server does. No jobs, including backups, run while Running is false.
+type sqlAgentService struct {
+	Name      string
+	State     string
+	StartMode string
+	Running   bool
+	AutoStart bool
+}
+
+// pageFile is a page file on windows or a swap area on linux.
+// Sizes are in megabytes. InitialSizeMB and MaximumSizeMB are only reported on windows and are 0
+// for page files managed by the system.
+type pageFile struct {
+	Name          string
+	Type          string `json:",omitempty"`
+	SizeMB        int64
+	UsedMB        int64
+	InitialSizeMB int64 `json:",omitempty"`
+	MaximumSizeMB int64 `json:",omitempty"`
+}
+
+// networkProtocols are the network protocols a SQL Server instance accepts connections on.
+// TCPPort is the static TCP port and TCPDynamicPorts the dynamic TCP ports, empty if not
+// configured. LegacyProtocolsEnabled flags Named Pipes or VIA, which are discouraged.
+type networkProtocols struct {
+	Instance               string
+	TCPEnabled             bool
+	TCPPort                string
+	TCPDynamicPorts        string
+	NamedPipesEnabled      bool
+	SharedMemoryEnabled    bool
+	LegacyProtocolsEnabled bool
+}
+
+// localSSDSize is the size of a compute engine local SSD; local SSD disks are multiples of it.
+const localSSDSize = 402653184000
+
+// DiskClassifier returns the type of a physical disk from its friendly name, the product of the
+// disk on linux, its size in bytes and its media type, which is 0 if unknown. It returns false if
+// it does not recognize the disk.
+type DiskClassifier func(friendlyName string, size int64, mediaType int16) (string, bool)
+
+// GCEDiskClassifier recognizes the disks of compute engine. Local SSDs and persistent SSDs are
+// reported as such and the other compute engine disks as OTHER.
+func GCEDiskClassifier(friendlyName string, size int64, mediaType int16) (string, bool) {
+	switch friendlyName {
+	case "nvme_card", "Google EphemeralDisk", ephemeralDisk:
+		if size%localSSDSize == 0 {
+			return internal.LocalSSD.String(), true
+		}
+		return internal.Other.String(), true
+	case "Google PersistentDisk":
+		if mediaType == 4 {
+			return internal.PersistentSSD.String(), true
+		}
+		return internal.Other.String(), true
+	case persistentDisk:
+		return internal.PersistentSSD.String(), true
+	}
+	return "", false
+}
+
+// DiskTypeMapping maps the disks whose friendly name matches FriendlyName and whose size is a
+// multiple of SizeMultiple, if not 0, to DiskType.
+type DiskTypeMapping struct {
+	FriendlyName *regexp.Regexp
+	SizeMultiple int64
+	DiskType     string
+}
+
+// NewDiskClassifier returns a classifier trying the mappings in order before GCEDiskClassifier.
+func NewDiskClassifier(mappings []DiskTypeMapping) DiskClassifier {
+	return func(friendlyName string, size int64, mediaType int16) (string, bool) {
+		for _, m := range mappings {
+			if m.FriendlyName.MatchString(friendlyName) && (m.SizeMultiple == 0 || size%m.SizeMultiple == 0) {
+				return m.DiskType, true
+			}
+		}
+		return GCEDiskClassifier(friendlyName, size, mediaType)
+	}
+}
+
+// diskClassifier classifies the physical disks of the guest collections.
+var diskClassifier = struct {
+	mu       sync.Mutex
+	classify DiskClassifier
+}{classify: GCEDiskClassifier}
+
+// SetDiskClassifier sets the classifier of the physical disks of the guest collections.
+// GCEDiskClassifier is used by default.
+func SetDiskClassifier(c DiskClassifier) {
+	diskClassifier.mu.Lock()
+	defer diskClassifier.mu.Unlock()
+	diskClassifier.classify = c
+}
+
+// classifyDisk returns the type of a physical disk. Disks that are not recognized keep their
+// friendly name, or are reported as OTHER if they have none.
+func classifyDisk(friendlyName string, size int64, mediaType int16) string {
+	diskClassifier.mu.Lock()
+	classify := diskClassifier.classify
+	diskClassifier.mu.Unlock()
+	if diskType, ok := classify(friendlyName, size, mediaType); ok {
+		return diskType
+	}
+	if name := strings.TrimSpace(friendlyName); name != "" {
+		return name
+	}
+	return internal.Other.String()
 }
 
 // CollectionOSFields returns all expected fields in OS collection
@@ -60,6 +273,10 @@ func MarkUnknownOsFields(details *[]internal.Details) error {
 			internal.LocalSSDRule:                "unknown",
 			internal.DataDiskAllocationUnitsRule: "unknown",
 			internal.GCBDRAgentRunning:           "unknown",
+			internal.SQLServiceAccountRule:       "unknown",
+			internal.PageFileRule:                "unknown",
+			internal.SQLNetworkProtocolsRule:     "unknown",
+			internal.SQLAgentServiceRule:         "unknown",
 		}
 		(*details)[0].Fields = append((*details)[0].Fields, fields)
 		return nil
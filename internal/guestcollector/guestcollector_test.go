@@ -17,6 +17,7 @@ limitations under the License.
 package guestcollector
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/jonboulle/clockwork"
@@ -49,6 +50,10 @@ func TestCheckOSCollectedMetrics(t *testing.T) {
 							internal.LocalSSDRule:                "unknown",
 							internal.DataDiskAllocationUnitsRule: "unknown",
 							internal.GCBDRAgentRunning:           "unknown",
+							internal.SQLServiceAccountRule:       "unknown",
+							internal.PageFileRule:                "unknown",
+							internal.SQLNetworkProtocolsRule:     "unknown",
+							internal.SQLAgentServiceRule:         "unknown",
 						},
 					},
 				},
@@ -75,6 +80,10 @@ func TestCheckOSCollectedMetrics(t *testing.T) {
 							internal.LocalSSDRule:                "unknown",
 							internal.DataDiskAllocationUnitsRule: "unknown",
 							internal.GCBDRAgentRunning:           "unknown",
+							internal.SQLServiceAccountRule:       "unknown",
+							internal.PageFileRule:                "unknown",
+							internal.SQLNetworkProtocolsRule:     "unknown",
+							internal.SQLAgentServiceRule:         "unknown",
 						},
 					},
 				},
@@ -101,6 +110,10 @@ func TestCheckOSCollectedMetrics(t *testing.T) {
 							internal.LocalSSDRule:                "unknown",
 							internal.DataDiskAllocationUnitsRule: "unknown",
 							internal.GCBDRAgentRunning:           "unknown",
+							internal.SQLServiceAccountRule:       "unknown",
+							internal.PageFileRule:                "unknown",
+							internal.SQLNetworkProtocolsRule:     "unknown",
+							internal.SQLAgentServiceRule:         "unknown",
 							"testing":                            "any output",
 						},
 					},
@@ -185,3 +198,53 @@ func TestCheckOSCollectedMetrics_BadInput(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceAccountType(t *testing.T) {
+	tests := []struct {
+		startName string
+		want      string
+	}{
+		{startName: "LocalSystem", want: "BUILTIN"},
+		{startName: `NT AUTHORITY\NetworkService`, want: "BUILTIN"},
+		{startName: `NT Service\MSSQLSERVER`, want: "VIRTUAL_ACCOUNT"},
+		{startName: `CONTOSO\sqlgmsa$`, want: "MANAGED_SERVICE_ACCOUNT"},
+		{startName: `CONTOSO\sqluser`, want: "USER"},
+		{startName: "mssql", want: "USER"},
+	}
+	for _, tc := range tests {
+		if got := ServiceAccountType(tc.startName); got != tc.want {
+			t.Errorf("ServiceAccountType(%q) = %q, want: %q", tc.startName, got, tc.want)
+		}
+	}
+}
+
+func TestClassifyDisk(t *testing.T) {
+	t.Cleanup(func() { SetDiskClassifier(GCEDiskClassifier) })
+	SetDiskClassifier(NewDiskClassifier([]DiskTypeMapping{
+		{FriendlyName: regexp.MustCompile(`^NETAPP LUN`), DiskType: "SAN"},
+		{FriendlyName: regexp.MustCompile(`(?i)nvme`), SizeMultiple: 1 << 30, DiskType: "LOCAL-NVME"},
+	}))
+	testcases := []struct {
+		name         string
+		friendlyName string
+		size         int64
+		mediaType    int16
+		want         string
+	}{
+		{name: "custom mapping", friendlyName: "NETAPP LUN C-Mode", size: 10, want: "SAN"},
+		{name: "custom mapping with size", friendlyName: "Samsung NVMe", size: 4 << 30, want: "LOCAL-NVME"},
+		{name: "size not matching falls back to compute engine", friendlyName: "nvme_card", size: 10, want: "OTHER"},
+		{name: "compute engine local ssd", friendlyName: "Google EphemeralDisk", size: 2 * localSSDSize, want: "LOCAL-SSD"},
+		{name: "compute engine persistent ssd", friendlyName: "Google PersistentDisk", size: 10, mediaType: 4, want: "PERSISTENT-SSD"},
+		{name: "compute engine persistent disk on linux", friendlyName: "PersistentDisk", size: 10, want: "PERSISTENT-SSD"},
+		{name: "unknown disk keeps its friendly name", friendlyName: "Msft Virtual Disk ", size: 10, want: "Msft Virtual Disk"},
+		{name: "unknown disk without friendly name", size: 10, want: "OTHER"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyDisk(tc.friendlyName, tc.size, tc.mediaType); got != tc.want {
+				t.Errorf("classifyDisk(%q, %d, %d) = %q, want %q", tc.friendlyName, tc.size, tc.mediaType, got, tc.want)
+			}
+		})
+	}
+}
@@ -23,13 +23,29 @@ package guestcollector
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/StackExchange/wmi"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/tracing"
+)
+
+// WMIQueryFunc runs a WMI query and appends the rows to dst, a pointer to a slice of structs, in
+// the same way as wmi.Query.
+type WMIQueryFunc func(query string, dst any, connectServerArgs ...any) error
+
+// wmiQuery runs the WMI query. It is replaced in unit tests.
+var wmiQuery WMIQueryFunc = wmi.Query
+
+const (
+	logicalDiskToPartitionQuery = `SELECT antecedent, dependent FROM win32_logicaldisktopartition`
+	physicalDiskQuery           = `SELECT deviceid, friendlyname, size, mediatype FROM msft_physicaldisk`
 )
 
 // WindowsCollector is the collector for windows system.
@@ -56,6 +72,8 @@ type wmiConnectionArgs struct {
 	password  any
 	namespace string
 	query     string
+	// run runs the WMI queries of the rule, paced by the rate limit of the host.
+	run WMIQueryFunc
 }
 
 // NewWindowsCollector initializes and returns new WindowsCollector object.
@@ -78,53 +96,39 @@ func NewWindowsCollector(host, username, password any, usageMetricLogger agentst
 				ElementName string
 			}
 			// https://learn.microsoft.com/en-us/windows/win32/wmisdk/swbemlocator-connectserver
-			if err := wmi.Query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+			if err := connArgs.run(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
 				return "", err
 			}
+			if len(result) == 0 {
+				return "unknown", nil
+			}
 			return result[0].ElementName, nil
 		},
 	}
 	c.guestRuleWMIMap[internal.LogicalDiskToPartition] = wmiExecutor{
 		namespace: `root\cimv2`,
-		query:     `SELECT antecedent, dependent FROM win32_logicaldisktopartition`,
+		query:     logicalDiskToPartitionQuery,
 		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
-			var result []struct {
-				Antecedent string
-				Dependent  string
-			}
-			if err := wmi.Query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+			disks, err := logicalToPhysicalDisks(connArgs.run, connArgs)
+			if err != nil {
 				return "", err
 			}
-			// example output:
-			// Antecedent: \\[HOSTNAME]\root\cimv2:Win32_DiskPartition.DeviceID="Disk #0, Partition #1"
-			// Dependent: \\[HOSTNAME]\root\cimv2:Win32_LogicalDisk.DeviceID="C:"
-			for _, v := range result {
-				if re := regexp.MustCompile(`\.*\\root\\cimv2:Win32_DiskPartition\.DeviceID=\"Disk #(.*), Partition #.*\"`); re.MatchString(v.Antecedent) {
-					disk := re.FindStringSubmatch(v.Antecedent)[1]
-					if re = regexp.MustCompile(`\.*\\root\\cimv2:Win32_LogicalDisk\.DeviceID=\"(.*)\"`); re.MatchString(v.Dependent) {
-						logicalDisk := re.FindStringSubmatch(v.Dependent)[1]
-						c.logicalToPhysicalDiskMap[logicalDisk] = disk
-					}
-				}
+			for logicalDisk, disk := range disks {
+				c.logicalToPhysicalDiskMap[logicalDisk] = disk
 			}
 			return "", nil
 		},
 	}
 	c.guestRuleWMIMap[internal.PhysicalDiskToType] = wmiExecutor{
 		namespace: `root\microsoft\windows\storage`,
-		query:     `SELECT deviceid, friendlyname, size, mediatype FROM msft_physicaldisk`,
+		query:     physicalDiskQuery,
 		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
-			var result []struct {
-				DeviceID     string
-				FriendlyName string
-				Size         int64
-				MediaType    int16
-			}
-			if err := wmi.Query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+			types, err := physicalDiskTypes(connArgs.run, connArgs)
+			if err != nil {
 				return "", err
 			}
-			for _, v := range result {
-				c.physicalDiskToTypeMap[v.DeviceID] = FriendlyNameToDiskType(v.FriendlyName, v.Size, v.MediaType)
+			for disk, diskType := range types {
+				c.physicalDiskToTypeMap[disk] = diskType
 			}
 			return "", nil
 		},
@@ -138,7 +142,7 @@ func NewWindowsCollector(host, username, password any, usageMetricLogger agentst
 				BlockSize int64
 				Caption   string
 			}
-			if err := wmi.Query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+			if err := connArgs.run(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
 				return "", err
 			}
 			re := regexp.MustCompile(`.*Volume{.*}.*`)
@@ -151,6 +155,9 @@ func NewWindowsCollector(host, username, password any, usageMetricLogger agentst
 					r = append(r, v)
 				}
 			}
+			if len(r) == 0 {
+				return "unknown", nil
+			}
 			res, err := json.Marshal(r)
 			if err != nil {
 				return "", err
@@ -166,7 +173,7 @@ func NewWindowsCollector(host, username, password any, usageMetricLogger agentst
 			var result []struct {
 				Caption string
 			}
-			if err := wmi.Query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+			if err := connArgs.run(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
 				return "", err
 			}
 			if len(result) == 0 {
@@ -175,18 +182,440 @@ func NewWindowsCollector(host, username, password any, usageMetricLogger agentst
 			return "true", nil
 		},
 	}
+	c.guestRuleWMIMap[internal.SQLServiceAccountRule] = wmiExecutor{
+		namespace: `root\cimv2`,
+		isRule:    true,
+		query:     `SELECT name, startname FROM win32_service WHERE name = 'MSSQLSERVER' OR name LIKE 'MSSQL$%'`,
+		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+			var result []struct {
+				Name      string
+				StartName string
+			}
+			if err := connArgs.run(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			if len(result) == 0 {
+				return "unknown", nil
+			}
+			var accounts []serviceAccount
+			for _, v := range result {
+				accounts = append(accounts, newServiceAccount(v.Name, v.StartName))
+			}
+			res, err := json.Marshal(accounts)
+			if err != nil {
+				return "", err
+			}
+			return string(res), nil
+		},
+	}
+	c.guestRuleWMIMap[internal.SQLAgentServiceRule] = wmiExecutor{
+		namespace: `root\cimv2`,
+		isRule:    true,
+		query:     `SELECT name, state, startmode FROM win32_service WHERE name = 'SQLSERVERAGENT' OR name LIKE 'SQLAgent$%'`,
+		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+			var result []win32Service
+			if err := connArgs.run(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			return windowsSQLAgentServices(result)
+		},
+	}
+	c.guestRuleWMIMap[internal.PageFileRule] = wmiExecutor{
+		namespace: `root\cimv2`,
+		isRule:    true,
+		query:     `SELECT name, allocatedbasesize, currentusage FROM Win32_PageFileUsage`,
+		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+			var usage []win32PageFileUsage
+			if err := connArgs.run(connArgs.query, &usage, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			var settings []win32PageFileSetting
+			if err := connArgs.run(`SELECT name, initialsize, maximumsize FROM Win32_PageFileSetting`, &settings, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			return windowsPageFiles(usage, settings)
+		},
+	}
+	c.guestRuleWMIMap[internal.SQLNetworkProtocolsRule] = wmiExecutor{
+		isRule: true,
+		query:  `SELECT instancename, protocolname, enabled FROM ServerNetworkProtocol`,
+		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+			// The namespace is named after the newest SQL Server version installed, which manages the
+			// instances of all versions.
+			var err error
+			for _, namespace := range sqlServerManagementNamespaces {
+				var protocols []serverNetworkProtocol
+				if err = connArgs.run(connArgs.query, &protocols, connArgs.host, namespace, connArgs.username, connArgs.password); err != nil {
+					continue
+				}
+				var properties []serverNetworkProtocolProperty
+				if err = connArgs.run(`SELECT instancename, propertyname, propertystrval FROM ServerNetworkProtocolProperty WHERE protocolname = 'Tcp' AND ipaddressname = 'IPAll'`, &properties, connArgs.host, namespace, connArgs.username, connArgs.password); err != nil {
+					return "", err
+				}
+				return windowsNetworkProtocols(protocols, properties)
+			}
+			return "", err
+		},
+	}
+	c.guestRuleWMIMap[internal.WSFCClusterRule] = wmiExecutor{
+		namespace: `root\MSCluster`,
+		isRule:    true,
+		query:     `SELECT name, quorumtype FROM MSCluster_Cluster`,
+		runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+			var clusters []msclusterCluster
+			if err := connArgs.run(connArgs.query, &clusters, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				// The namespace only exists on the nodes of a failover cluster.
+				if invalidNamespace(err) {
+					return notClustered, nil
+				}
+				return "", err
+			}
+			if len(clusters) == 0 {
+				return notClustered, nil
+			}
+			var nodes []msclusterNode
+			if err := connArgs.run(`SELECT name, state, nodeweight, dynamicweight FROM MSCluster_Node`, &nodes, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			var witnesses []msclusterResource
+			if err := connArgs.run(`SELECT name, type, state FROM MSCluster_Resource WHERE type = 'File Share Witness' OR type = 'Cloud Witness'`, &witnesses, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+				return "", err
+			}
+			return windowsCluster(clusters[0], nodes, witnesses)
+		},
+	}
 	return &c
 }
 
-// LogicalDiskMediaType generates the logicalDrive : mediaType mappings and add the result to details.
-func (c *WindowsCollector) logicalDiskMediaType(details *internal.Details) {
-	logicalToTypeMap := map[string]string{}
-	for key, val := range c.logicalToPhysicalDiskMap {
-		v, ok := c.physicalDiskToTypeMap[val]
-		if ok {
-			logicalToTypeMap[key] = v
+// Volumes returns the volumes of the machine with their allocation unit sizes.
+// Volumes without a file system are skipped.
+func (c *WindowsCollector) Volumes(ctx context.Context) ([]internal.Volume, error) {
+	var result []struct {
+		BlockSize int64
+		Caption   string
+	}
+	if err := c.limitedQuery(ctx)(`SELECT caption, blocksize FROM win32_volume`, &result, c.host, `root\cimv2`, c.username, c.password); err != nil {
+		return nil, err
+	}
+	var volumes []internal.Volume
+	for _, v := range result {
+		if v.BlockSize == 0 {
+			continue
+		}
+		volumes = append(volumes, internal.Volume{Name: v.Caption, AllocationUnitSize: v.BlockSize})
+	}
+	return volumes, nil
+}
+
+// win32LogicalDisk is a local disk of the machine. Sizes are in bytes.
+type win32LogicalDisk struct {
+	DeviceID  string
+	Size      uint64
+	FreeSpace uint64
+}
+
+// LogicalDisks returns the local logical disks of the machine with their sizes and free space.
+// The disks are named after their root directory, such as "C:\".
+func (c *WindowsCollector) LogicalDisks(ctx context.Context) ([]internal.Volume, error) {
+	var result []win32LogicalDisk
+	if err := c.limitedQuery(ctx)(`SELECT deviceid, size, freespace FROM win32_logicaldisk WHERE drivetype = 3`, &result, c.host, `root\cimv2`, c.username, c.password); err != nil {
+		return nil, err
+	}
+	var disks []internal.Volume
+	for _, d := range result {
+		disks = append(disks, internal.Volume{
+			Name:      d.DeviceID + `\`,
+			SizeBytes: int64(d.Size),
+			FreeBytes: int64(d.FreeSpace),
+		})
+	}
+	return disks, nil
+}
+
+// win32ComputerSystem is the computer system of the machine.
+type win32ComputerSystem struct {
+	NumberOfLogicalProcessors uint32
+}
+
+// LogicalProcessors returns the number of logical processors of the machine.
+func (c *WindowsCollector) LogicalProcessors(ctx context.Context) (int64, error) {
+	var result []win32ComputerSystem
+	if err := c.limitedQuery(ctx)(`SELECT numberoflogicalprocessors FROM win32_computersystem`, &result, c.host, `root\cimv2`, c.username, c.password); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("no computer system found")
+	}
+	return int64(result[0].NumberOfLogicalProcessors), nil
+}
+
+// win32PageFileUsage is a page file in use. Sizes are in megabytes.
+type win32PageFileUsage struct {
+	Name              string
+	AllocatedBaseSize uint32
+	CurrentUsage      uint32
+}
+
+// win32PageFileSetting is a configured page file. Sizes are in megabytes and are 0 if the page
+// file is managed by the system.
+type win32PageFileSetting struct {
+	Name        string
+	InitialSize uint32
+	MaximumSize uint32
+}
+
+// windowsPageFiles merges the usage and the settings of the page files. Page files that are
+// configured but not in use yet are reported with their configured sizes.
+// An empty list is returned if no page file is configured.
+func windowsPageFiles(usage []win32PageFileUsage, settings []win32PageFileSetting) (string, error) {
+	pageFiles := []pageFile{}
+	index := map[string]int{}
+	for _, u := range usage {
+		index[strings.ToLower(u.Name)] = len(pageFiles)
+		pageFiles = append(pageFiles, pageFile{
+			Name:   u.Name,
+			SizeMB: int64(u.AllocatedBaseSize),
+			UsedMB: int64(u.CurrentUsage),
+		})
+	}
+	for _, s := range settings {
+		i, ok := index[strings.ToLower(s.Name)]
+		if !ok {
+			i = len(pageFiles)
+			pageFiles = append(pageFiles, pageFile{Name: s.Name, SizeMB: int64(s.InitialSize)})
 		}
+		pageFiles[i].InitialSizeMB = int64(s.InitialSize)
+		pageFiles[i].MaximumSizeMB = int64(s.MaximumSize)
+	}
+	res, err := json.Marshal(pageFiles)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// win32Service is the state and start mode of a windows service. State is e.g. "Running" or
+// "Stopped" and StartMode "Auto", "Manual" or "Disabled".
+type win32Service struct {
+	Name      string
+	State     string
+	StartMode string
+}
+
+// windowsSQLAgentServices returns the SQL Server Agent services of the SQL Server instances.
+// It returns "unknown" if no SQL Server Agent service is installed.
+func windowsSQLAgentServices(services []win32Service) (string, error) {
+	if len(services) == 0 {
+		return "unknown", nil
+	}
+	var agents []sqlAgentService
+	for _, s := range services {
+		agents = append(agents, sqlAgentService{
+			Name:      s.Name,
+			State:     s.State,
+			StartMode: s.StartMode,
+			Running:   strings.EqualFold(s.State, "Running"),
+			AutoStart: strings.EqualFold(s.StartMode, "Auto"),
+		})
 	}
+	res, err := json.Marshal(agents)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// sqlServerManagementNamespaces are the WMI namespaces of the SQL Server network configuration,
+// from SQL Server 2022 down to SQL Server 2008.
+var sqlServerManagementNamespaces = []string{
+	`root\Microsoft\SqlServer\ComputerManagement16`,
+	`root\Microsoft\SqlServer\ComputerManagement15`,
+	`root\Microsoft\SqlServer\ComputerManagement14`,
+	`root\Microsoft\SqlServer\ComputerManagement13`,
+	`root\Microsoft\SqlServer\ComputerManagement12`,
+	`root\Microsoft\SqlServer\ComputerManagement11`,
+	`root\Microsoft\SqlServer\ComputerManagement10`,
+}
+
+// serverNetworkProtocol is a network protocol of a SQL Server instance. The protocol names are
+// Sm (Shared Memory), Np (Named Pipes), Tcp and Via.
+type serverNetworkProtocol struct {
+	InstanceName string
+	ProtocolName string
+	Enabled      bool
+}
+
+// serverNetworkProtocolProperty is a property of the TCP protocol of a SQL Server instance, such as
+// TcpPort or TcpDynamicPorts.
+type serverNetworkProtocolProperty struct {
+	InstanceName   string
+	PropertyName   string
+	PropertyStrVal string
+}
+
+// windowsNetworkProtocols merges the protocols and the TCP ports of each SQL Server instance.
+// It returns "unknown" if no instance is found.
+func windowsNetworkProtocols(protocols []serverNetworkProtocol, properties []serverNetworkProtocolProperty) (string, error) {
+	var instances []*networkProtocols
+	index := map[string]*networkProtocols{}
+	instance := func(name string) *networkProtocols {
+		p, ok := index[name]
+		if !ok {
+			p = &networkProtocols{Instance: name}
+			index[name] = p
+			instances = append(instances, p)
+		}
+		return p
+	}
+	for _, p := range protocols {
+		inst := instance(p.InstanceName)
+		switch strings.ToLower(p.ProtocolName) {
+		case "tcp":
+			inst.TCPEnabled = p.Enabled
+		case "np":
+			inst.NamedPipesEnabled = p.Enabled
+			inst.LegacyProtocolsEnabled = inst.LegacyProtocolsEnabled || p.Enabled
+		case "sm":
+			inst.SharedMemoryEnabled = p.Enabled
+		case "via":
+			inst.LegacyProtocolsEnabled = inst.LegacyProtocolsEnabled || p.Enabled
+		}
+	}
+	if len(instances) == 0 {
+		return "unknown", nil
+	}
+	for _, p := range properties {
+		inst, ok := index[p.InstanceName]
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(p.PropertyName) {
+		case "tcpport":
+			inst.TCPPort = strings.TrimSpace(p.PropertyStrVal)
+		case "tcpdynamicports":
+			inst.TCPDynamicPorts = strings.TrimSpace(p.PropertyStrVal)
+		}
+	}
+	res, err := json.Marshal(instances)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// notClustered is reported by the wsfc_cluster rule on machines that are not a node of a failover
+// cluster.
+const notClustered = "not_clustered"
+
+// invalidNamespace returns true if the WMI query failed because the namespace does not exist
+// (WBEM_E_INVALID_NAMESPACE).
+func invalidNamespace(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "invalid namespace") || strings.Contains(msg, "0x8004100e")
+}
+
+// msclusterCluster is the failover cluster of the machine. QuorumType is the name of the quorum
+// configuration, e.g. "Node and File Share Majority".
+type msclusterCluster struct {
+	Name       string
+	QuorumType string
+}
+
+// msclusterNode is a node of the failover cluster. State is -1 (unknown), 0 (up), 1 (down),
+// 2 (paused) or 3 (joining). NodeWeight is the configured vote of the node and DynamicWeight its
+// current vote with dynamic quorum.
+type msclusterNode struct {
+	Name          string
+	State         int32
+	NodeWeight    uint32
+	DynamicWeight uint32
+}
+
+// msclusterResource is a resource of the failover cluster. State is -1 (unknown), 2 (online),
+// 3 (offline), 4 (failed), 128 (pending), 129 (online pending) or 130 (offline pending).
+type msclusterResource struct {
+	Name  string
+	Type  string
+	State int32
+}
+
+// wsfcCluster is the Windows Server Failover Cluster of the machine. Witness is nil if the quorum
+// has no file share or cloud witness.
+type wsfcCluster struct {
+	Name       string
+	QuorumType string
+	Witness    *wsfcWitness `json:",omitempty"`
+	NodesUp    int
+	Nodes      []wsfcNode
+}
+
+// wsfcNode is a node of a failover cluster. NodeWeight is the configured quorum vote of the node
+// and DynamicWeight its current vote.
+type wsfcNode struct {
+	Name          string
+	State         string
+	NodeWeight    int64
+	DynamicWeight int64
+}
+
+// wsfcWitness is the witness resource of the quorum of a failover cluster.
+type wsfcWitness struct {
+	Name  string
+	Type  string
+	State string
+}
+
+// clusterNodeStates are the names of the states of a cluster node.
+var clusterNodeStates = map[int32]string{0: "UP", 1: "DOWN", 2: "PAUSED", 3: "JOINING"}
+
+// clusterResourceStates are the names of the states of a cluster resource.
+var clusterResourceStates = map[int32]string{2: "ONLINE", 3: "OFFLINE", 4: "FAILED", 128: "PENDING", 129: "ONLINE_PENDING", 130: "OFFLINE_PENDING"}
+
+// stateName returns the name of the state, or "UNKNOWN" if the state is not in names.
+func stateName(names map[int32]string, state int32) string {
+	if name, ok := names[state]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// windowsCluster merges the cluster, its nodes and its file share or cloud witness. A disk witness
+// is reported by the quorum type only.
+func windowsCluster(cluster msclusterCluster, nodes []msclusterNode, witnesses []msclusterResource) (string, error) {
+	wc := wsfcCluster{
+		Name:       cluster.Name,
+		QuorumType: cluster.QuorumType,
+		Nodes:      []wsfcNode{},
+	}
+	for _, n := range nodes {
+		state := stateName(clusterNodeStates, n.State)
+		if state == "UP" {
+			wc.NodesUp++
+		}
+		wc.Nodes = append(wc.Nodes, wsfcNode{
+			Name:          n.Name,
+			State:         state,
+			NodeWeight:    int64(n.NodeWeight),
+			DynamicWeight: int64(n.DynamicWeight),
+		})
+	}
+	if len(witnesses) > 0 {
+		wc.Witness = &wsfcWitness{
+			Name:  witnesses[0].Name,
+			Type:  witnesses[0].Type,
+			State: stateName(clusterResourceStates, witnesses[0].State),
+		}
+	}
+	res, err := json.Marshal(wc)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// LogicalDiskMediaType generates the logicalDrive : mediaType mappings and add the result to details.
+func (c *WindowsCollector) logicalDiskMediaType(details *internal.Details) {
+	logicalToTypeMap := logicalDiskTypes(c.logicalToPhysicalDiskMap, c.physicalDiskToTypeMap)
 	if len(logicalToTypeMap) == 0 {
 		details.Fields[0][internal.LocalSSDRule] = "unknown"
 		return
@@ -200,19 +629,152 @@ func (c *WindowsCollector) logicalDiskMediaType(details *internal.Details) {
 	}
 }
 
+// win32LogicalDiskToPartition is a row of win32_logicaldisktopartition, e.g.
+// Antecedent: \\[HOSTNAME]\root\cimv2:Win32_DiskPartition.DeviceID="Disk #0, Partition #1"
+// Dependent: \\[HOSTNAME]\root\cimv2:Win32_LogicalDisk.DeviceID="C:"
+type win32LogicalDiskToPartition struct {
+	Antecedent string
+	Dependent  string
+}
+
+// msftPhysicalDisk is a row of msft_physicaldisk.
+type msftPhysicalDisk struct {
+	DeviceID     string
+	FriendlyName string
+	Size         int64
+	MediaType    int16
+}
+
+// logicalToPhysicalDisks returns the number of the physical disk of each logical disk.
+func logicalToPhysicalDisks(query WMIQueryFunc, connArgs wmiConnectionArgs) (map[string]string, error) {
+	var result []win32LogicalDiskToPartition
+	if err := query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+		return nil, err
+	}
+	partitionRe := regexp.MustCompile(`\.*\\root\\cimv2:Win32_DiskPartition\.DeviceID=\"Disk #(.*), Partition #.*\"`)
+	logicalDiskRe := regexp.MustCompile(`\.*\\root\\cimv2:Win32_LogicalDisk\.DeviceID=\"(.*)\"`)
+	disks := map[string]string{}
+	for _, v := range result {
+		disk := partitionRe.FindStringSubmatch(v.Antecedent)
+		logicalDisk := logicalDiskRe.FindStringSubmatch(v.Dependent)
+		if disk != nil && logicalDisk != nil {
+			disks[logicalDisk[1]] = disk[1]
+		}
+	}
+	return disks, nil
+}
+
+// physicalDiskTypes returns the type of each physical disk, keyed by its number.
+func physicalDiskTypes(query WMIQueryFunc, connArgs wmiConnectionArgs) (map[string]string, error) {
+	var result []msftPhysicalDisk
+	if err := query(connArgs.query, &result, connArgs.host, connArgs.namespace, connArgs.username, connArgs.password); err != nil {
+		return nil, err
+	}
+	types := map[string]string{}
+	for _, v := range result {
+		types[v.DeviceID] = FriendlyNameToDiskType(v.FriendlyName, v.Size, v.MediaType)
+	}
+	return types, nil
+}
+
+// logicalDiskTypes returns the type of the physical disk of each logical disk. Logical disks whose
+// physical disk has no type are left out.
+func logicalDiskTypes(logicalToPhysical, physicalToType map[string]string) map[string]string {
+	types := map[string]string{}
+	for logicalDisk, disk := range logicalToPhysical {
+		if t, ok := physicalToType[disk]; ok {
+			types[logicalDisk] = t
+		}
+	}
+	return types
+}
+
+// LogicalDiskTypes runs only the disk classification of the guest collection and returns the type
+// of each logical disk of the machine, as reported in local_ssd. The WMI queries are run by query,
+// which is wmi.Query for real machines; tools and tests may pass a function returning mock rows.
+// The disks are classified by the classifier set by SetDiskClassifier.
+func LogicalDiskTypes(query WMIQueryFunc, host, username, password any) (map[string]string, error) {
+	connArgs := wmiConnectionArgs{host: host, username: username, password: password}
+	connArgs.namespace, connArgs.query = `root\cimv2`, logicalDiskToPartitionQuery
+	logicalToPhysical, err := logicalToPhysicalDisks(query, connArgs)
+	if err != nil {
+		return nil, fmt.Errorf("mapping logical disks to physical disks: %w", err)
+	}
+	connArgs.namespace, connArgs.query = `root\microsoft\windows\storage`, physicalDiskQuery
+	physicalToType, err := physicalDiskTypes(query, connArgs)
+	if err != nil {
+		return nil, fmt.Errorf("classifying physical disks: %w", err)
+	}
+	return logicalDiskTypes(logicalToPhysical, physicalToType), nil
+}
+
+// target identifies the machine in the logs of repeated rule errors.
+func (c *WindowsCollector) target() string {
+	if c.host == nil {
+		return "localhost"
+	}
+	return fmt.Sprint(c.host)
+}
+
+// limitedQuery returns a WMI query function that takes a token of the rate limit of the target
+// before each query. Waiting for a token ends with ctx.
+func (c *WindowsCollector) limitedQuery(ctx context.Context) WMIQueryFunc {
+	limiter := wmiLimiter(c.target())
+	return func(query string, dst any, connectServerArgs ...any) error {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		return wmiQuery(query, dst, connectServerArgs...)
+	}
+}
+
 // CollectGuestRules collects all guest rules. The rules are defined in rules.go.
+// Each WMI query is paced by the rate limit set with SetWMIQueryRate.
 func (c *WindowsCollector) CollectGuestRules(ctx context.Context, timeout time.Duration) internal.Details {
+	return c.collectGuestRules(ctx, timeout, nil)
+}
+
+// CollectGuestRule collects the guest rule with the given name and the WMI queries it depends on.
+func (c *WindowsCollector) CollectGuestRule(ctx context.Context, rule string, timeout time.Duration) internal.Details {
+	return c.collectGuestRules(ctx, timeout, ruleWithDependencies(rule))
+}
+
+// collectGuestRules collects the selected guest rules, or all guest rules if selected is nil.
+func (c *WindowsCollector) collectGuestRules(ctx context.Context, timeout time.Duration, selected map[string]bool) internal.Details {
 	details := internal.Details{
 		Name: "OS",
 	}
 	fields := map[string]string{}
 	for rule, exe := range c.guestRuleWMIMap {
+		if selected != nil && !selected[rule] {
+			continue
+		}
 		func() {
+			_, endSpan := tracing.StartRule(ctx, rule)
 			ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 			ch := make(chan bool, 1)
 
 			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Logger.Errorf("Running windows guest rule %s panicked: %v", rule, r)
+						c.usageMetricLogger.Error(agentstatus.WMIQueryExecutionError)
+						if exe.isRule {
+							fields[rule] = "unknown"
+						}
+						ch <- false
+					}
+				}()
+				release, err := internal.AcquireRule(ctxWithTimeout)
+				if err != nil {
+					if exe.isRule {
+						fields[rule] = "unknown"
+					}
+					ch <- false
+					return
+				}
+				defer release()
 				connArgs := wmiConnectionArgs{
 					host:     c.host,
 					username: c.username,
@@ -220,9 +782,11 @@ func (c *WindowsCollector) CollectGuestRules(ctx context.Context, timeout time.D
 				}
 				connArgs.namespace = exe.namespace
 				connArgs.query = exe.query
+				connArgs.run = c.limitedQuery(ctxWithTimeout)
 				res, err := exe.runWMIQuery(connArgs)
+				key := errorlog.Key(c.target(), rule)
 				if err != nil {
-					log.Logger.Error(err)
+					errorlog.Default.Failed(key, err, "Failed to run WMI query", "query", exe.query)
 					c.usageMetricLogger.Error(agentstatus.WMIQueryExecutionError)
 					if exe.isRule {
 						fields[rule] = "unknown"
@@ -230,31 +794,36 @@ func (c *WindowsCollector) CollectGuestRules(ctx context.Context, timeout time.D
 					ch <- false
 					return
 				}
+				errorlog.Default.Succeeded(key)
 				if exe.isRule {
 					fields[rule] = res
 				}
 				ch <- true
 			}()
+			var ruleErr error
 			select {
 			case <-ctxWithTimeout.Done():
 				log.Logger.Errorf("Running windows guest rule %s timeout", rule)
 				c.usageMetricLogger.Error(agentstatus.WinGuestCollectionTimeout)
-			case <-ch:
+				ruleErr = ctxWithTimeout.Err()
+			case ok := <-ch:
+				if !ok {
+					ruleErr = errGuestRuleFailed
+				}
 			}
+			endSpan(ruleErr)
+			recordRule(ctx, rule, ruleErr)
 		}()
 	}
 	details.Fields = append(details.Fields, fields)
-	c.logicalDiskMediaType(&details)
+	if selected == nil || selected[internal.LocalSSDRule] {
+		c.logicalDiskMediaType(&details)
+	}
 	return details
 }
 
-// FriendlyNameToDiskType determines disk type based on name, size, and media type.
+// FriendlyNameToDiskType determines disk type based on name, size, and media type with the
+// classifier set by SetDiskClassifier. Disks that are not recognized keep their friendly name.
 func FriendlyNameToDiskType(friendlyName string, size int64, mediaType int16) string {
-	if (friendlyName == "nvme_card" || friendlyName == "Google EphemeralDisk") && size%402653184000 == 0 {
-		return internal.LocalSSD.String()
-	} else if friendlyName == "Google PersistentDisk" && mediaType == 4 {
-		return internal.PersistentSSD.String()
-	} else {
-		return internal.Other.String()
-	}
+	return classifyDisk(friendlyName, size, mediaType)
 }
@@ -47,6 +47,11 @@ func TestCollectGuestRules(t *testing.T) {
 						"local_ssd":                  `{"C:":"OTHER"}`,
 						"data_disk_allocation_units": `[{"BlockSize":4096,"Caption":"C:\\"},{"BlockSize":1024,"Caption":"D:\\"}]`,
 						"gcbdr_agent_running":        "false",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"wsfc_cluster":               "not_clustered",
 					},
 				},
 			},
@@ -113,6 +118,11 @@ func TestCollectGuestRules(t *testing.T) {
 						"local_ssd":                  "unknown",
 						"power_profile_setting":      "unknown",
 						"gcbdr_agent_running":        "unknown",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"wsfc_cluster":               "not_clustered",
 					},
 				},
 			},
@@ -139,6 +149,332 @@ func TestCollectGuestRules(t *testing.T) {
 	}
 }
 
+func TestCollectGuestRulesEmptyWMIResult(t *testing.T) {
+	defer func(q func(string, any, ...any) error) { wmiQuery = q }(wmiQuery)
+	wmiQuery = func(query string, dst any, connectServerArgs ...any) error {
+		return nil
+	}
+	want := internal.Details{
+		Name: "OS",
+		Fields: []map[string]string{
+			map[string]string{
+				"power_profile_setting":      "unknown",
+				"local_ssd":                  "unknown",
+				"data_disk_allocation_units": "unknown",
+				"gcbdr_agent_running":        "false",
+				"sql_service_account":        "unknown",
+				"page_file":                  "[]",
+				"sql_network_protocols":      "unknown",
+				"sql_agent_service":          "unknown",
+				"wsfc_cluster":               "not_clustered",
+			},
+		},
+	}
+
+	collector := NewWindowsCollector(nil, nil, nil, fakeUsageMetricsLogger)
+	got := collector.CollectGuestRules(context.Background(), time.Minute)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CollectGuestRules() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestCollectGuestRulesPanicInWMIQuery(t *testing.T) {
+	collector := NewWindowsCollector(nil, nil, nil, fakeUsageMetricsLogger)
+	collector.guestRuleWMIMap = map[string]wmiExecutor{
+		internal.PowerProfileSettingRule: wmiExecutor{
+			isRule: true,
+			runWMIQuery: func(connArgs wmiConnectionArgs) (string, error) {
+				var result []string
+				return result[0], nil
+			},
+		},
+	}
+	want := internal.Details{
+		Name: "OS",
+		Fields: []map[string]string{
+			map[string]string{
+				"power_profile_setting": "unknown",
+				"local_ssd":             "unknown",
+			},
+		},
+	}
+
+	got := collector.CollectGuestRules(context.Background(), time.Minute)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CollectGuestRules() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestWindowsPageFiles(t *testing.T) {
+	testcases := []struct {
+		name     string
+		usage    []win32PageFileUsage
+		settings []win32PageFileSetting
+		want     string
+	}{
+		{
+			name:     "system managed page file",
+			usage:    []win32PageFileUsage{{Name: `C:\pagefile.sys`, AllocatedBaseSize: 4096, CurrentUsage: 100}},
+			settings: []win32PageFileSetting{{Name: `c:\pagefile.sys`}},
+			want:     `[{"Name":"C:\\pagefile.sys","SizeMB":4096,"UsedMB":100}]`,
+		},
+		{
+			name:  "page file configured but not in use",
+			usage: []win32PageFileUsage{{Name: `C:\pagefile.sys`, AllocatedBaseSize: 4096, CurrentUsage: 100}},
+			settings: []win32PageFileSetting{
+				{Name: `C:\pagefile.sys`, InitialSize: 4096, MaximumSize: 8192},
+				{Name: `D:\pagefile.sys`, InitialSize: 1024, MaximumSize: 2048},
+			},
+			want: `[{"Name":"C:\\pagefile.sys","SizeMB":4096,"UsedMB":100,"InitialSizeMB":4096,"MaximumSizeMB":8192},` +
+				`{"Name":"D:\\pagefile.sys","SizeMB":1024,"UsedMB":0,"InitialSizeMB":1024,"MaximumSizeMB":2048}]`,
+		},
+		{
+			name: "no page file",
+			want: `[]`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsPageFiles(tc.usage, tc.settings)
+			if err != nil {
+				t.Fatalf("windowsPageFiles() returned an unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("windowsPageFiles() = %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowsSQLAgentServices(t *testing.T) {
+	testcases := []struct {
+		name     string
+		services []win32Service
+		want     string
+	}{
+		{
+			name: "default and named instances",
+			services: []win32Service{
+				{Name: "SQLSERVERAGENT", State: "Running", StartMode: "Auto"},
+				{Name: "SQLAgent$SQL2", State: "Stopped", StartMode: "Manual"},
+			},
+			want: `[{"Name":"SQLSERVERAGENT","State":"Running","StartMode":"Auto","Running":true,"AutoStart":true},` +
+				`{"Name":"SQLAgent$SQL2","State":"Stopped","StartMode":"Manual","Running":false,"AutoStart":false}]`,
+		},
+		{
+			name: "no agent service",
+			want: "unknown",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsSQLAgentServices(tc.services)
+			if err != nil {
+				t.Fatalf("windowsSQLAgentServices() returned an unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("windowsSQLAgentServices() = %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowsNetworkProtocols(t *testing.T) {
+	testcases := []struct {
+		name       string
+		protocols  []serverNetworkProtocol
+		properties []serverNetworkProtocolProperty
+		want       string
+	}{
+		{
+			name: "default and named instances",
+			protocols: []serverNetworkProtocol{
+				{InstanceName: "MSSQLSERVER", ProtocolName: "Sm", Enabled: true},
+				{InstanceName: "MSSQLSERVER", ProtocolName: "Np", Enabled: false},
+				{InstanceName: "MSSQLSERVER", ProtocolName: "Tcp", Enabled: true},
+				{InstanceName: "SQL2", ProtocolName: "Sm", Enabled: true},
+				{InstanceName: "SQL2", ProtocolName: "Np", Enabled: true},
+				{InstanceName: "SQL2", ProtocolName: "Tcp", Enabled: false},
+			},
+			properties: []serverNetworkProtocolProperty{
+				{InstanceName: "MSSQLSERVER", PropertyName: "TcpPort", PropertyStrVal: "1433"},
+				{InstanceName: "MSSQLSERVER", PropertyName: "TcpDynamicPorts", PropertyStrVal: ""},
+				{InstanceName: "SQL2", PropertyName: "TcpPort", PropertyStrVal: ""},
+				{InstanceName: "SQL2", PropertyName: "TcpDynamicPorts", PropertyStrVal: " 49152 "},
+			},
+			want: `[{"Instance":"MSSQLSERVER","TCPEnabled":true,"TCPPort":"1433","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":true,"LegacyProtocolsEnabled":false},` +
+				`{"Instance":"SQL2","TCPEnabled":false,"TCPPort":"","TCPDynamicPorts":"49152","NamedPipesEnabled":true,"SharedMemoryEnabled":true,"LegacyProtocolsEnabled":true}]`,
+		},
+		{
+			name: "via enabled",
+			protocols: []serverNetworkProtocol{
+				{InstanceName: "MSSQLSERVER", ProtocolName: "Via", Enabled: true},
+			},
+			want: `[{"Instance":"MSSQLSERVER","TCPEnabled":false,"TCPPort":"","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":true}]`,
+		},
+		{
+			name: "no instance",
+			want: "unknown",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsNetworkProtocols(tc.protocols, tc.properties)
+			if err != nil {
+				t.Fatalf("windowsNetworkProtocols() returned an unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("windowsNetworkProtocols() = %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowsCluster(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cluster   msclusterCluster
+		nodes     []msclusterNode
+		witnesses []msclusterResource
+		want      string
+	}{
+		{
+			name:    "file share witness",
+			cluster: msclusterCluster{Name: "SQLCLUSTER", QuorumType: "Node and File Share Majority"},
+			nodes: []msclusterNode{
+				{Name: "NODE1", State: 0, NodeWeight: 1, DynamicWeight: 1},
+				{Name: "NODE2", State: 1, NodeWeight: 1, DynamicWeight: 0},
+			},
+			witnesses: []msclusterResource{{Name: "File Share Witness", Type: "File Share Witness", State: 2}},
+			want: `{"Name":"SQLCLUSTER","QuorumType":"Node and File Share Majority","Witness":{"Name":"File Share Witness","Type":"File Share Witness","State":"ONLINE"},"NodesUp":1,` +
+				`"Nodes":[{"Name":"NODE1","State":"UP","NodeWeight":1,"DynamicWeight":1},{"Name":"NODE2","State":"DOWN","NodeWeight":1,"DynamicWeight":0}]}`,
+		},
+		{
+			name:    "no witness and unknown node state",
+			cluster: msclusterCluster{Name: "SQLCLUSTER", QuorumType: "Node Majority"},
+			nodes:   []msclusterNode{{Name: "NODE1", State: -1, NodeWeight: 1}},
+			want:    `{"Name":"SQLCLUSTER","QuorumType":"Node Majority","NodesUp":0,"Nodes":[{"Name":"NODE1","State":"UNKNOWN","NodeWeight":1,"DynamicWeight":0}]}`,
+		},
+		{
+			name:    "no nodes",
+			cluster: msclusterCluster{Name: "SQLCLUSTER"},
+			want:    `{"Name":"SQLCLUSTER","QuorumType":"","NodesUp":0,"Nodes":[]}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsCluster(tc.cluster, tc.nodes, tc.witnesses)
+			if err != nil {
+				t.Fatalf("windowsCluster() returned an unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("windowsCluster() = %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInvalidNamespace(t *testing.T) {
+	testcases := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("Exception occurred. (Invalid namespace )"), want: true},
+		{err: fmt.Errorf("SWbemLocator.ConnectServer: 0x8004100E"), want: true},
+		{err: fmt.Errorf("Access is denied."), want: false},
+	}
+	for _, tc := range testcases {
+		if got := invalidNamespace(tc.err); got != tc.want {
+			t.Errorf("invalidNamespace(%v) = %v, want: %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	defer func(q func(string, any, ...any) error) { wmiQuery = q }(wmiQuery)
+	wmiQuery = func(query string, dst any, connectServerArgs ...any) error {
+		volumes, ok := dst.(*[]struct {
+			BlockSize int64
+			Caption   string
+		})
+		if !ok {
+			return fmt.Errorf("unexpected destination %T", dst)
+		}
+		*volumes = append(*volumes,
+			struct {
+				BlockSize int64
+				Caption   string
+			}{BlockSize: 4096, Caption: `C:\`},
+			struct {
+				BlockSize int64
+				Caption   string
+			}{BlockSize: 65536, Caption: `D:\mnt\data\`},
+			struct {
+				BlockSize int64
+				Caption   string
+			}{Caption: `E:\`})
+		return nil
+	}
+	c := NewWindowsCollector(nil, nil, nil, nil)
+	got, err := c.Volumes(context.Background())
+	if err != nil {
+		t.Fatalf("Volumes() returned an unexpected error: %v", err)
+	}
+	want := []internal.Volume{
+		{Name: `C:\`, AllocationUnitSize: 4096},
+		{Name: `D:\mnt\data\`, AllocationUnitSize: 65536},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Volumes() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestLogicalDisks(t *testing.T) {
+	defer func(q func(string, any, ...any) error) { wmiQuery = q }(wmiQuery)
+	wmiQuery = func(query string, dst any, connectServerArgs ...any) error {
+		disks, ok := dst.(*[]win32LogicalDisk)
+		if !ok {
+			return fmt.Errorf("unexpected destination %T", dst)
+		}
+		*disks = append(*disks,
+			win32LogicalDisk{DeviceID: "C:", Size: 100000, FreeSpace: 5000},
+			win32LogicalDisk{DeviceID: "D:", Size: 200000, FreeSpace: 150000})
+		return nil
+	}
+	c := NewWindowsCollector(nil, nil, nil, nil)
+	got, err := c.LogicalDisks(context.Background())
+	if err != nil {
+		t.Fatalf("LogicalDisks() returned an unexpected error: %v", err)
+	}
+	want := []internal.Volume{
+		{Name: `C:\`, SizeBytes: 100000, FreeBytes: 5000},
+		{Name: `D:\`, SizeBytes: 200000, FreeBytes: 150000},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("LogicalDisks() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestLogicalProcessors(t *testing.T) {
+	defer func(q func(string, any, ...any) error) { wmiQuery = q }(wmiQuery)
+	wmiQuery = func(query string, dst any, connectServerArgs ...any) error {
+		systems, ok := dst.(*[]win32ComputerSystem)
+		if !ok {
+			return fmt.Errorf("unexpected destination %T", dst)
+		}
+		*systems = append(*systems, win32ComputerSystem{NumberOfLogicalProcessors: 8})
+		return nil
+	}
+	c := NewWindowsCollector(nil, nil, nil, nil)
+	got, err := c.LogicalProcessors(context.Background())
+	if err != nil {
+		t.Fatalf("LogicalProcessors() returned an unexpected error: %v", err)
+	}
+	if got != 8 {
+		t.Errorf("LogicalProcessors() = %d, want 8", got)
+	}
+}
+
 func TestLogicalDiskMediaType(t *testing.T) {
 	testcases := []struct {
 		name                      string
@@ -206,6 +542,49 @@ func TestLogicalDiskMediaType(t *testing.T) {
 	}
 }
 
+func TestLogicalDiskTypes(t *testing.T) {
+	query := func(query string, dst any, connectServerArgs ...any) error {
+		switch rows := dst.(type) {
+		case *[]win32LogicalDiskToPartition:
+			*rows = append(*rows,
+				win32LogicalDiskToPartition{
+					Antecedent: `\\HOST\root\cimv2:Win32_DiskPartition.DeviceID="Disk #0, Partition #1"`,
+					Dependent:  `\\HOST\root\cimv2:Win32_LogicalDisk.DeviceID="C:"`,
+				},
+				win32LogicalDiskToPartition{
+					Antecedent: `\\HOST\root\cimv2:Win32_DiskPartition.DeviceID="Disk #1, Partition #0"`,
+					Dependent:  `\\HOST\root\cimv2:Win32_LogicalDisk.DeviceID="D:"`,
+				},
+				win32LogicalDiskToPartition{
+					Antecedent: `\\HOST\root\cimv2:Win32_DiskPartition.DeviceID="Disk #2, Partition #0"`,
+					Dependent:  `\\HOST\root\cimv2:Win32_LogicalDisk.DeviceID="E:"`,
+				})
+		case *[]msftPhysicalDisk:
+			*rows = append(*rows,
+				msftPhysicalDisk{DeviceID: "0", FriendlyName: "Google PersistentDisk", Size: 10, MediaType: 4},
+				msftPhysicalDisk{DeviceID: "1", FriendlyName: "nvme_card", Size: 402653184000, MediaType: 4})
+		default:
+			return fmt.Errorf("unexpected destination %T", dst)
+		}
+		return nil
+	}
+	got, err := LogicalDiskTypes(query, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("LogicalDiskTypes() returned an unexpected error: %v", err)
+	}
+	want := map[string]string{"C:": "PERSISTENT-SSD", "D:": "LOCAL-SSD"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("LogicalDiskTypes() returned wrong result (-got +want):\n%s", diff)
+	}
+
+	failing := func(query string, dst any, connectServerArgs ...any) error {
+		return fmt.Errorf("access denied")
+	}
+	if _, err := LogicalDiskTypes(failing, nil, nil, nil); err == nil {
+		t.Errorf("LogicalDiskTypes() with a failing WMI query returned no error")
+	}
+}
+
 func TestFriendlyNameToDiskType(t *testing.T) {
 	tests := []struct {
 		friendlyName string
@@ -241,6 +620,12 @@ func TestFriendlyNameToDiskType(t *testing.T) {
 			friendlyName: "Other friendly name",
 			size:         402653184000,
 			mediaType:    2,
+			want:         "Other friendly name",
+		},
+		{
+			friendlyName: " ",
+			size:         10,
+			mediaType:    2,
 			want:         "OTHER",
 		},
 		{
@@ -277,3 +662,27 @@ func TestCheckWindowsOsReturnedCount(t *testing.T) {
 		t.Errorf("guestCollectorWinCount = %d, want %d", guestCollectorWinCount, guestCollectorCount)
 	}
 }
+
+func TestLimitedQuery(t *testing.T) {
+	defer func(q func(string, any, ...any) error) { wmiQuery = q }(wmiQuery)
+	queries := 0
+	wmiQuery = func(query string, dst any, connectServerArgs ...any) error {
+		queries++
+		return nil
+	}
+	SetWMIQueryRate(1)
+	t.Cleanup(func() { SetWMIQueryRate(0) })
+	query := NewWindowsCollector("limitedquerytest", nil, nil, nil).limitedQuery(context.Background())
+	if err := query("SELECT name FROM win32_volume", nil); err != nil {
+		t.Fatalf("first query returned an unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	query = NewWindowsCollector("limitedquerytest", nil, nil, nil).limitedQuery(ctx)
+	if err := query("SELECT name FROM win32_volume", nil); err == nil {
+		t.Errorf("second query within the rate limit returned nil, want error")
+	}
+	if queries != 1 {
+		t.Errorf("limitedQuery() ran %d queries, want 1", queries)
+	}
+}
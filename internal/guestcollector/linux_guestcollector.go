@@ -20,19 +20,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/sapagent/shared/commandlineexecutor"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/instanceinfo"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/remote"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/tracing"
 )
 
 /*
@@ -49,6 +55,13 @@ const (
 	powerPlanCommand               = "sudo tuned-adm active"
 	dataDiskAllocationUnitsCommand = "sudo blockdev --getbsz /dev/"
 	gcbdrAgentRunningCommnad       = "sudo systemctl status udsagent | grep \"Active: \""
+	sqlServiceAccountCommand       = "sudo systemctl show mssql-server --property=LoadState,User"
+	swapCommand                    = "cat /proc/swaps && grep ^SwapTotal: /proc/meminfo"
+	mssqlConfCommand               = "test -d /var/opt/mssql && { if sudo cat /var/opt/mssql/mssql.conf 2>/dev/null; then echo; echo mssql.conf=read; elif sudo test -d /var/opt/mssql && ! sudo test -e /var/opt/mssql/mssql.conf; then echo mssql.conf=missing; else echo mssql.conf=unreadable; fi; }"
+	hostUtilizationCommand         = "head -n 1 /proc/stat && sleep 1 && head -n 1 /proc/stat && grep -e ^MemTotal: -e ^MemAvailable: /proc/meminfo"
+	containerCommand               = "pid=$(pgrep -o -x sqlservr) || exit 0; echo pid=$pid; sudo test -f /proc/$pid/root/.dockerenv && echo dockerenv=; sudo test -f /proc/$pid/root/run/.containerenv && echo containerenv=; cgroups=$(cat /proc/$pid/cgroup) || exit 0; echo cgroup=$cgroups; test -r /sys/fs/cgroup/cgroup.controllers && echo cgroup.controllers=; for l in $cgroups; do c=${l#*:}; p=${c#*:}; c=${c%%:*}; case ,$c, in ,,) d=/sys/fs/cgroup$p;; *,cpu,*) d=/sys/fs/cgroup/cpu$p;; *,memory,*) d=/sys/fs/cgroup/memory$p;; *) continue;; esac; for f in cpu.max memory.max cpu.cfs_quota_us cpu.cfs_period_us memory.limit_in_bytes; do test -r $d/$f && echo $f=$(cat $d/$f); done; done; true"
+	sqlAgentCommand                = "sudo systemctl show mssql-server --property=LoadState,ActiveState,UnitFileState; { rpm -q mssql-server-agent || dpkg -s mssql-server-agent; } >/dev/null 2>&1 && echo AgentPackage=installed; true"
+	sqlServiceName                 = "mssql-server"
 	persistentDisk                 = "PersistentDisk"
 	ephemeralDisk                  = "EphemeralDisk"
 )
@@ -77,6 +90,14 @@ type LinuxCollector struct {
 	usageMetricsLogger     agentstatus.AgentStatus
 }
 
+// linuxOSFields are the guest rules only collected on linux, after the rules of all OS
+// collections. They are collected if they are enabled.
+var linuxOSFields = []string{
+	internal.MSSQLConfRule,
+	internal.HostUtilizationRule,
+	internal.ContainerRule,
+}
+
 type commandExecutor struct {
 	command          string
 	isRule           bool
@@ -159,14 +180,7 @@ func NewLinuxCollector(disks []*instanceinfo.Disks, ipAddr, username, privateKey
 				return "", err
 			}
 
-			diskType := internal.Other.String()
-			if lshwFields.Product == persistentDisk {
-				diskType = internal.PersistentSSD.String()
-			} else if lshwFields.Product == ephemeralDisk && lshwFields.Size%402653184000 == 0 {
-				diskType = internal.LocalSSD.String()
-			}
-
-			c.physicalDriveToDiskMap[lshwFields.LogicalName] = diskType
+			c.physicalDriveToDiskMap[lshwFields.LogicalName] = classifyDisk(lshwFields.Product, int64(lshwFields.Size), 0)
 
 			res, errMar := json.Marshal(c.physicalDriveToDiskMap)
 			if errMar != nil {
@@ -285,6 +299,134 @@ func NewLinuxCollector(disks []*instanceinfo.Disks, ipAddr, username, privateKey
 			return c.gcbdrAgentRunning(res)
 		},
 	}
+	c.guestRuleCommandMap[internal.SQLServiceAccountRule] = commandExecutor{
+		command: sqlServiceAccountCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			res, err := internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", command), commandlineexecutor.ExecuteCommand)
+			if err != nil {
+				return "", err
+			}
+			return sqlServiceAccount(res)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			s, err := r.CreateSession("")
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+			res, err := r.Run(command, s)
+			if err != nil {
+				return "", err
+			}
+			return sqlServiceAccount(res)
+		},
+	}
+	c.guestRuleCommandMap[internal.SQLAgentServiceRule] = commandExecutor{
+		command: sqlAgentCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			res, err := internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", command), commandlineexecutor.ExecuteCommand)
+			if err != nil {
+				return "", err
+			}
+			return linuxSQLAgentService(res, func() (string, error) { return c.mssqlConf(ctx) })
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			s, err := r.CreateSession("")
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+			res, err := r.Run(command, s)
+			if err != nil {
+				return "", err
+			}
+			return linuxSQLAgentService(res, func() (string, error) { return c.mssqlConf(ctx) })
+		},
+	}
+	c.guestRuleCommandMap[internal.PageFileRule] = commandExecutor{
+		command: swapCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			res, err := internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", command), commandlineexecutor.ExecuteCommand)
+			if err != nil {
+				return "", err
+			}
+			return swapAreas(res)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			s, err := r.CreateSession("")
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+			res, err := r.Run(command, s)
+			if err != nil {
+				return "", err
+			}
+			return swapAreas(res)
+		},
+	}
+	c.guestRuleCommandMap[internal.MSSQLConfRule] = commandExecutor{
+		command: mssqlConfCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			conf, err := c.mssqlConf(ctx)
+			if err != nil {
+				return "", err
+			}
+			return mssqlConfSettings(conf)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			conf, err := c.mssqlConf(ctx)
+			if err != nil {
+				return "", err
+			}
+			return mssqlConfSettings(conf)
+		},
+	}
+	c.guestRuleCommandMap[internal.SQLNetworkProtocolsRule] = commandExecutor{
+		command: mssqlConfCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			conf, err := c.mssqlConf(ctx)
+			if err != nil {
+				return "", err
+			}
+			return linuxNetworkProtocols(conf)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			conf, err := c.mssqlConf(ctx)
+			if err != nil {
+				return "", err
+			}
+			return linuxNetworkProtocols(conf)
+		},
+	}
+	c.guestRuleCommandMap[internal.ContainerRule] = commandExecutor{
+		command: containerCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			res, err := internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", command), commandlineexecutor.ExecuteCommand)
+			if err != nil {
+				return "", err
+			}
+			return containerLimits(res)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			s, err := r.CreateSession("")
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+			res, err := r.Run(command, s)
+			if err != nil {
+				return "", err
+			}
+			return containerLimits(res)
+		},
+	}
 	return &c
 }
 
@@ -442,12 +584,23 @@ func findPowerProfile(powerProfileFull string) (string, error) {
 
 // CollectGuestRules collects os guest os rules
 func (c *LinuxCollector) CollectGuestRules(ctx context.Context, timeout time.Duration) internal.Details {
+	return c.collectGuestRules(ctx, timeout, nil)
+}
+
+// CollectGuestRule collects the guest rule with the given name and the rules it depends on.
+func (c *LinuxCollector) CollectGuestRule(ctx context.Context, rule string, timeout time.Duration) internal.Details {
+	return c.collectGuestRules(ctx, timeout, ruleWithDependencies(rule))
+}
+
+// collectGuestRules collects the selected guest rules, or all guest rules if selected is nil.
+func (c *LinuxCollector) collectGuestRules(ctx context.Context, timeout time.Duration, selected map[string]bool) internal.Details {
 	details := internal.Details{
 		Name: "OS",
 	}
 	fields := map[string]string{}
+	isSelected := func(rule string) bool { return selected == nil || selected[rule] }
 
-	if !c.remote {
+	if !c.remote && isSelected(internal.LocalSSDRule) {
 		ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		ch := make(chan bool, 1)
@@ -462,35 +615,67 @@ func (c *LinuxCollector) CollectGuestRules(ctx context.Context, timeout time.Dur
 		case <-ch:
 		}
 
-	} else {
+	} else if c.remote {
 		if c.remoteRunner == nil {
 			fields[internal.LocalSSDRule] = "unknown"
+			for _, rule := range CollectionOSFields() {
+				manifest.FromContext(ctx).Add(rule, manifest.Failed, manifest.FailedUnreachable)
+			}
 			details.Fields = append(details.Fields, fields)
 			log.Logger.Debugw("Remoterunner is nil. Remote collection attempted when ssh keys aren't set up correctly. Check customer support documentation.")
 			return details
 		}
 	}
 
+	var rules []string
 	for _, rule := range CollectionOSFields() {
+		if isSelected(rule) {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range linuxOSFields {
+		if !isSelected(rule) {
+			continue
+		}
+		if _, ok := c.guestRuleCommandMap[rule]; ok {
+			rules = append(rules, rule)
+			continue
+		}
+		manifest.FromContext(ctx).Add(rule, manifest.Skipped, manifest.SkippedDisabled)
+	}
+	// The rules reading mssql.conf share a single read of the file per collection.
+	ctx = context.WithValue(ctx, mssqlConfKey{}, &mssqlConfFile{read: c.readMSSQLConf})
+	for _, rule := range rules {
 		exe := c.guestRuleCommandMap[rule]
 		func() {
+			_, endSpan := tracing.StartRule(ctx, rule)
 			ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 			ch := make(chan bool, 1)
 			go func() {
+				release, err := internal.AcquireRule(ctxWithTimeout)
+				if err != nil {
+					if c.remote || exe.isRule {
+						fields[rule] = "unknown"
+					}
+					ch <- false
+					return
+				}
+				defer release()
 				if c.remote {
 					res, err := exe.runRemoteCommand(ctx, exe.command, c.remoteRunner)
 					if err != nil {
 						if strings.Contains(err.Error(), "Check help docs") {
 							log.Logger.Warnw("Failed to run remote command. Install command on linux vm to collect more data", "command", exe.command, "error", err)
 						} else {
-							log.Logger.Errorw("Failed to run remote command", "command", exe.command, "error", err)
+							errorlog.Default.Failed(errorlog.Key(c.target(), rule), err, "Failed to run remote command", "command", exe.command)
 							c.usageMetricsLogger.Error(agentstatus.RemoteCommandExecutionError)
 						}
 						fields[rule] = "unknown"
 						ch <- false
 						return
 					}
+					errorlog.Default.Succeeded(errorlog.Key(c.target(), rule))
 					fields[rule] = res
 				} else if exe.isRule { // local calls are only made if isrule is true
 					res, err := exe.runCommand(ctx, exe.command)
@@ -498,24 +683,32 @@ func (c *LinuxCollector) CollectGuestRules(ctx context.Context, timeout time.Dur
 						if strings.Contains(err.Error(), "Check help docs") {
 							log.Logger.Warnw("Failed to run remote command. Install command on linux vm to collect more data", "command", exe.command, "error", err)
 						} else {
-							log.Logger.Errorw("Failed to run command", "command", exe.command, "error", err)
+							errorlog.Default.Failed(errorlog.Key(c.target(), rule), err, "Failed to run command", "command", exe.command)
 							c.usageMetricsLogger.Error(agentstatus.CommandExecutionError)
 						}
 						fields[rule] = "unknown"
 						ch <- false
 						return
 					}
+					errorlog.Default.Succeeded(errorlog.Key(c.target(), rule))
 					fields[rule] = res
 				}
 				ch <- true
 			}()
 
+			var ruleErr error
 			select {
 			case <-ctxWithTimeout.Done():
 				log.Logger.Errorf("Running linux guest rule %s timeout", rule)
 				c.usageMetricsLogger.Error(agentstatus.LinuxGuestCollectionTimeout)
-			case <-ch:
+				ruleErr = ctxWithTimeout.Err()
+			case ok := <-ch:
+				if !ok {
+					ruleErr = errGuestRuleFailed
+				}
 			}
+			endSpan(ruleErr)
+			recordRule(ctx, rule, ruleErr)
 
 		}()
 
@@ -524,6 +717,14 @@ func (c *LinuxCollector) CollectGuestRules(ctx context.Context, timeout time.Dur
 	return details
 }
 
+// target identifies the machine in the logs of repeated rule errors.
+func (c *LinuxCollector) target() string {
+	if !c.remote {
+		return "localhost"
+	}
+	return c.ipaddr
+}
+
 func (c *LinuxCollector) gcbdrAgentRunning(cmdOutput string) (string, error) {
 	reg := regexp.MustCompile(`Active: (.*) since .*`)
 	match := reg.FindStringSubmatch(cmdOutput)
@@ -532,3 +733,527 @@ func (c *LinuxCollector) gcbdrAgentRunning(cmdOutput string) (string, error) {
 	}
 	return strconv.FormatBool(match[1] == "active (running)"), nil
 }
+
+// sqlServiceAccount takes the output of systemctl show for the mssql-server service and returns
+// the account it runs as. Services without a User property run as root.
+func sqlServiceAccount(cmdOutput string) (string, error) {
+	properties := map[string]string{}
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			properties[k] = v
+		}
+	}
+	if properties["LoadState"] != "loaded" {
+		return "", fmt.Errorf("%s service is not loaded: %q", sqlServiceName, cmdOutput)
+	}
+	user := properties["User"]
+	if user == "" {
+		user = "root"
+	}
+	res, err := json.Marshal([]serviceAccount{newServiceAccount(sqlServiceName, user)})
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// linuxSQLAgentService takes the properties of the mssql-server service and the marker of the
+// legacy mssql-server-agent package, and returns the SQL Server Agent service. The agent is
+// enabled by sqlagent.enabled in the content of mssql.conf returned by conf or, without the
+// setting, by the legacy package. It returns "unknown" if SQL Server is not installed, and an
+// error if mssql.conf cannot be read.
+func linuxSQLAgentService(cmdOutput string, conf func() (string, error)) (string, error) {
+	properties := map[string]string{}
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			properties[k] = v
+		}
+	}
+	if properties["LoadState"] != "loaded" {
+		return "unknown", nil
+	}
+	content, err := conf()
+	if err != nil {
+		return "", err
+	}
+	enabled := properties["AgentPackage"] == "installed"
+	if v, ok := parseMSSQLConf(content)["sqlagent.enabled"]; ok {
+		enabled = strings.EqualFold(v, "true") || v == "1"
+	}
+	sqlRunning := properties["ActiveState"] == "active"
+	agent := sqlAgentService{Name: "sqlagent", State: "Stopped", StartMode: "Disabled"}
+	if enabled {
+		agent.Running = sqlRunning
+		agent.AutoStart = properties["UnitFileState"] == "enabled"
+		agent.StartMode = "Manual"
+		if agent.AutoStart {
+			agent.StartMode = "Auto"
+		}
+		if agent.Running {
+			agent.State = "Running"
+		}
+	}
+	res, err := json.Marshal([]sqlAgentService{agent})
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// swapAreas takes the content of /proc/swaps followed by the SwapTotal line of /proc/meminfo and
+// returns the swap areas of the machine. An empty list is returned if no swap is configured.
+func swapAreas(cmdOutput string) (string, error) {
+	swaps := []pageFile{}
+	var totalKB int64 = -1
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 0 || f[0] == "Filename":
+			continue
+		case f[0] == "SwapTotal:" && len(f) >= 2:
+			v, err := strconv.ParseInt(f[1], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid SwapTotal in /proc/meminfo: %q", line)
+			}
+			totalKB = v
+		case len(f) >= 4:
+			size, err := strconv.ParseInt(f[2], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid size in /proc/swaps: %q", line)
+			}
+			used, err := strconv.ParseInt(f[3], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid used in /proc/swaps: %q", line)
+			}
+			swaps = append(swaps, pageFile{Name: f[0], Type: f[1], SizeMB: size / 1024, UsedMB: used / 1024})
+		}
+	}
+	if totalKB < 0 {
+		return "", fmt.Errorf("SwapTotal not found in /proc/meminfo")
+	}
+	if totalKB == 0 {
+		swaps = []pageFile{}
+	}
+	res, err := json.Marshal(swaps)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// mssqlConfKey is the context key of the mssqlConfFile shared by the rules of a collection.
+type mssqlConfKey struct{}
+
+// mssqlConfFile reads mssql.conf once for all the rules of a collection reading it.
+type mssqlConfFile struct {
+	once    sync.Once
+	read    func(context.Context) (string, error)
+	content string
+	err     error
+}
+
+// get returns the content of mssql.conf, reading it on the first call.
+func (f *mssqlConfFile) get(ctx context.Context) (string, error) {
+	f.once.Do(func() { f.content, f.err = f.read(ctx) })
+	return f.content, f.err
+}
+
+// mssqlConf returns the content of mssql.conf read once for the collection of ctx, or reads it if
+// ctx has none.
+func (c *LinuxCollector) mssqlConf(ctx context.Context) (string, error) {
+	if f, ok := ctx.Value(mssqlConfKey{}).(*mssqlConfFile); ok {
+		return f.get(ctx)
+	}
+	return c.readMSSQLConf(ctx)
+}
+
+// readMSSQLConf runs mssqlConfCommand on the machine and returns the content of mssql.conf.
+func (c *LinuxCollector) readMSSQLConf(ctx context.Context) (string, error) {
+	var res string
+	var err error
+	if c.remote {
+		var s remote.SSHSessionInterface
+		if s, err = c.remoteRunner.CreateSession(""); err != nil {
+			return "", err
+		}
+		defer s.Close()
+		res, err = c.remoteRunner.Run(mssqlConfCommand, s)
+	} else {
+		res, err = internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", mssqlConfCommand), commandlineexecutor.ExecuteCommand)
+	}
+	if err != nil {
+		return "", err
+	}
+	return mssqlConfContent(res)
+}
+
+// mssqlConfContent takes the output of mssqlConfCommand, the content of mssql.conf followed by
+// whether it was read, and returns the content. The content is empty if SQL Server is installed
+// without mssql.conf, in which case all the settings have their default value. It returns an
+// error if mssql.conf exists but cannot be read, e.g. without sudo, as its settings are unknown.
+func mssqlConfContent(cmdOutput string) (string, error) {
+	content, status := "", strings.TrimSpace(cmdOutput)
+	if i := strings.LastIndex(status, "\n"); i >= 0 {
+		content, status = status[:i], status[i+1:]
+	}
+	switch status {
+	case "mssql.conf=read":
+		return content, nil
+	case "mssql.conf=missing":
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed to read /var/opt/mssql/mssql.conf: %q", status)
+	}
+}
+
+// parseMSSQLConf takes the content of mssql.conf and returns its settings keyed by section and
+// name in lower case, e.g. "network.tcpport".
+func parseMSSQLConf(cmdOutput string) map[string]string {
+	settings := map[string]string{}
+	section := ""
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok && section != "" && !strings.HasPrefix(line, "#") {
+			settings[section+"."+strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return settings
+}
+
+// linuxNetworkProtocols takes the content of mssql.conf and returns the network protocols of
+// SQL Server on linux, which only accepts TCP connections. The TCP port defaults to 1433 when
+// network.tcpport is not set.
+func linuxNetworkProtocols(cmdOutput string) (string, error) {
+	port := "1433"
+	if p, ok := parseMSSQLConf(cmdOutput)["network.tcpport"]; ok {
+		port = p
+	}
+	res, err := json.Marshal([]networkProtocols{{Instance: sqlServiceName, TCPEnabled: true, TCPPort: port}})
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// mssqlConf are the settings of SQL Server on linux. Settings missing from mssql.conf have their
+// default value, except the memory limit which defaults to 80% of the physical memory and is
+// reported as unknown. TempDB holds the settings of the tempdb section keyed by name, and is
+// empty without the section.
+type mssqlConf struct {
+	MemoryLimitMB    string
+	DefaultDataDir   string
+	DefaultLogDir    string
+	DefaultBackupDir string
+	DefaultDumpDir   string
+	TraceFlags       []int
+	TempDB           map[string]string
+}
+
+// mssqlConfSettings takes the content of mssql.conf and returns the settings of SQL Server.
+// The content is empty if SQL Server is installed without mssql.conf, in which case all the
+// settings have their default value.
+func mssqlConfSettings(cmdOutput string) (string, error) {
+	settings := parseMSSQLConf(cmdOutput)
+	setting := func(name, defaultValue string) string {
+		if v, ok := settings[name]; ok && v != "" {
+			return v
+		}
+		return defaultValue
+	}
+	conf := mssqlConf{
+		MemoryLimitMB:    setting("memory.memorylimitmb", "unknown"),
+		DefaultDataDir:   setting("filelocation.defaultdatadir", "/var/opt/mssql/data"),
+		DefaultLogDir:    setting("filelocation.defaultlogdir", "/var/opt/mssql/data"),
+		DefaultBackupDir: setting("filelocation.defaultbackupdir", "/var/opt/mssql/data"),
+		DefaultDumpDir:   setting("filelocation.defaultdumpdir", "/var/opt/mssql/log"),
+		TraceFlags:       []int{},
+		TempDB:           map[string]string{},
+	}
+	var keys []string
+	for k, v := range settings {
+		if name, ok := strings.CutPrefix(k, "tempdb."); ok {
+			conf.TempDB[name] = v
+		}
+		if strings.HasPrefix(k, "traceflag.") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flag, err := strconv.Atoi(settings[k])
+		if err != nil {
+			log.Logger.Debugw("Invalid trace flag in mssql.conf", "setting", k, "value", settings[k])
+			continue
+		}
+		conf.TraceFlags = append(conf.TraceFlags, flag)
+	}
+	res, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// unlimitedCgroupMemory is the smallest memory limit of cgroup v1 treated as unlimited. Cgroups
+// without a memory limit report the largest page aligned int64.
+const unlimitedCgroupMemory = 1 << 62
+
+// containerRuntimes maps the markers found in the cgroups of sqlservr to the container runtime, in
+// the order they are checked.
+var containerRuntimes = []struct{ marker, runtime string }{
+	{"kubepods", "kubernetes"},
+	{"docker", "docker"},
+	{"libpod", "podman"},
+	{"containerd", "containerd"},
+	{"lxc", "lxc"},
+}
+
+// containerInfo is the container SQL Server runs in and the limits of its cgroup. The limits are
+// "unlimited" when the cgroup has none and "unknown" when the cgroups cannot be read. The cpu
+// limit is in cores.
+type containerInfo struct {
+	Containerized    bool
+	Runtime          string
+	CgroupVersion    string
+	CPULimitCores    string
+	MemoryLimitBytes string
+}
+
+// containerLimits takes the name=content lines of the container markers, cgroups and cgroup files
+// of sqlservr written by containerCommand and returns whether SQL Server runs in a container and
+// the limits of its cgroup. SQL Server runs in a container if /.dockerenv or /run/.containerenv
+// exists in its root, if its cgroups name a container runtime, or if its cgroup is the cgroup v2
+// root and has limits of its own, which only happens in a cgroup namespace; the runtime is
+// "unknown" in the latter case. It returns an error if sqlservr is not running.
+func containerLimits(cmdOutput string) (string, error) {
+	files := map[string]string{}
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			files[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	cgroup, ok := files["cgroup"]
+	if !ok {
+		return "", fmt.Errorf("no cgroups of sqlservr in %q", cmdOutput)
+	}
+	info := containerInfo{CgroupVersion: "unknown", CPULimitCores: "unknown", MemoryLimitBytes: "unknown"}
+	for _, r := range containerRuntimes {
+		if strings.Contains(cgroup, r.marker) {
+			info.Runtime = r.runtime
+			break
+		}
+	}
+	if info.Runtime == "" {
+		if _, ok := files["dockerenv"]; ok {
+			info.Runtime = "docker"
+		} else if _, ok := files["containerenv"]; ok {
+			info.Runtime = "podman"
+		}
+	}
+	cores := func(quota, period string) string {
+		q, qErr := strconv.ParseFloat(quota, 64)
+		p, pErr := strconv.ParseFloat(period, 64)
+		if qErr != nil || pErr != nil || p <= 0 {
+			return "unknown"
+		}
+		return strconv.FormatFloat(q/p, 'f', -1, 64)
+	}
+	if _, ok := files["cgroup.controllers"]; ok {
+		info.CgroupVersion = "2"
+		info.CPULimitCores, info.MemoryLimitBytes = "unlimited", "unlimited"
+		cpuMax, hasCPU := files["cpu.max"]
+		memoryMax, hasMemory := files["memory.max"]
+		if quota, period, ok := strings.Cut(cpuMax, " "); ok && quota != "max" {
+			info.CPULimitCores = cores(quota, period)
+		}
+		if hasMemory && memoryMax != "max" {
+			info.MemoryLimitBytes = memoryMax
+		}
+		if info.Runtime == "" && strings.Contains(" "+cgroup+" ", " 0::/ ") && (hasCPU || hasMemory) {
+			info.Runtime = "unknown"
+		}
+	} else if limit, ok := files["memory.limit_in_bytes"]; ok {
+		info.CgroupVersion = "1"
+		info.CPULimitCores = "unlimited"
+		if quota := files["cpu.cfs_quota_us"]; quota != "" && quota != "-1" {
+			info.CPULimitCores = cores(quota, files["cpu.cfs_period_us"])
+		}
+		info.MemoryLimitBytes = limit
+		if n, err := strconv.ParseInt(limit, 10, 64); err == nil && n >= unlimitedCgroupMemory {
+			info.MemoryLimitBytes = "unlimited"
+		}
+	}
+	info.Containerized = info.Runtime != ""
+	res, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// EnableHostUtilization adds a sample of the cpu and memory utilization of the machine to the
+// guest rules collected by CollectGuestRules, as one of the linuxOSFields. The cpu utilization is
+// sampled over one second when the rule runs.
+func (c *LinuxCollector) EnableHostUtilization() {
+	c.guestRuleCommandMap[internal.HostUtilizationRule] = commandExecutor{
+		command: hostUtilizationCommand,
+		isRule:  true,
+		runCommand: func(ctx context.Context, command string) (string, error) {
+			res, err := internal.CommandLineExecutorWrapper(ctx, "/bin/sh", fmt.Sprintf(" -c '%s'", command), commandlineexecutor.ExecuteCommand)
+			if err != nil {
+				return "", err
+			}
+			return hostUtilization(res)
+		},
+		runRemoteCommand: func(ctx context.Context, command string, r remote.Executor) (string, error) {
+			s, err := r.CreateSession("")
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+			res, err := r.Run(command, s)
+			if err != nil {
+				return "", err
+			}
+			return hostUtilization(res)
+		},
+	}
+}
+
+// hostUtilizationSample is the cpu and memory utilization of the machine. Percentages are rounded
+// to two decimals.
+type hostUtilizationSample struct {
+	CPUUtilizationPercent float64
+	MemoryTotalKB         int64
+	MemoryAvailableKB     int64
+	MemoryUsedPercent     float64
+}
+
+// hostUtilization takes two cpu lines of /proc/stat read one after the other followed by the
+// MemTotal and MemAvailable lines of /proc/meminfo and returns the utilization of the machine.
+// The cpu is idle in the idle and iowait times.
+func hostUtilization(cmdOutput string) (string, error) {
+	var cpuTimes [][]int64
+	var sample hostUtilizationSample
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "cpu":
+			if len(f) < 9 {
+				return "", fmt.Errorf("invalid cpu line in /proc/stat: %q", line)
+			}
+			times := make([]int64, 8)
+			for i := range times {
+				v, err := strconv.ParseInt(f[i+1], 10, 64)
+				if err != nil {
+					return "", fmt.Errorf("invalid cpu line in /proc/stat: %q", line)
+				}
+				times[i] = v
+			}
+			cpuTimes = append(cpuTimes, times)
+		case "MemTotal:", "MemAvailable:":
+			v, err := strconv.ParseInt(f[1], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid %s in /proc/meminfo: %q", f[0], line)
+			}
+			if f[0] == "MemTotal:" {
+				sample.MemoryTotalKB = v
+			} else {
+				sample.MemoryAvailableKB = v
+			}
+		}
+	}
+	if len(cpuTimes) != 2 {
+		return "", fmt.Errorf("expected 2 cpu lines in /proc/stat, got %d", len(cpuTimes))
+	}
+	if sample.MemoryTotalKB == 0 {
+		return "", fmt.Errorf("MemTotal not found in /proc/meminfo")
+	}
+	var total, idle int64
+	for i := range cpuTimes[0] {
+		d := cpuTimes[1][i] - cpuTimes[0][i]
+		total += d
+		// The fourth and fifth times are idle and iowait.
+		if i == 3 || i == 4 {
+			idle += d
+		}
+	}
+	if total > 0 {
+		sample.CPUUtilizationPercent = math.Round(10000*float64(total-idle)/float64(total)) / 100
+	}
+	sample.MemoryUsedPercent = math.Round(10000*float64(sample.MemoryTotalKB-sample.MemoryAvailableKB)/float64(sample.MemoryTotalKB)) / 100
+	res, err := json.Marshal(sample)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// LinuxVolumes returns the file systems containing the paths with their sizes and the space
+// available to SQL Server. df reads the file system statistics of the paths with statfs; paths on
+// the same file system are reported once.
+func LinuxVolumes(ctx context.Context, paths []string, exec commandlineexecutor.Execute) ([]internal.Volume, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	result := exec(ctx, commandlineexecutor.Params{
+		Executable: "df",
+		Args:       append([]string{"--block-size=1", "--output=size,avail,target"}, paths...),
+	})
+	// df fails if any path is missing but still reports the other paths.
+	if result.Error != nil && result.StdOut == "" {
+		return nil, fmt.Errorf("df failed: %v %s", result.Error, result.StdErr)
+	}
+	return dfVolumes(result.StdOut)
+}
+
+// dfVolumes parses the output of df with the size, avail and target columns.
+func dfVolumes(cmdOutput string) ([]internal.Volume, error) {
+	var volumes []internal.Volume
+	seen := map[string]bool{}
+	for i, line := range strings.Split(cmdOutput, "\n") {
+		f := strings.Fields(line)
+		if i == 0 || len(f) < 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(f[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in df output: %q", line)
+		}
+		avail, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid available space in df output: %q", line)
+		}
+		target := strings.Join(f[2:], " ")
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		volumes = append(volumes, internal.Volume{Name: target, SizeBytes: size, FreeBytes: avail})
+	}
+	return volumes, nil
+}
+
+// LinuxVCPUs returns the number of vCPUs of the machine, including the vCPUs the agent process
+// may not run on.
+func LinuxVCPUs(ctx context.Context, exec commandlineexecutor.Execute) (int64, error) {
+	result := exec(ctx, commandlineexecutor.Params{
+		Executable: "nproc",
+		Args:       []string{"--all"},
+	})
+	if result.Error != nil {
+		return 0, fmt.Errorf("nproc failed: %v %s", result.Error, result.StdErr)
+	}
+	vcpus, err := strconv.ParseInt(strings.TrimSpace(result.StdOut), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid nproc output: %q", result.StdOut)
+	}
+	return vcpus, nil
+}
@@ -26,8 +26,10 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/crypto/ssh"
+	"github.com/GoogleCloudPlatform/sapagent/shared/commandlineexecutor"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/instanceinfo"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/remote"
 )
 
@@ -73,6 +75,8 @@ type mockRemote struct {
 	createSessionErr bool
 	input            string
 	powerPlanInput   string
+	// runs counts the runs of each command.
+	runs map[string]int
 }
 
 func newMockRemote(runErr bool, createSessionErr bool, lshwErr bool, powerPlanInput string) *mockRemote {
@@ -82,10 +86,12 @@ func newMockRemote(runErr bool, createSessionErr bool, lshwErr bool, powerPlanIn
 		lshwErr:          lshwErr,
 		input:            "any input string",
 		powerPlanInput:   powerPlanInput,
+		runs:             map[string]int{},
 	}
 }
 
 func (m *mockRemote) Run(cmd string, session remote.SSHSessionInterface) (string, error) {
+	m.runs[cmd]++
 	if m.runErr {
 		return "", errors.New("run error")
 	}
@@ -112,6 +118,10 @@ func (m *mockRemote) Run(cmd string, session remote.SSHSessionInterface) (string
 		return m.powerPlanInput, nil
 	case dataDiskAllocationUnitsCommand:
 		return "", nil
+	case mssqlConfCommand:
+		return "[network]\ntcpport = 14330\n\nmssql.conf=read", nil
+	case containerCommand:
+		return "pid=4242\ndockerenv=\ncgroup=0::/system.slice/docker-0123abcd.scope\ncgroup.controllers=\ncpu.max=200000 100000\nmemory.max=4294967296\n", nil
 	default:
 		return "unknown", nil
 	}
@@ -216,10 +226,13 @@ func TestCollectLinuxGuestRules(t *testing.T) {
 		mockRuleMap            bool
 		mockWMIErr             bool
 		commandExecutorMapMock map[string]commandExecutor
-		want                   internal.Details
+		// ignoreFields are fields that depend on the host running the test.
+		ignoreFields []string
+		want         internal.Details
 	}{
 		{
-			name: "success",
+			name:         "success",
+			ignoreFields: []string{internal.PageFileRule, internal.ContainerRule},
 			want: internal.Details{
 				Name: "OS",
 				Fields: []map[string]string{
@@ -228,6 +241,12 @@ func TestCollectLinuxGuestRules(t *testing.T) {
 						"local_ssd":                  "unknown",
 						"power_profile_setting":      "unknown",
 						"gcbdr_agent_running":        "false",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"mssql_conf":                 "unknown",
+						"container":                  "unknown",
 					},
 				},
 			},
@@ -294,6 +313,12 @@ func TestCollectLinuxGuestRules(t *testing.T) {
 						"local_ssd":                  "unknown",
 						"power_profile_setting":      "unknown",
 						"gcbdr_agent_running":        "false",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"mssql_conf":                 "unknown",
+						"container":                  "unknown",
 					},
 				},
 			},
@@ -312,6 +337,11 @@ func TestCollectLinuxGuestRules(t *testing.T) {
 				}
 			}
 			got := collector.CollectGuestRules(context.Background(), time.Minute)
+			for _, f := range tc.ignoreFields {
+				for _, fields := range got.Fields {
+					fields[f] = tc.want.Fields[0][f]
+				}
+			}
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("CollectGuestRules() returned wrong result (-got +want):\n%s", diff)
 			}
@@ -319,6 +349,90 @@ func TestCollectLinuxGuestRules(t *testing.T) {
 	}
 }
 
+func TestCollectLinuxGuestRule(t *testing.T) {
+	collector := NewLinuxCollector(nil, "", "", "", false, 22, fakeUsageMetricsLogger)
+	var ran []string
+	executor := func(rule string) commandExecutor {
+		return commandExecutor{
+			isRule: true,
+			runCommand: func(ctx context.Context, command string) (string, error) {
+				ran = append(ran, rule)
+				return "testvalue", nil
+			},
+		}
+	}
+	collector.guestRuleCommandMap = map[string]commandExecutor{
+		internal.PowerProfileSettingRule: executor(internal.PowerProfileSettingRule),
+		internal.GCBDRAgentRunning:       executor(internal.GCBDRAgentRunning),
+	}
+	got := collector.CollectGuestRule(context.Background(), internal.PowerProfileSettingRule, time.Minute)
+	want := internal.Details{
+		Name:   "OS",
+		Fields: []map[string]string{{internal.PowerProfileSettingRule: "testvalue"}},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CollectGuestRule() returned wrong result (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(ran, []string{internal.PowerProfileSettingRule}); diff != "" {
+		t.Errorf("CollectGuestRule() ran wrong rules (-got +want):\n%s", diff)
+	}
+}
+
+func TestCollectLinuxGuestRulesHostUtilization(t *testing.T) {
+	sample := "cpu  1000 0 500 8000 500 0 0 0 0 0\n" +
+		"cpu  1301 0 600 8500 600 0 0 0 0 0\n" +
+		"MemTotal:       16000000 kB\n" +
+		"MemAvailable:    4000000 kB\n"
+	for _, enabled := range []bool{false, true} {
+		collector := NewLinuxCollector(nil, "", "", "", false, 22, fakeUsageMetricsLogger)
+		collector.guestRuleCommandMap = map[string]commandExecutor{}
+		if enabled {
+			collector.EnableHostUtilization()
+			exe := collector.guestRuleCommandMap[internal.HostUtilizationRule]
+			exe.runCommand = func(ctx context.Context, command string) (string, error) {
+				return hostUtilization(sample)
+			}
+			collector.guestRuleCommandMap[internal.HostUtilizationRule] = exe
+		}
+		got := collector.CollectGuestRules(context.Background(), time.Minute)
+		value, ok := got.Fields[0][internal.HostUtilizationRule]
+		if ok != enabled {
+			t.Fatalf("CollectGuestRules() with host utilization enabled: %v returned %s: %v, want %v", enabled, internal.HostUtilizationRule, ok, enabled)
+		}
+		if want := `{"CPUUtilizationPercent":40.06,"MemoryTotalKB":16000000,"MemoryAvailableKB":4000000,"MemoryUsedPercent":75}`; enabled && value != want {
+			t.Errorf("CollectGuestRules() returned %s = %s, want %s", internal.HostUtilizationRule, value, want)
+		}
+	}
+}
+
+func TestCollectLinuxGuestRulesManifest(t *testing.T) {
+	collector := NewLinuxCollector(nil, "", "", "", false, 22, fakeUsageMetricsLogger)
+	collector.guestRuleCommandMap = map[string]commandExecutor{
+		internal.PowerProfileSettingRule: commandExecutor{
+			isRule: true,
+			runCommand: func(ctx context.Context, command string) (string, error) {
+				return "", fmt.Errorf("error")
+			},
+		},
+	}
+	ctx, m := manifest.NewContext(context.Background())
+	collector.CollectGuestRules(ctx, time.Minute)
+
+	got := []map[string]string{}
+	for _, row := range m.Details().Fields {
+		if row["status"] != manifest.Ran {
+			got = append(got, row)
+		}
+	}
+	want := []map[string]string{
+		{"status": "skipped", "reason": "disabled", "count": "3", "rules": "container,host_utilization,mssql_conf"},
+		{"status": "failed", "reason": "command_error", "count": "1", "rules": "power_profile_setting"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CollectGuestRules() returned wrong manifest (-got +want):\n%s", diff)
+	}
+}
+
 func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 	testcases := []struct {
 		name              string
@@ -339,6 +453,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 					"local_ssd":                  `{"sda":"PERSISTENT-SSD"}`,
 					"power_profile_setting":      "High performance",
 					"gcbdr_agent_running":        "unknown",
+					"sql_service_account":        "unknown",
+					"page_file":                  "unknown",
+					"sql_network_protocols":      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"14330","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+					"sql_agent_service":          "unknown",
+					"mssql_conf":                 `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{}}`,
+					"container":                  `{"Containerized":true,"Runtime":"docker","CgroupVersion":"2","CPULimitCores":"2","MemoryLimitBytes":"4294967296"}`,
 				}},
 			},
 		},
@@ -353,6 +473,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 					"local_ssd":                  `{"sda":"PERSISTENT-SSD"}`,
 					"power_profile_setting":      "High performance",
 					"gcbdr_agent_running":        "unknown",
+					"sql_service_account":        "unknown",
+					"page_file":                  "unknown",
+					"sql_network_protocols":      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"14330","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+					"sql_agent_service":          "unknown",
+					"mssql_conf":                 `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{}}`,
+					"container":                  `{"Containerized":true,"Runtime":"docker","CgroupVersion":"2","CPULimitCores":"2","MemoryLimitBytes":"4294967296"}`,
 				}},
 			},
 		},
@@ -366,6 +492,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 					"local_ssd":                  `{"sda":"PERSISTENT-SSD"}`,
 					"power_profile_setting":      "balanced",
 					"gcbdr_agent_running":        "unknown",
+					"sql_service_account":        "unknown",
+					"page_file":                  "unknown",
+					"sql_network_protocols":      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"14330","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+					"sql_agent_service":          "unknown",
+					"mssql_conf":                 `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{}}`,
+					"container":                  `{"Containerized":true,"Runtime":"docker","CgroupVersion":"2","CPULimitCores":"2","MemoryLimitBytes":"4294967296"}`,
 				}},
 			},
 		},
@@ -379,6 +511,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 					"local_ssd":                  `{"sda":"PERSISTENT-SSD"}`,
 					"power_profile_setting":      "unknown",
 					"gcbdr_agent_running":        "unknown",
+					"sql_service_account":        "unknown",
+					"page_file":                  "unknown",
+					"sql_network_protocols":      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"14330","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+					"sql_agent_service":          "unknown",
+					"mssql_conf":                 `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{}}`,
+					"container":                  `{"Containerized":true,"Runtime":"docker","CgroupVersion":"2","CPULimitCores":"2","MemoryLimitBytes":"4294967296"}`,
 				}},
 			},
 		},
@@ -393,6 +531,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 						"local_ssd":                  "unknown",
 						"power_profile_setting":      "unknown",
 						"gcbdr_agent_running":        "false",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"mssql_conf":                 "unknown",
+						"container":                  "unknown",
 					},
 				},
 			},
@@ -408,6 +552,12 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 						"local_ssd":                  "unknown",
 						"power_profile_setting":      "unknown",
 						"gcbdr_agent_running":        "unknown",
+						"sql_service_account":        "unknown",
+						"page_file":                  "unknown",
+						"sql_network_protocols":      "unknown",
+						"sql_agent_service":          "unknown",
+						"mssql_conf":                 "unknown",
+						"container":                  "unknown",
 					},
 				},
 			},
@@ -438,6 +588,59 @@ func TestCollectLinuxGuestRulesRemote(t *testing.T) {
 	}
 }
 
+func TestCollectLinuxGuestRulesReadsMSSQLConfOnce(t *testing.T) {
+	collector := NewLinuxCollector(nil, "", "", "", true, 22, fakeUsageMetricsLogger)
+	r := newMockRemote(false, false, false, "")
+	collector.remoteRunner = r
+	collector.CollectGuestRules(context.Background(), time.Minute)
+	if got := r.runs[mssqlConfCommand]; got != 1 {
+		t.Errorf("CollectGuestRules() read mssql.conf %d times, want 1", got)
+	}
+}
+
+func TestMSSQLConfContent(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cmdOutput string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "read",
+			cmdOutput: "[network]\ntcpport = 14330\n\nmssql.conf=read",
+			want:      "[network]\ntcpport = 14330\n",
+		},
+		{
+			name:      "empty file",
+			cmdOutput: "\nmssql.conf=read",
+		},
+		{
+			name:      "missing",
+			cmdOutput: "mssql.conf=missing",
+		},
+		{
+			name:      "unreadable",
+			cmdOutput: "mssql.conf=unreadable",
+			wantErr:   true,
+		},
+		{
+			name:    "no output",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mssqlConfContent(tc.cmdOutput)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("mssqlConfContent(%q) returned error: %v, want error: %v", tc.cmdOutput, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("mssqlConfContent(%q) = %q, want %q", tc.cmdOutput, got, tc.want)
+			}
+		})
+	}
+}
+
 // TestCheckLinusOsReturnedCount compares the os returned fields for linux_guestcollector with the returned fields for OSCollectorResultFields
 func TestCheckLinusOsReturnedCount(t *testing.T) {
 	guestCollectorCount := len(allOSFields)
@@ -931,3 +1134,465 @@ func TestGcbdrAgentRunning(t *testing.T) {
 		}
 	}
 }
+
+func TestSQLServiceAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "success with user",
+			cmdOutput: "LoadState=loaded\nUser=mssql\n",
+			want:      `[{"Name":"mssql-server","StartName":"mssql","AccountType":"USER","IsBuiltin":false}]`,
+		},
+		{
+			name:      "success with default root user",
+			cmdOutput: "LoadState=loaded\nUser=\n",
+			want:      `[{"Name":"mssql-server","StartName":"root","AccountType":"BUILTIN","IsBuiltin":true}]`,
+		},
+		{
+			name:      "failure - service not found",
+			cmdOutput: "LoadState=not-found\nUser=\n",
+			wantErr:   true,
+		},
+		{
+			name:      "failure - invalid cmdOutput",
+			cmdOutput: "any input without correct format",
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		got, err := sqlServiceAccount(tc.cmdOutput)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("sqlServiceAccount(%q) returned an unexpected error: %v, wantErr: %v", tc.cmdOutput, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("sqlServiceAccount(%q) = %q, want: %q", tc.cmdOutput, got, tc.want)
+		}
+	}
+}
+
+func TestLinuxSQLAgentService(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		conf      string
+		confErr   bool
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "enabled in mssql.conf",
+			cmdOutput: "LoadState=loaded\nActiveState=active\nUnitFileState=enabled\n",
+			conf:      "[sqlagent]\nenabled = true\n",
+			want:      `[{"Name":"sqlagent","State":"Running","StartMode":"Auto","Running":true,"AutoStart":true}]`,
+		},
+		{
+			name:      "enabled but sql server stopped",
+			cmdOutput: "LoadState=loaded\nActiveState=inactive\nUnitFileState=disabled\n",
+			conf:      "[sqlagent]\nenabled = true\n",
+			want:      `[{"Name":"sqlagent","State":"Stopped","StartMode":"Manual","Running":false,"AutoStart":false}]`,
+		},
+		{
+			name:      "disabled by default",
+			cmdOutput: "LoadState=loaded\nActiveState=active\nUnitFileState=enabled\n",
+			conf:      "[network]\ntcpport = 1433\n",
+			want:      `[{"Name":"sqlagent","State":"Stopped","StartMode":"Disabled","Running":false,"AutoStart":false}]`,
+		},
+		{
+			name:      "mssql.conf unreadable",
+			cmdOutput: "LoadState=loaded\nActiveState=active\nUnitFileState=enabled\n",
+			confErr:   true,
+			wantErr:   true,
+		},
+		{
+			name:      "legacy package",
+			cmdOutput: "LoadState=loaded\nActiveState=active\nUnitFileState=enabled\nAgentPackage=installed\n",
+			want:      `[{"Name":"sqlagent","State":"Running","StartMode":"Auto","Running":true,"AutoStart":true}]`,
+		},
+		{
+			name:      "disabled in mssql.conf with legacy package",
+			cmdOutput: "LoadState=loaded\nActiveState=active\nUnitFileState=enabled\nAgentPackage=installed\n",
+			conf:      "[sqlagent]\nenabled = false\n",
+			want:      `[{"Name":"sqlagent","State":"Stopped","StartMode":"Disabled","Running":false,"AutoStart":false}]`,
+		},
+		{
+			name:      "sql server not installed",
+			cmdOutput: "LoadState=not-found\nActiveState=inactive\nUnitFileState=\n",
+			confErr:   true,
+			want:      "unknown",
+		},
+	}
+	for _, tc := range tests {
+		conf := func() (string, error) {
+			if tc.confErr {
+				return "", errors.New("unreadable")
+			}
+			return tc.conf, nil
+		}
+		got, err := linuxSQLAgentService(tc.cmdOutput, conf)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("%s: linuxSQLAgentService(%q) returned error: %v, want error: %v", tc.name, tc.cmdOutput, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: linuxSQLAgentService(%q) = %q, want: %q", tc.name, tc.cmdOutput, got, tc.want)
+		}
+	}
+}
+
+func TestSwapAreas(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "success with swap file and partition",
+			cmdOutput: "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n" +
+				"/swapfile                               file\t\t2097148\t\t10240\t\t-2\n" +
+				"/dev/sdb2                               partition\t1048572\t\t0\t\t-3\n" +
+				"SwapTotal:       3145720 kB\n",
+			want: `[{"Name":"/swapfile","Type":"file","SizeMB":2047,"UsedMB":10},{"Name":"/dev/sdb2","Type":"partition","SizeMB":1023,"UsedMB":0}]`,
+		},
+		{
+			name:      "success without swap",
+			cmdOutput: "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\nSwapTotal:             0 kB\n",
+			want:      `[]`,
+		},
+		{
+			name:      "failure - missing meminfo",
+			cmdOutput: "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n",
+			wantErr:   true,
+		},
+		{
+			name:      "failure - invalid size",
+			cmdOutput: "/swapfile file abc 0 -2\nSwapTotal: 1024 kB\n",
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		got, err := swapAreas(tc.cmdOutput)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("swapAreas(%q) returned an unexpected error: %v, wantErr: %v", tc.cmdOutput, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("swapAreas(%q) = %q, want: %q", tc.cmdOutput, got, tc.want)
+		}
+	}
+}
+
+func TestLinuxNetworkProtocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		want      string
+	}{
+		{
+			name:      "custom tcp port",
+			cmdOutput: "[sqlagent]\nenabled = false\n\n[network]\ntcpport = 14330\n",
+			want:      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"14330","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+		},
+		{
+			name:      "tcp port of another section",
+			cmdOutput: "[hadr]\ntcpport = 5022\n",
+			want:      `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"1433","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+		},
+		{
+			name: "no mssql.conf",
+			want: `[{"Instance":"mssql-server","TCPEnabled":true,"TCPPort":"1433","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":false,"LegacyProtocolsEnabled":false}]`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := linuxNetworkProtocols(tc.cmdOutput)
+		if err != nil {
+			t.Errorf("linuxNetworkProtocols(%q) returned an unexpected error: %v", tc.cmdOutput, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("linuxNetworkProtocols(%q) = %q, want: %q", tc.cmdOutput, got, tc.want)
+		}
+	}
+}
+
+func TestMSSQLConfSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		want      string
+	}{
+		{
+			name:      "custom settings",
+			cmdOutput: "[memory]\nmemorylimitmb = 4096\n\n[filelocation]\ndefaultdatadir = /data\ndefaultlogdir = /log\n\n# trace flags\n[traceflag]\ntraceflag0 = 1222\ntraceflag1 = 3226\n",
+			want:      `{"MemoryLimitMB":"4096","DefaultDataDir":"/data","DefaultLogDir":"/log","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[1222,3226],"TempDB":{}}`,
+		},
+		{
+			name:      "tempdb settings",
+			cmdOutput: "[tempdb]\nnumfiles = 8\n",
+			want:      `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{"numfiles":"8"}}`,
+		},
+		{
+			name:      "invalid trace flag",
+			cmdOutput: "[traceflag]\ntraceflag0 = abc\ntraceflag1 = 1117\n",
+			want:      `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[1117],"TempDB":{}}`,
+		},
+		{
+			name: "no mssql.conf",
+			want: `{"MemoryLimitMB":"unknown","DefaultDataDir":"/var/opt/mssql/data","DefaultLogDir":"/var/opt/mssql/data","DefaultBackupDir":"/var/opt/mssql/data","DefaultDumpDir":"/var/opt/mssql/log","TraceFlags":[],"TempDB":{}}`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := mssqlConfSettings(tc.cmdOutput)
+		if err != nil {
+			t.Errorf("mssqlConfSettings(%q) returned an unexpected error: %v", tc.cmdOutput, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("mssqlConfSettings(%q) = %q, want: %q", tc.cmdOutput, got, tc.want)
+		}
+	}
+}
+
+func TestContainerLimits(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cmdOutput string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "systemd service on a cgroup v2 host",
+			cmdOutput: "pid=812\ncgroup=0::/system.slice/mssql-server.service\ncgroup.controllers=\ncpu.max=max 100000\nmemory.max=max\n",
+			want:      `{"Containerized":false,"Runtime":"","CgroupVersion":"2","CPULimitCores":"unlimited","MemoryLimitBytes":"unlimited"}`,
+		},
+		{
+			name:      "systemd service with a memory limit",
+			cmdOutput: "pid=812\ncgroup=0::/system.slice/mssql-server.service\ncgroup.controllers=\ncpu.max=max 100000\nmemory.max=8589934592\n",
+			want:      `{"Containerized":false,"Runtime":"","CgroupVersion":"2","CPULimitCores":"unlimited","MemoryLimitBytes":"8589934592"}`,
+		},
+		{
+			name:      "docker container on a cgroup v2 host",
+			cmdOutput: "pid=4242\ndockerenv=\ncgroup=0::/system.slice/docker-0123abcd.scope\ncgroup.controllers=\ncpu.max=200000 100000\nmemory.max=4294967296\n",
+			want:      `{"Containerized":true,"Runtime":"docker","CgroupVersion":"2","CPULimitCores":"2","MemoryLimitBytes":"4294967296"}`,
+		},
+		{
+			name:      "cgroup v2 namespace without limits",
+			cmdOutput: "pid=7\ncgroup=0::/\ncgroup.controllers=\ncpu.max=max 100000\nmemory.max=max\n",
+			want:      `{"Containerized":true,"Runtime":"unknown","CgroupVersion":"2","CPULimitCores":"unlimited","MemoryLimitBytes":"unlimited"}`,
+		},
+		{
+			name:      "podman with cgroup v2 limits",
+			cmdOutput: "pid=7\ncontainerenv=\ncgroup=0::/\ncgroup.controllers=\ncpu.max=150000 100000\nmemory.max=8589934592\n",
+			want:      `{"Containerized":true,"Runtime":"podman","CgroupVersion":"2","CPULimitCores":"1.5","MemoryLimitBytes":"8589934592"}`,
+		},
+		{
+			name:      "kubernetes with cgroup v1 limits",
+			cmdOutput: "pid=4242\ncgroup=12:memory:/kubepods/burstable/pod1/abc 4:cpu,cpuacct:/kubepods/burstable/pod1/abc\ncpu.cfs_quota_us=400000\ncpu.cfs_period_us=100000\nmemory.limit_in_bytes=17179869184\n",
+			want:      `{"Containerized":true,"Runtime":"kubernetes","CgroupVersion":"1","CPULimitCores":"4","MemoryLimitBytes":"17179869184"}`,
+		},
+		{
+			name:      "cgroup v1 host",
+			cmdOutput: "pid=812\ncgroup=12:memory:/system.slice/mssql-server.service 4:cpu,cpuacct:/system.slice/mssql-server.service\ncpu.cfs_quota_us=-1\ncpu.cfs_period_us=100000\nmemory.limit_in_bytes=9223372036854771712\n",
+			want:      `{"Containerized":false,"Runtime":"","CgroupVersion":"1","CPULimitCores":"unlimited","MemoryLimitBytes":"unlimited"}`,
+		},
+		{
+			name:      "cgroups cannot be read",
+			cmdOutput: "pid=4242\ncgroup=12:memory:/docker/abc\n",
+			want:      `{"Containerized":true,"Runtime":"docker","CgroupVersion":"unknown","CPULimitCores":"unknown","MemoryLimitBytes":"unknown"}`,
+		},
+		{
+			name:      "sqlservr not running",
+			cmdOutput: "",
+			wantErr:   true,
+		},
+		{
+			name:      "cgroups of sqlservr unreadable",
+			cmdOutput: "pid=4242\n",
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := containerLimits(tc.cmdOutput)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("containerLimits() returned error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("containerLimits() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinuxVolumes(t *testing.T) {
+	testcases := []struct {
+		name     string
+		paths    []string
+		result   commandlineexecutor.Result
+		wantArgs []string
+		want     []internal.Volume
+		wantErr  bool
+	}{
+		{
+			name:  "success",
+			paths: []string{"/var/opt/mssql/data/master.mdf", "/var/opt/mssql/log/mastlog.ldf", "/mnt/sql data/db.mdf"},
+			result: commandlineexecutor.Result{StdOut: `    1B-blocks        Avail Mounted on
+  10000000000   1000000000 /var/opt/mssql
+  10000000000   1000000000 /var/opt/mssql
+ 500000000000 250000000000 /mnt/sql data
+`},
+			wantArgs: []string{"--block-size=1", "--output=size,avail,target", "/var/opt/mssql/data/master.mdf", "/var/opt/mssql/log/mastlog.ldf", "/mnt/sql data/db.mdf"},
+			want: []internal.Volume{
+				{Name: "/var/opt/mssql", SizeBytes: 10000000000, FreeBytes: 1000000000},
+				{Name: "/mnt/sql data", SizeBytes: 500000000000, FreeBytes: 250000000000},
+			},
+		},
+		{
+			name:  "missing path is skipped",
+			paths: []string{"/missing/db.mdf", "/data/db.mdf"},
+			result: commandlineexecutor.Result{
+				StdOut: "1B-blocks Avail Mounted on\n1000 10 /\n",
+				StdErr: "df: /missing/db.mdf: No such file or directory",
+				Error:  errors.New("exit status 1"),
+			},
+			wantArgs: []string{"--block-size=1", "--output=size,avail,target", "/missing/db.mdf", "/data/db.mdf"},
+			want:     []internal.Volume{{Name: "/", SizeBytes: 1000, FreeBytes: 10}},
+		},
+		{
+			name:     "df failure",
+			paths:    []string{"/data/db.mdf"},
+			result:   commandlineexecutor.Result{StdErr: "df: not found", Error: errors.New("exit status 127")},
+			wantArgs: []string{"--block-size=1", "--output=size,avail,target", "/data/db.mdf"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid output",
+			paths:    []string{"/data/db.mdf"},
+			result:   commandlineexecutor.Result{StdOut: "1B-blocks Avail Mounted on\nsize 10 /\n"},
+			wantArgs: []string{"--block-size=1", "--output=size,avail,target", "/data/db.mdf"},
+			wantErr:  true,
+		},
+		{
+			name: "no paths",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotArgs []string
+			exec := func(_ context.Context, p commandlineexecutor.Params) commandlineexecutor.Result {
+				gotArgs = p.Args
+				return tc.result
+			}
+			got, err := LinuxVolumes(context.Background(), tc.paths, exec)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("LinuxVolumes() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(gotArgs, tc.wantArgs); diff != "" {
+				t.Errorf("LinuxVolumes() ran df with wrong arguments (-got +want):\n%s", diff)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("LinuxVolumes() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLinuxVCPUs(t *testing.T) {
+	testcases := []struct {
+		name    string
+		result  commandlineexecutor.Result
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			result: commandlineexecutor.Result{StdOut: "16\n"},
+			want:   16,
+		},
+		{
+			name:    "nproc failure",
+			result:  commandlineexecutor.Result{StdErr: "nproc: not found", Error: errors.New("exit status 127")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid output",
+			result:  commandlineexecutor.Result{StdOut: "sixteen\n"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotArgs []string
+			exec := func(_ context.Context, p commandlineexecutor.Params) commandlineexecutor.Result {
+				gotArgs = append([]string{p.Executable}, p.Args...)
+				return tc.result
+			}
+			got, err := LinuxVCPUs(context.Background(), exec)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("LinuxVCPUs() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(gotArgs, []string{"nproc", "--all"}); diff != "" {
+				t.Errorf("LinuxVCPUs() ran wrong command (-got +want):\n%s", diff)
+			}
+			if got != tc.want {
+				t.Errorf("LinuxVCPUs() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHostUtilization(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdOutput string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			cmdOutput: "cpu  1000 0 500 8000 500 0 0 0 0 0\n" +
+				"cpu  1301 0 600 8500 600 0 0 0 0 0\n" +
+				"MemTotal:       16000000 kB\n" +
+				"MemAvailable:    4000000 kB\n",
+			want: `{"CPUUtilizationPercent":40.06,"MemoryTotalKB":16000000,"MemoryAvailableKB":4000000,"MemoryUsedPercent":75}`,
+		},
+		{
+			name: "success without cpu time elapsed",
+			cmdOutput: "cpu  1000 0 500 8000 500 0 0 0 0 0\n" +
+				"cpu  1000 0 500 8000 500 0 0 0 0 0\n" +
+				"MemTotal:       16000000 kB\n" +
+				"MemAvailable:   16000000 kB\n",
+			want: `{"CPUUtilizationPercent":0,"MemoryTotalKB":16000000,"MemoryAvailableKB":16000000,"MemoryUsedPercent":0}`,
+		},
+		{
+			name:      "failure - single cpu sample",
+			cmdOutput: "cpu  1000 0 500 8000 500 0 0 0 0 0\nMemTotal: 16000000 kB\nMemAvailable: 4000000 kB\n",
+			wantErr:   true,
+		},
+		{
+			name:      "failure - missing meminfo",
+			cmdOutput: "cpu  1000 0 500 8000 500 0 0 0 0 0\ncpu  1300 0 600 8500 600 0 0 0 0 0\n",
+			wantErr:   true,
+		},
+		{
+			name:      "failure - invalid cpu time",
+			cmdOutput: "cpu  abc 0 500 8000 500 0 0 0 0 0\ncpu  1300 0 600 8500 600 0 0 0 0 0\nMemTotal: 16000000 kB\n",
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		got, err := hostUtilization(tc.cmdOutput)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("hostUtilization(%q) returned an unexpected error: %v, wantErr: %v", tc.cmdOutput, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("hostUtilization(%q) = %q, want: %q", tc.cmdOutput, got, tc.want)
+		}
+	}
+}
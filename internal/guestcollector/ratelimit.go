@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package guestcollector
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// wmiLimiters holds the rate limiter of each target the WMI queries are sent to.
+var wmiLimiters = struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	targets map[string]*rate.Limiter
+}{limit: rate.Inf, targets: map[string]*rate.Limiter{}}
+
+// queryLimit returns the rate limit of qps queries per second. A qps that is not positive is
+// unlimited.
+func queryLimit(qps int32) rate.Limit {
+	if qps <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(qps)
+}
+
+// SetWMIQueryRate limits the WMI queries sent to each target to qps queries per second.
+// A qps of 0 removes the limit. The limiters of the known targets keep their tokens and
+// take the new rate.
+func SetWMIQueryRate(qps int32) {
+	wmiLimiters.mu.Lock()
+	defer wmiLimiters.mu.Unlock()
+	limit := queryLimit(qps)
+	if limit == wmiLimiters.limit {
+		return
+	}
+	wmiLimiters.limit = limit
+	for _, l := range wmiLimiters.targets {
+		l.SetLimit(limit)
+	}
+}
+
+// wmiLimiter returns the rate limiter of the WMI queries sent to target.
+func wmiLimiter(target string) *rate.Limiter {
+	wmiLimiters.mu.Lock()
+	defer wmiLimiters.mu.Unlock()
+	l, ok := wmiLimiters.targets[target]
+	if !ok {
+		l = rate.NewLimiter(wmiLimiters.limit, 1)
+		wmiLimiters.targets[target] = l
+	}
+	return l
+}
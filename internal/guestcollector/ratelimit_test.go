@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package guestcollector
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestQueryLimit(t *testing.T) {
+	tests := []struct {
+		qps  int32
+		want rate.Limit
+	}{
+		{qps: 0, want: rate.Inf},
+		{qps: -1, want: rate.Inf},
+		{qps: 5, want: 5},
+	}
+	for _, tc := range tests {
+		if got := queryLimit(tc.qps); got != tc.want {
+			t.Errorf("queryLimit(%d) = %v, want %v", tc.qps, got, tc.want)
+		}
+	}
+}
+
+func TestWMILimiter(t *testing.T) {
+	t.Cleanup(func() { SetWMIQueryRate(0) })
+	l1 := wmiLimiter("host1")
+	if l1.Limit() != rate.Inf {
+		t.Errorf("wmiLimiter() without a rate has limit %v, want unlimited", l1.Limit())
+	}
+	for i := 0; i < 3; i++ {
+		if err := l1.Wait(context.Background()); err != nil {
+			t.Errorf("Wait() without a rate returned error: %v", err)
+		}
+	}
+	SetWMIQueryRate(5)
+	l2 := wmiLimiter("host2")
+	if l1 == l2 {
+		t.Fatalf("wmiLimiter() = %p, %p, want a distinct limiter per target", l1, l2)
+	}
+	if l := wmiLimiter("host1"); l != l1 {
+		t.Errorf("wmiLimiter() returned a new limiter for the same target")
+	}
+	if l1.Limit() != 5 || l2.Limit() != 5 {
+		t.Errorf("wmiLimiter() limits = %v, %v, want 5", l1.Limit(), l2.Limit())
+	}
+	if !l2.Allow() {
+		t.Errorf("Allow() of a new limiter = false, want true")
+	}
+	if l2.Allow() {
+		t.Errorf("Allow() right after the first query = true, want false")
+	}
+	SetWMIQueryRate(0)
+	if l1.Limit() != rate.Inf {
+		t.Errorf("wmiLimiter() after removing the rate has limit %v, want unlimited", l1.Limit())
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package history records collected data in a local SQLite database, so that the history of the
+// collections can be queried with SQL on the machine running the agent.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	// Registers the "sqlite" driver.
+	_ "modernc.org/sqlite"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// schema creates the table of the collected fields. Each field of a row of a rule is stored with
+// the index of its row, so that the rows of rules reporting several rows can be told apart.
+// Timestamps are RFC 3339 UTC times, which sort in time order.
+const schema = `CREATE TABLE IF NOT EXISTS details (
+	instance TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	row INTEGER NOT NULL,
+	field TEXT NOT NULL,
+	value TEXT NOT NULL,
+	timestamp TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS details_instance_rule_timestamp ON details (instance, rule, timestamp);
+CREATE INDEX IF NOT EXISTS details_timestamp ON details (timestamp);`
+
+// busyTimeout is how long a write waits for the database to be unlocked by another process, e.g.
+// an operator querying the history, before failing.
+const busyTimeout = 10 * time.Second
+
+// mu serializes the writes of the os and sql collections, which run concurrently and record in
+// the same database.
+var mu sync.Mutex
+
+// Write records the details collected from the instance at now in the SQLite database at path,
+// creating the database if it does not exist. The fields recorded more than maxAge before now are
+// deleted; a zero maxAge keeps all fields. The details are recorded in a single transaction.
+func Write(ctx context.Context, path, instance string, details []internal.Details, now time.Time, maxAge time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(%d)", path, busyTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create the schema: %v", err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO details (instance, rule, row, field, value, timestamp) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	timestamp := now.UTC().Format(time.RFC3339)
+	for _, d := range details {
+		for i, row := range d.Fields {
+			for field, value := range row {
+				if _, err := stmt.ExecContext(ctx, instance, d.Name, i, field, value, timestamp); err != nil {
+					return fmt.Errorf("failed to record field %s of %s: %v", field, d.Name, err)
+				}
+			}
+		}
+	}
+	if maxAge > 0 {
+		cutoff := now.Add(-maxAge).UTC().Format(time.RFC3339)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM details WHERE timestamp < ?`, cutoff); err != nil {
+			return fmt.Errorf("failed to delete the expired fields: %v", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// record is a field recorded in the database.
+type record struct {
+	Instance, Rule string
+	Row            int
+	Field, Value   string
+	Timestamp      string
+}
+
+// records returns the fields recorded in the database at path in order.
+func records(t *testing.T, path string) []record {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open(%q) = %v", path, err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT instance, rule, row, field, value, timestamp FROM details ORDER BY timestamp, instance, rule, row, field`)
+	if err != nil {
+		t.Fatalf("Query() = %v", err)
+	}
+	defer rows.Close()
+	var res []record
+	for rows.Next() {
+		var r record
+		if err := rows.Scan(&r.Instance, &r.Rule, &r.Row, &r.Field, &r.Value, &r.Timestamp); err != nil {
+			t.Fatalf("Scan() = %v", err)
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	ctx := context.Background()
+	first := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	details := []internal.Details{
+		{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDegreeOfParallelism": "0"}}},
+		{Name: "DB_PAGE_VERIFY", Fields: []map[string]string{
+			{"db_name": "sales", "page_verify_option": "CHECKSUM"},
+			{"db_name": "hr", "page_verify_option": "NONE"},
+		}},
+	}
+	if err := Write(ctx, path, "sql-vm", details, first, 0); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	second := first.Add(24 * time.Hour)
+	if err := Write(ctx, path, "sql-vm", details[:1], second, 0); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	want := []record{
+		{"sql-vm", "DB_MAX_PARALLELISM", 0, "maxDegreeOfParallelism", "0", "2024-03-01T10:00:00Z"},
+		{"sql-vm", "DB_PAGE_VERIFY", 0, "db_name", "sales", "2024-03-01T10:00:00Z"},
+		{"sql-vm", "DB_PAGE_VERIFY", 0, "page_verify_option", "CHECKSUM", "2024-03-01T10:00:00Z"},
+		{"sql-vm", "DB_PAGE_VERIFY", 1, "db_name", "hr", "2024-03-01T10:00:00Z"},
+		{"sql-vm", "DB_PAGE_VERIFY", 1, "page_verify_option", "NONE", "2024-03-01T10:00:00Z"},
+		{"sql-vm", "DB_MAX_PARALLELISM", 0, "maxDegreeOfParallelism", "0", "2024-03-02T10:00:00Z"},
+	}
+	if diff := cmp.Diff(want, records(t, path)); diff != "" {
+		t.Errorf("Write() recorded unexpected fields (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	ctx := context.Background()
+	details := []internal.Details{{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDegreeOfParallelism": "0"}}}}
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-12 * time.Hour), now} {
+		if err := Write(ctx, path, "sql-vm", details, at, 24*time.Hour); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+	want := []record{
+		{"sql-vm", "DB_MAX_PARALLELISM", 0, "maxDegreeOfParallelism", "0", "2024-02-29T22:00:00Z"},
+		{"sql-vm", "DB_MAX_PARALLELISM", 0, "maxDegreeOfParallelism", "0", "2024-03-01T10:00:00Z"},
+	}
+	if diff := cmp.Diff(want, records(t, path)); diff != "" {
+		t.Errorf("Write() kept unexpected fields (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	details := []internal.Details{{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDegreeOfParallelism": "0"}}}}
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Write(context.Background(), path, "sql-vm", details, now, 0)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Write() = %v", err)
+		}
+	}
+	if got := len(records(t, path)); got != len(errs) {
+		t.Errorf("Write() recorded %d fields, want %d", got, len(errs))
+	}
+}
+
+func TestWriteInvalidPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "history.db")
+	if err := Write(context.Background(), path, "sql-vm", nil, time.Now(), 0); err == nil {
+		t.Errorf("Write(%q) succeeded, want error", path)
+	}
+}
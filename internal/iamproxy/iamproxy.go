@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package iamproxy connects to SQL Server through a local IAM-authenticated database proxy.
+package iamproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/impersonate"
+)
+
+// loginScope is the OAuth scope of the access tokens authenticating database logins.
+const loginScope = "https://www.googleapis.com/auth/sqlservice.login"
+
+// Dialer opens the connections to SQL Server through the local proxy and provides the access
+// tokens of the IAM identity authenticating them.
+type Dialer struct {
+	network  string
+	address  string
+	endpoint string
+	dialer   *net.Dialer
+	tokens   oauth2.TokenSource
+}
+
+// NewDialer returns a Dialer connecting to the proxy at endpoint with the short-lived access
+// tokens of the service account identity. The tokens are cached and refreshed before they expire.
+// The endpoint is either "host:port" or the path of a unix socket, optionally prefixed by "unix:".
+func NewDialer(ctx context.Context, endpoint, identity string) (*Dialer, error) {
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: identity,
+		Scopes:          []string{loginScope},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v error impersonating service account %s", err, identity)
+	}
+	return newDialer(endpoint, ts)
+}
+
+func newDialer(endpoint string, tokens oauth2.TokenSource) (*Dialer, error) {
+	network, address, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Dialer{network: network, address: address, endpoint: endpoint, dialer: &net.Dialer{}, tokens: tokens}, nil
+}
+
+// parseEndpoint returns the network and address of the proxy endpoint.
+func parseEndpoint(endpoint string) (string, string, error) {
+	if path, ok := strings.CutPrefix(endpoint, "unix:"); ok {
+		endpoint = path
+	}
+	if strings.HasPrefix(endpoint, "/") {
+		return "unix", endpoint, nil
+	}
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		return "", "", fmt.Errorf("invalid proxy endpoint %q: %v", endpoint, err)
+	}
+	return "tcp", endpoint, nil
+}
+
+// DialContext opens a connection to the proxy. The requested address is ignored since the proxy
+// forwards all connections to the SQL Server it fronts.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx, d.network, d.address)
+}
+
+// HostName returns the proxy endpoint.
+// It keeps the SQL Server driver from resolving the server name locally.
+func (d *Dialer) HostName() string {
+	return d.endpoint
+}
+
+// AccessToken returns a valid access token of the IAM identity.
+func (d *Dialer) AccessToken(ctx context.Context) (string, error) {
+	t, err := d.tokens.Token()
+	if err != nil {
+		return "", fmt.Errorf("%v error getting the access token for the proxy", err)
+	}
+	return t.AccessToken, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iamproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+type errTokenSource struct{}
+
+func (errTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("token error")
+}
+
+func TestParseEndpoint(t *testing.T) {
+	testcases := []struct {
+		name        string
+		endpoint    string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{
+			name:        "tcp",
+			endpoint:    "127.0.0.1:1433",
+			wantNetwork: "tcp",
+			wantAddress: "127.0.0.1:1433",
+		},
+		{
+			name:        "unix socket path",
+			endpoint:    "/var/run/proxy/sqlserver.sock",
+			wantNetwork: "unix",
+			wantAddress: "/var/run/proxy/sqlserver.sock",
+		},
+		{
+			name:        "unix socket prefix",
+			endpoint:    "unix:/var/run/proxy/sqlserver.sock",
+			wantNetwork: "unix",
+			wantAddress: "/var/run/proxy/sqlserver.sock",
+		},
+		{
+			name:     "missing port",
+			endpoint: "localhost",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			network, address, err := parseEndpoint(tc.endpoint)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("parseEndpoint(%q) returned error %v, want error presence = %v", tc.endpoint, err, tc.wantErr)
+			}
+			if network != tc.wantNetwork || address != tc.wantAddress {
+				t.Errorf("parseEndpoint(%q) = (%q, %q), want (%q, %q)", tc.endpoint, network, address, tc.wantNetwork, tc.wantAddress)
+			}
+		})
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "proxy.sock"))
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	defer l.Close()
+	accepted := make(chan struct{})
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+			close(accepted)
+		}
+	}()
+
+	d, err := newDialer("unix:"+l.Addr().String(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}))
+	if err != nil {
+		t.Fatalf("newDialer() = %v, want nil", err)
+	}
+	// The requested address is ignored in favor of the proxy endpoint.
+	c, err := d.DialContext(context.Background(), "tcp", "sql-server:1433")
+	if err != nil {
+		t.Fatalf("DialContext() = %v, want nil", err)
+	}
+	c.Close()
+	<-accepted
+}
+
+func TestAccessToken(t *testing.T) {
+	testcases := []struct {
+		name    string
+		tokens  oauth2.TokenSource
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			tokens: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}),
+			want:   "token",
+		},
+		{
+			name:    "error",
+			tokens:  errTokenSource{},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := newDialer("127.0.0.1:1433", tc.tokens)
+			if err != nil {
+				t.Fatalf("newDialer() = %v, want nil", err)
+			}
+			got, err := d.AccessToken(context.Background())
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("AccessToken() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("AccessToken() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
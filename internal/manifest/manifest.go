@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifest records which rules of a collection ran, were skipped or failed, and why, and
+// reports them as the collection manifest of the collected instance.
+package manifest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// Outcomes of the rules in the collection manifest.
+const (
+	Ran     = "ran"
+	Skipped = "skipped"
+	Failed  = "failed"
+)
+
+// Reasons rules are skipped or fail in the collection manifest.
+const (
+	// SkippedEdition master rules do not apply to the edition of the sql server.
+	SkippedEdition = "edition"
+	// SkippedVersion master rules do not apply to the major version of the sql server.
+	SkippedVersion = "version"
+	// SkippedCached master rules report their cached results.
+	SkippedCached = "cached"
+	// SkippedUnchanged per-database rules report their previous results as no database changed.
+	SkippedUnchanged = "unchanged"
+	// SkippedWorkload master rules do not suit the workload type of the sql server.
+	SkippedWorkload = "workload"
+	// SkippedDisabled rules are not enabled by the configuration.
+	SkippedDisabled = "disabled"
+	// SkippedIgnoredError master rules failed with an error configured to be ignored.
+	SkippedIgnoredError = "ignored_error"
+	// FailedLockTimeout master rules were aborted by the lock timeout.
+	FailedLockTimeout = "lock_timeout"
+	// FailedQuery master rules failed to run their query.
+	FailedQuery = "query_error"
+	// FailedPostProcessor master rules reference a post-processor that is not registered.
+	FailedPostProcessor = "post_processor_error"
+	// FailedCommand guest rules failed to run their command or WMI query.
+	FailedCommand = "command_error"
+	// FailedTimeout rules did not complete within the collection timeout.
+	FailedTimeout = "timeout"
+	// FailedUnreachable guest rules were not run as the remote machine cannot be reached.
+	FailedUnreachable = "unreachable"
+)
+
+// statusOrder is the order of the outcomes in the collection manifest.
+var statusOrder = map[string]int{Ran: 0, Skipped: 1, Failed: 2}
+
+// outcome is the outcome of a rule and the reason it was skipped or failed.
+type outcome struct {
+	status string
+	reason string
+}
+
+// Manifest records the outcome of each rule of a collection. All methods are safe for concurrent
+// use and do nothing on a nil Manifest.
+type Manifest struct {
+	mu       sync.Mutex
+	outcomes map[outcome][]string
+}
+
+// New returns an empty Manifest.
+func New() *Manifest {
+	return &Manifest{outcomes: map[outcome][]string{}}
+}
+
+type manifestKey struct{}
+
+// NewContext returns a context carrying a new Manifest, along with the Manifest.
+func NewContext(ctx context.Context) (context.Context, *Manifest) {
+	m := New()
+	return context.WithValue(ctx, manifestKey{}, m), m
+}
+
+// FromContext returns the Manifest of the context, or nil if it has none.
+func FromContext(ctx context.Context) *Manifest {
+	m, _ := ctx.Value(manifestKey{}).(*Manifest)
+	return m
+}
+
+// Add records the outcome of the rule.
+func (m *Manifest) Add(rule, status, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o := outcome{status: status, reason: reason}
+	m.outcomes[o] = append(m.outcomes[o], rule)
+}
+
+// Details returns the manifest as CollectionManifestName, with one row per outcome and reason
+// listing its rules in alphabetical order separated by commas.
+func (m *Manifest) Details() internal.Details {
+	if m == nil {
+		return internal.Details{Name: internal.CollectionManifestName, Fields: []map[string]string{}}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes := make([]outcome, 0, len(m.outcomes))
+	for o := range m.outcomes {
+		outcomes = append(outcomes, o)
+	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		if outcomes[i].status != outcomes[j].status {
+			return statusOrder[outcomes[i].status] < statusOrder[outcomes[j].status]
+		}
+		return outcomes[i].reason < outcomes[j].reason
+	})
+	fields := []map[string]string{}
+	for _, o := range outcomes {
+		rules := append([]string{}, m.outcomes[o]...)
+		sort.Strings(rules)
+		fields = append(fields, map[string]string{
+			"status": o.status,
+			"reason": o.reason,
+			"count":  strconv.Itoa(len(rules)),
+			"rules":  strings.Join(rules, ","),
+		})
+	}
+	return internal.Details{Name: internal.CollectionManifestName, Fields: fields}
+}
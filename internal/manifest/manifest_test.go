@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+func TestDetails(t *testing.T) {
+	m := New()
+	m.Add("RULE_C", Failed, FailedQuery)
+	m.Add("RULE_B", Ran, "")
+	m.Add("RULE_D", Skipped, SkippedVersion)
+	m.Add("RULE_A", Ran, "")
+	m.Add("RULE_E", Skipped, SkippedDisabled)
+
+	want := internal.Details{
+		Name: internal.CollectionManifestName,
+		Fields: []map[string]string{
+			{"status": "ran", "reason": "", "count": "2", "rules": "RULE_A,RULE_B"},
+			{"status": "skipped", "reason": "disabled", "count": "1", "rules": "RULE_E"},
+			{"status": "skipped", "reason": "version", "count": "1", "rules": "RULE_D"},
+			{"status": "failed", "reason": "query_error", "count": "1", "rules": "RULE_C"},
+		},
+	}
+	if diff := cmp.Diff(m.Details(), want); diff != "" {
+		t.Errorf("Details() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestNilManifest(t *testing.T) {
+	m := FromContext(context.Background())
+	if m != nil {
+		t.Fatalf("FromContext() = %v, want nil", m)
+	}
+	m.Add("RULE_A", Ran, "")
+	want := internal.Details{Name: internal.CollectionManifestName, Fields: []map[string]string{}}
+	if diff := cmp.Diff(m.Details(), want); diff != "" {
+		t.Errorf("Details() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	ctx, m := NewContext(context.Background())
+	if got := FromContext(ctx); got != m {
+		t.Errorf("FromContext() = %p, want %p", got, m)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package openmetrics writes collected data in the OpenMetrics text format to files picked up by
+// the textfile collector of node_exporter.
+package openmetrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// metricPrefix is the prefix of the names of all metrics.
+const metricPrefix = "sqlserver_"
+
+// labelFields are the string fields that become labels. They identify the rows of a rule with a
+// bounded set of values, such as the databases of the instance or the wait types. Other string
+// fields, such as paths, query texts and deadlock graphs, are left out to bound the number of
+// series.
+var labelFields = map[string]bool{
+	"authentication_mode": true,
+	"db_name":             true,
+	"edition":             true,
+	"host_name":           true,
+	"memory_model":        true,
+	"page_verify_option":  true,
+	"pool_name":           true,
+	"port_number":         true,
+	"product_level":       true,
+	"product_version":     true,
+	"role":                true,
+	"state":               true,
+	"type":                true,
+	"wait_type":           true,
+}
+
+// invalidNameChars matches the characters that are not allowed in metric and label names.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// labelEscaper escapes label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sample is a value of a metric along with its labels.
+type sample struct {
+	labels string
+	value  float64
+}
+
+// Format returns the details in the OpenMetrics text format. Each numeric or boolean field of a
+// row becomes a gauge named after the rule and the field, labeled with the label fields of the
+// row and the given labels. Rows without numeric fields become an info metric with value 1.
+// Unknown values are left out.
+func Format(labels map[string]string, details []internal.Details) []byte {
+	metrics := map[string][]sample{}
+	seen := map[string]bool{}
+	add := func(name, labels string, value float64) {
+		// Samples of a metric need distinct labels; later rows with the same labels are dropped.
+		if seen[name+labels] {
+			return
+		}
+		seen[name+labels] = true
+		metrics[name] = append(metrics[name], sample{labels: labels, value: value})
+	}
+	for _, d := range details {
+		rule := metricPrefix + sanitize(d.Name)
+		for _, row := range d.Fields {
+			rowLabels := map[string]string{}
+			for k, v := range labels {
+				rowLabels[k] = v
+			}
+			values := map[string]float64{}
+			for field, v := range row {
+				if v == "" || v == "unknown" {
+					continue
+				}
+				if value, ok := numeric(v); ok {
+					values[field] = value
+				} else if labelFields[field] {
+					rowLabels[sanitize(field)] = v
+				}
+			}
+			formatted := formatLabels(rowLabels)
+			if len(values) == 0 {
+				// OpenMetrics info metrics are written as gauges, since the textfile collector only
+				// accepts the types of the Prometheus text format.
+				add(rule+"_info", formatted, 1)
+				continue
+			}
+			for field, value := range values {
+				add(rule+"_"+sanitize(field), formatted, value)
+			}
+		}
+	}
+	var names []string
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		samples := metrics[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+		for _, s := range samples {
+			fmt.Fprintf(&b, "%s%s %s\n", name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+	b.WriteString("# EOF\n")
+	return []byte(b.String())
+}
+
+// WriteFile writes the details in the OpenMetrics text format to the file with the given name in
+// dir. The file is written to a temporary file first and then renamed, so the textfile collector
+// never reads a partially written file.
+func WriteFile(dir, name string, labels map[string]string, details []internal.Details) error {
+	// The textfile collector only reads files ending in .prom.
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(Format(labels, details)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// node_exporter usually runs as a different user than the agent.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// FileName returns the name of the file of the collection type of the target instance.
+func FileName(instance, collectionType string) string {
+	return fmt.Sprintf("%s-%s-%s.prom", internal.ServiceName, sanitize(instance), collectionType)
+}
+
+// numeric returns the value of numbers and booleans.
+func numeric(v string) (float64, bool) {
+	switch v {
+	case "true":
+		return 1, true
+	case "false":
+		return 0, true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, err == nil
+}
+
+// sanitize lowercases s and replaces the characters not allowed in names with underscores.
+func sanitize(s string) string {
+	return invalidNameChars.ReplaceAllString(strings.ToLower(s), "_")
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, labelEscaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
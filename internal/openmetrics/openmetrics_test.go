@@ -0,0 +1,131 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openmetrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+func TestFormat(t *testing.T) {
+	testcases := []struct {
+		name    string
+		labels  map[string]string
+		details []internal.Details
+		want    string
+	}{
+		{
+			name:   "numeric and boolean fields with label fields as labels",
+			labels: map[string]string{"instance": "sql-1"},
+			details: []internal.Details{
+				{
+					Name: "DB_VIRTUAL_LOG_FILE_COUNT",
+					Fields: []map[string]string{
+						{"db_name": "sales", "vlf_count": "1200", "exceeds_threshold": "true"},
+						{"db_name": `a"b`, "vlf_count": "4", "exceeds_threshold": "false"},
+					},
+				},
+			},
+			want: `# TYPE sqlserver_db_virtual_log_file_count_exceeds_threshold gauge
+sqlserver_db_virtual_log_file_count_exceeds_threshold{db_name="a\"b",instance="sql-1"} 0
+sqlserver_db_virtual_log_file_count_exceeds_threshold{db_name="sales",instance="sql-1"} 1
+# TYPE sqlserver_db_virtual_log_file_count_vlf_count gauge
+sqlserver_db_virtual_log_file_count_vlf_count{db_name="a\"b",instance="sql-1"} 4
+sqlserver_db_virtual_log_file_count_vlf_count{db_name="sales",instance="sql-1"} 1200
+# EOF
+`,
+		},
+		{
+			name: "rows without numeric fields as info metrics",
+			details: []internal.Details{
+				{
+					Name:   "DB_AUTHENTICATION_MODE",
+					Fields: []map[string]string{{"authentication_mode": "mixed", "is_integrated_security_only": "unknown"}},
+				},
+			},
+			want: `# TYPE sqlserver_db_authentication_mode_info gauge
+sqlserver_db_authentication_mode_info{authentication_mode="mixed"} 1
+# EOF
+`,
+		},
+		{
+			name: "unknown values, other string fields and duplicate rows left out",
+			details: []internal.Details{
+				{
+					Name: "DB_DEADLOCK_COUNT",
+					Fields: []map[string]string{
+						{"deadlock_count": "2", "latest_deadlock_graph": "<deadlock/>", "physical_name": `C:\data\sales.mdf`},
+						{"deadlock_count": "3"},
+						{"deadlock_count": "unknown"},
+					},
+				},
+			},
+			want: `# TYPE sqlserver_db_deadlock_count_deadlock_count gauge
+sqlserver_db_deadlock_count_deadlock_count 2
+# TYPE sqlserver_db_deadlock_count_info gauge
+sqlserver_db_deadlock_count_info 1
+# EOF
+`,
+		},
+		{
+			name: "no details",
+			want: "# EOF\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(Format(tc.labels, tc.details))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Format() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := FileName("sql-1", "sql")
+	details := []internal.Details{{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{{"max_backup_age": "3"}}}}
+	if err := WriteFile(dir, name, nil, details); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("os.ReadFile() failed: %v", err)
+	}
+	if diff := cmp.Diff(string(Format(nil, details)), string(got)); diff != "" {
+		t.Errorf("WriteFile() wrote unexpected content (-want +got):\n%s", diff)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("os.ReadDir() failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("WriteFile() left %d files in the directory, want 1", len(entries))
+	}
+}
+
+func TestFileName(t *testing.T) {
+	want := "google-cloud-sql-server-agent-sql_1_example_com-sql.prom"
+	if got := FileName("sql-1.example.com", "sql"); got != want {
+		t.Errorf("FileName() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// PostProcessor transforms the fields of a rule into its final fields, e.g. by adding values
+// derived from the collected columns. It must not modify its input.
+type PostProcessor func([]map[string]string) []map[string]string
+
+// PostProcessorFactory returns the post-processor for the arguments of a rule, or an error if the
+// arguments are invalid.
+type PostProcessorFactory func(args []string) (PostProcessor, error)
+
+// PostProcessorRef references a registered post-processor by name, with the arguments it is built
+// with, e.g. the fields a ratio is computed from.
+type PostProcessorRef struct {
+	Name string
+	Args []string
+}
+
+var (
+	postProcessorsMu sync.RWMutex
+	// postProcessors holds the built-in post-processors and the ones registered with
+	// RegisterPostProcessor.
+	postProcessors = map[string]PostProcessorFactory{
+		"ratio":     newRatio,
+		"threshold": newThreshold,
+	}
+)
+
+// RegisterPostProcessor makes a post-processor available to the rules under the given name.
+// It panics if the name is empty, the factory is nil or the name is already registered.
+func RegisterPostProcessor(name string, f PostProcessorFactory) {
+	postProcessorsMu.Lock()
+	defer postProcessorsMu.Unlock()
+	if name == "" || f == nil {
+		panic("internal: RegisterPostProcessor with an empty name or a nil factory")
+	}
+	if _, ok := postProcessors[name]; ok {
+		panic(fmt.Sprintf("internal: post-processor %q is already registered", name))
+	}
+	postProcessors[name] = f
+}
+
+// LookupPostProcessor returns the post-processor the reference names, built with its arguments.
+// It returns an error if the name is not registered or the arguments are invalid.
+func LookupPostProcessor(ref PostProcessorRef) (PostProcessor, error) {
+	postProcessorsMu.RLock()
+	f, ok := postProcessors[ref.Name]
+	postProcessorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown post-processor %q", ref.Name)
+	}
+	p, err := f(ref.Args)
+	if err != nil {
+		return nil, fmt.Errorf("post-processor %q: %v", ref.Name, err)
+	}
+	return p, nil
+}
+
+// newRatio builds the "ratio" post-processor from the numerator, denominator and name arguments of
+// Ratio.
+func newRatio(args []string) (PostProcessor, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("want the numerator, denominator and name arguments, got %q", args)
+	}
+	return Ratio(args[0], args[1], args[2]), nil
+}
+
+// newThreshold builds the "threshold" post-processor from the field, name and max arguments of
+// Threshold.
+func newThreshold(args []string) (PostProcessor, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("want the field, name and max arguments, got %q", args)
+	}
+	max, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max %q: %v", args[2], err)
+	}
+	return Threshold(args[0], args[1], max), nil
+}
+
+// Ratio returns a post-processor that adds the field name to every row, set to numerator divided
+// by denominator as a percentage with two decimals. The field is "unknown" if either value is
+// missing or not a number, or if the denominator is zero.
+func Ratio(numerator, denominator, name string) PostProcessor {
+	return func(rows []map[string]string) []map[string]string {
+		return withField(rows, name, func(row map[string]string) string {
+			n, err := strconv.ParseFloat(row[numerator], 64)
+			if err != nil {
+				return "unknown"
+			}
+			d, err := strconv.ParseFloat(row[denominator], 64)
+			if err != nil || d == 0 {
+				return "unknown"
+			}
+			return strconv.FormatFloat(n/d*100, 'f', 2, 64)
+		})
+	}
+}
+
+// Threshold returns a post-processor that adds the field name to every row, set to "true" if
+// field is greater than max and "false" otherwise. The field is "unknown" if the value is
+// missing or not a number.
+func Threshold(field, name string, max float64) PostProcessor {
+	return func(rows []map[string]string) []map[string]string {
+		return withField(rows, name, func(row map[string]string) string {
+			v, err := strconv.ParseFloat(row[field], 64)
+			if err != nil {
+				return "unknown"
+			}
+			return strconv.FormatBool(v > max)
+		})
+	}
+}
+
+// withField returns copies of the rows with the field name set to the value computed from each row.
+func withField(rows []map[string]string, name string, value func(map[string]string) string) []map[string]string {
+	if rows == nil {
+		return nil
+	}
+	res := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		r := make(map[string]string, len(row)+1)
+		for k, v := range row {
+			r[k] = v
+		}
+		r[name] = value(row)
+		res = append(res, r)
+	}
+	return res
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRatio(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input []map[string]string
+		want  []map[string]string
+	}{
+		{
+			name:  "computes the percentage",
+			input: []map[string]string{{"used": "1", "total": "8"}},
+			want:  []map[string]string{{"used": "1", "total": "8", "used_percent": "12.50"}},
+		},
+		{
+			name:  "zero denominator",
+			input: []map[string]string{{"used": "1", "total": "0"}},
+			want:  []map[string]string{{"used": "1", "total": "0", "used_percent": "unknown"}},
+		},
+		{
+			name:  "unknown numerator",
+			input: []map[string]string{{"used": "unknown", "total": "8"}},
+			want:  []map[string]string{{"used": "unknown", "total": "8", "used_percent": "unknown"}},
+		},
+		{
+			name:  "missing denominator",
+			input: []map[string]string{{"used": "1"}},
+			want:  []map[string]string{{"used": "1", "used_percent": "unknown"}},
+		},
+		{
+			name: "nil rows",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Ratio("used", "total", "used_percent")(tc.input)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Ratio() returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input []map[string]string
+		want  []map[string]string
+	}{
+		{
+			name:  "above the threshold",
+			input: []map[string]string{{"value": "90.5"}},
+			want:  []map[string]string{{"value": "90.5", "high": "true"}},
+		},
+		{
+			name:  "at the threshold",
+			input: []map[string]string{{"value": "90"}},
+			want:  []map[string]string{{"value": "90", "high": "false"}},
+		},
+		{
+			name:  "unknown value",
+			input: []map[string]string{{"value": "unknown"}},
+			want:  []map[string]string{{"value": "unknown", "high": "unknown"}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Threshold("value", "high", 90)(tc.input)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Threshold() returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPostProcessorsDoNotModifyInput(t *testing.T) {
+	input := []map[string]string{{"value": "1"}}
+	Threshold("value", "high", 0)(input)
+	if diff := cmp.Diff([]map[string]string{{"value": "1"}}, input); diff != "" {
+		t.Errorf("Threshold() modified its input (-want +got):\n%s", diff)
+	}
+}
+
+func TestRegisterPostProcessor(t *testing.T) {
+	defer func() {
+		postProcessorsMu.Lock()
+		delete(postProcessors, "test_register")
+		postProcessorsMu.Unlock()
+	}()
+	factory := func([]string) (PostProcessor, error) { return Threshold("value", "high", 0), nil }
+	RegisterPostProcessor("test_register", factory)
+	if _, err := LookupPostProcessor(PostProcessorRef{Name: "test_register"}); err != nil {
+		t.Errorf("LookupPostProcessor(%q) returned unexpected error: %v", "test_register", err)
+	}
+	for _, name := range []string{"", "test_register", "ratio"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterPostProcessor(%q) did not panic", name)
+				}
+			}()
+			RegisterPostProcessor(name, factory)
+		}()
+	}
+}
+
+func TestLookupPostProcessor(t *testing.T) {
+	testcases := []struct {
+		name    string
+		ref     PostProcessorRef
+		input   []map[string]string
+		want    []map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "ratio",
+			ref:   PostProcessorRef{Name: "ratio", Args: []string{"used", "total", "used_percent"}},
+			input: []map[string]string{{"used": "1", "total": "4"}},
+			want:  []map[string]string{{"used": "1", "total": "4", "used_percent": "25.00"}},
+		},
+		{
+			name:  "threshold",
+			ref:   PostProcessorRef{Name: "threshold", Args: []string{"value", "high", "90"}},
+			input: []map[string]string{{"value": "95"}},
+			want:  []map[string]string{{"value": "95", "high": "true"}},
+		},
+		{
+			name:    "unknown name",
+			ref:     PostProcessorRef{Name: "unregistered"},
+			wantErr: true,
+		},
+		{
+			name:    "missing arguments",
+			ref:     PostProcessorRef{Name: "ratio", Args: []string{"used", "total"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid max",
+			ref:     PostProcessorRef{Name: "threshold", Args: []string{"value", "high", "many"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := LookupPostProcessor(tc.ref)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("LookupPostProcessor(%v) returned error: %v, want error: %v", tc.ref, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, p(tc.input)); diff != "" {
+				t.Errorf("LookupPostProcessor(%v) returned diff (-want +got):\n%s", tc.ref, diff)
+			}
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	rule := MasterRuleStruct{
+		Name: "TEST",
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			return []map[string]string{{"value": HandleNilInt(fields[0][0])}}
+		},
+		PostProcessors: []PostProcessorRef{{Name: "threshold", Args: []string{"value", "high", "5"}}},
+	}
+	got, err := rule.Results([][]any{{int64(10)}}, DefaultRuleSettings())
+	if err != nil {
+		t.Fatalf("Results() returned unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]map[string]string{{"value": "10", "high": "true"}}, got); diff != "" {
+		t.Errorf("Results() returned diff (-want +got):\n%s", diff)
+	}
+
+	rule.PostProcessors = []PostProcessorRef{{Name: "unregistered"}}
+	if _, err := rule.Results([][]any{{int64(10)}}, DefaultRuleSettings()); err == nil {
+		t.Errorf("Results() with an unregistered post-processor returned nil error")
+	}
+}
+
+func TestMasterRulesPostProcessorsResolve(t *testing.T) {
+	for _, rule := range MasterRules {
+		for _, ref := range rule.PostProcessors {
+			if _, err := LookupPostProcessor(ref); err != nil {
+				t.Errorf("rule %q references a post-processor that does not resolve: %v", rule.Name, err)
+			}
+		}
+	}
+}
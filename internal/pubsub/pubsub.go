@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pubsub publishes collected data to Pub/Sub topics.
+package pubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+	htransport "google.golang.org/api/transport/http"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// topicIDRegex matches the id of a Pub/Sub topic.
+var topicIDRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9\-_.~+%]{2,254}$`)
+
+// topicNameRegex matches the full resource name of a Pub/Sub topic.
+var topicNameRegex = regexp.MustCompile(`^projects/[^/]+/topics/([^/]+)$`)
+
+// Publisher the interface of the Pub/Sub exporter.
+type Publisher interface {
+	Publish(ctx context.Context, data []byte, attributes map[string]string, orderingKey string) error
+}
+
+// Client publishes messages to a Pub/Sub topic.
+type Client struct {
+	service *pubsub.Service
+	topic   string
+}
+
+// ValidTopic returns true if topic is either a topic id or the full resource name of a topic.
+func ValidTopic(topic string) bool {
+	if m := topicNameRegex.FindStringSubmatch(topic); m != nil {
+		return topicIDRegex.MatchString(m[1])
+	}
+	return topicIDRegex.MatchString(topic)
+}
+
+// TopicName returns the full resource name of the topic.
+// A topic id is expanded to a topic in the given project.
+func TopicName(topic, projectID string) (string, error) {
+	if !ValidTopic(topic) {
+		return "", fmt.Errorf("invalid pubsub topic %q", topic)
+	}
+	if topicNameRegex.MatchString(topic) {
+		return topic, nil
+	}
+	return fmt.Sprintf("projects/%s/topics/%s", projectID, topic), nil
+}
+
+// NewClient creates a Client publishing to the topic with the full resource name.
+// Application default credentials are used unless a service account to impersonate is given.
+// If dialer is not nil, it is used for all connections to Pub/Sub.
+func NewClient(ctx context.Context, topic, impersonateServiceAccount string, dialer internal.ContextDialer) (*Client, error) {
+	var opts []option.ClientOption
+	if impersonateServiceAccount != "" {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: impersonateServiceAccount,
+			Scopes:          []string{pubsub.PubsubScope},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v error impersonating service account %s", err, impersonateServiceAccount)
+		}
+		opts = append(opts, option.WithTokenSource(ts))
+	}
+	if dialer != nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.DialContext = dialer.DialContext
+		trans, err := htransport.NewTransport(ctx, base, append(opts, option.WithScopes(pubsub.PubsubScope))...)
+		if err != nil {
+			return nil, fmt.Errorf("%v error creating Pub/Sub transport", err)
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: trans})}
+	}
+	return newClient(ctx, topic, opts...)
+}
+
+func newClient(ctx context.Context, topic string, opts ...option.ClientOption) (*Client, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%v error creating Pub/Sub client", err)
+	}
+	return &Client{service: service, topic: topic}, nil
+}
+
+// Publish publishes a message with the data and attributes to the topic.
+// Messages with the same non-empty ordering key are delivered in order to subscriptions with
+// message ordering enabled.
+func (c *Client) Publish(ctx context.Context, data []byte, attributes map[string]string, orderingKey string) error {
+	req := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:        base64.StdEncoding.EncodeToString(data),
+			Attributes:  attributes,
+			OrderingKey: orderingKey,
+		}},
+	}
+	_, err := c.service.Projects.Topics.Publish(c.topic, req).Context(ctx).Do()
+	return err
+}
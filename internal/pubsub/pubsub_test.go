@@ -0,0 +1,127 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+func TestTopicName(t *testing.T) {
+	testcases := []struct {
+		name    string
+		topic   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "topic id",
+			topic: "sql-server-agent",
+			want:  "projects/test-project/topics/sql-server-agent",
+		},
+		{
+			name:  "full resource name",
+			topic: "projects/other-project/topics/sql-server-agent",
+			want:  "projects/other-project/topics/sql-server-agent",
+		},
+		{
+			name:    "topic id too short",
+			topic:   "ab",
+			wantErr: true,
+		},
+		{
+			name:    "topic id starts with a digit",
+			topic:   "1topic",
+			wantErr: true,
+		},
+		{
+			name:    "invalid resource name",
+			topic:   "projects/other-project/subscriptions/sql-server-agent",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TopicName(tc.topic, "test-project")
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("TopicName(%q) = %v, want error presence = %v", tc.topic, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("TopicName(%q) = %q, want %q", tc.topic, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	var gotPath string
+	var gotReq pubsub.PublishRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			t.Errorf("failed to decode the publish request: %v", err)
+		}
+		w.Write([]byte(`{"messageIds": ["1"]}`))
+	}))
+	defer ts.Close()
+
+	c, err := newClient(context.Background(), "projects/test-project/topics/test-topic", option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("newClient() returned an unexpected error: %v", err)
+	}
+	attributes := map[string]string{"instance": "test-instance"}
+	if err := c.Publish(context.Background(), []byte(`{"insight":{}}`), attributes, "test-instance"); err != nil {
+		t.Fatalf("Publish() returned an unexpected error: %v", err)
+	}
+
+	if want := "/v1/projects/test-project/topics/test-topic:publish"; gotPath != want {
+		t.Errorf("Publish() sent request to %q, want %q", gotPath, want)
+	}
+	want := pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:        base64.StdEncoding.EncodeToString([]byte(`{"insight":{}}`)),
+			Attributes:  attributes,
+			OrderingKey: "test-instance",
+		}},
+	}
+	if diff := cmp.Diff(gotReq, want); diff != "" {
+		t.Errorf("Publish() sent wrong request (-got +want):\n%s", diff)
+	}
+}
+
+func TestPublishError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"code": 404, "message": "topic not found"}}`, http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c, err := newClient(context.Background(), "projects/test-project/topics/test-topic", option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("newClient() returned an unexpected error: %v", err)
+	}
+	if err := c.Publish(context.Background(), []byte("data"), nil, ""); err == nil {
+		t.Error("Publish() returned nil error, want error")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
+)
+
+// TunnelDialer opens TCP connections through an SSH bastion host.
+type TunnelDialer struct {
+	host   string
+	client *ssh.Client
+}
+
+// NewTunnelDialer connects to the bastion host and returns a TunnelDialer.
+// The known_hosts file is expected in the same directory as the private key.
+// If dialer is not nil, it is used for the connection to the bastion host.
+func NewTunnelDialer(ctx context.Context, ipaddr, user string, port int32, privateKeyPath string, dialer internal.ContextDialer, usageMetricsLogger agentstatus.AgentStatus) (*TunnelDialer, error) {
+	r := &remote{
+		ip:                 ipaddr,
+		port:               port,
+		user:               user,
+		key:                &key{},
+		usageMetricsLogger: usageMetricsLogger,
+	}
+	if err := r.SetupKeys(privateKeyPath); err != nil {
+		return nil, err
+	}
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	addr := net.JoinHostPort(ipaddr, strconv.FormatInt(int64(port), 10))
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("an error occured while dialing the bastion host. %v", err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, r.clientConfig())
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("an error occured while ssh connecting to the bastion host. %v", err)
+	}
+	return &TunnelDialer{host: ipaddr, client: ssh.NewClient(c, chans, reqs)}, nil
+}
+
+// DialContext opens a connection to addr from the bastion host.
+func (d *TunnelDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.client.DialContext(ctx, network, addr)
+}
+
+// HostName returns the bastion host.
+// It lets the SQL Server driver resolve the server name on the bastion instead of locally.
+func (d *TunnelDialer) HostName() string {
+	return d.host
+}
+
+// Close closes the SSH connection to the bastion host.
+func (d *TunnelDialer) Close() error {
+	return d.client.Close()
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeBastion starts an SSH server which accepts the given key and forwards direct-tcpip channels.
+func fakeBastion(t *testing.T, signer ssh.Signer) net.Listener {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(signer.PublicKey().Marshal()) {
+				return nil, fmt.Errorf("unknown public key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					var target struct {
+						DestAddr string
+						DestPort uint32
+						OrigAddr string
+						OrigPort uint32
+					}
+					if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &target) != nil {
+						newChannel.Reject(ssh.UnknownChannelType, "unsupported channel")
+						continue
+					}
+					targetConn, err := net.Dial("tcp", net.JoinHostPort(target.DestAddr, strconv.Itoa(int(target.DestPort))))
+					if err != nil {
+						newChannel.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+					ch, chReqs, err := newChannel.Accept()
+					if err != nil {
+						targetConn.Close()
+						continue
+					}
+					go ssh.DiscardRequests(chReqs)
+					go func() {
+						defer ch.Close()
+						defer targetConn.Close()
+						go io.Copy(targetConn, ch)
+						io.Copy(ch, targetConn)
+					}()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// echoServer starts a TCP server which echoes everything it reads.
+func echoServer(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
+
+func TestTunnelDialer(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyPath := path.Join(dir, "key")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := "127.0.0.1 " + string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	if err := os.WriteFile(path.Join(dir, "known_hosts"), []byte(knownHosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	bastion := fakeBastion(t, signer)
+	defer bastion.Close()
+	target := echoServer(t)
+	defer target.Close()
+	bastionPort := bastion.Addr().(*net.TCPAddr).Port
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	testcases := []struct {
+		name    string
+		port    int
+		keyPath string
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			port:    bastionPort,
+			keyPath: keyPath,
+		},
+		{
+			name:    "missing private key",
+			port:    bastionPort,
+			keyPath: path.Join(dir, "any"),
+			wantErr: true,
+		},
+		{
+			name:    "bastion unreachable",
+			port:    closedPort,
+			keyPath: keyPath,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			d, err := NewTunnelDialer(ctx, "127.0.0.1", "test-user", int32(tc.port), tc.keyPath, nil, nil)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("NewTunnelDialer() = %v, want error presence = %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer d.Close()
+			if got := d.HostName(); got != "127.0.0.1" {
+				t.Errorf("HostName() = %q, want %q", got, "127.0.0.1")
+			}
+			conn, err := d.DialContext(ctx, "tcp", target.Addr().String())
+			if err != nil {
+				t.Fatalf("DialContext() = %v, want nil", err)
+			}
+			defer conn.Close()
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatalf("Write() = %v, want nil", err)
+			}
+			got := make([]byte, 4)
+			if _, err := io.ReadFull(conn, got); err != nil {
+				t.Fatalf("ReadFull() = %v, want nil", err)
+			}
+			if string(got) != "ping" {
+				t.Errorf("tunneled connection returned %q, want %q", got, "ping")
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resourcelimits limits the CPU and memory used by the agent process so that it does not
+// compete with SQL Server for the resources of the host.
+package resourcelimits
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Limits are the resource limits of the agent process. Zero values leave the defaults of the
+// process unchanged.
+type Limits struct {
+	MaxProcs         int
+	MemoryLimitBytes int64
+	LowPriority      bool
+}
+
+// Apply applies the limits to the current process.
+func Apply(l Limits) error {
+	if l.MaxProcs > 0 {
+		runtime.GOMAXPROCS(l.MaxProcs)
+	}
+	if l.MemoryLimitBytes > 0 {
+		debug.SetMemoryLimit(l.MemoryLimitBytes)
+	}
+	if l.LowPriority {
+		return lowerPriority()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcelimits
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// niceness is the nice value of the agent process when running at a lower priority.
+const niceness = 10
+
+// lowerPriority sets the nice value of every thread of the process. The nice value is a per-thread
+// attribute on Linux, so setting it for the process only changes the calling thread; threads
+// started later inherit the value of the thread starting them.
+func lowerPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("failed to list the threads of the agent process: %w", err)
+	}
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness); err != nil {
+			return fmt.Errorf("failed to set the nice value of thread %d: %w", tid, err)
+		}
+	}
+	return nil
+}
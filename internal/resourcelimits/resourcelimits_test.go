@@ -0,0 +1,68 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcelimits
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	memoryLimit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		runtime.GOMAXPROCS(procs)
+		debug.SetMemoryLimit(memoryLimit)
+	})
+
+	testcases := []struct {
+		name            string
+		limits          Limits
+		wantProcs       int
+		wantMemoryLimit int64
+	}{
+		{
+			name:            "defaults",
+			limits:          Limits{},
+			wantProcs:       procs,
+			wantMemoryLimit: math.MaxInt64,
+		},
+		{
+			name:            "limits",
+			limits:          Limits{MaxProcs: 1, MemoryLimitBytes: 256 << 20},
+			wantProcs:       1,
+			wantMemoryLimit: 256 << 20,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			runtime.GOMAXPROCS(procs)
+			debug.SetMemoryLimit(math.MaxInt64)
+			if err := Apply(tc.limits); err != nil {
+				t.Fatalf("Apply(%+v) returned error: %v", tc.limits, err)
+			}
+			if got := runtime.GOMAXPROCS(0); got != tc.wantProcs {
+				t.Errorf("Apply(%+v) set GOMAXPROCS to %d, want %d", tc.limits, got, tc.wantProcs)
+			}
+			if got := debug.SetMemoryLimit(-1); got != tc.wantMemoryLimit {
+				t.Errorf("Apply(%+v) set the memory limit to %d, want %d", tc.limits, got, tc.wantMemoryLimit)
+			}
+		})
+	}
+}
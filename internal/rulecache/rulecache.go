@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rulecache keeps the results of rules that change rarely across collection cycles, so
+// that they are only queried again once their results expire.
+package rulecache
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
+)
+
+// Default is the cache shared by all collections of the agent.
+var Default = New(clockwork.NewRealClock())
+
+// result is the last collected result of a rule.
+type result struct {
+	collected time.Time
+	rows      []map[string]string
+}
+
+// Cache keeps the rows of rules collected from each target along with the time they were
+// collected.
+type Cache struct {
+	mu      sync.Mutex
+	clock   clockwork.Clock
+	results map[string]result
+	// identities are the last identities of the targets, e.g. their edition and version.
+	identities map[string]string
+}
+
+// New creates an empty Cache.
+func New(clock clockwork.Clock) *Cache {
+	return &Cache{clock: clock, results: map[string]result{}, identities: map[string]string{}}
+}
+
+// Get returns a copy of the rows of the rule last collected from the target if they were
+// collected less than ttl ago.
+func (c *Cache) Get(target, rule string, ttl time.Duration) ([]map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.results[errorlog.Key(target, rule)]
+	if !ok || c.clock.Since(r.collected) >= ttl {
+		return nil, false
+	}
+	return copyRows(r.rows), true
+}
+
+// Put records a copy of the rows of the rule just collected from the target.
+func (c *Cache) Put(target, rule string, rows []map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[errorlog.Key(target, rule)] = result{collected: c.clock.Now(), rows: copyRows(rows)}
+}
+
+// SetIdentity records the identity of the target, e.g. its edition and version. The cached rows
+// of the target are dropped when its identity changes, e.g. after an upgrade.
+func (c *Cache) SetIdentity(target, identity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if previous, ok := c.identities[target]; ok && previous != identity {
+		prefix := errorlog.Key(target, "")
+		for k := range c.results {
+			if strings.HasPrefix(k, prefix) {
+				delete(c.results, k)
+			}
+		}
+	}
+	c.identities[target] = identity
+}
+
+// copyRows returns a copy of the rows, so that the cached rows are not changed by the callers.
+func copyRows(rows []map[string]string) []map[string]string {
+	res := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		r := make(map[string]string, len(row))
+		for k, v := range row {
+			r[k] = v
+		}
+		res = append(res, r)
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonboulle/clockwork"
+)
+
+func TestCache(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	c := New(clock)
+	rows := []map[string]string{{"value": "1"}}
+	if _, ok := c.Get("host", "DB_PATCH_LEVEL", time.Hour); ok {
+		t.Errorf("Get() of a rule not collected yet returned cached rows")
+	}
+	c.Put("host", "DB_PATCH_LEVEL", rows)
+
+	clock.Advance(59 * time.Minute)
+	got, ok := c.Get("host", "DB_PATCH_LEVEL", time.Hour)
+	if !ok {
+		t.Fatalf("Get() before the ttl returned no rows")
+	}
+	if diff := cmp.Diff(got, rows); diff != "" {
+		t.Errorf("Get() returned wrong rows (-got +want):\n%s", diff)
+	}
+	if _, ok := c.Get("other-host", "DB_PATCH_LEVEL", time.Hour); ok {
+		t.Errorf("Get() of another target returned cached rows")
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := c.Get("host", "DB_PATCH_LEVEL", time.Hour); ok {
+		t.Errorf("Get() after the ttl returned cached rows")
+	}
+}
+
+func TestCacheReturnsCopies(t *testing.T) {
+	c := New(clockwork.NewFakeClock())
+	rows := []map[string]string{{"value": "1"}}
+	c.Put("host", "DB_FILL_FACTOR", rows)
+	rows[0]["value"] = "2"
+
+	got, _ := c.Get("host", "DB_FILL_FACTOR", time.Hour)
+	got[0]["value"] = "3"
+	again, _ := c.Get("host", "DB_FILL_FACTOR", time.Hour)
+	if diff := cmp.Diff(again, []map[string]string{{"value": "1"}}); diff != "" {
+		t.Errorf("Get() returned rows changed by the callers (-got +want):\n%s", diff)
+	}
+}
+
+func TestCacheSetIdentity(t *testing.T) {
+	c := New(clockwork.NewFakeClock())
+	rows := []map[string]string{{"value": "1"}}
+	c.SetIdentity("host", "Standard/15")
+	c.Put("host", "DB_FILL_FACTOR", rows)
+	c.Put("other-host", "DB_FILL_FACTOR", rows)
+
+	c.SetIdentity("host", "Standard/15")
+	if _, ok := c.Get("host", "DB_FILL_FACTOR", time.Hour); !ok {
+		t.Errorf("Get() after SetIdentity() with the same identity returned no rows")
+	}
+	c.SetIdentity("host", "Standard/16")
+	if _, ok := c.Get("host", "DB_FILL_FACTOR", time.Hour); ok {
+		t.Errorf("Get() after SetIdentity() with another identity returned cached rows")
+	}
+	if _, ok := c.Get("other-host", "DB_FILL_FACTOR", time.Hour); !ok {
+		t.Errorf("Get() of another target after SetIdentity() returned no rows")
+	}
+}
@@ -17,7 +17,17 @@ limitations under the License.
 package internal
 
 import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 )
 
 const (
@@ -33,8 +43,250 @@ const (
 	DataDiskAllocationUnitsRule = "data_disk_allocation_units"
 	// GCBDRAgentRunning used for checking if GCBDRAgentRunning is running on the target.
 	GCBDRAgentRunning = "gcbdr_agent_running"
+	// SQLServiceAccountRule used for the account the SQL Server service runs as.
+	SQLServiceAccountRule = "sql_service_account"
+	// PageFileRule used for the page file or swap configuration of the machine.
+	PageFileRule = "page_file"
+	// SQLNetworkProtocolsRule used for the network protocols SQL Server accepts connections on.
+	SQLNetworkProtocolsRule = "sql_network_protocols"
+	// SQLAgentServiceRule used for the state and start mode of the SQL Server Agent service.
+	SQLAgentServiceRule = "sql_agent_service"
+	// WSFCClusterRule used for the nodes and the quorum of the Windows Server Failover Cluster the
+	// machine is a node of.
+	WSFCClusterRule = "wsfc_cluster"
+	// MSSQLConfRule used for the settings of SQL Server on linux from mssql.conf.
+	MSSQLConfRule = "mssql_conf"
+	// HostUtilizationRule used for a sample of the cpu and memory utilization of the machine.
+	HostUtilizationRule = "host_utilization"
+	// ContainerRule used for the container SQL Server on linux runs in and its cgroup limits.
+	ContainerRule = "container"
+	// PowerPlanRecommendedField flags whether the power profile of the machine is recommended.
+	PowerPlanRecommendedField = "power_plan_recommended"
+)
+
+// SQL Server editions used to restrict master rules to the editions they apply to.
+const (
+	EditionEnterprise = "ENTERPRISE"
+	EditionStandard   = "STANDARD"
+	EditionWeb        = "WEB"
+	EditionExpress    = "EXPRESS"
+	EditionUnknown    = "unknown"
+)
+
+// Workload types of SQL Server instances used to skip the master rules that do not suit them:
+// non-default fill factors are deliberate on OLTP instances to limit page splits, and reading the
+// physical stats of every index competes with the large scans of OLAP instances and reporting
+// replicas.
+const (
+	WorkloadOLTP      = "oltp"
+	WorkloadOLAP      = "olap"
+	WorkloadReporting = "reporting"
 )
 
+// ValidWorkloadType reports whether w is one of the known workload types.
+func ValidWorkloadType(w string) bool {
+	return w == WorkloadOLTP || w == WorkloadOLAP || w == WorkloadReporting
+}
+
+// WorkloadTypeField is the field reporting the configured workload type of a SQL Server instance.
+const WorkloadTypeField = "workload_type"
+
+// LabelWorkload returns the details with the workload type added to the fields of every row. The
+// rows are copied so that the fields of the caller, e.g. cached results, are left unchanged.
+func LabelWorkload(details []Details, workloadType string) []Details {
+	labeled := make([]Details, 0, len(details))
+	for _, d := range details {
+		fields := make([]map[string]string, 0, len(d.Fields))
+		for _, row := range d.Fields {
+			r := make(map[string]string, len(row)+1)
+			for k, v := range row {
+				r[k] = v
+			}
+			r[WorkloadTypeField] = workloadType
+			fields = append(fields, r)
+		}
+		labeled = append(labeled, Details{Name: d.Name, Fields: fields})
+	}
+	return labeled
+}
+
+// engineEditions maps SERVERPROPERTY('EngineEdition') to the SQL Server edition.
+var engineEditions = map[string]string{
+	"2": EditionStandard,
+	"3": EditionEnterprise,
+	"4": EditionExpress,
+}
+
+// maxUserConnections is the maximum number of user connections SQL Server allows.
+const maxUserConnections = 32767
+
+// CollectionManifestName is the name of the details listing the master rules of a SQL collection
+// that ran, were skipped or failed.
+const CollectionManifestName = "COLLECTION_MANIFEST"
+
+// RuleSettings are the thresholds, time windows and options the master rules are collected with.
+// They are built from the configuration before each SQL collection.
+type RuleSettings struct {
+	// VLFCountThreshold is the number of virtual log files of a database above which its log is
+	// flagged by DB_VIRTUAL_LOG_FILE_COUNT.
+	VLFCountThreshold int64
+	// DeadlockWindow is the period before the collection in which deadlocks are counted by
+	// DB_DEADLOCK_COUNT.
+	DeadlockWindow time.Duration
+	// FailedLoginWindow is the period before the collection in which failed logins are counted by
+	// DB_LOGIN_AUDIT. Failed logins are only read for the last 24 hours.
+	FailedLoginWindow time.Duration
+	// IncrementalCollection runs per-database rules only for the databases that changed since the
+	// last collection, reporting the previous results of the other databases.
+	IncrementalCollection bool
+	// ReportCollectionManifest reports CollectionManifestName along with the master rules.
+	ReportCollectionManifest bool
+	// ClockSkewThreshold is the difference between the clocks of SQL Server and the agent host
+	// above which SQL Server is flagged by DB_CLOCK_SKEW.
+	ClockSkewThreshold time.Duration
+	// LockTimeout is the time queries wait for a lock before SQL Server aborts them with a lock
+	// timeout error. The configuration always sets a positive lock timeout, as values below one
+	// millisecond are replaced by the default; zero, which waits indefinitely, is only left by
+	// settings not built from a configuration.
+	LockTimeout time.Duration
+	// RuleCacheTTL is the time the results of cacheable rules are reused before the rules run
+	// again; zero disables the cache.
+	RuleCacheTTL time.Duration
+	// TopQueries is the number of queries reported in DB_TOP_QUERIES.
+	TopQueries int64
+	// TopQueriesMetric is the resource usage the queries of DB_TOP_QUERIES are ranked by, either
+	// "worker_time" or "logical_reads".
+	TopQueriesMetric string
+	// CollectIndexFillFactors reports whether DB_INDEX_FILL_FACTOR reads the indexes of the
+	// databases.
+	CollectIndexFillFactors bool
+	// IndexFillFactorMinPages is the size in pages below which indexes are left out of
+	// DB_INDEX_FILL_FACTOR.
+	IndexFillFactorMinPages int64
+	// CheckDBMaxAge is the age of the last successful DBCC CHECKDB above which DB_LAST_CHECKDB
+	// flags a database as overdue.
+	CheckDBMaxAge time.Duration
+	// DiskFreeSpaceThresholdPercent is the percentage of free space below which a volume hosting
+	// SQL Server data or log files is flagged by DB_SQL_VOLUME_FREE_SPACE.
+	DiskFreeSpaceThresholdPercent int64
+	// IgnoredWaitTypes are the wait types left out of DB_WAIT_STATS.
+	IgnoredWaitTypes map[string]bool
+	// IgnoredErrorNumbers are the SQL Server error numbers that are not reported as rule failures.
+	IgnoredErrorNumbers map[int32]bool
+	// ExpectTDEEncryption indicates whether databases are expected to use transparent data
+	// encryption. Unencrypted databases are flagged by DB_TDE_STATUS if it is set.
+	ExpectTDEEncryption bool
+	// AgentTime is the time of the agent host when the result of the query was returned. It is set
+	// by the collector for each rule and compared to the clock of SQL Server by DB_CLOCK_SKEW.
+	AgentTime time.Time
+}
+
+// DefaultRuleSettings returns the settings the master rules are collected with when they are not
+// configured.
+func DefaultRuleSettings() RuleSettings {
+	return RuleSettings{
+		VLFCountThreshold:             1000,
+		DeadlockWindow:                time.Hour,
+		FailedLoginWindow:             time.Hour,
+		ClockSkewThreshold:            5 * time.Second,
+		LockTimeout:                   5 * time.Second,
+		TopQueries:                    10,
+		TopQueriesMetric:              "worker_time",
+		IndexFillFactorMinPages:       1000,
+		CheckDBMaxAge:                 7 * 24 * time.Hour,
+		DiskFreeSpaceThresholdPercent: 10,
+		IgnoredWaitTypes:              stringSet(DefaultIgnoredWaitTypes),
+		IgnoredErrorNumbers:           map[int32]bool{},
+	}
+}
+
+// checkDBTimeLayout is the layout of the dates of DBCC DBINFO and of CONVERT style 121.
+const checkDBTimeLayout = "2006-01-02 15:04:05.000"
+
+// maxQueryTextLength bounds the length of the query texts of DB_TOP_QUERIES to keep the payload
+// small. It is passed to the query as @max_query_text_length.
+const maxQueryTextLength = 1000
+
+// maxDatabaseFiles is the number of the largest database files reported by DB_DATABASE_FILES.
+const maxDatabaseFiles = 500
+
+// maxWaitStats is the number of wait types with the longest wait time reported by DB_WAIT_STATS.
+const maxWaitStats = 10
+
+// DefaultIgnoredWaitTypes are idle and background wait types that are always left out of
+// DB_WAIT_STATS, along with the configured wait types.
+var DefaultIgnoredWaitTypes = []string{
+	"BROKER_EVENTHANDLER", "BROKER_RECEIVE_WAITFOR", "BROKER_TASK_STOP", "BROKER_TO_FLUSH",
+	"BROKER_TRANSMITTER", "CHECKPOINT_QUEUE", "CLR_AUTO_EVENT", "CLR_MANUAL_EVENT",
+	"DIRTY_PAGE_POLL", "DISPATCHER_QUEUE_SEMAPHORE", "FT_IFTS_SCHEDULER_IDLE_WAIT",
+	"HADR_FILESTREAM_IOMGR_IOCOMPLETION", "HADR_WORK_QUEUE", "LAZYWRITER_SLEEP", "LOGMGR_QUEUE",
+	"ONDEMAND_TASK_QUEUE", "QDS_ASYNC_QUEUE", "QDS_PERSIST_TASK_MAIN_LOOP_SLEEP",
+	"REQUEST_FOR_DEADLOCK_SEARCH", "SLEEP_SYSTEMTASK", "SLEEP_TASK", "SP_SERVER_DIAGNOSTICS_SLEEP",
+	"SQLTRACE_BUFFER_FLUSH", "SQLTRACE_INCREMENTAL_FLUSH_SLEEP", "WAITFOR", "XE_DISPATCHER_WAIT",
+	"XE_TIMER_EVENT",
+}
+
+// optionEnabled converts the value of an on/off option of sys.configurations to "true" or "false".
+func optionEnabled(data any) string {
+	switch v := HandleNilInt(data); v {
+	case "0":
+		return "false"
+	case "1":
+		return "true"
+	default:
+		return v
+	}
+}
+
+// sortedKeys returns the values of the set in order.
+func sortedKeys(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// stringSet returns the set of the values.
+func stringSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// SetIgnoreLists sets the ignored wait types and error numbers. The wait types are ignored in
+// addition to DefaultIgnoredWaitTypes.
+func (s *RuleSettings) SetIgnoreLists(waitTypes []string, errorNumbers []int32) {
+	s.IgnoredWaitTypes = stringSet(append(append([]string{}, DefaultIgnoredWaitTypes...), waitTypes...))
+	s.IgnoredErrorNumbers = map[int32]bool{}
+	for _, n := range errorNumbers {
+		s.IgnoredErrorNumbers[n] = true
+	}
+}
+
+// tdeEncryptionStates maps encryption_state of sys.dm_database_encryption_keys to its description.
+// Databases without a database encryption key are reported with encryption_state 0.
+var tdeEncryptionStates = map[string]string{
+	"0": "NO_DATABASE_ENCRYPTION_KEY",
+	"1": "UNENCRYPTED",
+	"2": "ENCRYPTION_IN_PROGRESS",
+	"3": "ENCRYPTED",
+	"4": "KEY_CHANGE_IN_PROGRESS",
+	"5": "DECRYPTION_IN_PROGRESS",
+	"6": "PROTECTION_CHANGE_IN_PROGRESS",
+}
+
+// loginAuditLevels maps the AuditLevel registry value of SQL Server to its description.
+var loginAuditLevels = map[string]string{
+	"0": "NONE",
+	"1": "SUCCESSFUL_LOGINS_ONLY",
+	"2": "FAILED_LOGINS_ONLY",
+	"3": "BOTH_FAILED_AND_SUCCESSFUL_LOGINS",
+}
+
 // Details represents collected details results.
 type Details struct {
 	Name   string
@@ -48,8 +300,171 @@ type MasterRuleStruct struct {
 	// Query is the sql query statement for the rule.
 	Query string
 	// Fields returns the <key, value> of collected columns and values. Different rules query
-	// different tables and columns.
-	Fields func([][]any) []map[string]string
+	// different tables and columns. Thresholds and time windows are read from the settings.
+	Fields func([][]any, RuleSettings) []map[string]string
+	// Args returns the named arguments of the query for the settings. It is nil for queries without
+	// arguments.
+	Args func(RuleSettings) []any
+	// CanRunOnSecondary reports whether the rule returns the same result on a readable
+	// secondary replica of an availability group as on the primary.
+	CanRunOnSecondary bool
+	// PreferSecondary routes the rule to a readable secondary replica when one is available.
+	// It is only honored when CanRunOnSecondary is also set.
+	PreferSecondary bool
+	// Editions lists the SQL Server editions the rule applies to. The rule applies to all
+	// editions if it is empty.
+	Editions []string
+	// MinMajorVersion is the earliest SQL Server major version the rule applies to, e.g. 13 for
+	// SQL Server 2016. The rule applies to all versions if it is zero.
+	MinMajorVersion int
+	// FiltersDatabases marks rules whose query only reads the databases listed in the xml
+	// parameter @databases, as <db>name</db> elements, unless it is NULL. The databases included
+	// and excluded by the credentials restrict these rules.
+	FiltersDatabases bool
+	// PerDatabase marks rules that filter databases like FiltersDatabases rules and report rows
+	// keyed by db_name. In incremental collections these rules only run for the databases that
+	// changed.
+	PerDatabase bool
+	// Cacheable marks expensive rules whose results rarely change. Their results are reused in
+	// the following collections until they are older than the rule cache ttl of the settings.
+	Cacheable bool
+	// Enabled reports whether the rule is enabled by the settings, e.g. expensive rules that are
+	// opt-in. Disabled rules are not run. The rule is always enabled if it is nil.
+	Enabled func(RuleSettings) bool
+	// SkipWorkloads lists the workload types the rule is skipped for, e.g. expensive rules that
+	// would compete with the workload of the instance.
+	SkipWorkloads []string
+	// PostProcessors references the registered post-processors applied, in order, to the fields
+	// returned by Fields.
+	PostProcessors []PostProcessorRef
+}
+
+// Results returns the final fields of the rule for the query result and the settings. It returns an
+// error if one of the post-processors of the rule is not registered or has invalid arguments.
+func (r MasterRuleStruct) Results(queryResult [][]any, settings RuleSettings) ([]map[string]string, error) {
+	fields := r.Fields(queryResult, settings)
+	for _, ref := range r.PostProcessors {
+		p, err := LookupPostProcessor(ref)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+		fields = p(fields)
+	}
+	return fields, nil
+}
+
+// AppliesTo reports whether the rule applies to the given SQL Server edition.
+// Rules are not skipped when the edition is unknown.
+func (r MasterRuleStruct) AppliesTo(edition string) bool {
+	if len(r.Editions) == 0 || edition == EditionUnknown {
+		return true
+	}
+	for _, e := range r.Editions {
+		if e == edition {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesToVersion reports whether the rule applies to the given SQL Server major version.
+// Rules are not skipped when the version is unknown, which is zero.
+func (r MasterRuleStruct) AppliesToVersion(major int) bool {
+	return major == 0 || major >= r.MinMajorVersion
+}
+
+// ReadsDatabases reports whether the query of the rule only reads the databases listed in the
+// parameter @databases.
+func (r MasterRuleStruct) ReadsDatabases() bool {
+	return r.FiltersDatabases || r.PerDatabase
+}
+
+// AppliesToWorkload reports whether the rule applies to the given workload type. Rules apply to
+// all workloads if the workload type is not set.
+func (r MasterRuleStruct) AppliesToWorkload(workloadType string) bool {
+	for _, w := range r.SkipWorkloads {
+		if w == workloadType {
+			return false
+		}
+	}
+	return true
+}
+
+// EditionQuery returns the engine edition, the edition name and the major version of the target
+// sql server.
+const EditionQuery = `SELECT CAST(SERVERPROPERTY('EngineEdition') AS int), CAST(SERVERPROPERTY('Edition') AS nvarchar(128)),
+						CAST(PARSENAME(CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128)), 4) AS int)`
+
+// MajorVersion returns the SQL Server major version from the result of EditionQuery, e.g. 16 for
+// SQL Server 2022. It returns zero if the version is unknown.
+func MajorVersion(fields [][]any) int {
+	if len(fields) == 0 || len(fields[0]) < 3 {
+		return 0
+	}
+	major, err := strconv.Atoi(HandleNilInt(fields[0][2]))
+	if err != nil {
+		return 0
+	}
+	return major
+}
+
+// Edition returns the SQL Server edition from the result of EditionQuery.
+// Web edition reports the same engine edition as Standard edition and is told apart by its name.
+// Developer and Evaluation editions report the engine edition of Enterprise edition.
+func Edition(fields [][]any) string {
+	if len(fields) == 0 || len(fields[0]) < 2 {
+		return EditionUnknown
+	}
+	engineEdition := HandleNilInt(fields[0][0])
+	if engineEdition == "2" && strings.HasPrefix(HandleNilString(fields[0][1]), "Web") {
+		return EditionWeb
+	}
+	if edition, ok := engineEditions[engineEdition]; ok {
+		return edition
+	}
+	return EditionUnknown
+}
+
+// systemDatabases lists the system databases left out of the rules reading the user databases.
+const systemDatabases = `('master', 'tempdb', 'model', 'msdb')`
+
+// listedDatabases returns the condition restricting the database names of the column to the
+// databases listed in the xml parameter @databases, as <db>name</db> elements, unless it is NULL.
+func listedDatabases(column string) string {
+	return `(@databases IS NULL OR ` + column + ` IN (SELECT x.db.value('.', 'sysname')
+		FROM (SELECT CAST(@databases AS xml) AS doc) AS s CROSS APPLY s.doc.nodes('/db') AS x(db)))`
+}
+
+// userDatabases returns the condition restricting the database names of the column to the user
+// databases listed in @databases.
+func userDatabases(column string) string {
+	return column + ` NOT IN ` + systemDatabases + ` AND ` + listedDatabases(column)
+}
+
+// quoted returns the statements escaped to be quoted in a string literal of a query, e.g. the
+// argument of EXEC.
+func quoted(statements string) string {
+	return strings.ReplaceAll(statements, "'", "''")
+}
+
+// forEachDatabase returns the statements running the body for each database named by the query,
+// in order, with the name of the database in @db. Databases that are not online or that the login
+// cannot access are skipped; the body handles the errors of the other databases.
+func forEachDatabase(databases, body string) string {
+	return `DECLARE @db sysname;
+		DECLARE db_cursor CURSOR LOCAL FAST_FORWARD FOR
+			SELECT name FROM (` + databases + `) AS dbs(name)
+			WHERE DATABASEPROPERTYEX(name, 'Status') = 'ONLINE' AND HAS_DBACCESS(name) = 1
+			ORDER BY name;
+		OPEN db_cursor;
+		FETCH NEXT FROM db_cursor INTO @db;
+		WHILE @@FETCH_STATUS = 0
+		BEGIN
+			` + body + `
+			FETCH NEXT FROM db_cursor INTO @db;
+		END
+		CLOSE db_cursor;
+		DEALLOCATE db_cursor;`
 }
 
 // MasterRules defines the rules the agent will collect from sql server.
@@ -59,7 +474,7 @@ var MasterRules = []MasterRuleStruct{
 		Query: `SELECT type, d.name, physical_name, m.state, size, growth, is_percent_growth
 						FROM sys.master_files m
 						JOIN sys.databases d ON m.database_id = d.database_id`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -81,7 +496,7 @@ var MasterRules = []MasterRuleStruct{
 		Query: `SELECT value_in_use as maxDegreeOfParallelism
 						FROM sys.configurations
 						WHERE name = 'max degree of parallelism'`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -98,7 +513,7 @@ var MasterRules = []MasterRuleStruct{
 						FROM master.sys.sysdatabases d
 								LEFT JOIN msdb.dbo.backupset b ON b.database_name = d.name AND b.type = 'L'
 								LEFT JOIN sys.master_files m ON d.dbid = m.database_id AND m.type = 1
-						WHERE d.name NOT IN ('master', 'tempdb', 'model', 'msdb')
+						WHERE ` + userDatabases("d.name") + `
 						GROUP BY d.name
 						)
 					SELECT cte.name,
@@ -115,7 +530,7 @@ var MasterRules = []MasterRuleStruct{
 					LEFT JOIN msdb.dbo.backupset b
 					ON b.database_name = cte.name
 					AND b.backup_finish_date = cte.backup_finish_date`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -128,35 +543,50 @@ var MasterRules = []MasterRuleStruct{
 			}
 			return res
 		},
+		FiltersDatabases: true,
 	},
 	{
 		Name: "DB_VIRTUAL_LOG_FILE_COUNT",
-		Query: `SELECT [name], COUNT(l.database_id) AS 'VLFCount', SUM(vlf_size_mb) AS 'VLFSizeInMB',
-								SUM(CAST(vlf_active AS INT)) AS 'ActiveVLFCount',
-								SUM(vlf_active*vlf_size_mb) AS 'ActiveVLFSizeInMB'
-						FROM sys.databases s
-						CROSS APPLY sys.dm_db_log_info(s.database_id) l
-						WHERE [name] NOT IN ('master', 'tempdb', 'model', 'msdb')
-						GROUP BY [name]`,
-		Fields: func(fields [][]any) []map[string]string {
+		// sys.dm_db_log_info is only available in SQL Server 2016 SP2 and later.
+		// The VLF counts are reported as unknown for earlier versions.
+		Query: `IF OBJECT_ID('sys.dm_db_log_info') IS NOT NULL
+							EXEC sp_executesql N'SELECT [name], COUNT(l.database_id) AS VLFCount, SUM(vlf_size_mb) AS VLFSizeInMB,
+									SUM(CAST(vlf_active AS INT)) AS ActiveVLFCount,
+									SUM(vlf_active*vlf_size_mb) AS ActiveVLFSizeInMB
+								FROM sys.databases s
+								CROSS APPLY sys.dm_db_log_info(s.database_id) l
+								WHERE ` + quoted(userDatabases("[name]")) + `
+								GROUP BY [name]', N'@databases nvarchar(max)', @databases = @databases
+						ELSE
+							SELECT [name], NULL AS VLFCount, NULL AS VLFSizeInMB, NULL AS ActiveVLFCount, NULL AS ActiveVLFSizeInMB
+							FROM sys.databases
+							WHERE ` + userDatabases("[name]"),
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
+				vlfCount := HandleNilInt(f[1])
+				exceedsThreshold := "unknown"
+				if count, err := strconv.ParseInt(vlfCount, 10, 64); err == nil {
+					exceedsThreshold = strconv.FormatBool(count > settings.VLFCountThreshold)
+				}
 				res = append(res, map[string]string{
 					"db_name":               HandleNilString(f[0]),
-					"vlf_count":             HandleNilInt(f[1]),
+					"vlf_count":             vlfCount,
 					"vlf_size_in_mb":        HandleNilFloat64(f[2]),
 					"active_vlf_count":      HandleNilInt(f[3]),
 					"active_vlf_size_in_mb": HandleNilFloat64(f[4]),
+					"exceeds_threshold":     exceedsThreshold,
 				})
 			}
 			return res
 		},
+		FiltersDatabases: true,
 	},
 	{
 		Name: "DB_BUFFER_POOL_EXTENSION",
 		Query: `SELECT path, state, current_size_in_kb
 						FROM sys.dm_os_buffer_pool_extension_configuration`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -167,13 +597,16 @@ var MasterRules = []MasterRuleStruct{
 			}
 			return res
 		},
+		// Buffer pool extension is only available in Enterprise and Standard editions.
+		Editions:  []string{EditionEnterprise, EditionStandard},
+		Cacheable: true,
 	},
 	{
 		Name: "DB_MAX_SERVER_MEMORY",
 		Query: `SELECT [name], [value], [value_in_use]
 						FROM sys.configurations
 						WHERE [name] = 'max server memory (MB)';`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -191,7 +624,7 @@ var MasterRules = []MasterRuleStruct{
 						FROM sys.databases d
 							CROSS APPLY sys.dm_db_index_physical_stats (d.database_id, NULL, NULL, NULL, NULL) AS DDIPS
 						WHERE ddips.avg_fragmentation_in_percent > 95
-							AND d.name NOT IN ('master', 'model', 'msdb', 'tempdb')
+							AND ` + userDatabases("d.name") + `
 							And d.name NOT IN (
 								SELECT DISTINCT dbcs.database_name AS [DatabaseName]
 								FROM master.sys.availability_groups AS AG
@@ -199,7 +632,7 @@ var MasterRules = []MasterRuleStruct{
 									INNER JOIN master.sys.dm_hadr_availability_replica_states AS arstates ON AR.replica_id = arstates.replica_id AND arstates.is_local = 1
 									INNER JOIN master.sys.dm_hadr_database_replica_cluster_states AS dbcs ON arstates.replica_id = dbcs.replica_id
 								WHERE ISNULL(arstates.role, 3) = 2 AND ISNULL(dbcs.is_database_joined, 0) = 1)`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -208,13 +641,17 @@ var MasterRules = []MasterRuleStruct{
 			}
 			return res
 		},
+		// The databases of a secondary replica are left out, so the rule does not return the same
+		// result on a secondary replica as on the primary.
+		FiltersDatabases: true,
+		SkipWorkloads:    []string{WorkloadOLAP, WorkloadReporting},
 	},
 	{
 		Name: "DB_TABLE_INDEX_COMPRESSION",
 		Query: `SELECT COUNT(*) numOfPartitionsWithCompressionEnabled
 						FROM sys.partitions p
 						WHERE data_compression <> 0 and rows > 0`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -223,22 +660,30 @@ var MasterRules = []MasterRuleStruct{
 			}
 			return res
 		},
+		Cacheable: true,
 	},
 	{
 		Name: "INSTANCE_METRICS",
-		Query: `SELECT
-							SERVERPROPERTY('productversion') AS productversion,
-							SERVERPROPERTY ('productlevel') AS productlevel,
-							SERVERPROPERTY ('edition') AS edition,
+		// socket_count, cores_per_socket and numa_node_count of sys.dm_os_sys_info are only available
+		// in SQL Server 2016 SP2 and later, and are reported as unknown on earlier versions.
+		Query: `DECLARE @topology nvarchar(max) = CASE
+							WHEN EXISTS (SELECT 1 FROM sys.all_columns
+								WHERE object_id = OBJECT_ID('sys.dm_os_sys_info') AND name = 'socket_count')
+							THEN N'socket_count, cores_per_socket, numa_node_count'
+							ELSE N'NULL, NULL, NULL'
+						END;
+						DECLARE @sql nvarchar(max) = N'SELECT
+							SERVERPROPERTY(''productversion'') AS productversion,
+							SERVERPROPERTY (''productlevel'') AS productlevel,
+							SERVERPROPERTY (''edition'') AS edition,
 							cpu_count AS cpuCount,
 							hyperthread_ratio AS hyperthreadRatio,
 							physical_memory_kb AS physicalMemoryKb,
 							virtual_memory_kb AS virtualMemoryKb,
-							socket_count AS socketCount,
-							cores_per_socket AS coresPerSocket,
-							numa_node_count AS numaNodeCount
-						FROM sys.dm_os_sys_info`,
-		Fields: func(fields [][]any) []map[string]string {
+							' + @topology + N'
+						FROM sys.dm_os_sys_info';
+						EXEC sp_executesql @sql`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -271,7 +716,7 @@ var MasterRules = []MasterRuleStruct{
 									LEFT JOIN msdb.dbo.backupset
 									ON master.sys.sysdatabases.name = msdb.dbo.backupset.database_name
 							WHERE
-									master.sys.sysdatabases.name NOT IN ('master', 'model', 'msdb', 'tempdb' )
+									` + userDatabases("master.sys.sysdatabases.name") + `
 							GROUP BY
 									master.sys.sysdatabases.name
 							HAVING
@@ -281,7 +726,7 @@ var MasterRules = []MasterRuleStruct{
 					SELECT
 							MAX(backup_age) as maxBackupAge
 					FROM cte`,
-		Fields: func(fields [][]any) []map[string]string {
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
 			res := []map[string]string{}
 			for _, f := range fields {
 				res = append(res, map[string]string{
@@ -290,5 +735,1744 @@ var MasterRules = []MasterRuleStruct{
 			}
 			return res
 		},
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_PATCH_LEVEL",
+		Query: `SELECT
+							SERVERPROPERTY('ProductVersion') AS productVersion,
+							SERVERPROPERTY('ProductLevel') AS productLevel,
+							SERVERPROPERTY('ProductUpdateLevel') AS productUpdateLevel,
+							SERVERPROPERTY('ProductUpdateReference') AS productUpdateReference`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"product_version":          HandleNilString(f[0]),
+					"product_level":            HandleNilString(f[1]),
+					"product_update_level":     HandleNilString(f[2]),
+					"product_update_reference": HandleNilString(f[3]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_DEADLOCK_COUNT",
+		// Deadlocks are read from the ring buffer of the system_health extended events session.
+		// No row is returned if the session is not running.
+		Query: `SELECT CAST(t.target_data AS NVARCHAR(MAX)) AS target_data, SYSUTCDATETIME() AS now
+							FROM sys.dm_xe_session_targets t
+							JOIN sys.dm_xe_sessions s ON s.address = t.event_session_address
+							WHERE s.name = 'system_health' AND t.target_name = 'ring_buffer'`,
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			res := map[string]string{
+				"deadlock_count":        "unknown",
+				"latest_deadlock_graph": "unknown",
+			}
+			if len(fields) == 0 {
+				return []map[string]string{res}
+			}
+			targetData, ok := fields[0][0].(string)
+			if !ok {
+				return []map[string]string{res}
+			}
+			now, ok := fields[0][1].(time.Time)
+			if !ok {
+				return []map[string]string{res}
+			}
+			count, latest, err := deadlocks(targetData, now.Add(-settings.DeadlockWindow))
+			if err != nil {
+				return []map[string]string{res}
+			}
+			res["deadlock_count"] = strconv.Itoa(count)
+			res["latest_deadlock_graph"] = latest
+			return []map[string]string{res}
+		},
+	},
+	{
+		Name: "DB_LOCK_PAGES_IN_MEMORY",
+		// sql_memory_model_desc is only available in SQL Server 2016 SP1 and later.
+		// The memory model is reported as unknown for earlier versions.
+		Query: `IF COL_LENGTH('sys.dm_os_sys_info', 'sql_memory_model_desc') IS NOT NULL
+							EXEC('SELECT sql_memory_model_desc FROM sys.dm_os_sys_info')
+						ELSE
+							SELECT NULL AS sql_memory_model_desc`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				memoryModel := HandleNilString(f[0])
+				lockPagesInMemory := "unknown"
+				switch memoryModel {
+				// Large pages also require the lock pages in memory privilege.
+				case "LOCK_PAGES", "LARGE_PAGES":
+					lockPagesInMemory = "true"
+				case "CONVENTIONAL":
+					lockPagesInMemory = "false"
+				}
+				res = append(res, map[string]string{
+					"memory_model":         memoryModel,
+					"lock_pages_in_memory": lockPagesInMemory,
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_TDE_STATUS",
+		// sys.dm_database_encryption_keys requires VIEW SERVER STATE.
+		// Only is_encrypted from sys.databases is reported without the permission.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							EXEC sp_executesql N'SELECT d.name, d.is_encrypted, ISNULL(k.encryption_state, 0), k.key_algorithm, k.key_length
+								FROM sys.databases d
+								LEFT JOIN sys.dm_database_encryption_keys k ON d.database_id = k.database_id
+								WHERE ` + quoted(userDatabases("d.name")) + `', N'@databases nvarchar(max)', @databases = @databases
+						ELSE
+							SELECT d.name, d.is_encrypted, NULL AS encryption_state, NULL AS key_algorithm, NULL AS key_length
+							FROM sys.databases d
+							WHERE ` + userDatabases("d.name"),
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				isEncrypted := HandleNilBool(f[1])
+				encryptionState := HandleNilInt(f[2])
+				if desc, ok := tdeEncryptionStates[encryptionState]; ok {
+					encryptionState = desc
+				}
+				violatesPolicy := "unknown"
+				if isEncrypted != "unknown" {
+					violatesPolicy = strconv.FormatBool(settings.ExpectTDEEncryption && isEncrypted == "false")
+				}
+				res = append(res, map[string]string{
+					"db_name":             HandleNilString(f[0]),
+					"is_encrypted":        isEncrypted,
+					"encryption_state":    encryptionState,
+					"key_algorithm":       HandleNilString(f[3]),
+					"key_length":          HandleNilInt(f[4]),
+					"violates_tde_policy": violatesPolicy,
+				})
+			}
+			return res
+		},
+		// Transparent data encryption is not available in Web and Express editions.
+		Editions:         []string{EditionEnterprise, EditionStandard},
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_OWNER_AND_ORPHANED_USERS",
+		// Orphaned users are SQL users authenticated by a login that does not exist on the instance.
+		// The orphaned user count is reported as unknown for databases that cannot be read.
+		Query: `SET NOCOUNT ON;
+						DECLARE @owners TABLE (db_name sysname PRIMARY KEY, owner_name sysname NULL, owner_exists bit,
+							owner_is_disabled bit NULL, owner_is_expired bit NULL, orphaned_users int NULL);
+						INSERT INTO @owners (db_name, owner_name, owner_exists, owner_is_disabled, owner_is_expired)
+							SELECT d.name, SUSER_SNAME(d.owner_sid), CASE WHEN sp.sid IS NULL THEN 0 ELSE 1 END,
+								sp.is_disabled, CAST(LOGINPROPERTY(sp.name, 'IsExpired') AS bit)
+							FROM sys.databases d
+							LEFT JOIN sys.server_principals sp ON sp.sid = d.owner_sid
+							WHERE ` + userDatabases("d.name") + `;
+						DECLARE @count int, @sql nvarchar(max);
+						` + forEachDatabase(`SELECT db_name FROM @owners`, `BEGIN TRY
+								SET @sql = N'SELECT @count = COUNT(*) FROM ' + QUOTENAME(@db) + N'.sys.database_principals dp
+									LEFT JOIN sys.server_principals sp ON dp.sid = sp.sid
+									WHERE dp.type = ''S'' AND dp.authentication_type = 1 AND dp.principal_id > 4 AND sp.sid IS NULL';
+								EXEC sp_executesql @sql, N'@count int OUTPUT', @count = @count OUTPUT;
+								UPDATE @owners SET orphaned_users = @count WHERE db_name = @db;
+							END TRY
+							BEGIN CATCH
+							END CATCH`) + `
+						SELECT db_name, owner_name, owner_exists, owner_is_disabled, owner_is_expired, orphaned_users
+						FROM @owners`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"db_name":              HandleNilString(f[0]),
+					"owner_name":           HandleNilString(f[1]),
+					"owner_exists":         HandleNilBool(f[2]),
+					"owner_is_disabled":    HandleNilBool(f[3]),
+					"owner_is_expired":     HandleNilBool(f[4]),
+					"orphaned_users_count": HandleNilInt(f[5]),
+				})
+			}
+			return res
+		},
+		// Not a per-database rule: logins dropped or disabled on the server change the rows of
+		// databases that did not change.
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_LOGIN_AUDIT",
+		// The audit level is read from the registry and failed logins (event class 20) from the
+		// default trace, bucketed by the minutes passed since the failure. Reading the registry and
+		// the default trace require elevated permissions; the values are reported as unknown without.
+		// A single row with NULL buckets is returned if there are no failed logins.
+		Query: `SET NOCOUNT ON;
+						DECLARE @audit_level int = NULL, @trace_readable bit = 0, @trace_path nvarchar(260), @sep nchar(1);
+						DECLARE @buckets TABLE (minutes_ago int, failed_logins int);
+						BEGIN TRY
+							EXEC master.dbo.xp_instance_regread N'HKEY_LOCAL_MACHINE',
+								N'Software\Microsoft\MSSQLServer\MSSQLServer', N'AuditLevel', @audit_level OUTPUT;
+						END TRY
+						BEGIN CATCH
+						END CATCH
+						BEGIN TRY
+							SELECT @trace_path = path FROM sys.traces WHERE is_default = 1;
+							IF @trace_path IS NOT NULL
+							BEGIN
+								-- log.trc reads all rollover files of the default trace.
+								SET @sep = CASE WHEN CHARINDEX('/', @trace_path) > 0 THEN '/' ELSE '\' END;
+								SET @trace_path = LEFT(@trace_path, LEN(@trace_path) - CHARINDEX(@sep, REVERSE(@trace_path))) + @sep + 'log.trc';
+								INSERT INTO @buckets
+									SELECT DATEDIFF(minute, StartTime, GETDATE()), COUNT(*)
+									FROM sys.fn_trace_gettable(@trace_path, DEFAULT)
+									WHERE EventClass = 20 AND StartTime > DATEADD(hour, -24, GETDATE())
+									GROUP BY DATEDIFF(minute, StartTime, GETDATE());
+								SET @trace_readable = 1;
+							END
+						END TRY
+						BEGIN CATCH
+						END CATCH
+						SELECT @audit_level AS audit_level, @trace_readable AS trace_readable, b.minutes_ago, b.failed_logins
+						FROM (SELECT 1 AS one) x
+						LEFT JOIN @buckets b ON 1 = 1`,
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			res := map[string]string{
+				"audit_level":          "unknown",
+				"audits_failed_logins": "unknown",
+				"failed_login_count":   "unknown",
+			}
+			if len(fields) == 0 {
+				return []map[string]string{res}
+			}
+			if level, ok := loginAuditLevels[HandleNilInt(fields[0][0])]; ok {
+				res["audit_level"] = level
+				res["audits_failed_logins"] = strconv.FormatBool(level == "FAILED_LOGINS_ONLY" || level == "BOTH_FAILED_AND_SUCCESSFUL_LOGINS")
+			}
+			if HandleNilBool(fields[0][1]) != "true" {
+				return []map[string]string{res}
+			}
+			count := int64(0)
+			for _, f := range fields {
+				minutesAgo, err := strconv.ParseInt(HandleNilInt(f[2]), 10, 64)
+				if err != nil || time.Duration(minutesAgo)*time.Minute >= settings.FailedLoginWindow {
+					continue
+				}
+				failedLogins, err := strconv.ParseInt(HandleNilInt(f[3]), 10, 64)
+				if err != nil {
+					continue
+				}
+				count += failedLogins
+			}
+			res["failed_login_count"] = strconv.FormatInt(count, 10)
+			return []map[string]string{res}
+		},
+	},
+	{
+		Name: "DB_USER_CONNECTIONS",
+		// Sessions of other logins are only visible with VIEW SERVER STATE.
+		Query: `SELECT s.program_name,
+							COUNT(*) AS sessions,
+							SUM(CASE WHEN s.status = 'running' THEN 1 ELSE 0 END) AS active_sessions,
+							(SELECT COUNT(*) FROM sys.dm_exec_connections) AS total_connections,
+							(SELECT CAST(value_in_use AS int) FROM sys.configurations WHERE name = 'user connections') AS max_user_connections
+						FROM sys.dm_exec_sessions s
+						WHERE s.is_user_process = 1
+						GROUP BY s.program_name`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				maxConnections := HandleNilInt(f[4])
+				// A max user connections value of 0 allows the maximum number of connections.
+				if maxConnections == "0" {
+					maxConnections = strconv.Itoa(maxUserConnections)
+				}
+				res = append(res, map[string]string{
+					"program_name":         HandleNilString(f[0]),
+					"sessions":             HandleNilInt(f[1]),
+					"active_sessions":      HandleNilInt(f[2]),
+					"total_connections":    HandleNilInt(f[3]),
+					"max_user_connections": maxConnections,
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_QUERY_STORE",
+		// Query store was introduced in SQL Server 2016 (major version 13); the state of every
+		// database is reported as not_supported on earlier versions. The state is reported as
+		// unknown for databases that cannot be read. Databases excluded by the credentials are left
+		// out like in the other per-database rules.
+		Query: `SET NOCOUNT ON;
+						DECLARE @query_store TABLE (db_name sysname PRIMARY KEY, actual_state_desc nvarchar(60) NULL);
+						DECLARE @supported bit = CASE WHEN CAST(PARSENAME(CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128)), 4) AS int) >= 13 THEN 1 ELSE 0 END;
+						INSERT INTO @query_store (db_name)
+							SELECT d.name FROM sys.databases d
+							WHERE ` + userDatabases("d.name") + `;
+						IF @supported = 0
+							UPDATE @query_store SET actual_state_desc = 'not_supported';
+						DECLARE @state nvarchar(60), @sql nvarchar(max);
+						` + forEachDatabase(`SELECT db_name FROM @query_store WHERE @supported = 1`, `BEGIN TRY
+								SET @state = NULL;
+								SET @sql = N'SELECT @state = actual_state_desc FROM ' + QUOTENAME(@db) + N'.sys.database_query_store_options';
+								EXEC sp_executesql @sql, N'@state nvarchar(60) OUTPUT', @state = @state OUTPUT;
+								UPDATE @query_store SET actual_state_desc = @state WHERE db_name = @db;
+							END TRY
+							BEGIN CATCH
+							END CATCH`) + `
+						SELECT db_name, actual_state_desc FROM @query_store`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				state := HandleNilString(f[1])
+				queryStoreOff := "false"
+				switch state {
+				case "OFF":
+					queryStoreOff = "true"
+				case "unknown":
+					queryStoreOff = "unknown"
+				}
+				res = append(res, map[string]string{
+					"db_name":         HandleNilString(f[0]),
+					"actual_state":    state,
+					"query_store_off": queryStoreOff,
+				})
+			}
+			return res
+		},
+		PerDatabase: true,
+	},
+	{
+		Name: "DB_WAIT_STATS",
+		// Wait statistics are cumulative since the last restart of SQL Server or the last time they
+		// were cleared. Ignored wait types are left out before the longest waits are taken.
+		Query: `SELECT wait_type, waiting_tasks_count, wait_time_ms, signal_wait_time_ms
+						FROM sys.dm_os_wait_stats
+						WHERE wait_time_ms > 0
+						ORDER BY wait_time_ms DESC`,
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				waitType := HandleNilString(f[0])
+				if settings.IgnoredWaitTypes[waitType] {
+					continue
+				}
+				if len(res) == maxWaitStats {
+					break
+				}
+				res = append(res, map[string]string{
+					"wait_type":           waitType,
+					"waiting_tasks_count": HandleNilInt(f[1]),
+					"wait_time_ms":        HandleNilInt(f[2]),
+					"signal_wait_time_ms": HandleNilInt(f[3]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_CLOCK_SKEW",
+		// The clock of the agent host is read when the result is returned, so the skew includes the
+		// duration of the query. It is negligible compared to the threshold. The agent time is unknown
+		// if the collector did not set it.
+		Query: `SELECT SYSUTCDATETIME() AS server_utc_time`,
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			agentTime := settings.AgentTime.UTC()
+			res := map[string]string{
+				"server_utc_time":    "unknown",
+				"agent_utc_time":     "unknown",
+				"skew_seconds":       "unknown",
+				"is_above_threshold": "unknown",
+			}
+			if settings.AgentTime.IsZero() {
+				return []map[string]string{res}
+			}
+			res["agent_utc_time"] = agentTime.Format(time.RFC3339Nano)
+			if len(fields) == 0 {
+				return []map[string]string{res}
+			}
+			serverTime, ok := fields[0][0].(time.Time)
+			if !ok {
+				return []map[string]string{res}
+			}
+			skew := serverTime.Sub(agentTime)
+			aboveThreshold := skew > settings.ClockSkewThreshold || skew < -settings.ClockSkewThreshold
+			if aboveThreshold {
+				log.Logger.Warnw("The clock of SQL Server differs from the clock of the agent host, time windowed rules may be misaligned", "skew", skew, "threshold", settings.ClockSkewThreshold)
+			}
+			res["server_utc_time"] = serverTime.UTC().Format(time.RFC3339Nano)
+			res["skew_seconds"] = strconv.FormatFloat(skew.Seconds(), 'f', 3, 64)
+			res["is_above_threshold"] = strconv.FormatBool(aboveThreshold)
+			return []map[string]string{res}
+		},
+	},
+	{
+		Name: "DB_LICENSED_CORES",
+		// Schedulers of the cores SQL Server may not use under the core limit of its edition are
+		// VISIBLE OFFLINE. sys.dm_os_schedulers and sys.dm_os_sys_info require VIEW SERVER STATE;
+		// only the edition is reported without the permission.
+		// The vCPU count of the VM is added from the guest data after the collection.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							EXEC('SELECT SERVERPROPERTY(''Edition''), i.cpu_count, s.visible_online, s.visible_offline
+								FROM sys.dm_os_sys_info i
+								CROSS JOIN (SELECT SUM(CASE WHEN status = ''VISIBLE ONLINE'' THEN 1 ELSE 0 END) AS visible_online,
+										SUM(CASE WHEN status = ''VISIBLE OFFLINE'' THEN 1 ELSE 0 END) AS visible_offline
+									FROM sys.dm_os_schedulers) s')
+						ELSE
+							SELECT SERVERPROPERTY('Edition'), NULL AS cpu_count, NULL AS visible_online, NULL AS visible_offline`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				visibleOffline := HandleNilInt(f[3])
+				limitedByLicense := "unknown"
+				if count, err := strconv.ParseInt(visibleOffline, 10, 64); err == nil {
+					limitedByLicense = strconv.FormatBool(count > 0)
+				}
+				res = append(res, map[string]string{
+					"edition":                    HandleNilString(f[0]),
+					"cpu_count":                  HandleNilInt(f[1]),
+					"visible_online_schedulers":  HandleNilInt(f[2]),
+					"visible_offline_schedulers": visibleOffline,
+					"sql_visible_cores":          HandleNilInt(f[2]),
+					"limited_by_license":         limitedByLicense,
+					"vm_vcpu_count":              "unknown",
+					"uses_all_vcpus":             "unknown",
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_DATABASE_FILES",
+		// The used space is read with FILEPROPERTY in the context of each database and is reported as
+		// unknown for databases that cannot be read. Sizes are in KB; a max_size of -1 means the
+		// file grows until the disk is full. used_percent is the used space in percent of the size
+		// and is_nearly_full flags files more than 90 percent used.
+		Query: `SET NOCOUNT ON;
+						DECLARE @files TABLE (db_name sysname, file_name sysname, type_desc nvarchar(60), physical_name nvarchar(260),
+							size_kb bigint, used_kb bigint NULL, max_size_kb bigint, growth bigint, is_percent_growth bit);
+						INSERT INTO @files (db_name, file_name, type_desc, physical_name, size_kb, max_size_kb, growth, is_percent_growth)
+							SELECT d.name, m.name, m.type_desc, m.physical_name, CAST(m.size AS bigint) * 8,
+								CASE WHEN m.max_size = -1 THEN -1 ELSE CAST(m.max_size AS bigint) * 8 END, m.growth, m.is_percent_growth
+							FROM sys.master_files m
+							JOIN sys.databases d ON d.database_id = m.database_id
+							WHERE m.type IN (0, 1) AND ` + userDatabases("d.name") + `;
+						DECLARE @used TABLE (file_name sysname, used_kb bigint NULL);
+						DECLARE @sql nvarchar(max);
+						` + forEachDatabase(`SELECT DISTINCT db_name FROM @files`, `BEGIN TRY
+								DELETE FROM @used;
+								SET @sql = N'USE ' + QUOTENAME(@db) + N'; SELECT name, CAST(FILEPROPERTY(name, ''SpaceUsed'') AS bigint) * 8
+									FROM sys.database_files WHERE type IN (0, 1)';
+								INSERT INTO @used EXEC sp_executesql @sql;
+								UPDATE f SET used_kb = u.used_kb FROM @files f JOIN @used u ON u.file_name = f.file_name WHERE f.db_name = @db;
+							END TRY
+							BEGIN CATCH
+							END CATCH`) + `
+						SELECT db_name, file_name, type_desc, physical_name, size_kb, used_kb, max_size_kb, growth, is_percent_growth
+						FROM @files
+						ORDER BY size_kb DESC, db_name, file_name`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				if len(res) == maxDatabaseFiles {
+					log.Logger.Warnw("Only the largest database files are reported", "files", len(fields), "reported", maxDatabaseFiles)
+					break
+				}
+				growthType, growthKB, growthPercent := "unknown", "unknown", "unknown"
+				growth, okGrowth := f[7].(int64)
+				isPercent, okPercent := f[8].(bool)
+				if okGrowth && okPercent {
+					switch {
+					case growth == 0:
+						growthType, growthKB, growthPercent = "disabled", "0", "0"
+					case isPercent:
+						growthType, growthKB, growthPercent = "percent", "0", strconv.FormatInt(growth, 10)
+					default:
+						growthType, growthKB, growthPercent = "fixed", strconv.FormatInt(growth*8, 10), "0"
+					}
+				}
+				maxSizeKB := HandleNilInt(f[6])
+				if maxSizeKB == "-1" {
+					maxSizeKB = "unlimited"
+				}
+				res = append(res, map[string]string{
+					"db_name":           HandleNilString(f[0]),
+					"file_name":         HandleNilString(f[1]),
+					"type":              HandleNilString(f[2]),
+					"physical_name":     HandleNilString(f[3]),
+					"size_kb":           HandleNilInt(f[4]),
+					"used_kb":           HandleNilInt(f[5]),
+					"max_size_kb":       maxSizeKB,
+					"growth_type":       growthType,
+					"growth_kb":         growthKB,
+					"growth_percent":    growthPercent,
+					"is_percent_growth": HandleNilBool(f[8]),
+				})
+			}
+			return res
+		},
+		PostProcessors: []PostProcessorRef{
+			{Name: "ratio", Args: []string{"used_kb", "size_kb", "used_percent"}},
+			{Name: "threshold", Args: []string{"used_percent", "is_nearly_full", "90"}},
+		},
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_OPTIMIZE_FOR_AD_HOC_WORKLOADS",
+		// Single-use ad hoc plans bloat the plan cache unless optimize for ad hoc workloads is enabled.
+		// The size of the plan cache and the share of single-use ad hoc plans, by count and by size,
+		// are reported along with the setting. sys.dm_exec_cached_plans requires VIEW SERVER STATE;
+		// only the setting is reported without the permission.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							EXEC('SELECT c.value_in_use, p.single_use_plans, p.single_use_plans_bytes / 1024, p.cached_plans,
+									p.cached_plans_bytes / 1024,
+									ISNULL(CAST(100.0 * p.single_use_plans / NULLIF(p.cached_plans, 0) AS float), 0),
+									ISNULL(CAST(100.0 * p.single_use_plans_bytes / NULLIF(p.cached_plans_bytes, 0) AS float), 0)
+								FROM sys.configurations c
+								CROSS JOIN (SELECT ISNULL(SUM(CASE WHEN objtype = ''Adhoc'' AND usecounts = 1 THEN 1 ELSE 0 END), 0) AS single_use_plans,
+										ISNULL(SUM(CASE WHEN objtype = ''Adhoc'' AND usecounts = 1 THEN CAST(size_in_bytes AS bigint) ELSE 0 END), 0) AS single_use_plans_bytes,
+										COUNT_BIG(*) AS cached_plans,
+										ISNULL(SUM(CAST(size_in_bytes AS bigint)), 0) AS cached_plans_bytes
+									FROM sys.dm_exec_cached_plans) p
+								WHERE c.name = ''optimize for ad hoc workloads''')
+						ELSE
+							SELECT value_in_use, NULL AS single_use_plans, NULL AS single_use_plans_kb, NULL AS cached_plans,
+								NULL AS cached_plans_kb, NULL AS single_use_plan_percent, NULL AS single_use_plan_size_percent
+							FROM sys.configurations
+							WHERE name = 'optimize for ad hoc workloads'`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				enabled := HandleNilInt(f[0])
+				switch enabled {
+				case "0":
+					enabled = "false"
+				case "1":
+					enabled = "true"
+				}
+				res = append(res, map[string]string{
+					"optimize_for_ad_hoc_workloads": enabled,
+					"single_use_plan_count":         HandleNilInt(f[1]),
+					"single_use_plan_size_kb":       HandleNilInt(f[2]),
+					"cached_plan_count":             HandleNilInt(f[3]),
+					"plan_cache_size_kb":            HandleNilInt(f[4]),
+					"single_use_plan_percent":       HandleNilFloat64(f[5]),
+					"single_use_plan_size_percent":  HandleNilFloat64(f[6]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_AUTHENTICATION_MODE",
+		// SQL Server logins can only connect in mixed mode authentication.
+		Query: `SELECT SERVERPROPERTY('IsIntegratedSecurityOnly')`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				integratedOnly := HandleNilInt(f[0])
+				mode := "unknown"
+				switch integratedOnly {
+				case "0":
+					integratedOnly = "false"
+					mode = "mixed"
+				case "1":
+					integratedOnly = "true"
+					mode = "windows"
+				}
+				res = append(res, map[string]string{
+					"is_integrated_security_only": integratedOnly,
+					"authentication_mode":         mode,
+				})
+			}
+			return res
+		},
+		Cacheable: true,
+	},
+	{
+		Name: "DB_DEFAULT_DIRECTORIES",
+		// InstanceDefaultBackupPath is only available in SQL Server 2019 and later; the backup directory
+		// is read from the registry on earlier versions, which requires elevated permissions. Paths
+		// that cannot be read are reported as unknown.
+		Query: `SET NOCOUNT ON;
+						DECLARE @backup nvarchar(4000) = CAST(SERVERPROPERTY('InstanceDefaultBackupPath') AS nvarchar(4000));
+						IF @backup IS NULL
+						BEGIN
+							BEGIN TRY
+								EXEC master.dbo.xp_instance_regread N'HKEY_LOCAL_MACHINE',
+									N'Software\Microsoft\MSSQLServer\MSSQLServer', N'BackupDirectory', @backup OUTPUT;
+							END TRY
+							BEGIN CATCH
+							END CATCH
+						END
+						SELECT CAST(SERVERPROPERTY('InstanceDefaultDataPath') AS nvarchar(4000)),
+							CAST(SERVERPROPERTY('InstanceDefaultLogPath') AS nvarchar(4000)),
+							@backup`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"data_directory":   HandleNilString(f[0]),
+					"log_directory":    HandleNilString(f[1]),
+					"backup_directory": HandleNilString(f[2]),
+				})
+			}
+			return res
+		},
+		Cacheable: true,
+	},
+	{
+		Name: "DB_TEMPDB_CONTENTION",
+		// Tasks waiting on page latches of the PFS, GAM and SGAM allocation pages of tempdb at the time
+		// of the collection indicate allocation contention, which is relieved by more tempdb data
+		// files, up to one per CPU for at most 8 CPUs. PFS pages repeat every 8088 pages and GAM and
+		// SGAM pages every 511232 pages. sys.dm_os_waiting_tasks and sys.dm_os_sys_info require
+		// VIEW SERVER STATE; only the number of tempdb data files is reported without the permission.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							EXEC('SELECT w.pagelatch_waits, w.allocation_page_waits, w.max_wait_ms, f.data_files, i.cpu_count
+								FROM (SELECT COUNT(*) AS pagelatch_waits,
+										ISNULL(SUM(CASE WHEN p.page_id = 1 OR p.page_id % 8088 = 0 OR (p.page_id - 2) % 511232 = 0 OR (p.page_id - 3) % 511232 = 0 THEN 1 ELSE 0 END), 0) AS allocation_page_waits,
+										ISNULL(MAX(p.wait_duration_ms), 0) AS max_wait_ms
+									FROM (SELECT t.wait_duration_ms, TRY_CAST(PARSENAME(REPLACE(LEFT(t.resource_description, CHARINDEX('' '', t.resource_description + '' '') - 1), '':'', ''.''), 1) AS bigint) AS page_id
+										FROM sys.dm_os_waiting_tasks t
+										WHERE t.wait_type LIKE ''PAGELATCH[_]%'' AND t.resource_description LIKE ''2:%'') p) w
+								CROSS JOIN (SELECT COUNT(*) AS data_files FROM tempdb.sys.database_files WHERE type = 0) f
+								CROSS JOIN sys.dm_os_sys_info i')
+						ELSE
+							SELECT NULL AS pagelatch_waits, NULL AS allocation_page_waits, NULL AS max_wait_ms,
+								(SELECT COUNT(*) FROM tempdb.sys.database_files WHERE type = 0) AS data_files, NULL AS cpu_count`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				allocationWaits := HandleNilInt(f[1])
+				contention := "unknown"
+				if waits, err := strconv.ParseInt(allocationWaits, 10, 64); err == nil {
+					contention = strconv.FormatBool(waits > 0)
+				}
+				dataFiles := HandleNilInt(f[3])
+				cpuCount := HandleNilInt(f[4])
+				tooFewDataFiles := "unknown"
+				files, errFiles := strconv.ParseInt(dataFiles, 10, 64)
+				cpus, errCPUs := strconv.ParseInt(cpuCount, 10, 64)
+				if errFiles == nil && errCPUs == nil {
+					tooFewDataFiles = strconv.FormatBool(files < min(cpus, 8))
+				}
+				res = append(res, map[string]string{
+					"pagelatch_waits":       HandleNilInt(f[0]),
+					"allocation_page_waits": allocationWaits,
+					"max_wait_ms":           HandleNilInt(f[2]),
+					"allocation_contention": contention,
+					"tempdb_data_files":     dataFiles,
+					"cpu_count":             cpuCount,
+					"too_few_data_files":    tooFewDataFiles,
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_FAILOVER_CLUSTER",
+		// The agent connects to a failover cluster instance through its virtual network name, which
+		// moves with the instance; ComputerNamePhysicalNetBIOS is the node the instance is running on
+		// at the time of the collection.
+		Query: `SELECT CAST(SERVERPROPERTY('IsClustered') AS bit),
+							CAST(SERVERPROPERTY('ComputerNamePhysicalNetBIOS') AS nvarchar(128)),
+							CAST(SERVERPROPERTY('MachineName') AS nvarchar(128)),
+							CAST(SERVERPROPERTY('IsHadrEnabled') AS bit)`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"is_clustered":        HandleNilBool(f[0]),
+					"active_node":         HandleNilString(f[1]),
+					"virtual_server_name": HandleNilString(f[2]),
+					"is_hadr_enabled":     HandleNilBool(f[3]),
+					"configured_as_fci":   "unknown",
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_RESOURCE_GOVERNOR",
+		// One row per resource pool with the configuration of Resource Governor. A pool is capped when
+		// it limits the CPU or memory it can use. sys.dm_resource_governor_resource_pools requires
+		// VIEW SERVER STATE; only the configuration is reported without the permission.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							EXEC('SELECT c.is_enabled, OBJECT_NAME(c.classifier_function_id, 1), p.name,
+									p.min_cpu_percent, p.max_cpu_percent, p.cap_cpu_percent, p.min_memory_percent, p.max_memory_percent
+								FROM sys.resource_governor_configuration c
+								CROSS JOIN sys.dm_resource_governor_resource_pools p')
+						ELSE
+							SELECT c.is_enabled, OBJECT_NAME(c.classifier_function_id, 1), NULL AS name,
+								NULL AS min_cpu_percent, NULL AS max_cpu_percent, NULL AS cap_cpu_percent,
+								NULL AS min_memory_percent, NULL AS max_memory_percent
+							FROM sys.resource_governor_configuration c`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				maxCPU := HandleNilInt(f[4])
+				capCPU := HandleNilInt(f[5])
+				maxMemory := HandleNilInt(f[7])
+				capped := "unknown"
+				if maxCPU != "unknown" && capCPU != "unknown" && maxMemory != "unknown" {
+					capped = strconv.FormatBool(maxCPU != "100" || capCPU != "100" || maxMemory != "100")
+				}
+				res = append(res, map[string]string{
+					"enabled":             HandleNilBool(f[0]),
+					"classifier_function": HandleNilString(f[1]),
+					"pool_name":           HandleNilString(f[2]),
+					"min_cpu_percent":     HandleNilInt(f[3]),
+					"max_cpu_percent":     maxCPU,
+					"cap_cpu_percent":     capCPU,
+					"min_memory_percent":  HandleNilInt(f[6]),
+					"max_memory_percent":  maxMemory,
+					"capped":              capped,
+				})
+			}
+			return res
+		},
+		// Resource Governor is only available in Enterprise edition.
+		Editions: []string{EditionEnterprise},
+	},
+	{
+		Name: "DB_TOP_QUERIES",
+		// The statements of the plan cache using the most resources since their plans were cached,
+		// aggregated by query hash and ranked by @top_queries_metric. The text of a statement is
+		// truncated and is unknown when it cannot be read, e.g. for encrypted modules or plans evicted
+		// during the collection. sys.dm_exec_query_stats requires VIEW SERVER STATE; no queries are
+		// reported without the permission.
+		Query: `IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+							SELECT CONVERT(varchar(18), q.query_hash, 1), q.execution_count, q.total_worker_time_ms,
+								q.total_logical_reads, q.total_elapsed_time_ms,
+								LEFT(SUBSTRING(t.text, s.statement_start_offset / 2 + 1,
+									(CASE s.statement_end_offset WHEN -1 THEN DATALENGTH(t.text) ELSE s.statement_end_offset END - s.statement_start_offset) / 2 + 1), @max_query_text_length)
+							FROM (SELECT TOP (@top_queries) query_hash, SUM(execution_count) AS execution_count,
+									SUM(total_worker_time) / 1000 AS total_worker_time_ms, SUM(total_logical_reads) AS total_logical_reads,
+									SUM(total_elapsed_time) / 1000 AS total_elapsed_time_ms
+								FROM sys.dm_exec_query_stats
+								GROUP BY query_hash
+								ORDER BY CASE @top_queries_metric WHEN 'logical_reads' THEN SUM(total_logical_reads) ELSE SUM(total_worker_time) END DESC) q
+							CROSS APPLY (SELECT TOP 1 sql_handle, statement_start_offset, statement_end_offset
+								FROM sys.dm_exec_query_stats
+								WHERE query_hash = q.query_hash) s
+							OUTER APPLY sys.dm_exec_sql_text(s.sql_handle) t
+							ORDER BY CASE @top_queries_metric WHEN 'logical_reads' THEN q.total_logical_reads ELSE q.total_worker_time_ms END DESC`,
+		Args: func(settings RuleSettings) []any {
+			return []any{sql.Named("top_queries", settings.TopQueries), sql.Named("top_queries_metric", settings.TopQueriesMetric),
+				sql.Named("max_query_text_length", maxQueryTextLength)}
+		},
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"query_hash":            HandleNilString(f[0]),
+					"execution_count":       HandleNilInt(f[1]),
+					"total_worker_time_ms":  HandleNilInt(f[2]),
+					"total_logical_reads":   HandleNilInt(f[3]),
+					"total_elapsed_time_ms": HandleNilInt(f[4]),
+					"query_text":            truncateQueryText(HandleNilString(f[5])),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_BACKUP_DEFAULTS",
+		// Backups are compressed and verified with checksums by default when the options are enabled.
+		// backup checksum default is only available in SQL Server 2014 and later and is reported as
+		// unknown on earlier versions.
+		Query: `SELECT MAX(CASE WHEN name = 'backup compression default' THEN CAST(value_in_use AS int) END),
+							MAX(CASE WHEN name = 'backup checksum default' THEN CAST(value_in_use AS int) END)
+						FROM sys.configurations
+						WHERE name IN ('backup compression default', 'backup checksum default')`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"backup_compression_default": optionEnabled(f[0]),
+					"backup_checksum_default":    optionEnabled(f[1]),
+				})
+			}
+			return res
+		},
+		Cacheable: true,
+	},
+	{
+		Name: "DB_DATABASE_SNAPSHOTS",
+		// Snapshots are sparse files that grow as pages of the source database change. The space used
+		// on disk is read from sys.dm_io_virtual_file_stats and is reported as unknown for snapshots
+		// that are not online. max_size_kb is the size the sparse files can grow to.
+		Query: `SELECT s.name, src.name, s.state_desc, DATEDIFF(hour, s.create_date, GETDATE()),
+							CASE WHEN s.state = 0 THEN
+								(SELECT SUM(v.size_on_disk_bytes) / 1024 FROM sys.dm_io_virtual_file_stats(s.database_id, NULL) v)
+							END,
+							(SELECT SUM(CAST(m.size AS bigint)) * 8 FROM sys.master_files m WHERE m.database_id = s.database_id)
+						FROM sys.databases s
+						LEFT JOIN sys.databases src ON src.database_id = s.source_database_id
+						WHERE s.source_database_id IS NOT NULL
+						ORDER BY s.create_date`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"snapshot_name":   HandleNilString(f[0]),
+					"source_database": HandleNilString(f[1]),
+					"state":           HandleNilString(f[2]),
+					"age_hours":       HandleNilInt(f[3]),
+					"size_on_disk_kb": HandleNilInt(f[4]),
+					"max_size_kb":     HandleNilInt(f[5]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_DATABASE_STATE",
+		// Only user databases that are not online or are read-only are reported; no rows means all
+		// user databases are online and writable. Databases in SUSPECT, RECOVERY_PENDING or EMERGENCY
+		// state need immediate attention.
+		Query: `SELECT name, state_desc, is_read_only
+						FROM sys.databases
+						WHERE ` + userDatabases("name") + ` AND (state_desc <> 'ONLINE' OR is_read_only = 1)
+						ORDER BY name`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"db_name":      HandleNilString(f[0]),
+					"state":        HandleNilString(f[1]),
+					"is_read_only": HandleNilBool(f[2]),
+				})
+			}
+			return res
+		},
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_AUTO_UPDATE_STATISTICS",
+		// User databases with auto update statistics disabled are flagged as they risk bad plans from
+		// stale statistics. The settings are reported as unknown for databases that are not online or
+		// that the login cannot access.
+		Query: `SELECT name,
+							CASE WHEN state_desc = 'ONLINE' AND HAS_DBACCESS(name) = 1 THEN is_auto_update_stats_on END,
+							CASE WHEN state_desc = 'ONLINE' AND HAS_DBACCESS(name) = 1 THEN is_auto_update_stats_async_on END
+						FROM sys.databases
+						WHERE ` + userDatabases("name") + `
+						ORDER BY name`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				autoUpdateDisabled := "unknown"
+				if on, ok := f[1].(bool); ok {
+					autoUpdateDisabled = strconv.FormatBool(!on)
+				}
+				res = append(res, map[string]string{
+					"db_name":                 HandleNilString(f[0]),
+					"auto_update_stats":       HandleNilBool(f[1]),
+					"auto_update_stats_async": HandleNilBool(f[2]),
+					"auto_update_disabled":    autoUpdateDisabled,
+				})
+			}
+			return res
+		},
+		PerDatabase: true,
+	},
+	{
+		Name: "DB_XE_SESSIONS",
+		// Extended events sessions defined on the server or running, with whether they are running
+		// and their targets. Targets are read from the running session when it runs and from its
+		// definition otherwise. Built-in sessions that run without a definition report unknown for
+		// start_on_server_startup.
+		Query: `SELECT COALESCE(s.name, r.name),
+							CAST(CASE WHEN r.address IS NULL THEN 0 ELSE 1 END AS bit),
+							s.startup_state,
+							CASE WHEN r.address IS NOT NULL
+								THEN STUFF((SELECT ',' + t.target_name FROM sys.dm_xe_session_targets t
+									WHERE t.event_session_address = r.address ORDER BY t.target_name
+									FOR XML PATH(''), TYPE).value('.', 'nvarchar(max)'), 1, 1, '')
+								ELSE STUFF((SELECT ',' + t.name FROM sys.server_event_session_targets t
+									WHERE t.event_session_id = s.event_session_id ORDER BY t.name
+									FOR XML PATH(''), TYPE).value('.', 'nvarchar(max)'), 1, 1, '')
+							END
+						FROM sys.server_event_sessions s
+						FULL OUTER JOIN sys.dm_xe_sessions r ON r.name = s.name
+						ORDER BY COALESCE(s.name, r.name)`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				targets := ""
+				if f[3] != nil {
+					targets = HandleNilString(f[3])
+				}
+				res = append(res, map[string]string{
+					"session_name":            HandleNilString(f[0]),
+					"running":                 HandleNilBool(f[1]),
+					"start_on_server_startup": HandleNilBool(f[2]),
+					"targets":                 targets,
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_MIRRORING_LOG_SHIPPING",
+		// Databases protected by database mirroring or log shipping, with their role, partner and
+		// synchronization state. Mirrored databases are unsynchronized unless they are synchronized or
+		// synchronizing. Log shipped databases are unsynchronized when their last backup, on the
+		// primary, or their last restore, on a secondary, is older than the threshold of the log
+		// shipping monitor or never happened. Log shipping is only reported with SELECT permission on
+		// the monitor tables of msdb.
+		Query: `DECLARE @r TABLE (db_name sysname, ha_type varchar(20), role nvarchar(60), partner nvarchar(256),
+							state nvarchar(60), minutes_since_last_sync int, unsynchronized bit);
+						INSERT @r SELECT DB_NAME(database_id), 'MIRRORING', mirroring_role_desc, mirroring_partner_instance,
+								mirroring_state_desc, NULL,
+								CASE WHEN mirroring_state_desc IN ('SYNCHRONIZED', 'SYNCHRONIZING') THEN 0 ELSE 1 END
+							FROM sys.database_mirroring
+							WHERE mirroring_guid IS NOT NULL;
+						IF HAS_PERMS_BY_NAME('msdb.dbo.log_shipping_monitor_primary', 'OBJECT', 'SELECT') = 1
+							AND HAS_PERMS_BY_NAME('msdb.dbo.log_shipping_monitor_secondary', 'OBJECT', 'SELECT') = 1
+							INSERT @r SELECT primary_database, 'LOG_SHIPPING', 'PRIMARY', NULL,
+									CASE WHEN last_backup_date_utc IS NULL THEN 'NEVER_BACKED_UP'
+										WHEN DATEDIFF(minute, last_backup_date_utc, GETUTCDATE()) > backup_threshold THEN 'BACKUP_THRESHOLD_EXCEEDED'
+										ELSE 'SYNCHRONIZED' END,
+									DATEDIFF(minute, last_backup_date_utc, GETUTCDATE()),
+									CASE WHEN last_backup_date_utc IS NULL OR DATEDIFF(minute, last_backup_date_utc, GETUTCDATE()) > backup_threshold THEN 1 ELSE 0 END
+								FROM msdb.dbo.log_shipping_monitor_primary
+							UNION ALL
+							SELECT secondary_database, 'LOG_SHIPPING', 'SECONDARY', primary_server,
+									CASE WHEN last_restored_date_utc IS NULL THEN 'NEVER_RESTORED'
+										WHEN DATEDIFF(minute, last_restored_date_utc, GETUTCDATE()) > restore_threshold THEN 'RESTORE_THRESHOLD_EXCEEDED'
+										ELSE 'SYNCHRONIZED' END,
+									DATEDIFF(minute, last_restored_date_utc, GETUTCDATE()),
+									CASE WHEN last_restored_date_utc IS NULL OR DATEDIFF(minute, last_restored_date_utc, GETUTCDATE()) > restore_threshold THEN 1 ELSE 0 END
+								FROM msdb.dbo.log_shipping_monitor_secondary;
+						SELECT db_name, ha_type, role, partner, state, minutes_since_last_sync, unsynchronized
+						FROM @r
+						ORDER BY db_name, ha_type, role`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"db_name":                 HandleNilString(f[0]),
+					"ha_type":                 HandleNilString(f[1]),
+					"role":                    HandleNilString(f[2]),
+					"partner":                 HandleNilString(f[3]),
+					"state":                   HandleNilString(f[4]),
+					"minutes_since_last_sync": HandleNilInt(f[5]),
+					"unsynchronized":          HandleNilBool(f[6]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_FILL_FACTOR",
+		// The server default fill factor of new indexes. Values other than 0 and 100, which both fill
+		// the leaf pages completely, often come from legacy tuning and cause sparse pages.
+		Query: `SELECT CAST(value AS int), CAST(value_in_use AS int)
+						FROM sys.configurations
+						WHERE name = 'fill factor (%)'`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				isDefault := "unknown"
+				if v := HandleNilInt(f[1]); v != "unknown" {
+					isDefault = strconv.FormatBool(v == "0" || v == "100")
+				}
+				res = append(res, map[string]string{
+					"configured_fill_factor": HandleNilInt(f[0]),
+					"fill_factor":            HandleNilInt(f[1]),
+					"is_default":             isDefault,
+				})
+			}
+			return res
+		},
+		Cacheable: true,
+	},
+	{
+		Name: "DB_INDEX_FILL_FACTOR",
+		// The indexes of user tables with a fill factor other than the default that have at least
+		// @min_pages pages. Reading the indexes of every database is expensive, so the rule only runs
+		// if collecting the index fill factors is enabled. Databases that cannot be read are skipped.
+		Query: `SET NOCOUNT ON;
+						DECLARE @indexes TABLE (db_name sysname, table_name nvarchar(257), index_name sysname NULL,
+							fill_factor int, page_count bigint);
+						DECLARE @sql nvarchar(max);
+						` + forEachDatabase(`SELECT name FROM sys.databases WHERE `+userDatabases("name"), `BEGIN TRY
+								SET @sql = N'SELECT @db, s.name + ''.'' + o.name, i.name, i.fill_factor, SUM(a.used_pages)
+									FROM ' + QUOTENAME(@db) + N'.sys.indexes i
+									JOIN ' + QUOTENAME(@db) + N'.sys.objects o ON o.object_id = i.object_id
+									JOIN ' + QUOTENAME(@db) + N'.sys.schemas s ON s.schema_id = o.schema_id
+									JOIN ' + QUOTENAME(@db) + N'.sys.partitions p ON p.object_id = i.object_id AND p.index_id = i.index_id
+									JOIN ' + QUOTENAME(@db) + N'.sys.allocation_units a ON a.container_id = p.partition_id
+									WHERE o.is_ms_shipped = 0 AND i.fill_factor NOT IN (0, 100)
+									GROUP BY s.name, o.name, i.name, i.fill_factor
+									HAVING SUM(a.used_pages) >= @min_pages';
+								INSERT INTO @indexes EXEC sp_executesql @sql, N'@db sysname, @min_pages bigint', @db = @db, @min_pages = @min_pages;
+							END TRY
+							BEGIN CATCH
+							END CATCH`) + `
+						SELECT db_name, table_name, index_name, fill_factor, page_count
+						FROM @indexes
+						ORDER BY db_name, table_name, index_name`,
+		Args: func(settings RuleSettings) []any {
+			return []any{sql.Named("min_pages", settings.IndexFillFactorMinPages)}
+		},
+		Enabled: func(settings RuleSettings) bool {
+			return settings.CollectIndexFillFactors
+		},
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"db_name":     HandleNilString(f[0]),
+					"table_name":  HandleNilString(f[1]),
+					"index_name":  HandleNilString(f[2]),
+					"fill_factor": HandleNilInt(f[3]),
+					"page_count":  HandleNilInt(f[4]),
+				})
+			}
+			return res
+		},
+		FiltersDatabases: true,
+		SkipWorkloads:    []string{WorkloadOLTP},
+	},
+	{
+		Name: "DB_LAST_CHECKDB",
+		// The last successful DBCC CHECKDB of each database, in the local time of the server. It is
+		// read from DATABASEPROPERTYEX where SQL Server reports it and parsed from DBCC DBINFO
+		// otherwise, which requires sysadmin; the date is reported as unknown without the permission.
+		// SQL Server reports 1900-01-01 for databases never checked, which are reported as never
+		// checked and overdue.
+		Query: `SET NOCOUNT ON;
+						DECLARE @dbinfo TABLE (ParentObject nvarchar(255), Object nvarchar(255), Field nvarchar(255), Value nvarchar(max));
+						DECLARE @r TABLE (db_name sysname, last_good nvarchar(255) NULL, source varchar(10));
+						DECLARE @last nvarchar(255);
+						` + forEachDatabase(`SELECT name FROM sys.databases WHERE name <> 'tempdb' AND `+listedDatabases("name"), `SET @last = CONVERT(nvarchar(255), CAST(DATABASEPROPERTYEX(@db, 'LastGoodCheckDbTime') AS datetime), 121);
+							IF @last IS NOT NULL
+								INSERT INTO @r VALUES (@db, @last, 'property');
+							ELSE
+							BEGIN
+								BEGIN TRY
+									DELETE FROM @dbinfo;
+									INSERT INTO @dbinfo EXEC ('DBCC DBINFO (' + QUOTENAME(@db, '''') + ') WITH TABLERESULTS, NO_INFOMSGS');
+									INSERT INTO @r SELECT @db, MAX(Value), 'dbinfo' FROM @dbinfo WHERE Field = 'dbi_dbccLastKnownGood';
+								END TRY
+								BEGIN CATCH
+									INSERT INTO @r VALUES (@db, NULL, 'unknown');
+								END CATCH
+							END`) + `
+						SELECT db_name, last_good, source, CONVERT(nvarchar(30), GETDATE(), 121)
+						FROM @r`,
+		Fields: func(fields [][]any, settings RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				row := lastCheckDB(HandleNilString(f[1]), HandleNilString(f[3]), settings.CheckDBMaxAge)
+				row["db_name"] = HandleNilString(f[0])
+				row["source"] = HandleNilString(f[2])
+				res = append(res, row)
+			}
+			return res
+		},
+		FiltersDatabases: true,
+	},
+	{
+		Name: "DB_WORKER_THREADS",
+		// The configured max worker threads, where 0 sizes the pool automatically, the size of the
+		// worker pool in effect and the workers of the visible schedulers. A pool close to its maximum
+		// or queued tasks along with THREADPOOL waits point to worker thread exhaustion. The DMVs
+		// require VIEW SERVER STATE; only the configuration is reported without the permission.
+		Query: `SET NOCOUNT ON;
+						DECLARE @max_workers int, @current_workers bigint, @active_workers bigint, @queued_tasks bigint;
+						IF HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE') = 1
+						BEGIN
+							SELECT @max_workers = max_workers_count FROM sys.dm_os_sys_info;
+							SELECT @current_workers = SUM(CAST(current_workers_count AS bigint)),
+								@active_workers = SUM(CAST(active_workers_count AS bigint)),
+								@queued_tasks = SUM(CAST(work_queue_count AS bigint))
+							FROM sys.dm_os_schedulers
+							WHERE status = 'VISIBLE ONLINE';
+						END
+						SELECT CAST(value AS int), CAST(value_in_use AS int), @max_workers, @current_workers, @active_workers,
+							@current_workers - @active_workers, @queued_tasks,
+							CAST(100.0 * @current_workers / NULLIF(@max_workers, 0) AS float)
+						FROM sys.configurations
+						WHERE name = 'max worker threads'`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				res = append(res, map[string]string{
+					"configured_max_worker_threads": HandleNilInt(f[0]),
+					"max_worker_threads":            HandleNilInt(f[1]),
+					"max_workers_count":             HandleNilInt(f[2]),
+					"current_workers":               HandleNilInt(f[3]),
+					"active_workers":                HandleNilInt(f[4]),
+					"idle_workers":                  HandleNilInt(f[5]),
+					"queued_tasks":                  HandleNilInt(f[6]),
+					"worker_utilization_percent":    HandleNilFloat64(f[7]),
+				})
+			}
+			return res
+		},
+	},
+	{
+		Name: "DB_PAGE_VERIFY",
+		// User databases whose page verify option is not CHECKSUM are flagged as corrupted pages may
+		// go undetected. The option is reported as unknown for databases that are not online or that
+		// the login cannot access.
+		Query: `SELECT name,
+							CASE WHEN state_desc = 'ONLINE' AND HAS_DBACCESS(name) = 1 THEN page_verify_option_desc END
+						FROM sys.databases
+						WHERE ` + userDatabases("name") + `
+						ORDER BY name`,
+		Fields: func(fields [][]any, _ RuleSettings) []map[string]string {
+			res := []map[string]string{}
+			for _, f := range fields {
+				notChecksum := "unknown"
+				if option, ok := f[1].(string); ok {
+					notChecksum = strconv.FormatBool(option != "CHECKSUM")
+				}
+				res = append(res, map[string]string{
+					"db_name":                  HandleNilString(f[0]),
+					"page_verify_option":       HandleNilString(f[1]),
+					"page_verify_not_checksum": notChecksum,
+				})
+			}
+			return res
+		},
+		PerDatabase: true,
 	},
 }
+
+// Bounds of the memory left to the operating system by max server memory, in percent of the
+// physical memory, outside of which DB_MEMORY_ALLOCATION flags the memory allocation.
+const (
+	minOSMemoryPercent = 10
+	maxOSMemoryPercent = 50
+)
+
+// MemoryAllocation derives DB_MEMORY_ALLOCATION from the DB_MAX_SERVER_MEMORY and
+// INSTANCE_METRICS details of the same instance. It reports max server memory as a percentage of
+// the physical memory of the operating system and flags whether it leaves too little or too much
+// memory to the operating system.
+// It returns false if either detail is missing or has no valid value.
+func MemoryAllocation(details []Details) (Details, bool) {
+	var maxServerMemoryMB, physicalMemoryKB int64
+	var foundMax, foundPhysical bool
+	for _, d := range details {
+		if len(d.Fields) == 0 {
+			continue
+		}
+		var err error
+		switch d.Name {
+		case "DB_MAX_SERVER_MEMORY":
+			maxServerMemoryMB, err = strconv.ParseInt(d.Fields[0]["value_in_use"], 10, 64)
+			foundMax = err == nil && maxServerMemoryMB > 0
+		case "INSTANCE_METRICS":
+			physicalMemoryKB, err = strconv.ParseInt(d.Fields[0]["physical_memory_kb"], 10, 64)
+			foundPhysical = err == nil && physicalMemoryKB > 0
+		}
+	}
+	if !foundMax || !foundPhysical {
+		return Details{}, false
+	}
+	physicalMemoryMB := physicalMemoryKB / 1024
+	// The default max server memory is far larger than the physical memory.
+	if maxServerMemoryMB > physicalMemoryMB {
+		maxServerMemoryMB = physicalMemoryMB
+	}
+	osMemoryMB := physicalMemoryMB - maxServerMemoryMB
+	percent := float64(maxServerMemoryMB) * 100 / float64(physicalMemoryMB)
+	allocation := "OK"
+	switch {
+	case osMemoryMB*100 < physicalMemoryMB*minOSMemoryPercent:
+		allocation = "TOO_LITTLE_FOR_OS"
+	case osMemoryMB*100 > physicalMemoryMB*maxOSMemoryPercent:
+		allocation = "TOO_MUCH_FOR_OS"
+	}
+	return Details{
+		Name: "DB_MEMORY_ALLOCATION",
+		Fields: []map[string]string{{
+			"max_server_memory_mb":      strconv.FormatInt(maxServerMemoryMB, 10),
+			"physical_memory_mb":        strconv.FormatInt(physicalMemoryMB, 10),
+			"max_server_memory_percent": strconv.FormatFloat(percent, 'f', 1, 64),
+			"os_memory_mb":              strconv.FormatInt(osMemoryMB, 10),
+			"memory_allocation":         allocation,
+		}},
+	}, true
+}
+
+// DataLogSameDisk derives DB_DATA_LOG_SAME_DISK from the physical drives of the data and log files
+// in DB_LOG_DISK_SEPARATION. Each database is reported with the physical drives of its data and log
+// files and whether a data file and a log file share a physical drive. Whether they share a drive
+// is unknown if a file of the database is not mapped to a physical drive and the mapped files do
+// not share one.
+// It returns false if DB_LOG_DISK_SEPARATION is missing or has no data or log files.
+func DataLogSameDisk(details []Details) (Details, bool) {
+	type databaseDrives struct {
+		data, log map[string]bool
+		unmapped  bool
+	}
+	databases := map[string]*databaseDrives{}
+	var names []string
+	for _, d := range details {
+		if d.Name != "DB_LOG_DISK_SEPARATION" {
+			continue
+		}
+		for _, f := range d.Fields {
+			fileType := f["filetype"]
+			if fileType != "0" && fileType != "1" {
+				continue
+			}
+			db, ok := databases[f["db_name"]]
+			if !ok {
+				db = &databaseDrives{data: map[string]bool{}, log: map[string]bool{}}
+				databases[f["db_name"]] = db
+				names = append(names, f["db_name"])
+			}
+			drives := physicalDrives(f["physical_drive"])
+			if len(drives) == 0 {
+				db.unmapped = true
+			}
+			for _, drive := range drives {
+				if fileType == "0" {
+					db.data[drive] = true
+				} else {
+					db.log[drive] = true
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		return Details{}, false
+	}
+	sort.Strings(names)
+	res := Details{Name: "DB_DATA_LOG_SAME_DISK"}
+	for _, name := range names {
+		db := databases[name]
+		sameDisk := "false"
+		if db.unmapped {
+			sameDisk = "unknown"
+		}
+		for drive := range db.data {
+			if db.log[drive] {
+				sameDisk = "true"
+			}
+		}
+		res.Fields = append(res.Fields, map[string]string{
+			"db_name":     name,
+			"data_drives": strings.Join(sortedKeys(db.data), ","),
+			"log_drives":  strings.Join(sortedKeys(db.log), ","),
+			"same_disk":   sameDisk,
+		})
+	}
+	return res, true
+}
+
+// physicalDrives returns the physical drives of the physical_drive field of DB_LOG_DISK_SEPARATION,
+// which lists the drives of the file separated by commas. It returns no drives if the file is not
+// mapped to a physical drive.
+func physicalDrives(field string) []string {
+	var drives []string
+	for _, drive := range strings.Split(field, ",") {
+		drive = strings.TrimSpace(drive)
+		if drive != "" && drive != "unknown" {
+			drives = append(drives, drive)
+		}
+	}
+	return drives
+}
+
+// RecommendedAllocationUnitSize is the allocation unit size in bytes recommended for volumes
+// hosting SQL Server data and log files.
+const RecommendedAllocationUnitSize = 64 * 1024
+
+// Volume is a volume of the machine running SQL Server.
+type Volume struct {
+	// Name is the mount point of the volume, such as "D:\" or "D:\mnt\data\" on windows and
+	// "/var/opt/mssql" on linux.
+	Name string
+	// AllocationUnitSize is the allocation unit size of the file system in bytes.
+	AllocationUnitSize int64
+	// SizeBytes is the size of the file system in bytes.
+	SizeBytes int64
+	// FreeBytes is the space of the file system available to SQL Server in bytes.
+	FreeBytes int64
+}
+
+// sqlFile is the path of a data or log file of SQL Server, or of the directory new data or log
+// files are created in.
+type sqlFile struct {
+	path     string
+	fileType string
+}
+
+// sqlFiles returns the data and log files in DB_LOG_DISK_SEPARATION followed by the default data
+// and log directories in DB_DEFAULT_DIRECTORIES.
+func sqlFiles(details []Details) []sqlFile {
+	var files, directories []sqlFile
+	for _, d := range details {
+		switch d.Name {
+		case "DB_LOG_DISK_SEPARATION":
+			for _, f := range d.Fields {
+				switch f["filetype"] {
+				case "0":
+					files = append(files, sqlFile{path: f["physical_name"], fileType: "data"})
+				case "1":
+					files = append(files, sqlFile{path: f["physical_name"], fileType: "log"})
+				}
+			}
+		case "DB_DEFAULT_DIRECTORIES":
+			for _, f := range d.Fields {
+				directories = append(directories, sqlFile{path: f["data_directory"], fileType: "data"})
+				directories = append(directories, sqlFile{path: f["log_directory"], fileType: "log"})
+			}
+		}
+	}
+	var res []sqlFile
+	for _, f := range append(files, directories...) {
+		if f.path != "" && f.path != "unknown" {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// SQLFilePaths returns the paths of the data and log files in DB_LOG_DISK_SEPARATION and of the
+// default data and log directories in DB_DEFAULT_DIRECTORIES.
+func SQLFilePaths(details []Details) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, f := range sqlFiles(details) {
+		if seen[f.path] {
+			continue
+		}
+		seen[f.path] = true
+		paths = append(paths, f.path)
+	}
+	return paths
+}
+
+// SQLVolumeAllocationUnits derives DB_SQL_VOLUME_ALLOCATION_UNITS from the data and log files in
+// DB_LOG_DISK_SEPARATION and the volumes of the machine. Each volume hosting data or log files is
+// reported with its allocation unit size and whether the size is the recommended 64KB.
+// The default data and log directories in DB_DEFAULT_DIRECTORIES count as data and log files.
+// Files are mapped to the volume with the longest mount point containing them.
+// It returns false if no data or log file is on a known volume.
+func SQLVolumeAllocationUnits(details []Details, volumes []Volume) (Details, bool) {
+	fileTypes := sqlVolumeFileTypes(details, volumes)
+	if len(fileTypes) == 0 {
+		return Details{}, false
+	}
+	res := Details{Name: "DB_SQL_VOLUME_ALLOCATION_UNITS"}
+	for _, v := range volumes {
+		types, ok := fileTypes[v.Name]
+		if !ok {
+			continue
+		}
+		res.Fields = append(res.Fields, map[string]string{
+			"volume":               v.Name,
+			"allocation_unit_size": strconv.FormatInt(v.AllocationUnitSize, 10),
+			"file_types":           types,
+			"is_recommended_size":  strconv.FormatBool(v.AllocationUnitSize == RecommendedAllocationUnitSize),
+		})
+		delete(fileTypes, v.Name)
+	}
+	return res, true
+}
+
+// SQLVolumeFreeSpace derives DB_SQL_VOLUME_FREE_SPACE from the data and log files in
+// DB_LOG_DISK_SEPARATION and the volumes of the machine. Each volume hosting data or log files is
+// reported with its size, free space and whether the free space is below
+// thresholdPercent. The free space is the headroom left for the files to grow.
+// The default data and log directories in DB_DEFAULT_DIRECTORIES count as data and log files.
+// It returns false if no data or log file is on a known volume.
+func SQLVolumeFreeSpace(details []Details, volumes []Volume, thresholdPercent int64) (Details, bool) {
+	fileTypes := sqlVolumeFileTypes(details, volumes)
+	if len(fileTypes) == 0 {
+		return Details{}, false
+	}
+	res := Details{Name: "DB_SQL_VOLUME_FREE_SPACE"}
+	for _, v := range volumes {
+		types, ok := fileTypes[v.Name]
+		if !ok {
+			continue
+		}
+		freePercent, belowThreshold := "unknown", "unknown"
+		if v.SizeBytes > 0 {
+			percent := float64(v.FreeBytes) * 100 / float64(v.SizeBytes)
+			freePercent = strconv.FormatFloat(percent, 'f', 2, 64)
+			belowThreshold = strconv.FormatBool(percent < float64(thresholdPercent))
+		}
+		res.Fields = append(res.Fields, map[string]string{
+			"volume":             v.Name,
+			"file_types":         types,
+			"size_bytes":         strconv.FormatInt(v.SizeBytes, 10),
+			"free_bytes":         strconv.FormatInt(v.FreeBytes, 10),
+			"free_percent":       freePercent,
+			"is_below_threshold": belowThreshold,
+		})
+		delete(fileTypes, v.Name)
+	}
+	return res, true
+}
+
+// sqlVolumeFileTypes maps the volumes hosting the data and log files of sqlFiles to the types of the files they host, such as "data,log".
+func sqlVolumeFileTypes(details []Details, volumes []Volume) map[string]string {
+	fileTypes := map[string]map[string]bool{}
+	for _, f := range sqlFiles(details) {
+		volume, ok := fileVolume(f.path, volumes)
+		if !ok {
+			continue
+		}
+		if fileTypes[volume] == nil {
+			fileTypes[volume] = map[string]bool{}
+		}
+		fileTypes[volume][f.fileType] = true
+	}
+	res := map[string]string{}
+	for volume, types := range fileTypes {
+		var t []string
+		for _, fileType := range []string{"data", "log"} {
+			if types[fileType] {
+				t = append(t, fileType)
+			}
+		}
+		res[volume] = strings.Join(t, ",")
+	}
+	return res
+}
+
+// fileVolume returns the name of the volume with the longest mount point containing the file.
+// Windows paths are compared case-insensitively.
+func fileVolume(path string, volumes []Volume) (string, bool) {
+	windows := !strings.HasPrefix(path, "/")
+	if windows {
+		path = strings.ToLower(path)
+	}
+	var name string
+	for _, v := range volumes {
+		mountPoint, sep := v.Name, "/"
+		if windows {
+			mountPoint, sep = strings.ToLower(mountPoint), `\`
+		}
+		if !strings.HasSuffix(mountPoint, sep) {
+			mountPoint += sep
+		}
+		if (strings.HasPrefix(path, mountPoint) || path+sep == mountPoint) && len(v.Name) > len(name) {
+			name = v.Name
+		}
+	}
+	return name, name != ""
+}
+
+// xeRingBuffer is the target data of an extended events ring buffer target.
+type xeRingBuffer struct {
+	Events []struct {
+		Name      string `xml:"name,attr"`
+		Timestamp string `xml:"timestamp,attr"`
+		Data      []struct {
+			Name  string `xml:"name,attr"`
+			Value struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"value"`
+		} `xml:"data"`
+	} `xml:"event"`
+}
+
+// deadlocks returns the number of xml_deadlock_report events in the ring buffer target data
+// raised after since, along with the deadlock graph of the latest one.
+func deadlocks(targetData string, since time.Time) (int, string, error) {
+	var rb xeRingBuffer
+	if err := xml.Unmarshal([]byte(targetData), &rb); err != nil {
+		return 0, "", err
+	}
+	count := 0
+	var latestTime time.Time
+	latest := ""
+	for _, e := range rb.Events {
+		if e.Name != "xml_deadlock_report" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, e.Timestamp)
+		if err != nil {
+			return 0, "", err
+		}
+		if t.Before(since) {
+			continue
+		}
+		count++
+		if !t.Before(latestTime) {
+			latestTime = t
+			for _, d := range e.Data {
+				if d.Name == "xml_report" {
+					latest = d.Value.Inner
+				}
+			}
+		}
+	}
+	return count, latest, nil
+}
+
+// AddVMVCPUCount adds the vCPU count of the VM running SQL Server to DB_LICENSED_CORES and
+// reports whether SQL Server uses all vCPUs of the VM.
+func AddVMVCPUCount(details []Details, vcpus int64) {
+	for _, detail := range details {
+		if detail.Name != "DB_LICENSED_CORES" {
+			continue
+		}
+		for _, field := range detail.Fields {
+			field["vm_vcpu_count"] = strconv.FormatInt(vcpus, 10)
+			if cores, err := strconv.ParseInt(field["sql_visible_cores"], 10, 64); err == nil {
+				field["uses_all_vcpus"] = strconv.FormatBool(cores >= vcpus)
+			}
+		}
+	}
+}
+
+// AddFailoverClusterInstance records in DB_FAILOVER_CLUSTER whether the instance is configured as
+// a failover cluster instance and warns when it is configured as one but is not clustered.
+func AddFailoverClusterInstance(details []Details, configured bool) {
+	for _, detail := range details {
+		if detail.Name != "DB_FAILOVER_CLUSTER" {
+			continue
+		}
+		for _, field := range detail.Fields {
+			field["configured_as_fci"] = strconv.FormatBool(configured)
+			if configured && field["is_clustered"] == "false" {
+				log.Logger.Warnw("The instance is configured as a failover cluster instance but is not clustered", "virtual_server_name", field["virtual_server_name"])
+			}
+		}
+	}
+}
+
+// truncateQueryText truncates text to maxQueryTextLength characters.
+func truncateQueryText(text string) string {
+	r := []rune(text)
+	if len(r) <= maxQueryTextLength {
+		return text
+	}
+	return string(r[:maxQueryTextLength])
+}
+
+// lastCheckDB returns the fields of DB_LAST_CHECKDB of the date of the last successful DBCC
+// CHECKDB of a database and the current time of the server, both in the layout of DBCC DBINFO.
+// Dates before 1901 mean that CHECKDB never succeeded. Databases checked longer than maxAge ago are
+// overdue.
+func lastCheckDB(lastGood, serverTime string, maxAge time.Duration) map[string]string {
+	row := map[string]string{
+		"last_good_checkdb":            "unknown",
+		"days_since_last_good_checkdb": "unknown",
+		"checkdb_overdue":              "unknown",
+	}
+	last, err := time.Parse(checkDBTimeLayout, lastGood)
+	if err != nil {
+		return row
+	}
+	if last.Year() < 1901 {
+		row["last_good_checkdb"] = "never"
+		row["checkdb_overdue"] = "true"
+		return row
+	}
+	row["last_good_checkdb"] = last.Format("2006-01-02T15:04:05")
+	now, err := time.Parse(checkDBTimeLayout, serverTime)
+	if err != nil {
+		return row
+	}
+	age := now.Sub(last)
+	row["days_since_last_good_checkdb"] = strconv.Itoa(int(age.Hours() / 24))
+	row["checkdb_overdue"] = strconv.FormatBool(age > maxAge)
+	return row
+}
+
+// DefaultRecommendedPowerPlans are the power plans recommended for SQL Server if none are
+// configured.
+var DefaultRecommendedPowerPlans = []string{"High performance"}
+
+// AddPowerPlanRecommended adds to the OS details whether the power profile of the machine is one of
+// the recommended power plans. It is unknown if the power profile could not be collected.
+func AddPowerPlanRecommended(details []Details, recommended []string) {
+	if len(recommended) == 0 {
+		recommended = DefaultRecommendedPowerPlans
+	}
+	for _, detail := range details {
+		if detail.Name != "OS" {
+			continue
+		}
+		for _, field := range detail.Fields {
+			plan := field[PowerProfileSettingRule]
+			if plan == "" || plan == "unknown" {
+				field[PowerPlanRecommendedField] = "unknown"
+				continue
+			}
+			matched := false
+			for _, r := range recommended {
+				if strings.EqualFold(strings.TrimSpace(r), plan) {
+					matched = true
+					break
+				}
+			}
+			field[PowerPlanRecommendedField] = strconv.FormatBool(matched)
+		}
+	}
+}
+
+// AgentResourceUsageName is the name of the details of the resource usage of the agent.
+const AgentResourceUsageName = "AGENT_RESOURCE_USAGE"
+
+// derivedDetailFields are the fields of the details that are not collected by a master rule: the
+// guest rules, the edition of SQL Server, the details derived from other details, the collection
+// manifest and the resource usage of the agent.
+var derivedDetailFields = map[string][]string{
+	"OS": {PowerProfileSettingRule, LocalSSDRule, DataDiskAllocationUnitsRule, GCBDRAgentRunning, SQLServiceAccountRule,
+		PageFileRule, SQLNetworkProtocolsRule, SQLAgentServiceRule, WSFCClusterRule, MSSQLConfRule, HostUtilizationRule,
+		ContainerRule, PowerPlanRecommendedField},
+	"SQL_EDITION":                    {"edition"},
+	"DB_MEMORY_ALLOCATION":           {"max_server_memory_mb", "physical_memory_mb", "max_server_memory_percent", "os_memory_mb", "memory_allocation"},
+	"DB_SQL_VOLUME_ALLOCATION_UNITS": {"volume", "allocation_unit_size", "file_types", "is_recommended_size"},
+	"DB_SQL_VOLUME_FREE_SPACE":       {"volume", "file_types", "size_bytes", "free_bytes", "free_percent", "is_below_threshold"},
+	"DB_DATA_LOG_SAME_DISK":          {"db_name", "data_drives", "log_drives", "same_disk"},
+	CollectionManifestName:           {"status", "reason", "count", "rules"},
+	AgentResourceUsageName:           {"agent_version", "cpu_time_seconds", "rss_bytes", "goroutines", "open_connections"},
+}
+
+// sqlInstanceFields are the fields the SQL collection adds to the rows of the details of an
+// instance.
+var sqlInstanceFields = []string{"host_name", "port_number"}
+
+// DetailNames returns the names of the details of the master rules and the details that are not
+// collected by a master rule.
+func DetailNames() []string {
+	var names []string
+	for name := range derivedDetailFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, rule := range MasterRules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// DetailFields returns the names of the fields of each detail. The fields of a master rule are
+// the fields its Results returns for an empty result and for a row of NULL columns.
+func DetailFields() map[string][]string {
+	details := map[string][]string{}
+	for name, fields := range derivedDetailFields {
+		details[name] = append([]string{}, fields...)
+		if name != "OS" && name != AgentResourceUsageName {
+			details[name] = append(details[name], sqlInstanceFields...)
+		}
+	}
+	nullRow := [][]any{make([]any, 64)}
+	for _, rule := range MasterRules {
+		fields := map[string]bool{}
+		for _, result := range [][][]any{nil, nullRow} {
+			// The post-processors of the master rules are known to resolve, see
+			// TestMasterRulesPostProcessorsResolve.
+			rows, _ := rule.Results(result, RuleSettings{})
+			for _, row := range rows {
+				for field := range row {
+					fields[field] = true
+				}
+			}
+		}
+		names := append([]string{}, sqlInstanceFields...)
+		for field := range fields {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+		details[rule.Name] = names
+	}
+	return details
+}
+
+// FieldRename renames a field of the details of a rule before the details are exported.
+type FieldRename struct {
+	Rule  string
+	Field string
+	Name  string
+}
+
+// ValidateFieldRenames returns an error if a rename misses its rule, field or name, renames a rule
+// or a field that is not in details, which maps the rules to their fields, or collides: a field
+// renamed twice, two fields renamed to the same name or a field renamed to the name of another
+// field of the rule.
+func ValidateFieldRenames(renames []FieldRename, details map[string][]string) error {
+	type ruleField struct{ rule, field string }
+	fields := map[ruleField]bool{}
+	names := map[ruleField]bool{}
+	for _, r := range renames {
+		if r.Rule == "" || r.Field == "" || r.Name == "" {
+			return errors.New("rename requires a rule, a field and a name")
+		}
+		ruleFields, ok := details[r.Rule]
+		if !ok {
+			return fmt.Errorf("unknown rule %q", r.Rule)
+		}
+		known := stringSet(ruleFields)
+		if !known[r.Field] {
+			return fmt.Errorf("unknown field %q of %s", r.Field, r.Rule)
+		}
+		if r.Name != r.Field && known[r.Name] {
+			return fmt.Errorf("field %q of %s is renamed to %q, which is a field of %s too", r.Field, r.Rule, r.Name, r.Rule)
+		}
+		field, name := ruleField{r.Rule, r.Field}, ruleField{r.Rule, r.Name}
+		if fields[field] {
+			return fmt.Errorf("field %q of %s is renamed more than once", r.Field, r.Rule)
+		}
+		if names[name] {
+			return fmt.Errorf("more than one field of %s is renamed to %q", r.Rule, r.Name)
+		}
+		fields[field], names[name] = true, true
+	}
+	return nil
+}
+
+// RenameFields returns the details with their fields renamed by the renames. A field keeps its
+// name if the row has another field with the new name, so that no value is lost. The details of
+// the caller are not modified.
+func RenameFields(details []Details, renames []FieldRename) []Details {
+	if len(renames) == 0 {
+		return details
+	}
+	byRule := map[string]map[string]string{}
+	for _, r := range renames {
+		if byRule[r.Rule] == nil {
+			byRule[r.Rule] = map[string]string{}
+		}
+		byRule[r.Rule][r.Field] = r.Name
+	}
+	res := make([]Details, len(details))
+	for i, d := range details {
+		ruleRenames, ok := byRule[d.Name]
+		if !ok {
+			res[i] = d
+			continue
+		}
+		fields := make([]map[string]string, len(d.Fields))
+		for j, f := range d.Fields {
+			renamed := make(map[string]string, len(f))
+			for k, v := range f {
+				if _, ok := ruleRenames[k]; !ok {
+					renamed[k] = v
+				}
+			}
+			for k, v := range f {
+				name, ok := ruleRenames[k]
+				if !ok {
+					continue
+				}
+				if _, exists := renamed[name]; exists {
+					name = k
+				}
+				renamed[name] = v
+			}
+			fields[j] = renamed
+		}
+		res[i] = Details{Name: d.Name, Fields: fields}
+	}
+	return res
+}
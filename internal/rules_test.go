@@ -17,13 +17,19 @@ limitations under the License.
 package internal
 
 import (
+	"fmt"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
 
 func TestFields(t *testing.T) {
+	settings := DefaultRuleSettings()
+	settings.AgentTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
 	testcases := []struct {
 		name    string
 		windows bool
@@ -100,6 +106,20 @@ func TestFields(t *testing.T) {
 					int64(0),
 					float64(1.0),
 				},
+				{
+					"test_db_name_2",
+					int64(1001),
+					float64(500.5),
+					int64(2),
+					float64(1.0),
+				},
+				{
+					"test_db_name_3",
+					nil,
+					nil,
+					nil,
+					nil,
+				},
 			},
 			want: []map[string]string{
 				{
@@ -108,6 +128,23 @@ func TestFields(t *testing.T) {
 					"vlf_size_in_mb":        "1.000000",
 					"active_vlf_count":      "0",
 					"active_vlf_size_in_mb": "1.000000",
+					"exceeds_threshold":     "false",
+				},
+				{
+					"db_name":               "test_db_name_2",
+					"vlf_count":             "1001",
+					"vlf_size_in_mb":        "500.500000",
+					"active_vlf_count":      "2",
+					"active_vlf_size_in_mb": "1.000000",
+					"exceeds_threshold":     "true",
+				},
+				{
+					"db_name":               "test_db_name_3",
+					"vlf_count":             "unknown",
+					"vlf_size_in_mb":        "unknown",
+					"active_vlf_count":      "unknown",
+					"active_vlf_size_in_mb": "unknown",
+					"exceeds_threshold":     "unknown",
 				},
 			},
 		},
@@ -216,11 +253,1794 @@ func TestFields(t *testing.T) {
 				},
 			},
 		},
-	}
-	for idx, tc := range testcases {
-		got := MasterRules[idx].Fields(tc.input)
-		if diff := cmp.Diff(got, tc.want); diff != "" {
-			t.Errorf("Fields() for rule %s returned wrong result (-got +want):\n%s", MasterRules[idx].Name, diff)
+		{
+			name: "DB_PATCH_LEVEL",
+			input: [][]any{
+				{
+					"16.0.4095.4",
+					"RTM",
+					"CU11",
+					nil,
+				},
+			},
+			want: []map[string]string{
+				{
+					"product_version":          "16.0.4095.4",
+					"product_level":            "RTM",
+					"product_update_level":     "CU11",
+					"product_update_reference": "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_DEADLOCK_COUNT",
+			input: [][]any{
+				{
+					testRingBuffer,
+					time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+				},
+			},
+			want: []map[string]string{
+				{
+					"deadlock_count":        "2",
+					"latest_deadlock_graph": "<deadlock><victim-list></victim-list></deadlock>",
+				},
+			},
+		},
+		{
+			name: "DB_LOCK_PAGES_IN_MEMORY",
+			input: [][]any{
+				{"LOCK_PAGES"},
+				{"LARGE_PAGES"},
+				{"CONVENTIONAL"},
+				{nil},
+			},
+			want: []map[string]string{
+				{
+					"memory_model":         "LOCK_PAGES",
+					"lock_pages_in_memory": "true",
+				},
+				{
+					"memory_model":         "LARGE_PAGES",
+					"lock_pages_in_memory": "true",
+				},
+				{
+					"memory_model":         "CONVENTIONAL",
+					"lock_pages_in_memory": "false",
+				},
+				{
+					"memory_model":         "unknown",
+					"lock_pages_in_memory": "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_TDE_STATUS",
+			input: [][]any{
+				{"test_db_name", true, int64(3), "AES", int64(256)},
+				{"test_db_name_2", false, int64(0), nil, nil},
+				{"test_db_name_3", false, nil, nil, nil},
+			},
+			want: []map[string]string{
+				{
+					"db_name":             "test_db_name",
+					"is_encrypted":        "true",
+					"encryption_state":    "ENCRYPTED",
+					"key_algorithm":       "AES",
+					"key_length":          "256",
+					"violates_tde_policy": "false",
+				},
+				{
+					"db_name":             "test_db_name_2",
+					"is_encrypted":        "false",
+					"encryption_state":    "NO_DATABASE_ENCRYPTION_KEY",
+					"key_algorithm":       "unknown",
+					"key_length":          "unknown",
+					"violates_tde_policy": "false",
+				},
+				{
+					"db_name":             "test_db_name_3",
+					"is_encrypted":        "false",
+					"encryption_state":    "unknown",
+					"key_algorithm":       "unknown",
+					"key_length":          "unknown",
+					"violates_tde_policy": "false",
+				},
+			},
+		},
+		{
+			name: "DB_OWNER_AND_ORPHANED_USERS",
+			input: [][]any{
+				{"test_db_name", "sa", true, false, false, int64(0)},
+				{"test_db_name_2", "DOMAIN\\former_employee", false, nil, nil, int64(2)},
+				{"test_db_name_3", "test_login", true, true, true, nil},
+			},
+			want: []map[string]string{
+				{
+					"db_name":              "test_db_name",
+					"owner_name":           "sa",
+					"owner_exists":         "true",
+					"owner_is_disabled":    "false",
+					"owner_is_expired":     "false",
+					"orphaned_users_count": "0",
+				},
+				{
+					"db_name":              "test_db_name_2",
+					"owner_name":           "DOMAIN\\former_employee",
+					"owner_exists":         "false",
+					"owner_is_disabled":    "unknown",
+					"owner_is_expired":     "unknown",
+					"orphaned_users_count": "2",
+				},
+				{
+					"db_name":              "test_db_name_3",
+					"owner_name":           "test_login",
+					"owner_exists":         "true",
+					"owner_is_disabled":    "true",
+					"owner_is_expired":     "true",
+					"orphaned_users_count": "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_LOGIN_AUDIT",
+			input: [][]any{
+				{int64(2), true, int64(0), int64(3)},
+				{int64(2), true, int64(59), int64(4)},
+				{int64(2), true, int64(60), int64(100)},
+			},
+			want: []map[string]string{
+				{
+					"audit_level":          "FAILED_LOGINS_ONLY",
+					"audits_failed_logins": "true",
+					"failed_login_count":   "7",
+				},
+			},
+		},
+		{
+			name: "DB_USER_CONNECTIONS",
+			input: [][]any{
+				{"sqlcmd", int64(3), int64(1), int64(12), int64(0)},
+				{nil, int64(9), int64(0), int64(12), int64(0)},
+			},
+			want: []map[string]string{
+				{
+					"program_name":         "sqlcmd",
+					"sessions":             "3",
+					"active_sessions":      "1",
+					"total_connections":    "12",
+					"max_user_connections": "32767",
+				},
+				{
+					"program_name":         "unknown",
+					"sessions":             "9",
+					"active_sessions":      "0",
+					"total_connections":    "12",
+					"max_user_connections": "32767",
+				},
+			},
+		},
+		{
+			name: "DB_QUERY_STORE",
+			input: [][]any{
+				{"db1", "OFF"},
+				{"db2", "READ_WRITE"},
+				{"db3", "not_supported"},
+				{"db4", nil},
+			},
+			want: []map[string]string{
+				{"db_name": "db1", "actual_state": "OFF", "query_store_off": "true"},
+				{"db_name": "db2", "actual_state": "READ_WRITE", "query_store_off": "false"},
+				{"db_name": "db3", "actual_state": "not_supported", "query_store_off": "false"},
+				{"db_name": "db4", "actual_state": "unknown", "query_store_off": "unknown"},
+			},
+		},
+		{
+			name: "DB_WAIT_STATS",
+			input: [][]any{
+				{"SLEEP_TASK", int64(100), int64(90000), int64(10)},
+				{"PAGEIOLATCH_SH", int64(20), int64(5000), int64(100)},
+				{"WRITELOG", int64(10), int64(1000), nil},
+			},
+			want: []map[string]string{
+				{"wait_type": "PAGEIOLATCH_SH", "waiting_tasks_count": "20", "wait_time_ms": "5000", "signal_wait_time_ms": "100"},
+				{"wait_type": "WRITELOG", "waiting_tasks_count": "10", "wait_time_ms": "1000", "signal_wait_time_ms": "unknown"},
+			},
+		},
+		{
+			name:  "DB_CLOCK_SKEW",
+			input: [][]any{{time.Date(2024, 1, 1, 12, 0, 2, 0, time.UTC)}},
+			want: []map[string]string{{
+				"server_utc_time":    "2024-01-01T12:00:02Z",
+				"agent_utc_time":     "2024-01-01T12:00:00Z",
+				"skew_seconds":       "2.000",
+				"is_above_threshold": "false",
+			}},
+		},
+		{
+			name: "DB_LICENSED_CORES",
+			input: [][]any{
+				{"Standard Edition (64-bit)", int64(32), int64(24), int64(8)},
+				{"Enterprise Edition: Core-based Licensing (64-bit)", int64(16), int64(16), int64(0)},
+				{"Developer Edition (64-bit)", nil, nil, nil},
+			},
+			want: []map[string]string{
+				{
+					"edition":                    "Standard Edition (64-bit)",
+					"cpu_count":                  "32",
+					"visible_online_schedulers":  "24",
+					"visible_offline_schedulers": "8",
+					"sql_visible_cores":          "24",
+					"limited_by_license":         "true",
+					"vm_vcpu_count":              "unknown",
+					"uses_all_vcpus":             "unknown",
+				},
+				{
+					"edition":                    "Enterprise Edition: Core-based Licensing (64-bit)",
+					"cpu_count":                  "16",
+					"visible_online_schedulers":  "16",
+					"visible_offline_schedulers": "0",
+					"sql_visible_cores":          "16",
+					"limited_by_license":         "false",
+					"vm_vcpu_count":              "unknown",
+					"uses_all_vcpus":             "unknown",
+				},
+				{
+					"edition":                    "Developer Edition (64-bit)",
+					"cpu_count":                  "unknown",
+					"visible_online_schedulers":  "unknown",
+					"visible_offline_schedulers": "unknown",
+					"sql_visible_cores":          "unknown",
+					"limited_by_license":         "unknown",
+					"vm_vcpu_count":              "unknown",
+					"uses_all_vcpus":             "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_DATABASE_FILES",
+			input: [][]any{
+				{"db1", "db1", "ROWS", `D:\data\db1.mdf`, int64(1048576), int64(524288), int64(-1), int64(8192), false},
+				{"db1", "db1_log", "LOG", `L:\log\db1_log.ldf`, int64(262144), nil, int64(2147483648), int64(10), true},
+				{"db2", "db2", "ROWS", `D:\data\db2.mdf`, int64(8192), int64(4096), int64(8192), int64(0), false},
+			},
+			want: []map[string]string{
+				{
+					"db_name":           "db1",
+					"file_name":         "db1",
+					"type":              "ROWS",
+					"physical_name":     `D:\data\db1.mdf`,
+					"size_kb":           "1048576",
+					"used_kb":           "524288",
+					"max_size_kb":       "unlimited",
+					"growth_type":       "fixed",
+					"growth_kb":         "65536",
+					"growth_percent":    "0",
+					"is_percent_growth": "false",
+				},
+				{
+					"db_name":           "db1",
+					"file_name":         "db1_log",
+					"type":              "LOG",
+					"physical_name":     `L:\log\db1_log.ldf`,
+					"size_kb":           "262144",
+					"used_kb":           "unknown",
+					"max_size_kb":       "2147483648",
+					"growth_type":       "percent",
+					"growth_kb":         "0",
+					"growth_percent":    "10",
+					"is_percent_growth": "true",
+				},
+				{
+					"db_name":           "db2",
+					"file_name":         "db2",
+					"type":              "ROWS",
+					"physical_name":     `D:\data\db2.mdf`,
+					"size_kb":           "8192",
+					"used_kb":           "4096",
+					"max_size_kb":       "8192",
+					"growth_type":       "disabled",
+					"growth_kb":         "0",
+					"growth_percent":    "0",
+					"is_percent_growth": "false",
+				},
+			},
+		},
+		{
+			name: "DB_OPTIMIZE_FOR_AD_HOC_WORKLOADS",
+			input: [][]any{
+				{int64(0), int64(4500), int64(327680), int64(6000), int64(524288), float64(75), float64(62.5)},
+				{int64(1), int64(0), int64(0), int64(0), int64(0), float64(0), float64(0)},
+				{int64(0), nil, nil, nil, nil, nil, nil},
+			},
+			want: []map[string]string{
+				{
+					"optimize_for_ad_hoc_workloads": "false",
+					"single_use_plan_count":         "4500",
+					"single_use_plan_size_kb":       "327680",
+					"cached_plan_count":             "6000",
+					"plan_cache_size_kb":            "524288",
+					"single_use_plan_percent":       "75.000000",
+					"single_use_plan_size_percent":  "62.500000",
+				},
+				{
+					"optimize_for_ad_hoc_workloads": "true",
+					"single_use_plan_count":         "0",
+					"single_use_plan_size_kb":       "0",
+					"cached_plan_count":             "0",
+					"plan_cache_size_kb":            "0",
+					"single_use_plan_percent":       "0.000000",
+					"single_use_plan_size_percent":  "0.000000",
+				},
+				{
+					"optimize_for_ad_hoc_workloads": "false",
+					"single_use_plan_count":         "unknown",
+					"single_use_plan_size_kb":       "unknown",
+					"cached_plan_count":             "unknown",
+					"plan_cache_size_kb":            "unknown",
+					"single_use_plan_percent":       "unknown",
+					"single_use_plan_size_percent":  "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_AUTHENTICATION_MODE",
+			input: [][]any{
+				{int64(1)},
+				{int64(0)},
+				{nil},
+			},
+			want: []map[string]string{
+				{"is_integrated_security_only": "true", "authentication_mode": "windows"},
+				{"is_integrated_security_only": "false", "authentication_mode": "mixed"},
+				{"is_integrated_security_only": "unknown", "authentication_mode": "unknown"},
+			},
+		},
+		{
+			name: "DB_DEFAULT_DIRECTORIES",
+			input: [][]any{
+				{`D:\data\`, `L:\log\`, `B:\backup\`},
+				{"/var/opt/mssql/data/", "/var/opt/mssql/data/", nil},
+			},
+			want: []map[string]string{
+				{"data_directory": `D:\data\`, "log_directory": `L:\log\`, "backup_directory": `B:\backup\`},
+				{"data_directory": "/var/opt/mssql/data/", "log_directory": "/var/opt/mssql/data/", "backup_directory": "unknown"},
+			},
+		},
+		{
+			name: "DB_TEMPDB_CONTENTION",
+			input: [][]any{
+				{int64(12), int64(9), int64(350), int64(1), int64(16)},
+				{int64(0), int64(0), int64(0), int64(8), int64(16)},
+				{nil, nil, nil, int64(4), nil},
+			},
+			want: []map[string]string{
+				{
+					"pagelatch_waits":       "12",
+					"allocation_page_waits": "9",
+					"max_wait_ms":           "350",
+					"allocation_contention": "true",
+					"tempdb_data_files":     "1",
+					"cpu_count":             "16",
+					"too_few_data_files":    "true",
+				},
+				{
+					"pagelatch_waits":       "0",
+					"allocation_page_waits": "0",
+					"max_wait_ms":           "0",
+					"allocation_contention": "false",
+					"tempdb_data_files":     "8",
+					"cpu_count":             "16",
+					"too_few_data_files":    "false",
+				},
+				{
+					"pagelatch_waits":       "unknown",
+					"allocation_page_waits": "unknown",
+					"max_wait_ms":           "unknown",
+					"allocation_contention": "unknown",
+					"tempdb_data_files":     "4",
+					"cpu_count":             "unknown",
+					"too_few_data_files":    "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_FAILOVER_CLUSTER",
+			input: [][]any{
+				{true, "NODE2", "SQLFCI", false},
+				{false, "SQLVM", "SQLVM", true},
+				{nil, nil, nil, nil},
+			},
+			want: []map[string]string{
+				{"is_clustered": "true", "active_node": "NODE2", "virtual_server_name": "SQLFCI", "is_hadr_enabled": "false", "configured_as_fci": "unknown"},
+				{"is_clustered": "false", "active_node": "SQLVM", "virtual_server_name": "SQLVM", "is_hadr_enabled": "true", "configured_as_fci": "unknown"},
+				{"is_clustered": "unknown", "active_node": "unknown", "virtual_server_name": "unknown", "is_hadr_enabled": "unknown", "configured_as_fci": "unknown"},
+			},
+		},
+		{
+			name: "DB_RESOURCE_GOVERNOR",
+			input: [][]any{
+				{true, "rg_classifier", "reporting", int64(0), int64(30), int64(40), int64(0), int64(25)},
+				{true, "rg_classifier", "default", int64(0), int64(100), int64(100), int64(0), int64(100)},
+				{false, nil, nil, nil, nil, nil, nil, nil},
+			},
+			want: []map[string]string{
+				{
+					"enabled":             "true",
+					"classifier_function": "rg_classifier",
+					"pool_name":           "reporting",
+					"min_cpu_percent":     "0",
+					"max_cpu_percent":     "30",
+					"cap_cpu_percent":     "40",
+					"min_memory_percent":  "0",
+					"max_memory_percent":  "25",
+					"capped":              "true",
+				},
+				{
+					"enabled":             "true",
+					"classifier_function": "rg_classifier",
+					"pool_name":           "default",
+					"min_cpu_percent":     "0",
+					"max_cpu_percent":     "100",
+					"cap_cpu_percent":     "100",
+					"min_memory_percent":  "0",
+					"max_memory_percent":  "100",
+					"capped":              "false",
+				},
+				{
+					"enabled":             "false",
+					"classifier_function": "unknown",
+					"pool_name":           "unknown",
+					"min_cpu_percent":     "unknown",
+					"max_cpu_percent":     "unknown",
+					"cap_cpu_percent":     "unknown",
+					"min_memory_percent":  "unknown",
+					"max_memory_percent":  "unknown",
+					"capped":              "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_TOP_QUERIES",
+			input: [][]any{
+				{"0x1A2B3C4D5E6F7081", int64(1200), int64(98000), int64(4500000), int64(120000), "SELECT * FROM orders WHERE customer_id = @p1"},
+				{"0x0102030405060708", int64(3), int64(10), int64(20), int64(15), nil},
+			},
+			want: []map[string]string{
+				{
+					"query_hash":            "0x1A2B3C4D5E6F7081",
+					"execution_count":       "1200",
+					"total_worker_time_ms":  "98000",
+					"total_logical_reads":   "4500000",
+					"total_elapsed_time_ms": "120000",
+					"query_text":            "SELECT * FROM orders WHERE customer_id = @p1",
+				},
+				{
+					"query_hash":            "0x0102030405060708",
+					"execution_count":       "3",
+					"total_worker_time_ms":  "10",
+					"total_logical_reads":   "20",
+					"total_elapsed_time_ms": "15",
+					"query_text":            "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_BACKUP_DEFAULTS",
+			input: [][]any{
+				{int64(1), int64(0)},
+				{int64(0), nil},
+			},
+			want: []map[string]string{
+				{"backup_compression_default": "true", "backup_checksum_default": "false"},
+				{"backup_compression_default": "false", "backup_checksum_default": "unknown"},
+			},
+		},
+		{
+			name: "DB_DATABASE_SNAPSHOTS",
+			input: [][]any{
+				{"db1_snapshot", "db1", "ONLINE", int64(720), int64(65536), int64(1048576)},
+				{"db2_snapshot", nil, "SUSPECT", int64(2), nil, int64(8192)},
+			},
+			want: []map[string]string{
+				{
+					"snapshot_name":   "db1_snapshot",
+					"source_database": "db1",
+					"state":           "ONLINE",
+					"age_hours":       "720",
+					"size_on_disk_kb": "65536",
+					"max_size_kb":     "1048576",
+				},
+				{
+					"snapshot_name":   "db2_snapshot",
+					"source_database": "unknown",
+					"state":           "SUSPECT",
+					"age_hours":       "2",
+					"size_on_disk_kb": "unknown",
+					"max_size_kb":     "8192",
+				},
+			},
+		},
+		{
+			name: "DB_DATABASE_STATE",
+			input: [][]any{
+				{"db1", "SUSPECT", false},
+				{"db2", "ONLINE", true},
+				{"db3", "RECOVERY_PENDING", nil},
+			},
+			want: []map[string]string{
+				{"db_name": "db1", "state": "SUSPECT", "is_read_only": "false"},
+				{"db_name": "db2", "state": "ONLINE", "is_read_only": "true"},
+				{"db_name": "db3", "state": "RECOVERY_PENDING", "is_read_only": "unknown"},
+			},
+		},
+		{
+			name: "DB_AUTO_UPDATE_STATISTICS",
+			input: [][]any{
+				{"db1", true, false},
+				{"db2", false, true},
+				{"db3", nil, nil},
+			},
+			want: []map[string]string{
+				{"db_name": "db1", "auto_update_stats": "true", "auto_update_stats_async": "false", "auto_update_disabled": "false"},
+				{"db_name": "db2", "auto_update_stats": "false", "auto_update_stats_async": "true", "auto_update_disabled": "true"},
+				{"db_name": "db3", "auto_update_stats": "unknown", "auto_update_stats_async": "unknown", "auto_update_disabled": "unknown"},
+			},
+		},
+		{
+			name: "DB_XE_SESSIONS",
+			input: [][]any{
+				{"AlwaysOn_health", false, false, "event_file"},
+				{"hkenginexesession", true, nil, nil},
+				{"system_health", true, true, "event_file,ring_buffer"},
+			},
+			want: []map[string]string{
+				{"session_name": "AlwaysOn_health", "running": "false", "start_on_server_startup": "false", "targets": "event_file"},
+				{"session_name": "hkenginexesession", "running": "true", "start_on_server_startup": "unknown", "targets": ""},
+				{"session_name": "system_health", "running": "true", "start_on_server_startup": "true", "targets": "event_file,ring_buffer"},
+			},
+		},
+		{
+			name: "DB_MIRRORING_LOG_SHIPPING",
+			input: [][]any{
+				{"db1", "MIRRORING", "PRINCIPAL", "TCP://mirror.example.com:5022", "DISCONNECTED", nil, true},
+				{"db2", "LOG_SHIPPING", "PRIMARY", nil, "SYNCHRONIZED", int64(12), false},
+				{"db3", "LOG_SHIPPING", "SECONDARY", "primary-sql", "NEVER_RESTORED", nil, true},
+			},
+			want: []map[string]string{
+				{"db_name": "db1", "ha_type": "MIRRORING", "role": "PRINCIPAL", "partner": "TCP://mirror.example.com:5022", "state": "DISCONNECTED", "minutes_since_last_sync": "unknown", "unsynchronized": "true"},
+				{"db_name": "db2", "ha_type": "LOG_SHIPPING", "role": "PRIMARY", "partner": "unknown", "state": "SYNCHRONIZED", "minutes_since_last_sync": "12", "unsynchronized": "false"},
+				{"db_name": "db3", "ha_type": "LOG_SHIPPING", "role": "SECONDARY", "partner": "primary-sql", "state": "NEVER_RESTORED", "minutes_since_last_sync": "unknown", "unsynchronized": "true"},
+			},
+		},
+		{
+			name: "DB_FILL_FACTOR",
+			input: [][]any{
+				{int64(0), int64(0)},
+				{int64(80), int64(80)},
+				{int64(100), int64(90)},
+				{nil, nil},
+			},
+			want: []map[string]string{
+				{"configured_fill_factor": "0", "fill_factor": "0", "is_default": "true"},
+				{"configured_fill_factor": "80", "fill_factor": "80", "is_default": "false"},
+				{"configured_fill_factor": "100", "fill_factor": "90", "is_default": "false"},
+				{"configured_fill_factor": "unknown", "fill_factor": "unknown", "is_default": "unknown"},
+			},
+		},
+		{
+			name: "DB_INDEX_FILL_FACTOR",
+			input: [][]any{
+				{"sales", "dbo.orders", "ix_orders_customer", int64(70), int64(25600)},
+				{"sales", "dbo.events", nil, int64(90), int64(1000)},
+			},
+			want: []map[string]string{
+				{
+					"db_name":     "sales",
+					"table_name":  "dbo.orders",
+					"index_name":  "ix_orders_customer",
+					"fill_factor": "70",
+					"page_count":  "25600",
+				},
+				{
+					"db_name":     "sales",
+					"table_name":  "dbo.events",
+					"index_name":  "unknown",
+					"fill_factor": "90",
+					"page_count":  "1000",
+				},
+			},
+		},
+		{
+			name: "DB_LAST_CHECKDB",
+			input: [][]any{
+				{"master", "2024-01-14 02:00:13.457", "property", "2024-01-15 10:00:00.000"},
+				{"sales", "2024-01-02 02:00:00.000", "dbinfo", "2024-01-15 10:00:00.000"},
+				{"staging", "1900-01-01 00:00:00.000", "dbinfo", "2024-01-15 10:00:00.000"},
+				{"archive", nil, "unknown", "2024-01-15 10:00:00.000"},
+			},
+			want: []map[string]string{
+				{
+					"db_name":                      "master",
+					"source":                       "property",
+					"last_good_checkdb":            "2024-01-14T02:00:13",
+					"days_since_last_good_checkdb": "1",
+					"checkdb_overdue":              "false",
+				},
+				{
+					"db_name":                      "sales",
+					"source":                       "dbinfo",
+					"last_good_checkdb":            "2024-01-02T02:00:00",
+					"days_since_last_good_checkdb": "13",
+					"checkdb_overdue":              "true",
+				},
+				{
+					"db_name":                      "staging",
+					"source":                       "dbinfo",
+					"last_good_checkdb":            "never",
+					"days_since_last_good_checkdb": "unknown",
+					"checkdb_overdue":              "true",
+				},
+				{
+					"db_name":                      "archive",
+					"source":                       "unknown",
+					"last_good_checkdb":            "unknown",
+					"days_since_last_good_checkdb": "unknown",
+					"checkdb_overdue":              "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_WORKER_THREADS",
+			input: [][]any{
+				{int64(0), int64(0), int64(576), int64(540), int64(500), int64(40), int64(12), float64(93.75)},
+				{int64(0), int64(0), nil, nil, nil, nil, nil, nil},
+			},
+			want: []map[string]string{
+				{
+					"configured_max_worker_threads": "0",
+					"max_worker_threads":            "0",
+					"max_workers_count":             "576",
+					"current_workers":               "540",
+					"active_workers":                "500",
+					"idle_workers":                  "40",
+					"queued_tasks":                  "12",
+					"worker_utilization_percent":    "93.750000",
+				},
+				{
+					"configured_max_worker_threads": "0",
+					"max_worker_threads":            "0",
+					"max_workers_count":             "unknown",
+					"current_workers":               "unknown",
+					"active_workers":                "unknown",
+					"idle_workers":                  "unknown",
+					"queued_tasks":                  "unknown",
+					"worker_utilization_percent":    "unknown",
+				},
+			},
+		},
+		{
+			name: "DB_PAGE_VERIFY",
+			input: [][]any{
+				{"db1", "CHECKSUM"},
+				{"db2", "TORN_PAGE_DETECTION"},
+				{"db3", "NONE"},
+				{"db4", nil},
+			},
+			want: []map[string]string{
+				{"db_name": "db1", "page_verify_option": "CHECKSUM", "page_verify_not_checksum": "false"},
+				{"db_name": "db2", "page_verify_option": "TORN_PAGE_DETECTION", "page_verify_not_checksum": "true"},
+				{"db_name": "db3", "page_verify_option": "NONE", "page_verify_not_checksum": "true"},
+				{"db_name": "db4", "page_verify_option": "unknown", "page_verify_not_checksum": "unknown"},
+			},
+		},
+	}
+	for idx, tc := range testcases {
+		got := MasterRules[idx].Fields(tc.input, settings)
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("Fields() for rule %s returned wrong result (-got +want):\n%s", MasterRules[idx].Name, diff)
+		}
+	}
+}
+
+const testRingBuffer = `<RingBufferTarget truncated="0">
+	<event name="xml_deadlock_report" package="sqlserver" timestamp="2024-05-01T10:00:00.000Z">
+		<data name="xml_report"><value><deadlock><process-list></process-list></deadlock></value></data>
+	</event>
+	<event name="sp_server_diagnostics_component_result" package="sqlserver" timestamp="2024-05-01T11:10:00.000Z">
+		<data name="data"><value>any</value></data>
+	</event>
+	<event name="xml_deadlock_report" package="sqlserver" timestamp="2024-05-01T11:20:00.000Z">
+		<data name="xml_report"><value><deadlock><resource-list></resource-list></deadlock></value></data>
+	</event>
+	<event name="xml_deadlock_report" package="sqlserver" timestamp="2024-05-01T11:30:00.000Z">
+		<data name="xml_report"><value><deadlock><victim-list></victim-list></deadlock></value></data>
+	</event>
+</RingBufferTarget>`
+
+// ruleByName returns the master rule with the given name.
+func ruleByName(t *testing.T, name string) MasterRuleStruct {
+	t.Helper()
+	for _, r := range MasterRules {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("No master rule named %q", name)
+	return MasterRuleStruct{}
+}
+
+func TestDeadlockCountFields(t *testing.T) {
+	rule := ruleByName(t, "DB_DEADLOCK_COUNT")
+	unknown := []map[string]string{
+		{
+			"deadlock_count":        "unknown",
+			"latest_deadlock_graph": "unknown",
+		},
+	}
+	testcases := []struct {
+		name  string
+		input [][]any
+		want  []map[string]string
+	}{
+		{
+			name:  "session not running",
+			input: [][]any{},
+			want:  unknown,
+		},
+		{
+			name:  "target data is nil",
+			input: [][]any{{nil, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}},
+			want:  unknown,
+		},
+		{
+			name:  "invalid target data",
+			input: [][]any{{"<RingBufferTarget>", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}},
+			want:  unknown,
+		},
+		{
+			name:  "no deadlock in window",
+			input: [][]any{{testRingBuffer, time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)}},
+			want: []map[string]string{
+				{
+					"deadlock_count":        "0",
+					"latest_deadlock_graph": "",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rule.Fields(tc.input, DefaultRuleSettings())
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("Fields() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTDEStatusFieldsWithPolicy(t *testing.T) {
+	rule := ruleByName(t, "DB_TDE_STATUS")
+	settings := DefaultRuleSettings()
+	settings.ExpectTDEEncryption = true
+
+	got := rule.Fields([][]any{
+		{"test_db_name", true, int64(3), "AES", int64(256)},
+		{"test_db_name_2", false, int64(0), nil, nil},
+		{"test_db_name_3", nil, nil, nil, nil},
+	}, settings)
+	want := []map[string]string{
+		{
+			"db_name":             "test_db_name",
+			"is_encrypted":        "true",
+			"encryption_state":    "ENCRYPTED",
+			"key_algorithm":       "AES",
+			"key_length":          "256",
+			"violates_tde_policy": "false",
+		},
+		{
+			"db_name":             "test_db_name_2",
+			"is_encrypted":        "false",
+			"encryption_state":    "NO_DATABASE_ENCRYPTION_KEY",
+			"key_algorithm":       "unknown",
+			"key_length":          "unknown",
+			"violates_tde_policy": "true",
+		},
+		{
+			"db_name":             "test_db_name_3",
+			"is_encrypted":        "unknown",
+			"encryption_state":    "unknown",
+			"key_algorithm":       "unknown",
+			"key_length":          "unknown",
+			"violates_tde_policy": "unknown",
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Fields() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestLoginAuditFields(t *testing.T) {
+	rule := ruleByName(t, "DB_LOGIN_AUDIT")
+	testcases := []struct {
+		name  string
+		input [][]any
+		want  []map[string]string
+	}{
+		{
+			name:  "no failed logins",
+			input: [][]any{{int64(3), true, nil, nil}},
+			want: []map[string]string{
+				{
+					"audit_level":          "BOTH_FAILED_AND_SUCCESSFUL_LOGINS",
+					"audits_failed_logins": "true",
+					"failed_login_count":   "0",
+				},
+			},
+		},
+		{
+			name:  "auditing disabled and default trace not readable",
+			input: [][]any{{int64(0), false, nil, nil}},
+			want: []map[string]string{
+				{
+					"audit_level":          "NONE",
+					"audits_failed_logins": "false",
+					"failed_login_count":   "unknown",
+				},
+			},
+		},
+		{
+			name:  "registry not readable",
+			input: [][]any{{nil, true, int64(5), int64(2)}},
+			want: []map[string]string{
+				{
+					"audit_level":          "unknown",
+					"audits_failed_logins": "unknown",
+					"failed_login_count":   "2",
+				},
+			},
+		},
+		{
+			name: "no rows",
+			want: []map[string]string{
+				{
+					"audit_level":          "unknown",
+					"audits_failed_logins": "unknown",
+					"failed_login_count":   "unknown",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rule.Fields(tc.input, DefaultRuleSettings())
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("Fields() for rule DB_LOGIN_AUDIT returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEdition(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input [][]any
+		want  string
+	}{
+		{
+			name:  "enterprise",
+			input: [][]any{{int64(3), "Enterprise Edition (64-bit)"}},
+			want:  EditionEnterprise,
+		},
+		{
+			name:  "developer reports enterprise",
+			input: [][]any{{int64(3), "Developer Edition (64-bit)"}},
+			want:  EditionEnterprise,
+		},
+		{
+			name:  "standard",
+			input: [][]any{{int64(2), "Standard Edition (64-bit)"}},
+			want:  EditionStandard,
+		},
+		{
+			name:  "web",
+			input: [][]any{{int64(2), "Web Edition (64-bit)"}},
+			want:  EditionWeb,
+		},
+		{
+			name:  "express",
+			input: [][]any{{int64(4), "Express Edition (64-bit)"}},
+			want:  EditionExpress,
+		},
+		{
+			name:  "unsupported engine edition",
+			input: [][]any{{int64(8), "SQL Azure"}},
+			want:  EditionUnknown,
+		},
+		{
+			name: "no rows",
+			want: EditionUnknown,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Edition(tc.input); got != tc.want {
+				t.Errorf("Edition() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	testcases := []struct {
+		name     string
+		editions []string
+		edition  string
+		want     bool
+	}{
+		{
+			name:    "rule without editions",
+			edition: EditionExpress,
+			want:    true,
+		},
+		{
+			name:     "edition listed",
+			editions: []string{EditionEnterprise, EditionStandard},
+			edition:  EditionStandard,
+			want:     true,
+		},
+		{
+			name:     "edition not listed",
+			editions: []string{EditionEnterprise, EditionStandard},
+			edition:  EditionExpress,
+			want:     false,
+		},
+		{
+			name:     "unknown edition",
+			editions: []string{EditionEnterprise, EditionStandard},
+			edition:  EditionUnknown,
+			want:     true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := MasterRuleStruct{Name: "testRule", Editions: tc.editions}
+			if got := rule.AppliesTo(tc.edition); got != tc.want {
+				t.Errorf("AppliesTo(%q) = %v, want %v", tc.edition, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemoryAllocation(t *testing.T) {
+	details := func(maxServerMemory, physicalMemory string) []Details {
+		return []Details{
+			{Name: "DB_MAX_SERVER_MEMORY", Fields: []map[string]string{{"name": "max server memory (MB)", "value": maxServerMemory, "value_in_use": maxServerMemory}}},
+			{Name: "INSTANCE_METRICS", Fields: []map[string]string{{"physical_memory_kb": physicalMemory}}},
+		}
+	}
+	testcases := []struct {
+		name    string
+		details []Details
+		want    Details
+		wantOK  bool
+	}{
+		{
+			name:    "memory allocation ok",
+			details: details("13107", "16777216"),
+			want: Details{
+				Name: "DB_MEMORY_ALLOCATION",
+				Fields: []map[string]string{{
+					"max_server_memory_mb":      "13107",
+					"physical_memory_mb":        "16384",
+					"max_server_memory_percent": "80.0",
+					"os_memory_mb":              "3277",
+					"memory_allocation":         "OK",
+				}},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "default max server memory leaves too little for os",
+			details: details("2147483647", "16777216"),
+			want: Details{
+				Name: "DB_MEMORY_ALLOCATION",
+				Fields: []map[string]string{{
+					"max_server_memory_mb":      "16384",
+					"physical_memory_mb":        "16384",
+					"max_server_memory_percent": "100.0",
+					"os_memory_mb":              "0",
+					"memory_allocation":         "TOO_LITTLE_FOR_OS",
+				}},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "max server memory leaves too much for os",
+			details: details("4096", "16777216"),
+			want: Details{
+				Name: "DB_MEMORY_ALLOCATION",
+				Fields: []map[string]string{{
+					"max_server_memory_mb":      "4096",
+					"physical_memory_mb":        "16384",
+					"max_server_memory_percent": "25.0",
+					"os_memory_mb":              "12288",
+					"memory_allocation":         "TOO_MUCH_FOR_OS",
+				}},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "physical memory unknown",
+			details: details("4096", "unknown"),
+		},
+		{
+			name:    "max server memory missing",
+			details: details("4096", "16777216")[1:],
+		},
+		{
+			name: "no details",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := MemoryAllocation(tc.details)
+			if ok != tc.wantOK {
+				t.Fatalf("MemoryAllocation() returned ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("MemoryAllocation() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDataLogSameDisk(t *testing.T) {
+	file := func(db, fileType, drive string) map[string]string {
+		return map[string]string{"db_name": db, "filetype": fileType, "physical_name": db + fileType, "physical_drive": drive}
+	}
+	testcases := []struct {
+		name    string
+		details []Details
+		want    Details
+		wantOK  bool
+	}{
+		{
+			name: "data and log on separate and shared drives",
+			details: []Details{{Name: "DB_LOG_DISK_SEPARATION", Fields: []map[string]string{
+				file("sales", "0", "PhysicalDrive1"),
+				file("sales", "1", "PhysicalDrive2"),
+				file("hr", "0", "sda, sdb"),
+				file("hr", "1", "sdb"),
+				file("hr", "2", "sdc"),
+			}}},
+			want: Details{
+				Name: "DB_DATA_LOG_SAME_DISK",
+				Fields: []map[string]string{
+					{"db_name": "hr", "data_drives": "sda,sdb", "log_drives": "sdb", "same_disk": "true"},
+					{"db_name": "sales", "data_drives": "PhysicalDrive1", "log_drives": "PhysicalDrive2", "same_disk": "false"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "incomplete mapping",
+			details: []Details{{Name: "DB_LOG_DISK_SEPARATION", Fields: []map[string]string{
+				file("sales", "0", "PhysicalDrive1"),
+				file("sales", "0", "unknown"),
+				file("sales", "1", "PhysicalDrive2"),
+				file("crm", "0", "PhysicalDrive1"),
+				file("crm", "1", "PhysicalDrive1"),
+				file("crm", "1", ""),
+			}}},
+			want: Details{
+				Name: "DB_DATA_LOG_SAME_DISK",
+				Fields: []map[string]string{
+					{"db_name": "crm", "data_drives": "PhysicalDrive1", "log_drives": "PhysicalDrive1", "same_disk": "true"},
+					{"db_name": "sales", "data_drives": "PhysicalDrive1", "log_drives": "PhysicalDrive2", "same_disk": "unknown"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "no files",
+			details: []Details{{Name: "DB_LOG_DISK_SEPARATION"}},
+		},
+		{
+			name: "no details",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := DataLogSameDisk(tc.details)
+			if ok != tc.wantOK {
+				t.Fatalf("DataLogSameDisk() returned ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("DataLogSameDisk() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSQLVolumeAllocationUnits(t *testing.T) {
+	volumes := []Volume{
+		{Name: `C:\`, AllocationUnitSize: 4096},
+		{Name: `D:\`, AllocationUnitSize: 65536},
+		{Name: `D:\mnt\log\`, AllocationUnitSize: 4096},
+		{Name: `E:\`, AllocationUnitSize: 65536},
+	}
+	testcases := []struct {
+		name    string
+		details []Details
+		want    Details
+		wantOK  bool
+	}{
+		{
+			name: "data and log files mapped to volumes",
+			details: []Details{
+				{
+					Name: "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{
+						{"filetype": "0", "physical_name": `C:\Program Files\Microsoft SQL Server\MSSQL\DATA\master.mdf`},
+						{"filetype": "1", "physical_name": `C:\Program Files\Microsoft SQL Server\MSSQL\DATA\mastlog.ldf`},
+						{"filetype": "0", "physical_name": `d:\data\db.mdf`},
+						{"filetype": "1", "physical_name": `D:\MNT\LOG\db_log.ldf`},
+						{"filetype": "2", "physical_name": `E:\filestream`},
+					},
+				},
+			},
+			want: Details{
+				Name: "DB_SQL_VOLUME_ALLOCATION_UNITS",
+				Fields: []map[string]string{
+					{"volume": `C:\`, "allocation_unit_size": "4096", "file_types": "data,log", "is_recommended_size": "false"},
+					{"volume": `D:\`, "allocation_unit_size": "65536", "file_types": "data", "is_recommended_size": "true"},
+					{"volume": `D:\mnt\log\`, "allocation_unit_size": "4096", "file_types": "log", "is_recommended_size": "false"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "files on unknown volumes",
+			details: []Details{
+				{
+					Name:   "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{{"filetype": "0", "physical_name": `F:\data\db.mdf`}},
+				},
+			},
+		},
+		{
+			name: "default directories mapped to volumes",
+			details: []Details{
+				{
+					Name:   "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{{"filetype": "0", "physical_name": `D:\data\db.mdf`}},
+				},
+				{
+					Name:   "DB_DEFAULT_DIRECTORIES",
+					Fields: []map[string]string{{"data_directory": `E:\data\`, "log_directory": `D:\mnt\log\`, "backup_directory": `C:\backup\`}},
+				},
+			},
+			want: Details{
+				Name: "DB_SQL_VOLUME_ALLOCATION_UNITS",
+				Fields: []map[string]string{
+					{"volume": `D:\`, "allocation_unit_size": "65536", "file_types": "data", "is_recommended_size": "true"},
+					{"volume": `D:\mnt\log\`, "allocation_unit_size": "4096", "file_types": "log", "is_recommended_size": "false"},
+					{"volume": `E:\`, "allocation_unit_size": "65536", "file_types": "data", "is_recommended_size": "true"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "no data and log files",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := SQLVolumeAllocationUnits(tc.details, volumes)
+			if ok != tc.wantOK {
+				t.Fatalf("SQLVolumeAllocationUnits() returned ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("SQLVolumeAllocationUnits() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSQLFilePaths(t *testing.T) {
+	details := []Details{
+		{
+			Name: "DB_LOG_DISK_SEPARATION",
+			Fields: []map[string]string{
+				{"filetype": "0", "physical_name": "/var/opt/mssql/data/master.mdf"},
+				{"filetype": "1", "physical_name": "/var/opt/mssql/log/mastlog.ldf"},
+				{"filetype": "0", "physical_name": "/var/opt/mssql/data/master.mdf"},
+				{"filetype": "2", "physical_name": "/var/opt/mssql/filestream"},
+				{"filetype": "0", "physical_name": ""},
+			},
+		},
+		{
+			Name:   "DB_MAX_PARALLELISM",
+			Fields: []map[string]string{{"physical_name": "/other"}},
+		},
+		{
+			Name: "DB_DEFAULT_DIRECTORIES",
+			Fields: []map[string]string{
+				{"data_directory": "/var/opt/mssql/data/", "log_directory": "unknown", "backup_directory": "/var/opt/mssql/backup/"},
+			},
+		},
+	}
+	want := []string{"/var/opt/mssql/data/master.mdf", "/var/opt/mssql/log/mastlog.ldf", "/var/opt/mssql/data/"}
+	if diff := cmp.Diff(SQLFilePaths(details), want); diff != "" {
+		t.Errorf("SQLFilePaths() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestSQLVolumeFreeSpace(t *testing.T) {
+	testcases := []struct {
+		name    string
+		details []Details
+		volumes []Volume
+		want    Details
+		wantOK  bool
+	}{
+		{
+			name: "windows volumes",
+			details: []Details{
+				{
+					Name: "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{
+						{"filetype": "0", "physical_name": `c:\data\db.mdf`},
+						{"filetype": "1", "physical_name": `D:\log\db_log.ldf`},
+					},
+				},
+			},
+			volumes: []Volume{
+				{Name: `C:\`, SizeBytes: 1000, FreeBytes: 50},
+				{Name: `D:\`, SizeBytes: 1000, FreeBytes: 500},
+				{Name: `E:\`, SizeBytes: 1000, FreeBytes: 0},
+			},
+			want: Details{
+				Name: "DB_SQL_VOLUME_FREE_SPACE",
+				Fields: []map[string]string{
+					{"volume": `C:\`, "file_types": "data", "size_bytes": "1000", "free_bytes": "50", "free_percent": "5.00", "is_below_threshold": "true"},
+					{"volume": `D:\`, "file_types": "log", "size_bytes": "1000", "free_bytes": "500", "free_percent": "50.00", "is_below_threshold": "false"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "linux mount points",
+			details: []Details{
+				{
+					Name: "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{
+						{"filetype": "0", "physical_name": "/var/opt/mssql/data/db.mdf"},
+						{"filetype": "1", "physical_name": "/var/opt/mssql/data/db_log.ldf"},
+						{"filetype": "1", "physical_name": "/mnt/log/db2_log.ldf"},
+					},
+				},
+			},
+			volumes: []Volume{
+				{Name: "/", SizeBytes: 3000, FreeBytes: 1000},
+				{Name: "/var/opt/mssql", SizeBytes: 0, FreeBytes: 0},
+			},
+			want: Details{
+				Name: "DB_SQL_VOLUME_FREE_SPACE",
+				Fields: []map[string]string{
+					{"volume": "/", "file_types": "log", "size_bytes": "3000", "free_bytes": "1000", "free_percent": "33.33", "is_below_threshold": "false"},
+					{"volume": "/var/opt/mssql", "file_types": "data,log", "size_bytes": "0", "free_bytes": "0", "free_percent": "unknown", "is_below_threshold": "unknown"},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "files on unknown volumes",
+			details: []Details{
+				{
+					Name:   "DB_LOG_DISK_SEPARATION",
+					Fields: []map[string]string{{"filetype": "0", "physical_name": `F:\data\db.mdf`}},
+				},
+			},
+			volumes: []Volume{{Name: `C:\`, SizeBytes: 1000, FreeBytes: 50}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := SQLVolumeFreeSpace(tc.details, tc.volumes, 10)
+			if ok != tc.wantOK {
+				t.Fatalf("SQLVolumeFreeSpace() returned ok = %v, want %v", ok, tc.wantOK)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("SQLVolumeFreeSpace() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWaitStatsIgnoreLists(t *testing.T) {
+	rule := ruleByName(t, "DB_WAIT_STATS")
+	input := [][]any{}
+	for i := 0; i < 12; i++ {
+		input = append(input, []any{fmt.Sprintf("WAIT_%d", i), int64(1), int64(100 - i), int64(0)})
+	}
+
+	settings := DefaultRuleSettings()
+	settings.SetIgnoreLists([]string{"WAIT_0", "WAIT_1"}, []int32{1222})
+	got := rule.Fields(input, settings)
+	if len(got) != maxWaitStats {
+		t.Fatalf("Fields() returned %d wait types, want %d", len(got), maxWaitStats)
+	}
+	if got[0]["wait_type"] != "WAIT_2" || got[maxWaitStats-1]["wait_type"] != "WAIT_11" {
+		t.Errorf("Fields() returned wait types %s to %s, want WAIT_2 to WAIT_11", got[0]["wait_type"], got[maxWaitStats-1]["wait_type"])
+	}
+	if !settings.IgnoredErrorNumbers[1222] {
+		t.Errorf("IgnoredErrorNumbers = %v, want 1222 ignored", settings.IgnoredErrorNumbers)
+	}
+	if !settings.IgnoredWaitTypes["SLEEP_TASK"] {
+		t.Errorf("IgnoredWaitTypes = %v, want the default wait types merged with the configured ones", settings.IgnoredWaitTypes)
+	}
+
+	settings.SetIgnoreLists(nil, nil)
+	if !settings.IgnoredWaitTypes["SLEEP_TASK"] || settings.IgnoredWaitTypes["WAIT_0"] {
+		t.Errorf("IgnoredWaitTypes = %v, want the default wait types", settings.IgnoredWaitTypes)
+	}
+	if len(settings.IgnoredErrorNumbers) != 0 {
+		t.Errorf("IgnoredErrorNumbers = %v, want empty", settings.IgnoredErrorNumbers)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	settings := DefaultRuleSettings()
+	settings.AgentTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rule := ruleByName(t, "DB_CLOCK_SKEW")
+	testcases := []struct {
+		name  string
+		input [][]any
+		want  []map[string]string
+	}{
+		{
+			name:  "clocks within threshold",
+			input: [][]any{{time.Date(2024, 1, 1, 12, 0, 1, 500000000, time.UTC)}},
+			want: []map[string]string{{
+				"server_utc_time":    "2024-01-01T12:00:01.5Z",
+				"agent_utc_time":     "2024-01-01T12:00:00Z",
+				"skew_seconds":       "1.500",
+				"is_above_threshold": "false",
+			}},
+		},
+		{
+			name:  "sql server clock behind above threshold",
+			input: [][]any{{time.Date(2024, 1, 1, 11, 59, 50, 0, time.UTC)}},
+			want: []map[string]string{{
+				"server_utc_time":    "2024-01-01T11:59:50Z",
+				"agent_utc_time":     "2024-01-01T12:00:00Z",
+				"skew_seconds":       "-10.000",
+				"is_above_threshold": "true",
+			}},
+		},
+		{
+			name:  "unexpected value",
+			input: [][]any{{nil}},
+			want: []map[string]string{{
+				"server_utc_time":    "unknown",
+				"agent_utc_time":     "2024-01-01T12:00:00Z",
+				"skew_seconds":       "unknown",
+				"is_above_threshold": "unknown",
+			}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rule.Fields(tc.input, settings)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("Fields() returned wrong result (-got +want):\n%s", diff)
+			}
+		})
+	}
+	got := rule.Fields([][]any{{time.Now()}}, DefaultRuleSettings())
+	if got[0]["agent_utc_time"] != "unknown" || got[0]["is_above_threshold"] != "unknown" {
+		t.Errorf("Fields() without the agent time = %v, want unknown agent time and skew", got[0])
+	}
+}
+
+func TestAddVMVCPUCount(t *testing.T) {
+	details := []Details{
+		{
+			Name: "DB_LICENSED_CORES",
+			Fields: []map[string]string{
+				{"sql_visible_cores": "24", "vm_vcpu_count": "unknown", "uses_all_vcpus": "unknown"},
+				{"sql_visible_cores": "unknown", "vm_vcpu_count": "unknown", "uses_all_vcpus": "unknown"},
+			},
+		},
+		{
+			Name:   "DB_MAX_PARALLELISM",
+			Fields: []map[string]string{{"maxDop": "0"}},
+		},
+	}
+	AddVMVCPUCount(details, 32)
+	want := []Details{
+		{
+			Name: "DB_LICENSED_CORES",
+			Fields: []map[string]string{
+				{"sql_visible_cores": "24", "vm_vcpu_count": "32", "uses_all_vcpus": "false"},
+				{"sql_visible_cores": "unknown", "vm_vcpu_count": "32", "uses_all_vcpus": "unknown"},
+			},
+		},
+		{
+			Name:   "DB_MAX_PARALLELISM",
+			Fields: []map[string]string{{"maxDop": "0"}},
+		},
+	}
+	if diff := cmp.Diff(details, want); diff != "" {
+		t.Errorf("AddVMVCPUCount() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestDatabaseFilesCap(t *testing.T) {
+	rule := ruleByName(t, "DB_DATABASE_FILES")
+	input := [][]any{}
+	for i := 0; i < maxDatabaseFiles+10; i++ {
+		input = append(input, []any{"db", fmt.Sprintf("file%d", i), "ROWS", "/data/file.mdf", int64(8), int64(8), int64(-1), int64(8), false})
+	}
+	if got := rule.Fields(input, DefaultRuleSettings()); len(got) != maxDatabaseFiles {
+		t.Errorf("Fields() returned %d files, want %d", len(got), maxDatabaseFiles)
+	}
+}
+
+func TestDatabaseFilesUsedPercent(t *testing.T) {
+	rule := ruleByName(t, "DB_DATABASE_FILES")
+	input := [][]any{
+		{"db", "full", "ROWS", "/data/full.mdf", int64(1000), int64(950), int64(-1), int64(8), false},
+		{"db", "unread", "LOG", "/data/unread.ldf", int64(1000), nil, int64(-1), int64(8), false},
+	}
+	got, err := rule.Results(input, DefaultRuleSettings())
+	if err != nil {
+		t.Fatalf("Results() returned unexpected error: %v", err)
+	}
+	want := [][2]string{{"95.00", "true"}, {"unknown", "unknown"}}
+	for i, row := range got {
+		if row["used_percent"] != want[i][0] || row["is_nearly_full"] != want[i][1] {
+			t.Errorf("Results()[%d] used_percent, is_nearly_full = %q, %q, want %q, %q", i, row["used_percent"], row["is_nearly_full"], want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestAddFailoverClusterInstance(t *testing.T) {
+	details := []Details{
+		{
+			Name: "DB_FAILOVER_CLUSTER",
+			Fields: []map[string]string{
+				{"is_clustered": "true", "active_node": "NODE2", "configured_as_fci": "unknown"},
+			},
+		},
+		{
+			Name:   "DB_MAX_PARALLELISM",
+			Fields: []map[string]string{{"maxDop": "0"}},
+		},
+	}
+	AddFailoverClusterInstance(details, true)
+	want := []Details{
+		{
+			Name: "DB_FAILOVER_CLUSTER",
+			Fields: []map[string]string{
+				{"is_clustered": "true", "active_node": "NODE2", "configured_as_fci": "true"},
+			},
+		},
+		{
+			Name:   "DB_MAX_PARALLELISM",
+			Fields: []map[string]string{{"maxDop": "0"}},
+		},
+	}
+	if diff := cmp.Diff(details, want); diff != "" {
+		t.Errorf("AddFailoverClusterInstance() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestTruncateQueryText(t *testing.T) {
+	long := strings.Repeat("é", maxQueryTextLength+10)
+	if got := truncateQueryText(long); got != strings.Repeat("é", maxQueryTextLength) {
+		t.Errorf("truncateQueryText() returned %d characters, want %d", len([]rune(got)), maxQueryTextLength)
+	}
+	if got := truncateQueryText("SELECT 1"); got != "SELECT 1" {
+		t.Errorf("truncateQueryText(%q) = %q, want %q", "SELECT 1", got, "SELECT 1")
+	}
+}
+
+func TestAddPowerPlanRecommended(t *testing.T) {
+	testcases := []struct {
+		name        string
+		powerPlan   string
+		recommended []string
+		want        string
+	}{
+		{
+			name:      "default recommended plan",
+			powerPlan: "High performance",
+			want:      "true",
+		},
+		{
+			name:      "not recommended by default",
+			powerPlan: "Balanced",
+			want:      "false",
+		},
+		{
+			name:        "configured plans compared case-insensitively",
+			powerPlan:   "Balanced",
+			recommended: []string{"high performance", "balanced"},
+			want:        "true",
+		},
+		{
+			name:      "unknown power plan",
+			powerPlan: "unknown",
+			want:      "unknown",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			details := []Details{
+				{Name: "OS", Fields: []map[string]string{{PowerProfileSettingRule: tc.powerPlan}}},
+				{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDop": "0"}}},
+			}
+			AddPowerPlanRecommended(details, tc.recommended)
+			want := []Details{
+				{Name: "OS", Fields: []map[string]string{{PowerProfileSettingRule: tc.powerPlan, PowerPlanRecommendedField: tc.want}}},
+				{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDop": "0"}}},
+			}
+			if diff := cmp.Diff(details, want); diff != "" {
+				t.Errorf("AddPowerPlanRecommended(%v) returned wrong result (-got +want):\n%s", tc.recommended, diff)
+			}
+		})
+	}
+}
+
+func TestMajorVersion(t *testing.T) {
+	testcases := []struct {
+		name   string
+		fields [][]any
+		want   int
+	}{
+		{
+			name:   "sql server 2022",
+			fields: [][]any{{int64(3), "Enterprise Edition (64-bit)", int64(16)}},
+			want:   16,
+		},
+		{
+			name:   "unknown version",
+			fields: [][]any{{int64(3), "Enterprise Edition (64-bit)", nil}},
+		},
+		{
+			name:   "no version column",
+			fields: [][]any{{int64(3), "Enterprise Edition (64-bit)"}},
+		},
+		{
+			name: "no rows",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MajorVersion(tc.fields); got != tc.want {
+				t.Errorf("MajorVersion(%v) = %d, want %d", tc.fields, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppliesToVersion(t *testing.T) {
+	rule := MasterRuleStruct{Name: "test", MinMajorVersion: 13}
+	for major, want := range map[int]bool{0: true, 12: false, 13: true, 16: true} {
+		if got := rule.AppliesToVersion(major); got != want {
+			t.Errorf("AppliesToVersion(%d) = %t, want %t", major, got, want)
+		}
+	}
+	if got := (MasterRuleStruct{Name: "test"}).AppliesToVersion(12); !got {
+		t.Errorf("AppliesToVersion(12) of a rule without minimum version = %t, want true", got)
+	}
+}
+
+func TestAppliesToWorkload(t *testing.T) {
+	rule := MasterRuleStruct{Name: "test", SkipWorkloads: []string{WorkloadReporting}}
+	for workloadType, want := range map[string]bool{"": true, WorkloadOLTP: true, WorkloadOLAP: true, WorkloadReporting: false} {
+		if got := rule.AppliesToWorkload(workloadType); got != want {
+			t.Errorf("AppliesToWorkload(%q) = %t, want %t", workloadType, got, want)
+		}
+	}
+}
+
+func TestMasterRulesSkipEveryWorkloadType(t *testing.T) {
+	for _, workloadType := range []string{WorkloadOLTP, WorkloadOLAP, WorkloadReporting} {
+		skipped := 0
+		for _, rule := range MasterRules {
+			if !rule.AppliesToWorkload(workloadType) {
+				skipped++
+			}
+		}
+		if skipped == 0 {
+			t.Errorf("No master rule is skipped for workload type %q", workloadType)
+		}
+	}
+}
+
+func TestMasterRulesReadDatabases(t *testing.T) {
+	for _, rule := range MasterRules {
+		if got := strings.Contains(rule.Query, "@databases"); got != rule.ReadsDatabases() {
+			t.Errorf("Query of rule %q reads @databases = %v, want %v", rule.Name, got, rule.ReadsDatabases())
+		}
+	}
+}
+
+func TestLabelWorkload(t *testing.T) {
+	details := []Details{
+		{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDop": "0"}}},
+		{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{}},
+	}
+	got := LabelWorkload(details, WorkloadOLAP)
+	want := []Details{
+		{Name: "DB_MAX_PARALLELISM", Fields: []map[string]string{{"maxDop": "0", "workload_type": "olap"}}},
+		{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LabelWorkload() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, ok := details[0].Fields[0][WorkloadTypeField]; ok {
+		t.Errorf("LabelWorkload() modified the details of the caller: %v", details)
+	}
+}
+
+func TestValidWorkloadType(t *testing.T) {
+	for workloadType, want := range map[string]bool{"": false, "oltp": true, "olap": true, "reporting": true, "OLTP": false, "batch": false} {
+		if got := ValidWorkloadType(workloadType); got != want {
+			t.Errorf("ValidWorkloadType(%q) = %t, want %t", workloadType, got, want)
+		}
+	}
+}
+
+func TestValidateFieldRenames(t *testing.T) {
+	details := map[string][]string{
+		"OS":               {"local_ssd", "page_file", "power_profile_setting"},
+		"DB_BACKUP_POLICY": {"max_backup_age", "host_name", "port_number"},
+	}
+	testcases := []struct {
+		name    string
+		renames []FieldRename
+		wantErr bool
+	}{
+		{
+			name: "valid renames",
+			renames: []FieldRename{
+				{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "backup_age_days"},
+				{Rule: "OS", Field: "power_profile_setting", Name: "power_plan"},
+				{Rule: "OS", Field: "local_ssd", Name: "local_ssd"},
+			},
+		},
+		{
+			name:    "missing name",
+			renames: []FieldRename{{Rule: "OS", Field: "local_ssd"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown rule",
+			renames: []FieldRename{{Rule: "DB_UNKNOWN", Field: "value", Name: "other"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			renames: []FieldRename{{Rule: "DB_BACKUP_POLICY", Field: "backup_age", Name: "backup_age_days"}},
+			wantErr: true,
+		},
+		{
+			name:    "field renamed to an existing field",
+			renames: []FieldRename{{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "host_name"}},
+			wantErr: true,
+		},
+		{
+			name: "field renamed twice",
+			renames: []FieldRename{
+				{Rule: "OS", Field: "local_ssd", Name: "ssd"},
+				{Rule: "OS", Field: "local_ssd", Name: "disks"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fields renamed to the same name",
+			renames: []FieldRename{
+				{Rule: "OS", Field: "local_ssd", Name: "disks"},
+				{Rule: "OS", Field: "page_file", Name: "disks"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "field renamed to a renamed field",
+			renames: []FieldRename{
+				{Rule: "OS", Field: "local_ssd", Name: "page_file"},
+				{Rule: "OS", Field: "page_file", Name: "swap"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateFieldRenames(tc.renames, details)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("ValidateFieldRenames(%v) returned error: %v, want error: %v", tc.renames, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenameFields(t *testing.T) {
+	details := []Details{
+		{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{
+			{"max_backup_age": "3", "backup_age_days": "old"},
+			{"max_backup_age": "5"},
+		}},
+		{Name: "OS", Fields: []map[string]string{{"local_ssd": "unknown"}}},
+	}
+	renames := []FieldRename{{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: "backup_age_days"}}
+	want := []Details{
+		{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{
+			{"max_backup_age": "3", "backup_age_days": "old"},
+			{"backup_age_days": "5"},
+		}},
+		{Name: "OS", Fields: []map[string]string{{"local_ssd": "unknown"}}},
+	}
+	got := RenameFields(details, renames)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RenameFields() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if details[0].Fields[0]["max_backup_age"] != "3" {
+		t.Errorf("RenameFields() modified the details of the caller: %v", details)
+	}
+}
+
+func TestRenameFieldsConcurrent(t *testing.T) {
+	details := []Details{
+		{Name: "DB_BACKUP_POLICY", Fields: []map[string]string{{"max_backup_age": "3"}}},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("backup_age_%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			renames := []FieldRename{{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Name: name}}
+			got := RenameFields(details, renames)
+			if got[0].Fields[0][name] != "3" {
+				t.Errorf("RenameFields(%v) = %v, want field %q renamed", renames, got, name)
+			}
+		}()
+	}
+	wg.Wait()
+	if details[0].Fields[0]["max_backup_age"] != "3" {
+		t.Errorf("RenameFields() modified the details of the caller: %v", details)
+	}
+}
+
+func TestDetailFields(t *testing.T) {
+	details := DetailFields()
+	for _, name := range DetailNames() {
+		if len(details[name]) == 0 {
+			t.Errorf("DetailFields() has no fields for %q", name)
+		}
+	}
+	for _, tc := range []struct{ rule, field string }{
+		{"DB_BACKUP_POLICY", "max_backup_age"},
+		{"DB_BACKUP_POLICY", "host_name"},
+		{"OS", "local_ssd"},
+		{AgentResourceUsageName, "rss_bytes"},
+	} {
+		if !stringSet(details[tc.rule])[tc.field] {
+			t.Errorf("DetailFields()[%q] does not contain %q", tc.rule, tc.field)
+		}
+	}
+}
+
+func TestDetailNames(t *testing.T) {
+	names := stringSet(DetailNames())
+	for _, name := range []string{"OS", "SQL_EDITION", "DB_MEMORY_ALLOCATION", "DB_BACKUP_POLICY"} {
+		if !names[name] {
+			t.Errorf("DetailNames() does not contain %q", name)
 		}
 	}
 }
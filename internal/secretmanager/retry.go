@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+)
+
+// RetryConfig is the exponential backoff of the retries of transient errors.
+type RetryConfig struct {
+	MaxRetries      int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+// StatusError is an HTTP error response of a secret provider.
+type StatusError struct {
+	Provider string
+	Code     int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status code %d", e.Provider, e.Code)
+}
+
+// transientCodes are the gRPC codes of Secret Manager errors that may succeed when retried.
+var transientCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.Internal:          true,
+}
+
+// Transient reports whether err is a transient error of a secret provider that may succeed when
+// retried. Permission and not found errors are not transient.
+func Transient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= http.StatusInternalServerError
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK && s.Code() != codes.Unknown {
+		return transientCodes[s.Code()]
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// GetSecretWithRetry returns the secret from the provider. Transient errors are retried with
+// exponential backoff; other errors are returned right away.
+func GetSecretWithRetry(ctx context.Context, p SecretProvider, ref string, cfg RetryConfig) (string, error) {
+	return retry(ctx, func() (string, error) { return p.GetSecret(ctx, ref) }, cfg)
+}
+
+// GetSecretVersionsWithRetry returns the given versions of the secret from the provider, in the
+// order of the versions. Versions that cannot be read are logged and skipped; an error is only
+// returned if none of the versions can be read. Transient errors are retried as in
+// GetSecretWithRetry. An empty version or "latest" refers to the latest version, which is also
+// the only version read if no versions are given.
+func GetSecretVersionsWithRetry(ctx context.Context, p SecretProvider, ref string, versions []string, cfg RetryConfig) ([]string, error) {
+	if len(versions) == 0 {
+		versions = []string{LatestVersion}
+	}
+	var secrets []string
+	var errs []error
+	for _, version := range versions {
+		get := func() (string, error) { return p.GetSecret(ctx, ref) }
+		if version != "" && version != LatestVersion {
+			vp, ok := p.(VersionedProvider)
+			if !ok {
+				return nil, fmt.Errorf("the secret provider does not support secret versions")
+			}
+			get = func() (string, error) { return vp.GetSecretVersion(ctx, ref, version) }
+		}
+		s, err := retry(ctx, get, cfg)
+		if err != nil {
+			log.Logger.Warnw("Failed to get secret version", "version", version, "error", err)
+			errs = append(errs, fmt.Errorf("version %q: %w", version, err))
+			continue
+		}
+		secrets = append(secrets, s)
+	}
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("failed to get any version of the secret: %w", errors.Join(errs...))
+	}
+	return secrets, nil
+}
+
+// retry calls get with exponential backoff until it succeeds, returns a permanent error or the
+// retries are exhausted.
+func retry(ctx context.Context, getSecret func() (string, error), cfg RetryConfig) (string, error) {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = cfg.InitialInterval
+	b.MaxInterval = cfg.MaxInterval
+	b.MaxElapsedTime = 0
+	var secret string
+	attempt := 0
+	get := func() error {
+		attempt++
+		s, err := getSecret()
+		if err == nil {
+			secret = s
+			return nil
+		}
+		if !Transient(err) {
+			return backoff.Permanent(err)
+		}
+		if attempt <= cfg.MaxRetries {
+			log.Logger.Warnw("Transient error getting secret, retrying", "attempt", attempt, "error", err)
+		}
+		return err
+	}
+	if err := backoff.Retry(get, backoff.WithContext(backoff.WithMaxRetries(b, uint64(cfg.MaxRetries)), ctx)); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *fakeProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return "", p.errs[p.calls-1]
+	}
+	return "secret-value", nil
+}
+
+func (p *fakeProvider) Close() error { return nil }
+
+type fakeVersionedProvider struct {
+	fakeProvider
+	versions map[string]string
+}
+
+func (p *fakeVersionedProvider) GetSecretVersion(ctx context.Context, ref, version string) (string, error) {
+	if s, ok := p.versions[version]; ok {
+		return s, nil
+	}
+	return "", status.Error(codes.NotFound, "secret version not found")
+}
+
+func TestTransient(t *testing.T) {
+	testcases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "secret manager unavailable", err: status.Error(codes.Unavailable, "service unavailable"), want: true},
+		{name: "secret manager deadline exceeded", err: status.Error(codes.DeadlineExceeded, "deadline exceeded"), want: true},
+		{name: "secret manager permission denied", err: status.Error(codes.PermissionDenied, "permission denied")},
+		{name: "secret manager not found", err: status.Error(codes.NotFound, "secret not found")},
+		{name: "vault unavailable", err: &StatusError{Provider: "vault", Code: 503}, want: true},
+		{name: "vault rate limited", err: &StatusError{Provider: "vault", Code: 429}, want: true},
+		{name: "vault forbidden", err: &StatusError{Provider: "vault", Code: 403}},
+		{name: "other error", err: errors.New("empty secret value from secret manager")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Transient(tc.err); got != tc.want {
+				t.Errorf("Transient(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretWithRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "service unavailable")
+	testcases := []struct {
+		name      string
+		errs      []error
+		want      string
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			want:      "secret-value",
+			wantCalls: 1,
+		},
+		{
+			name:      "transient errors are retried",
+			errs:      []error{unavailable, unavailable},
+			want:      "secret-value",
+			wantCalls: 3,
+		},
+		{
+			name:      "transient errors exceed max retries",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable},
+			wantErr:   true,
+			wantCalls: 3,
+		},
+		{
+			name:      "permanent error is not retried",
+			errs:      []error{status.Error(codes.PermissionDenied, "permission denied")},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &fakeProvider{errs: tc.errs}
+			cfg := RetryConfig{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+			got, err := GetSecretWithRetry(context.Background(), p, "secret", cfg)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("GetSecretWithRetry() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetSecretWithRetry() = %q, want %q", got, tc.want)
+			}
+			if p.calls != tc.wantCalls {
+				t.Errorf("GetSecretWithRetry() called the provider %d times, want %d", p.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetSecretVersionsWithRetry(t *testing.T) {
+	testcases := []struct {
+		name     string
+		provider SecretProvider
+		versions []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "no versions read the latest version",
+			provider: &fakeProvider{},
+			want:     []string{"secret-value"},
+		},
+		{
+			name:     "latest and pinned version",
+			provider: &fakeVersionedProvider{versions: map[string]string{"2": "previous-value"}},
+			versions: []string{"latest", "2"},
+			want:     []string{"secret-value", "previous-value"},
+		},
+		{
+			name: "unreadable versions are skipped",
+			provider: &fakeVersionedProvider{
+				fakeProvider: fakeProvider{errs: []error{status.Error(codes.PermissionDenied, "permission denied")}},
+				versions:     map[string]string{"2": "previous-value"},
+			},
+			versions: []string{"latest", "3", "2"},
+			want:     []string{"previous-value"},
+		},
+		{
+			name:     "no version can be read",
+			provider: &fakeVersionedProvider{},
+			versions: []string{"3", "2"},
+			wantErr:  true,
+		},
+		{
+			name:     "provider does not support versions",
+			provider: &fakeProvider{},
+			versions: []string{"latest", "2"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := RetryConfig{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+			got, err := GetSecretVersionsWithRetry(context.Background(), tc.provider, "secret", tc.versions, cfg)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("GetSecretVersionsWithRetry() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecretVersionsWithRetry() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
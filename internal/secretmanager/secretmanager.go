@@ -14,17 +14,43 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package secretmanager is the wrapper of google cloud secretmanager api.
+// Package secretmanager is the wrapper of google cloud secretmanager api and other secret providers.
 package secretmanager
 
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 )
 
+const (
+	// GCPSecretManager is the provider type for Google Cloud Secret Manager.
+	GCPSecretManager = "GCP_SECRET_MANAGER"
+	// Vault is the provider type for HashiCorp Vault.
+	Vault = "VAULT"
+	// LatestVersion is the version of a secret that refers to its most recent version.
+	LatestVersion = "latest"
+)
+
+// SecretProvider defines functions in the interface of a secret provider.
+// The format of ref depends on the provider.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, ref string) (string, error)
+	Close() error
+}
+
+// VersionedProvider is a SecretProvider that can also return a given version of a secret.
+type VersionedProvider interface {
+	SecretProvider
+	GetSecretVersion(ctx context.Context, ref, version string) (string, error)
+}
+
 // SecretMgrInterface defines functions in the interface of secret manager.
 type SecretMgrInterface interface {
 	GetSecretValue(ctx context.Context, projectID, secretName string) (string, error)
@@ -38,9 +64,23 @@ type Client struct {
 
 // NewClient create and return an instance of SecretManagerClient.
 // Returns nil if there is an error during the NewClient.
-func NewClient(ctx context.Context) (*Client, error) {
-	// Create the client.
-	client, err := secretmanager.NewClient(ctx)
+// If dialer is not nil, it is used for all connections to Secret Manager.
+func NewClient(ctx context.Context, dialer internal.ContextDialer) (*Client, error) {
+	if dialer == nil {
+		client, err := secretmanager.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Client{client: client}, nil
+	}
+	// The REST client is used as the transport of the dialer is an HTTP transport.
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = dialer.DialContext
+	trans, err := htransport.NewTransport(ctx, base, option.WithScopes(secretmanager.DefaultAuthScopes()...))
+	if err != nil {
+		return nil, fmt.Errorf("%v error creating Secret Manager transport", err)
+	}
+	client, err := secretmanager.NewRESTClient(ctx, option.WithHTTPClient(&http.Client{Transport: trans}))
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +89,13 @@ func NewClient(ctx context.Context) (*Client, error) {
 
 // GetSecretValue returns the latest version of given secret name from Secret Manager.
 func (s *Client) GetSecretValue(ctx context.Context, projectID, secretName string) (string, error) {
+	return s.GetSecretVersionValue(ctx, projectID, secretName, LatestVersion)
+}
+
+// GetSecretVersionValue returns the given version of the secret name from Secret Manager.
+func (s *Client) GetSecretVersionValue(ctx context.Context, projectID, secretName, version string) (string, error) {
 	result, err := s.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
-		Name: fmt.Sprintf("projects/%s/secrets/%s/versions/%s", projectID, secretName, "latest"),
+		Name: fmt.Sprintf("projects/%s/secrets/%s/versions/%s", projectID, secretName, version),
 	})
 	if err != nil {
 		return "", err
@@ -68,3 +113,36 @@ func (s *Client) GetSecretValue(ctx context.Context, projectID, secretName strin
 func (s *Client) Close() error {
 	return s.client.Close()
 }
+
+// GCPProvider is the SecretProvider backed by Google Cloud Secret Manager.
+// The ref passed to GetSecret is the secret name in the project.
+type GCPProvider struct {
+	client    *Client
+	projectID string
+}
+
+// NewGCPProvider creates and returns an instance of GCPProvider for the given project.
+// If dialer is not nil, it is used for all connections to Secret Manager.
+func NewGCPProvider(ctx context.Context, projectID string, dialer internal.ContextDialer) (*GCPProvider, error) {
+	client, err := NewClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return &GCPProvider{client: client, projectID: projectID}, nil
+}
+
+// GetSecret returns the latest version of the given secret from Secret Manager.
+func (p *GCPProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	return p.client.GetSecretValue(ctx, p.projectID, ref)
+}
+
+// GetSecretVersion returns the given version of the secret from Secret Manager, e.g. "latest" or
+// "3".
+func (p *GCPProvider) GetSecretVersion(ctx context.Context, ref, version string) (string, error) {
+	return p.client.GetSecretVersionValue(ctx, p.projectID, ref, version)
+}
+
+// Close the secret manager client.
+func (p *GCPProvider) Close() error {
+	return p.client.Close()
+}
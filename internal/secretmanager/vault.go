@@ -0,0 +1,198 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+const (
+	defaultVaultMountPath = "secret"
+	defaultVaultSecretKey = "password"
+)
+
+// VaultConfig contains the settings for connecting to a HashiCorp Vault server.
+// Either TokenFilePath or RoleID with SecretIDFilePath must be provided.
+type VaultConfig struct {
+	Address          string
+	MountPath        string
+	TokenFilePath    string
+	RoleID           string
+	SecretIDFilePath string
+	Namespace        string
+	// Dialer, if not nil, is used for all connections to Vault.
+	Dialer internal.ContextDialer
+}
+
+// VaultProvider is the SecretProvider backed by the HashiCorp Vault KV version 2 secrets engine.
+// The ref passed to GetSecret has the format "<path>#<key>". The key defaults to "password".
+type VaultProvider struct {
+	address    string
+	mountPath  string
+	namespace  string
+	token      string
+	httpClient *http.Client
+}
+
+// NewVaultProvider creates and returns an instance of VaultProvider.
+// If no token file is configured, the provider logs in with AppRole auth.
+func NewVaultProvider(ctx context.Context, cfg VaultConfig) (*VaultProvider, error) {
+	if cfg.Dialer == nil {
+		return newVaultProvider(ctx, cfg, http.DefaultClient)
+	}
+	trans := http.DefaultTransport.(*http.Transport).Clone()
+	trans.DialContext = cfg.Dialer.DialContext
+	return newVaultProvider(ctx, cfg, &http.Client{Transport: trans})
+}
+
+func newVaultProvider(ctx context.Context, cfg VaultConfig, httpClient *http.Client) (*VaultProvider, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is empty")
+	}
+	p := &VaultProvider{
+		address:    strings.TrimSuffix(cfg.Address, "/"),
+		mountPath:  strings.Trim(cfg.MountPath, "/"),
+		namespace:  cfg.Namespace,
+		httpClient: httpClient,
+	}
+	if p.mountPath == "" {
+		p.mountPath = defaultVaultMountPath
+	}
+
+	if cfg.TokenFilePath != "" {
+		token, err := readFileTrimmed(cfg.TokenFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault token file: %v", err)
+		}
+		p.token = token
+		return p, nil
+	}
+	if cfg.RoleID == "" || cfg.SecretIDFilePath == "" {
+		return nil, fmt.Errorf("either token_file_path or role_id and secret_id_file_path must be provided for vault")
+	}
+	secretID, err := readFileTrimmed(cfg.SecretIDFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret id file: %v", err)
+	}
+	if err := p.loginAppRole(ctx, cfg.RoleID, secretID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetSecret returns the value of the key in the secret stored at the given path.
+func (p *VaultProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	return p.GetSecretVersion(ctx, ref, LatestVersion)
+}
+
+// GetSecretVersion returns the value of the key in the given version of the secret stored at the
+// given path. The version is the number of a KV version 2 secret version or "latest".
+func (p *VaultProvider) GetSecretVersion(ctx context.Context, ref, version string) (string, error) {
+	path, key, found := strings.Cut(ref, "#")
+	if !found || key == "" {
+		key = defaultVaultSecretKey
+	}
+	var result struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mountPath, strings.Trim(path, "/"))
+	if version != "" && version != LatestVersion {
+		url += "?version=" + version
+	}
+	if err := p.do(ctx, http.MethodGet, url, nil, &result); err != nil {
+		return "", err
+	}
+	value, ok := result.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in vault secret %q", key, path)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %q in vault secret %q is not a string", key, path)
+	}
+	return s, nil
+}
+
+// Close is a no-op. The provider does not hold any connection.
+func (p *VaultProvider) Close() error {
+	return nil
+}
+
+func (p *VaultProvider) loginAppRole(ctx context.Context, roleID, secretID string) error {
+	body, err := json.Marshal(map[string]string{"role_id": roleID, "secret_id": secretID})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, p.address+"/v1/auth/approle/login", body, &result); err != nil {
+		return fmt.Errorf("vault approle login failed: %v", err)
+	}
+	if result.Auth.ClientToken == "" {
+		return fmt.Errorf("vault approle login returned an empty token")
+	}
+	p.token = result.Auth.ClientToken
+	return nil
+}
+
+func (p *VaultProvider) do(ctx context.Context, method, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Vault-Token", p.token)
+	}
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Provider: "vault", Code: resp.StatusCode}
+	}
+	return json.Unmarshal(b, out)
+}
+
+func readFileTrimmed(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func fakeVaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["role_id"] != "test-role" || body["secret_id"] != "test-secret-id" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+		case "/v1/secret/data/sql/prod", "/v1/kv/data/sql/prod":
+			token := r.Header.Get("X-Vault-Token")
+			if token != "file-token" && token != "approle-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			switch r.URL.Query().Get("version") {
+			case "":
+				w.Write([]byte(`{"data": {"data": {"password": "pswd", "other": "other-value", "number": 1}}}`))
+			case "1":
+				w.Write([]byte(`{"data": {"data": {"password": "previous-pswd"}}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultProviderGetSecret(t *testing.T) {
+	server := fakeVaultServer(t)
+	defer server.Close()
+	dir := t.TempDir()
+	tokenPath := path.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secretIDPath := path.Join(dir, "secret_id")
+	if err := os.WriteFile(secretIDPath, []byte("test-secret-id"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name             string
+		cfg              VaultConfig
+		ref              string
+		want             string
+		wantCreateErr    bool
+		wantGetSecretErr bool
+	}{
+		{
+			name: "token auth with default key",
+			cfg:  VaultConfig{Address: server.URL, TokenFilePath: tokenPath},
+			ref:  "sql/prod",
+			want: "pswd",
+		},
+		{
+			name: "token auth with explicit key and mount path",
+			cfg:  VaultConfig{Address: server.URL + "/", MountPath: "/kv/", TokenFilePath: tokenPath},
+			ref:  "sql/prod#other",
+			want: "other-value",
+		},
+		{
+			name: "approle auth",
+			cfg:  VaultConfig{Address: server.URL, RoleID: "test-role", SecretIDFilePath: secretIDPath},
+			ref:  "sql/prod",
+			want: "pswd",
+		},
+		{
+			name:          "approle auth with invalid role",
+			cfg:           VaultConfig{Address: server.URL, RoleID: "any", SecretIDFilePath: secretIDPath},
+			wantCreateErr: true,
+		},
+		{
+			name:          "missing auth",
+			cfg:           VaultConfig{Address: server.URL},
+			wantCreateErr: true,
+		},
+		{
+			name:          "missing address",
+			cfg:           VaultConfig{TokenFilePath: tokenPath},
+			wantCreateErr: true,
+		},
+		{
+			name:          "missing token file",
+			cfg:           VaultConfig{Address: server.URL, TokenFilePath: path.Join(dir, "any")},
+			wantCreateErr: true,
+		},
+		{
+			name:             "key not found",
+			cfg:              VaultConfig{Address: server.URL, TokenFilePath: tokenPath},
+			ref:              "sql/prod#any",
+			wantGetSecretErr: true,
+		},
+		{
+			name:             "value is not a string",
+			cfg:              VaultConfig{Address: server.URL, TokenFilePath: tokenPath},
+			ref:              "sql/prod#number",
+			wantGetSecretErr: true,
+		},
+		{
+			name:             "secret not found",
+			cfg:              VaultConfig{Address: server.URL, TokenFilePath: tokenPath},
+			ref:              "sql/any",
+			wantGetSecretErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, err := newVaultProvider(ctx, tc.cfg, server.Client())
+			if gotErr := err != nil; gotErr != tc.wantCreateErr {
+				t.Fatalf("newVaultProvider() = %v, want error presence = %v", err, tc.wantCreateErr)
+			}
+			if err != nil {
+				return
+			}
+			defer p.Close()
+			got, err := p.GetSecret(ctx, tc.ref)
+			if gotErr := err != nil; gotErr != tc.wantGetSecretErr {
+				t.Fatalf("GetSecret(%q) = %v, want error presence = %v", tc.ref, err, tc.wantGetSecretErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetSecret(%q) = %q, want %q", tc.ref, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVaultProviderGetSecretVersion(t *testing.T) {
+	server := fakeVaultServer(t)
+	defer server.Close()
+	tokenPath := path.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("file-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	p, err := newVaultProvider(ctx, VaultConfig{Address: server.URL, TokenFilePath: tokenPath}, server.Client())
+	if err != nil {
+		t.Fatalf("newVaultProvider() = %v, want nil", err)
+	}
+	defer p.Close()
+
+	testcases := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "latest version", version: LatestVersion, want: "pswd"},
+		{name: "pinned version", version: "1", want: "previous-pswd"},
+		{name: "version not found", version: "5", wantErr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.GetSecretVersion(ctx, "sql/prod", tc.version)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("GetSecretVersion(%q) = %v, want error presence = %v", tc.version, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetSecretVersion(%q) = %q, want %q", tc.version, got, tc.want)
+			}
+		})
+	}
+}
+
+// recordingDialer records the addresses it connects to.
+type recordingDialer struct {
+	addresses []string
+}
+
+func (d *recordingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.addresses = append(d.addresses, address)
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+func TestVaultProviderDialer(t *testing.T) {
+	server := fakeVaultServer(t)
+	defer server.Close()
+	secretIDPath := path.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretIDPath, []byte("test-secret-id"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dialer := &recordingDialer{}
+	p, err := NewVaultProvider(context.Background(), VaultConfig{Address: server.URL, RoleID: "test-role", SecretIDFilePath: secretIDPath, Dialer: dialer})
+	if err != nil {
+		t.Fatalf("NewVaultProvider() = %v, want nil", err)
+	}
+	defer p.Close()
+	if len(dialer.addresses) == 0 {
+		t.Errorf("NewVaultProvider() connected without the dialer")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlcollector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+)
+
+// maxPoolFailures is the number of consecutive failed cycles after which a pooled collector is
+// closed, so that the next cycle connects to SQL Server again.
+const maxPoolFailures = 3
+
+// pooledCollector is a collector kept open across collection cycles.
+type pooledCollector struct {
+	c        *V1
+	lastUsed time.Time
+	failures int
+}
+
+// Pool keeps the collectors of the SQL Server instances open across collection cycles, so that
+// each cycle reuses the connections of the previous one instead of logging in again.
+// Collectors that are not used for longer than the idle timeout are closed.
+type Pool struct {
+	mu          sync.Mutex
+	clock       clockwork.Clock
+	idleTimeout time.Duration
+	collectors  map[string]*pooledCollector
+}
+
+// NewPool creates an empty Pool whose collectors are closed after idleTimeout without use.
+func NewPool(clock clockwork.Clock, idleTimeout time.Duration) *Pool {
+	return &Pool{clock: clock, idleTimeout: idleTimeout, collectors: map[string]*pooledCollector{}}
+}
+
+// SetIdleTimeout sets the time after which unused collectors and their idle connections are
+// closed.
+func (p *Pool) SetIdleTimeout(idleTimeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idleTimeout = idleTimeout
+	for _, pc := range p.collectors {
+		pc.c.dbConn.SetConnMaxIdleTime(idleTimeout)
+	}
+}
+
+// Get returns the open collector of the connection string. A new collector is opened with open
+// if the pool has none. Collectors idle for longer than the idle timeout are closed first.
+func (p *Pool) Get(conn string, open func() (*V1, error)) (*V1, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	for key, pc := range p.collectors {
+		if p.idleTimeout > 0 && now.Sub(pc.lastUsed) > p.idleTimeout {
+			log.Logger.Debugw("Closing the idle connections to SQL Server", "target", pc.c.target)
+			p.closeLocked(key)
+		}
+	}
+	if pc, ok := p.collectors[conn]; ok {
+		pc.lastUsed = now
+		return pc.c, nil
+	}
+	c, err := open()
+	if err != nil {
+		return nil, err
+	}
+	c.dbConn.SetConnMaxIdleTime(p.idleTimeout)
+	p.collectors[conn] = &pooledCollector{c: c, lastUsed: now}
+	return c, nil
+}
+
+// Release records whether the cycle using the collector of the connection string failed. The
+// collector is closed after maxPoolFailures consecutive failed cycles.
+func (p *Pool) Release(conn string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.collectors[conn]
+	if !ok {
+		return
+	}
+	if err == nil {
+		pc.failures = 0
+		return
+	}
+	pc.failures++
+	if pc.failures >= maxPoolFailures {
+		log.Logger.Warnw("Closing the connections to SQL Server after repeated failures", "target", pc.c.target, "failures", pc.failures, "error", err)
+		p.closeLocked(conn)
+	}
+}
+
+// Close closes all collectors of the pool, e.g. when the configuration is reloaded.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.collectors {
+		p.closeLocked(key)
+	}
+}
+
+// closeLocked closes the collector of the connection string and removes it from the pool.
+// The caller must hold p.mu.
+func (p *Pool) closeLocked(conn string) {
+	if err := p.collectors[conn].c.Close(); err != nil {
+		log.Logger.Debugw("Failed to close the connections to SQL Server", "error", err)
+	}
+	delete(p.collectors, conn)
+}
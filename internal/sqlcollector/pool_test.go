@@ -0,0 +1,137 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlcollector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// fakeOpen returns an open func counting the collectors it opens.
+func fakeOpen(t *testing.T, opened *int) func() (*V1, error) {
+	return func() (*V1, error) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() = %v", err)
+		}
+		mock.ExpectClose()
+		*opened++
+		return &V1{dbConn: db, target: "host:1433"}, nil
+	}
+}
+
+func TestPoolReusesCollectors(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	p := NewPool(clock, time.Hour)
+	defer p.Close()
+	opened := 0
+	first, err := p.Get("conn", fakeOpen(t, &opened))
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	clock.Advance(59 * time.Minute)
+	second, err := p.Get("conn", fakeOpen(t, &opened))
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if first != second || opened != 1 {
+		t.Errorf("Get() within the idle timeout opened %d collectors, want 1", opened)
+	}
+	if _, err := p.Get("other conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if opened != 2 {
+		t.Errorf("Get() of another connection string opened %d collectors, want 2", opened)
+	}
+}
+
+func TestPoolClosesIdleCollectors(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	p := NewPool(clock, time.Hour)
+	defer p.Close()
+	opened := 0
+	if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	clock.Advance(61 * time.Minute)
+	if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if opened != 2 {
+		t.Errorf("Get() after the idle timeout opened %d collectors, want 2", opened)
+	}
+}
+
+func TestPoolClosesFailingCollectors(t *testing.T) {
+	p := NewPool(clockwork.NewFakeClock(), time.Hour)
+	defer p.Close()
+	opened := 0
+	for i := 0; i < maxPoolFailures; i++ {
+		if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+			t.Fatalf("Get() = %v", err)
+		}
+		p.Release("conn", errors.New("login failed"))
+	}
+	if opened != 1 {
+		t.Fatalf("Get() before %d failures opened %d collectors, want 1", maxPoolFailures, opened)
+	}
+	if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if opened != 2 {
+		t.Errorf("Get() after %d failures opened %d collectors, want 2", maxPoolFailures, opened)
+	}
+}
+
+func TestPoolResetsFailures(t *testing.T) {
+	p := NewPool(clockwork.NewFakeClock(), time.Hour)
+	defer p.Close()
+	opened := 0
+	for i := 0; i < 2*maxPoolFailures; i++ {
+		if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+			t.Fatalf("Get() = %v", err)
+		}
+		if i%2 == 0 {
+			p.Release("conn", errors.New("timeout"))
+		} else {
+			p.Release("conn", nil)
+		}
+	}
+	if opened != 1 {
+		t.Errorf("Get() with successful cycles between failures opened %d collectors, want 1", opened)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	p := NewPool(clockwork.NewFakeClock(), time.Hour)
+	opened := 0
+	if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	p.Close()
+	if _, err := p.Get("conn", fakeOpen(t, &opened)); err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	p.Close()
+	if opened != 2 {
+		t.Errorf("Get() after Close() opened %d collectors, want 2", opened)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlcollector
+
+import (
+	"github.com/GoogleCloudPlatform/sapagent/shared/log"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
+)
+
+// applicableRules returns the master rules applying to the edition and the major version of a
+// sql server, and the reasons the other master rules are skipped, by rule name.
+func applicableRules(edition string, version int, master []internal.MasterRuleStruct) ([]internal.MasterRuleStruct, map[string]string) {
+	rules := []internal.MasterRuleStruct{}
+	skipped := map[string]string{}
+	for _, rule := range master {
+		if !rule.AppliesTo(edition) {
+			log.Logger.Debugw("Skipping rule that does not apply to the sql server edition", "rule", rule.Name, "edition", edition)
+			skipped[rule.Name] = manifest.SkippedEdition
+			continue
+		}
+		if !rule.AppliesToVersion(version) {
+			log.Logger.Debugw("Skipping rule that does not apply to the sql server version", "rule", rule.Name, "version", version)
+			skipped[rule.Name] = manifest.SkippedVersion
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, skipped
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlcollector
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+func TestApplicableRules(t *testing.T) {
+	master := []internal.MasterRuleStruct{
+		{Name: "all"},
+		{Name: "enterprise", Editions: []string{internal.EditionEnterprise}},
+		{Name: "sql2016", MinMajorVersion: 13},
+	}
+	names := func(rules []internal.MasterRuleStruct) []string {
+		res := []string{}
+		for _, r := range rules {
+			res = append(res, r.Name)
+		}
+		return res
+	}
+
+	testcases := []struct {
+		name        string
+		edition     string
+		version     int
+		want        []string
+		wantSkipped map[string]string
+	}{
+		{
+			name:        "sql server 2014 standard",
+			edition:     internal.EditionStandard,
+			version:     12,
+			want:        []string{"all"},
+			wantSkipped: map[string]string{"enterprise": "edition", "sql2016": "version"},
+		},
+		{
+			name:        "sql server 2019 standard",
+			edition:     internal.EditionStandard,
+			version:     15,
+			want:        []string{"all", "sql2016"},
+			wantSkipped: map[string]string{"enterprise": "edition"},
+		},
+		{
+			name:        "sql server 2022 enterprise",
+			edition:     internal.EditionEnterprise,
+			version:     16,
+			want:        []string{"all", "enterprise", "sql2016"},
+			wantSkipped: map[string]string{},
+		},
+		{
+			name:        "unknown edition and version",
+			edition:     internal.EditionUnknown,
+			want:        []string{"all", "enterprise", "sql2016"},
+			wantSkipped: map[string]string{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, skipped := applicableRules(tc.edition, tc.version, master)
+			if diff := cmp.Diff(names(got), tc.want); diff != "" {
+				t.Errorf("applicableRules(%q, %d) returned wrong rules (-got +want):\n%s", tc.edition, tc.version, diff)
+			}
+			if diff := cmp.Diff(skipped, tc.wantSkipped); diff != "" {
+				t.Errorf("applicableRules(%q, %d) returned wrong skipped reasons (-got +want):\n%s", tc.edition, tc.version, diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package sqlcollector
 
 import (
 	"context"
+	"net"
 	"time"
 
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
@@ -29,3 +30,17 @@ import (
 type SQLCollector interface {
 	CollectMasterRules(context.Context, time.Duration) []internal.Details
 }
+
+// Dialer opens the connections to SQL Server.
+// *net.Dialer and remote.TunnelDialer both implement it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// AccessTokenDialer is a Dialer whose connections are authenticated by access tokens instead of
+// the user and password of the connection string.
+// iamproxy.Dialer implements it.
+type AccessTokenDialer interface {
+	Dialer
+	AccessToken(ctx context.Context) (string, error)
+}
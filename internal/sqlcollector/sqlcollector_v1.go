@@ -19,65 +19,655 @@ package sqlcollector
 import (
 	"context"
 	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/cyclestatus"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/dbcache"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/errorlog"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/manifest"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/rulecache"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal/tracing"
 )
 
+// readableSecondaryQuery returns the read-only routing URL of the highest priority readable
+// secondary replica that is connected to the local primary replica. No rows are returned when
+// the local replica is not a primary or no readable secondary is routable.
+const readableSecondaryQuery = `SELECT TOP 1 ar.read_only_routing_url
+					FROM sys.dm_hadr_availability_replica_states AS local_rs
+						INNER JOIN sys.availability_read_only_routing_lists AS rl ON rl.replica_id = local_rs.replica_id
+						INNER JOIN sys.availability_replicas AS ar ON ar.replica_id = rl.read_only_replica_id
+						INNER JOIN sys.dm_hadr_availability_replica_states AS rs ON rs.replica_id = ar.replica_id
+					WHERE local_rs.is_local = 1 AND local_rs.role = 1
+						AND rs.role = 2 AND rs.connected_state = 1
+						AND ar.secondary_role_allow_connections > 0
+						AND ar.read_only_routing_url IS NOT NULL
+					ORDER BY rl.routing_priority`
+
+// databaseSignalsQuery returns a signal of the changes of each database: the database is changed
+// when it is recreated, its state or owner changes, or it writes to its transaction log. The bytes
+// written are reset when SQL Server restarts, which changes all databases.
+const databaseSignalsQuery = `SELECT d.name, d.create_date, d.state, d.owner_sid, SUM(vfs.num_of_bytes_written)
+					FROM sys.databases d
+						LEFT JOIN sys.master_files m ON m.database_id = d.database_id AND m.type = 1
+						LEFT JOIN sys.dm_io_virtual_file_stats(NULL, NULL) vfs ON vfs.database_id = m.database_id AND vfs.file_id = m.file_id
+					GROUP BY d.name, d.create_date, d.state, d.owner_sid`
+
+// databaseNamesQuery returns the names of the databases of the sql server.
+const databaseNamesQuery = `SELECT name FROM sys.databases`
+
+// lockTimeoutErrorNumber is the number of the SQL Server error of queries aborted by the lock
+// timeout.
+const lockTimeoutErrorNumber = 1222
+
+// loginFailedErrorNumber is the number of the SQL Server error of logins with invalid credentials.
+const loginFailedErrorNumber = 18456
+
+// openPools are the connection pools of the collectors that are not closed yet.
+var openPools = struct {
+	sync.Mutex
+	pools map[*sql.DB]bool
+}{pools: map[*sql.DB]bool{}}
+
+// OpenConnections returns the number of connections to SQL Server held by the collectors that are
+// not closed yet. A growing number points to collectors that are never closed.
+func OpenConnections() int {
+	openPools.Lock()
+	defer openPools.Unlock()
+	n := 0
+	for db := range openPools.pools {
+		n += db.Stats().OpenConnections
+	}
+	return n
+}
+
 // V1 that execute cmd and connect to SQL server.
 type V1 struct {
 	dbConn             *sql.DB
 	windows            bool
 	usageMetricsLogger agentstatus.AgentStatus
+	// conn is the connection string used for dbConn.
+	conn string
+	// openDB opens a connection with the given connection string. It is used to connect to
+	// readable secondary replicas.
+	openDB func(conn string) (*sql.DB, error)
+	// target identifies the sql server in the logs of repeated rule errors.
+	target string
+	// databaseInclude are the names or glob patterns of the databases the rules reading databases
+	// are restricted to. All databases are collected if it is empty.
+	databaseInclude []string
+	// databaseExclude are the names or glob patterns of the databases the rules reading databases
+	// leave out. It is ignored if databaseInclude is set.
+	databaseExclude []string
+	// workloadType is the workload type of the sql server. The rules that skip it are not run.
+	workloadType string
+	// settings are the thresholds and options the master rules are collected with.
+	settings internal.RuleSettings
 }
 
 // NewV1 initializes a V1 instance.
-func NewV1(driver, conn string, windows bool, usageMetricsLogger agentstatus.AgentStatus) (*V1, error) {
-	dbConn, err := sql.Open(driver, conn)
+// If dialer is not nil, it is used for all connections to SQL Server. The connections are
+// authenticated by the access tokens of the dialer if it is an AccessTokenDialer.
+func NewV1(driver, conn string, windows bool, usageMetricsLogger agentstatus.AgentStatus, dialer Dialer) (*V1, error) {
+	if dialer != nil && driver != "sqlserver" {
+		return nil, fmt.Errorf("custom dialer is not supported for driver %q", driver)
+	}
+	openDB := func(conn string) (*sql.DB, error) {
+		if dialer == nil {
+			return sql.Open(driver, conn)
+		}
+		var connector *mssql.Connector
+		var err error
+		if d, ok := dialer.(AccessTokenDialer); ok {
+			connector, err = mssql.NewConnectorWithAccessTokenProvider(conn, d.AccessToken)
+		} else {
+			connector, err = mssql.NewConnector(conn)
+		}
+		if err != nil {
+			return nil, err
+		}
+		connector.Dialer = dialer
+		return sql.OpenDB(connector), nil
+	}
+	dbConn, err := openDB(conn)
 	if err != nil {
 		return nil, err
 	}
-	return &V1{dbConn: dbConn, windows: windows, usageMetricsLogger: usageMetricsLogger}, nil
+	openPools.Lock()
+	openPools.pools[dbConn] = true
+	openPools.Unlock()
+	return &V1{dbConn: dbConn, windows: windows, usageMetricsLogger: usageMetricsLogger, conn: conn, openDB: openDB, target: connTarget(conn), settings: internal.DefaultRuleSettings()}, nil
+}
+
+// SetDatabaseFilter restricts the rules reading databases to the databases matching the names or
+// glob patterns of include, if any, or else to the databases not matching the ones of exclude. The
+// patterns are matched case-insensitively.
+func (c *V1) SetDatabaseFilter(include, exclude []string) {
+	c.databaseInclude = include
+	c.databaseExclude = exclude
+}
+
+// SetRuleSettings sets the thresholds and options the master rules are collected with.
+func (c *V1) SetRuleSettings(settings internal.RuleSettings) {
+	c.settings = settings
+}
+
+// SetWorkloadType skips the master rules that do not apply to the workload type.
+func (c *V1) SetWorkloadType(workloadType string) {
+	c.workloadType = workloadType
+}
+
+// connTarget returns the host and port of the sql server of the connection string.
+func connTarget(conn string) string {
+	cfg, err := msdsn.Parse(conn)
+	if err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 }
 
 // CollectMasterRules collects master rules from target sql server.
 // Master rules are defined in rules.go file.
+// Rules that prefer a secondary replica run on a readable secondary of the availability group
+// when one is available; all other rules run on the target sql server.
+// Rules that do not apply to the edition or the major version of the target sql server are
+// skipped and the detected edition is reported as SQL_EDITION.
+// In incremental collections, per-database rules only run for the databases that changed since
+// their last collection from the target sql server.
+// Cacheable rules report their previous results from the target sql server until the results are
+// older than the rule cache ttl, or the edition or version of the target sql server changes.
+// The outcome of each rule is recorded in the collection cycle of ctx, if any; ignored errors do
+// not fail the rule. If the collection manifest is enabled, the outcome of each master rule and
+// the reason it was skipped or failed are also reported as the collection manifest.
 func (c *V1) CollectMasterRules(ctx context.Context, timeout time.Duration) []internal.Details {
 	details := []internal.Details{}
-	for _, rule := range internal.MasterRules {
+	edition, version := internal.EditionUnknown, 0
+	caching := c.settings.RuleCacheTTL > 0 && cacheable(internal.MasterRules)
+	if restrictedByEdition(internal.MasterRules) || restrictedByVersion(internal.MasterRules) || caching {
+		edition, version = c.edition(ctx, timeout)
+		details = append(details, internal.Details{
+			Name:   "SQL_EDITION",
+			Fields: []map[string]string{{"edition": edition}},
+		})
+		if caching && edition != internal.EditionUnknown {
+			rulecache.Default.SetIdentity(c.target, fmt.Sprintf("%s/%d", edition, version))
+		}
+	}
+	var secondary *sql.DB
+	if preferSecondary(internal.MasterRules) {
+		secondary = c.secondaryConnection(ctx, timeout)
+		if secondary != nil {
+			defer secondary.Close()
+		}
+	}
+	var signals map[string]string
+	if c.settings.IncrementalCollection && perDatabase(internal.MasterRules) {
+		signals = c.databaseSignals(ctx, timeout)
+	}
+	var included []string
+	if (len(c.databaseInclude) > 0 || len(c.databaseExclude) > 0) && readsDatabases(internal.MasterRules) {
+		included = c.includedDatabases(ctx, timeout, signals)
+		if signals != nil {
+			signals = includedSignals(signals, included)
+		}
+	}
+	rules, skipped := applicableRules(edition, version, internal.MasterRules)
+	cycle := cyclestatus.FromContext(ctx)
+	m := manifest.New()
+	for _, rule := range rules {
 		func() {
-			ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+			if !rule.AppliesToWorkload(c.workloadType) {
+				log.Logger.Debugw("Skipping rule that does not apply to the workload type", "rule", rule.Name, "workload_type", c.workloadType)
+				m.Add(rule.Name, manifest.Skipped, manifest.SkippedWorkload)
+				return
+			}
+			if rule.Enabled != nil && !rule.Enabled(c.settings) {
+				log.Logger.Debugw("Skipping rule that is not enabled", "rule", rule.Name)
+				m.Add(rule.Name, manifest.Skipped, manifest.SkippedDisabled)
+				return
+			}
+			var ruleErr error
+			defer func() { cycle.Rule(ruleErr) }()
+			ruleCtx, endSpan := tracing.StartRule(ctx, rule.Name)
+			ctxWithTimeout, cancel := context.WithTimeout(ruleCtx, timeout)
 			defer cancel()
-			queryResult, err := c.executeSQL(ctxWithTimeout, rule.Query)
+			cached := rule.Cacheable && c.settings.RuleCacheTTL > 0
+			if cached {
+				if fields, ok := rulecache.Default.Get(c.target, rule.Name, c.settings.RuleCacheTTL); ok {
+					log.Logger.Debugw("Reporting cached results of rule", "rule", rule.Name)
+					endSpan(nil)
+					m.Add(rule.Name, manifest.Skipped, manifest.SkippedCached)
+					details = append(details, internal.Details{Name: rule.Name, Fields: fields})
+					return
+				}
+			}
+			args := ruleArgs(rule, c.settings, included)
+			var changed []string
+			if rule.PerDatabase && signals != nil {
+				changed = dbcache.Default.Changed(c.target, rule.Name, signals)
+				if len(changed) == 0 {
+					log.Logger.Debugw("Skipping per-database rule as no database changed", "rule", rule.Name)
+					endSpan(nil)
+					m.Add(rule.Name, manifest.Skipped, manifest.SkippedUnchanged)
+					details = append(details, internal.Details{
+						Name:   rule.Name,
+						Fields: dbcache.Default.Update(c.target, rule.Name, signals, nil, nil),
+					})
+					return
+				}
+				args = ruleArgs(rule, c.settings, changed)
+			}
+			db := c.dbConn
+			if secondary != nil && rule.CanRunOnSecondary && rule.PreferSecondary {
+				db = secondary
+			}
+			release, err := internal.AcquireRule(ctxWithTimeout)
+			if err != nil {
+				ruleErr = err
+				endSpan(err)
+				log.Logger.Errorw("Timed out waiting for the rules running at the same time", "rule", rule.Name, "error", err)
+				m.Add(rule.Name, manifest.Failed, manifest.FailedTimeout)
+				return
+			}
+			queryResult, err := executeSQL(ctxWithTimeout, db, c.settings.LockTimeout, rule.Query, args...)
+			if err != nil && db != c.dbConn {
+				log.Logger.Warnw("Failed to run sql query on secondary replica, falling back to primary", "rule", rule.Name, "error", err)
+				queryResult, err = executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, rule.Query, args...)
+			}
+			release()
+			endSpan(err)
+			key := errorlog.Key(c.target, rule.Name)
+			if ignoredError(err, c.settings.IgnoredErrorNumbers) {
+				log.Logger.Debugw("Ignoring sql query error", "rule", rule.Name, "error", err)
+				m.Add(rule.Name, manifest.Skipped, manifest.SkippedIgnoredError)
+				return
+			}
+			if lockTimedOut(err) {
+				ruleErr = err
+				errorlog.Default.Failed(key, err, "Sql query aborted by the lock timeout", "lock_timeout", c.settings.LockTimeout)
+				c.usageMetricsLogger.Error(agentstatus.SQLLockTimeoutError)
+				m.Add(rule.Name, manifest.Failed, manifest.FailedLockTimeout)
+				return
+			}
 			if err != nil {
-				log.Logger.Errorw("Failed to run sql query", "query", rule.Query, "error", err)
+				ruleErr = err
+				errorlog.Default.Failed(key, err, "Failed to run sql query", "query", rule.Query)
 				c.usageMetricsLogger.Error(agentstatus.SQLQueryExecutionError)
+				m.Add(rule.Name, manifest.Failed, manifest.FailedQuery)
+				return
+			}
+			fields, err := rule.Results(queryResult, c.resultSettings())
+			if err != nil {
+				ruleErr = err
+				errorlog.Default.Failed(key, err, "Failed to post-process sql query results")
+				m.Add(rule.Name, manifest.Failed, manifest.FailedPostProcessor)
 				return
 			}
+			errorlog.Default.Succeeded(key)
+			m.Add(rule.Name, manifest.Ran, "")
+			if rule.PerDatabase && signals != nil {
+				fields = dbcache.Default.Update(c.target, rule.Name, signals, changed, fields)
+			}
+			if cached {
+				rulecache.Default.Put(c.target, rule.Name, fields)
+			}
 			details = append(details, internal.Details{
 				Name:   rule.Name,
-				Fields: rule.Fields(queryResult),
+				Fields: fields,
 			})
 		}()
 	}
+	if c.settings.ReportCollectionManifest {
+		for rule, reason := range skipped {
+			m.Add(rule, manifest.Skipped, reason)
+		}
+		details = append(details, m.Details())
+	}
 	return details
 }
 
+// resultSettings returns the settings the result of a query that just returned is read with.
+func (c *V1) resultSettings() internal.RuleSettings {
+	settings := c.settings
+	settings.AgentTime = time.Now()
+	return settings
+}
+
+// ignoredError reports whether err is a SQL Server error whose number is one of the ignored error
+// numbers.
+func ignoredError(err error, ignored map[int32]bool) bool {
+	var sqlErr mssql.Error
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	return ignored[sqlErr.Number]
+}
+
+// lockTimedOut reports whether err is a SQL Server error of a query aborted by the lock timeout.
+func lockTimedOut(err error) bool {
+	var sqlErr mssql.Error
+	return errors.As(err, &sqlErr) && sqlErr.Number == lockTimeoutErrorNumber
+}
+
+// LoginFailed reports whether err is a SQL Server error of a login with invalid credentials.
+func LoginFailed(err error) bool {
+	var sqlErr mssql.Error
+	return errors.As(err, &sqlErr) && sqlErr.Number == loginFailedErrorNumber
+}
+
+// cacheable reports whether any of the rules is cacheable.
+func cacheable(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if rule.Cacheable {
+			return true
+		}
+	}
+	return false
+}
+
+// perDatabase reports whether any of the rules is a per-database rule.
+func perDatabase(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if rule.PerDatabase {
+			return true
+		}
+	}
+	return false
+}
+
+// readsDatabases reports whether any of the rules reads the databases listed in @databases.
+func readsDatabases(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if rule.ReadsDatabases() {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleArgs returns the query arguments of the rule for the settings. Rules reading databases are
+// restricted to the databases, or read all databases if databases is nil.
+func ruleArgs(rule internal.MasterRuleStruct, settings internal.RuleSettings, databases []string) []any {
+	var args []any
+	if rule.Args != nil {
+		args = rule.Args(settings)
+	}
+	if !rule.ReadsDatabases() {
+		return args
+	}
+	if databases == nil {
+		return append(args, sql.Named("databases", nil))
+	}
+	return append(args, sql.Named("databases", databaseList(databases)))
+}
+
+// databaseList returns the databases as the xml list read by per-database rules.
+func databaseList(databases []string) string {
+	var b strings.Builder
+	for _, d := range databases {
+		b.WriteString("<db>")
+		xml.EscapeText(&b, []byte(d))
+		b.WriteString("</db>")
+	}
+	return b.String()
+}
+
+// includedDatabases returns the databases of the sql server matching the database include
+// patterns, or not matching the exclude patterns if there are no include patterns. The signals are
+// used as the list of databases if they were read. If the databases cannot be read, no database is
+// collected rather than all of them.
+func (c *V1) includedDatabases(ctx context.Context, timeout time.Duration, signals map[string]string) []string {
+	var names []string
+	if signals != nil {
+		for name := range signals {
+			names = append(names, name)
+		}
+	} else {
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		rows, err := executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, databaseNamesQuery)
+		if err != nil {
+			log.Logger.Warnw("Failed to read the databases to include, skipping the rules reading databases", "error", err)
+			return []string{}
+		}
+		for _, r := range rows {
+			names = append(names, internal.HandleNilString(r[0]))
+		}
+	}
+	included := []string{}
+	for _, name := range names {
+		if len(c.databaseInclude) > 0 && databaseIncluded(c.databaseInclude, name) ||
+			len(c.databaseInclude) == 0 && !databaseIncluded(c.databaseExclude, name) {
+			included = append(included, name)
+		}
+	}
+	sort.Strings(included)
+	if len(included) == 0 {
+		log.Logger.Warnw("No database is included, skipping the rules reading databases", "database_include", c.databaseInclude, "database_exclude", c.databaseExclude)
+	}
+	return included
+}
+
+// databaseIncluded reports whether the name of the database matches one of the names or glob
+// patterns, ignoring case.
+func databaseIncluded(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if ok, err := path.Match(strings.ToLower(p), name); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// includedSignals returns the signals of the included databases only.
+func includedSignals(signals map[string]string, included []string) map[string]string {
+	res := make(map[string]string, len(included))
+	for _, name := range included {
+		res[name] = signals[name]
+	}
+	return res
+}
+
+// databaseSignals returns the change signal of each database of the sql server.
+// It returns nil if the signals cannot be read, in which case all databases are collected.
+func (c *V1) databaseSignals(ctx context.Context, timeout time.Duration) map[string]string {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rows, err := executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, databaseSignalsQuery)
+	if err != nil {
+		log.Logger.Warnw("Failed to read database changes, collecting all databases", "error", err)
+		return nil
+	}
+	signals := map[string]string{}
+	for _, r := range rows {
+		signals[internal.HandleNilString(r[0])] = fmt.Sprintf("%v|%v|%x|%v", r[1], r[2], r[3], r[4])
+	}
+	return signals
+}
+
+// preferSecondary reports whether any of the rules should run on a readable secondary replica.
+func preferSecondary(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if rule.CanRunOnSecondary && rule.PreferSecondary {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedByEdition reports whether any of the rules only applies to some sql server editions.
+func restrictedByEdition(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if len(rule.Editions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedByVersion reports whether any of the rules only applies to some sql server versions.
+func restrictedByVersion(rules []internal.MasterRuleStruct) bool {
+	for _, rule := range rules {
+		if rule.MinMajorVersion > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// edition detects the edition and the major version of the target sql server.
+// EditionUnknown and zero are returned if they cannot be detected.
+func (c *V1) edition(ctx context.Context, timeout time.Duration) (string, int) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	res, err := executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, internal.EditionQuery)
+	if err != nil {
+		log.Logger.Warnw("Failed to detect the sql server edition and version, running all rules", "error", err)
+		return internal.EditionUnknown, 0
+	}
+	return internal.Edition(res), internal.MajorVersion(res)
+}
+
+// secondaryConnection discovers a readable secondary replica of the target sql server and opens
+// a read-only connection to it. It returns nil if no readable secondary is available.
+func (c *V1) secondaryConnection(ctx context.Context, timeout time.Duration) *sql.DB {
+	if c.openDB == nil {
+		return nil
+	}
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	res, err := executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, readableSecondaryQuery)
+	if err != nil {
+		log.Logger.Debugw("Failed to discover readable secondary replica", "error", err)
+		return nil
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	routingURL := internal.HandleNilString(res[0][0])
+	conn, err := secondaryConnString(c.conn, routingURL)
+	if err != nil {
+		log.Logger.Warnw("Invalid read-only routing url", "url", routingURL, "error", err)
+		return nil
+	}
+	db, err := c.openDB(conn)
+	if err != nil {
+		log.Logger.Warnw("Failed to open connection to secondary replica", "url", routingURL, "error", err)
+		return nil
+	}
+	if err := db.PingContext(ctxWithTimeout); err != nil {
+		log.Logger.Warnw("Failed to connect to secondary replica", "url", routingURL, "error", err)
+		db.Close()
+		return nil
+	}
+	log.Logger.Debugw("Using readable secondary replica for heavy rules", "url", routingURL)
+	return db
+}
+
+// secondaryConnString returns the connection string to the replica at routingURL, which is in
+// the form of TCP://<host>:<port>. The credentials and the other settings of conn are reused and
+// the connection is opened with read-only application intent, to the master database unless conn
+// sets the database.
+func secondaryConnString(conn, routingURL string) (string, error) {
+	u, err := url.Parse(routingURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(u.Scheme, "tcp") || u.Hostname() == "" {
+		return "", fmt.Errorf("unsupported routing url %q", routingURL)
+	}
+	cfg, err := msdsn.Parse(conn)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	for k, v := range cfg.Parameters {
+		switch k {
+		case "server", "port", "user id", "password", "applicationintent":
+			continue
+		}
+		query.Set(k, v)
+	}
+	// go-mssqldb requires a database for read-only application intent.
+	if query.Get("database") == "" {
+		query.Set("database", "master")
+	}
+	query.Set("applicationintent", "ReadOnly")
+	res := url.URL{Scheme: "sqlserver", Host: u.Host, RawQuery: query.Encode()}
+	if user, ok := cfg.Parameters["user id"]; ok {
+		res.User = url.UserPassword(user, cfg.Parameters["password"])
+	}
+	return res.String(), nil
+}
+
+// RunRule runs the master rule with the given name.
+// It returns the raw query result along with the details transformed by the rule.
+func (c *V1) RunRule(ctx context.Context, name string, timeout time.Duration) ([][]any, internal.Details, error) {
+	for _, rule := range internal.MasterRules {
+		if rule.Name != name {
+			continue
+		}
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		queryResult, err := executeSQL(ctxWithTimeout, c.dbConn, c.settings.LockTimeout, rule.Query, ruleArgs(rule, c.settings, nil)...)
+		if err != nil {
+			return nil, internal.Details{}, err
+		}
+		fields, err := rule.Results(queryResult, c.resultSettings())
+		if err != nil {
+			return nil, internal.Details{}, err
+		}
+		return queryResult, internal.Details{Name: rule.Name, Fields: fields}, nil
+	}
+	return nil, internal.Details{}, fmt.Errorf("master rule %q not found", name)
+}
+
 // Close closes the database collection.
 func (c *V1) Close() error {
+	openPools.Lock()
+	delete(openPools.pools, c.dbConn)
+	openPools.Unlock()
 	return c.dbConn.Close()
 }
 
-func (c *V1) executeSQL(ctx context.Context, query string) ([][]any, error) {
-	err := c.dbConn.PingContext(ctx)
+// Ping connects to the sql server to verify that the connection string can log in.
+func (c *V1) Ping(ctx context.Context) error {
+	return c.dbConn.PingContext(ctx)
+}
+
+// executeSQL runs the query on db and returns all rows of the result set.
+// The query is aborted on the server when ctx is done: go-mssqldb sends a TDS attention signal
+// and waits for the server to confirm the cancellation, so queries that time out do not keep
+// running on the sql server.
+// The query waits for locks for at most lockTimeout, or indefinitely if it is zero. The lock timeout
+// is set in the same batch as the query since pooled connections are reset before they are reused.
+func executeSQL(ctx context.Context, db *sql.DB, lockTimeout time.Duration, query string, args ...any) ([][]any, error) {
+	err := db.PingContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if lockTimeout > 0 {
+		query = fmt.Sprintf("SET LOCK_TIMEOUT %d;\n", lockTimeout.Milliseconds()) + query
+	}
 	// Execute query
-	rows, err := c.dbConn.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if lockTimedOut(err) {
+		return nil, fmt.Errorf("query exceeded the lock timeout of %v: %w", lockTimeout, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -102,5 +692,11 @@ func (c *V1) executeSQL(ctx context.Context, query string) ([][]any, error) {
 		res = append(res, row)
 
 	}
+	// rows.Next stops early when the query is canceled while the rows are read.
+	if err := rows.Err(); lockTimedOut(err) {
+		return nil, fmt.Errorf("query exceeded the lock timeout of %v: %w", lockTimeout, err)
+	} else if err != nil {
+		return nil, err
+	}
 	return res, nil
 }
@@ -17,14 +17,22 @@ limitations under the License.
 package sqlcollector
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/google/go-cmp/cmp"
-	_ "github.com/microsoft/go-mssqldb"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal/agentstatus"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
@@ -34,6 +42,9 @@ var fakeCloudProperties = agentstatus.NewCloudProperties("testProjectID", "testZ
 var fakeAgentProperties = agentstatus.NewAgentProperties("testName", "testVersion", false)
 var fakeUsageMetricsLogger = agentstatus.NewUsageMetricsLogger(fakeAgentProperties, fakeCloudProperties, clockwork.NewRealClock(), []string{})
 
+// masterRules are the master rules of the agent, which some tests replace.
+var masterRules = internal.MasterRules
+
 func TestCollectMasterRules(t *testing.T) {
 	testcases := []struct {
 		name         string
@@ -57,7 +68,7 @@ func TestCollectMasterRules(t *testing.T) {
 				{
 					Name:  "testRule",
 					Query: "testQuery",
-					Fields: func(fields [][]any) []map[string]string {
+					Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
 						return []map[string]string{
 							map[string]string{
 								"col1": internal.HandleNilString(fields[0][0]),
@@ -104,7 +115,7 @@ func TestCollectMasterRules(t *testing.T) {
 				{
 					Name:   "testRule",
 					Query:  "testQuery",
-					Fields: func(fields [][]any) []map[string]string { return []map[string]string{} },
+					Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string { return []map[string]string{} },
 				},
 			},
 			want: []internal.Details{
@@ -163,10 +174,131 @@ func TestCollectMasterRules(t *testing.T) {
 	}
 }
 
+func TestIgnoredError(t *testing.T) {
+	ignored := map[int32]bool{1222: true}
+	testcases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "ignored error number",
+			err:  mssql.Error{Number: 1222, Message: "Lock request time out period exceeded."},
+			want: true,
+		},
+		{
+			name: "wrapped ignored error number",
+			err:  fmt.Errorf("query failed: %w", mssql.Error{Number: 1222}),
+			want: true,
+		},
+		{
+			name: "other error number",
+			err:  mssql.Error{Number: 208, Message: "Invalid object name."},
+		},
+		{
+			name: "not a sql server error",
+			err:  errors.New("new error"),
+		},
+		{
+			name: "no error",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ignoredError(tc.err, ignored); got != tc.want {
+				t.Errorf("ignoredError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunRule(t *testing.T) {
+	internal.MasterRules = []internal.MasterRuleStruct{
+		{
+			Name:  "testRule",
+			Query: "testQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{
+					map[string]string{"col1": internal.HandleNilString(fields[0][0])},
+				}
+			},
+		},
+	}
+	testcases := []struct {
+		name       string
+		rule       string
+		queryError bool
+		wantRaw    [][]any
+		want       internal.Details
+		wantErr    bool
+	}{
+		{
+			name:    "success",
+			rule:    "testRule",
+			wantRaw: [][]any{{"row1"}},
+			want: internal.Details{
+				Name:   "testRule",
+				Fields: []map[string]string{map[string]string{"col1": "row1"}},
+			},
+		},
+		{
+			name:    "rule not found",
+			rule:    "anyRule",
+			wantErr: true,
+		},
+		{
+			name:       "sql query returns error",
+			rule:       "testRule",
+			queryError: true,
+			wantErr:    true,
+		},
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			switch {
+			case tc.queryError:
+				mock.ExpectQuery("testQuery").WillReturnError(errors.New("new error"))
+			case !tc.wantErr:
+				mock.ExpectQuery("testQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("row1"))
+			}
+			gotRaw, got, err := c.RunRule(context.Background(), tc.rule, time.Second)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("RunRule(%q) = %v, want error presence = %v", tc.rule, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(gotRaw, tc.wantRaw); diff != "" {
+				t.Errorf("RunRule(%q) returned wrong raw result (-got +want):\n%s", tc.rule, diff)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("RunRule(%q) returned wrong result (-got +want):\n%s", tc.rule, diff)
+			}
+		})
+	}
+}
+
+type fakeAccessTokenDialer struct {
+	net.Dialer
+}
+
+func (*fakeAccessTokenDialer) AccessToken(context.Context) (string, error) {
+	return "token", nil
+}
+
 func TestNewV1(t *testing.T) {
 	testcases := []struct {
 		name    string
 		driver  string
+		dialer  Dialer
 		wantErr bool
 	}{
 		{
@@ -178,10 +310,26 @@ func TestNewV1(t *testing.T) {
 			driver:  "any",
 			wantErr: true,
 		},
+		{
+			name:   "success with dialer",
+			driver: "sqlserver",
+			dialer: &net.Dialer{},
+		},
+		{
+			name:   "success with access token dialer",
+			driver: "sqlserver",
+			dialer: &fakeAccessTokenDialer{},
+		},
+		{
+			name:    "error with dialer for unsupported driver",
+			driver:  "any",
+			dialer:  &net.Dialer{},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testcases {
-		_, err := NewV1(tc.driver, "", true, fakeUsageMetricsLogger)
+		_, err := NewV1(tc.driver, "", true, fakeUsageMetricsLogger, tc.dialer)
 		if gotErr := err != nil; gotErr != tc.wantErr {
 			t.Errorf("NewV1() = %v, want error presence = %v", err, tc.wantErr)
 		}
@@ -189,7 +337,7 @@ func TestNewV1(t *testing.T) {
 }
 
 func TestClose(t *testing.T) {
-	c, err := NewV1("sqlserver", "", true, fakeUsageMetricsLogger)
+	c, err := NewV1("sqlserver", "", true, fakeUsageMetricsLogger, nil)
 	if err != nil {
 		t.Errorf("NewV1() = %v, want nil", err)
 	}
@@ -197,3 +345,953 @@ func TestClose(t *testing.T) {
 		t.Errorf("Close() = %v, want nil", err)
 	}
 }
+
+func TestOpenConnections(t *testing.T) {
+	_, mock, err := sqlmock.NewWithDSN("open-connections")
+	if err != nil {
+		t.Fatalf("sqlmock.NewWithDSN() returned error: %v", err)
+	}
+	mock.ExpectClose()
+	c, err := NewV1("sqlmock", "open-connections", true, fakeUsageMetricsLogger, nil)
+	if err != nil {
+		t.Fatalf("NewV1() returned error: %v", err)
+	}
+	if err := c.dbConn.Ping(); err != nil {
+		t.Fatalf("Ping() returned error: %v", err)
+	}
+	if got := OpenConnections(); got != 1 {
+		t.Errorf("OpenConnections() = %d, want 1", got)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if got := OpenConnections(); got != 0 {
+		t.Errorf("OpenConnections() after Close() = %d, want 0", got)
+	}
+}
+
+func TestCollectMasterRulesOnSecondary(t *testing.T) {
+	rule := func(name string, preferSecondary bool) internal.MasterRuleStruct {
+		return internal.MasterRuleStruct{
+			Name:  name,
+			Query: name + "Query",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{map[string]string{"col1": internal.HandleNilString(fields[0][0])}}
+			},
+			CanRunOnSecondary: preferSecondary,
+			PreferSecondary:   preferSecondary,
+		}
+	}
+	testcases := []struct {
+		name          string
+		routingURL    string
+		secondaryFail bool
+		want          []internal.Details
+	}{
+		{
+			name:       "heavy rule runs on secondary",
+			routingURL: "TCP://secondary.example.com:1433",
+			want: []internal.Details{
+				{Name: "light", Fields: []map[string]string{map[string]string{"col1": "primary"}}},
+				{Name: "heavy", Fields: []map[string]string{map[string]string{"col1": "secondary"}}},
+			},
+		},
+		{
+			name: "no readable secondary",
+			want: []internal.Details{
+				{Name: "light", Fields: []map[string]string{map[string]string{"col1": "primary"}}},
+				{Name: "heavy", Fields: []map[string]string{map[string]string{"col1": "primary"}}},
+			},
+		},
+		{
+			name:          "secondary query fails falls back to primary",
+			routingURL:    "TCP://secondary.example.com:1433",
+			secondaryFail: true,
+			want: []internal.Details{
+				{Name: "light", Fields: []map[string]string{map[string]string{"col1": "primary"}}},
+				{Name: "heavy", Fields: []map[string]string{map[string]string{"col1": "primary"}}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			internal.MasterRules = []internal.MasterRuleStruct{rule("light", false), rule("heavy", true)}
+			primary, primaryMock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer primary.Close()
+			secondary, secondaryMock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			var gotConn string
+			c := V1{
+				dbConn:             primary,
+				usageMetricsLogger: fakeUsageMetricsLogger,
+				conn:               "server=primary;user id=user;password=pass;port=1433;",
+				openDB: func(conn string) (*sql.DB, error) {
+					gotConn = conn
+					return secondary, nil
+				},
+			}
+
+			routing := sqlmock.NewRows([]string{"read_only_routing_url"})
+			if tc.routingURL != "" {
+				routing.AddRow(tc.routingURL)
+			}
+			primaryMock.ExpectQuery("read_only_routing_url").WillReturnRows(routing)
+			primaryMock.ExpectQuery("lightQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("primary"))
+			switch {
+			case tc.routingURL == "":
+				primaryMock.ExpectQuery("heavyQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("primary"))
+			case tc.secondaryFail:
+				secondaryMock.ExpectQuery("heavyQuery").WillReturnError(errors.New("new error"))
+				primaryMock.ExpectQuery("heavyQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("primary"))
+			default:
+				secondaryMock.ExpectQuery("heavyQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("secondary"))
+			}
+
+			got := c.CollectMasterRules(context.Background(), time.Second)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("CollectMasterRules returned wrong result (-got +want):\n%s", diff)
+			}
+			if err := primaryMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("primary expectations were not met: %v", err)
+			}
+			if err := secondaryMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("secondary expectations were not met: %v", err)
+			}
+			if tc.routingURL != "" {
+				if cfg, err := msdsn.Parse(gotConn); err != nil || !cfg.ReadOnlyIntent {
+					t.Errorf("secondary connection string = %q, want read-only application intent", gotConn)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectMasterRulesByEdition(t *testing.T) {
+	rule := func(name string, editions ...string) internal.MasterRuleStruct {
+		return internal.MasterRuleStruct{
+			Name:  name,
+			Query: name + "Query",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{map[string]string{"col1": internal.HandleNilString(fields[0][0])}}
+			},
+			Editions: editions,
+		}
+	}
+	testcases := []struct {
+		name         string
+		editionRows  *sqlmock.Rows
+		editionError bool
+		wantQueries  []string
+		want         []internal.Details
+	}{
+		{
+			name:        "enterprise runs all rules",
+			editionRows: sqlmock.NewRows([]string{"engine_edition", "edition"}).AddRow(int64(3), "Enterprise Edition (64-bit)"),
+			wantQueries: []string{"allQuery", "enterpriseQuery"},
+			want: []internal.Details{
+				{Name: "SQL_EDITION", Fields: []map[string]string{map[string]string{"edition": internal.EditionEnterprise}}},
+				{Name: "all", Fields: []map[string]string{map[string]string{"col1": "val"}}},
+				{Name: "enterprise", Fields: []map[string]string{map[string]string{"col1": "val"}}},
+			},
+		},
+		{
+			name:        "express skips unsupported rules",
+			editionRows: sqlmock.NewRows([]string{"engine_edition", "edition"}).AddRow(int64(4), "Express Edition (64-bit)"),
+			wantQueries: []string{"allQuery"},
+			want: []internal.Details{
+				{Name: "SQL_EDITION", Fields: []map[string]string{map[string]string{"edition": internal.EditionExpress}}},
+				{Name: "all", Fields: []map[string]string{map[string]string{"col1": "val"}}},
+			},
+		},
+		{
+			name:         "edition detection fails runs all rules",
+			editionError: true,
+			wantQueries:  []string{"allQuery", "enterpriseQuery"},
+			want: []internal.Details{
+				{Name: "SQL_EDITION", Fields: []map[string]string{map[string]string{"edition": internal.EditionUnknown}}},
+				{Name: "all", Fields: []map[string]string{map[string]string{"col1": "val"}}},
+				{Name: "enterprise", Fields: []map[string]string{map[string]string{"col1": "val"}}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			internal.MasterRules = []internal.MasterRuleStruct{rule("all"), rule("enterprise", internal.EditionEnterprise)}
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			c := V1{
+				dbConn:             db,
+				usageMetricsLogger: fakeUsageMetricsLogger,
+			}
+
+			if tc.editionError {
+				mock.ExpectQuery("EngineEdition").WillReturnError(errors.New("new error"))
+			} else {
+				mock.ExpectQuery("EngineEdition").WillReturnRows(tc.editionRows)
+			}
+			for _, q := range tc.wantQueries {
+				mock.ExpectQuery(q).WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("val"))
+			}
+
+			got := c.CollectMasterRules(context.Background(), time.Second)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("CollectMasterRules returned wrong result (-got +want):\n%s", diff)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("expectations were not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestSecondaryConnString(t *testing.T) {
+	testcases := []struct {
+		name       string
+		conn       string
+		routingURL string
+		wantHost   string
+		wantPort   uint64
+		wantUser   string
+		wantPass   string
+		wantDB     string
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			conn:       "server=primary;user id=user;password=pass;port=1433;",
+			routingURL: "TCP://secondary.example.com:1434",
+			wantHost:   "secondary.example.com",
+			wantPort:   1434,
+			wantUser:   "user",
+			wantPass:   "pass",
+			wantDB:     "master",
+		},
+		{
+			name:       "no port and other settings",
+			conn:       "server=primary\\inst;user id=user;password=p@ss:w/rd?;database=db1",
+			routingURL: "tcp://10.0.0.2",
+			wantHost:   "10.0.0.2",
+			wantUser:   "user",
+			wantPass:   "p@ss:w/rd?",
+			wantDB:     "db1",
+		},
+		{
+			name:       "unsupported scheme",
+			conn:       "server=primary;",
+			routingURL: "http://secondary:1433",
+			wantErr:    true,
+		},
+		{
+			name:       "missing host",
+			conn:       "server=primary;",
+			routingURL: "TCP://:1433",
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := secondaryConnString(tc.conn, tc.routingURL)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("secondaryConnString(%q, %q) = %v, want error presence = %v", tc.conn, tc.routingURL, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			cfg, err := msdsn.Parse(got)
+			if err != nil {
+				t.Fatalf("secondaryConnString(%q, %q) = %q, which is not a valid connection string: %v", tc.conn, tc.routingURL, got, err)
+			}
+			if cfg.Host != tc.wantHost || cfg.Instance != "" || cfg.Port != tc.wantPort || cfg.User != tc.wantUser || cfg.Password != tc.wantPass || cfg.Database != tc.wantDB || !cfg.ReadOnlyIntent {
+				t.Errorf("secondaryConnString(%q, %q) = %q, want host %q, port %d, user %q, password %q, database %q and read-only intent", tc.conn, tc.routingURL, got, tc.wantHost, tc.wantPort, tc.wantUser, tc.wantPass, tc.wantDB)
+			}
+		})
+	}
+}
+
+// tdsPacket reads a TDS message from r and returns its type and payload.
+func tdsPacket(r io.Reader) (byte, []byte, error) {
+	var payload []byte
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, nil, err
+		}
+		data := make([]byte, int(binary.BigEndian.Uint16(header[2:4]))-len(header))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, nil, err
+		}
+		payload = append(payload, data...)
+		// The first bit of the status marks the last packet of the message.
+		if header[1]&1 != 0 {
+			return header[0], payload, nil
+		}
+	}
+}
+
+// writeTDSReply writes the payload to w as a TDS reply message.
+func writeTDSReply(w io.Writer, payload []byte) error {
+	header := []byte{4, 1, 0, 0, 0, 0, 1, 0}
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(header)+len(payload)))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// tdsDone returns a DONE token with the status.
+func tdsDone(status uint16) []byte {
+	done := make([]byte, 13)
+	done[0] = 0xFD
+	binary.LittleEndian.PutUint16(done[1:3], status)
+	return done
+}
+
+// fakeSQLServer serves enough of the TDS protocol for go-mssqldb on a local port: it accepts any
+// login without encryption and answers every batch with an empty result, except the batches
+// containing WAITFOR, which run until an attention signal aborts them. The aborted batches are
+// sent to attention.
+func fakeSQLServer(t *testing.T) (string, chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	attention := make(chan string, 1)
+	waitFor := make([]byte, 0, 14)
+	for _, c := range "WAITFOR" {
+		waitFor = append(waitFor, byte(c), 0)
+	}
+	serve := func(conn net.Conn) error {
+		defer conn.Close()
+		// The prelogin response only sets the encryption option: not supported.
+		if _, _, err := tdsPacket(conn); err != nil {
+			return err
+		}
+		if err := writeTDSReply(conn, []byte{1, 0, 6, 0, 1, 0xFF, 2}); err != nil {
+			return err
+		}
+		// The login is acknowledged as TDS 7.4 whatever the credentials.
+		if _, _, err := tdsPacket(conn); err != nil {
+			return err
+		}
+		loginAck := []byte{0xAD, 10, 0, 1, 0x74, 0, 0, 4, 0, 0, 0, 0, 0}
+		if err := writeTDSReply(conn, append(loginAck, tdsDone(0)...)); err != nil {
+			return err
+		}
+		for {
+			typ, batch, err := tdsPacket(conn)
+			if err != nil {
+				return err
+			}
+			if typ == 1 && bytes.Contains(batch, waitFor) {
+				if typ, _, err = tdsPacket(conn); err != nil {
+					return err
+				}
+				// 6 is the type of attention signals, confirmed by a DONE token with the attention bit.
+				if typ == 6 {
+					attention <- "WAITFOR"
+					if err := writeTDSReply(conn, tdsDone(0x20)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := writeTDSReply(conn, tdsDone(0)); err != nil {
+				return err
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return l.Addr().String(), attention
+}
+
+func TestExecuteSQLCanceled(t *testing.T) {
+	addr, attention := fakeSQLServer(t)
+	connector, err := mssql.NewConnector(fmt.Sprintf("sqlserver://user:password@%s?encrypt=disable", addr))
+	if err != nil {
+		t.Fatalf("mssql.NewConnector() = %v", err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = executeSQL(ctx, db, 0, "WAITFOR DELAY '00:10:00'")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("executeSQL() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("executeSQL() returned after %v, want it to return when the context is canceled", elapsed)
+	}
+	select {
+	case <-attention:
+	case <-time.After(time.Second):
+		t.Error("executeSQL() did not abort the query on the server with an attention signal")
+	}
+}
+
+func TestExecuteSQLRowError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	rows := sqlmock.NewRows([]string{"col1"}).AddRow("row1").AddRow("row2").RowError(1, context.Canceled)
+	mock.ExpectQuery("testQuery").WillReturnRows(rows)
+
+	got, err := executeSQL(context.Background(), db, 0, "testQuery")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("executeSQL() = %v, %v, want error %v", got, err, context.Canceled)
+	}
+}
+
+func TestConnTarget(t *testing.T) {
+	testcases := []struct {
+		name string
+		conn string
+		want string
+	}{
+		{
+			name: "host and port",
+			conn: "server=sql.example.com;user id=user;password=pass;port=1434;",
+			want: "sql.example.com:1434",
+		},
+		{
+			name: "invalid connection string",
+			conn: "server=sql.example.com;port=abc;",
+			want: "unknown",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := connTarget(tc.conn); got != tc.want {
+				t.Errorf("connTarget(%q) = %q, want %q", tc.conn, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCollectMasterRulesIncremental(t *testing.T) {
+	internal.MasterRules = []internal.MasterRuleStruct{
+		{
+			Name:  "perDatabase",
+			Query: "perDatabaseQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				res := []map[string]string{}
+				for _, f := range fields {
+					res = append(res, map[string]string{"db_name": internal.HandleNilString(f[0]), "value": internal.HandleNilString(f[1])})
+				}
+				return res
+			},
+			PerDatabase: true,
+		},
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	signalRows := func(db2Written int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"name", "create_date", "state", "owner_sid", "written"}).
+			AddRow("db1", created, int64(0), []byte{1}, int64(100)).
+			AddRow("db2", created, int64(0), []byte{1}, db2Written)
+	}
+	cycles := []struct {
+		name      string
+		signals   *sqlmock.Rows
+		wantArg   string
+		queryRows *sqlmock.Rows
+		want      []map[string]string
+	}{
+		{
+			name:      "first collection runs all databases",
+			signals:   signalRows(100),
+			wantArg:   "<db>db1</db><db>db2</db>",
+			queryRows: sqlmock.NewRows([]string{"db_name", "value"}).AddRow("db1", "a").AddRow("db2", "b"),
+			want:      []map[string]string{{"db_name": "db1", "value": "a"}, {"db_name": "db2", "value": "b"}},
+		},
+		{
+			name:      "changed database runs",
+			signals:   signalRows(200),
+			wantArg:   "<db>db2</db>",
+			queryRows: sqlmock.NewRows([]string{"db_name", "value"}).AddRow("db2", "c"),
+			want:      []map[string]string{{"db_name": "db1", "value": "a"}, {"db_name": "db2", "value": "c"}},
+		},
+		{
+			name:    "unchanged databases are reported from the cache",
+			signals: signalRows(200),
+			want:    []map[string]string{{"db_name": "db1", "value": "a"}, {"db_name": "db2", "value": "c"}},
+		},
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+		target:             "incremental-test:1433",
+		settings:           internal.RuleSettings{IncrementalCollection: true},
+	}
+	for _, cy := range cycles {
+		mock.ExpectQuery("dm_io_virtual_file_stats").WillReturnRows(cy.signals)
+		if cy.queryRows != nil {
+			mock.ExpectQuery("perDatabaseQuery").WithArgs(sql.Named("databases", cy.wantArg)).WillReturnRows(cy.queryRows)
+		}
+		got := c.CollectMasterRules(context.Background(), time.Second)
+		want := []internal.Details{{Name: "perDatabase", Fields: cy.want}}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s: CollectMasterRules returned wrong result (-got +want):\n%s", cy.name, diff)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: expectations were not met: %v", cy.name, err)
+		}
+	}
+}
+
+func TestCollectMasterRulesCached(t *testing.T) {
+	internal.MasterRules = []internal.MasterRuleStruct{
+		{
+			Name:  "cacheable",
+			Query: "cacheableQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{{"value": internal.HandleNilString(fields[0][0])}}
+			},
+			Cacheable: true,
+		},
+		{
+			Name:  "uncached",
+			Query: "uncachedQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{{"value": internal.HandleNilString(fields[0][0])}}
+			},
+		},
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+		target:             "cache-test:1433",
+		settings:           internal.RuleSettings{RuleCacheTTL: time.Hour},
+	}
+	editionRows := func(version int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"engine_edition", "edition", "version"}).AddRow(int64(2), "Standard Edition (64-bit)", version)
+	}
+	want := []internal.Details{
+		{Name: "SQL_EDITION", Fields: []map[string]string{{"edition": internal.EditionStandard}}},
+		{Name: "cacheable", Fields: []map[string]string{{"value": "a"}}},
+		{Name: "uncached", Fields: []map[string]string{{"value": "b"}}},
+	}
+
+	mock.ExpectQuery("EngineEdition").WillReturnRows(editionRows(15))
+	mock.ExpectQuery("cacheableQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+	mock.ExpectQuery("uncachedQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("b"))
+	if diff := cmp.Diff(c.CollectMasterRules(context.Background(), time.Second), want); diff != "" {
+		t.Errorf("first CollectMasterRules returned wrong result (-got +want):\n%s", diff)
+	}
+	// The cacheable rule is not queried again while its results are fresh.
+	mock.ExpectQuery("EngineEdition").WillReturnRows(editionRows(15))
+	mock.ExpectQuery("uncachedQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("b"))
+	if diff := cmp.Diff(c.CollectMasterRules(context.Background(), time.Second), want); diff != "" {
+		t.Errorf("second CollectMasterRules returned wrong result (-got +want):\n%s", diff)
+	}
+	// The cacheable rule is queried again after an upgrade of the sql server.
+	mock.ExpectQuery("EngineEdition").WillReturnRows(editionRows(16))
+	mock.ExpectQuery("cacheableQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+	mock.ExpectQuery("uncachedQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("b"))
+	if diff := cmp.Diff(c.CollectMasterRules(context.Background(), time.Second), want); diff != "" {
+		t.Errorf("CollectMasterRules after an upgrade returned wrong result (-got +want):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %v", err)
+	}
+}
+
+func TestCollectMasterRulesDatabaseFilter(t *testing.T) {
+	internal.MasterRules = []internal.MasterRuleStruct{
+		{
+			Name:  "perDatabase",
+			Query: "perDatabaseQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				res := []map[string]string{}
+				for _, f := range fields {
+					res = append(res, map[string]string{"db_name": internal.HandleNilString(f[0])})
+				}
+				return res
+			},
+			PerDatabase: true,
+		},
+		{
+			Name:  "filtersDatabases",
+			Query: "filtersDatabasesQuery",
+			Args: func(s internal.RuleSettings) []any {
+				return []any{sql.Named("top_queries", s.TopQueries)}
+			},
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{}
+			},
+			FiltersDatabases: true,
+		},
+		{
+			Name:  "server",
+			Query: "serverQuery",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{{"value": internal.HandleNilString(fields[0][0])}}
+			},
+		},
+	}
+	testcases := []struct {
+		name    string
+		include []string
+		exclude []string
+		wantArg string
+	}{
+		{
+			name:    "names and glob patterns",
+			include: []string{"Sales", "crm_*"},
+			wantArg: "<db>crm_eu</db><db>crm_us</db><db>sales</db>",
+		},
+		{
+			name:    "no matching database",
+			include: []string{"billing"},
+			wantArg: "",
+		},
+		{
+			name:    "exclude",
+			exclude: []string{"CRM_*", "master"},
+			wantArg: "<db>hr</db><db>sales</db>",
+		},
+		{
+			name:    "include takes precedence over exclude",
+			include: []string{"crm_*"},
+			exclude: []string{"crm_eu"},
+			wantArg: "<db>crm_eu</db><db>crm_us</db>",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			c := V1{
+				dbConn:             db,
+				usageMetricsLogger: fakeUsageMetricsLogger,
+				target:             "include-test:1433",
+				settings:           internal.RuleSettings{TopQueries: 5},
+			}
+			c.SetDatabaseFilter(tc.include, tc.exclude)
+			mock.ExpectQuery("SELECT name FROM sys.databases").WillReturnRows(sqlmock.NewRows([]string{"name"}).
+				AddRow("master").AddRow("sales").AddRow("crm_us").AddRow("crm_eu").AddRow("hr"))
+			mock.ExpectQuery("perDatabaseQuery").WithArgs(sql.Named("databases", tc.wantArg)).WillReturnRows(sqlmock.NewRows([]string{"db_name"}))
+			mock.ExpectQuery("filtersDatabasesQuery").WithArgs(sql.Named("top_queries", int64(5)), sql.Named("databases", tc.wantArg)).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+			mock.ExpectQuery("serverQuery").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+			c.CollectMasterRules(context.Background(), time.Second)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("expectations were not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestCollectMasterRulesQueryStoreExcludesDatabases(t *testing.T) {
+	internal.MasterRules = nil
+	for _, rule := range masterRules {
+		if rule.Name == "DB_QUERY_STORE" {
+			internal.MasterRules = append(internal.MasterRules, rule)
+		}
+	}
+	if len(internal.MasterRules) != 1 {
+		t.Fatalf("DB_QUERY_STORE is not a master rule")
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+		target:             "query-store-test:1433",
+	}
+	c.SetDatabaseFilter(nil, []string{"staging_*"})
+	mock.ExpectQuery("SELECT name FROM sys.databases").WillReturnRows(sqlmock.NewRows([]string{"name"}).
+		AddRow("sales").AddRow("staging_sales"))
+	mock.ExpectQuery("database_query_store_options").WithArgs(sql.Named("databases", "<db>sales</db>")).
+		WillReturnRows(sqlmock.NewRows([]string{"db_name", "actual_state_desc"}).AddRow("sales", "OFF"))
+	got := c.CollectMasterRules(context.Background(), time.Second)
+	want := []internal.Details{{
+		Name:   "DB_QUERY_STORE",
+		Fields: []map[string]string{{"db_name": "sales", "actual_state": "OFF", "query_store_off": "true"}},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CollectMasterRules() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %v", err)
+	}
+}
+
+func TestDatabaseIncluded(t *testing.T) {
+	testcases := []struct {
+		name     string
+		patterns []string
+		db       string
+		want     bool
+	}{
+		{name: "exact name", patterns: []string{"sales"}, db: "sales", want: true},
+		{name: "different case", patterns: []string{"Sales"}, db: "SALES", want: true},
+		{name: "glob pattern", patterns: []string{"hr", "crm_*"}, db: "crm_eu", want: true},
+		{name: "no match", patterns: []string{"crm_?"}, db: "crm_eu", want: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := databaseIncluded(tc.patterns, tc.db); got != tc.want {
+				t.Errorf("databaseIncluded(%v, %q) = %v, want %v", tc.patterns, tc.db, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDatabaseList(t *testing.T) {
+	got := databaseList([]string{"db1", "a<b&c"})
+	want := "<db>db1</db><db>a&lt;b&amp;c</db>"
+	if got != want {
+		t.Errorf("databaseList() = %q, want %q", got, want)
+	}
+}
+
+func TestExecuteSQLLockTimeout(t *testing.T) {
+	testcases := []struct {
+		name          string
+		lockTimeout   time.Duration
+		wantQuery     string
+		queryErr      error
+		wantLockError bool
+	}{
+		{
+			name:        "lock timeout set before the query",
+			lockTimeout: 5 * time.Second,
+			wantQuery:   "SET LOCK_TIMEOUT 5000;\nSELECT 1",
+		},
+		{
+			name:        "no lock timeout",
+			lockTimeout: 0,
+			wantQuery:   "SELECT 1",
+		},
+		{
+			name:          "query aborted by the lock timeout",
+			lockTimeout:   time.Second,
+			wantQuery:     "SET LOCK_TIMEOUT 1000;\nSELECT 1",
+			queryErr:      mssql.Error{Number: 1222, Message: "Lock request time out period exceeded."},
+			wantLockError: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual), sqlmock.MonitorPingsOption(false))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			q := mock.ExpectQuery(tc.wantQuery)
+			if tc.queryErr != nil {
+				q.WillReturnError(tc.queryErr)
+			} else {
+				q.WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow(int64(1)))
+			}
+			_, err = executeSQL(context.Background(), db, tc.lockTimeout, "SELECT 1")
+			if got := lockTimedOut(err); got != tc.wantLockError {
+				t.Errorf("lockTimedOut(executeSQL()) = %v, want %v, error: %v", got, tc.wantLockError, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("executeSQL() did not run the expected query: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	testcases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "login failed", err: mssql.Error{Number: 18456, Message: "login error: Login failed for user 'sa'."}, want: true},
+		{name: "wrapped login failed", err: fmt.Errorf("ping: %w", mssql.Error{Number: 18456}), want: true},
+		{name: "other sql server error", err: mssql.Error{Number: 1222}},
+		{name: "other error", err: errors.New("connection refused")},
+		{name: "no error"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := LoginFailed(tc.err); got != tc.want {
+				t.Errorf("LoginFailed(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRuleArgs(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rule      internal.MasterRuleStruct
+		databases []string
+		want      []any
+	}{
+		{
+			name: "no arguments",
+			rule: internal.MasterRuleStruct{Name: "testRule"},
+		},
+		{
+			name: "per-database rule reads all databases",
+			rule: internal.MasterRuleStruct{Name: "testRule", PerDatabase: true},
+			want: []any{sql.Named("databases", nil)},
+		},
+		{
+			name: "rule arguments",
+			rule: internal.MasterRuleStruct{
+				Name: "testRule",
+				Args: func(s internal.RuleSettings) []any { return []any{sql.Named("top_queries", s.TopQueries)} },
+			},
+			want: []any{sql.Named("top_queries", int64(5))},
+		},
+		{
+			name:      "rule filtering databases",
+			rule:      internal.MasterRuleStruct{Name: "testRule", FiltersDatabases: true},
+			databases: []string{"db1", "db2"},
+			want:      []any{sql.Named("databases", "<db>db1</db><db>db2</db>")},
+		},
+		{
+			name: "rule arguments and databases",
+			rule: internal.MasterRuleStruct{
+				Name:             "testRule",
+				Args:             func(s internal.RuleSettings) []any { return []any{sql.Named("top_queries", s.TopQueries)} },
+				FiltersDatabases: true,
+			},
+			want: []any{sql.Named("top_queries", int64(5)), sql.Named("databases", nil)},
+		},
+	}
+	settings := internal.RuleSettings{TopQueries: 5}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ruleArgs(tc.rule, settings, tc.databases); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ruleArgs(%v) = %v, want %v", tc.rule.Name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCollectMasterRulesManifest(t *testing.T) {
+	rule := func(name string, editions ...string) internal.MasterRuleStruct {
+		return internal.MasterRuleStruct{
+			Name:  name,
+			Query: name + "Query",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{{"col1": internal.HandleNilString(fields[0][0])}}
+			},
+			Editions: editions,
+		}
+	}
+	disabled := rule("disabled")
+	disabled.Enabled = func(s internal.RuleSettings) bool { return s.CollectIndexFillFactors }
+	internal.MasterRules = []internal.MasterRuleStruct{rule("ran"), rule("failed"), rule("enterprise", internal.EditionEnterprise), disabled, rule("other")}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+		target:             "manifest-test:1433",
+		settings:           internal.RuleSettings{ReportCollectionManifest: true},
+	}
+	mock.ExpectQuery("EngineEdition").WillReturnRows(sqlmock.NewRows([]string{"engine_edition", "edition"}).AddRow(int64(2), "Standard Edition (64-bit)"))
+	mock.ExpectQuery("ranQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("val"))
+	mock.ExpectQuery("failedQuery").WillReturnError(errors.New("new error"))
+	mock.ExpectQuery("otherQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("val"))
+
+	got := c.CollectMasterRules(context.Background(), time.Second)
+	want := internal.Details{
+		Name: internal.CollectionManifestName,
+		Fields: []map[string]string{
+			{"status": "ran", "reason": "", "count": "2", "rules": "other,ran"},
+			{"status": "skipped", "reason": "disabled", "count": "1", "rules": "disabled"},
+			{"status": "skipped", "reason": "edition", "count": "1", "rules": "enterprise"},
+			{"status": "failed", "reason": "query_error", "count": "1", "rules": "failed"},
+		},
+	}
+	if diff := cmp.Diff(got[len(got)-1], want); diff != "" {
+		t.Errorf("CollectMasterRules returned wrong manifest (-got +want):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %v", err)
+	}
+}
+
+func TestCollectMasterRulesWorkloadType(t *testing.T) {
+	rule := func(name string, skipWorkloads ...string) internal.MasterRuleStruct {
+		return internal.MasterRuleStruct{
+			Name:  name,
+			Query: name + "Query",
+			Fields: func(fields [][]any, _ internal.RuleSettings) []map[string]string {
+				return []map[string]string{{"col1": internal.HandleNilString(fields[0][0])}}
+			},
+			SkipWorkloads: skipWorkloads,
+		}
+	}
+	internal.MasterRules = []internal.MasterRuleStruct{rule("heavy", internal.WorkloadReporting), rule("light")}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	c := V1{
+		dbConn:             db,
+		usageMetricsLogger: fakeUsageMetricsLogger,
+		target:             "workload-test:1433",
+		settings:           internal.RuleSettings{ReportCollectionManifest: true},
+	}
+	c.SetWorkloadType(internal.WorkloadReporting)
+	mock.ExpectQuery("lightQuery").WillReturnRows(sqlmock.NewRows([]string{"col1"}).AddRow("val"))
+
+	got := c.CollectMasterRules(context.Background(), time.Second)
+	want := []internal.Details{
+		{Name: "light", Fields: []map[string]string{{"col1": "val"}}},
+		{
+			Name: internal.CollectionManifestName,
+			Fields: []map[string]string{
+				{"status": "ran", "reason": "", "count": "1", "rules": "light"},
+				{"status": "skipped", "reason": "workload", "count": "1", "rules": "heavy"},
+			},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CollectMasterRules returned diff (-got +want):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expectations were not met: %v", err)
+	}
+}
@@ -0,0 +1,633 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package synthetic generates plausible collection details without a SQL Server so that the
+// export path and the dashboards built on the collected data can be exercised.
+package synthetic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// Instance is a fake SQL Server instance along with the machine it runs on.
+type Instance struct {
+	Name       string
+	InstanceID string
+	edition    string
+	version    string
+	level      string
+	cpuCount   int
+	memoryKB   int64
+	databases  []string
+}
+
+// Generator generates the details of fake instances.
+// The same seed always generates the same instances and details.
+type Generator struct {
+	rand *rand.Rand
+	now  func() time.Time
+	// settings are the thresholds the generated fields are flagged by.
+	settings internal.RuleSettings
+}
+
+// ruleFields generates the fields of a master rule for an instance.
+type ruleFields func(g *Generator, inst *Instance) []map[string]string
+
+var (
+	editions = []string{"Enterprise Edition (64-bit)", "Standard Edition (64-bit)", "Web Edition (64-bit)", "Developer Edition (64-bit)"}
+	versions = []struct{ version, level, update, reference string }{
+		{"15.0.4355.3", "RTM", "CU25", "KB5033688"},
+		{"16.0.4105.2", "RTM", "CU11", "KB5032679"},
+		{"14.0.3465.1", "RTM", "CU31", "KB5016884"},
+	}
+	cpuCounts   = []int{2, 4, 8, 16, 32}
+	waitTypes   = []string{"CXPACKET", "PAGEIOLATCH_SH", "WRITELOG", "LCK_M_X", "SOS_SCHEDULER_YIELD", "ASYNC_NETWORK_IO"}
+	programs    = []string{"Microsoft SQL Server Management Studio", "Core Microsoft SqlClient Data Provider", "SQLAgent - Job invocation engine"}
+	databases   = []string{"sales", "inventory", "hr", "reporting", "billing", "crm", "analytics", "archive"}
+	queryStates = []string{"READ_WRITE", "READ_ONLY", "OFF"}
+)
+
+// rules maps the name of each master rule to the generator of its fields.
+var rules = map[string]ruleFields{
+	"DB_LOG_DISK_SEPARATION": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			for filetype, drive := range []string{"D", "L"} {
+				res = append(res, map[string]string{
+					"db_name":           db,
+					"filetype":          strconv.Itoa(filetype),
+					"physical_name":     fmt.Sprintf(`%s:\data\%s_%d.mdf`, drive, db, filetype),
+					"physical_drive":    "unknown",
+					"state":             "0",
+					"size":              strconv.Itoa(1024 * (1 + g.rand.Intn(512))),
+					"growth":            "8192",
+					"is_percent_growth": "false",
+				})
+			}
+		}
+		return res
+	},
+	"DB_MAX_PARALLELISM": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"maxDegreeOfParallelism": strconv.Itoa(g.pickInt([]int{0, 1, inst.cpuCount / 2}))}}
+	},
+	"DB_TRANSACTION_LOG_HANDLING": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			size := 1024 * 1024 * int64(1+g.rand.Intn(1024))
+			res = append(res, map[string]string{
+				"db_name":                db,
+				"backup_age_in_hours":    strconv.Itoa(g.rand.Intn(48)),
+				"backup_size":            strconv.FormatInt(size, 10),
+				"compressed_backup_size": strconv.FormatInt(size/3, 10),
+				"auto_growth":            "1",
+			})
+		}
+		return res
+	},
+	"DB_VIRTUAL_LOG_FILE_COUNT": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			count := 4 + g.rand.Intn(1200)
+			res = append(res, map[string]string{
+				"db_name":               db,
+				"vlf_count":             strconv.Itoa(count),
+				"vlf_size_in_mb":        strconv.FormatFloat(float64(count)*0.5, 'f', 6, 64),
+				"active_vlf_count":      strconv.Itoa(1 + g.rand.Intn(count)),
+				"active_vlf_size_in_mb": "0.500000",
+				"exceeds_threshold":     strconv.FormatBool(int64(count) > g.settings.VLFCountThreshold),
+			})
+		}
+		return res
+	},
+	"DB_BUFFER_POOL_EXTENSION": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"path": "unknown", "state": "0", "size_in_kb": "0"}}
+	},
+	"DB_MAX_SERVER_MEMORY": func(g *Generator, inst *Instance) []map[string]string {
+		value := strconv.FormatInt(inst.memoryKB/1024*(70+int64(g.rand.Intn(20)))/100, 10)
+		if g.rand.Intn(4) == 0 {
+			// The default max server memory.
+			value = "2147483647"
+		}
+		return []map[string]string{{"name": "max server memory (MB)", "value": value, "value_in_use": value}}
+	},
+	"DB_INDEX_FRAGMENTATION": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"found_index_fragmentation": strconv.Itoa(g.rand.Intn(2))}}
+	},
+	"DB_TABLE_INDEX_COMPRESSION": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"numOfPartitionsWithCompressionEnabled": strconv.Itoa(g.rand.Intn(50))}}
+	},
+	"INSTANCE_METRICS": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{
+			"os":                 "Windows",
+			"product_version":    inst.version,
+			"product_level":      inst.level,
+			"edition":            inst.edition,
+			"cpu_count":          strconv.Itoa(inst.cpuCount),
+			"hyperthread_ratio":  "2",
+			"physical_memory_kb": strconv.FormatInt(inst.memoryKB, 10),
+			"virtual_memory_kb":  "137438953344",
+			"socket_count":       "1",
+			"cores_per_socket":   strconv.Itoa(inst.cpuCount / 2),
+			"numa_node_count":    "1",
+		}}
+	},
+	"DB_BACKUP_POLICY": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"max_backup_age": strconv.Itoa(g.rand.Intn(14))}}
+	},
+	"DB_PATCH_LEVEL": func(g *Generator, inst *Instance) []map[string]string {
+		for _, v := range versions {
+			if v.version == inst.version {
+				return []map[string]string{{
+					"product_version":          v.version,
+					"product_level":            v.level,
+					"product_update_level":     v.update,
+					"product_update_reference": v.reference,
+				}}
+			}
+		}
+		return []map[string]string{}
+	},
+
+	"DB_DEADLOCK_COUNT": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"deadlock_count": strconv.Itoa(g.rand.Intn(3)), "latest_deadlock_graph": ""}}
+	},
+	"DB_LOCK_PAGES_IN_MEMORY": func(g *Generator, inst *Instance) []map[string]string {
+		if g.rand.Intn(2) == 0 {
+			return []map[string]string{{"memory_model": "LOCK_PAGES", "lock_pages_in_memory": "true"}}
+		}
+		return []map[string]string{{"memory_model": "CONVENTIONAL", "lock_pages_in_memory": "false"}}
+	},
+	"DB_TDE_STATUS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			encrypted := g.rand.Intn(2) == 0
+			row := map[string]string{
+				"db_name":             db,
+				"is_encrypted":        strconv.FormatBool(encrypted),
+				"encryption_state":    "0",
+				"key_algorithm":       "unknown",
+				"key_length":          "unknown",
+				"violates_tde_policy": "false",
+			}
+			if encrypted {
+				row["encryption_state"] = "3"
+				row["key_algorithm"] = "AES"
+				row["key_length"] = "256"
+			}
+			res = append(res, row)
+		}
+		return res
+	},
+	"DB_OWNER_AND_ORPHANED_USERS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			res = append(res, map[string]string{
+				"db_name":              db,
+				"owner_name":           "sa",
+				"owner_exists":         "true",
+				"owner_is_disabled":    "false",
+				"owner_is_expired":     "false",
+				"orphaned_users_count": strconv.Itoa(g.rand.Intn(3)),
+			})
+		}
+		return res
+	},
+	"DB_LOGIN_AUDIT": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"audit_level": "2", "audits_failed_logins": "true", "failed_login_count": strconv.Itoa(g.rand.Intn(20))}}
+	},
+	"DB_USER_CONNECTIONS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		total := 0
+		sessions := make([]int, len(programs))
+		for i := range programs {
+			sessions[i] = 1 + g.rand.Intn(40)
+			total += sessions[i]
+		}
+		for i, program := range programs {
+			res = append(res, map[string]string{
+				"program_name":         program,
+				"sessions":             strconv.Itoa(sessions[i]),
+				"active_sessions":      strconv.Itoa(g.rand.Intn(sessions[i] + 1)),
+				"total_connections":    strconv.Itoa(total),
+				"max_user_connections": "32767",
+			})
+		}
+		return res
+	},
+	"DB_QUERY_STORE": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			state := g.pick(queryStates)
+			res = append(res, map[string]string{
+				"db_name":         db,
+				"actual_state":    state,
+				"query_store_off": strconv.FormatBool(state == "OFF"),
+			})
+		}
+		return res
+	},
+	"DB_WAIT_STATS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, waitType := range waitTypes {
+			waitTime := g.rand.Int63n(10000000)
+			res = append(res, map[string]string{
+				"wait_type":           waitType,
+				"waiting_tasks_count": strconv.FormatInt(g.rand.Int63n(100000), 10),
+				"wait_time_ms":        strconv.FormatInt(waitTime, 10),
+				"signal_wait_time_ms": strconv.FormatInt(waitTime/10, 10),
+			})
+		}
+		return res
+	},
+	"DB_CLOCK_SKEW": func(g *Generator, inst *Instance) []map[string]string {
+		now := g.now().UTC()
+		skew := time.Duration(g.rand.Intn(2000)) * time.Millisecond
+		return []map[string]string{{
+			"server_utc_time":    now.Add(skew).Format(time.RFC3339Nano),
+			"agent_utc_time":     now.Format(time.RFC3339Nano),
+			"skew_seconds":       fmt.Sprintf("%.3f", skew.Seconds()),
+			"is_above_threshold": strconv.FormatBool(skew > g.settings.ClockSkewThreshold),
+		}}
+	},
+	"DB_LICENSED_CORES": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{
+			"edition":                    inst.edition,
+			"cpu_count":                  strconv.Itoa(inst.cpuCount),
+			"visible_online_schedulers":  strconv.Itoa(inst.cpuCount),
+			"visible_offline_schedulers": "0",
+			"sql_visible_cores":          strconv.Itoa(inst.cpuCount),
+			"limited_by_license":         "false",
+			"vm_vcpu_count":              strconv.Itoa(inst.cpuCount),
+			"uses_all_vcpus":             "true",
+		}}
+	},
+	"DB_DATABASE_FILES": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			for _, fileType := range []string{"ROWS", "LOG"} {
+				size := 8192 * int64(1+g.rand.Intn(1024))
+				res = append(res, map[string]string{
+					"db_name":           db,
+					"file_name":         fmt.Sprintf("%s_%s", db, fileType),
+					"type":              fileType,
+					"physical_name":     fmt.Sprintf(`D:\data\%s_%s.mdf`, db, fileType),
+					"size_kb":           strconv.FormatInt(size, 10),
+					"used_kb":           strconv.FormatInt(size*int64(g.rand.Intn(100))/100, 10),
+					"max_size_kb":       "unlimited",
+					"growth_type":       "fixed",
+					"growth_kb":         "65536",
+					"growth_percent":    "0",
+					"is_percent_growth": "false",
+				})
+			}
+		}
+		return res
+	},
+	"DB_OPTIMIZE_FOR_AD_HOC_WORKLOADS": func(g *Generator, inst *Instance) []map[string]string {
+		cached := 1000 + g.rand.Intn(50000)
+		singleUse := g.rand.Intn(cached)
+		sizeKB := cached * 48
+		singleUseKB := singleUse * 32
+		return []map[string]string{{
+			"optimize_for_ad_hoc_workloads": strconv.FormatBool(g.rand.Intn(2) == 0),
+			"single_use_plan_count":         strconv.Itoa(singleUse),
+			"single_use_plan_size_kb":       strconv.Itoa(singleUseKB),
+			"cached_plan_count":             strconv.Itoa(cached),
+			"plan_cache_size_kb":            strconv.Itoa(sizeKB),
+			"single_use_plan_percent":       strconv.FormatFloat(100*float64(singleUse)/float64(cached), 'f', 6, 64),
+			"single_use_plan_size_percent":  strconv.FormatFloat(100*float64(singleUseKB)/float64(sizeKB), 'f', 6, 64),
+		}}
+	},
+	"DB_AUTHENTICATION_MODE": func(g *Generator, inst *Instance) []map[string]string {
+		if g.rand.Intn(2) == 0 {
+			return []map[string]string{{"is_integrated_security_only": "true", "authentication_mode": "windows"}}
+		}
+		return []map[string]string{{"is_integrated_security_only": "false", "authentication_mode": "mixed"}}
+	},
+	"DB_DEFAULT_DIRECTORIES": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{"data_directory": `D:\data\`, "log_directory": `L:\log\`, "backup_directory": `B:\backup\`}}
+	},
+	"DB_TEMPDB_CONTENTION": func(g *Generator, inst *Instance) []map[string]string {
+		dataFiles := g.pickInt([]int{1, 2, 4, 8})
+		allocationWaits := g.rand.Intn(3) * g.rand.Intn(10)
+		return []map[string]string{{
+			"pagelatch_waits":       strconv.Itoa(allocationWaits + g.rand.Intn(3)),
+			"allocation_page_waits": strconv.Itoa(allocationWaits),
+			"max_wait_ms":           strconv.Itoa(allocationWaits * g.rand.Intn(100)),
+			"allocation_contention": strconv.FormatBool(allocationWaits > 0),
+			"tempdb_data_files":     strconv.Itoa(dataFiles),
+			"cpu_count":             strconv.Itoa(inst.cpuCount),
+			"too_few_data_files":    strconv.FormatBool(dataFiles < min(inst.cpuCount, 8)),
+		}}
+	},
+	"DB_FAILOVER_CLUSTER": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{
+			"is_clustered":        "false",
+			"active_node":         strings.ToUpper(inst.Name),
+			"virtual_server_name": strings.ToUpper(inst.Name),
+			"is_hadr_enabled":     strconv.FormatBool(g.rand.Intn(2) == 0),
+			"configured_as_fci":   "false",
+		}}
+	},
+	"DB_RESOURCE_GOVERNOR": func(g *Generator, inst *Instance) []map[string]string {
+		pool := func(name string, maxCPU, maxMemory int) map[string]string {
+			return map[string]string{
+				"enabled":             "true",
+				"classifier_function": "rg_classifier",
+				"pool_name":           name,
+				"min_cpu_percent":     "0",
+				"max_cpu_percent":     strconv.Itoa(maxCPU),
+				"cap_cpu_percent":     "100",
+				"min_memory_percent":  "0",
+				"max_memory_percent":  strconv.Itoa(maxMemory),
+				"capped":              strconv.FormatBool(maxCPU != 100 || maxMemory != 100),
+			}
+		}
+		// Developer edition has the features of Enterprise edition.
+		enterprise := strings.HasPrefix(inst.edition, "Enterprise") || strings.HasPrefix(inst.edition, "Developer")
+		if !enterprise || g.rand.Intn(2) == 0 {
+			return []map[string]string{}
+		}
+		return []map[string]string{pool("internal", 100, 100), pool("default", 100, 100), pool("reporting", 10+g.rand.Intn(50), 10+g.rand.Intn(50))}
+	},
+	"DB_TOP_QUERIES": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		workerTime := 100000 + g.rand.Intn(1000000)
+		for i := 0; i < 10; i++ {
+			executions := 1 + g.rand.Intn(10000)
+			res = append(res, map[string]string{
+				"query_hash":            fmt.Sprintf("0x%016X", g.rand.Uint64()),
+				"execution_count":       strconv.Itoa(executions),
+				"total_worker_time_ms":  strconv.Itoa(workerTime),
+				"total_logical_reads":   strconv.Itoa(workerTime * (1 + g.rand.Intn(50))),
+				"total_elapsed_time_ms": strconv.Itoa(workerTime + g.rand.Intn(workerTime)),
+				"query_text":            fmt.Sprintf("SELECT * FROM dbo.table_%d WHERE id = @p1", g.rand.Intn(100)),
+			})
+			workerTime = workerTime * (50 + g.rand.Intn(50)) / 100
+		}
+		return res
+	},
+	"DB_BACKUP_DEFAULTS": func(g *Generator, inst *Instance) []map[string]string {
+		return []map[string]string{{
+			"backup_compression_default": strconv.FormatBool(g.rand.Intn(2) == 0),
+			"backup_checksum_default":    strconv.FormatBool(g.rand.Intn(3) == 0),
+		}}
+	},
+	"DB_DATABASE_SNAPSHOTS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			if g.rand.Intn(4) != 0 {
+				continue
+			}
+			maxSize := 8192 * int64(1+g.rand.Intn(1024))
+			res = append(res, map[string]string{
+				"snapshot_name":   db + "_snapshot",
+				"source_database": db,
+				"state":           "ONLINE",
+				"age_hours":       strconv.Itoa(g.rand.Intn(2000)),
+				"size_on_disk_kb": strconv.FormatInt(maxSize*int64(g.rand.Intn(100))/100, 10),
+				"max_size_kb":     strconv.FormatInt(maxSize, 10),
+			})
+		}
+		return res
+	},
+	"DB_DATABASE_STATE": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			if g.rand.Intn(20) != 0 {
+				continue
+			}
+			// Online databases are only reported when they are read-only.
+			state := g.pick([]string{"ONLINE", "SUSPECT", "RECOVERY_PENDING", "OFFLINE"})
+			res = append(res, map[string]string{
+				"db_name":      db,
+				"state":        state,
+				"is_read_only": strconv.FormatBool(state == "ONLINE" || g.rand.Intn(2) == 0),
+			})
+		}
+		return res
+	},
+	"DB_AUTO_UPDATE_STATISTICS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			on := g.rand.Intn(10) != 0
+			res = append(res, map[string]string{
+				"db_name":                 db,
+				"auto_update_stats":       strconv.FormatBool(on),
+				"auto_update_stats_async": strconv.FormatBool(g.rand.Intn(4) == 0),
+				"auto_update_disabled":    strconv.FormatBool(!on),
+			})
+		}
+		return res
+	},
+	"DB_XE_SESSIONS": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{
+			{"session_name": "AlwaysOn_health", "running": "false", "start_on_server_startup": "false", "targets": "event_file"},
+		}
+		if g.rand.Intn(3) == 0 {
+			res = append(res, map[string]string{"session_name": "blocked_process_monitor", "running": "true", "start_on_server_startup": "true", "targets": "event_file"})
+		}
+		return append(res, map[string]string{"session_name": "system_health", "running": "true", "start_on_server_startup": "true", "targets": "event_file,ring_buffer"})
+	},
+	"DB_MIRRORING_LOG_SHIPPING": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		if len(inst.databases) == 0 || g.rand.Intn(5) != 0 {
+			return res
+		}
+		state := g.pick([]string{"SYNCHRONIZED", "SYNCHRONIZED", "SYNCHRONIZED", "DISCONNECTED"})
+		return append(res, map[string]string{
+			"db_name":                 inst.databases[0],
+			"ha_type":                 "MIRRORING",
+			"role":                    "PRINCIPAL",
+			"partner":                 "TCP://" + strings.ToLower(inst.Name) + "-mirror:5022",
+			"state":                   state,
+			"minutes_since_last_sync": "unknown",
+			"unsynchronized":          strconv.FormatBool(state != "SYNCHRONIZED"),
+		})
+	},
+	"DB_FILL_FACTOR": func(g *Generator, inst *Instance) []map[string]string {
+		fillFactor := "0"
+		if g.rand.Intn(5) == 0 {
+			fillFactor = "80"
+		}
+		return []map[string]string{{
+			"configured_fill_factor": fillFactor,
+			"fill_factor":            fillFactor,
+			"is_default":             strconv.FormatBool(fillFactor == "0"),
+		}}
+	},
+	"DB_INDEX_FILL_FACTOR": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			if g.rand.Intn(4) != 0 {
+				continue
+			}
+			res = append(res, map[string]string{
+				"db_name":     db,
+				"table_name":  fmt.Sprintf("dbo.table_%d", g.rand.Intn(100)),
+				"index_name":  fmt.Sprintf("ix_table_%d", g.rand.Intn(100)),
+				"fill_factor": strconv.Itoa(50 + 10*g.rand.Intn(5)),
+				"page_count":  strconv.Itoa(1000 + g.rand.Intn(100000)),
+			})
+		}
+		return res
+	},
+	"DB_LAST_CHECKDB": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range append([]string{"master", "model", "msdb"}, inst.databases...) {
+			days := g.rand.Intn(14)
+			res = append(res, map[string]string{
+				"db_name":                      db,
+				"source":                       "property",
+				"last_good_checkdb":            time.Date(2024, 1, 15-days, 2, 0, 0, 0, time.UTC).Format("2006-01-02T15:04:05"),
+				"days_since_last_good_checkdb": strconv.Itoa(days),
+				"checkdb_overdue":              strconv.FormatBool(days > 7),
+			})
+		}
+		return res
+	},
+	"DB_WORKER_THREADS": func(g *Generator, inst *Instance) []map[string]string {
+		maxWorkers := 512
+		current := 50 + g.rand.Intn(maxWorkers-50)
+		active := g.rand.Intn(current)
+		queued := 0
+		if current > maxWorkers*9/10 {
+			queued = g.rand.Intn(20)
+		}
+		return []map[string]string{{
+			"configured_max_worker_threads": "0",
+			"max_worker_threads":            "0",
+			"max_workers_count":             strconv.Itoa(maxWorkers),
+			"current_workers":               strconv.Itoa(current),
+			"active_workers":                strconv.Itoa(active),
+			"idle_workers":                  strconv.Itoa(current - active),
+			"queued_tasks":                  strconv.Itoa(queued),
+			"worker_utilization_percent":    strconv.FormatFloat(100*float64(current)/float64(maxWorkers), 'f', 6, 64),
+		}}
+	},
+	"DB_PAGE_VERIFY": func(g *Generator, inst *Instance) []map[string]string {
+		res := []map[string]string{}
+		for _, db := range inst.databases {
+			option := "CHECKSUM"
+			if g.rand.Intn(15) == 0 {
+				option = "TORN_PAGE_DETECTION"
+			}
+			res = append(res, map[string]string{
+				"db_name":                  db,
+				"page_verify_option":       option,
+				"page_verify_not_checksum": strconv.FormatBool(option != "CHECKSUM"),
+			})
+		}
+		return res
+	},
+}
+
+// New returns a generator seeded with the given seed.
+func New(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed)), now: time.Now, settings: internal.DefaultRuleSettings()}
+}
+
+// Instances returns n fake instances.
+func (g *Generator) Instances(n int) []*Instance {
+	res := []*Instance{}
+	for i := 0; i < n; i++ {
+		v := versions[g.rand.Intn(len(versions))]
+		inst := &Instance{
+			Name:       fmt.Sprintf("synthetic-sql-%03d", i),
+			InstanceID: strconv.FormatInt(1000000000000000000+g.rand.Int63n(1000000000000000000), 10),
+			edition:    g.pick(editions),
+			version:    v.version,
+			level:      v.level,
+			cpuCount:   g.pickInt(cpuCounts),
+		}
+		inst.memoryKB = int64(inst.cpuCount) * 8 * 1024 * 1024
+		for _, db := range databases {
+			if g.rand.Intn(2) == 0 {
+				inst.databases = append(inst.databases, db)
+			}
+		}
+		res = append(res, inst)
+	}
+	return res
+}
+
+// SQLDetails returns the details of every master rule of the instance. The post-processors of
+// the rules derive their fields from the generated fields as they do from the collected ones.
+func (g *Generator) SQLDetails(inst *Instance) []internal.Details {
+	details := []internal.Details{}
+	for _, rule := range internal.MasterRules {
+		fields, ok := rules[rule.Name]
+		if !ok {
+			details = append(details, internal.Details{Name: rule.Name, Fields: []map[string]string{}})
+			continue
+		}
+		rows := fields(g, inst)
+		for _, ref := range rule.PostProcessors {
+			if p, err := internal.LookupPostProcessor(ref); err == nil {
+				rows = p(rows)
+			}
+		}
+		details = append(details, internal.Details{Name: rule.Name, Fields: rows})
+	}
+	return details
+}
+
+// OSDetails returns the details of the guest os rules of the machine the instance runs on.
+func (g *Generator) OSDetails(inst *Instance) internal.Details {
+	type allocationUnits struct {
+		BlockSize string
+		Caption   string
+	}
+	units, _ := json.Marshal([]allocationUnits{{BlockSize: "4096", Caption: "sdb"}, {BlockSize: "65536", Caption: "sdc"}})
+	cpu := 5 + g.rand.Float64()*80
+	utilization, _ := json.Marshal(map[string]any{
+		"CPUUtilizationPercent": float64(int(cpu*100)) / 100,
+		"MemoryTotalKB":         inst.memoryKB,
+		"MemoryAvailableKB":     inst.memoryKB / 4,
+		"MemoryUsedPercent":     75.0,
+	})
+	powerPlan := g.pick([]string{"High performance", "Balanced"})
+	return internal.Details{
+		Name: "OS",
+		Fields: []map[string]string{{
+			internal.PowerProfileSettingRule:     powerPlan,
+			internal.PowerPlanRecommendedField:   strconv.FormatBool(powerPlan == "High performance"),
+			internal.LocalSSDRule:                `{"sdb":"PERSISTENT","sdc":"PERSISTENT"}`,
+			internal.DataDiskAllocationUnitsRule: string(units),
+			internal.GCBDRAgentRunning:           strconv.FormatBool(g.rand.Intn(2) == 0),
+			internal.SQLServiceAccountRule:       "NT Service\\MSSQLSERVER",
+			internal.PageFileRule:                "unknown",
+			internal.SQLNetworkProtocolsRule:     `[{"Instance":"MSSQLSERVER","TCPEnabled":true,"TCPPort":"1433","TCPDynamicPorts":"","NamedPipesEnabled":false,"SharedMemoryEnabled":true,"LegacyProtocolsEnabled":false}]`,
+			internal.SQLAgentServiceRule:         `[{"Name":"SQLSERVERAGENT","State":"Running","StartMode":"Auto","Running":true,"AutoStart":true}]`,
+			internal.HostUtilizationRule:         string(utilization),
+		}},
+	}
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.rand.Intn(len(values))]
+}
+
+func (g *Generator) pickInt(values []int) int {
+	return values[g.rand.Intn(len(values))]
+}
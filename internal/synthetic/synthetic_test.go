@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synthetic
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+func newGenerator(seed int64) *Generator {
+	g := New(seed)
+	g.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	return g
+}
+
+func TestRulesCoverMasterRules(t *testing.T) {
+	for _, rule := range internal.MasterRules {
+		if _, ok := rules[rule.Name]; !ok {
+			t.Errorf("rules[%q] is missing; every master rule needs a synthetic generator", rule.Name)
+		}
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	testcases := []struct {
+		name      string
+		seedA     int64
+		seedB     int64
+		wantEqual bool
+	}{
+		{
+			name:      "same seed",
+			seedA:     1,
+			seedB:     1,
+			wantEqual: true,
+		},
+		{
+			name:      "different seeds",
+			seedA:     1,
+			seedB:     2,
+			wantEqual: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			details := func(seed int64) []internal.Details {
+				g := newGenerator(seed)
+				res := []internal.Details{}
+				for _, inst := range g.Instances(3) {
+					res = append(res, g.SQLDetails(inst)...)
+					res = append(res, g.OSDetails(inst))
+				}
+				return res
+			}
+			got := cmp.Equal(details(tc.seedA), details(tc.seedB))
+			if got != tc.wantEqual {
+				t.Errorf("cmp.Equal(details(%d), details(%d)) = %v, want %v", tc.seedA, tc.seedB, got, tc.wantEqual)
+			}
+		})
+	}
+}
+
+func TestSQLDetails(t *testing.T) {
+	g := newGenerator(1)
+	instances := g.Instances(5)
+	if len(instances) != 5 {
+		t.Fatalf("Instances(5) returned %d instances, want 5", len(instances))
+	}
+	for _, inst := range instances {
+		details := g.SQLDetails(inst)
+		if len(details) != len(internal.MasterRules) {
+			t.Fatalf("SQLDetails(%s) returned %d details, want %d", inst.Name, len(details), len(internal.MasterRules))
+		}
+		for i, d := range details {
+			if d.Name != internal.MasterRules[i].Name {
+				t.Errorf("SQLDetails(%s)[%d].Name = %s, want %s", inst.Name, i, d.Name, internal.MasterRules[i].Name)
+			}
+		}
+		// The memory allocation is derived from the details as it is for collected details.
+		if _, ok := internal.MemoryAllocation(details); !ok {
+			t.Errorf("MemoryAllocation(SQLDetails(%s)) = false, want true", inst.Name)
+		}
+	}
+}
+
+func TestOSDetails(t *testing.T) {
+	g := newGenerator(1)
+	got := g.OSDetails(g.Instances(1)[0])
+	wantKeys := []string{
+		internal.PowerProfileSettingRule,
+		internal.LocalSSDRule,
+		internal.DataDiskAllocationUnitsRule,
+		internal.GCBDRAgentRunning,
+		internal.SQLServiceAccountRule,
+		internal.PageFileRule,
+		internal.SQLNetworkProtocolsRule,
+		internal.SQLAgentServiceRule,
+		internal.HostUtilizationRule,
+		internal.PowerPlanRecommendedField,
+	}
+	gotKeys := []string{}
+	for k := range got.Fields[0] {
+		gotKeys = append(gotKeys, k)
+	}
+	sort.Strings(wantKeys)
+	sort.Strings(gotKeys)
+	if diff := cmp.Diff(wantKeys, gotKeys); diff != "" {
+		t.Errorf("OSDetails() returned unexpected fields (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	testcases := []struct {
+		name     string
+		endpoint string
+		wantErr  bool
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:     "http endpoint",
+			endpoint: "http://localhost:4318",
+		},
+		{
+			name:     "https endpoint with path",
+			endpoint: "https://collector.example.com/otlp/v1/traces",
+		},
+		{
+			name:     "endpoint without scheme",
+			endpoint: "localhost:4318",
+			wantErr:  true,
+		},
+	}
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.endpoint, "testService", "testVersion")
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Setup(%q) = %v, want error presence = %v", tc.endpoint, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown() returned an unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(tp)
+
+	ctx, cycle := Start(context.Background(), "sql_collection")
+	ruleCtx, endRule := StartRule(ctx, "testRule")
+	endRule(errors.New("new error"))
+	_, endRule = StartRule(ctx, "otherRule")
+	endRule(nil)
+	End(cycle, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d ended spans, want 3", len(spans))
+	}
+	failed, succeeded, root := spans[0], spans[1], spans[2]
+	if root.Name() != "sql_collection" || root.Parent().IsValid() {
+		t.Errorf("root span = %q with parent %v, want sql_collection without parent", root.Name(), root.Parent())
+	}
+	for _, s := range []sdktrace.ReadOnlySpan{failed, succeeded} {
+		if s.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the cycle span", s.Name())
+		}
+		attrs := map[attribute.Key]bool{}
+		for _, a := range s.Attributes() {
+			attrs[a.Key] = true
+		}
+		if !attrs["rule"] || !attrs["duration_ms"] {
+			t.Errorf("span %q attributes = %v, want rule and duration_ms", s.Name(), s.Attributes())
+		}
+	}
+	if failed.Status().Code != codes.Error {
+		t.Errorf("failed rule span status = %v, want %v", failed.Status().Code, codes.Error)
+	}
+	if succeeded.Status().Code != codes.Unset {
+		t.Errorf("succeeded rule span status = %v, want %v", succeeded.Status().Code, codes.Unset)
+	}
+	if ruleCtx == ctx {
+		t.Errorf("StartRule() returned the parent context, want a context carrying the rule span")
+	}
+}
@@ -21,12 +21,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/jonboulle/clockwork"
+	"golang.org/x/net/proxy"
 	"github.com/GoogleCloudPlatform/sapagent/shared/commandlineexecutor"
 	"github.com/GoogleCloudPlatform/sapagent/shared/log"
 )
@@ -40,8 +46,15 @@ const (
 	// AgentVersion is the version of the agent.
 	AgentVersion = `1.2`
 	
+	// ServiceName is the name of the agent service.
+	ServiceName = "google-cloud-sql-server-agent"
 )
 
+// BuildTimestamp is the time the agent was built.
+// It is set at build time with -ldflags "-X github.com/GoogleCloudPlatform/sql-server-agent/internal.BuildTimestamp=<time>".
+// If it is not set, the commit time recorded by the go toolchain is used.
+var BuildTimestamp = ""
+
 // DiskTypeEnum enum used for disktypes to keep linux and windows collection consistent .
 type DiskTypeEnum int
 
@@ -125,6 +138,45 @@ func SaveToFile(path string, data []byte) error {
 	return nil
 }
 
+// ApplyRetention deletes the files matching the pattern in the given directory which are older
+// than maxAge, then deletes the oldest remaining ones until at most maxFiles are left. The pattern
+// must only match files written by the agent, e.g. by starting with a prefix of the agent.
+func ApplyRetention(dir, pattern string, maxFiles int, maxAge time.Duration, now time.Time) error {
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return err
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var files []file
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if now.Sub(info.ModTime()) > maxAge {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			continue
+		}
+		files = append(files, file{path: p, modTime: info.ModTime()})
+	}
+	if len(files) <= maxFiles {
+		return nil
+	}
+	// Sort files from the newest to the oldest.
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files[maxFiles:] {
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // PrettyStruct converts the passed in struct into a pretty json format.
 func PrettyStruct(data any) (string, error) {
 	val, err := json.MarshalIndent(data, "", "    ")
@@ -195,6 +247,73 @@ func GetPhysicalDriveFromPath(ctx context.Context, path string, windows bool, ex
 	return physicalDrive
 }
 
+// ContextDialer makes outbound connections. *net.Dialer and the dialers of SOCKS5 proxies
+// implement it.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// SOCKS5Dialer returns a dialer which makes outbound connections through the SOCKS5 proxy at the
+// host:port address. The user name and password are sent to the proxy unless the user name is
+// empty. The connections to the proxy are made by forward, or directly if it is nil.
+func SOCKS5Dialer(address, username, password string, forward *net.Dialer) (ContextDialer, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy address %q: %v", address, err)
+	}
+	var auth *proxy.Auth
+	if username != "" {
+		auth = &proxy.Auth{User: username, Password: password}
+	}
+	var f proxy.Dialer = proxy.Direct
+	if forward != nil {
+		f = forward
+	}
+	d, err := proxy.SOCKS5("tcp", address, auth, f)
+	if err != nil {
+		return nil, err
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("the SOCKS5 dialer does not support contexts")
+	}
+	return cd, nil
+}
+
+// SourceDialer returns a dialer which binds outbound connections to the given source address.
+// The source address can be either an IP address or the name of a network interface. For a
+// network interface, its first IPv4 address is used, or its first address if it has no IPv4.
+func SourceDialer(sourceAddress string) (*net.Dialer, error) {
+	if ip := net.ParseIP(sourceAddress); ip != nil {
+		return &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip}}, nil
+	}
+	iface, err := net.InterfaceByName(sourceAddress)
+	if err != nil {
+		return nil, fmt.Errorf("source address %q is neither an IP address nor a network interface: %v", sourceAddress, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			ip = ipNet.IP
+			break
+		}
+		if ip == nil {
+			ip = ipNet.IP
+		}
+	}
+	if ip == nil {
+		return nil, fmt.Errorf("network interface %q has no IP address", sourceAddress)
+	}
+	return &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip}}, nil
+}
+
 // integerToString converts any valid integer type to a string representation.
 func integerToString(num any) (string, error) {
 	switch v := num.(type) {
@@ -224,3 +343,129 @@ func integerToString(num any) (string, error) {
 		return "", fmt.Errorf("unsupported number type: %T", num)
 	}
 }
+
+// Semaphore limits the number of operations running at the same time.
+// The limit can be changed while operations are running.
+type Semaphore struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	inUse int
+}
+
+// NewSemaphore returns a Semaphore allowing limit operations at the same time.
+// A limit lower than 1 is treated as 1.
+func NewSemaphore(limit int) *Semaphore {
+	s := &Semaphore{}
+	s.cond = sync.NewCond(&s.mu)
+	s.SetLimit(limit)
+	return s
+}
+
+// SetLimit changes the limit. Operations already running are not interrupted
+// when the limit is lowered.
+func (s *Semaphore) SetLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit < 1 {
+		limit = 1
+	}
+	s.limit = limit
+	s.cond.Broadcast()
+}
+
+// Acquire blocks until an operation is allowed to run or ctx is done.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	defer stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.inUse >= s.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.cond.Wait()
+	}
+	s.inUse++
+	return nil
+}
+
+// Release marks an operation as finished.
+func (s *Semaphore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inUse--
+	s.cond.Signal()
+}
+
+type ruleLimiterKey struct{}
+
+// WithRuleLimiter returns a copy of ctx whose rules run within the limit of s.
+func WithRuleLimiter(ctx context.Context, s *Semaphore) context.Context {
+	return context.WithValue(ctx, ruleLimiterKey{}, s)
+}
+
+// AcquireRule blocks until the rule limiter of ctx allows one more rule to run or ctx is done.
+// The returned function marks the rule as finished. Contexts without a rule limiter do not wait.
+func AcquireRule(ctx context.Context) (func(), error) {
+	s, ok := ctx.Value(ruleLimiterKey{}).(*Semaphore)
+	if !ok {
+		return func() {}, nil
+	}
+	if err := s.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	return s.Release, nil
+}
+
+// CycleBudget shares the time of a collection cycle between the instances collected in the cycle,
+// so that a slow instance cannot delay the instances after it past the end of the cycle.
+type CycleBudget struct {
+	clock    clockwork.Clock
+	deadline time.Time
+	left     int
+	minShare time.Duration
+}
+
+// NewCycleBudget returns a budget of d for n instances. Instances whose share of the time left
+// would be shorter than minShare are deferred to the next cycle.
+func NewCycleBudget(d time.Duration, n int, minShare time.Duration, clock clockwork.Clock) *CycleBudget {
+	return &CycleBudget{clock: clock, deadline: clock.Now().Add(d), left: n, minShare: minShare}
+}
+
+// Next returns the deadline of the next instance, which gets an equal share of the time left
+// with the instances not collected yet. It returns false if the instance is deferred.
+func (b *CycleBudget) Next() (time.Time, bool) {
+	left := max(b.left, 1)
+	b.left = left - 1
+	now := b.clock.Now()
+	share := b.deadline.Sub(now) / time.Duration(left)
+	if share < b.minShare {
+		return time.Time{}, false
+	}
+	return now.Add(share), true
+}
+
+// CollectionOrder returns the indices of the targets of a collection cycle in the order they are
+// collected in: from the highest priority to the lowest. Each consecutive cycle a target was
+// deferred in raises its priority by one, so that targets of a low priority are not deferred
+// forever by targets of a higher priority that fill every cycle. Among targets of the same raised
+// priority, the deferred ones come first and the others keep their order.
+func CollectionOrder(priorities, deferrals []int32) []int {
+	order := make([]int, len(priorities))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if pa, pb := priorities[a]+deferrals[a], priorities[b]+deferrals[b]; pa != pb {
+			return pa > pb
+		}
+		return deferrals[a] > deferrals[b]
+	})
+	return order
+}
@@ -17,12 +17,22 @@ limitations under the License.
 package internal
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"net"
+	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonboulle/clockwork"
 	"github.com/GoogleCloudPlatform/sapagent/shared/commandlineexecutor"
 )
 
@@ -207,6 +217,78 @@ func TestSaveToFile(t *testing.T) {
 	}
 }
 
+func TestApplyRetention(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		files    map[string]time.Duration
+		maxFiles int
+		maxAge   time.Duration
+		want     []string
+	}{
+		{
+			name: "keeps all files within limits",
+			files: map[string]time.Duration{
+				"a-sql.json":   time.Hour,
+				"b-sql.json":   2 * time.Hour,
+				"a-guest.json": 72 * time.Hour,
+			},
+			maxFiles: 2,
+			maxAge:   24 * time.Hour,
+			want:     []string{"a-guest.json", "a-sql.json", "b-sql.json"},
+		},
+		{
+			name: "deletes files older than max age",
+			files: map[string]time.Duration{
+				"a-sql.json": time.Hour,
+				"b-sql.json": 48 * time.Hour,
+			},
+			maxFiles: 10,
+			maxAge:   24 * time.Hour,
+			want:     []string{"a-sql.json"},
+		},
+		{
+			name: "deletes oldest files over max files",
+			files: map[string]time.Duration{
+				"a-sql.json": 3 * time.Hour,
+				"b-sql.json": time.Hour,
+				"c-sql.json": 2 * time.Hour,
+			},
+			maxFiles: 2,
+			maxAge:   24 * time.Hour,
+			want:     []string{"b-sql.json", "c-sql.json"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, age := range tc.files {
+				p := filepath.Join(dir, name)
+				if err := os.WriteFile(p, []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ApplyRetention(dir, "*-sql.json", tc.maxFiles, tc.maxAge, now); err != nil {
+				t.Fatalf("ApplyRetention() returned an unexpected error: %v", err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("ApplyRetention() left files %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestPrettyStruct(t *testing.T) {
 
 	type testStruct struct {
@@ -422,3 +504,342 @@ func TestIntegerToString(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceDialer(t *testing.T) {
+	tests := []struct {
+		name          string
+		sourceAddress string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "ipv4 address",
+			sourceAddress: "10.0.0.1",
+			want:          "10.0.0.1",
+		},
+		{
+			name:          "ipv6 address",
+			sourceAddress: "fe80::1",
+			want:          "fe80::1",
+		},
+		{
+			name:          "loopback interface",
+			sourceAddress: "lo",
+			want:          "127.0.0.1",
+		},
+		{
+			name:          "invalid address",
+			sourceAddress: "invalid-interface",
+			wantErr:       true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.sourceAddress == "lo" {
+				if _, err := net.InterfaceByName("lo"); err != nil {
+					t.Skip("loopback interface lo is not available")
+				}
+			}
+			got, err := SourceDialer(tc.sourceAddress)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("SourceDialer(%v) returned an unexpected error: %v, want error: %v", tc.sourceAddress, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if gotIP := got.LocalAddr.(*net.TCPAddr).IP.String(); gotIP != tc.want {
+				t.Errorf("SourceDialer(%v) bound to %v, want: %v", tc.sourceAddress, gotIP, tc.want)
+			}
+		})
+	}
+}
+
+// fakeSOCKS5Proxy accepts a single connection on a local listener, performs the SOCKS5
+// handshake and forwards the connection to the requested address. The credentials and the
+// address requested by the client are sent to the returned channel.
+func fakeSOCKS5Proxy(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	requests := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		read := func(n int) []byte {
+			b := make([]byte, n)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return make([]byte, n)
+			}
+			return b
+		}
+		methods := read(int(read(2)[1]))
+		credentials := ""
+		if bytes.IndexByte(methods, 2) >= 0 {
+			conn.Write([]byte{5, 2})
+			user := string(read(int(read(2)[1])))
+			password := string(read(int(read(1)[0])))
+			credentials = user + ":" + password + "@"
+			conn.Write([]byte{1, 0})
+		} else {
+			conn.Write([]byte{5, 0})
+		}
+		var host string
+		switch header := read(4); header[3] {
+		case 1:
+			host = net.IP(read(4)).String()
+		case 3:
+			host = string(read(int(read(1)[0])))
+		}
+		port := read(2)
+		addr := net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+		requests <- credentials + addr
+		target, err := net.Dial("tcp", addr)
+		if err != nil {
+			conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	}()
+	return l.Addr().String(), requests
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name        string
+		username    string
+		password    string
+		wantRequest string
+	}{
+		{
+			name:        "no authentication",
+			wantRequest: target.Addr().String(),
+		},
+		{
+			name:        "username and password",
+			username:    "agent",
+			password:    "secret",
+			wantRequest: "agent:secret@" + target.Addr().String(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyAddr, requests := fakeSOCKS5Proxy(t)
+			d, err := SOCKS5Dialer(proxyAddr, tc.username, tc.password, nil)
+			if err != nil {
+				t.Fatalf("SOCKS5Dialer() returned an unexpected error: %v", err)
+			}
+			conn, err := d.DialContext(context.Background(), "tcp", target.Addr().String())
+			if err != nil {
+				t.Fatalf("DialContext() returned an unexpected error: %v", err)
+			}
+			defer conn.Close()
+			got, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("ReadAll() returned an unexpected error: %v", err)
+			}
+			if string(got) != "hello" {
+				t.Errorf("DialContext() connection read %q, want %q", got, "hello")
+			}
+			if got := <-requests; got != tc.wantRequest {
+				t.Errorf("SOCKS5 proxy received request %q, want %q", got, tc.wantRequest)
+			}
+		})
+	}
+}
+
+func TestSOCKS5DialerInvalidAddress(t *testing.T) {
+	if _, err := SOCKS5Dialer("proxy.example.com", "", "", nil); err == nil {
+		t.Error("SOCKS5Dialer() with an address without port returned nil error, want error")
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(2)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Acquire(context.Background()); err != nil {
+				t.Errorf("Acquire() returned error: %v", err)
+				return
+			}
+			defer s.Release()
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Errorf("Semaphore allowed %d operations at the same time, want at most 2", maxRunning)
+	}
+}
+
+func TestSemaphoreSetLimit(t *testing.T) {
+	s := NewSemaphore(0)
+	s.Acquire(context.Background())
+	acquired := make(chan bool)
+	go func() {
+		s.Acquire(context.Background())
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Acquire() returned while the limit was reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.SetLimit(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() did not return after the limit was raised")
+	}
+}
+
+func TestCycleBudget(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	start := clock.Now()
+	b := NewCycleBudget(time.Hour, 3, time.Minute, clock)
+
+	// The first instance gets a third of the cycle.
+	deadline, ok := b.Next()
+	if want := start.Add(20 * time.Minute); !ok || !deadline.Equal(want) {
+		t.Errorf("Next() = (%v, %t), want (%v, true)", deadline, ok, want)
+	}
+	// A slow first instance leaves the rest of the cycle to the remaining instances.
+	clock.Advance(50 * time.Minute)
+	deadline, ok = b.Next()
+	if want := start.Add(55 * time.Minute); !ok || !deadline.Equal(want) {
+		t.Errorf("Next() = (%v, %t), want (%v, true)", deadline, ok, want)
+	}
+	// The last instance is deferred when the time left is shorter than the minimum share.
+	clock.Advance(9*time.Minute + 30*time.Second)
+	if deadline, ok = b.Next(); ok {
+		t.Errorf("Next() = (%v, %t), want deferred", deadline, ok)
+	}
+}
+
+func TestCollectionOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities []int32
+		deferrals  []int32
+		want       []int
+	}{
+		{
+			name:       "configuration order",
+			priorities: []int32{0, 0, 0},
+			deferrals:  []int32{0, 0, 0},
+			want:       []int{0, 1, 2},
+		},
+		{
+			name:       "highest priority first",
+			priorities: []int32{0, 2, 1},
+			deferrals:  []int32{0, 0, 0},
+			want:       []int{1, 2, 0},
+		},
+		{
+			name:       "deferred first within a priority",
+			priorities: []int32{1, 1, 0},
+			deferrals:  []int32{0, 0, 1},
+			want:       []int{2, 0, 1},
+		},
+		{
+			name:       "deferred target raised above a higher priority",
+			priorities: []int32{1, 0, 0},
+			deferrals:  []int32{0, 0, 2},
+			want:       []int{2, 0, 1},
+		},
+		{
+			name:       "deferred target below a higher priority",
+			priorities: []int32{3, 0},
+			deferrals:  []int32{0, 1},
+			want:       []int{0, 1},
+		},
+		{
+			name:       "deferred target overtakes after enough cycles",
+			priorities: []int32{3, 0},
+			deferrals:  []int32{0, 4},
+			want:       []int{1, 0},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CollectionOrder(tc.priorities, tc.deferrals); !cmp.Equal(got, tc.want) {
+				t.Errorf("CollectionOrder(%v, %v) = %v, want %v", tc.priorities, tc.deferrals, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSemaphoreAcquireCanceled(t *testing.T) {
+	s := NewSemaphore(1)
+	s.Acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx); err == nil {
+		t.Error("Acquire() returned nil error after ctx was done, want error")
+	}
+	s.Release()
+	if err := s.Acquire(context.Background()); err != nil {
+		t.Errorf("Acquire() after a canceled Acquire() returned error: %v", err)
+	}
+}
+
+func TestAcquireRule(t *testing.T) {
+	release, err := AcquireRule(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireRule() without a rule limiter returned error: %v", err)
+	}
+	release()
+
+	ctx := WithRuleLimiter(context.Background(), NewSemaphore(1))
+	release, err = AcquireRule(ctx)
+	if err != nil {
+		t.Fatalf("AcquireRule() returned error: %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := AcquireRule(waitCtx); err == nil {
+		t.Error("AcquireRule() beyond the limit returned nil error, want error")
+	}
+	release()
+	if _, err := AcquireRule(ctx); err != nil {
+		t.Errorf("AcquireRule() after the release returned error: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook posts summaries of collected data to webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+// Operators of the conditions.
+const (
+	OperatorEquals      = "equals"
+	OperatorNotEquals   = "not_equals"
+	OperatorGreaterThan = "greater_than"
+	OperatorLessThan    = "less_than"
+)
+
+// requestTimeout is the timeout of a post to the webhook.
+const requestTimeout = 30 * time.Second
+
+// Condition matches the rows of a rule whose field compares to the value with the operator.
+type Condition struct {
+	Rule     string `json:"rule"`
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// Detail is the collected data of a rule in a summary.
+type Detail struct {
+	Name   string              `json:"name"`
+	Fields []map[string]string `json:"fields"`
+}
+
+// Summary is the JSON body posted to the webhook.
+type Summary struct {
+	CollectionType    string      `json:"collection_type"`
+	Instance          string      `json:"instance"`
+	InstanceID        string      `json:"instance_id"`
+	AgentVersion      string      `json:"agent_version"`
+	MatchedConditions []Condition `json:"matched_conditions,omitempty"`
+	Details           []Detail    `json:"details"`
+}
+
+// Client posts summaries to a webhook.
+type Client struct {
+	url         string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// ValidURL returns true if u is an absolute http or https URL without fragment.
+func ValidURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" && parsed.Fragment == ""
+}
+
+// ValidCondition returns an error if the condition has no rule or field or an unknown operator.
+func ValidCondition(c Condition) error {
+	if c.Rule == "" || c.Field == "" {
+		return fmt.Errorf("condition requires a rule and a field")
+	}
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals:
+	case OperatorGreaterThan, OperatorLessThan:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("operator %s requires a number, got %q", c.Operator, c.Value)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+	return nil
+}
+
+// Matched returns the conditions matched by any row of the details.
+func Matched(details []internal.Details, conditions []Condition) []Condition {
+	var matched []Condition
+	for _, c := range conditions {
+		if matchesAny(details, c) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+func matchesAny(details []internal.Details, c Condition) bool {
+	for _, d := range details {
+		if d.Name != c.Rule {
+			continue
+		}
+		for _, fields := range d.Fields {
+			if v, ok := fields[c.Field]; ok && c.matches(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether the value satisfies the condition. Values that are not numbers never
+// match greater_than and less_than.
+func (c Condition) matches(value string) bool {
+	switch c.Operator {
+	case OperatorEquals:
+		return value == c.Value
+	case OperatorNotEquals:
+		return value != c.Value
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	want, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return false
+	}
+	if c.Operator == OperatorGreaterThan {
+		return v > want
+	}
+	return c.Operator == OperatorLessThan && v < want
+}
+
+// NewSummary returns the summary of the details.
+func NewSummary(collectionType, instance, instanceID string, details []internal.Details, matched []Condition) Summary {
+	s := Summary{
+		CollectionType:    collectionType,
+		Instance:          instance,
+		InstanceID:        instanceID,
+		AgentVersion:      internal.AgentVersion,
+		MatchedConditions: matched,
+		Details:           []Detail{},
+	}
+	for _, d := range details {
+		s.Details = append(s.Details, Detail{Name: d.Name, Fields: d.Fields})
+	}
+	return s
+}
+
+// NewClient creates a Client posting to the webhook URL.
+// The bearer token is sent in the Authorization header unless it is empty.
+// If dialer is not nil, it is used for all connections to the webhook.
+func NewClient(webhookURL, bearerToken string, dialer internal.ContextDialer) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if dialer != nil {
+		transport.DialContext = dialer.DialContext
+	}
+	return &Client{
+		url:         webhookURL,
+		bearerToken: bearerToken,
+		httpClient:  &http.Client{Transport: transport, Timeout: requestTimeout},
+	}
+}
+
+// Post posts the summary to the webhook. It returns an error if the webhook does not respond
+// with a 2xx status.
+func (c *Client) Post(ctx context.Context, s Summary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
+)
+
+var testDetails = []internal.Details{
+	{
+		Name:   "DB_BACKUP_POLICY",
+		Fields: []map[string]string{{"max_backup_age": "10"}},
+	},
+	{
+		Name:   "DB_TDE_STATUS",
+		Fields: []map[string]string{{"db_name": "db1", "is_encrypted": "true"}, {"db_name": "db2", "is_encrypted": "false"}},
+	},
+}
+
+func TestValidURL(t *testing.T) {
+	testcases := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://alerts.example.com/hooks/sql?team=dba", want: true},
+		{url: "http://localhost:8080/", want: true},
+		{url: "alerts.example.com/hooks"},
+		{url: "ftp://alerts.example.com/hooks"},
+		{url: "https://alerts.example.com/hooks#fragment"},
+	}
+	for _, tc := range testcases {
+		if got := ValidURL(tc.url); got != tc.want {
+			t.Errorf("ValidURL(%q) = %v, want %v", tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestValidCondition(t *testing.T) {
+	testcases := []struct {
+		name    string
+		c       Condition
+		wantErr bool
+	}{
+		{
+			name: "equals",
+			c:    Condition{Rule: "DB_TDE_STATUS", Field: "is_encrypted", Operator: OperatorEquals, Value: "false"},
+		},
+		{
+			name: "greater than number",
+			c:    Condition{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: OperatorGreaterThan, Value: "7"},
+		},
+		{
+			name:    "greater than text",
+			c:       Condition{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: OperatorGreaterThan, Value: "week"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown operator",
+			c:       Condition{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: "contains", Value: "7"},
+			wantErr: true,
+		},
+		{
+			name:    "missing field",
+			c:       Condition{Rule: "DB_BACKUP_POLICY", Operator: OperatorEquals},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidCondition(tc.c); (err != nil) != tc.wantErr {
+				t.Errorf("ValidCondition(%v) returned error %v, want error presence = %v", tc.c, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatched(t *testing.T) {
+	backupTooOld := Condition{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: OperatorGreaterThan, Value: "7"}
+	backupRecent := Condition{Rule: "DB_BACKUP_POLICY", Field: "max_backup_age", Operator: OperatorLessThan, Value: "7"}
+	notEncrypted := Condition{Rule: "DB_TDE_STATUS", Field: "is_encrypted", Operator: OperatorEquals, Value: "false"}
+	missingRule := Condition{Rule: "DB_MAX_PARALLELISM", Field: "maxDop", Operator: OperatorNotEquals, Value: "0"}
+	got := Matched(testDetails, []Condition{backupTooOld, backupRecent, notEncrypted, missingRule})
+	want := []Condition{backupTooOld, notEncrypted}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Matched() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
+func TestPost(t *testing.T) {
+	testcases := []struct {
+		name     string
+		token    string
+		status   int
+		wantAuth string
+		wantErr  bool
+	}{
+		{
+			name:     "success with bearer token",
+			token:    "secret-token",
+			status:   http.StatusNoContent,
+			wantAuth: "Bearer secret-token",
+		},
+		{
+			name:   "success without token",
+			status: http.StatusOK,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotAuth, gotContentType string
+			var got Summary
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				gotContentType = r.Header.Get("Content-Type")
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("Failed to decode the posted summary: %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			s := NewSummary("sql", "instance-1", "123", testDetails, nil)
+			err := NewClient(server.URL, tc.token, nil).Post(context.Background(), s)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Post() returned error %v, want error presence = %v", err, tc.wantErr)
+			}
+			if gotAuth != tc.wantAuth {
+				t.Errorf("Post() sent Authorization %q, want %q", gotAuth, tc.wantAuth)
+			}
+			if gotContentType != "application/json" {
+				t.Errorf("Post() sent Content-Type %q, want application/json", gotContentType)
+			}
+			if diff := cmp.Diff(got, s); diff != "" {
+				t.Errorf("Post() posted wrong summary (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
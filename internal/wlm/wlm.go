@@ -19,9 +19,15 @@ package wlm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 	workloadmanager "google.golang.org/api/workloadmanager/v1"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
 )
@@ -30,12 +36,21 @@ const (
 	basePath = "https://workloadmanager-datawarehouse.googleapis.com/"
 )
 
+// probeRejection matches the message of the bad request workloadmanager returns for a probe
+// request, which lacks the insight.
+var probeRejection = regexp.MustCompile(`(?i)\binsight\b`)
+
 // WorkloadManagerService the interface of WLM.
 type WorkloadManagerService interface {
 	SendRequest(string) (*workloadmanager.WriteInsightResponse, error)
 	UpdateRequest(*workloadmanager.WriteInsightRequest)
+	WriteInsightRequest() *workloadmanager.WriteInsightRequest
+	Probe(string) error
 }
 
+// ErrPermissionDenied is returned by Probe if the agent is not allowed to write insights.
+var ErrPermissionDenied = errors.New("permission denied")
+
 // WLM struct which contains workloadmanager service.
 type WLM struct {
 	wlmService *workloadmanager.Service
@@ -43,8 +58,23 @@ type WLM struct {
 }
 
 // NewWorkloadManager creates new WLM and it return non-nil error if any error was caught.
-func NewWorkloadManager(ctx context.Context) (*WLM, error) {
-	wlm, err := workloadmanager.NewService(ctx, option.WithEndpoint(basePath))
+// If endpoint is empty, the production endpoint is used.
+// If dialer is not nil, it is used for all connections to workload manager.
+func NewWorkloadManager(ctx context.Context, endpoint string, dialer internal.ContextDialer) (*WLM, error) {
+	if endpoint == "" {
+		endpoint = basePath
+	}
+	opts := []option.ClientOption{option.WithEndpoint(endpoint)}
+	if dialer != nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.DialContext = dialer.DialContext
+		trans, err := htransport.NewTransport(ctx, base, option.WithScopes(workloadmanager.CloudPlatformScope))
+		if err != nil {
+			return nil, fmt.Errorf("%v error creating WLM transport", err)
+		}
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: trans}))
+	}
+	wlm, err := workloadmanager.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("%v error creating WLM client", err)
 	}
@@ -56,11 +86,70 @@ func (wlm *WLM) SendRequest(location string) (*workloadmanager.WriteInsightRespo
 	return wlm.wlmService.Projects.Locations.Insights.WriteInsight(location, wlm.Request).Do()
 }
 
+// Probe checks whether insights can be written to workloadmanager at the location without sending
+// collected data. The probe writes a request without an insight, which workloadmanager rejects as
+// a bad request for the missing insight once the agent is authenticated and authorized. Bad
+// requests for other reasons, such as an unknown location, fail the probe.
+func (wlm *WLM) Probe(location string) error {
+	_, err := wlm.wlmService.Projects.Locations.Insights.WriteInsight(location, &workloadmanager.WriteInsightRequest{}).Do()
+	return probeError(err)
+}
+
+// probeError classifies the error of a probe request.
+func probeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		switch gErr.Code {
+		case http.StatusBadRequest:
+			if missingInsight(gErr) {
+				return nil
+			}
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+		}
+	}
+	return err
+}
+
+// missingInsight reports whether the bad request err rejects the request for its missing insight,
+// either as a violation of the insight field or in the message.
+func missingInsight(err *googleapi.Error) bool {
+	for _, d := range err.Details {
+		m, ok := d.(map[string]any)
+		if !ok || m["@type"] != "type.googleapis.com/google.rpc.BadRequest" {
+			continue
+		}
+		violations, _ := m["fieldViolations"].([]any)
+		for _, v := range violations {
+			if fv, ok := v.(map[string]any); ok && fv["field"] == "insight" {
+				return true
+			}
+		}
+	}
+	if probeRejection.MatchString(err.Message) {
+		return true
+	}
+	for _, item := range err.Errors {
+		if probeRejection.MatchString(item.Message) {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateRequest updates WLM request.
 func (wlm *WLM) UpdateRequest(writeInsightRequest *workloadmanager.WriteInsightRequest) {
 	wlm.Request = writeInsightRequest
 }
 
+// WriteInsightRequest returns the WLM request.
+func (wlm *WLM) WriteInsightRequest() *workloadmanager.WriteInsightRequest {
+	return wlm.Request
+}
+
 // InitializeSQLServerValidation initializes and returns SqlserverValidation.
 func InitializeSQLServerValidation(projectID, instance string) *workloadmanager.SqlserverValidation {
 	return &workloadmanager.SqlserverValidation{
@@ -103,3 +192,110 @@ func UpdateValidationDetails(sqlservervalidation *workloadmanager.SqlserverValid
 	}
 	return sqlservervalidation
 }
+
+// ValidationDetailsToDetails converts the ValidationDetails in SqlserverValidation back to details.
+// It is the inverse of UpdateValidationDetails.
+func ValidationDetailsToDetails(sqlservervalidation *workloadmanager.SqlserverValidation) []internal.Details {
+	details := []internal.Details{}
+	if sqlservervalidation == nil {
+		return details
+	}
+	for _, vd := range sqlservervalidation.ValidationDetails {
+		fields := []map[string]string{}
+		for _, d := range vd.Details {
+			fields = append(fields, d.Fields)
+		}
+		details = append(details, internal.Details{Name: vd.Type, Fields: fields})
+	}
+	return details
+}
+
+// SplitRequest splits the request into requests of at most maxBytes of JSON, so that workload
+// manager does not reject the collected data of very large instances as a whole. The validation
+// details are split by rule, and the rows of a rule are split further if the rule alone does not
+// fit. All the parts keep the instance, project and sent time of the request; a row larger than
+// maxBytes is sent in a part of its own. The request is returned as the only part if it fits or
+// maxBytes is not positive.
+func SplitRequest(request *workloadmanager.WriteInsightRequest, maxBytes int) ([]*workloadmanager.WriteInsightRequest, error) {
+	size, err := jsonSize(request)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes <= 0 || size <= maxBytes || request.Insight == nil || request.Insight.SqlserverValidation == nil {
+		return []*workloadmanager.WriteInsightRequest{request}, nil
+	}
+	newPart := func() *workloadmanager.WriteInsightRequest {
+		validation := *request.Insight.SqlserverValidation
+		validation.ValidationDetails = nil
+		insight := *request.Insight
+		insight.SqlserverValidation = &validation
+		part := *request
+		part.Insight = &insight
+		return &part
+	}
+	base, err := jsonSize(newPart())
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*workloadmanager.WriteInsightRequest
+	var current *workloadmanager.WriteInsightRequest
+	currentSize := 0
+	add := func(detail *workloadmanager.SqlserverValidationValidationDetail, size int) {
+		if current != nil && currentSize+size > maxBytes {
+			current = nil
+		}
+		if current == nil {
+			current = newPart()
+			currentSize = base
+			parts = append(parts, current)
+		}
+		validation := current.Insight.SqlserverValidation
+		validation.ValidationDetails = append(validation.ValidationDetails, detail)
+		currentSize += size
+	}
+	for _, detail := range request.Insight.SqlserverValidation.ValidationDetails {
+		// Sizes include the separating comma.
+		size, err := jsonSize(detail)
+		if err != nil {
+			return nil, err
+		}
+		if base+size+1 <= maxBytes {
+			add(detail, size+1)
+			continue
+		}
+		overhead, err := jsonSize(&workloadmanager.SqlserverValidationValidationDetail{Type: detail.Type})
+		if err != nil {
+			return nil, err
+		}
+		chunk := &workloadmanager.SqlserverValidationValidationDetail{Type: detail.Type}
+		chunkSize := overhead
+		for _, row := range detail.Details {
+			rowSize, err := jsonSize(row)
+			if err != nil {
+				return nil, err
+			}
+			if len(chunk.Details) > 0 && base+chunkSize+rowSize+1 > maxBytes {
+				add(chunk, chunkSize+1)
+				chunk = &workloadmanager.SqlserverValidationValidationDetail{Type: detail.Type}
+				chunkSize = overhead
+			}
+			chunk.Details = append(chunk.Details, row)
+			chunkSize += rowSize + 1
+		}
+		add(chunk, chunkSize+1)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, newPart())
+	}
+	return parts, nil
+}
+
+// jsonSize returns the size of v marshaled to JSON.
+func jsonSize(v any) (int, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
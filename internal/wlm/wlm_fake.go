@@ -27,6 +27,7 @@ import (
 type MockWlmService struct {
 	MockError    bool
 	MockHTTPCode int
+	MockProbeErr error
 	Request      *workloadmanager.WriteInsightRequest
 }
 
@@ -52,6 +53,16 @@ func (m *MockWlmService) UpdateRequest(writeInsightRequest *workloadmanager.Writ
 	m.Request = writeInsightRequest
 }
 
+// WriteInsightRequest mock function.
+func (m *MockWlmService) WriteInsightRequest() *workloadmanager.WriteInsightRequest {
+	return m.Request
+}
+
+// Probe mock function.
+func (m *MockWlmService) Probe(location string) error {
+	return m.MockProbeErr
+}
+
 // InitializeMockWriteInsightRequest mock function.
 func (m *MockWlmService) InitializeMockWriteInsightRequest() *workloadmanager.WriteInsightRequest {
 	return &workloadmanager.WriteInsightRequest{}
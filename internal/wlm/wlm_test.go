@@ -17,9 +17,15 @@ limitations under the License.
 package wlm
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/api/googleapi"
 	workloadmanager "google.golang.org/api/workloadmanager/v1"
 	"google.golang.org/protobuf/testing/protocmp"
 	"github.com/GoogleCloudPlatform/sql-server-agent/internal"
@@ -86,6 +92,25 @@ func TestUpdateValidationDetails(t *testing.T) {
 	}
 }
 
+func TestValidationDetailsToDetails(t *testing.T) {
+	want := []internal.Details{
+		{
+			Name:   "testDetailName",
+			Fields: []map[string]string{{"testField": "testValue"}},
+		},
+		{
+			Name:   "testEmptyDetailName",
+			Fields: []map[string]string{},
+		},
+	}
+	validation := UpdateValidationDetails(&workloadmanager.SqlserverValidation{}, want)
+
+	got := ValidationDetailsToDetails(validation)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ValidationDetailsToDetails() returned wrong result (-got +want):\n%s", diff)
+	}
+}
+
 func TestUpdateRequest(t *testing.T) {
 	w := WLM{}
 	input := &workloadmanager.WriteInsightRequest{
@@ -97,6 +122,72 @@ func TestUpdateRequest(t *testing.T) {
 	}
 }
 
+func TestSplitRequest(t *testing.T) {
+	rows := func(n int) []map[string]string {
+		res := []map[string]string{}
+		for i := 0; i < n; i++ {
+			res = append(res, map[string]string{"db_name": fmt.Sprintf("db%03d", i), "value": strings.Repeat("x", 100)})
+		}
+		return res
+	}
+	details := []internal.Details{
+		{Name: "DB_LOG_DISK_SEPARATION", Fields: rows(3)},
+		{Name: "DB_MAX_PARALLELISM", Fields: rows(1)},
+		{Name: "DB_VIRTUAL_LOG_FILE_COUNT", Fields: rows(40)},
+		{Name: "DB_BUFFER_POOL_EXTENSION", Fields: []map[string]string{}},
+	}
+	newRequest := func() *workloadmanager.WriteInsightRequest {
+		validation := UpdateValidationDetails(InitializeSQLServerValidation("test-project", "test-instance"), details)
+		request := InitializeWriteInsightRequest(validation, "123")
+		request.Insight.SentTime = "2024-05-01T10:00:00Z"
+		return request
+	}
+
+	testcases := []struct {
+		name      string
+		maxBytes  int
+		wantParts int
+	}{
+		{name: "no limit", wantParts: 1},
+		{name: "request fits", maxBytes: 1 << 20, wantParts: 1},
+		{name: "split by rule and rows", maxBytes: 2000, wantParts: 5},
+		{name: "row larger than the limit", maxBytes: 100, wantParts: 45},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := newRequest()
+			parts, err := SplitRequest(request, tc.maxBytes)
+			if err != nil {
+				t.Fatalf("SplitRequest() returned an unexpected error: %v", err)
+			}
+			if len(parts) != tc.wantParts {
+				t.Errorf("SplitRequest() returned %d parts, want %d", len(parts), tc.wantParts)
+			}
+			got := map[string][]map[string]string{}
+			for _, part := range parts {
+				if part.Insight.InstanceId != "123" || part.Insight.SentTime != request.Insight.SentTime ||
+					part.Insight.SqlserverValidation.Instance != "test-instance" || part.Insight.SqlserverValidation.ProjectId != "test-project" {
+					t.Errorf("SplitRequest() returned a part without the identity of the instance: %+v", part.Insight)
+				}
+				if size, _ := jsonSize(part); tc.maxBytes >= 2000 && size > tc.maxBytes {
+					t.Errorf("SplitRequest() returned a part of %d bytes, want at most %d", size, tc.maxBytes)
+				}
+				for _, d := range ValidationDetailsToDetails(part.Insight.SqlserverValidation) {
+					got[d.Name] = append(got[d.Name], d.Fields...)
+				}
+			}
+			for _, d := range details {
+				if diff := cmp.Diff(got[d.Name], d.Fields, cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("SplitRequest() returned wrong rows for %s (-got +want):\n%s", d.Name, diff)
+				}
+			}
+			if diff := cmp.Diff(request, newRequest()); diff != "" {
+				t.Errorf("SplitRequest() modified the request (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestMockWLMService(t *testing.T) {
 	w := MockWlmService{}
 	if _, err := w.SendRequest(""); err == nil {
@@ -106,4 +197,83 @@ func TestMockWLMService(t *testing.T) {
 	if _, err := w.SendRequest(""); err != nil {
 		t.Errorf("Mocked SendRequest() returned unexpected error: %v", err)
 	}
+	if w.WriteInsightRequest() != w.Request {
+		t.Errorf("Mocked WriteInsightRequest() did not return the updated request")
+	}
+}
+
+func TestProbeError(t *testing.T) {
+	testcases := []struct {
+		name                 string
+		err                  error
+		wantErr              bool
+		wantPermissionDenied bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name: "bad request for the missing insight means the request was authorized",
+			err:  &googleapi.Error{Code: http.StatusBadRequest, Message: "Field insight is required"},
+		},
+		{
+			name: "field violation of the missing insight",
+			err: &googleapi.Error{Code: http.StatusBadRequest, Details: []any{
+				map[string]any{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "INVALID_ARGUMENT"},
+				map[string]any{
+					"@type":           "type.googleapis.com/google.rpc.BadRequest",
+					"fieldViolations": []any{map[string]any{"field": "insight", "description": "required"}},
+				},
+			}},
+		},
+		{
+			name: "missing insight in the error items",
+			err: &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{
+				{Reason: "badRequest", Message: "Insight must be set."},
+			}},
+		},
+		{
+			name:    "bad request for another reason",
+			err:     &googleapi.Error{Code: http.StatusBadRequest, Message: "Location us-nowhere1 is not found"},
+			wantErr: true,
+		},
+		{
+			name:    "bad request without a reason",
+			err:     &googleapi.Error{Code: http.StatusBadRequest},
+			wantErr: true,
+		},
+		{
+			name:                 "forbidden",
+			err:                  fmt.Errorf("wrapped: %w", &googleapi.Error{Code: http.StatusForbidden}),
+			wantErr:              true,
+			wantPermissionDenied: true,
+		},
+		{
+			name:                 "unauthorized",
+			err:                  &googleapi.Error{Code: http.StatusUnauthorized},
+			wantErr:              true,
+			wantPermissionDenied: true,
+		},
+		{
+			name:    "server error",
+			err:     &googleapi.Error{Code: http.StatusServiceUnavailable},
+			wantErr: true,
+		},
+		{
+			name:    "connection error",
+			err:     errors.New("dial tcp: i/o timeout"),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := probeError(tc.err)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("probeError(%v) = %v, want error presence = %v", tc.err, err, tc.wantErr)
+			}
+			if got := errors.Is(err, ErrPermissionDenied); got != tc.wantPermissionDenied {
+				t.Errorf("errors.Is(probeError(%v), ErrPermissionDenied) = %v, want %v", tc.err, got, tc.wantPermissionDenied)
+			}
+		})
+	}
 }
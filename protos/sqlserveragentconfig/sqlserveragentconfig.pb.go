@@ -47,11 +47,11 @@ type Configuration struct {
 	CredentialConfiguration []*CredentialConfiguration `protobuf:"bytes,2,rep,name=credential_configuration,json=credentialConfiguration,proto3" json:"credential_configuration,omitempty"`
 	// default logging is INFO level
 	LogLevel string `protobuf:"bytes,3,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
-	// default is 10 seconds
+	// default is 10 seconds, values below 5 seconds are raised to 5 seconds
 	CollectionTimeoutSeconds int32 `protobuf:"varint,4,opt,name=collection_timeout_seconds,json=collectionTimeoutSeconds,proto3" json:"collection_timeout_seconds,omitempty"`
 	// default max_retries is 3
 	MaxRetries int32 `protobuf:"varint,5,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
-	// default retry_interval is 3600
+	// default retry_interval is 3600, values below 10 are raised to 10
 	RetryIntervalInSeconds int32 `protobuf:"varint,6,opt,name=retry_interval_in_seconds,json=retryIntervalInSeconds,proto3" json:"retry_interval_in_seconds,omitempty"`
 	// default remote collection is false
 	RemoteCollection bool `protobuf:"varint,7,opt,name=remote_collection,json=remoteCollection,proto3" json:"remote_collection,omitempty"`
@@ -59,6 +59,129 @@ type Configuration struct {
 	LogToCloud bool `protobuf:"varint,8,opt,name=log_to_cloud,json=logToCloud,proto3" json:"log_to_cloud,omitempty"`
 	// default log_usage is false
 	DisableLogUsage bool `protobuf:"varint,9,opt,name=disable_log_usage,json=disableLogUsage,proto3" json:"disable_log_usage,omitempty"`
+	// defaults to Google Cloud Secret Manager
+	SecretProvider *SecretProviderConfiguration `protobuf:"bytes,10,opt,name=secret_provider,json=secretProvider,proto3" json:"secret_provider,omitempty"`
+	// IP address or network interface name used as the source of outbound
+	// SQL Server and workload manager connections
+	// defaults to empty, which uses the default routing
+	OutboundSourceAddress string `protobuf:"bytes,11,opt,name=outbound_source_address,json=outboundSourceAddress,proto3" json:"outbound_source_address,omitempty"`
+	// defaults to 100
+	// maximum number of persisted collection output files of each collection
+	// type kept in the log directory; only the files the agent names
+	// google-cloud-sql-server-agent-[host]-[type].json are deleted
+	OutputRetentionMaxFiles int32 `protobuf:"varint,12,opt,name=output_retention_max_files,json=outputRetentionMaxFiles,proto3" json:"output_retention_max_files,omitempty"`
+	// defaults to 30
+	// persisted collection output files older than this are deleted
+	OutputRetentionMaxAgeInDays int32 `protobuf:"varint,13,opt,name=output_retention_max_age_in_days,json=outputRetentionMaxAgeInDays,proto3" json:"output_retention_max_age_in_days,omitempty"`
+	// overrides the workload manager API endpoint, e.g. for a staging endpoint
+	// defaults to empty, which uses the production endpoint
+	WlmEndpoint string `protobuf:"bytes,14,opt,name=wlm_endpoint,json=wlmEndpoint,proto3" json:"wlm_endpoint,omitempty"`
+	// overrides the workload manager location collected data is sent to,
+	// e.g. "europe-west4" for data residency requirements
+	// defaults to empty, which uses the region of the instance
+	WlmLocation string `protobuf:"bytes,15,opt,name=wlm_location,json=wlmLocation,proto3" json:"wlm_location,omitempty"`
+	// defaults to 0, which uses the number of CPUs usable by the agent after
+	// resource_limits.max_procs is applied
+	// maximum number of rules running at the same time across guest os and
+	// SQL Server collections, including guest rules still running after their
+	// timeout; a rule waiting longer than its timeout fails
+	MaxConcurrentCollections int32 `protobuf:"varint,16,opt,name=max_concurrent_collections,json=maxConcurrentCollections,proto3" json:"max_concurrent_collections,omitempty"`
+	// OTLP/HTTP endpoint traces of the collection cycles are exported to,
+	// e.g. "http://localhost:4318"
+	// defaults to empty, which disables tracing
+	// read at startup; changes take effect after the agent restarts
+	OtlpTracesEndpoint string `protobuf:"bytes,17,opt,name=otlp_traces_endpoint,json=otlpTracesEndpoint,proto3" json:"otlp_traces_endpoint,omitempty"`
+	// publishes the collected data of each collection to a Pub/Sub topic
+	// defaults to empty, which disables publishing
+	Pubsub *PubSubConfiguration `protobuf:"bytes,18,opt,name=pubsub,proto3" json:"pubsub,omitempty"`
+	// defaults to 3600 (1 hour)
+	// a rule failing with the same error is logged in full once and then only
+	// summarized once per window
+	RepeatedErrorLogWindowInSeconds int32 `protobuf:"varint,19,opt,name=repeated_error_log_window_in_seconds,json=repeatedErrorLogWindowInSeconds,proto3" json:"repeated_error_log_window_in_seconds,omitempty"`
+	// wait types and SQL Server errors considered noise in all rules
+	Ignore *IgnoreConfiguration `protobuf:"bytes,20,opt,name=ignore,proto3" json:"ignore,omitempty"`
+	// posts a JSON summary of the collected data of each collection to a
+	// webhook, e.g. for alerting
+	// defaults to empty, which disables the webhook
+	Webhook *WebhookConfiguration `protobuf:"bytes,21,opt,name=webhook,proto3" json:"webhook,omitempty"`
+	// absolute path of the directory of the textfile collector of node_exporter
+	// the numeric collected data of each collection is written to, in the
+	// OpenMetrics text format, e.g. "/var/lib/node_exporter/textfile_collector";
+	// only string fields identifying rows, such as db_name and wait_type, are
+	// written as labels
+	// defaults to empty, which disables writing the files
+	TextfileDirectory string `protobuf:"bytes,22,opt,name=textfile_directory,json=textfileDirectory,proto3" json:"textfile_directory,omitempty"`
+	// minimum TLS version of the connections to SQL Server: "1.0", "1.1", "1.2"
+	// or "1.3"; connections are encrypted and fail if SQL Server does not
+	// support encryption with the version
+	// defaults to empty, which uses the minimum version of the driver
+	// overridden by the tls settings of a SQL credential
+	MinTlsVersion string `protobuf:"bytes,23,opt,name=min_tls_version,json=minTlsVersion,proto3" json:"min_tls_version,omitempty"`
+	// limits the CPU and memory used by the agent process so that it does not
+	// compete with SQL Server on busy hosts
+	// defaults to empty, which leaves the agent process unlimited
+	// read at startup; changes take effect after the agent restarts
+	ResourceLimits *ResourceLimits `protobuf:"bytes,24,opt,name=resource_limits,json=resourceLimits,proto3" json:"resource_limits,omitempty"`
+	// defaults to 10485760 (10 MiB), values below 65536 are raised to 65536
+	// maximum size in bytes of a request to workload manager; larger collected
+	// data is split by rule into several requests of the same instance
+	MaxWlmPayloadBytes int32 `protobuf:"varint,25,opt,name=max_wlm_payload_bytes,json=maxWlmPayloadBytes,proto3" json:"max_wlm_payload_bytes,omitempty"`
+	// classifies the physical disks reported in the local_ssd field of the
+	// guest os collection, e.g. SAN LUNs or third-party NVMe disks; the mappings
+	// are tried in order before the disks of compute engine are recognized, and
+	// disks that are not recognized are reported with their friendly name
+	DiskTypeMappings []*DiskTypeMapping `protobuf:"bytes,26,rep,name=disk_type_mappings,json=diskTypeMappings,proto3" json:"disk_type_mappings,omitempty"`
+	// time windows the collection services start collections in, e.g. the
+	// maintenance hours of the SQL Server instances; a collection started in a
+	// window runs to completion, and one-time collections ignore the windows;
+	// invalid windows are dropped, and the configuration is rejected if none of
+	// the windows is valid
+	// defaults to empty, which allows collections at any time
+	CollectionWindows []*CollectionWindow `protobuf:"bytes,27,rep,name=collection_windows,json=collectionWindows,proto3" json:"collection_windows,omitempty"`
+	// keeps the requests to workload manager that failed after all retries in a
+	// local directory instead of losing them
+	// defaults to empty, which disables the dead-letter directory
+	DeadLetter *DeadLetterConfiguration `protobuf:"bytes,28,opt,name=dead_letter,json=deadLetter,proto3" json:"dead_letter,omitempty"`
+	// thresholds of a successful collection cycle; successful cycles write the
+	// readiness file google-cloud-sql-server-agent-guest.ready or
+	// google-cloud-sql-server-agent-sql.ready in
+	// /var/run/google-cloud-sql-server-agent on linux and next to the agent
+	// executable on windows, and failed cycles remove it and are reported in the
+	// agent status
+	CycleStatus *CycleStatusConfiguration `protobuf:"bytes,29,opt,name=cycle_status,json=cycleStatus,proto3" json:"cycle_status,omitempty"`
+	// name of the secret in the secret provider holding the key the persisted
+	// collection output files are signed with; the HMAC-SHA256 signature of each
+	// file is written next to it with the extension ".sig" and is checked with
+	// the --verify flag
+	// defaults to empty, which does not sign the files
+	OutputSigningSecretName string `protobuf:"bytes,30,opt,name=output_signing_secret_name,json=outputSigningSecretName,proto3" json:"output_signing_secret_name,omitempty"`
+	// SOCKS5 proxy all outbound connections of the agent are made through: the
+	// connections to SQL Server and bastion hosts and the requests to workload
+	// manager, Pub/Sub, webhooks and the secret provider; WMI, remote ssh
+	// collection and the compute API listing the disks connect directly, and so
+	// does the secret provider when it reads the password of the proxy
+	// defaults to empty, which connects directly
+	Socks5Proxy *Socks5ProxyConfiguration `protobuf:"bytes,31,opt,name=socks5_proxy,json=socks5Proxy,proto3" json:"socks5_proxy,omitempty"`
+	// format of the files persisted by one-time collections: "json" writes one
+	// file per host; "json+archive" additionally bundles the guest os files of
+	// all hosts of a remote collection run into one gzip-compressed tar archive
+	// "google-cloud-sql-server-agent-guest-[time].tar.gz" with a manifest.json
+	// listing the files and their SHA-256 checksums
+	// defaults to empty, which is the same as "json"
+	OutputFormat string `protobuf:"bytes,32,opt,name=output_format,json=outputFormat,proto3" json:"output_format,omitempty"`
+	// fields of the collected details renamed before the details are exported,
+	// e.g. to match the column names of a downstream system; the renames are
+	// dropped if any rename names an unknown rule or field, or collides with
+	// another rename or an existing field of the rule
+	// defaults to empty, which exports the fields with their collected names
+	FieldRenames []*FieldRename `protobuf:"bytes,33,rep,name=field_renames,json=fieldRenames,proto3" json:"field_renames,omitempty"`
+	// timeout and retries of the requests to the metadata server reading the
+	// project, zone and name of the instance the agent runs on
+	MetadataServer *MetadataServerConfiguration `protobuf:"bytes,34,opt,name=metadata_server,json=metadataServer,proto3" json:"metadata_server,omitempty"`
+	// local SQLite database the collected data of every collection cycle is
+	// recorded in, in addition to the output format
+	// defaults to empty, which records nothing
+	Sqlite *SqliteConfiguration `protobuf:"bytes,35,opt,name=sqlite,proto3" json:"sqlite,omitempty"`
 }
 
 func (x *Configuration) Reset() {
@@ -156,85 +279,1542 @@ func (x *Configuration) GetDisableLogUsage() bool {
 	return false
 }
 
-type CollectionConfiguration struct {
+func (x *Configuration) GetSecretProvider() *SecretProviderConfiguration {
+	if x != nil {
+		return x.SecretProvider
+	}
+	return nil
+}
+
+func (x *Configuration) GetOutboundSourceAddress() string {
+	if x != nil {
+		return x.OutboundSourceAddress
+	}
+	return ""
+}
+
+func (x *Configuration) GetOutputRetentionMaxFiles() int32 {
+	if x != nil {
+		return x.OutputRetentionMaxFiles
+	}
+	return 0
+}
+
+func (x *Configuration) GetOutputRetentionMaxAgeInDays() int32 {
+	if x != nil {
+		return x.OutputRetentionMaxAgeInDays
+	}
+	return 0
+}
+
+func (x *Configuration) GetWlmEndpoint() string {
+	if x != nil {
+		return x.WlmEndpoint
+	}
+	return ""
+}
+
+func (x *Configuration) GetWlmLocation() string {
+	if x != nil {
+		return x.WlmLocation
+	}
+	return ""
+}
+
+func (x *Configuration) GetMaxConcurrentCollections() int32 {
+	if x != nil {
+		return x.MaxConcurrentCollections
+	}
+	return 0
+}
+
+func (x *Configuration) GetOtlpTracesEndpoint() string {
+	if x != nil {
+		return x.OtlpTracesEndpoint
+	}
+	return ""
+}
+
+func (x *Configuration) GetPubsub() *PubSubConfiguration {
+	if x != nil {
+		return x.Pubsub
+	}
+	return nil
+}
+
+func (x *Configuration) GetRepeatedErrorLogWindowInSeconds() int32 {
+	if x != nil {
+		return x.RepeatedErrorLogWindowInSeconds
+	}
+	return 0
+}
+
+func (x *Configuration) GetIgnore() *IgnoreConfiguration {
+	if x != nil {
+		return x.Ignore
+	}
+	return nil
+}
+
+func (x *Configuration) GetWebhook() *WebhookConfiguration {
+	if x != nil {
+		return x.Webhook
+	}
+	return nil
+}
+
+func (x *Configuration) GetTextfileDirectory() string {
+	if x != nil {
+		return x.TextfileDirectory
+	}
+	return ""
+}
+
+func (x *Configuration) GetMinTlsVersion() string {
+	if x != nil {
+		return x.MinTlsVersion
+	}
+	return ""
+}
+
+func (x *Configuration) GetResourceLimits() *ResourceLimits {
+	if x != nil {
+		return x.ResourceLimits
+	}
+	return nil
+}
+
+func (x *Configuration) GetMaxWlmPayloadBytes() int32 {
+	if x != nil {
+		return x.MaxWlmPayloadBytes
+	}
+	return 0
+}
+
+func (x *Configuration) GetDiskTypeMappings() []*DiskTypeMapping {
+	if x != nil {
+		return x.DiskTypeMappings
+	}
+	return nil
+}
+
+func (x *Configuration) GetCollectionWindows() []*CollectionWindow {
+	if x != nil {
+		return x.CollectionWindows
+	}
+	return nil
+}
+
+func (x *Configuration) GetDeadLetter() *DeadLetterConfiguration {
+	if x != nil {
+		return x.DeadLetter
+	}
+	return nil
+}
+
+func (x *Configuration) GetCycleStatus() *CycleStatusConfiguration {
+	if x != nil {
+		return x.CycleStatus
+	}
+	return nil
+}
+
+func (x *Configuration) GetOutputSigningSecretName() string {
+	if x != nil {
+		return x.OutputSigningSecretName
+	}
+	return ""
+}
+
+func (x *Configuration) GetSocks5Proxy() *Socks5ProxyConfiguration {
+	if x != nil {
+		return x.Socks5Proxy
+	}
+	return nil
+}
+
+func (x *Configuration) GetOutputFormat() string {
+	if x != nil {
+		return x.OutputFormat
+	}
+	return ""
+}
+
+func (x *Configuration) GetFieldRenames() []*FieldRename {
+	if x != nil {
+		return x.FieldRenames
+	}
+	return nil
+}
+
+func (x *Configuration) GetMetadataServer() *MetadataServerConfiguration {
+	if x != nil {
+		return x.MetadataServer
+	}
+	return nil
+}
+
+func (x *Configuration) GetSqlite() *SqliteConfiguration {
+	if x != nil {
+		return x.Sqlite
+	}
+	return nil
+}
+
+type SqliteConfiguration struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// defaults to True
-	// enables or disables guest os collection
-	CollectGuestOsMetrics bool `protobuf:"varint,1,opt,name=collect_guest_os_metrics,json=collectGuestOsMetrics,proto3" json:"collect_guest_os_metrics,omitempty"`
-	// defaults to 3600 (1 hour)
-	// guest os metrics collection interval
-	GuestOsMetricsCollectionIntervalInSeconds int32 `protobuf:"varint,2,opt,name=guest_os_metrics_collection_interval_in_seconds,json=guestOsMetricsCollectionIntervalInSeconds,proto3" json:"guest_os_metrics_collection_interval_in_seconds,omitempty"`
-	// defaultsto True
-	// enables or disables SQL Server collection
-	CollectSqlMetrics bool `protobuf:"varint,3,opt,name=collect_sql_metrics,json=collectSqlMetrics,proto3" json:"collect_sql_metrics,omitempty"`
-	// defaults to 3600 (1 hour)
-	// SQL Server metrics collection interval
-	SqlMetricsCollectionIntervalInSeconds int32 `protobuf:"varint,4,opt,name=sql_metrics_collection_interval_in_seconds,json=sqlMetricsCollectionIntervalInSeconds,proto3" json:"sql_metrics_collection_interval_in_seconds,omitempty"`
+	// absolute path of the SQLite database, which is created if it does not
+	// exist; each collected field is a row of the table "details" with the
+	// columns instance, rule, row, field, value and timestamp
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// defaults to 0, which keeps all fields
+	// fields recorded more than this many days ago are deleted
+	MaxAgeInDays int32 `protobuf:"varint,2,opt,name=max_age_in_days,json=maxAgeInDays,proto3" json:"max_age_in_days,omitempty"`
+}
+
+func (x *SqliteConfiguration) Reset() {
+	*x = SqliteConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SqliteConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SqliteConfiguration) ProtoMessage() {}
+
+func (x *SqliteConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SqliteConfiguration.ProtoReflect.Descriptor instead.
+func (*SqliteConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SqliteConfiguration) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SqliteConfiguration) GetMaxAgeInDays() int32 {
+	if x != nil {
+		return x.MaxAgeInDays
+	}
+	return 0
+}
+
+type MetadataServerConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// defaults to 2
+	// timeout of each request to the metadata server
+	TimeoutInSeconds int32 `protobuf:"varint,1,opt,name=timeout_in_seconds,json=timeoutInSeconds,proto3" json:"timeout_in_seconds,omitempty"`
+	// defaults to 3
+	// number of times a failed request is retried, waiting 1 second before the
+	// first retry and twice as long before every further retry; if all retries
+	// fail, the properties last read from the metadata server are used, and
+	// the cycle is skipped if they were never read
+	MaxRetries int32 `protobuf:"varint,2,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+}
+
+func (x *MetadataServerConfiguration) Reset() {
+	*x = MetadataServerConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MetadataServerConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetadataServerConfiguration) ProtoMessage() {}
+
+func (x *MetadataServerConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetadataServerConfiguration.ProtoReflect.Descriptor instead.
+func (*MetadataServerConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MetadataServerConfiguration) GetTimeoutInSeconds() int32 {
+	if x != nil {
+		return x.TimeoutInSeconds
+	}
+	return 0
+}
+
+func (x *MetadataServerConfiguration) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+type Socks5ProxyConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// host:port of the SOCKS5 proxy, e.g. "proxy.example.com:1080"
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// optional user name of the username/password authentication to the proxy
+	UserName string `protobuf:"bytes,2,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	// name of the secret in the secret provider holding the password of
+	// user_name; it is read once per collection cycle
+	PasswordSecretName string `protobuf:"bytes,3,opt,name=password_secret_name,json=passwordSecretName,proto3" json:"password_secret_name,omitempty"`
+}
+
+func (x *Socks5ProxyConfiguration) Reset() {
+	*x = Socks5ProxyConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Socks5ProxyConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Socks5ProxyConfiguration) ProtoMessage() {}
+
+func (x *Socks5ProxyConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Socks5ProxyConfiguration.ProtoReflect.Descriptor instead.
+func (*Socks5ProxyConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Socks5ProxyConfiguration) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Socks5ProxyConfiguration) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *Socks5ProxyConfiguration) GetPasswordSecretName() string {
+	if x != nil {
+		return x.PasswordSecretName
+	}
+	return ""
+}
+
+type CycleStatusConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// defaults to 80
+	// percentage of the instances of a cycle that must be collected for the
+	// cycle to succeed; values outside 1 to 100 use the default
+	MinInstanceSuccessPercent int32 `protobuf:"varint,1,opt,name=min_instance_success_percent,json=minInstanceSuccessPercent,proto3" json:"min_instance_success_percent,omitempty"`
+	// defaults to 90
+	// percentage of the rules run in a cycle that must be collected for the
+	// cycle to succeed; values outside 1 to 100 use the default
+	MinRuleSuccessPercent int32 `protobuf:"varint,2,opt,name=min_rule_success_percent,json=minRuleSuccessPercent,proto3" json:"min_rule_success_percent,omitempty"`
+}
+
+func (x *CycleStatusConfiguration) Reset() {
+	*x = CycleStatusConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CycleStatusConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleStatusConfiguration) ProtoMessage() {}
+
+func (x *CycleStatusConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleStatusConfiguration.ProtoReflect.Descriptor instead.
+func (*CycleStatusConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CycleStatusConfiguration) GetMinInstanceSuccessPercent() int32 {
+	if x != nil {
+		return x.MinInstanceSuccessPercent
+	}
+	return 0
+}
+
+func (x *CycleStatusConfiguration) GetMinRuleSuccessPercent() int32 {
+	if x != nil {
+		return x.MinRuleSuccessPercent
+	}
+	return 0
+}
+
+type DeadLetterConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// absolute path of the directory the failed requests are written to, one
+	// JSON file per request
+	Directory string `protobuf:"bytes,1,opt,name=directory,proto3" json:"directory,omitempty"`
+	// defaults to 100
+	// maximum size of the directory in MiB; the oldest requests are deleted
+	// when a new request does not fit
+	MaxSizeMb int32 `protobuf:"varint,2,opt,name=max_size_mb,json=maxSizeMb,proto3" json:"max_size_mb,omitempty"`
+	// defaults to False
+	// resends the requests of the directory before each request, from the
+	// oldest to the newest, so that workload manager receives the data in the
+	// order it was collected; sent requests are deleted
+	Replay bool `protobuf:"varint,3,opt,name=replay,proto3" json:"replay,omitempty"`
+}
+
+func (x *DeadLetterConfiguration) Reset() {
+	*x = DeadLetterConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeadLetterConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLetterConfiguration) ProtoMessage() {}
+
+func (x *DeadLetterConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLetterConfiguration.ProtoReflect.Descriptor instead.
+func (*DeadLetterConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeadLetterConfiguration) GetDirectory() string {
+	if x != nil {
+		return x.Directory
+	}
+	return ""
+}
+
+func (x *DeadLetterConfiguration) GetMaxSizeMb() int32 {
+	if x != nil {
+		return x.MaxSizeMb
+	}
+	return 0
+}
+
+func (x *DeadLetterConfiguration) GetReplay() bool {
+	if x != nil {
+		return x.Replay
+	}
+	return false
+}
+
+type CollectionWindow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// days of the week the window opens on, e.g. ["SATURDAY", "SUNDAY"]
+	// defaults to empty, which opens the window every day
+	Days []string `protobuf:"bytes,1,rep,name=days,proto3" json:"days,omitempty"`
+	// time of day the window opens at in the 24-hour format "HH:MM"
+	StartTime string `protobuf:"bytes,2,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	// time of day the window closes at in the 24-hour format "HH:MM"; a window
+	// closing before or when it opens closes the next day
+	EndTime string `protobuf:"bytes,3,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	// IANA time zone of the window, e.g. "America/New_York"
+	// defaults to "UTC"
+	TimeZone string `protobuf:"bytes,4,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+}
+
+func (x *CollectionWindow) Reset() {
+	*x = CollectionWindow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionWindow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionWindow) ProtoMessage() {}
+
+func (x *CollectionWindow) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionWindow.ProtoReflect.Descriptor instead.
+func (*CollectionWindow) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CollectionWindow) GetDays() []string {
+	if x != nil {
+		return x.Days
+	}
+	return nil
+}
+
+func (x *CollectionWindow) GetStartTime() string {
+	if x != nil {
+		return x.StartTime
+	}
+	return ""
+}
+
+func (x *CollectionWindow) GetEndTime() string {
+	if x != nil {
+		return x.EndTime
+	}
+	return ""
+}
+
+func (x *CollectionWindow) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+type DiskTypeMapping struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// regular expression matched against the friendly name of the disk on
+	// windows, or the product of the disk on remote linux machines
+	FriendlyNamePattern string `protobuf:"bytes,1,opt,name=friendly_name_pattern,json=friendlyNamePattern,proto3" json:"friendly_name_pattern,omitempty"`
+	// defaults to 0, which matches any size
+	// the size of the disk in bytes must be a multiple of this value
+	SizeMultipleBytes int64 `protobuf:"varint,2,opt,name=size_multiple_bytes,json=sizeMultipleBytes,proto3" json:"size_multiple_bytes,omitempty"`
+	// disk type reported for the matching disks, e.g. "SAN"
+	DiskType string `protobuf:"bytes,3,opt,name=disk_type,json=diskType,proto3" json:"disk_type,omitempty"`
+}
+
+func (x *DiskTypeMapping) Reset() {
+	*x = DiskTypeMapping{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DiskTypeMapping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskTypeMapping) ProtoMessage() {}
+
+func (x *DiskTypeMapping) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskTypeMapping.ProtoReflect.Descriptor instead.
+func (*DiskTypeMapping) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DiskTypeMapping) GetFriendlyNamePattern() string {
+	if x != nil {
+		return x.FriendlyNamePattern
+	}
+	return ""
+}
+
+func (x *DiskTypeMapping) GetSizeMultipleBytes() int64 {
+	if x != nil {
+		return x.SizeMultipleBytes
+	}
+	return 0
+}
+
+func (x *DiskTypeMapping) GetDiskType() string {
+	if x != nil {
+		return x.DiskType
+	}
+	return ""
+}
+
+type ResourceLimits struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// maximum number of CPUs executing the agent at the same time (GOMAXPROCS)
+	// defaults to 0, which uses all CPUs usable by the agent
+	MaxProcs int32 `protobuf:"varint,1,opt,name=max_procs,json=maxProcs,proto3" json:"max_procs,omitempty"`
+	// soft limit of the memory of the agent process in MiB; the agent collects
+	// garbage more often as it approaches the limit
+	// defaults to 0, which disables the limit
+	MemoryLimitMb int32 `protobuf:"varint,2,opt,name=memory_limit_mb,json=memoryLimitMb,proto3" json:"memory_limit_mb,omitempty"`
+	// runs the agent at a lower scheduling priority than SQL Server: nice 10 on
+	// Linux and the below normal priority class on Windows
+	// defaults to False
+	LowPriority bool `protobuf:"varint,3,opt,name=low_priority,json=lowPriority,proto3" json:"low_priority,omitempty"`
+}
+
+func (x *ResourceLimits) Reset() {
+	*x = ResourceLimits{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourceLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceLimits) ProtoMessage() {}
+
+func (x *ResourceLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceLimits.ProtoReflect.Descriptor instead.
+func (*ResourceLimits) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ResourceLimits) GetMaxProcs() int32 {
+	if x != nil {
+		return x.MaxProcs
+	}
+	return 0
+}
+
+func (x *ResourceLimits) GetMemoryLimitMb() int32 {
+	if x != nil {
+		return x.MemoryLimitMb
+	}
+	return 0
+}
+
+func (x *ResourceLimits) GetLowPriority() bool {
+	if x != nil {
+		return x.LowPriority
+	}
+	return false
+}
+
+type IgnoreConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// wait types left out of DB_WAIT_STATS, e.g. "PAGEIOLATCH_SH", in addition
+	// to a built-in list of idle and background waits
+	WaitTypes []string `protobuf:"bytes,1,rep,name=wait_types,json=waitTypes,proto3" json:"wait_types,omitempty"`
+	// SQL Server error numbers that are not reported as rule failures,
+	// e.g. 1222 for lock request time out
+	ErrorNumbers []int32 `protobuf:"varint,2,rep,packed,name=error_numbers,json=errorNumbers,proto3" json:"error_numbers,omitempty"`
+}
+
+func (x *IgnoreConfiguration) Reset() {
+	*x = IgnoreConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IgnoreConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IgnoreConfiguration) ProtoMessage() {}
+
+func (x *IgnoreConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IgnoreConfiguration.ProtoReflect.Descriptor instead.
+func (*IgnoreConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *IgnoreConfiguration) GetWaitTypes() []string {
+	if x != nil {
+		return x.WaitTypes
+	}
+	return nil
+}
+
+func (x *IgnoreConfiguration) GetErrorNumbers() []int32 {
+	if x != nil {
+		return x.ErrorNumbers
+	}
+	return nil
+}
+
+type WebhookConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// http or https URL the summaries are posted to
+	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// name of the secret holding the bearer token sent to the webhook
+	// defaults to empty, which sends no Authorization header
+	BearerTokenSecretName string `protobuf:"bytes,2,opt,name=bearer_token_secret_name,json=bearerTokenSecretName,proto3" json:"bearer_token_secret_name,omitempty"`
+	// the summary is only posted if any of the conditions matches
+	// defaults to empty, which posts the summary of every collection
+	Conditions []*WebhookCondition `protobuf:"bytes,3,rep,name=conditions,proto3" json:"conditions,omitempty"`
+}
+
+func (x *WebhookConfiguration) Reset() {
+	*x = WebhookConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WebhookConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookConfiguration) ProtoMessage() {}
+
+func (x *WebhookConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookConfiguration.ProtoReflect.Descriptor instead.
+func (*WebhookConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WebhookConfiguration) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *WebhookConfiguration) GetBearerTokenSecretName() string {
+	if x != nil {
+		return x.BearerTokenSecretName
+	}
+	return ""
+}
+
+func (x *WebhookConfiguration) GetConditions() []*WebhookCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+type WebhookCondition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the rule, e.g. "DB_BACKUP_POLICY"
+	Rule string `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	// name of the field of the rule, e.g. "max_backup_age"
+	Field string `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	// "equals", "not_equals", "greater_than" or "less_than"
+	// greater_than and less_than compare numbers
+	Operator string `protobuf:"bytes,3,opt,name=operator,proto3" json:"operator,omitempty"`
+	// value the field is compared to
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *WebhookCondition) Reset() {
+	*x = WebhookCondition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WebhookCondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookCondition) ProtoMessage() {}
+
+func (x *WebhookCondition) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookCondition.ProtoReflect.Descriptor instead.
+func (*WebhookCondition) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WebhookCondition) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *WebhookCondition) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *WebhookCondition) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *WebhookCondition) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type FieldRename struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the rule, e.g. "DB_BACKUP_POLICY"
+	Rule string `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	// name of the field of the rule, e.g. "max_backup_age"
+	Field string `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	// name the field is exported as, e.g. "backup_age_days"
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *FieldRename) Reset() {
+	*x = FieldRename{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldRename) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldRename) ProtoMessage() {}
+
+func (x *FieldRename) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldRename.ProtoReflect.Descriptor instead.
+func (*FieldRename) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *FieldRename) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *FieldRename) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldRename) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type PubSubConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// topic id in the project of the agent, or topic name in the format
+	// "projects/{project}/topics/{topic}"
+	Topic string `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// service account impersonated to publish messages
+	// defaults to empty, which uses the application default credentials
+	ImpersonateServiceAccount string `protobuf:"bytes,2,opt,name=impersonate_service_account,json=impersonateServiceAccount,proto3" json:"impersonate_service_account,omitempty"`
+	// sets the instance name as the ordering key of the messages
+	// defaults to false
+	EnableMessageOrdering bool `protobuf:"varint,3,opt,name=enable_message_ordering,json=enableMessageOrdering,proto3" json:"enable_message_ordering,omitempty"`
+}
+
+func (x *PubSubConfiguration) Reset() {
+	*x = PubSubConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PubSubConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PubSubConfiguration) ProtoMessage() {}
+
+func (x *PubSubConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PubSubConfiguration.ProtoReflect.Descriptor instead.
+func (*PubSubConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PubSubConfiguration) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PubSubConfiguration) GetImpersonateServiceAccount() string {
+	if x != nil {
+		return x.ImpersonateServiceAccount
+	}
+	return ""
+}
+
+func (x *PubSubConfiguration) GetEnableMessageOrdering() bool {
+	if x != nil {
+		return x.EnableMessageOrdering
+	}
+	return false
+}
+
+type SecretProviderConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// "GCP_SECRET_MANAGER" or "VAULT"
+	// defaults to "GCP_SECRET_MANAGER"
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// required when type is "VAULT"
+	Vault *VaultConfiguration `protobuf:"bytes,2,opt,name=vault,proto3" json:"vault,omitempty"`
+	// defaults to 3
+	// number of retries of transient errors of the secret provider, such as
+	// Secret Manager being unavailable; other errors are not retried
+	MaxRetries int32 `protobuf:"varint,3,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+	// defaults to 500
+	// interval before the first retry, doubled for each further retry up to
+	// 10 seconds
+	InitialRetryIntervalInMilliseconds int32 `protobuf:"varint,4,opt,name=initial_retry_interval_in_milliseconds,json=initialRetryIntervalInMilliseconds,proto3" json:"initial_retry_interval_in_milliseconds,omitempty"`
+}
+
+func (x *SecretProviderConfiguration) Reset() {
+	*x = SecretProviderConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretProviderConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretProviderConfiguration) ProtoMessage() {}
+
+func (x *SecretProviderConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretProviderConfiguration.ProtoReflect.Descriptor instead.
+func (*SecretProviderConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SecretProviderConfiguration) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SecretProviderConfiguration) GetVault() *VaultConfiguration {
+	if x != nil {
+		return x.Vault
+	}
+	return nil
+}
+
+func (x *SecretProviderConfiguration) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *SecretProviderConfiguration) GetInitialRetryIntervalInMilliseconds() int32 {
+	if x != nil {
+		return x.InitialRetryIntervalInMilliseconds
+	}
+	return 0
+}
+
+type VaultConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// address of the Vault server, e.g. "https://vault.example.com:8200"
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// mount path of the KV version 2 secrets engine
+	// defaults to "secret"
+	MountPath string `protobuf:"bytes,2,opt,name=mount_path,json=mountPath,proto3" json:"mount_path,omitempty"`
+	// path of a file containing the Vault token used for token auth
+	TokenFilePath string `protobuf:"bytes,3,opt,name=token_file_path,json=tokenFilePath,proto3" json:"token_file_path,omitempty"`
+	// role id used for AppRole auth
+	RoleId string `protobuf:"bytes,4,opt,name=role_id,json=roleId,proto3" json:"role_id,omitempty"`
+	// path of a file containing the secret id used for AppRole auth
+	SecretIdFilePath string `protobuf:"bytes,5,opt,name=secret_id_file_path,json=secretIdFilePath,proto3" json:"secret_id_file_path,omitempty"`
+	// optional Vault namespace (Vault Enterprise)
+	Namespace string `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+}
+
+func (x *VaultConfiguration) Reset() {
+	*x = VaultConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VaultConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VaultConfiguration) ProtoMessage() {}
+
+func (x *VaultConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VaultConfiguration.ProtoReflect.Descriptor instead.
+func (*VaultConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *VaultConfiguration) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *VaultConfiguration) GetMountPath() string {
+	if x != nil {
+		return x.MountPath
+	}
+	return ""
+}
+
+func (x *VaultConfiguration) GetTokenFilePath() string {
+	if x != nil {
+		return x.TokenFilePath
+	}
+	return ""
+}
+
+func (x *VaultConfiguration) GetRoleId() string {
+	if x != nil {
+		return x.RoleId
+	}
+	return ""
+}
+
+func (x *VaultConfiguration) GetSecretIdFilePath() string {
+	if x != nil {
+		return x.SecretIdFilePath
+	}
+	return ""
+}
+
+func (x *VaultConfiguration) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type CollectionConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// defaults to True
+	// enables or disables guest os collection
+	CollectGuestOsMetrics bool `protobuf:"varint,1,opt,name=collect_guest_os_metrics,json=collectGuestOsMetrics,proto3" json:"collect_guest_os_metrics,omitempty"`
+	// defaults to 3600 (1 hour), values below 10 are raised to 10
+	// guest os metrics collection interval
+	GuestOsMetricsCollectionIntervalInSeconds int32 `protobuf:"varint,2,opt,name=guest_os_metrics_collection_interval_in_seconds,json=guestOsMetricsCollectionIntervalInSeconds,proto3" json:"guest_os_metrics_collection_interval_in_seconds,omitempty"`
+	// defaultsto True
+	// enables or disables SQL Server collection
+	CollectSqlMetrics bool `protobuf:"varint,3,opt,name=collect_sql_metrics,json=collectSqlMetrics,proto3" json:"collect_sql_metrics,omitempty"`
+	// defaults to 3600 (1 hour), values below 10 are raised to 10
+	// SQL Server metrics collection interval
+	// the interval is shared equally between the instances of a cycle; an
+	// instance whose share of the time left is shorter than
+	// collection_timeout_seconds is deferred to the next cycle
+	SqlMetricsCollectionIntervalInSeconds int32 `protobuf:"varint,4,opt,name=sql_metrics_collection_interval_in_seconds,json=sqlMetricsCollectionIntervalInSeconds,proto3" json:"sql_metrics_collection_interval_in_seconds,omitempty"`
+	// defaults to 1000
+	// databases whose log file has more virtual log files than the threshold
+	// are flagged in DB_VIRTUAL_LOG_FILE_COUNT
+	VlfCountThreshold int32 `protobuf:"varint,5,opt,name=vlf_count_threshold,json=vlfCountThreshold,proto3" json:"vlf_count_threshold,omitempty"`
+	// defaults to False
+	// databases without transparent data encryption are flagged in
+	// DB_TDE_STATUS when enabled
+	ExpectTdeEncryption bool `protobuf:"varint,6,opt,name=expect_tde_encryption,json=expectTdeEncryption,proto3" json:"expect_tde_encryption,omitempty"`
+	// defaults to 10
+	// volumes hosting SQL Server data or log files with less free space, in
+	// percent of their size, are flagged in DB_SQL_VOLUME_FREE_SPACE
+	DiskFreeSpaceThresholdPercent int32 `protobuf:"varint,7,opt,name=disk_free_space_threshold_percent,json=diskFreeSpaceThresholdPercent,proto3" json:"disk_free_space_threshold_percent,omitempty"`
+	// defaults to 5
+	// SQL Server instances whose clock differs from the clock of the agent host
+	// by more seconds are flagged in DB_CLOCK_SKEW
+	ClockSkewThresholdSeconds int32 `protobuf:"varint,8,opt,name=clock_skew_threshold_seconds,json=clockSkewThresholdSeconds,proto3" json:"clock_skew_threshold_seconds,omitempty"`
+	// defaults to False
+	// per-database rules only run for the databases that changed since the
+	// last collection when enabled; the last results of the other databases
+	// are reported
+	IncrementalCollection bool `protobuf:"varint,9,opt,name=incremental_collection,json=incrementalCollection,proto3" json:"incremental_collection,omitempty"`
+	// defaults to False
+	// guest os collections of linux machines report a sample of the cpu and
+	// memory utilization of the machine when enabled
+	SampleHostUtilization bool `protobuf:"varint,10,opt,name=sample_host_utilization,json=sampleHostUtilization,proto3" json:"sample_host_utilization,omitempty"`
+	// defaults to 5000 (5 seconds)
+	// SQL Server queries of the collection waiting longer for a lock are aborted
+	// with a lock timeout error instead of blocking the collection; values below
+	// 1 use the default
+	LockTimeoutMilliseconds int32 `protobuf:"varint,11,opt,name=lock_timeout_milliseconds,json=lockTimeoutMilliseconds,proto3" json:"lock_timeout_milliseconds,omitempty"`
+	// defaults to 10, values above 100 are lowered to 100
+	// number of the queries using the most resources reported in DB_TOP_QUERIES
+	TopQueries int32 `protobuf:"varint,12,opt,name=top_queries,json=topQueries,proto3" json:"top_queries,omitempty"`
+	// "worker_time" or "logical_reads"
+	// defaults to "worker_time"
+	// resource usage the queries of DB_TOP_QUERIES are ranked by
+	TopQueriesMetric string `protobuf:"bytes,13,opt,name=top_queries_metric,json=topQueriesMetric,proto3" json:"top_queries_metric,omitempty"`
+	// defaults to ["High performance"]
+	// power plans recommended for SQL Server; the power_plan_recommended field
+	// of the guest os collection flags whether the active power plan is one of
+	// them, compared case-insensitively; high performance tuned profiles of
+	// linux are reported as "High performance"
+	RecommendedPowerPlans []string `protobuf:"bytes,14,rep,name=recommended_power_plans,json=recommendedPowerPlans,proto3" json:"recommended_power_plans,omitempty"`
+	// defaults to 0 (unlimited)
+	// maximum number of WMI queries per second sent to each windows machine;
+	// every WMI query, including each query of rules running several of them,
+	// takes a token; paces the guest os collections of many remote machines
+	WmiQueriesPerSecond int32 `protobuf:"varint,15,opt,name=wmi_queries_per_second,json=wmiQueriesPerSecond,proto3" json:"wmi_queries_per_second,omitempty"`
+	// defaults to False
+	// guest os collections report the CPU time, resident memory, goroutines
+	// and open SQL Server connections of the agent process in
+	// AGENT_RESOURCE_USAGE when enabled
+	ReportAgentResourceUsage bool `protobuf:"varint,16,opt,name=report_agent_resource_usage,json=reportAgentResourceUsage,proto3" json:"report_agent_resource_usage,omitempty"`
+	// defaults to 0 (disabled)
+	// results of expensive rules that rarely change, e.g.
+	// DB_TABLE_INDEX_COMPRESSION and DB_DEFAULT_DIRECTORIES, are reused for this
+	// many seconds before the rules run again, or until the edition or version
+	// of the sql server changes
+	CachedRulesTtlInSeconds int32 `protobuf:"varint,17,opt,name=cached_rules_ttl_in_seconds,json=cachedRulesTtlInSeconds,proto3" json:"cached_rules_ttl_in_seconds,omitempty"`
+	// defaults to False
+	// reports the indexes with a fill factor other than the default in
+	// DB_INDEX_FILL_FACTOR; reading the indexes of all databases is expensive
+	// on instances with many databases; when disabled, DB_INDEX_FILL_FACTOR
+	// is not run and is reported as disabled in the collection manifest
+	CollectIndexFillFactors bool `protobuf:"varint,18,opt,name=collect_index_fill_factors,json=collectIndexFillFactors,proto3" json:"collect_index_fill_factors,omitempty"`
+	// defaults to 1000
+	// indexes smaller than this many pages are left out of DB_INDEX_FILL_FACTOR
+	IndexFillFactorMinPages int32 `protobuf:"varint,19,opt,name=index_fill_factor_min_pages,json=indexFillFactorMinPages,proto3" json:"index_fill_factor_min_pages,omitempty"`
+	// defaults to 7
+	// databases without a successful DBCC CHECKDB in this many days are flagged
+	// as overdue in DB_LAST_CHECKDB
+	CheckdbMaxAgeInDays int32 `protobuf:"varint,20,opt,name=checkdb_max_age_in_days,json=checkdbMaxAgeInDays,proto3" json:"checkdb_max_age_in_days,omitempty"`
+	// defaults to False
+	// keeps the connections to each SQL Server instance open across the cycles
+	// of the collection service instead of logging in again every cycle;
+	// instances connected through a bastion host and one-time collections
+	// always log in again; the connections are closed when the configuration is
+	// reloaded or after three consecutive failed cycles of the instance
+	ReuseSqlConnections bool `protobuf:"varint,21,opt,name=reuse_sql_connections,json=reuseSqlConnections,proto3" json:"reuse_sql_connections,omitempty"`
+	// defaults to twice sql_metrics_collection_interval_in_seconds
+	// reused connections unused for this many seconds are closed
+	SqlConnectionIdleTimeoutInSeconds int32 `protobuf:"varint,22,opt,name=sql_connection_idle_timeout_in_seconds,json=sqlConnectionIdleTimeoutInSeconds,proto3" json:"sql_connection_idle_timeout_in_seconds,omitempty"`
+	// defaults to False
+	// reports COLLECTION_MANIFEST with the SQL Server and guest rules of each
+	// instance, listing the rules that ran, were skipped because they do not
+	// apply to the edition or version, are disabled, report cached or unchanged
+	// results or failed with an ignored error, and the rules that failed
+	ReportCollectionManifest bool `protobuf:"varint,23,opt,name=report_collection_manifest,json=reportCollectionManifest,proto3" json:"report_collection_manifest,omitempty"`
+}
+
+func (x *CollectionConfiguration) Reset() {
+	*x = CollectionConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionConfiguration) ProtoMessage() {}
+
+func (x *CollectionConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionConfiguration.ProtoReflect.Descriptor instead.
+func (*CollectionConfiguration) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CollectionConfiguration) GetCollectGuestOsMetrics() bool {
+	if x != nil {
+		return x.CollectGuestOsMetrics
+	}
+	return false
+}
+
+func (x *CollectionConfiguration) GetGuestOsMetricsCollectionIntervalInSeconds() int32 {
+	if x != nil {
+		return x.GuestOsMetricsCollectionIntervalInSeconds
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetCollectSqlMetrics() bool {
+	if x != nil {
+		return x.CollectSqlMetrics
+	}
+	return false
+}
+
+func (x *CollectionConfiguration) GetSqlMetricsCollectionIntervalInSeconds() int32 {
+	if x != nil {
+		return x.SqlMetricsCollectionIntervalInSeconds
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetVlfCountThreshold() int32 {
+	if x != nil {
+		return x.VlfCountThreshold
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetExpectTdeEncryption() bool {
+	if x != nil {
+		return x.ExpectTdeEncryption
+	}
+	return false
+}
+
+func (x *CollectionConfiguration) GetDiskFreeSpaceThresholdPercent() int32 {
+	if x != nil {
+		return x.DiskFreeSpaceThresholdPercent
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetClockSkewThresholdSeconds() int32 {
+	if x != nil {
+		return x.ClockSkewThresholdSeconds
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetIncrementalCollection() bool {
+	if x != nil {
+		return x.IncrementalCollection
+	}
+	return false
+}
+
+func (x *CollectionConfiguration) GetSampleHostUtilization() bool {
+	if x != nil {
+		return x.SampleHostUtilization
+	}
+	return false
+}
+
+func (x *CollectionConfiguration) GetLockTimeoutMilliseconds() int32 {
+	if x != nil {
+		return x.LockTimeoutMilliseconds
+	}
+	return 0
 }
 
-func (x *CollectionConfiguration) Reset() {
-	*x = CollectionConfiguration{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *CollectionConfiguration) GetTopQueries() int32 {
+	if x != nil {
+		return x.TopQueries
 	}
+	return 0
 }
 
-func (x *CollectionConfiguration) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *CollectionConfiguration) GetTopQueriesMetric() string {
+	if x != nil {
+		return x.TopQueriesMetric
+	}
+	return ""
 }
 
-func (*CollectionConfiguration) ProtoMessage() {}
+func (x *CollectionConfiguration) GetRecommendedPowerPlans() []string {
+	if x != nil {
+		return x.RecommendedPowerPlans
+	}
+	return nil
+}
 
-func (x *CollectionConfiguration) ProtoReflect() protoreflect.Message {
-	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *CollectionConfiguration) GetWmiQueriesPerSecond() int32 {
+	if x != nil {
+		return x.WmiQueriesPerSecond
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use CollectionConfiguration.ProtoReflect.Descriptor instead.
-func (*CollectionConfiguration) Descriptor() ([]byte, []int) {
-	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{1}
+func (x *CollectionConfiguration) GetReportAgentResourceUsage() bool {
+	if x != nil {
+		return x.ReportAgentResourceUsage
+	}
+	return false
 }
 
-func (x *CollectionConfiguration) GetCollectGuestOsMetrics() bool {
+func (x *CollectionConfiguration) GetCachedRulesTtlInSeconds() int32 {
 	if x != nil {
-		return x.CollectGuestOsMetrics
+		return x.CachedRulesTtlInSeconds
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetCollectIndexFillFactors() bool {
+	if x != nil {
+		return x.CollectIndexFillFactors
 	}
 	return false
 }
 
-func (x *CollectionConfiguration) GetGuestOsMetricsCollectionIntervalInSeconds() int32 {
+func (x *CollectionConfiguration) GetIndexFillFactorMinPages() int32 {
 	if x != nil {
-		return x.GuestOsMetricsCollectionIntervalInSeconds
+		return x.IndexFillFactorMinPages
 	}
 	return 0
 }
 
-func (x *CollectionConfiguration) GetCollectSqlMetrics() bool {
+func (x *CollectionConfiguration) GetCheckdbMaxAgeInDays() int32 {
 	if x != nil {
-		return x.CollectSqlMetrics
+		return x.CheckdbMaxAgeInDays
+	}
+	return 0
+}
+
+func (x *CollectionConfiguration) GetReuseSqlConnections() bool {
+	if x != nil {
+		return x.ReuseSqlConnections
 	}
 	return false
 }
 
-func (x *CollectionConfiguration) GetSqlMetricsCollectionIntervalInSeconds() int32 {
+func (x *CollectionConfiguration) GetSqlConnectionIdleTimeoutInSeconds() int32 {
 	if x != nil {
-		return x.SqlMetricsCollectionIntervalInSeconds
+		return x.SqlConnectionIdleTimeoutInSeconds
 	}
 	return 0
 }
 
+func (x *CollectionConfiguration) GetReportCollectionManifest() bool {
+	if x != nil {
+		return x.ReportCollectionManifest
+	}
+	return false
+}
+
 type CredentialConfiguration struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -292,12 +1872,28 @@ type CredentialConfiguration struct {
 	//	*CredentialConfiguration_RemoteWin
 	//	*CredentialConfiguration_RemoteLinux
 	GuestConfigurations isCredentialConfiguration_GuestConfigurations `protobuf_oneof:"guest_configurations"`
+	// defaults to 0
+	// order of the sql collection of the instances of this credential in a
+	// cycle; credentials with a higher priority are collected first so that the
+	// instances that matter most are collected before the cycle runs out of
+	// time; each consecutive cycle an instance is deferred in raises the
+	// priority by one so that low priority instances are not deferred forever,
+	// and credentials with the same priority keep the order of the
+	// configuration, starting with the deferred ones
+	Priority int32 `protobuf:"varint,17,opt,name=priority,proto3" json:"priority,omitempty"`
+	// optional workload of the instances of this credential: "oltp", "olap" or
+	// "reporting"; it is added as workload_type to the fields of the collected
+	// details and skips the rules that do not suit the workload: the index fill
+	// factor rule on oltp instances and the index fragmentation rule on olap
+	// instances and reporting replicas
+	// defaults to empty, which runs all rules
+	WorkloadType string `protobuf:"bytes,18,opt,name=workload_type,json=workloadType,proto3" json:"workload_type,omitempty"`
 }
 
 func (x *CredentialConfiguration) Reset() {
 	*x = CredentialConfiguration{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2]
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -310,7 +1906,7 @@ func (x *CredentialConfiguration) String() string {
 func (*CredentialConfiguration) ProtoMessage() {}
 
 func (x *CredentialConfiguration) ProtoReflect() protoreflect.Message {
-	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2]
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +1919,7 @@ func (x *CredentialConfiguration) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CredentialConfiguration.ProtoReflect.Descriptor instead.
 func (*CredentialConfiguration) Descriptor() ([]byte, []int) {
-	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{2}
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17}
 }
 
 // Deprecated: Marked as deprecated in sqlserveragentconfig/sqlserveragentconfig.proto.
@@ -452,63 +2048,392 @@ func (x *CredentialConfiguration) GetRemoteLinux() *CredentialConfiguration_Gues
 	if x, ok := x.GetGuestConfigurations().(*CredentialConfiguration_RemoteLinux); ok {
 		return x.RemoteLinux
 	}
-	return nil
+	return nil
+}
+
+func (x *CredentialConfiguration) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *CredentialConfiguration) GetWorkloadType() string {
+	if x != nil {
+		return x.WorkloadType
+	}
+	return ""
+}
+
+type isCredentialConfiguration_GuestConfigurations interface {
+	isCredentialConfiguration_GuestConfigurations()
+}
+
+type CredentialConfiguration_LocalCollection struct {
+	LocalCollection bool `protobuf:"varint,14,opt,name=local_collection,json=localCollection,proto3,oneof"`
+}
+
+type CredentialConfiguration_RemoteWin struct {
+	RemoteWin *CredentialConfiguration_GuestCredentialsRemoteWin `protobuf:"bytes,15,opt,name=remote_win,json=remoteWin,proto3,oneof"`
+}
+
+type CredentialConfiguration_RemoteLinux struct {
+	RemoteLinux *CredentialConfiguration_GuestCredentialsRemoteLinux `protobuf:"bytes,16,opt,name=remote_linux,json=remoteLinux,proto3,oneof"`
+}
+
+func (*CredentialConfiguration_LocalCollection) isCredentialConfiguration_GuestConfigurations() {}
+
+func (*CredentialConfiguration_RemoteWin) isCredentialConfiguration_GuestConfigurations() {}
+
+func (*CredentialConfiguration_RemoteLinux) isCredentialConfiguration_GuestConfigurations() {}
+
+type CredentialConfiguration_SqlCredentials struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// host name for SQL Server connection
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	// full user name for SQL Server connection
+	UserName string `protobuf:"bytes,2,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	// credential secret name stored in secrets manager
+	SecretName string `protobuf:"bytes,3,opt,name=secret_name,json=secretName,proto3" json:"secret_name,omitempty"`
+	// defaults to 1433
+	PortNumber int32 `protobuf:"varint,4,opt,name=port_number,json=portNumber,proto3" json:"port_number,omitempty"`
+	// optional SSH bastion host the SQL Server connection is tunneled through
+	Bastion *CredentialConfiguration_SshBastion `protobuf:"bytes,5,opt,name=bastion,proto3" json:"bastion,omitempty"`
+	// optional go-mssqldb connection string parameters appended to the
+	// connection string generated by the agent, e.g. "packet size=16384;log=1"
+	// the parameters override the driver defaults; the parameters managed by
+	// the agent are not allowed: server (and its aliases data source, address,
+	// addr, network address), user id (user, uid), password (pwd) and port
+	ConnectionStringExtra string `protobuf:"bytes,6,opt,name=connection_string_extra,json=connectionStringExtra,proto3" json:"connection_string_extra,omitempty"`
+	// optional IAM-authenticated database proxy the SQL Server connection is
+	// made through; user_name and secret_name are not used with a proxy
+	IamProxy *CredentialConfiguration_IamProxy `protobuf:"bytes,7,opt,name=iam_proxy,json=iamProxy,proto3" json:"iam_proxy,omitempty"`
+	// defaults to False
+	// marks the host as the virtual network name of a failover cluster
+	// instance; the collection follows the instance to the node it is active
+	// on and reports the node; the host must be the virtual network name, not
+	// a node, an IP address or an availability group listener with
+	// ApplicationIntent in connection_string_extra
+	FailoverClusterInstance bool `protobuf:"varint,8,opt,name=failover_cluster_instance,json=failoverClusterInstance,proto3" json:"failover_cluster_instance,omitempty"`
+	// optional encryption of the SQL Server connection of this instance,
+	// overriding min_tls_version and the encryption parameters of
+	// connection_string_extra, which must not be set with it
+	Tls *CredentialConfiguration_Tls `protobuf:"bytes,9,opt,name=tls,proto3" json:"tls,omitempty"`
+	// "tcp", "np" (named pipes) or "shared_memory"
+	// defaults to "tcp"
+	// named pipes and shared memory avoid the TCP stack when the agent runs on
+	// the same windows machine as SQL Server; they require the host to be
+	// "localhost", ".", "(local)" or a loopback address, ignore port_number and
+	// min_tls_version, and cannot be combined with remote collection, a
+	// bastion, an IAM proxy or a failover cluster instance
+	LocalTransport string `protobuf:"bytes,10,opt,name=local_transport,json=localTransport,proto3" json:"local_transport,omitempty"`
+	// optional versions of secret_name tried in order until SQL Server
+	// accepts the password, e.g. ["latest", "4"] keeps the collection working
+	// while a rotated password propagates; versions are Secret Manager version
+	// numbers or Vault KV version 2 versions; "latest" is the latest version
+	// defaults to ["latest"]
+	SecretVersions []string `protobuf:"bytes,11,rep,name=secret_versions,json=secretVersions,proto3" json:"secret_versions,omitempty"`
+	// optional databases the per-database rules are restricted to, matched
+	// case-insensitively; entries may be glob patterns, e.g. ["sales", "crm_*"]
+	// the server-level rules still cover all databases
+	// takes precedence over database_exclude, which is ignored when it is set
+	// defaults to empty, which collects all databases
+	DatabaseInclude []string `protobuf:"bytes,12,rep,name=database_include,json=databaseInclude,proto3" json:"database_include,omitempty"`
+	// optional databases the per-database rules leave out, matched
+	// case-insensitively; entries may be glob patterns, e.g. ["staging_*"]
+	// an entry must not also be listed in database_include
+	// defaults to empty, which collects all databases
+	DatabaseExclude []string `protobuf:"bytes,13,rep,name=database_exclude,json=databaseExclude,proto3" json:"database_exclude,omitempty"`
+}
+
+func (x *CredentialConfiguration_SqlCredentials) Reset() {
+	*x = CredentialConfiguration_SqlCredentials{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CredentialConfiguration_SqlCredentials) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CredentialConfiguration_SqlCredentials) ProtoMessage() {}
+
+func (x *CredentialConfiguration_SqlCredentials) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CredentialConfiguration_SqlCredentials.ProtoReflect.Descriptor instead.
+func (*CredentialConfiguration_SqlCredentials) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 0}
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetSecretName() string {
+	if x != nil {
+		return x.SecretName
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetPortNumber() int32 {
+	if x != nil {
+		return x.PortNumber
+	}
+	return 0
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetBastion() *CredentialConfiguration_SshBastion {
+	if x != nil {
+		return x.Bastion
+	}
+	return nil
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetConnectionStringExtra() string {
+	if x != nil {
+		return x.ConnectionStringExtra
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetIamProxy() *CredentialConfiguration_IamProxy {
+	if x != nil {
+		return x.IamProxy
+	}
+	return nil
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetFailoverClusterInstance() bool {
+	if x != nil {
+		return x.FailoverClusterInstance
+	}
+	return false
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetTls() *CredentialConfiguration_Tls {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetLocalTransport() string {
+	if x != nil {
+		return x.LocalTransport
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetSecretVersions() []string {
+	if x != nil {
+		return x.SecretVersions
+	}
+	return nil
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetDatabaseInclude() []string {
+	if x != nil {
+		return x.DatabaseInclude
+	}
+	return nil
+}
+
+func (x *CredentialConfiguration_SqlCredentials) GetDatabaseExclude() []string {
+	if x != nil {
+		return x.DatabaseExclude
+	}
+	return nil
+}
+
+type CredentialConfiguration_Tls struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// "true", "false" or "disable"; "true" encrypts the connection, "false"
+	// only encrypts the login and "disable" does not encrypt the connection
+	// defaults to "true"
+	Encrypt string `protobuf:"bytes,1,opt,name=encrypt,proto3" json:"encrypt,omitempty"`
+	// defaults to False
+	// trusts the certificate of SQL Server without validating it
+	TrustServerCertificate bool `protobuf:"varint,2,opt,name=trust_server_certificate,json=trustServerCertificate,proto3" json:"trust_server_certificate,omitempty"`
+	// minimum TLS version of the connection: "1.0", "1.1", "1.2" or "1.3"
+	// defaults to min_tls_version
+	MinTlsVersion string `protobuf:"bytes,3,opt,name=min_tls_version,json=minTlsVersion,proto3" json:"min_tls_version,omitempty"`
+}
+
+func (x *CredentialConfiguration_Tls) Reset() {
+	*x = CredentialConfiguration_Tls{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CredentialConfiguration_Tls) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CredentialConfiguration_Tls) ProtoMessage() {}
+
+func (x *CredentialConfiguration_Tls) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CredentialConfiguration_Tls.ProtoReflect.Descriptor instead.
+func (*CredentialConfiguration_Tls) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 1}
+}
+
+func (x *CredentialConfiguration_Tls) GetEncrypt() string {
+	if x != nil {
+		return x.Encrypt
+	}
+	return ""
+}
+
+func (x *CredentialConfiguration_Tls) GetTrustServerCertificate() bool {
+	if x != nil {
+		return x.TrustServerCertificate
+	}
+	return false
+}
+
+func (x *CredentialConfiguration_Tls) GetMinTlsVersion() string {
+	if x != nil {
+		return x.MinTlsVersion
+	}
+	return ""
 }
 
-type isCredentialConfiguration_GuestConfigurations interface {
-	isCredentialConfiguration_GuestConfigurations()
+type CredentialConfiguration_IamProxy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// local address of the proxy, either "host:port" or the path of a unix
+	// socket, e.g. "127.0.0.1:1433" or "unix:/var/run/proxy/sqlserver.sock"
+	Endpoint string `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	// email of the service account whose short-lived access tokens
+	// authenticate the connections through the proxy
+	IamIdentity string `protobuf:"bytes,2,opt,name=iam_identity,json=iamIdentity,proto3" json:"iam_identity,omitempty"`
 }
 
-type CredentialConfiguration_LocalCollection struct {
-	LocalCollection bool `protobuf:"varint,14,opt,name=local_collection,json=localCollection,proto3,oneof"`
+func (x *CredentialConfiguration_IamProxy) Reset() {
+	*x = CredentialConfiguration_IamProxy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-type CredentialConfiguration_RemoteWin struct {
-	RemoteWin *CredentialConfiguration_GuestCredentialsRemoteWin `protobuf:"bytes,15,opt,name=remote_win,json=remoteWin,proto3,oneof"`
+func (x *CredentialConfiguration_IamProxy) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-type CredentialConfiguration_RemoteLinux struct {
-	RemoteLinux *CredentialConfiguration_GuestCredentialsRemoteLinux `protobuf:"bytes,16,opt,name=remote_linux,json=remoteLinux,proto3,oneof"`
+func (*CredentialConfiguration_IamProxy) ProtoMessage() {}
+
+func (x *CredentialConfiguration_IamProxy) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (*CredentialConfiguration_LocalCollection) isCredentialConfiguration_GuestConfigurations() {}
+// Deprecated: Use CredentialConfiguration_IamProxy.ProtoReflect.Descriptor instead.
+func (*CredentialConfiguration_IamProxy) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 2}
+}
 
-func (*CredentialConfiguration_RemoteWin) isCredentialConfiguration_GuestConfigurations() {}
+func (x *CredentialConfiguration_IamProxy) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
 
-func (*CredentialConfiguration_RemoteLinux) isCredentialConfiguration_GuestConfigurations() {}
+func (x *CredentialConfiguration_IamProxy) GetIamIdentity() string {
+	if x != nil {
+		return x.IamIdentity
+	}
+	return ""
+}
 
-type CredentialConfiguration_SqlCredentials struct {
+type CredentialConfiguration_SshBastion struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// host name for SQL Server connection
+	// host name or IP address of the bastion
 	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
-	// full user name for SQL Server connection
+	// user name for the SSH connection to the bastion
 	UserName string `protobuf:"bytes,2,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
-	// credential secret name stored in secrets manager
-	SecretName string `protobuf:"bytes,3,opt,name=secret_name,json=secretName,proto3" json:"secret_name,omitempty"`
-	// defaults to 1433
+	// private key for the SSH connection to the bastion
+	// the known_hosts file must be in the same directory as the key
+	PrivateKeyPath string `protobuf:"bytes,3,opt,name=private_key_path,json=privateKeyPath,proto3" json:"private_key_path,omitempty"`
+	// defaults to 22
 	PortNumber int32 `protobuf:"varint,4,opt,name=port_number,json=portNumber,proto3" json:"port_number,omitempty"`
 }
 
-func (x *CredentialConfiguration_SqlCredentials) Reset() {
-	*x = CredentialConfiguration_SqlCredentials{}
+func (x *CredentialConfiguration_SshBastion) Reset() {
+	*x = CredentialConfiguration_SshBastion{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[3]
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CredentialConfiguration_SqlCredentials) String() string {
+func (x *CredentialConfiguration_SshBastion) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CredentialConfiguration_SqlCredentials) ProtoMessage() {}
+func (*CredentialConfiguration_SshBastion) ProtoMessage() {}
 
-func (x *CredentialConfiguration_SqlCredentials) ProtoReflect() protoreflect.Message {
-	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[3]
+func (x *CredentialConfiguration_SshBastion) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -519,33 +2444,33 @@ func (x *CredentialConfiguration_SqlCredentials) ProtoReflect() protoreflect.Mes
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CredentialConfiguration_SqlCredentials.ProtoReflect.Descriptor instead.
-func (*CredentialConfiguration_SqlCredentials) Descriptor() ([]byte, []int) {
-	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{2, 0}
+// Deprecated: Use CredentialConfiguration_SshBastion.ProtoReflect.Descriptor instead.
+func (*CredentialConfiguration_SshBastion) Descriptor() ([]byte, []int) {
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 3}
 }
 
-func (x *CredentialConfiguration_SqlCredentials) GetHost() string {
+func (x *CredentialConfiguration_SshBastion) GetHost() string {
 	if x != nil {
 		return x.Host
 	}
 	return ""
 }
 
-func (x *CredentialConfiguration_SqlCredentials) GetUserName() string {
+func (x *CredentialConfiguration_SshBastion) GetUserName() string {
 	if x != nil {
 		return x.UserName
 	}
 	return ""
 }
 
-func (x *CredentialConfiguration_SqlCredentials) GetSecretName() string {
+func (x *CredentialConfiguration_SshBastion) GetPrivateKeyPath() string {
 	if x != nil {
-		return x.SecretName
+		return x.PrivateKeyPath
 	}
 	return ""
 }
 
-func (x *CredentialConfiguration_SqlCredentials) GetPortNumber() int32 {
+func (x *CredentialConfiguration_SshBastion) GetPortNumber() int32 {
 	if x != nil {
 		return x.PortNumber
 	}
@@ -568,7 +2493,7 @@ type CredentialConfiguration_GuestCredentialsRemoteWin struct {
 func (x *CredentialConfiguration_GuestCredentialsRemoteWin) Reset() {
 	*x = CredentialConfiguration_GuestCredentialsRemoteWin{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[4]
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -581,7 +2506,7 @@ func (x *CredentialConfiguration_GuestCredentialsRemoteWin) String() string {
 func (*CredentialConfiguration_GuestCredentialsRemoteWin) ProtoMessage() {}
 
 func (x *CredentialConfiguration_GuestCredentialsRemoteWin) ProtoReflect() protoreflect.Message {
-	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[4]
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -594,7 +2519,7 @@ func (x *CredentialConfiguration_GuestCredentialsRemoteWin) ProtoReflect() proto
 
 // Deprecated: Use CredentialConfiguration_GuestCredentialsRemoteWin.ProtoReflect.Descriptor instead.
 func (*CredentialConfiguration_GuestCredentialsRemoteWin) Descriptor() ([]byte, []int) {
-	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{2, 1}
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 4}
 }
 
 func (x *CredentialConfiguration_GuestCredentialsRemoteWin) GetServerName() string {
@@ -636,7 +2561,7 @@ type CredentialConfiguration_GuestCredentialsRemoteLinux struct {
 func (x *CredentialConfiguration_GuestCredentialsRemoteLinux) Reset() {
 	*x = CredentialConfiguration_GuestCredentialsRemoteLinux{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[5]
+		mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -649,7 +2574,7 @@ func (x *CredentialConfiguration_GuestCredentialsRemoteLinux) String() string {
 func (*CredentialConfiguration_GuestCredentialsRemoteLinux) ProtoMessage() {}
 
 func (x *CredentialConfiguration_GuestCredentialsRemoteLinux) ProtoReflect() protoreflect.Message {
-	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[5]
+	mi := &file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -662,7 +2587,7 @@ func (x *CredentialConfiguration_GuestCredentialsRemoteLinux) ProtoReflect() pro
 
 // Deprecated: Use CredentialConfiguration_GuestCredentialsRemoteLinux.ProtoReflect.Descriptor instead.
 func (*CredentialConfiguration_GuestCredentialsRemoteLinux) Descriptor() ([]byte, []int) {
-	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{2, 2}
+	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP(), []int{17, 5}
 }
 
 func (x *CredentialConfiguration_GuestCredentialsRemoteLinux) GetServerName() string {
@@ -700,7 +2625,7 @@ var file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDesc = []byte{
 	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
 	0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74,
 	0x6f, 0x12, 0x14, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e,
-	0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0x95, 0x04, 0x0a, 0x0d, 0x43, 0x6f, 0x6e, 0x66,
+	0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0xc2, 0x11, 0x0a, 0x0d, 0x43, 0x6f, 0x6e, 0x66,
 	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x68, 0x0a, 0x18, 0x63, 0x6f, 0x6c,
 	0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
 	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x73, 0x71,
@@ -733,115 +2658,478 @@ var file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDesc = []byte{
 	0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x6c, 0x6f, 0x67, 0x54, 0x6f, 0x43, 0x6c,
 	0x6f, 0x75, 0x64, 0x12, 0x2a, 0x0a, 0x11, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x6c,
 	0x6f, 0x67, 0x5f, 0x75, 0x73, 0x61, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f,
-	0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x4c, 0x6f, 0x67, 0x55, 0x73, 0x61, 0x67, 0x65, 0x22,
-	0xc1, 0x02, 0x0a, 0x17, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f,
-	0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x18, 0x63,
-	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x5f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x6f, 0x73, 0x5f,
-	0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x63,
-	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x47, 0x75, 0x65, 0x73, 0x74, 0x4f, 0x73, 0x4d, 0x65, 0x74,
-	0x72, 0x69, 0x63, 0x73, 0x12, 0x62, 0x0a, 0x2f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x6f, 0x73,
-	0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x5f,
-	0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x29, 0x67,
-	0x75, 0x65, 0x73, 0x74, 0x4f, 0x73, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x43, 0x6f, 0x6c,
-	0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x49,
-	0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x63, 0x6f, 0x6c, 0x6c,
-	0x65, 0x63, 0x74, 0x5f, 0x73, 0x71, 0x6c, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x11, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x53, 0x71,
-	0x6c, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x59, 0x0a, 0x2a, 0x73, 0x71, 0x6c, 0x5f,
-	0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x5f, 0x73,
-	0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x25, 0x73, 0x71,
-	0x6c, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f,
-	0x6e, 0x64, 0x73, 0x22, 0xf0, 0x0a, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69,
-	0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
-	0x16, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18,
-	0x01, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x08,
-	0x75, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0b, 0x73, 0x65, 0x63, 0x72,
-	0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18,
-	0x01, 0x52, 0x0a, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a,
-	0x0b, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x05, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0a, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62,
-	0x65, 0x72, 0x12, 0x23, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0a, 0x73, 0x65, 0x72,
-	0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74,
-	0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
-	0x42, 0x02, 0x18, 0x01, 0x52, 0x0d, 0x67, 0x75, 0x65, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4e,
-	0x61, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x11, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x63,
-	0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02,
-	0x18, 0x01, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e,
-	0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x69, 0x6e, 0x73, 0x74,
-	0x61, 0x6e, 0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x73, 0x74,
-	0x61, 0x6e, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69,
-	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x64, 0x12, 0x2e, 0x0a, 0x11, 0x67, 0x75, 0x65,
-	0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x0a,
-	0x20, 0x01, 0x28, 0x05, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x50,
-	0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x0c, 0x6c, 0x69, 0x6e,
-	0x75, 0x78, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x42,
-	0x02, 0x18, 0x01, 0x52, 0x0b, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65,
-	0x12, 0x3e, 0x0a, 0x1a, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x5f, 0x73, 0x73, 0x68, 0x5f, 0x70, 0x72,
-	0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x0c,
-	0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x16, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x53,
-	0x73, 0x68, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x50, 0x61, 0x74, 0x68,
-	0x12, 0x6b, 0x0a, 0x12, 0x73, 0x71, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x3c, 0x2e, 0x73,
+	0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x4c, 0x6f, 0x67, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12,
+	0x5a, 0x0a, 0x0f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x76, 0x69, 0x64,
+	0x65, 0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e,
+	0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x64, 0x65, 0x72, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0e, 0x73, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x64, 0x65, 0x72, 0x12, 0x36, 0x0a, 0x17, 0x6f,
+	0x75, 0x74, 0x62, 0x6f, 0x75, 0x6e, 0x64, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x6f, 0x75,
+	0x74, 0x62, 0x6f, 0x75, 0x6e, 0x64, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x3b, 0x0a, 0x1a, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x72, 0x65,
+	0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x66, 0x69, 0x6c, 0x65,
+	0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x05, 0x52, 0x17, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52,
+	0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x61, 0x78, 0x46, 0x69, 0x6c, 0x65, 0x73,
+	0x12, 0x45, 0x0a, 0x20, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x72, 0x65, 0x74, 0x65, 0x6e,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x6e, 0x5f,
+	0x64, 0x61, 0x79, 0x73, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x1b, 0x6f, 0x75, 0x74, 0x70,
+	0x75, 0x74, 0x52, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x61, 0x78, 0x41, 0x67,
+	0x65, 0x49, 0x6e, 0x44, 0x61, 0x79, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x77, 0x6c, 0x6d, 0x5f, 0x65,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x77,
+	0x6c, 0x6d, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x77, 0x6c,
+	0x6d, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0b, 0x77, 0x6c, 0x6d, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3c, 0x0a,
+	0x1a, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f,
+	0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x10, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x18, 0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x30, 0x0a, 0x14, 0x6f,
+	0x74, 0x6c, 0x70, 0x5f, 0x74, 0x72, 0x61, 0x63, 0x65, 0x73, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x6f, 0x74, 0x6c, 0x70, 0x54,
+	0x72, 0x61, 0x63, 0x65, 0x73, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x41, 0x0a,
+	0x06, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e,
+	0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x50, 0x75, 0x62, 0x53, 0x75, 0x62, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x70, 0x75, 0x62, 0x73, 0x75, 0x62,
+	0x12, 0x4d, 0x0a, 0x24, 0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x5f, 0x6c, 0x6f, 0x67, 0x5f, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x69, 0x6e,
+	0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x13, 0x20, 0x01, 0x28, 0x05, 0x52, 0x1f,
+	0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x65, 0x64, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x4c, 0x6f, 0x67,
+	0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12,
+	0x41, 0x0a, 0x06, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x29, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x49, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x69, 0x67, 0x6e, 0x6f,
+	0x72, 0x65, 0x12, 0x44, 0x0a, 0x07, 0x77, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x18, 0x15, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61,
+	0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x57, 0x65, 0x62, 0x68, 0x6f,
+	0x6f, 0x6b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x07, 0x77, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x12, 0x2d, 0x0a, 0x12, 0x74, 0x65, 0x78, 0x74,
+	0x66, 0x69, 0x6c, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x18, 0x16,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x74, 0x65, 0x78, 0x74, 0x66, 0x69, 0x6c, 0x65, 0x44, 0x69,
+	0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x26, 0x0a, 0x0f, 0x6d, 0x69, 0x6e, 0x5f, 0x74,
+	0x6c, 0x73, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x17, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0d, 0x6d, 0x69, 0x6e, 0x54, 0x6c, 0x73, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x4d, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x73, 0x18, 0x18, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e,
+	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x0e,
+	0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x31,
+	0x0a, 0x15, 0x6d, 0x61, 0x78, 0x5f, 0x77, 0x6c, 0x6d, 0x5f, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61,
+	0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x19, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x6d,
+	0x61, 0x78, 0x57, 0x6c, 0x6d, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x79, 0x74, 0x65,
+	0x73, 0x12, 0x53, 0x0a, 0x12, 0x64, 0x69, 0x73, 0x6b, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x5f, 0x6d,
+	0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x1a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e,
+	0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x44, 0x69, 0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x4d, 0x61, 0x70,
+	0x70, 0x69, 0x6e, 0x67, 0x52, 0x10, 0x64, 0x69, 0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x4d, 0x61,
+	0x70, 0x70, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x55, 0x0a, 0x12, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x73, 0x18, 0x1b, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x26, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67,
+	0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x52, 0x11, 0x63, 0x6f, 0x6c, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x73, 0x12, 0x4e, 0x0a,
+	0x0b, 0x64, 0x65, 0x61, 0x64, 0x5f, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x72, 0x18, 0x1c, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67,
+	0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x44, 0x65, 0x61, 0x64, 0x4c, 0x65,
+	0x74, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x0a, 0x64, 0x65, 0x61, 0x64, 0x4c, 0x65, 0x74, 0x74, 0x65, 0x72, 0x12, 0x51, 0x0a,
+	0x0c, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x1d, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61,
+	0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x79, 0x63, 0x6c, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x3b, 0x0a, 0x1a, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x73, 0x69, 0x67, 0x6e, 0x69,
+	0x6e, 0x67, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x1e,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x17, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x53, 0x69, 0x67, 0x6e,
+	0x69, 0x6e, 0x67, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x51, 0x0a,
+	0x0c, 0x73, 0x6f, 0x63, 0x6b, 0x73, 0x35, 0x5f, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x18, 0x1f, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61,
+	0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x53, 0x6f, 0x63, 0x6b, 0x73,
+	0x35, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x73, 0x6f, 0x63, 0x6b, 0x73, 0x35, 0x50, 0x72, 0x6f, 0x78, 0x79,
+	0x12, 0x23, 0x0a, 0x0d, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x18, 0x20, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x46,
+	0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x46, 0x0a, 0x0d, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x5f, 0x72,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x21, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73,
 	0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e,
-	0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43, 0x6f,
-	0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x53, 0x71, 0x6c, 0x43,
-	0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x11, 0x73, 0x71, 0x6c, 0x43,
-	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2b, 0x0a,
-	0x10, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x0f, 0x6c, 0x6f, 0x63, 0x61, 0x6c,
-	0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x68, 0x0a, 0x0a, 0x72, 0x65,
-	0x6d, 0x6f, 0x74, 0x65, 0x5f, 0x77, 0x69, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x47,
-	0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63,
-	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
-	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x47, 0x75,
-	0x65, 0x73, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x65,
-	0x6d, 0x6f, 0x74, 0x65, 0x57, 0x69, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x72, 0x65, 0x6d, 0x6f, 0x74,
-	0x65, 0x57, 0x69, 0x6e, 0x12, 0x6e, 0x0a, 0x0c, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x5f, 0x6c,
-	0x69, 0x6e, 0x75, 0x78, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x49, 0x2e, 0x73, 0x71, 0x6c,
-	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69,
-	0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66,
-	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x47, 0x75, 0x65, 0x73, 0x74, 0x43,
+	0x66, 0x69, 0x67, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x52,
+	0x0c, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x5a, 0x0a,
+	0x0f, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x18, 0x22, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x4d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0e, 0x6d, 0x65, 0x74, 0x61, 0x64,
+	0x61, 0x74, 0x61, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x41, 0x0a, 0x06, 0x73, 0x71, 0x6c,
+	0x69, 0x74, 0x65, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x73, 0x71, 0x6c, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x2e, 0x53, 0x71, 0x6c, 0x69, 0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x73, 0x71, 0x6c, 0x69, 0x74, 0x65, 0x22, 0x50, 0x0a, 0x13,
+	0x53, 0x71, 0x6c, 0x69, 0x74, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x25, 0x0a, 0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x61,
+	0x67, 0x65, 0x5f, 0x69, 0x6e, 0x5f, 0x64, 0x61, 0x79, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0c, 0x6d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x49, 0x6e, 0x44, 0x61, 0x79, 0x73, 0x22, 0x6c,
+	0x0a, 0x1b, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a,
+	0x12, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f,
+	0x6e, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6d,
+	0x61, 0x78, 0x5f, 0x72, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0a, 0x6d, 0x61, 0x78, 0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x22, 0x83, 0x01, 0x0a,
+	0x18, 0x53, 0x6f, 0x63, 0x6b, 0x73, 0x35, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x12, 0x30, 0x0a, 0x14, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x5f, 0x73, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12,
+	0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61,
+	0x6d, 0x65, 0x22, 0x94, 0x01, 0x0a, 0x18, 0x43, 0x79, 0x63, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x3f, 0x0a, 0x1c, 0x6d, 0x69, 0x6e, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f,
+	0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x19, 0x6d, 0x69, 0x6e, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e,
+	0x63, 0x65, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74,
+	0x12, 0x37, 0x0a, 0x18, 0x6d, 0x69, 0x6e, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x5f, 0x73, 0x75, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x15, 0x6d, 0x69, 0x6e, 0x52, 0x75, 0x6c, 0x65, 0x53, 0x75, 0x63, 0x63, 0x65,
+	0x73, 0x73, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x22, 0x6f, 0x0a, 0x17, 0x44, 0x65, 0x61,
+	0x64, 0x4c, 0x65, 0x74, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f,
+	0x72, 0x79, 0x12, 0x1e, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x6d,
+	0x62, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6d, 0x61, 0x78, 0x53, 0x69, 0x7a, 0x65,
+	0x4d, 0x62, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x22, 0x7d, 0x0a, 0x10, 0x43, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x12,
+	0x0a, 0x04, 0x64, 0x61, 0x79, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61,
+	0x79, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d,
+	0x65, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09,
+	0x74, 0x69, 0x6d, 0x65, 0x5f, 0x7a, 0x6f, 0x6e, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x74, 0x69, 0x6d, 0x65, 0x5a, 0x6f, 0x6e, 0x65, 0x22, 0x92, 0x01, 0x0a, 0x0f, 0x44, 0x69,
+	0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x12, 0x32, 0x0a,
+	0x15, 0x66, 0x72, 0x69, 0x65, 0x6e, 0x64, 0x6c, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x5f, 0x70,
+	0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13, 0x66, 0x72,
+	0x69, 0x65, 0x6e, 0x64, 0x6c, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72,
+	0x6e, 0x12, 0x2e, 0x0a, 0x13, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70,
+	0x6c, 0x65, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11,
+	0x73, 0x69, 0x7a, 0x65, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x65, 0x42, 0x79, 0x74, 0x65,
+	0x73, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x69, 0x73, 0x6b, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x69, 0x73, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x22, 0x78,
+	0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x72, 0x6f, 0x63, 0x73, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x50, 0x72, 0x6f, 0x63, 0x73, 0x12, 0x26, 0x0a,
+	0x0f, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x5f, 0x6d, 0x62,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x4d, 0x62, 0x12, 0x21, 0x0a, 0x0c, 0x6c, 0x6f, 0x77, 0x5f, 0x70, 0x72, 0x69,
+	0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x6c, 0x6f, 0x77,
+	0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x59, 0x0a, 0x13, 0x49, 0x67, 0x6e, 0x6f,
+	0x72, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x1d, 0x0a, 0x0a, 0x77, 0x61, 0x69, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x09, 0x77, 0x61, 0x69, 0x74, 0x54, 0x79, 0x70, 0x65, 0x73, 0x12, 0x23,
+	0x0a, 0x0d, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x4e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x73, 0x22, 0xa9, 0x01, 0x0a, 0x14, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03,
+	0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x37,
+	0x0a, 0x18, 0x62, 0x65, 0x61, 0x72, 0x65, 0x72, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x5f, 0x73,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x15, 0x62, 0x65, 0x61, 0x72, 0x65, 0x72, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x53, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x46, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x73, 0x71,
+	0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22,
+	0x6e, 0x0a, 0x10, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x1a, 0x0a,
+	0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22,
+	0x4b, 0x0a, 0x0b, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x75,
+	0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0xa3, 0x01, 0x0a,
+	0x13, 0x50, 0x75, 0x62, 0x53, 0x75, 0x62, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x12, 0x3e, 0x0a, 0x1b, 0x69, 0x6d,
+	0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x61, 0x74, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x19, 0x69, 0x6d, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x61, 0x74, 0x65, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x36, 0x0a, 0x17, 0x65, 0x6e,
+	0x61, 0x62, 0x6c, 0x65, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x6f, 0x72, 0x64,
+	0x65, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x65, 0x6e, 0x61,
+	0x62, 0x6c, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x69,
+	0x6e, 0x67, 0x22, 0xe6, 0x01, 0x0a, 0x1b, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x50, 0x72, 0x6f,
+	0x76, 0x69, 0x64, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x3e, 0x0a, 0x05, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x56, 0x61, 0x75,
+	0x6c, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x05, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x72, 0x65,
+	0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x6d, 0x61, 0x78,
+	0x52, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x52, 0x0a, 0x26, 0x69, 0x6e, 0x69, 0x74, 0x69,
+	0x61, 0x6c, 0x5f, 0x72, 0x65, 0x74, 0x72, 0x79, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61,
+	0x6c, 0x5f, 0x69, 0x6e, 0x5f, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x22, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c,
+	0x52, 0x65, 0x74, 0x72, 0x79, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x49, 0x6e, 0x4d,
+	0x69, 0x6c, 0x6c, 0x69, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0xdb, 0x01, 0x0a, 0x12,
+	0x56, 0x61, 0x75, 0x6c, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a,
+	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x50, 0x61, 0x74, 0x68, 0x12, 0x26, 0x0a, 0x0f, 0x74,
+	0x6f, 0x6b, 0x65, 0x6e, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x50,
+	0x61, 0x74, 0x68, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x6f, 0x6c, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x6f, 0x6c, 0x65, 0x49, 0x64, 0x12, 0x2d, 0x0a, 0x13,
+	0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x70,
+	0x61, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x73, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x49, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x61, 0x74, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x6e,
+	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x22, 0x8a, 0x0b, 0x0a, 0x17, 0x43, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x18, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
+	0x5f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x6f, 0x73, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
+	0x47, 0x75, 0x65, 0x73, 0x74, 0x4f, 0x73, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x62,
+	0x0a, 0x2f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x6f, 0x73, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x29, 0x67, 0x75, 0x65, 0x73, 0x74, 0x4f, 0x73,
+	0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x5f, 0x73, 0x71,
+	0x6c, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x11, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x53, 0x71, 0x6c, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x12, 0x59, 0x0a, 0x2a, 0x73, 0x71, 0x6c, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63,
+	0x73, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x25, 0x73, 0x71, 0x6c, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x2e, 0x0a,
+	0x13, 0x76, 0x6c, 0x66, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x73,
+	0x68, 0x6f, 0x6c, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x11, 0x76, 0x6c, 0x66, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x54, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x12, 0x32, 0x0a,
+	0x15, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x64, 0x65, 0x5f, 0x65, 0x6e, 0x63, 0x72,
+	0x79, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x13, 0x65, 0x78,
+	0x70, 0x65, 0x63, 0x74, 0x54, 0x64, 0x65, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x48, 0x0a, 0x21, 0x64, 0x69, 0x73, 0x6b, 0x5f, 0x66, 0x72, 0x65, 0x65, 0x5f, 0x73,
+	0x70, 0x61, 0x63, 0x65, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x5f, 0x70,
+	0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x1d, 0x64, 0x69,
+	0x73, 0x6b, 0x46, 0x72, 0x65, 0x65, 0x53, 0x70, 0x61, 0x63, 0x65, 0x54, 0x68, 0x72, 0x65, 0x73,
+	0x68, 0x6f, 0x6c, 0x64, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x12, 0x3f, 0x0a, 0x1c, 0x63,
+	0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x73, 0x6b, 0x65, 0x77, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68,
+	0x6f, 0x6c, 0x64, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x19, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x6b, 0x65, 0x77, 0x54, 0x68, 0x72, 0x65,
+	0x73, 0x68, 0x6f, 0x6c, 0x64, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x35, 0x0a, 0x16,
+	0x69, 0x6e, 0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6c, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x69, 0x6e,
+	0x63, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x61, 0x6c, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x17, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x68, 0x6f,
+	0x73, 0x74, 0x5f, 0x75, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x48, 0x6f, 0x73, 0x74,
+	0x55, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3a, 0x0a, 0x19, 0x6c,
+	0x6f, 0x63, 0x6b, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x69, 0x6c, 0x6c,
+	0x69, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x52, 0x17,
+	0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x69, 0x6c, 0x6c, 0x69,
+	0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x70, 0x5f, 0x71,
+	0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x74, 0x6f,
+	0x70, 0x51, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x74, 0x6f, 0x70, 0x5f,
+	0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x5f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x18, 0x0d,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x74, 0x6f, 0x70, 0x51, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73,
+	0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x36, 0x0a, 0x17, 0x72, 0x65, 0x63, 0x6f, 0x6d, 0x6d,
+	0x65, 0x6e, 0x64, 0x65, 0x64, 0x5f, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x5f, 0x70, 0x6c, 0x61, 0x6e,
+	0x73, 0x18, 0x0e, 0x20, 0x03, 0x28, 0x09, 0x52, 0x15, 0x72, 0x65, 0x63, 0x6f, 0x6d, 0x6d, 0x65,
+	0x6e, 0x64, 0x65, 0x64, 0x50, 0x6f, 0x77, 0x65, 0x72, 0x50, 0x6c, 0x61, 0x6e, 0x73, 0x12, 0x33,
+	0x0a, 0x16, 0x77, 0x6d, 0x69, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x5f, 0x70, 0x65,
+	0x72, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x05, 0x52, 0x13,
+	0x77, 0x6d, 0x69, 0x51, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x12, 0x3d, 0x0a, 0x1b, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x61, 0x67,
+	0x65, 0x6e, 0x74, 0x5f, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x75, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x18, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x41, 0x67, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x55, 0x73, 0x61,
+	0x67, 0x65, 0x12, 0x3c, 0x0a, 0x1b, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x5f, 0x72, 0x75, 0x6c,
+	0x65, 0x73, 0x5f, 0x74, 0x74, 0x6c, 0x5f, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52, 0x17, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x52,
+	0x75, 0x6c, 0x65, 0x73, 0x54, 0x74, 0x6c, 0x49, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73,
+	0x12, 0x3b, 0x0a, 0x1a, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x5f, 0x66, 0x69, 0x6c, 0x6c, 0x5f, 0x66, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x12,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x17, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x49, 0x6e, 0x64,
+	0x65, 0x78, 0x46, 0x69, 0x6c, 0x6c, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x3c, 0x0a,
+	0x1b, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x66, 0x69, 0x6c, 0x6c, 0x5f, 0x66, 0x61, 0x63, 0x74,
+	0x6f, 0x72, 0x5f, 0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x73, 0x18, 0x13, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x17, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x46, 0x69, 0x6c, 0x6c, 0x46, 0x61, 0x63,
+	0x74, 0x6f, 0x72, 0x4d, 0x69, 0x6e, 0x50, 0x61, 0x67, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x17, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x64, 0x62, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x67, 0x65, 0x5f, 0x69,
+	0x6e, 0x5f, 0x64, 0x61, 0x79, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x05, 0x52, 0x13, 0x63, 0x68,
+	0x65, 0x63, 0x6b, 0x64, 0x62, 0x4d, 0x61, 0x78, 0x41, 0x67, 0x65, 0x49, 0x6e, 0x44, 0x61, 0x79,
+	0x73, 0x12, 0x32, 0x0a, 0x15, 0x72, 0x65, 0x75, 0x73, 0x65, 0x5f, 0x73, 0x71, 0x6c, 0x5f, 0x63,
+	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x15, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x13, 0x72, 0x65, 0x75, 0x73, 0x65, 0x53, 0x71, 0x6c, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x51, 0x0a, 0x26, 0x73, 0x71, 0x6c, 0x5f, 0x63, 0x6f, 0x6e,
+	0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x6c, 0x65, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18,
+	0x16, 0x20, 0x01, 0x28, 0x05, 0x52, 0x21, 0x73, 0x71, 0x6c, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x6c, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x49,
+	0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x3c, 0x0a, 0x1a, 0x72, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x61,
+	0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x18, 0x17, 0x20, 0x01, 0x28, 0x08, 0x52, 0x18, 0x72, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x61,
+	0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x22, 0x95, 0x12, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x64, 0x65,
+	0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x42, 0x02, 0x18, 0x01, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x09, 0x75, 0x73,
+	0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18,
+	0x01, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0b, 0x73,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x42, 0x02, 0x18, 0x01, 0x52, 0x0a, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65,
+	0x12, 0x23, 0x0a, 0x0b, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0a, 0x70, 0x6f, 0x72, 0x74, 0x4e,
+	0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x23, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0a,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x0f, 0x67, 0x75,
+	0x65, 0x73, 0x74, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0d, 0x67, 0x75, 0x65, 0x73, 0x74, 0x55, 0x73,
+	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x11, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f,
+	0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x53, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e,
+	0x63, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x69,
+	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x69,
+	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x64, 0x12, 0x2e, 0x0a, 0x11,
+	0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65,
+	0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0f, 0x67, 0x75, 0x65,
+	0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x0c,
+	0x6c, 0x69, 0x6e, 0x75, 0x78, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x18, 0x0b, 0x20, 0x01,
+	0x28, 0x08, 0x42, 0x02, 0x18, 0x01, 0x52, 0x0b, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x52, 0x65, 0x6d,
+	0x6f, 0x74, 0x65, 0x12, 0x3e, 0x0a, 0x1a, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x5f, 0x73, 0x73, 0x68,
+	0x5f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x74,
+	0x68, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x42, 0x02, 0x18, 0x01, 0x52, 0x16, 0x6c, 0x69, 0x6e,
+	0x75, 0x78, 0x53, 0x73, 0x68, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x50,
+	0x61, 0x74, 0x68, 0x12, 0x6b, 0x0a, 0x12, 0x73, 0x71, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x3c, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
+	0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x53,
+	0x71, 0x6c, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x11, 0x73,
+	0x71, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x12, 0x2b, 0x0a, 0x10, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x0f, 0x6c, 0x6f,
+	0x63, 0x61, 0x6c, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x68, 0x0a,
+	0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x5f, 0x77, 0x69, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x47, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65,
+	0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x47, 0x75, 0x65, 0x73, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
+	0x73, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x57, 0x69, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x72, 0x65,
+	0x6d, 0x6f, 0x74, 0x65, 0x57, 0x69, 0x6e, 0x12, 0x6e, 0x0a, 0x0c, 0x72, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x5f, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x49, 0x2e,
+	0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x47, 0x75, 0x65,
+	0x73, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x6d,
+	0x6f, 0x74, 0x65, 0x4c, 0x69, 0x6e, 0x75, 0x78, 0x48, 0x00, 0x52, 0x0b, 0x72, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x4c, 0x69, 0x6e, 0x75, 0x78, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72,
+	0x69, 0x74, 0x79, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72,
+	0x69, 0x74, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x77, 0x6f, 0x72, 0x6b, 0x6c, 0x6f, 0x61, 0x64, 0x5f,
+	0x74, 0x79, 0x70, 0x65, 0x18, 0x12, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x77, 0x6f, 0x72, 0x6b,
+	0x6c, 0x6f, 0x61, 0x64, 0x54, 0x79, 0x70, 0x65, 0x1a, 0x8d, 0x05, 0x0a, 0x0e, 0x53, 0x71, 0x6c,
+	0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x68,
+	0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12,
+	0x1b, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a,
+	0x0b, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0a, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x52,
+	0x0a, 0x07, 0x62, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x38, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
+	0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x53,
+	0x73, 0x68, 0x42, 0x61, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x62, 0x61, 0x73, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x17, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x5f, 0x65, 0x78, 0x74, 0x72, 0x61, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x15, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53,
+	0x74, 0x72, 0x69, 0x6e, 0x67, 0x45, 0x78, 0x74, 0x72, 0x61, 0x12, 0x53, 0x0a, 0x09, 0x69, 0x61,
+	0x6d, 0x5f, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x36, 0x2e,
+	0x73, 0x71, 0x6c, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x49, 0x61, 0x6d,
+	0x50, 0x72, 0x6f, 0x78, 0x79, 0x52, 0x08, 0x69, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x12,
+	0x3a, 0x0a, 0x19, 0x66, 0x61, 0x69, 0x6c, 0x6f, 0x76, 0x65, 0x72, 0x5f, 0x63, 0x6c, 0x75, 0x73,
+	0x74, 0x65, 0x72, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x17, 0x66, 0x61, 0x69, 0x6c, 0x6f, 0x76, 0x65, 0x72, 0x43, 0x6c, 0x75, 0x73,
+	0x74, 0x65, 0x72, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x43, 0x0a, 0x03, 0x74,
+	0x6c, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x73, 0x71, 0x6c, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e,
+	0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x54, 0x6c, 0x73, 0x52, 0x03, 0x74, 0x6c, 0x73,
+	0x12, 0x27, 0x0a, 0x0f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x6c, 0x6f, 0x63, 0x61, 0x6c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x27, 0x0a, 0x0f, 0x73, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0b, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0e, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x69,
+	0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x64, 0x61,
+	0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x49, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x12, 0x29, 0x0a,
+	0x10, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64,
+	0x65, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73,
+	0x65, 0x45, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x1a, 0x81, 0x01, 0x0a, 0x03, 0x54, 0x6c, 0x73,
+	0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x12, 0x38, 0x0a, 0x18, 0x74, 0x72,
+	0x75, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x69,
+	0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x16, 0x74, 0x72,
+	0x75, 0x73, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x12, 0x26, 0x0a, 0x0f, 0x6d, 0x69, 0x6e, 0x5f, 0x74, 0x6c, 0x73, 0x5f,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6d,
+	0x69, 0x6e, 0x54, 0x6c, 0x73, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x49, 0x0a, 0x08,
+	0x49, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x61, 0x6d, 0x5f, 0x69, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x69, 0x61, 0x6d, 0x49,
+	0x64, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x1a, 0x88, 0x01, 0x0a, 0x0a, 0x53, 0x73, 0x68, 0x42,
+	0x61, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x75, 0x73,
+	0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75,
+	0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x50, 0x61, 0x74,
+	0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x70, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x1a, 0x90, 0x01, 0x0a, 0x19, 0x47, 0x75, 0x65, 0x73, 0x74, 0x43, 0x72, 0x65, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x57, 0x69, 0x6e,
+	0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x26, 0x0a, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x67, 0x75, 0x65, 0x73,
+	0x74, 0x55, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x11, 0x67, 0x75, 0x65,
+	0x73, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x1a, 0xce, 0x01, 0x0a, 0x1b, 0x47, 0x75, 0x65, 0x73, 0x74, 0x43,
 	0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65,
-	0x4c, 0x69, 0x6e, 0x75, 0x78, 0x48, 0x00, 0x52, 0x0b, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x4c,
-	0x69, 0x6e, 0x75, 0x78, 0x1a, 0x83, 0x01, 0x0a, 0x0e, 0x53, 0x71, 0x6c, 0x43, 0x72, 0x65, 0x64,
-	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x75,
-	0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
-	0x75, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x63, 0x72,
-	0x65, 0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73,
-	0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6f, 0x72,
-	0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
-	0x70, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x1a, 0x90, 0x01, 0x0a, 0x19, 0x47,
-	0x75, 0x65, 0x73, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x52,
-	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x57, 0x69, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76,
-	0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73,
-	0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x26, 0x0a, 0x0f, 0x67, 0x75, 0x65,
-	0x73, 0x74, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x0d, 0x67, 0x75, 0x65, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d,
-	0x65, 0x12, 0x2a, 0x0a, 0x11, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65,
-	0x74, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x67, 0x75,
-	0x65, 0x73, 0x74, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x1a, 0xce, 0x01,
-	0x0a, 0x1b, 0x47, 0x75, 0x65, 0x73, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
-	0x6c, 0x73, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x4c, 0x69, 0x6e, 0x75, 0x78, 0x12, 0x1f, 0x0a,
-	0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x26,
-	0x0a, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x67, 0x75, 0x65, 0x73, 0x74, 0x55, 0x73,
-	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x11, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f,
-	0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62,
-	0x65, 0x72, 0x12, 0x3a, 0x0a, 0x1a, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x5f, 0x73, 0x73, 0x68, 0x5f,
-	0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x74, 0x68,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x16, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x53, 0x73, 0x68,
-	0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x50, 0x61, 0x74, 0x68, 0x42, 0x16,
-	0x0a, 0x14, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x4c, 0x69, 0x6e, 0x75, 0x78, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x26, 0x0a, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f,
+	0x75, 0x73, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x67, 0x75, 0x65, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2a,
+	0x0a, 0x11, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x6e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x67, 0x75, 0x65, 0x73, 0x74,
+	0x50, 0x6f, 0x72, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x3a, 0x0a, 0x1a, 0x6c, 0x69,
+	0x6e, 0x75, 0x78, 0x5f, 0x73, 0x73, 0x68, 0x5f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f,
+	0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x16,
+	0x6c, 0x69, 0x6e, 0x75, 0x78, 0x53, 0x73, 0x68, 0x50, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4b,
+	0x65, 0x79, 0x50, 0x61, 0x74, 0x68, 0x42, 0x16, 0x0a, 0x14, 0x67, 0x75, 0x65, 0x73, 0x74, 0x5f,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -856,26 +3144,62 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescGZIP() []byte {
 	return file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDescData
 }
 
-var file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_sqlserveragentconfig_sqlserveragentconfig_proto_goTypes = []interface{}{
 	(*Configuration)(nil),                                       // 0: sqlserveragentconfig.Configuration
-	(*CollectionConfiguration)(nil),                             // 1: sqlserveragentconfig.CollectionConfiguration
-	(*CredentialConfiguration)(nil),                             // 2: sqlserveragentconfig.CredentialConfiguration
-	(*CredentialConfiguration_SqlCredentials)(nil),              // 3: sqlserveragentconfig.CredentialConfiguration.SqlCredentials
-	(*CredentialConfiguration_GuestCredentialsRemoteWin)(nil),   // 4: sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteWin
-	(*CredentialConfiguration_GuestCredentialsRemoteLinux)(nil), // 5: sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteLinux
+	(*SqliteConfiguration)(nil),                                 // 1: sqlserveragentconfig.SqliteConfiguration
+	(*MetadataServerConfiguration)(nil),                         // 2: sqlserveragentconfig.MetadataServerConfiguration
+	(*Socks5ProxyConfiguration)(nil),                            // 3: sqlserveragentconfig.Socks5ProxyConfiguration
+	(*CycleStatusConfiguration)(nil),                            // 4: sqlserveragentconfig.CycleStatusConfiguration
+	(*DeadLetterConfiguration)(nil),                             // 5: sqlserveragentconfig.DeadLetterConfiguration
+	(*CollectionWindow)(nil),                                    // 6: sqlserveragentconfig.CollectionWindow
+	(*DiskTypeMapping)(nil),                                     // 7: sqlserveragentconfig.DiskTypeMapping
+	(*ResourceLimits)(nil),                                      // 8: sqlserveragentconfig.ResourceLimits
+	(*IgnoreConfiguration)(nil),                                 // 9: sqlserveragentconfig.IgnoreConfiguration
+	(*WebhookConfiguration)(nil),                                // 10: sqlserveragentconfig.WebhookConfiguration
+	(*WebhookCondition)(nil),                                    // 11: sqlserveragentconfig.WebhookCondition
+	(*FieldRename)(nil),                                         // 12: sqlserveragentconfig.FieldRename
+	(*PubSubConfiguration)(nil),                                 // 13: sqlserveragentconfig.PubSubConfiguration
+	(*SecretProviderConfiguration)(nil),                         // 14: sqlserveragentconfig.SecretProviderConfiguration
+	(*VaultConfiguration)(nil),                                  // 15: sqlserveragentconfig.VaultConfiguration
+	(*CollectionConfiguration)(nil),                             // 16: sqlserveragentconfig.CollectionConfiguration
+	(*CredentialConfiguration)(nil),                             // 17: sqlserveragentconfig.CredentialConfiguration
+	(*CredentialConfiguration_SqlCredentials)(nil),              // 18: sqlserveragentconfig.CredentialConfiguration.SqlCredentials
+	(*CredentialConfiguration_Tls)(nil),                         // 19: sqlserveragentconfig.CredentialConfiguration.Tls
+	(*CredentialConfiguration_IamProxy)(nil),                    // 20: sqlserveragentconfig.CredentialConfiguration.IamProxy
+	(*CredentialConfiguration_SshBastion)(nil),                  // 21: sqlserveragentconfig.CredentialConfiguration.SshBastion
+	(*CredentialConfiguration_GuestCredentialsRemoteWin)(nil),   // 22: sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteWin
+	(*CredentialConfiguration_GuestCredentialsRemoteLinux)(nil), // 23: sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteLinux
 }
 var file_sqlserveragentconfig_sqlserveragentconfig_proto_depIdxs = []int32{
-	1, // 0: sqlserveragentconfig.Configuration.collection_configuration:type_name -> sqlserveragentconfig.CollectionConfiguration
-	2, // 1: sqlserveragentconfig.Configuration.credential_configuration:type_name -> sqlserveragentconfig.CredentialConfiguration
-	3, // 2: sqlserveragentconfig.CredentialConfiguration.sql_configurations:type_name -> sqlserveragentconfig.CredentialConfiguration.SqlCredentials
-	4, // 3: sqlserveragentconfig.CredentialConfiguration.remote_win:type_name -> sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteWin
-	5, // 4: sqlserveragentconfig.CredentialConfiguration.remote_linux:type_name -> sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteLinux
-	5, // [5:5] is the sub-list for method output_type
-	5, // [5:5] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	16, // 0: sqlserveragentconfig.Configuration.collection_configuration:type_name -> sqlserveragentconfig.CollectionConfiguration
+	17, // 1: sqlserveragentconfig.Configuration.credential_configuration:type_name -> sqlserveragentconfig.CredentialConfiguration
+	14, // 2: sqlserveragentconfig.Configuration.secret_provider:type_name -> sqlserveragentconfig.SecretProviderConfiguration
+	13, // 3: sqlserveragentconfig.Configuration.pubsub:type_name -> sqlserveragentconfig.PubSubConfiguration
+	9,  // 4: sqlserveragentconfig.Configuration.ignore:type_name -> sqlserveragentconfig.IgnoreConfiguration
+	10, // 5: sqlserveragentconfig.Configuration.webhook:type_name -> sqlserveragentconfig.WebhookConfiguration
+	8,  // 6: sqlserveragentconfig.Configuration.resource_limits:type_name -> sqlserveragentconfig.ResourceLimits
+	7,  // 7: sqlserveragentconfig.Configuration.disk_type_mappings:type_name -> sqlserveragentconfig.DiskTypeMapping
+	6,  // 8: sqlserveragentconfig.Configuration.collection_windows:type_name -> sqlserveragentconfig.CollectionWindow
+	5,  // 9: sqlserveragentconfig.Configuration.dead_letter:type_name -> sqlserveragentconfig.DeadLetterConfiguration
+	4,  // 10: sqlserveragentconfig.Configuration.cycle_status:type_name -> sqlserveragentconfig.CycleStatusConfiguration
+	3,  // 11: sqlserveragentconfig.Configuration.socks5_proxy:type_name -> sqlserveragentconfig.Socks5ProxyConfiguration
+	12, // 12: sqlserveragentconfig.Configuration.field_renames:type_name -> sqlserveragentconfig.FieldRename
+	2,  // 13: sqlserveragentconfig.Configuration.metadata_server:type_name -> sqlserveragentconfig.MetadataServerConfiguration
+	1,  // 14: sqlserveragentconfig.Configuration.sqlite:type_name -> sqlserveragentconfig.SqliteConfiguration
+	11, // 15: sqlserveragentconfig.WebhookConfiguration.conditions:type_name -> sqlserveragentconfig.WebhookCondition
+	15, // 16: sqlserveragentconfig.SecretProviderConfiguration.vault:type_name -> sqlserveragentconfig.VaultConfiguration
+	18, // 17: sqlserveragentconfig.CredentialConfiguration.sql_configurations:type_name -> sqlserveragentconfig.CredentialConfiguration.SqlCredentials
+	22, // 18: sqlserveragentconfig.CredentialConfiguration.remote_win:type_name -> sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteWin
+	23, // 19: sqlserveragentconfig.CredentialConfiguration.remote_linux:type_name -> sqlserveragentconfig.CredentialConfiguration.GuestCredentialsRemoteLinux
+	21, // 20: sqlserveragentconfig.CredentialConfiguration.SqlCredentials.bastion:type_name -> sqlserveragentconfig.CredentialConfiguration.SshBastion
+	20, // 21: sqlserveragentconfig.CredentialConfiguration.SqlCredentials.iam_proxy:type_name -> sqlserveragentconfig.CredentialConfiguration.IamProxy
+	19, // 22: sqlserveragentconfig.CredentialConfiguration.SqlCredentials.tls:type_name -> sqlserveragentconfig.CredentialConfiguration.Tls
+	23, // [23:23] is the sub-list for method output_type
+	23, // [23:23] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_sqlserveragentconfig_sqlserveragentconfig_proto_init() }
@@ -897,7 +3221,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CollectionConfiguration); i {
+			switch v := v.(*SqliteConfiguration); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -909,7 +3233,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CredentialConfiguration); i {
+			switch v := v.(*MetadataServerConfiguration); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -921,7 +3245,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CredentialConfiguration_SqlCredentials); i {
+			switch v := v.(*Socks5ProxyConfiguration); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -933,7 +3257,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CredentialConfiguration_GuestCredentialsRemoteWin); i {
+			switch v := v.(*CycleStatusConfiguration); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -945,6 +3269,222 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeadLetterConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionWindow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DiskTypeMapping); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceLimits); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IgnoreConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WebhookConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WebhookCondition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FieldRename); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PubSubConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecretProviderConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VaultConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration_SqlCredentials); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration_Tls); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration_IamProxy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration_SshBastion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CredentialConfiguration_GuestCredentialsRemoteWin); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CredentialConfiguration_GuestCredentialsRemoteLinux); i {
 			case 0:
 				return &v.state
@@ -957,7 +3497,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			}
 		}
 	}
-	file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[2].OneofWrappers = []interface{}{
+	file_sqlserveragentconfig_sqlserveragentconfig_proto_msgTypes[17].OneofWrappers = []interface{}{
 		(*CredentialConfiguration_LocalCollection)(nil),
 		(*CredentialConfiguration_RemoteWin)(nil),
 		(*CredentialConfiguration_RemoteLinux)(nil),
@@ -968,7 +3508,7 @@ func file_sqlserveragentconfig_sqlserveragentconfig_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sqlserveragentconfig_sqlserveragentconfig_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   0,
 		},